@@ -6,23 +6,29 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/bricks-cloud/bricksllm/internal/alert"
 	auth "github.com/bricks-cloud/bricksllm/internal/authenticator"
 	"github.com/bricks-cloud/bricksllm/internal/cache"
 	"github.com/bricks-cloud/bricksllm/internal/config"
 	"github.com/bricks-cloud/bricksllm/internal/encryptor"
+	"github.com/bricks-cloud/bricksllm/internal/jwt"
 	"github.com/bricks-cloud/bricksllm/internal/logger/zap"
 	"github.com/bricks-cloud/bricksllm/internal/manager"
 	"github.com/bricks-cloud/bricksllm/internal/message"
 	"github.com/bricks-cloud/bricksllm/internal/pii"
 	"github.com/bricks-cloud/bricksllm/internal/pii/amazon"
+	"github.com/bricks-cloud/bricksllm/internal/pii/local"
 	custompolicy "github.com/bricks-cloud/bricksllm/internal/policy/custom"
 	"github.com/bricks-cloud/bricksllm/internal/provider/anthropic"
 	"github.com/bricks-cloud/bricksllm/internal/provider/azure"
+	"github.com/bricks-cloud/bricksllm/internal/provider/bedrock"
 	"github.com/bricks-cloud/bricksllm/internal/provider/custom"
 	"github.com/bricks-cloud/bricksllm/internal/provider/deepinfra"
+	"github.com/bricks-cloud/bricksllm/internal/provider/gemini"
 	"github.com/bricks-cloud/bricksllm/internal/provider/openai"
 	"github.com/bricks-cloud/bricksllm/internal/provider/vllm"
 	"github.com/bricks-cloud/bricksllm/internal/recorder"
@@ -127,6 +133,47 @@ func main() {
 		log.Sugar().Fatalf("error creating policies table: %v", err)
 	}
 
+	err = store.AlterPolicyTable()
+	if err != nil {
+		log.Sugar().Fatalf("error altering policies table: %v", err)
+	}
+
+	err = store.CreateModelAliasesTable()
+	if err != nil {
+		log.Sugar().Fatalf("error creating model aliases table: %v", err)
+	}
+
+	err = store.CreatePricesTable()
+	if err != nil {
+		log.Sugar().Fatalf("error creating prices table: %v", err)
+	}
+
+	prm := manager.NewPriceManager(store)
+	err = prm.SeedDefaultPrices()
+	if err != nil {
+		log.Sugar().Fatalf("error seeding prices: %v", err)
+	}
+
+	err = store.CreatePrincipalsTable()
+	if err != nil {
+		log.Sugar().Fatalf("error creating admin principals table: %v", err)
+	}
+
+	err = store.CreateAuditLogsTable()
+	if err != nil {
+		log.Sugar().Fatalf("error creating audit logs table: %v", err)
+	}
+
+	err = store.CreateBudgetsTable()
+	if err != nil {
+		log.Sugar().Fatalf("error creating budgets table: %v", err)
+	}
+
+	err = store.CreateWebhooksTable()
+	if err != nil {
+		log.Sugar().Fatalf("error creating webhooks table: %v", err)
+	}
+
 	err = store.CreateEventsByDayTable()
 	if err != nil {
 		log.Sugar().Fatalf("error creating event aggregated by day table: %v", err)
@@ -152,6 +199,11 @@ func main() {
 		log.Sugar().Fatalf("error creating users table: %v", err)
 	}
 
+	err = store.AlterUsersTable()
+	if err != nil {
+		log.Sugar().Fatalf("error altering users table: %v", err)
+	}
+
 	err = store.CreateCreatedAtIndexForUsers()
 	if err != nil {
 		log.Sugar().Fatalf("error creating created at index for users table: %v", err)
@@ -174,6 +226,24 @@ func main() {
 	}
 	rMemStore.Listen()
 
+	maMemStore, err := memdb.NewModelAliasesMemDb(store, log, cfg.InMemoryDbUpdateInterval)
+	if err != nil {
+		log.Sugar().Fatalf("cannot initialize model aliases memdb: %v", err)
+	}
+	maMemStore.Listen()
+
+	prMemStore, err := memdb.NewPricesMemDb(store, log, cfg.InMemoryDbUpdateInterval)
+	if err != nil {
+		log.Sugar().Fatalf("cannot initialize prices memdb: %v", err)
+	}
+	prMemStore.Listen()
+
+	bMemStore, err := memdb.NewBudgetsMemDb(store, log, cfg.InMemoryDbUpdateInterval)
+	if err != nil {
+		log.Sugar().Fatalf("cannot initialize budgets memdb: %v", err)
+	}
+	bMemStore.Listen()
+
 	defaultRedisOption := func(cfg *config.Config, dbIndex int) *redis.Options {
 
 		options := &redis.Options{
@@ -273,6 +343,7 @@ func main() {
 	}
 
 	rateLimitCache := redisStorage.NewCache(rateLimitRedisCache, cfg.RedisWriteTimeout, cfg.RedisReadTimeout)
+	rateLimiter := redisStorage.NewRateLimiter(rateLimitRedisCache, cfg.RedisWriteTimeout, cfg.RedisReadTimeout)
 	costLimitCache := redisStorage.NewCache(costLimitRedisCache, cfg.RedisWriteTimeout, cfg.RedisReadTimeout)
 	costStorage := redisStorage.NewStore(costRedisStorage, cfg.RedisWriteTimeout, cfg.RedisReadTimeout)
 	apiCache := redisStorage.NewCache(apiRedisCache, cfg.RedisWriteTimeout, cfg.RedisReadTimeout)
@@ -283,7 +354,14 @@ func main() {
 	userCostStorage := redisStorage.NewStore(userCostRedisStorage, cfg.RedisWriteTimeout, cfg.RedisReadTimeout)
 	userAccessCache := redisStorage.NewAccessCache(userAccessRedisCache, cfg.RedisWriteTimeout, cfg.RedisReadTimeout)
 
+	budgetCostLimitCache := redisStorage.NewCacheWithPrefix(costLimitRedisCache, "budget:", cfg.RedisWriteTimeout, cfg.RedisReadTimeout)
+	budgetCostStorage := redisStorage.NewStoreWithPrefix(costRedisStorage, "budget:", cfg.RedisWriteTimeout, cfg.RedisReadTimeout)
+
+	tokenLimitCache := redisStorage.NewCacheWithPrefix(rateLimitRedisCache, "tokens:", cfg.RedisWriteTimeout, cfg.RedisReadTimeout)
+	userTokenLimitCache := redisStorage.NewCacheWithPrefix(userRateLimitRedisCache, "tokens:", cfg.RedisWriteTimeout, cfg.RedisReadTimeout)
+
 	psCache := redisStorage.NewProviderSettingsCache(providerSettingsRedisCache, cfg.RedisWriteTimeout, cfg.RedisReadTimeout)
+	psHealthCache := redisStorage.NewProviderSettingHealthCache(providerSettingsRedisCache, cfg.RedisWriteTimeout, cfg.RedisReadTimeout)
 	keysCache := redisStorage.NewKeysCache(keysRedisCache, cfg.RedisWriteTimeout, cfg.RedisReadTimeout)
 
 	encryptor, err := encryptor.NewEncryptor(cfg.DecryptionEndpoint, cfg.EncryptionEndpoint, cfg.EnableEncrytion, cfg.EncryptionTimeout, cfg.Audience)
@@ -298,8 +376,17 @@ func main() {
 	rm := manager.NewRouteManager(store, store, rMemStore, psm)
 	pm := manager.NewPolicyManager(store, rMemStore)
 	um := manager.NewUserManager(store, store)
+	mam := manager.NewModelAliasManager(store, maMemStore)
+
+	dispatcher := alert.NewDispatcher(store, log, cfg.WebhookDispatchInterval)
+	dispatcher.Listen()
 
-	as, err := admin.NewAdminServer(log, *modePtr, m, krm, psm, cpm, rm, pm, um, cfg.AdminPass)
+	wm := manager.NewWebhookManager(store, dispatcher)
+	bm := manager.NewBudgetManager(store, budgetCostLimitCache, budgetCostStorage)
+	pam := manager.NewPrincipalManager(store)
+	alm := manager.NewAuditManager(store)
+
+	as, err := admin.NewAdminServer(log, *modePtr, m, krm, psm, cpm, rm, pm, um, mam, prm, wm, bm, pam, alm, cfg.AdminPass)
 	if err != nil {
 		log.Sugar().Fatalf("error creating admin http server: %v", err)
 	}
@@ -309,7 +396,7 @@ func main() {
 
 	as.Run()
 
-	ce := openai.NewCostEstimator(openai.OpenAiPerThousandTokenCost, tc)
+	ce := openai.NewCostEstimator(openai.OpenAiPerThousandTokenCost, tc, prMemStore)
 
 	atc, err := anthropic.NewTokenCounter()
 	if err != nil {
@@ -321,38 +408,77 @@ func main() {
 		log.Sugar().Fatalf("error creating vllm token counter: %v", err)
 	}
 
-	ace := anthropic.NewCostEstimator(atc)
-	aoe := azure.NewCostEstimator()
+	ace := anthropic.NewCostEstimator(atc, prMemStore)
+	aoe := azure.NewCostEstimator(prMemStore)
 	vllme := vllm.NewCostEstimator(vllmtc)
-	die := deepinfra.NewCostEstimator()
+	die := deepinfra.NewCostEstimator(prMemStore)
+	ge := gemini.NewCostEstimator(prMemStore)
+	be := bedrock.NewCostEstimator(prMemStore)
 
-	v := validator.NewValidator(costLimitCache, rateLimitCache, costStorage)
+	v := validator.NewValidator(costLimitCache, rateLimitCache, costStorage, bMemStore, budgetCostLimitCache, budgetCostStorage)
 	uv := validator.NewUserValidator(userCostLimitCache, userRateLimitCache, userCostStorage)
 
-	rec := recorder.NewRecorder(costStorage, userCostStorage, costLimitCache, userCostLimitCache, ce, store)
-	rlm := manager.NewRateLimitManager(rateLimitCache, userRateLimitCache)
-	a := auth.NewAuthenticator(psm, m, rm, store, encryptor)
+	rec := recorder.NewRecorder(costStorage, userCostStorage, budgetCostStorage, costLimitCache, userCostLimitCache, budgetCostLimitCache, bMemStore, ce, store)
+	rlm := manager.NewRateLimitManager(rateLimitCache, userRateLimitCache, tokenLimitCache, userTokenLimitCache, rateLimiter)
+	var ja *auth.JwtAuthenticator
+	var jv *jwt.Verifier
+	if len(cfg.JwtJwksFile) != 0 || len(cfg.JwtJwksUrl) != 0 {
+		jv, err = jwt.NewVerifier(cfg.JwtJwksFile, cfg.JwtJwksUrl, cfg.JwtIssuer, cfg.JwtAudience, cfg.JwtJwksRefreshInterval, log)
+		if err != nil {
+			log.Sugar().Fatalf("error creating jwt verifier: %v", err)
+		}
+
+		mappings, err := auth.ParseClaimMappings(cfg.JwtClaimMappings)
+		if err != nil {
+			log.Sugar().Fatalf("error parsing jwt claim mappings: %v", err)
+		}
+
+		jv.Listen()
+		ja = auth.NewJwtAuthenticator(jv, store, mappings, cfg.JwtUserIdClaim)
+	}
+
+	a := auth.NewAuthenticator(psm, m, rm, store, encryptor, psHealthCache, ja)
 
 	c := cache.NewCache(apiCache)
+	sc := cache.NewSemanticCache(redisStorage.NewSemanticCache(apiRedisCache, cfg.RedisWriteTimeout, cfg.RedisReadTimeout), c)
 
 	messageBus := message.NewMessageBus()
 	eventMessageChan := make(chan message.Message)
 	messageBus.Subscribe("event", eventMessageChan)
 
-	handler := message.NewHandler(rec, log, ace, ce, vllme, aoe, v, uv, m, um, rlm, accessCache, userAccessCache)
+	notifier := alert.NewNotifier(store, costLimitCache, costStorage, userCostLimitCache, userCostStorage, log)
+
+	keyRotator := manager.NewKeyRotator(m, alm, notifier, log, cfg.KeyRotationCheckInterval)
+	keyRotator.Listen()
+
+	handler := message.NewHandler(rec, log, ace, ce, vllme, aoe, v, uv, m, um, rlm, accessCache, userAccessCache, psHealthCache, notifier)
 
 	eventConsumer := message.NewConsumer(eventMessageChan, log, 4, handler.HandleEventWithRequestAndResponse)
 	eventConsumer.StartEventMessageConsumers()
 
-	detector, err := amazon.NewClient(cfg.AmazonRequestTimeout, cfg.AmazonConnectionTimeout, log, cfg.AmazonRegion)
-	if err != nil {
-		log.Sugar().Infof("error when connecting to amazon: %v", err)
+	var detector pii.Detector
+	if cfg.PiiDetector == "local" {
+		detector = local.NewDetector()
+	} else {
+		client, err := amazon.NewClient(cfg.AmazonRequestTimeout, cfg.AmazonConnectionTimeout, log, cfg.AmazonRegion)
+		if err != nil {
+			log.Sugar().Infof("error when connecting to amazon: %v", err)
+		}
+
+		detector = client
 	}
 
 	scanner := pii.NewScanner(detector)
-	cd := custompolicy.NewOpenAiDetector(cfg.CustomPolicyDetectionTimeout, cfg.OpenAiApiKey)
+	cd := custompolicy.NewDetector(cfg.CustomPolicyDetectionTimeout, cfg.OpenAiApiKey, psm, encryptor, c, messageBus, ce, ace, aoe, log)
 
-	ps, err := proxy.NewProxyServer(log, *modePtr, *privacyPtr, c, m, rm, a, psm, cpm, store, ce, ace, aoe, v, rec, messageBus, rlm, cfg.ProxyTimeout, accessCache, userAccessCache, pm, scanner, cd, die, um, cfg.RemoveUserAgent)
+	trustedProxies := []string{}
+	for _, tp := range strings.Split(cfg.TrustedProxies, ",") {
+		if trimmed := strings.TrimSpace(tp); len(trimmed) != 0 {
+			trustedProxies = append(trustedProxies, trimmed)
+		}
+	}
+
+	ps, err := proxy.NewProxyServer(log, *modePtr, *privacyPtr, c, sc, m, rm, a, psm, cpm, store, ce, ace, aoe, v, rec, messageBus, rlm, cfg.ProxyTimeout, accessCache, userAccessCache, pm, scanner, cd, die, ge, gemini.NewVertexTokenSource(), be, um, cfg.RemoveUserAgent, mam, trustedProxies)
 	if err != nil {
 		log.Sugar().Fatalf("error creating proxy http server: %v", err)
 	}
@@ -366,6 +492,15 @@ func main() {
 	eventConsumer.Stop()
 	cpMemStore.Stop()
 	rMemStore.Stop()
+	maMemStore.Stop()
+	prMemStore.Stop()
+	bMemStore.Stop()
+	dispatcher.Stop()
+	keyRotator.Stop()
+
+	if jv != nil {
+		jv.Stop()
+	}
 
 	log.Sugar().Infof("shutting down server...")
 
@@ -0,0 +1,308 @@
+package alert
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+	"github.com/bricks-cloud/bricksllm/internal/key"
+)
+
+type Format string
+
+const (
+	JsonFormat  Format = "json"
+	SlackFormat Format = "slack"
+)
+
+type DeliveryStatus string
+
+const (
+	DeliveryStatusPending   DeliveryStatus = "pending"
+	DeliveryStatusSucceeded DeliveryStatus = "succeeded"
+	DeliveryStatusFailed    DeliveryStatus = "failed"
+)
+
+const (
+	EventTypeBudgetThresholdCrossed = "budget.threshold_crossed"
+	EventTypeKeyRotated             = "key.rotated"
+	EventTypeTest                   = "webhook.test"
+)
+
+const (
+	SignatureHeader = "X-BricksLLM-Signature"
+	TimestampHeader = "X-BricksLLM-Timestamp"
+	DeliveryHeader  = "X-BricksLLM-Delivery-Id"
+)
+
+var DefaultThresholds = []float64{50, 80, 100}
+
+type Webhook struct {
+	Id        string `json:"id"`
+	CreatedAt int64  `json:"createdAt"`
+	UpdatedAt int64  `json:"updatedAt"`
+	Name      string `json:"name"`
+	Url       string `json:"url"`
+	// Secret is only returned when the webhook is created.
+	Secret string `json:"secret,omitempty"`
+	Format Format `json:"format"`
+	// Thresholds are percentages of a budget that trigger an alert once spend reaches them.
+	Thresholds []float64 `json:"thresholds"`
+	// Tags limit alerts to keys and users with at least one of the tags. Alerts are
+	// sent for every key and user when there are no tags.
+	Tags     []string `json:"tags"`
+	Disabled bool     `json:"disabled"`
+	Deleted  bool     `json:"-"`
+}
+
+type UpdateWebhook struct {
+	UpdatedAt  int64     `json:"updatedAt"`
+	Name       string    `json:"name"`
+	Url        string    `json:"url"`
+	Secret     string    `json:"secret"`
+	Format     Format    `json:"format"`
+	Thresholds []float64 `json:"thresholds"`
+	Tags       []string  `json:"tags"`
+	Disabled   *bool     `json:"disabled"`
+}
+
+func validateUrl(raw string) []string {
+	if len(raw) == 0 {
+		return []string{"url cannot be empty"}
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || len(parsed.Host) == 0 {
+		return []string{fmt.Sprintf("url %s is not a valid http url", raw)}
+	}
+
+	return nil
+}
+
+func validateFormat(f Format) []string {
+	if f != JsonFormat && f != SlackFormat {
+		return []string{fmt.Sprintf("format %s is not supported", f)}
+	}
+
+	return nil
+}
+
+func validateThresholds(thresholds []float64) []string {
+	msgs := []string{}
+	for idx, t := range thresholds {
+		if t <= 0 || t > 1000 {
+			msgs = append(msgs, fmt.Sprintf("threshold at index [%d] must be greater than 0 and at most 1000", idx))
+		}
+	}
+
+	return msgs
+}
+
+func (w *Webhook) Validate() error {
+	if w == nil {
+		return internal_errors.NewValidationError("webhook cannot be nil")
+	}
+
+	msgs := []string{}
+
+	if len(w.Name) == 0 {
+		msgs = append(msgs, "name cannot be empty")
+	}
+
+	msgs = append(msgs, validateUrl(w.Url)...)
+	msgs = append(msgs, validateFormat(w.Format)...)
+	msgs = append(msgs, validateThresholds(w.Thresholds)...)
+
+	if len(msgs) != 0 {
+		return internal_errors.NewValidationError("webhook is not valid: " + strings.Join(msgs, " ,"))
+	}
+
+	return nil
+}
+
+func (uw *UpdateWebhook) Validate() error {
+	if uw == nil {
+		return internal_errors.NewValidationError("webhook update cannot be nil")
+	}
+
+	msgs := []string{}
+
+	if len(uw.Url) != 0 {
+		msgs = append(msgs, validateUrl(uw.Url)...)
+	}
+
+	if len(uw.Format) != 0 {
+		msgs = append(msgs, validateFormat(uw.Format)...)
+	}
+
+	msgs = append(msgs, validateThresholds(uw.Thresholds)...)
+
+	if len(msgs) != 0 {
+		return internal_errors.NewValidationError("webhook is not valid: " + strings.Join(msgs, " ,"))
+	}
+
+	return nil
+}
+
+// Matches reports whether alerts for an entity with the tags should be sent to the webhook.
+func (w *Webhook) Matches(tags []string) bool {
+	if len(w.Tags) == 0 {
+		return true
+	}
+
+	for _, wt := range w.Tags {
+		for _, t := range tags {
+			if wt == t {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+type Delivery struct {
+	Id             string          `json:"id"`
+	CreatedAt      int64           `json:"createdAt"`
+	UpdatedAt      int64           `json:"updatedAt"`
+	WebhookId      string          `json:"webhookId"`
+	EventType      string          `json:"eventType"`
+	DedupeKey      string          `json:"-"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         DeliveryStatus  `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  int64           `json:"nextAttemptAt"`
+	LastStatusCode int             `json:"lastStatusCode"`
+	LastError      string          `json:"lastError"`
+}
+
+type DeliveryFilter struct {
+	WebhookId string
+	Status    DeliveryStatus
+	Offset    int
+	Limit     int
+}
+
+type BudgetAlert struct {
+	EntityType string       `json:"entityType"`
+	EntityId   string       `json:"entityId"`
+	Name       string       `json:"name,omitempty"`
+	Tags       []string     `json:"tags,omitempty"`
+	LimitType  string       `json:"limitType"`
+	Period     key.TimeUnit `json:"period,omitempty"`
+	LimitInUsd float64      `json:"limitInUsd"`
+	SpendInUsd float64      `json:"spendInUsd"`
+	Threshold  float64      `json:"threshold"`
+}
+
+// KeyRotation is sent when a key is rotated on its schedule. Secret is only sent to
+// webhooks with the json format and is removed from the stored payload once the
+// delivery succeeds or fails for good.
+type KeyRotation struct {
+	KeyId                string   `json:"keyId"`
+	Name                 string   `json:"name,omitempty"`
+	Tags                 []string `json:"tags,omitempty"`
+	Secret               string   `json:"secret,omitempty"`
+	RotatedAt            int64    `json:"rotatedAt"`
+	PreviousKeyExpiresAt int64    `json:"previousKeyExpiresAt"`
+	NextRotationAt       int64    `json:"nextRotationAt"`
+}
+
+type Event struct {
+	Id        string       `json:"id"`
+	Type      string       `json:"type"`
+	CreatedAt int64        `json:"createdAt"`
+	Data      *BudgetAlert `json:"data,omitempty"`
+	Key       *KeyRotation `json:"key,omitempty"`
+}
+
+var periodNames = map[key.TimeUnit]string{
+	key.MinuteTimeUnit: "minutely",
+	key.HourTimeUnit:   "hourly",
+	key.DayTimeUnit:    "daily",
+	key.MonthTimeUnit:  "monthly",
+}
+
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+func formatSlackText(e *Event) string {
+	if e.Key != nil {
+		name := e.Key.KeyId
+		if len(e.Key.Name) != 0 {
+			name = fmt.Sprintf("%s (`%s`)", e.Key.Name, e.Key.KeyId)
+		}
+
+		return fmt.Sprintf(":key: Key %s has been rotated. Its previous secret stops working at %s.", name, time.Unix(e.Key.PreviousKeyExpiresAt, 0).UTC().Format(time.RFC3339))
+	}
+
+	if e.Data == nil {
+		return "Test delivery from BricksLLM"
+	}
+
+	ba := e.Data
+	name := ba.EntityId
+	if len(ba.Name) != 0 {
+		name = fmt.Sprintf("%s (`%s`)", ba.Name, ba.EntityId)
+	}
+
+	budget := "total"
+	if ba.LimitType == "period" {
+		budget = periodNames[ba.Period]
+	}
+
+	return fmt.Sprintf(":warning: %s %s has spent $%.2f of its $%.2f %s budget and reached the %s%% threshold.", strings.ToUpper(ba.EntityType[:1])+ba.EntityType[1:], name, ba.SpendInUsd, ba.LimitInUsd, budget, strconv.FormatFloat(ba.Threshold, 'f', -1, 64))
+}
+
+// Body returns the request body sent to a webhook with the given format.
+func Body(f Format, payload []byte) ([]byte, error) {
+	if f != SlackFormat {
+		return payload, nil
+	}
+
+	e := &Event{}
+	err := json.Unmarshal(payload, e)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(&slackMessage{
+		Text: formatSlackText(e),
+	})
+}
+
+// RedactPayload removes the key secret from the payload of a delivery. The payload is
+// returned as is when it does not have a secret.
+func RedactPayload(payload []byte) []byte {
+	e := &Event{}
+	err := json.Unmarshal(payload, e)
+	if err != nil || e.Key == nil || len(e.Key.Secret) == 0 {
+		return payload
+	}
+
+	e.Key.Secret = ""
+	redacted, err := json.Marshal(e)
+	if err != nil {
+		return payload
+	}
+
+	return redacted
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of the timestamp and body joined by a period.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
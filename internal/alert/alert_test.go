@@ -0,0 +1,103 @@
+package alert
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhook_Validate(t *testing.T) {
+	cases := []struct {
+		name    string
+		webhook *Webhook
+		valid   bool
+	}{
+		{"nil", nil, false},
+		{"valid", &Webhook{Name: "ops", Url: "https://example.com/hook", Format: JsonFormat, Thresholds: []float64{50, 100}}, true},
+		{"slack", &Webhook{Name: "ops", Url: "https://hooks.slack.com/services/x", Format: SlackFormat}, true},
+		{"missing name", &Webhook{Url: "https://example.com/hook", Format: JsonFormat}, false},
+		{"missing url", &Webhook{Name: "ops", Format: JsonFormat}, false},
+		{"non http url", &Webhook{Name: "ops", Url: "ftp://example.com", Format: JsonFormat}, false},
+		{"unsupported format", &Webhook{Name: "ops", Url: "https://example.com/hook", Format: "xml"}, false},
+		{"zero threshold", &Webhook{Name: "ops", Url: "https://example.com/hook", Format: JsonFormat, Thresholds: []float64{0}}, false},
+		{"threshold too large", &Webhook{Name: "ops", Url: "https://example.com/hook", Format: JsonFormat, Thresholds: []float64{1001}}, false},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.valid, c.webhook.Validate() == nil, c.name)
+	}
+}
+
+func TestUpdateWebhook_Validate(t *testing.T) {
+	assert.NoError(t, (&UpdateWebhook{}).Validate())
+	assert.NoError(t, (&UpdateWebhook{Url: "http://example.com", Format: SlackFormat, Thresholds: []float64{120}}).Validate())
+	assert.Error(t, (&UpdateWebhook{Url: "example.com"}).Validate())
+	assert.Error(t, (&UpdateWebhook{Format: "xml"}).Validate())
+	assert.Error(t, (&UpdateWebhook{Thresholds: []float64{-5}}).Validate())
+}
+
+func TestWebhook_Matches(t *testing.T) {
+	assert.True(t, (&Webhook{}).Matches(nil))
+	assert.True(t, (&Webhook{Tags: []string{"a", "b"}}).Matches([]string{"c", "b"}))
+	assert.False(t, (&Webhook{Tags: []string{"a"}}).Matches([]string{"c"}))
+	assert.False(t, (&Webhook{Tags: []string{"a"}}).Matches(nil))
+}
+
+func TestSign(t *testing.T) {
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1700000000.{}"))
+
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), Sign("secret", 1700000000, []byte("{}")))
+	assert.NotEqual(t, Sign("secret", 1700000000, []byte("{}")), Sign("secret", 1700000001, []byte("{}")))
+}
+
+func TestBody(t *testing.T) {
+	payload, err := json.Marshal(&Event{
+		Type: EventTypeBudgetThresholdCrossed,
+		Data: &BudgetAlert{
+			EntityType: "key",
+			EntityId:   "key-1",
+			Name:       "prod",
+			LimitType:  "period",
+			Period:     key.MonthTimeUnit,
+			LimitInUsd: 100,
+			SpendInUsd: 80.5,
+			Threshold:  80,
+		},
+	})
+	require.NoError(t, err)
+
+	body, err := Body(JsonFormat, payload)
+	require.NoError(t, err)
+	assert.Equal(t, payload, body)
+
+	body, err = Body(SlackFormat, payload)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"text": ":warning: Key prod (`+"`key-1`"+`) has spent $80.50 of its $100.00 monthly budget and reached the 80% threshold."}`, string(body))
+
+	body, err = Body(SlackFormat, []byte(`{"type": "webhook.test"}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"text": "Test delivery from BricksLLM"}`, string(body))
+
+	_, err = Body(SlackFormat, []byte(`{`))
+	assert.Error(t, err)
+}
+
+func TestRedactPayload(t *testing.T) {
+	payload, err := json.Marshal(&Event{Type: EventTypeKeyRotated, Key: &KeyRotation{KeyId: "key-1", Secret: "new-secret"}})
+	require.NoError(t, err)
+
+	e := &Event{}
+	require.NoError(t, json.Unmarshal(RedactPayload(payload), e))
+	assert.Equal(t, "key-1", e.Key.KeyId)
+	assert.Empty(t, e.Key.Secret)
+
+	budgetPayload := []byte(`{"type": "budget.threshold_crossed", "data": {"entityId": "key-1"}}`)
+	assert.Equal(t, budgetPayload, RedactPayload(budgetPayload))
+}
@@ -0,0 +1,202 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+	"go.uber.org/zap"
+)
+
+type DispatcherStorage interface {
+	GetWebhook(id string) (*Webhook, error)
+	ClaimDueDeliveries(now, leaseUntil int64, limit int) ([]*Delivery, error)
+	UpdateDelivery(d *Delivery) error
+}
+
+type notFoundError interface {
+	Error() string
+	NotFound()
+}
+
+const (
+	MaxDeliveryAttempts = 10
+
+	deliveryLease     = 60 * time.Second
+	deliveryBatchSize = 50
+	deliveryTimeout   = 10 * time.Second
+	minRetryBackoff   = 10 * time.Second
+	maxRetryBackoff   = time.Hour
+)
+
+// Dispatcher sends pending deliveries and retries failed ones with an exponential
+// backoff. Deliveries are claimed with a lease so that a delivery interrupted by a
+// restart is picked up again once the lease expires.
+type Dispatcher struct {
+	storage  DispatcherStorage
+	client   *http.Client
+	log      *zap.Logger
+	interval time.Duration
+	done     chan bool
+}
+
+func NewDispatcher(s DispatcherStorage, log *zap.Logger, interval time.Duration) *Dispatcher {
+	return &Dispatcher{
+		storage: s,
+		client: &http.Client{
+			Timeout: deliveryTimeout,
+		},
+		log:      log,
+		interval: interval,
+		done:     make(chan bool),
+	}
+}
+
+func getRetryBackoff(attempts int) time.Duration {
+	backoff := minRetryBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= maxRetryBackoff {
+			return maxRetryBackoff
+		}
+	}
+
+	return backoff
+}
+
+func (d *Dispatcher) Listen() {
+	ticker := time.NewTicker(d.interval)
+	d.log.Info("webhook dispatcher started listening for deliveries")
+
+	go func() {
+		for {
+			select {
+			case <-d.done:
+				ticker.Stop()
+				d.log.Info("webhook dispatcher stopped")
+				return
+			case <-ticker.C:
+				d.dispatch()
+			}
+		}
+	}()
+}
+
+func (d *Dispatcher) Stop() {
+	d.log.Info("shutting down webhook dispatcher...")
+
+	d.done <- true
+}
+
+func (d *Dispatcher) dispatch() {
+	now := time.Now()
+	deliveries, err := d.storage.ClaimDueDeliveries(now.Unix(), now.Add(deliveryLease).Unix(), deliveryBatchSize)
+	if err != nil {
+		telemetry.Incr("bricksllm.alert.dispatcher.dispatch.claim_due_deliveries_error", nil, 1)
+		d.log.Debug("error when claiming webhook deliveries", zap.Error(err))
+		return
+	}
+
+	for _, delivery := range deliveries {
+		w, err := d.storage.GetWebhook(delivery.WebhookId)
+		if err != nil {
+			if _, ok := err.(notFoundError); !ok {
+				telemetry.Incr("bricksllm.alert.dispatcher.dispatch.get_webhook_error", nil, 1)
+				d.log.Debug("error when getting webhook", zap.Error(err))
+				continue
+			}
+		}
+
+		if w == nil || w.Disabled {
+			delivery.Status = DeliveryStatusFailed
+			delivery.LastError = "webhook is deleted or disabled"
+			delivery.UpdatedAt = time.Now().Unix()
+			d.updateDelivery(delivery)
+			continue
+		}
+
+		d.Deliver(w, delivery)
+	}
+}
+
+// Deliver sends a delivery to a webhook and records the outcome. A delivery that fails
+// is scheduled for a retry until it runs out of attempts.
+func (d *Dispatcher) Deliver(w *Webhook, delivery *Delivery) *Delivery {
+	delivery.Attempts += 1
+
+	code, err := d.send(w, delivery)
+	delivery.LastStatusCode = code
+	delivery.UpdatedAt = time.Now().Unix()
+
+	if err == nil {
+		telemetry.Incr("bricksllm.alert.dispatcher.deliver.success", nil, 1)
+		delivery.Status = DeliveryStatusSucceeded
+		delivery.LastError = ""
+	} else {
+		telemetry.Incr("bricksllm.alert.dispatcher.deliver.error", nil, 1)
+		delivery.LastError = err.Error()
+
+		if delivery.Attempts >= MaxDeliveryAttempts {
+			delivery.Status = DeliveryStatusFailed
+		} else {
+			delivery.Status = DeliveryStatusPending
+			delivery.NextAttemptAt = time.Now().Add(getRetryBackoff(delivery.Attempts)).Unix()
+		}
+	}
+
+	d.updateDelivery(delivery)
+
+	return delivery
+}
+
+func (d *Dispatcher) updateDelivery(delivery *Delivery) {
+	if delivery.Status != DeliveryStatusPending {
+		delivery.Payload = RedactPayload(delivery.Payload)
+	}
+
+	err := d.storage.UpdateDelivery(delivery)
+	if err != nil {
+		telemetry.Incr("bricksllm.alert.dispatcher.update_delivery.update_delivery_error", nil, 1)
+		d.log.Debug("error when updating webhook delivery", zap.Error(err))
+	}
+}
+
+func (d *Dispatcher) send(w *Webhook, delivery *Delivery) (int, error) {
+	body, err := Body(w.Format, delivery.Payload)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	ts := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(DeliveryHeader, delivery.Id)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(ts, 10))
+	req.Header.Set(SignatureHeader, Sign(w.Secret, ts, body))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("webhook responded with status code %d", res.StatusCode)
+	}
+
+	return res.StatusCode, nil
+}
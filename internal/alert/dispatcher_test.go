@@ -0,0 +1,114 @@
+package alert
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeDispatcherStorage struct {
+	webhooks   map[string]*Webhook
+	deliveries []*Delivery
+	updated    []*Delivery
+}
+
+func (s *fakeDispatcherStorage) GetWebhook(id string) (*Webhook, error) {
+	w, ok := s.webhooks[id]
+	if !ok {
+		return nil, errors.New("storage is down")
+	}
+
+	return w, nil
+}
+
+func (s *fakeDispatcherStorage) ClaimDueDeliveries(now, leaseUntil int64, limit int) ([]*Delivery, error) {
+	return s.deliveries, nil
+}
+
+func (s *fakeDispatcherStorage) UpdateDelivery(d *Delivery) error {
+	s.updated = append(s.updated, d)
+	return nil
+}
+
+func TestDispatcher_Deliver(t *testing.T) {
+	payload := []byte(`{"type": "key.rotated", "key": {"keyId": "key-1", "secret": "new-secret"}}`)
+
+	var received []byte
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+		headers = r.Header
+	}))
+	defer server.Close()
+
+	s := &fakeDispatcherStorage{}
+	d := NewDispatcher(s, zap.NewNop(), time.Second)
+
+	delivery := d.Deliver(&Webhook{Url: server.URL, Secret: "secret", Format: JsonFormat}, &Delivery{Id: "d-1", Payload: payload, Status: DeliveryStatusPending})
+	assert.Equal(t, DeliveryStatusSucceeded, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, http.StatusOK, delivery.LastStatusCode)
+
+	assert.Equal(t, payload, received)
+	assert.Equal(t, "d-1", headers.Get(DeliveryHeader))
+
+	ts, err := strconv.ParseInt(headers.Get(TimestampHeader), 10, 64)
+	require.NoError(t, err)
+	assert.Equal(t, Sign("secret", ts, received), headers.Get(SignatureHeader))
+
+	require.Len(t, s.updated, 1)
+	assert.NotContains(t, string(s.updated[0].Payload), "new-secret")
+}
+
+func TestDispatcher_DeliverRetries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	payload := []byte(`{"type": "key.rotated", "key": {"keyId": "key-1", "secret": "new-secret"}}`)
+	w := &Webhook{Url: server.URL, Format: JsonFormat}
+	d := NewDispatcher(&fakeDispatcherStorage{}, zap.NewNop(), time.Second)
+
+	delivery := d.Deliver(w, &Delivery{Payload: payload})
+	assert.Equal(t, DeliveryStatusPending, delivery.Status)
+	assert.Equal(t, http.StatusServiceUnavailable, delivery.LastStatusCode)
+	assert.NotEmpty(t, delivery.LastError)
+	assert.GreaterOrEqual(t, delivery.NextAttemptAt, time.Now().Add(minRetryBackoff).Unix()-1)
+	assert.Contains(t, string(delivery.Payload), "new-secret")
+
+	delivery = d.Deliver(w, &Delivery{Payload: payload, Attempts: MaxDeliveryAttempts - 1})
+	assert.Equal(t, DeliveryStatusFailed, delivery.Status)
+	assert.NotContains(t, string(delivery.Payload), "new-secret")
+}
+
+func TestGetRetryBackoff(t *testing.T) {
+	assert.Equal(t, minRetryBackoff, getRetryBackoff(1))
+	assert.Equal(t, 2*minRetryBackoff, getRetryBackoff(2))
+	assert.Equal(t, 8*minRetryBackoff, getRetryBackoff(4))
+	assert.Equal(t, maxRetryBackoff, getRetryBackoff(MaxDeliveryAttempts))
+}
+
+func TestDispatcher_DispatchToDisabledWebhook(t *testing.T) {
+	s := &fakeDispatcherStorage{
+		webhooks: map[string]*Webhook{"disabled": {Id: "disabled", Disabled: true}},
+		deliveries: []*Delivery{
+			{Id: "d-1", WebhookId: "disabled", Status: DeliveryStatusPending},
+			{Id: "d-2", WebhookId: "unknown", Status: DeliveryStatusPending},
+		},
+	}
+
+	NewDispatcher(s, zap.NewNop(), time.Second).dispatch()
+
+	require.Len(t, s.updated, 1)
+	assert.Equal(t, "d-1", s.updated[0].Id)
+	assert.Equal(t, DeliveryStatusFailed, s.updated[0].Status)
+}
@@ -0,0 +1,369 @@
+package alert
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+	"github.com/bricks-cloud/bricksllm/internal/user"
+	"github.com/bricks-cloud/bricksllm/internal/util"
+	"go.uber.org/zap"
+)
+
+type NotifierStorage interface {
+	GetWebhooks() ([]*Webhook, error)
+	CreateDelivery(d *Delivery) (bool, error)
+}
+
+type costLimitCache interface {
+	GetCounter(keyId string, rateLimitUnit key.TimeUnit) (int64, error)
+}
+
+type costLimitStorage interface {
+	GetCounter(keyId string) (int64, error)
+}
+
+const (
+	webhooksRefreshInterval = 10 * time.Second
+	maxSentDedupeKeys       = 10000
+)
+
+// Notifier queues a delivery for every webhook when spend of a key or a user crosses one
+// of the webhook's thresholds. Each threshold is alerted once per budget period.
+type Notifier struct {
+	storage NotifierStorage
+	kclc    costLimitCache
+	kcls    costLimitStorage
+	uclc    costLimitCache
+	ucls    costLimitStorage
+	log     *zap.Logger
+
+	lock            sync.Mutex
+	webhooks        []*Webhook
+	webhooksFetched time.Time
+	sent            map[string]bool
+}
+
+func NewNotifier(s NotifierStorage, kclc costLimitCache, kcls costLimitStorage, uclc costLimitCache, ucls costLimitStorage, log *zap.Logger) *Notifier {
+	return &Notifier{
+		storage: s,
+		kclc:    kclc,
+		kcls:    kcls,
+		uclc:    uclc,
+		ucls:    ucls,
+		log:     log,
+		sent:    map[string]bool{},
+	}
+}
+
+type budget struct {
+	entityType string
+	entityId   string
+	name       string
+	tags       []string
+	limitType  string
+	period     key.TimeUnit
+	limit      float64
+	periodKey  string
+}
+
+func getPeriodStart(unit key.TimeUnit, now time.Time) (int64, error) {
+	now = now.UTC()
+	switch unit {
+	case key.MinuteTimeUnit:
+		return now.Truncate(time.Minute).Unix(), nil
+	case key.HourTimeUnit:
+		return now.Truncate(time.Hour).Unix(), nil
+	case key.DayTimeUnit:
+		return now.Truncate(24 * time.Hour).Unix(), nil
+	case key.MonthTimeUnit:
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).Unix(), nil
+	}
+
+	return 0, fmt.Errorf("cannot recognize cost limit time unit %v", unit)
+}
+
+func (n *Notifier) NotifyKeySpend(k *key.ResponseKey) {
+	if k == nil {
+		return
+	}
+
+	n.notify(k.KeyId, n.kclc, n.kcls, budget{
+		entityType: "key",
+		entityId:   k.KeyId,
+		name:       k.Name,
+		tags:       k.Tags,
+		limit:      k.CostLimitInUsd,
+	}, k.CostLimitInUsdOverTime, k.CostLimitInUsdUnit)
+}
+
+func (n *Notifier) NotifyUserSpend(u *user.User) {
+	if u == nil {
+		return
+	}
+
+	n.notify(u.Id, n.uclc, n.ucls, budget{
+		entityType: "user",
+		entityId:   u.Id,
+		name:       u.Name,
+		tags:       u.Tags,
+		limit:      u.CostLimitInUsd,
+	}, u.CostLimitInUsdOverTime, u.CostLimitInUsdUnit)
+}
+
+func (n *Notifier) notify(counterId string, clc costLimitCache, cls costLimitStorage, total budget, limitOverTime float64, unit key.TimeUnit) {
+	if total.limit == 0 && limitOverTime == 0 {
+		return
+	}
+
+	webhooks := n.getWebhooks()
+	if len(webhooks) == 0 {
+		return
+	}
+
+	if total.limit != 0 {
+		micros, err := cls.GetCounter(counterId)
+		if err != nil {
+			telemetry.Incr("bricksllm.alert.notifier.notify.get_total_spend_error", nil, 1)
+			n.log.Debug("error when getting total spend", zap.Error(err))
+		} else {
+			total.limitType = "total"
+			total.periodKey = strconv.FormatFloat(total.limit, 'f', -1, 64)
+			n.evaluate(webhooks, total, float64(micros)/1000000)
+		}
+	}
+
+	if limitOverTime != 0 && len(unit) != 0 {
+		start, err := getPeriodStart(unit, time.Now())
+		if err != nil {
+			n.log.Debug("error when getting budget period", zap.Error(err))
+			return
+		}
+
+		micros, err := clc.GetCounter(counterId, unit)
+		if err != nil {
+			telemetry.Incr("bricksllm.alert.notifier.notify.get_period_spend_error", nil, 1)
+			n.log.Debug("error when getting spend over time", zap.Error(err))
+			return
+		}
+
+		period := total
+		period.limitType = "period"
+		period.period = unit
+		period.limit = limitOverTime
+		period.periodKey = fmt.Sprintf("%s:%d", unit, start)
+		n.evaluate(webhooks, period, float64(micros)/1000000)
+	}
+}
+
+func (n *Notifier) evaluate(webhooks []*Webhook, b budget, spend float64) {
+	for _, w := range webhooks {
+		if !w.Matches(b.tags) {
+			continue
+		}
+
+		for _, t := range w.Thresholds {
+			if spend < b.limit*t/100 {
+				continue
+			}
+
+			dedupeKey := fmt.Sprintf("%s:%s:%s:%s:%s", b.entityType, b.entityId, b.limitType, b.periodKey, strconv.FormatFloat(t, 'f', -1, 64))
+			if n.wasSent(w.Id + ":" + dedupeKey) {
+				continue
+			}
+
+			err := n.createDelivery(w, dedupeKey, &Event{
+				Type: EventTypeBudgetThresholdCrossed,
+				Data: &BudgetAlert{
+					EntityType: b.entityType,
+					EntityId:   b.entityId,
+					Name:       b.name,
+					Tags:       b.tags,
+					LimitType:  b.limitType,
+					Period:     b.period,
+					LimitInUsd: b.limit,
+					SpendInUsd: spend,
+					Threshold:  t,
+				},
+			})
+			if err != nil {
+				telemetry.Incr("bricksllm.alert.notifier.evaluate.create_delivery_error", nil, 1)
+				n.log.Debug("error when creating webhook delivery", zap.Error(err))
+				continue
+			}
+
+			n.markSent(w.Id + ":" + dedupeKey)
+		}
+	}
+}
+
+// CanDeliverKeyRotation reports whether a webhook can receive the new secret of a key
+// with the given tags. Only JSON webhooks carry the secret.
+func (n *Notifier) CanDeliverKeyRotation(tags []string) bool {
+	for _, w := range n.getWebhooks() {
+		if w.Format == JsonFormat && w.Matches(tags) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NotifyKeyRotation queues a delivery for every webhook that matches the tags of a key
+// rotated on its schedule. It fails when the new secret cannot be queued for any JSON
+// webhook, in which case no other webhook is notified either.
+func (n *Notifier) NotifyKeyRotation(rk *key.RotatedKey) error {
+	if rk == nil {
+		return nil
+	}
+
+	dedupeKey := fmt.Sprintf("key:%s:rotated:%d", rk.KeyId, rk.LastRotatedAt)
+	newEvent := func(withSecret bool) *Event {
+		kr := &KeyRotation{
+			KeyId:                rk.KeyId,
+			Name:                 rk.Name,
+			Tags:                 rk.Tags,
+			RotatedAt:            rk.LastRotatedAt,
+			PreviousKeyExpiresAt: rk.PreviousKeyExpiresAt,
+			NextRotationAt:       rk.NextRotationAt,
+		}
+
+		if withSecret {
+			kr.Secret = rk.Secret
+		}
+
+		return &Event{
+			Type: EventTypeKeyRotated,
+			Key:  kr,
+		}
+	}
+
+	webhooks := []*Webhook{}
+	for _, w := range n.getWebhooks() {
+		if w.Matches(rk.Tags) {
+			webhooks = append(webhooks, w)
+		}
+	}
+
+	delivered := false
+	var lastErr error
+	for _, w := range webhooks {
+		if w.Format != JsonFormat {
+			continue
+		}
+
+		err := n.createDelivery(w, dedupeKey, newEvent(true))
+		if err != nil {
+			telemetry.Incr("bricksllm.alert.notifier.notify_key_rotation.create_delivery_error", nil, 1)
+			n.log.Debug("error when creating webhook delivery", zap.Error(err))
+			lastErr = err
+			continue
+		}
+
+		delivered = true
+	}
+
+	if !delivered {
+		if lastErr != nil {
+			return lastErr
+		}
+
+		return fmt.Errorf("no json webhook matches the tags of key %s", rk.KeyId)
+	}
+
+	for _, w := range webhooks {
+		if w.Format == JsonFormat {
+			continue
+		}
+
+		err := n.createDelivery(w, dedupeKey, newEvent(false))
+		if err != nil {
+			telemetry.Incr("bricksllm.alert.notifier.notify_key_rotation.create_delivery_error", nil, 1)
+			n.log.Debug("error when creating webhook delivery", zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+func (n *Notifier) createDelivery(w *Webhook, dedupeKey string, e *Event) error {
+	now := time.Now().Unix()
+	e.Id = util.NewUuid()
+	e.CreatedAt = now
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	created, err := n.storage.CreateDelivery(&Delivery{
+		Id:            e.Id,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		WebhookId:     w.Id,
+		EventType:     e.Type,
+		DedupeKey:     dedupeKey,
+		Payload:       payload,
+		Status:        DeliveryStatusPending,
+		NextAttemptAt: now,
+	})
+	if err != nil {
+		return err
+	}
+
+	if created {
+		telemetry.Incr("bricksllm.alert.notifier.create_delivery.created", nil, 1)
+	}
+
+	return nil
+}
+
+func (n *Notifier) getWebhooks() []*Webhook {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if time.Since(n.webhooksFetched) < webhooksRefreshInterval {
+		return n.webhooks
+	}
+
+	webhooks, err := n.storage.GetWebhooks()
+	if err != nil {
+		telemetry.Incr("bricksllm.alert.notifier.get_webhooks.get_webhooks_error", nil, 1)
+		n.log.Debug("error when getting webhooks", zap.Error(err))
+		return n.webhooks
+	}
+
+	enabled := []*Webhook{}
+	for _, w := range webhooks {
+		if !w.Disabled {
+			enabled = append(enabled, w)
+		}
+	}
+
+	n.webhooks = enabled
+	n.webhooksFetched = time.Now()
+
+	return n.webhooks
+}
+
+func (n *Notifier) wasSent(dedupeKey string) bool {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	return n.sent[dedupeKey]
+}
+
+func (n *Notifier) markSent(dedupeKey string) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if len(n.sent) >= maxSentDedupeKeys {
+		n.sent = map[string]bool{}
+	}
+
+	n.sent[dedupeKey] = true
+}
@@ -0,0 +1,170 @@
+package alert
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeNotifierStorage struct {
+	webhooks   []*Webhook
+	deliveries []*Delivery
+	err        error
+}
+
+func (s *fakeNotifierStorage) GetWebhooks() ([]*Webhook, error) {
+	return s.webhooks, nil
+}
+
+func (s *fakeNotifierStorage) CreateDelivery(d *Delivery) (bool, error) {
+	if s.err != nil {
+		return false, s.err
+	}
+
+	s.deliveries = append(s.deliveries, d)
+	return true, nil
+}
+
+func newRotatedKey() *key.RotatedKey {
+	return &key.RotatedKey{
+		ResponseKey: key.ResponseKey{KeyId: "key-1", Tags: []string{"team-a"}, LastRotatedAt: 100},
+		Secret:      "new-secret",
+	}
+}
+
+func TestNotifyKeyRotation(t *testing.T) {
+	s := &fakeNotifierStorage{
+		webhooks: []*Webhook{
+			{Id: "json", Format: JsonFormat, Tags: []string{"team-a"}},
+			{Id: "slack", Format: SlackFormat},
+			{Id: "other-team", Format: JsonFormat, Tags: []string{"team-b"}},
+			{Id: "disabled", Format: JsonFormat, Disabled: true},
+		},
+	}
+
+	n := NewNotifier(s, nil, nil, nil, nil, zap.NewNop())
+	assert.True(t, n.CanDeliverKeyRotation([]string{"team-a"}))
+
+	require.NoError(t, n.NotifyKeyRotation(newRotatedKey()))
+	require.Len(t, s.deliveries, 2)
+
+	secrets := map[string]string{}
+	for _, d := range s.deliveries {
+		e := &Event{}
+		require.NoError(t, json.Unmarshal(d.Payload, e))
+		secrets[d.WebhookId] = e.Key.Secret
+	}
+
+	assert.Equal(t, map[string]string{"json": "new-secret", "slack": ""}, secrets)
+}
+
+func TestNotifyKeyRotation_Undelivered(t *testing.T) {
+	s := &fakeNotifierStorage{
+		webhooks: []*Webhook{
+			{Id: "slack", Format: SlackFormat},
+			{Id: "other-team", Format: JsonFormat, Tags: []string{"team-b"}},
+		},
+	}
+
+	n := NewNotifier(s, nil, nil, nil, nil, zap.NewNop())
+	assert.False(t, n.CanDeliverKeyRotation([]string{"team-a"}))
+
+	assert.Error(t, n.NotifyKeyRotation(newRotatedKey()))
+	assert.Empty(t, s.deliveries, "webhooks without the secret must not be told about a rotation that failed")
+
+	s = &fakeNotifierStorage{
+		webhooks: []*Webhook{{Id: "json", Format: JsonFormat}},
+		err:      errors.New("storage is down"),
+	}
+
+	n = NewNotifier(s, nil, nil, nil, nil, zap.NewNop())
+	assert.Error(t, n.NotifyKeyRotation(newRotatedKey()))
+}
+
+type fakeCostLimitCache struct {
+	micros int64
+}
+
+func (c *fakeCostLimitCache) GetCounter(keyId string, rateLimitUnit key.TimeUnit) (int64, error) {
+	return c.micros, nil
+}
+
+type fakeCostLimitStorage struct {
+	micros int64
+}
+
+func (s *fakeCostLimitStorage) GetCounter(keyId string) (int64, error) {
+	return s.micros, nil
+}
+
+func TestNotifyKeySpend(t *testing.T) {
+	s := &fakeNotifierStorage{
+		webhooks: []*Webhook{
+			{Id: "all", Format: JsonFormat, Thresholds: []float64{50, 80, 100}},
+			{Id: "other-team", Format: JsonFormat, Thresholds: []float64{50}, Tags: []string{"team-b"}},
+		},
+	}
+
+	clc := &fakeCostLimitCache{micros: 6000000}
+	cls := &fakeCostLimitStorage{micros: 85000000}
+	n := NewNotifier(s, clc, cls, nil, nil, zap.NewNop())
+
+	k := &key.ResponseKey{
+		KeyId:                  "key-1",
+		Tags:                   []string{"team-a"},
+		CostLimitInUsd:         100,
+		CostLimitInUsdOverTime: 10,
+		CostLimitInUsdUnit:     key.DayTimeUnit,
+	}
+
+	n.NotifyKeySpend(k)
+
+	crossed := map[string][]float64{}
+	for _, d := range s.deliveries {
+		assert.Equal(t, "all", d.WebhookId)
+		assert.Equal(t, EventTypeBudgetThresholdCrossed, d.EventType)
+
+		e := &Event{}
+		require.NoError(t, json.Unmarshal(d.Payload, e))
+		crossed[e.Data.LimitType] = append(crossed[e.Data.LimitType], e.Data.Threshold)
+	}
+
+	assert.Equal(t, map[string][]float64{"total": {50, 80}, "period": {50}}, crossed)
+
+	n.NotifyKeySpend(k)
+	assert.Len(t, s.deliveries, 3, "thresholds are alerted once per budget period")
+
+	cls.micros = 100000000
+	n.NotifyKeySpend(k)
+	require.Len(t, s.deliveries, 4)
+	assert.Contains(t, s.deliveries[3].DedupeKey, "key:key-1:total:100:100")
+
+	n.NotifyKeySpend(&key.ResponseKey{KeyId: "key-2"})
+	assert.Len(t, s.deliveries, 4)
+}
+
+func TestGetPeriodStart(t *testing.T) {
+	now := time.Date(2024, 5, 17, 13, 45, 30, 0, time.UTC)
+
+	cases := map[key.TimeUnit]time.Time{
+		key.MinuteTimeUnit: time.Date(2024, 5, 17, 13, 45, 0, 0, time.UTC),
+		key.HourTimeUnit:   time.Date(2024, 5, 17, 13, 0, 0, 0, time.UTC),
+		key.DayTimeUnit:    time.Date(2024, 5, 17, 0, 0, 0, 0, time.UTC),
+		key.MonthTimeUnit:  time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	for unit, start := range cases {
+		got, err := getPeriodStart(unit, now)
+		require.NoError(t, err)
+		assert.Equal(t, start.Unix(), got, string(unit))
+	}
+
+	_, err := getPeriodStart("y", now)
+	assert.Error(t, err)
+}
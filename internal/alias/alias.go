@@ -0,0 +1,136 @@
+package alias
+
+import (
+	"fmt"
+	"strings"
+
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+)
+
+var supportedProviders = map[string]bool{
+	"openai":    true,
+	"azure":     true,
+	"anthropic": true,
+	"bedrock":   true,
+	"vllm":      true,
+	"deepinfra": true,
+	"gemini":    true,
+}
+
+// Override replaces the provider and model an alias resolves to for a key.
+type Override struct {
+	KeyId    string `json:"keyId"`
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+}
+
+type ModelAlias struct {
+	Id        string      `json:"id"`
+	CreatedAt int64       `json:"createdAt"`
+	UpdatedAt int64       `json:"updatedAt"`
+	Name      string      `json:"name"`
+	Provider  string      `json:"provider"`
+	Model     string      `json:"model"`
+	Overrides []*Override `json:"overrides"`
+	Deleted   bool        `json:"-"`
+}
+
+type UpdateModelAlias struct {
+	UpdatedAt int64       `json:"updatedAt"`
+	Provider  string      `json:"provider"`
+	Model     string      `json:"model"`
+	Overrides []*Override `json:"overrides"`
+}
+
+func validateTarget(provider, model string) []string {
+	msgs := []string{}
+
+	if !supportedProviders[provider] {
+		msgs = append(msgs, fmt.Sprintf("provider %s is not supported", provider))
+	}
+
+	if len(model) == 0 {
+		msgs = append(msgs, "model cannot be empty")
+	}
+
+	return msgs
+}
+
+func validateOverrides(overrides []*Override) []string {
+	msgs := []string{}
+	keyIds := map[string]bool{}
+
+	for idx, o := range overrides {
+		if o == nil {
+			msgs = append(msgs, fmt.Sprintf("override at index [%d] cannot be nil", idx))
+			continue
+		}
+
+		if len(o.KeyId) == 0 {
+			msgs = append(msgs, fmt.Sprintf("override at index [%d] must have a keyId", idx))
+		}
+
+		if keyIds[o.KeyId] {
+			msgs = append(msgs, fmt.Sprintf("override at index [%d] duplicates keyId %s", idx, o.KeyId))
+		}
+		keyIds[o.KeyId] = true
+
+		for _, msg := range validateTarget(o.Provider, o.Model) {
+			msgs = append(msgs, fmt.Sprintf("override at index [%d]: %s", idx, msg))
+		}
+	}
+
+	return msgs
+}
+
+func (ma *ModelAlias) Validate() error {
+	if ma == nil {
+		return internal_errors.NewValidationError("model alias cannot be nil")
+	}
+
+	msgs := []string{}
+
+	if len(ma.Name) == 0 {
+		msgs = append(msgs, "name cannot be empty")
+	}
+
+	msgs = append(msgs, validateTarget(ma.Provider, ma.Model)...)
+	msgs = append(msgs, validateOverrides(ma.Overrides)...)
+
+	if len(msgs) != 0 {
+		return internal_errors.NewValidationError("model alias is not valid: " + strings.Join(msgs, " ,"))
+	}
+
+	return nil
+}
+
+func (uma *UpdateModelAlias) Validate() error {
+	if uma == nil {
+		return internal_errors.NewValidationError("model alias update cannot be nil")
+	}
+
+	msgs := []string{}
+
+	if len(uma.Provider) != 0 && !supportedProviders[uma.Provider] {
+		msgs = append(msgs, fmt.Sprintf("provider %s is not supported", uma.Provider))
+	}
+
+	msgs = append(msgs, validateOverrides(uma.Overrides)...)
+
+	if len(msgs) != 0 {
+		return internal_errors.NewValidationError("model alias is not valid: " + strings.Join(msgs, " ,"))
+	}
+
+	return nil
+}
+
+// Resolve returns the provider and model the alias resolves to for a key.
+func (ma *ModelAlias) Resolve(keyId string) (string, string) {
+	for _, o := range ma.Overrides {
+		if o != nil && o.KeyId == keyId {
+			return o.Provider, o.Model
+		}
+	}
+
+	return ma.Provider, ma.Model
+}
@@ -0,0 +1,58 @@
+package alias
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModelAlias_Validate(t *testing.T) {
+	cases := []struct {
+		name  string
+		ma    *ModelAlias
+		valid bool
+	}{
+		{"nil", nil, false},
+		{"valid", &ModelAlias{Name: "fast", Provider: "anthropic", Model: "claude-3-haiku-20240307"}, true},
+		{"gemini", &ModelAlias{Name: "flash", Provider: "gemini", Model: "gemini-1.5-flash"}, true},
+		{"missing name", &ModelAlias{Provider: "openai", Model: "gpt-4o"}, false},
+		{"unsupported provider", &ModelAlias{Name: "fast", Provider: "cohere", Model: "command"}, false},
+		{"missing model", &ModelAlias{Name: "fast", Provider: "openai"}, false},
+		{"override without key", &ModelAlias{Name: "fast", Provider: "openai", Model: "gpt-4o", Overrides: []*Override{{Provider: "openai", Model: "gpt-4o-mini"}}}, false},
+		{"duplicated override", &ModelAlias{Name: "fast", Provider: "openai", Model: "gpt-4o", Overrides: []*Override{
+			{KeyId: "k", Provider: "openai", Model: "gpt-4o-mini"},
+			{KeyId: "k", Provider: "azure", Model: "deployment"},
+		}}, false},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.valid, c.ma.Validate() == nil, c.name)
+	}
+}
+
+func TestUpdateModelAlias_Validate(t *testing.T) {
+	assert.NoError(t, (&UpdateModelAlias{}).Validate())
+	assert.NoError(t, (&UpdateModelAlias{Provider: "vllm", Model: "llama"}).Validate())
+	assert.Error(t, (&UpdateModelAlias{Provider: "cohere"}).Validate())
+	assert.Error(t, (&UpdateModelAlias{Overrides: []*Override{nil}}).Validate())
+}
+
+func TestModelAlias_Resolve(t *testing.T) {
+	ma := &ModelAlias{
+		Name:     "fast",
+		Provider: "openai",
+		Model:    "gpt-4o-mini",
+		Overrides: []*Override{
+			nil,
+			{KeyId: "k", Provider: "anthropic", Model: "claude-3-haiku-20240307"},
+		},
+	}
+
+	provider, model := ma.Resolve("k")
+	assert.Equal(t, "anthropic", provider)
+	assert.Equal(t, "claude-3-haiku-20240307", model)
+
+	provider, model = ma.Resolve("other")
+	assert.Equal(t, "openai", provider)
+	assert.Equal(t, "gpt-4o-mini", model)
+}
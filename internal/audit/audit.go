@@ -0,0 +1,321 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+	ActionRotate Action = "rotate"
+)
+
+// Status tells whether the change of an entry has been applied. A change is recorded as
+// pending before it is applied and its outcome is recorded in a second entry that refers
+// to the pending one, so a change that is applied always has an entry even when its
+// outcome cannot be recorded. Entries without a status are committed changes.
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusCommitted Status = "committed"
+	StatusAborted   Status = "aborted"
+)
+
+const MaskedValue = "******"
+
+// secretFields are JSON fields whose values are never written to the audit log.
+var secretFields = map[string]bool{
+	"key":                true,
+	"previouskey":        true,
+	"apikey":             true,
+	"api_key":            true,
+	"secret":             true,
+	"token":              true,
+	"password":           true,
+	"authorization":      true,
+	"awsaccesskeyid":     true,
+	"awssecretaccesskey": true,
+}
+
+// secretObjects are JSON fields whose nested values are all masked. Provider settings
+// keep credentials under arbitrary names in the setting object.
+var secretObjects = map[string]bool{
+	"setting": true,
+}
+
+type Change struct {
+	Field  string          `json:"field"`
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+}
+
+// Entry is a record of a change made through the admin API. Entries are chained by
+// including the hash of the previous entry in the hash of every entry, so an edited,
+// removed or reordered entry breaks the chain from that point on.
+type Entry struct {
+	Sequence   int64           `json:"sequence"`
+	Id         string          `json:"id"`
+	CreatedAt  int64           `json:"createdAt"`
+	ActorId    string          `json:"actorId"`
+	ActorName  string          `json:"actorName"`
+	ActorRole  string          `json:"actorRole"`
+	Action     Action          `json:"action"`
+	EntityType string          `json:"entityType"`
+	EntityId   string          `json:"entityId"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	Changes    []*Change       `json:"changes"`
+	Status     Status          `json:"status,omitempty"`
+	IntentId   string          `json:"intentId,omitempty"`
+	PrevHash   string          `json:"prevHash"`
+	Hash       string          `json:"hash"`
+}
+
+type Filter struct {
+	ActorId    string
+	EntityType string
+	EntityId   string
+	Action     Action
+	Start      int64
+	End        int64
+	Offset     int
+	Limit      int
+}
+
+type Verification struct {
+	Valid            bool   `json:"valid"`
+	EntriesChecked   int64  `json:"entriesChecked"`
+	LastSequence     int64  `json:"lastSequence"`
+	LastHash         string `json:"lastHash"`
+	BrokenAtSequence int64  `json:"brokenAtSequence,omitempty"`
+	Reason           string `json:"reason,omitempty"`
+	// UnresolvedSequences are pending entries without an outcome. Their changes may
+	// have been applied.
+	UnresolvedSequences []int64 `json:"unresolvedSequences,omitempty"`
+}
+
+// Snapshot converts an entity into its decoded JSON representation.
+func Snapshot(v any) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed any
+	err = json.Unmarshal(data, &parsed)
+	if err != nil {
+		return nil, err
+	}
+
+	return parsed, nil
+}
+
+func isSecretField(name string) bool {
+	lowered := strings.ToLower(name)
+	return secretFields[lowered] || strings.Contains(lowered, "secret") || strings.Contains(lowered, "password")
+}
+
+func isSecretObject(name string) bool {
+	return isSecretField(name) || secretObjects[strings.ToLower(name)]
+}
+
+func mask(v any, masked bool) any {
+	switch value := v.(type) {
+	case map[string]any:
+		copied := make(map[string]any, len(value))
+		for field, nested := range value {
+			copied[field] = mask(nested, masked || isSecretObject(field))
+		}
+
+		return copied
+	case []any:
+		copied := make([]any, 0, len(value))
+		for _, nested := range value {
+			copied = append(copied, mask(nested, masked))
+		}
+
+		return copied
+	}
+
+	if masked && v != nil {
+		return MaskedValue
+	}
+
+	return v
+}
+
+// Mask returns a copy of a snapshot with secret values replaced by MaskedValue.
+func Mask(v any) any {
+	return mask(v, false)
+}
+
+func marshal(v any) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return json.RawMessage("null")
+	}
+
+	return data
+}
+
+// Diff compares the top level fields of two snapshots. A nil snapshot has no fields.
+// Changes are computed before masking so that a rotated secret shows up as a change
+// with masked values.
+func Diff(before, after any) []*Change {
+	bm, bok := before.(map[string]any)
+	if before == nil {
+		bm, bok = map[string]any{}, true
+	}
+
+	am, aok := after.(map[string]any)
+	if after == nil {
+		am, aok = map[string]any{}, true
+	}
+
+	if !bok || !aok {
+		if reflect.DeepEqual(before, after) {
+			return []*Change{}
+		}
+
+		return []*Change{{
+			Before: marshal(Mask(before)),
+			After:  marshal(Mask(after)),
+		}}
+	}
+
+	fields := []string{}
+	for field := range bm {
+		fields = append(fields, field)
+	}
+
+	for field := range am {
+		if _, ok := bm[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+
+	sort.Strings(fields)
+
+	changes := []*Change{}
+	for _, field := range fields {
+		if reflect.DeepEqual(bm[field], am[field]) {
+			continue
+		}
+
+		changes = append(changes, &Change{
+			Field:  field,
+			Before: marshal(mask(bm[field], isSecretObject(field))),
+			After:  marshal(mask(am[field], isSecretObject(field))),
+		})
+	}
+
+	return changes
+}
+
+// NewEntry builds an entry from snapshots of an entity before and after a change.
+func NewEntry(action Action, entityType, entityId string, before, after any) *Entry {
+	return &Entry{
+		Action:     action,
+		EntityType: entityType,
+		EntityId:   entityId,
+		Before:     marshal(Mask(before)),
+		After:      marshal(Mask(after)),
+		Changes:    Diff(before, after),
+	}
+}
+
+// NewIntent builds a pending entry that is recorded before a change is applied.
+func NewIntent(action Action, entityType, entityId string, before any) *Entry {
+	return &Entry{
+		Action:     action,
+		EntityType: entityType,
+		EntityId:   entityId,
+		Before:     marshal(Mask(before)),
+		After:      marshal(nil),
+		Changes:    []*Change{},
+		Status:     StatusPending,
+	}
+}
+
+// Resolve sets the outcome of the pending entry and the actor of the pending entry on
+// an entry that records the outcome of the change.
+func (e *Entry) Resolve(intent *Entry, status Status) *Entry {
+	e.Status = status
+	e.IntentId = intent.Id
+	e.ActorId = intent.ActorId
+	e.ActorName = intent.ActorName
+	e.ActorRole = intent.ActorRole
+
+	return e
+}
+
+// hashedEntry leaves out an empty status and intent id so that the hashes of entries
+// recorded before changes had a status stay the same.
+type hashedEntry struct {
+	Sequence   int64           `json:"sequence"`
+	Id         string          `json:"id"`
+	CreatedAt  int64           `json:"createdAt"`
+	ActorId    string          `json:"actorId"`
+	ActorName  string          `json:"actorName"`
+	ActorRole  string          `json:"actorRole"`
+	Action     Action          `json:"action"`
+	EntityType string          `json:"entityType"`
+	EntityId   string          `json:"entityId"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	Changes    []*Change       `json:"changes"`
+	Status     Status          `json:"status,omitempty"`
+	IntentId   string          `json:"intentId,omitempty"`
+	PrevHash   string          `json:"prevHash"`
+}
+
+// ComputeHash returns the hex encoded SHA-256 of every field of the entry except
+// the hash itself.
+func (e *Entry) ComputeHash() string {
+	changes := e.Changes
+	if changes == nil {
+		changes = []*Change{}
+	}
+
+	data, _ := json.Marshal(&hashedEntry{
+		Sequence:   e.Sequence,
+		Id:         e.Id,
+		CreatedAt:  e.CreatedAt,
+		ActorId:    e.ActorId,
+		ActorName:  e.ActorName,
+		ActorRole:  e.ActorRole,
+		Action:     e.Action,
+		EntityType: e.EntityType,
+		EntityId:   e.EntityId,
+		Before:     e.Before,
+		After:      e.After,
+		Changes:    changes,
+		Status:     e.Status,
+		IntentId:   e.IntentId,
+		PrevHash:   e.PrevHash,
+	})
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Chain appends the entry to the entry with the given sequence and hash. The first
+// entry of the log is chained to sequence 0 and an empty hash.
+func (e *Entry) Chain(prevSequence int64, prevHash string) {
+	e.Sequence = prevSequence + 1
+	e.PrevHash = prevHash
+	e.Hash = e.ComputeHash()
+}
@@ -0,0 +1,151 @@
+package audit
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	before := map[string]any{
+		"name":    "old",
+		"key":     "secret-1",
+		"tags":    []any{"a"},
+		"setting": map[string]any{"apikey": "sk-1", "region": "us"},
+		"ttl":     "1h",
+	}
+
+	after := map[string]any{
+		"name":    "new",
+		"key":     "secret-2",
+		"tags":    []any{"a"},
+		"setting": map[string]any{"apikey": "sk-2", "region": "us"},
+		"revoked": true,
+	}
+
+	changes := Diff(before, after)
+
+	fields := []string{}
+	for _, c := range changes {
+		fields = append(fields, c.Field)
+	}
+
+	require.Equal(t, []string{"key", "name", "revoked", "setting", "ttl"}, fields)
+
+	masked, _ := json.Marshal(MaskedValue)
+	assert.JSONEq(t, string(masked), string(changes[0].Before))
+	assert.JSONEq(t, string(masked), string(changes[0].After))
+	assert.JSONEq(t, `"old"`, string(changes[1].Before))
+	assert.JSONEq(t, `"new"`, string(changes[1].After))
+	assert.JSONEq(t, `null`, string(changes[2].Before))
+	assert.JSONEq(t, `true`, string(changes[2].After))
+	assert.JSONEq(t, `{"apikey":"******","region":"******"}`, string(changes[3].Before))
+	assert.JSONEq(t, `"1h"`, string(changes[4].Before))
+	assert.JSONEq(t, `null`, string(changes[4].After))
+
+	assert.Empty(t, Diff(before, before))
+}
+
+func TestNewEntry_MasksSnapshots(t *testing.T) {
+	e := NewEntry(ActionCreate, "key", "key-1", nil, map[string]any{"name": "test", "key": "secret", "previousKey": "old"})
+
+	assert.JSONEq(t, `null`, string(e.Before))
+	assert.JSONEq(t, `{"name":"test","key":"******","previousKey":"******"}`, string(e.After))
+	assert.NotContains(t, string(e.After), "secret")
+
+	for _, c := range e.Changes {
+		assert.NotContains(t, string(c.After), "secret")
+	}
+}
+
+func TestChain(t *testing.T) {
+	first := NewEntry(ActionCreate, "key", "key-1", nil, map[string]any{"name": "test"})
+	first.Chain(0, "")
+
+	assert.Equal(t, int64(1), first.Sequence)
+	assert.Empty(t, first.PrevHash)
+	assert.Equal(t, first.ComputeHash(), first.Hash)
+
+	second := NewEntry(ActionUpdate, "key", "key-1", map[string]any{"name": "test"}, map[string]any{"name": "updated"})
+	second.Chain(first.Sequence, first.Hash)
+
+	assert.Equal(t, int64(2), second.Sequence)
+	assert.Equal(t, first.Hash, second.PrevHash)
+	assert.Equal(t, second.ComputeHash(), second.Hash)
+
+	second.ActorName = "someone else"
+	assert.NotEqual(t, second.Hash, second.ComputeHash())
+}
+
+func TestComputeHash_WithoutStatus(t *testing.T) {
+	e := NewEntry(ActionCreate, "key", "key-1", nil, map[string]any{"name": "test"})
+	e.Chain(0, "")
+
+	legacy, err := json.Marshal(struct {
+		Sequence   int64           `json:"sequence"`
+		Id         string          `json:"id"`
+		CreatedAt  int64           `json:"createdAt"`
+		ActorId    string          `json:"actorId"`
+		ActorName  string          `json:"actorName"`
+		ActorRole  string          `json:"actorRole"`
+		Action     Action          `json:"action"`
+		EntityType string          `json:"entityType"`
+		EntityId   string          `json:"entityId"`
+		Before     json.RawMessage `json:"before"`
+		After      json.RawMessage `json:"after"`
+		Changes    []*Change       `json:"changes"`
+		PrevHash   string          `json:"prevHash"`
+	}{
+		Sequence:   e.Sequence,
+		Action:     e.Action,
+		EntityType: e.EntityType,
+		EntityId:   e.EntityId,
+		Before:     e.Before,
+		After:      e.After,
+		Changes:    e.Changes,
+	})
+	require.NoError(t, err)
+
+	hashed, err := json.Marshal(hashedEntry{
+		Sequence:   e.Sequence,
+		Action:     e.Action,
+		EntityType: e.EntityType,
+		EntityId:   e.EntityId,
+		Before:     e.Before,
+		After:      e.After,
+		Changes:    e.Changes,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, string(legacy), string(hashed))
+}
+
+func TestNewIntent(t *testing.T) {
+	intent := NewIntent(ActionUpdate, "key", "key-1", map[string]any{"name": "test", "key": "secret"})
+	intent.Id = "intent-1"
+	intent.ActorId = "principal-1"
+	intent.ActorName = "platform team"
+	intent.ActorRole = "key-manager"
+
+	assert.Equal(t, StatusPending, intent.Status)
+	assert.JSONEq(t, `{"name":"test","key":"******"}`, string(intent.Before))
+	assert.JSONEq(t, `null`, string(intent.After))
+	assert.Empty(t, intent.Changes)
+
+	outcome := NewEntry(ActionUpdate, "key", "key-1", map[string]any{"name": "test"}, map[string]any{"name": "updated"}).Resolve(intent, StatusCommitted)
+
+	assert.Equal(t, StatusCommitted, outcome.Status)
+	assert.Equal(t, "intent-1", outcome.IntentId)
+	assert.Equal(t, "principal-1", outcome.ActorId)
+	assert.Equal(t, "platform team", outcome.ActorName)
+	assert.Equal(t, "key-manager", outcome.ActorRole)
+	require.Len(t, outcome.Changes, 1)
+
+	outcome.Chain(1, "prev")
+	hash := outcome.Hash
+
+	outcome.Status = StatusAborted
+	assert.NotEqual(t, hash, outcome.ComputeHash())
+}
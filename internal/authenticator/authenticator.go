@@ -3,13 +3,13 @@ package auth
 import (
 	"errors"
 	"fmt"
-	"math/rand"
 	"net/http"
 	"strconv"
 	"strings"
 
 	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
 	"github.com/bricks-cloud/bricksllm/internal/hasher"
+	"github.com/bricks-cloud/bricksllm/internal/jwt"
 	"github.com/bricks-cloud/bricksllm/internal/telemetry"
 	metricname "github.com/bricks-cloud/bricksllm/internal/telemetry/metric_name"
 
@@ -46,15 +46,21 @@ type Authenticator struct {
 	rm        routesManager
 	ks        keyStorage
 	decryptor Decryptor
+	hm        healthManager
+	ja        *JwtAuthenticator
 }
 
-func NewAuthenticator(psm providerSettingsManager, kc keysCache, rm routesManager, ks keyStorage, decryptor Decryptor) *Authenticator {
+// NewAuthenticator creates an authenticator for proxy requests. JWTs are only accepted
+// when ja is not nil.
+func NewAuthenticator(psm providerSettingsManager, kc keysCache, rm routesManager, ks keyStorage, decryptor Decryptor, hm healthManager, ja *JwtAuthenticator) *Authenticator {
 	return &Authenticator{
 		psm:       psm,
 		kc:        kc,
 		rm:        rm,
 		ks:        ks,
 		decryptor: decryptor,
+		hm:        hm,
+		ja:        ja,
 	}
 }
 
@@ -62,6 +68,11 @@ func getApiKey(req *http.Request) (string, error) {
 	list := []string{
 		req.Header.Get("x-api-key"),
 		req.Header.Get("api-key"),
+		req.Header.Get("x-goog-api-key"),
+	}
+
+	if strings.HasPrefix(req.URL.Path, "/api/providers/gemini") {
+		list = append(list, req.URL.Query().Get("key"))
 	}
 
 	split := strings.Split(req.Header.Get("Authorization"), " ")
@@ -79,9 +90,26 @@ func getApiKey(req *http.Request) (string, error) {
 	return "", internal_errors.NewAuthError("api key not found in header")
 }
 
+// removeCredential deletes the headers carrying a credential so that it is not
+// forwarded to providers.
+func removeCredential(req *http.Request, raw string) {
+	for _, name := range []string{"x-api-key", "api-key", "x-goog-api-key"} {
+		if req.Header.Get(name) == raw {
+			req.Header.Del(name)
+		}
+	}
+
+	if strings.HasSuffix(req.Header.Get("Authorization"), " "+raw) {
+		req.Header.Del("Authorization")
+	}
+}
+
 func rewriteHttpAuthHeader(req *http.Request, setting *provider.Setting) error {
-	uri := req.URL.RequestURI()
-	if strings.HasPrefix(uri, "/api/routes") {
+	return rewriteHttpAuthHeaderForUri(req.URL.RequestURI(), req, setting)
+}
+
+func rewriteHttpAuthHeaderForUri(uri string, req *http.Request, setting *provider.Setting) error {
+	if strings.HasPrefix(uri, "/api/routes") || strings.HasPrefix(uri, "/v1/") {
 		return nil
 	}
 
@@ -95,7 +123,7 @@ func rewriteHttpAuthHeader(req *http.Request, setting *provider.Setting) error {
 	}
 
 	if len(apiKey) == 0 {
-		if setting.Provider == "bedrock" {
+		if setting.Provider == "bedrock" || (setting.Provider == "gemini" && len(setting.GetParam("credentials")) != 0) {
 			return nil
 		}
 
@@ -112,6 +140,11 @@ func rewriteHttpAuthHeader(req *http.Request, setting *provider.Setting) error {
 		return nil
 	}
 
+	if strings.HasPrefix(uri, "/api/providers/gemini") {
+		req.Header.Set("x-goog-api-key", apiKey)
+		return nil
+	}
+
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
 
 	return nil
@@ -164,6 +197,10 @@ func (a *Authenticator) getProviderSettingsThatCanAccessCustomRoute(path string,
 }
 
 func canAccessPath(provider string, path string) bool {
+	if strings.HasPrefix(path, "/v1/") {
+		return route.IsUnifiedProvider(provider)
+	}
+
 	if provider == "bedrock" && !strings.HasPrefix(path, "/api/providers/bedrock") {
 		return false
 	}
@@ -184,6 +221,14 @@ func canAccessPath(provider string, path string) bool {
 		return false
 	}
 
+	if provider == "gemini" && !strings.HasPrefix(path, "/api/providers/gemini") {
+		return false
+	}
+
+	if provider != "gemini" && strings.HasPrefix(path, "/api/providers/gemini") {
+		return false
+	}
+
 	return true
 }
 
@@ -204,44 +249,112 @@ func anonymize(input string) string {
 	return string(input[0:5]) + "**********************************************"
 }
 
-func (a *Authenticator) AuthenticateHttpRequest(req *http.Request) (*key.ResponseKey, []*provider.Setting, error) {
+func (a *Authenticator) decryptSetting(setting *provider.Setting) {
+	if !a.decryptor.Enabled() {
+		return
+	}
+
+	encryptedParam := ""
+	if setting.Provider == "amazon" {
+		encryptedParam = setting.Setting["awsSecretAccessKey"]
+	} else if len(setting.Setting["apikey"]) != 0 {
+		encryptedParam = setting.Setting["apikey"]
+	}
+
+	if len(encryptedParam) != 0 {
+		decryptedSecret, err := a.decryptor.Decrypt(encryptedParam, map[string]string{"X-UPDATED-AT": strconv.FormatInt(setting.UpdatedAt, 10)})
+		if err == nil {
+			if setting.Provider == "amazon" {
+				setting.Setting["awsSecretAccessKey"] = decryptedSecret
+			} else {
+				setting.Setting["apikey"] = decryptedSecret
+			}
+		}
+	}
+
+	if setting.Provider == "gemini" && len(setting.Setting["credentials"]) != 0 {
+		decrypted, err := a.decryptor.Decrypt(setting.Setting["credentials"], map[string]string{"X-UPDATED-AT": strconv.FormatInt(setting.UpdatedAt, 10)})
+		if err == nil {
+			setting.Setting["credentials"] = decrypted
+		}
+	}
+}
+
+// RewriteHttpAuthHeaderWithSetting sets the credentials of a provider setting on an
+// outgoing request so that it can be retried against a different setting. uri is the
+// path of the proxied request that determines which header carries the credentials.
+func (a *Authenticator) RewriteHttpAuthHeaderWithSetting(uri string, req *http.Request, setting *provider.Setting) error {
+	copied := &provider.Setting{}
+	*copied = *setting
+	copied.Setting = map[string]string{}
+	for k, v := range setting.Setting {
+		copied.Setting[k] = v
+	}
+
+	a.decryptSetting(copied)
+
+	return rewriteHttpAuthHeaderForUri(uri, req, copied)
+}
+
+// AuthenticateHttpRequest returns the key of a request and the provider settings it
+// can use. The returned user id is taken from the claims when the request carries a
+// JWT and is empty otherwise.
+func (a *Authenticator) AuthenticateHttpRequest(req *http.Request) (*key.ResponseKey, []*provider.Setting, string, error) {
 	raw, err := getApiKey(req)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, "", err
 	}
 
 	hash := hasher.Hash(raw)
+	userId := ""
+	isJwt := a.ja != nil && jwt.IsJwt(raw)
+
+	if isJwt {
+		hash, userId, err = a.ja.Authenticate(raw)
+		if err != nil {
+			return nil, nil, "", err
+		}
+
+		removeCredential(req, raw)
+	}
 
 	key, err := a.kc.GetKeyViaCache(hash)
 	if key != nil {
 		telemetry.Incr(metricname.COUNTER_AUTHENTICATOR_FOUND_KEY_FROM_MEMDB, nil, 1)
 	}
 
-	if key == nil {
+	// A key is stored hashed or, when created with isKeyNotHashed, in its raw form. A JWT
+	// resolves to the form the key was stored in when its claim mapping was resolved, so
+	// the hash of that form is looked up as well in case the key has been hashed since.
+	if key == nil && !isJwt {
 		key, err = a.kc.GetKeyViaCache(raw)
 	}
 
+	if key == nil && isJwt {
+		key, err = a.kc.GetKeyViaCache(hasher.Hash(hash))
+	}
+
 	if err != nil {
 		_, ok := err.(notFoundError)
 		if ok {
-			return nil, nil, internal_errors.NewAuthError(fmt.Sprintf("key %s is not found", anonymize(raw)))
+			return nil, nil, "", internal_errors.NewAuthError(fmt.Sprintf("key %s is not found", anonymize(raw)))
 		}
 
-		return nil, nil, err
+		return nil, nil, "", err
 	}
 
 	if key == nil {
-		return nil, nil, internal_errors.NewAuthError(fmt.Sprintf("key %s is not found", anonymize(raw)))
+		return nil, nil, "", internal_errors.NewAuthError(fmt.Sprintf("key %s is not found", anonymize(raw)))
 	}
 
 	if key.Revoked {
-		return nil, nil, internal_errors.NewAuthError(fmt.Sprintf("key %s has been revoked", anonymize(raw)))
+		return nil, nil, "", internal_errors.NewAuthError(fmt.Sprintf("key %s has been revoked", anonymize(raw)))
 	}
 
 	if strings.HasPrefix(req.URL.Path, "/api/routes") {
 		err = a.canKeyAccessCustomRoute(req.URL.Path, key.KeyId)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, "", err
 		}
 	}
 
@@ -266,43 +379,28 @@ func (a *Authenticator) AuthenticateHttpRequest(req *http.Request) (*key.Respons
 		selected = a.getProviderSettingsThatCanAccessCustomRoute(req.URL.Path, allSettings)
 
 		if len(selected) == 0 {
-			return nil, nil, internal_errors.NewAuthError(fmt.Sprintf("provider settings associated with the key %s are not compatible with the route", anonymize(raw)))
+			return nil, nil, "", internal_errors.NewAuthError(fmt.Sprintf("provider settings associated with the key %s are not compatible with the route", anonymize(raw)))
 		}
 	}
 
 	if len(selected) != 0 {
-		used := selected[0]
-		if key.RotationEnabled {
-			used = selected[rand.Intn(len(selected))]
+		index := a.selectSetting(key, selected)
+		used := selected[index]
+		if index != 0 {
+			reordered := []*provider.Setting{used}
+			reordered = append(reordered, selected[:index]...)
+			selected = append(reordered, selected[index+1:]...)
 		}
 
-		if a.decryptor.Enabled() {
-			encryptedParam := ""
-			if used.Provider == "amazon" {
-				encryptedParam = used.Setting["awsSecretAccessKey"]
-			} else if len(used.Setting["apikey"]) != 0 {
-				encryptedParam = used.Setting["apikey"]
-			}
-
-			if len(encryptedParam) != 0 {
-				decryptedSecret, err := a.decryptor.Decrypt(encryptedParam, map[string]string{"X-UPDATED-AT": strconv.FormatInt(used.UpdatedAt, 10)})
-				if err == nil {
-					if used.Provider == "amazon" {
-						used.Setting["awsSecretAccessKey"] = decryptedSecret
-					} else {
-						used.Setting["apikey"] = decryptedSecret
-					}
-				}
-			}
-		}
+		a.decryptSetting(used)
 
 		err := rewriteHttpAuthHeader(req, used)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, "", err
 		}
 
-		return key, selected, nil
+		return key, selected, userId, nil
 	}
 
-	return nil, nil, internal_errors.NewAuthError(fmt.Sprintf("provider setting not found for key %s", raw))
+	return nil, nil, "", internal_errors.NewAuthError(fmt.Sprintf("provider setting not found for key %s", raw))
 }
@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanAccessPath(t *testing.T) {
+	cases := []struct {
+		provider string
+		path     string
+		allowed  bool
+	}{
+		{"openai", "/api/providers/openai/v1/chat/completions", true},
+		{"openai", "/api/providers/anthropic/v1/messages", false},
+		{"anthropic", "/api/providers/anthropic/v1/messages", true},
+		{"azure", "/api/providers/azure/openai/deployments/d/chat/completions", true},
+		{"gemini", "/api/providers/gemini/v1beta/models/gemini-1.5-flash:generateContent", true},
+		{"openai", "/api/providers/gemini/v1beta/models/gemini-1.5-flash:generateContent", false},
+		{"openai", "/v1/chat/completions", true},
+		{"anthropic", "/v1/chat/completions", true},
+		{"deepinfra", "/v1/embeddings", true},
+		{"amazon", "/v1/chat/completions", false},
+		{"custom", "/v1/chat/completions", false},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.allowed, canAccessPath(c.provider, c.path), c.provider+" "+c.path)
+	}
+}
@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+)
+
+// mappingTtl is how long the key resolved for a claim mapping is reused before the
+// key storage is queried again.
+const mappingTtl = time.Minute
+
+type jwtVerifier interface {
+	Verify(token string) (map[string]any, error)
+}
+
+type mappedKeyStorage interface {
+	GetKey(keyId string) (*key.ResponseKey, error)
+	GetKeys(tags, keyIds []string, provider string) ([]*key.ResponseKey, error)
+}
+
+// ClaimMapping maps JWTs with a claim value to a key. The key is either set by id or
+// is the oldest key that is not revoked and has every one of the tags.
+type ClaimMapping struct {
+	Claim string `json:"claim"`
+	// Value is compared with the claim or with every element of the claim when the
+	// claim is a list. A value of * matches any JWT with the claim.
+	Value string   `json:"value"`
+	KeyId string   `json:"keyId"`
+	Tags  []string `json:"tags"`
+}
+
+// ParseClaimMappings parses a JSON array of claim mappings. Mappings are evaluated in
+// order and the first one that matches a JWT is used.
+func ParseClaimMappings(raw string) ([]*ClaimMapping, error) {
+	mappings := []*ClaimMapping{}
+	if len(raw) == 0 {
+		return mappings, nil
+	}
+
+	err := json.Unmarshal([]byte(raw), &mappings)
+	if err != nil {
+		return nil, err
+	}
+
+	for idx, m := range mappings {
+		if len(m.Claim) == 0 || len(m.Value) == 0 {
+			return nil, fmt.Errorf("claim mapping at index [%d] must have a claim and a value", idx)
+		}
+
+		if (len(m.KeyId) == 0) == (len(m.Tags) == 0) {
+			return nil, fmt.Errorf("claim mapping at index [%d] must have either a key id or tags", idx)
+		}
+	}
+
+	return mappings, nil
+}
+
+func (m *ClaimMapping) Matches(claims map[string]any) bool {
+	claim, ok := claims[m.Claim]
+	if !ok {
+		return false
+	}
+
+	if m.Value == "*" {
+		return true
+	}
+
+	switch value := claim.(type) {
+	case string:
+		return value == m.Value
+	case []any:
+		for _, v := range value {
+			if s, ok := v.(string); ok && s == m.Value {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+type resolvedKey struct {
+	hash      string
+	expiresAt time.Time
+}
+
+// JwtAuthenticator authenticates proxy requests with JWTs instead of static keys.
+type JwtAuthenticator struct {
+	verifier    jwtVerifier
+	ks          mappedKeyStorage
+	mappings    []*ClaimMapping
+	userIdClaim string
+	resolved    map[int]*resolvedKey
+	lock        sync.Mutex
+}
+
+func NewJwtAuthenticator(verifier jwtVerifier, ks mappedKeyStorage, mappings []*ClaimMapping, userIdClaim string) *JwtAuthenticator {
+	return &JwtAuthenticator{
+		verifier:    verifier,
+		ks:          ks,
+		mappings:    mappings,
+		userIdClaim: userIdClaim,
+		resolved:    map[int]*resolvedKey{},
+	}
+}
+
+func (ja *JwtAuthenticator) getResolved(index int) string {
+	ja.lock.Lock()
+	defer ja.lock.Unlock()
+
+	r := ja.resolved[index]
+	if r == nil || time.Now().After(r.expiresAt) {
+		return ""
+	}
+
+	return r.hash
+}
+
+func (ja *JwtAuthenticator) setResolved(index int, hash string) {
+	ja.lock.Lock()
+	defer ja.lock.Unlock()
+
+	ja.resolved[index] = &resolvedKey{
+		hash:      hash,
+		expiresAt: time.Now().Add(mappingTtl),
+	}
+}
+
+func (ja *JwtAuthenticator) resolve(index int) (string, error) {
+	if hash := ja.getResolved(index); len(hash) != 0 {
+		return hash, nil
+	}
+
+	m := ja.mappings[index]
+	if len(m.KeyId) != 0 {
+		k, err := ja.ks.GetKey(m.KeyId)
+		if err != nil {
+			return "", err
+		}
+
+		if k == nil {
+			return "", internal_errors.NewAuthError(fmt.Sprintf("key %s of claim mapping is not found", m.KeyId))
+		}
+
+		ja.setResolved(index, k.Key)
+		return k.Key, nil
+	}
+
+	keys, err := ja.ks.GetKeys(m.Tags, nil, "")
+	if err != nil {
+		return "", err
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt < keys[j].CreatedAt
+	})
+
+	for _, k := range keys {
+		if !k.Revoked {
+			ja.setResolved(index, k.Key)
+			return k.Key, nil
+		}
+	}
+
+	return "", internal_errors.NewAuthError(fmt.Sprintf("key with tags %s of claim mapping is not found", strings.Join(m.Tags, ",")))
+}
+
+// Authenticate verifies a JWT and returns the hash of the key it maps to along with
+// the user id taken from its claims.
+func (ja *JwtAuthenticator) Authenticate(token string) (string, string, error) {
+	claims, err := ja.verifier.Verify(token)
+	if err != nil {
+		telemetry.Incr("bricksllm.authenticator.jwt_authenticator.authenticate.verify_error", nil, 1)
+		return "", "", err
+	}
+
+	userId, _ := claims[ja.userIdClaim].(string)
+
+	for idx, m := range ja.mappings {
+		if !m.Matches(claims) {
+			continue
+		}
+
+		hash, err := ja.resolve(idx)
+		if err != nil {
+			telemetry.Incr("bricksllm.authenticator.jwt_authenticator.authenticate.resolve_error", nil, 1)
+			return "", "", err
+		}
+
+		return hash, userId, nil
+	}
+
+	telemetry.Incr("bricksllm.authenticator.jwt_authenticator.authenticate.no_matching_mapping", nil, 1)
+	return "", "", internal_errors.NewAuthError("jwt does not match any claim mapping")
+}
@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/bricks-cloud/bricksllm/internal/provider"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+)
+
+type healthManager interface {
+	GetHealth(settingIds []string) (map[string]*provider.SettingHealth, error)
+	NextRoundRobinCounter(keyId string) (int64, error)
+}
+
+func selectRandomSetting(settings []*provider.Setting) int {
+	return rand.Intn(len(settings))
+}
+
+func selectWeightedRoundRobinSetting(counter int64, lbc *key.LoadBalancingConfig, settings []*provider.Setting) int {
+	total := 0
+	for _, setting := range settings {
+		total += lbc.GetWeight(setting.Id)
+	}
+
+	if total <= 0 {
+		return 0
+	}
+
+	position := int((counter - 1) % int64(total))
+	if position < 0 {
+		position += total
+	}
+
+	for i, setting := range settings {
+		position -= lbc.GetWeight(setting.Id)
+		if position < 0 {
+			return i
+		}
+	}
+
+	return 0
+}
+
+// selectLeastSetting returns the index of the setting with the lowest measured
+// value. Settings without a measurement are never ranked ahead of measured ones and
+// are only picked at random when no setting has been measured yet.
+func selectLeastSetting(settings []*provider.Setting, health map[string]*provider.SettingHealth, measure func(*provider.SettingHealth) (float64, bool)) int {
+	candidates := []int{}
+	unmeasured := []int{}
+	lowest := 0.0
+
+	for i, setting := range settings {
+		h, ok := health[setting.Id]
+		if !ok || h == nil {
+			unmeasured = append(unmeasured, i)
+			continue
+		}
+
+		value, measured := measure(h)
+		if !measured {
+			unmeasured = append(unmeasured, i)
+			continue
+		}
+
+		if len(candidates) == 0 || value < lowest {
+			candidates = []int{i}
+			lowest = value
+			continue
+		}
+
+		if value == lowest {
+			candidates = append(candidates, i)
+		}
+	}
+
+	if len(candidates) == 0 {
+		candidates = unmeasured
+	}
+
+	if len(candidates) == 0 {
+		return 0
+	}
+
+	return candidates[rand.Intn(len(candidates))]
+}
+
+// selectSetting picks the provider setting used to serve a request. Settings
+// ejected by the circuit breaker are skipped unless every setting is ejected.
+func (a *Authenticator) selectSetting(k *key.ResponseKey, settings []*provider.Setting) int {
+	lbc := k.LoadBalancingConfig
+	if lbc == nil || a.hm == nil || len(settings) == 1 {
+		if k.RotationEnabled {
+			return selectRandomSetting(settings)
+		}
+
+		return 0
+	}
+
+	ids := []string{}
+	for _, setting := range settings {
+		ids = append(ids, setting.Id)
+	}
+
+	health, err := a.hm.GetHealth(ids)
+	if err != nil {
+		telemetry.Incr("bricksllm.authenticator.select_setting.get_health_error", nil, 1)
+		return selectRandomSetting(settings)
+	}
+
+	now := time.Now().UnixMilli()
+	available := []int{}
+	candidates := []*provider.Setting{}
+	for i, setting := range settings {
+		if health[setting.Id].IsEjected(now) {
+			continue
+		}
+
+		available = append(available, i)
+		candidates = append(candidates, setting)
+	}
+
+	if len(candidates) == 0 {
+		telemetry.Incr("bricksllm.authenticator.select_setting.all_settings_ejected", nil, 1)
+		return selectRandomSetting(settings)
+	}
+
+	selected := 0
+	switch lbc.Strategy {
+	case key.WeightedRoundRobinStrategy:
+		counter, err := a.hm.NextRoundRobinCounter(k.KeyId)
+		if err != nil {
+			telemetry.Incr("bricksllm.authenticator.select_setting.next_round_robin_counter_error", nil, 1)
+			selected = selectRandomSetting(candidates)
+			break
+		}
+
+		selected = selectWeightedRoundRobinSetting(counter, lbc, candidates)
+	case key.LeastLatencyStrategy:
+		selected = selectLeastSetting(candidates, health, func(h *provider.SettingHealth) (float64, bool) {
+			return h.LatencyInMs, h.HasLatency
+		})
+	case key.LeastErrorsStrategy:
+		selected = selectLeastSetting(candidates, health, func(h *provider.SettingHealth) (float64, bool) {
+			return h.ErrorRate, true
+		})
+	default:
+		selected = selectRandomSetting(candidates)
+	}
+
+	telemetry.Incr("bricksllm.authenticator.select_setting.selected", []string{"strategy:" + string(lbc.Strategy)}, 1)
+
+	return available[selected]
+}
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/bricks-cloud/bricksllm/internal/provider"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeHealthManager struct {
+	health  map[string]*provider.SettingHealth
+	counter int64
+}
+
+func (hm *fakeHealthManager) GetHealth(settingIds []string) (map[string]*provider.SettingHealth, error) {
+	return hm.health, nil
+}
+
+func (hm *fakeHealthManager) NextRoundRobinCounter(keyId string) (int64, error) {
+	hm.counter++
+	return hm.counter, nil
+}
+
+func newTestSettings(ids ...string) []*provider.Setting {
+	settings := []*provider.Setting{}
+	for _, id := range ids {
+		settings = append(settings, &provider.Setting{Id: id})
+	}
+
+	return settings
+}
+
+func latency(h *provider.SettingHealth) (float64, bool) {
+	return h.LatencyInMs, h.HasLatency
+}
+
+func TestSelectLeastSetting(t *testing.T) {
+	settings := newTestSettings("a", "b", "c")
+
+	cases := []struct {
+		name     string
+		health   map[string]*provider.SettingHealth
+		expected []int
+	}{
+		{
+			name: "lowest latency",
+			health: map[string]*provider.SettingHealth{
+				"a": {LatencyInMs: 300, HasLatency: true},
+				"b": {LatencyInMs: 100, HasLatency: true},
+				"c": {LatencyInMs: 200, HasLatency: true},
+			},
+			expected: []int{1},
+		},
+		{
+			name: "settings without a latency sample are not the fastest",
+			health: map[string]*provider.SettingHealth{
+				"a": {ErrorRate: 0.9},
+				"b": {LatencyInMs: 500, HasLatency: true},
+			},
+			expected: []int{1},
+		},
+		{
+			name:     "unmeasured settings are picked when nothing is measured",
+			health:   map[string]*provider.SettingHealth{"a": {ErrorRate: 0.5}},
+			expected: []int{0, 1, 2},
+		},
+	}
+
+	for _, c := range cases {
+		for i := 0; i < 10; i++ {
+			assert.Contains(t, c.expected, selectLeastSetting(settings, c.health, latency), c.name)
+		}
+	}
+}
+
+func TestSelectWeightedRoundRobinSetting(t *testing.T) {
+	settings := newTestSettings("a", "b")
+	lbc := &key.LoadBalancingConfig{Weights: map[string]int{"a": 2, "b": 1}}
+
+	selected := []int{}
+	for counter := int64(1); counter <= 6; counter++ {
+		selected = append(selected, selectWeightedRoundRobinSetting(counter, lbc, settings))
+	}
+
+	assert.Equal(t, []int{0, 0, 1, 0, 0, 1}, selected)
+}
+
+func TestSelectSetting_SkipsEjectedSettings(t *testing.T) {
+	hm := &fakeHealthManager{
+		health: map[string]*provider.SettingHealth{
+			"a": {LatencyInMs: 10, HasLatency: true, EjectedUntil: time.Now().Add(time.Minute).UnixMilli()},
+			"b": {LatencyInMs: 50, HasLatency: true},
+			"c": {LatencyInMs: 90, HasLatency: true},
+		},
+	}
+
+	a := NewAuthenticator(nil, nil, nil, nil, nil, hm, nil)
+	k := &key.ResponseKey{KeyId: "k", LoadBalancingConfig: &key.LoadBalancingConfig{Strategy: key.LeastLatencyStrategy}}
+
+	assert.Equal(t, 1, a.selectSetting(k, newTestSettings("a", "b", "c")))
+
+	k.LoadBalancingConfig.Strategy = key.WeightedRoundRobinStrategy
+	assert.Equal(t, 1, a.selectSetting(k, newTestSettings("a", "b", "c")))
+	assert.Equal(t, 2, a.selectSetting(k, newTestSettings("a", "b", "c")))
+}
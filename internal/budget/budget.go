@@ -0,0 +1,144 @@
+package budget
+
+import (
+	"fmt"
+	"strings"
+
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+	"github.com/bricks-cloud/bricksllm/internal/key"
+)
+
+// Budget is a cost limit shared by a group of keys. A budget with a tag applies to
+// every key with the tag, a budget with key ids applies to the listed keys and a
+// budget with neither applies to every key of the organization.
+type Budget struct {
+	Id                     string       `json:"id"`
+	CreatedAt              int64        `json:"createdAt"`
+	UpdatedAt              int64        `json:"updatedAt"`
+	Name                   string       `json:"name"`
+	Tag                    string       `json:"tag"`
+	KeyIds                 []string     `json:"keyIds"`
+	CostLimitInUsd         float64      `json:"costLimitInUsd"`
+	CostLimitInUsdOverTime float64      `json:"costLimitInUsdOverTime"`
+	CostLimitInUsdUnit     key.TimeUnit `json:"costLimitInUsdUnit"`
+	Deleted                bool         `json:"-"`
+}
+
+type UpdateBudget struct {
+	UpdatedAt              int64         `json:"updatedAt"`
+	Name                   string        `json:"name"`
+	Tag                    *string       `json:"tag"`
+	KeyIds                 []string      `json:"keyIds"`
+	CostLimitInUsd         *float64      `json:"costLimitInUsd"`
+	CostLimitInUsdOverTime *float64      `json:"costLimitInUsdOverTime"`
+	CostLimitInUsdUnit     *key.TimeUnit `json:"costLimitInUsdUnit"`
+}
+
+func (b *Budget) Validate() error {
+	if b == nil {
+		return internal_errors.NewValidationError("budget cannot be nil")
+	}
+
+	msgs := []string{}
+
+	if len(b.Name) == 0 {
+		msgs = append(msgs, "name cannot be empty")
+	}
+
+	if len(b.Tag) != 0 && len(b.KeyIds) != 0 {
+		msgs = append(msgs, "tag and keyIds cannot be set at the same time")
+	}
+
+	for idx, id := range b.KeyIds {
+		if len(id) == 0 {
+			msgs = append(msgs, fmt.Sprintf("key id at index [%d] cannot be empty", idx))
+		}
+	}
+
+	if b.CostLimitInUsd < 0 {
+		msgs = append(msgs, "costLimitInUsd cannot be negative")
+	}
+
+	if b.CostLimitInUsdOverTime < 0 {
+		msgs = append(msgs, "costLimitInUsdOverTime cannot be negative")
+	}
+
+	if b.CostLimitInUsd == 0 && b.CostLimitInUsdOverTime == 0 {
+		msgs = append(msgs, "either costLimitInUsd or costLimitInUsdOverTime must be set")
+	}
+
+	if b.CostLimitInUsdOverTime != 0 && b.CostLimitInUsdUnit != key.DayTimeUnit && b.CostLimitInUsdUnit != key.HourTimeUnit && b.CostLimitInUsdUnit != key.MonthTimeUnit && b.CostLimitInUsdUnit != key.MinuteTimeUnit {
+		msgs = append(msgs, "costLimitInUsdUnit must be one of m, h, d and mo when costLimitInUsdOverTime is set")
+	}
+
+	if len(msgs) != 0 {
+		return internal_errors.NewValidationError("budget is not valid: " + strings.Join(msgs, " ,"))
+	}
+
+	return nil
+}
+
+// Apply returns a copy of the budget with the update applied.
+func (b *Budget) Apply(ub *UpdateBudget) *Budget {
+	updated := *b
+
+	if len(ub.Name) != 0 {
+		updated.Name = ub.Name
+	}
+
+	if ub.Tag != nil {
+		updated.Tag = *ub.Tag
+	}
+
+	if ub.KeyIds != nil {
+		updated.KeyIds = ub.KeyIds
+	}
+
+	if ub.CostLimitInUsd != nil {
+		updated.CostLimitInUsd = *ub.CostLimitInUsd
+	}
+
+	if ub.CostLimitInUsdOverTime != nil {
+		updated.CostLimitInUsdOverTime = *ub.CostLimitInUsdOverTime
+	}
+
+	if ub.CostLimitInUsdUnit != nil {
+		updated.CostLimitInUsdUnit = *ub.CostLimitInUsdUnit
+	}
+
+	return &updated
+}
+
+// AppliesTo reports whether spend of a key counts towards the budget.
+func (b *Budget) AppliesTo(keyId string, tags []string) bool {
+	if len(b.Tag) != 0 {
+		for _, t := range tags {
+			if t == b.Tag {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if len(b.KeyIds) != 0 {
+		for _, id := range b.KeyIds {
+			if id == keyId {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
+
+type Spend struct {
+	BudgetId               string       `json:"budgetId"`
+	CostInUsd              float64      `json:"costInUsd"`
+	CostLimitInUsd         float64      `json:"costLimitInUsd"`
+	CostInUsdOverTime      float64      `json:"costInUsdOverTime"`
+	CostLimitInUsdOverTime float64      `json:"costLimitInUsdOverTime"`
+	CostLimitInUsdUnit     key.TimeUnit `json:"costLimitInUsdUnit"`
+}
@@ -0,0 +1,51 @@
+package budget
+
+import (
+	"testing"
+
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudget_Validate(t *testing.T) {
+	cases := []struct {
+		name   string
+		budget *Budget
+		valid  bool
+	}{
+		{"nil", nil, false},
+		{"organization", &Budget{Name: "org", CostLimitInUsd: 1000}, true},
+		{"tag", &Budget{Name: "team", Tag: "team-a", CostLimitInUsdOverTime: 100, CostLimitInUsdUnit: key.MonthTimeUnit}, true},
+		{"keys", &Budget{Name: "keys", KeyIds: []string{"a", "b"}, CostLimitInUsd: 10}, true},
+		{"missing name", &Budget{CostLimitInUsd: 10}, false},
+		{"tag and keys", &Budget{Name: "b", Tag: "team-a", KeyIds: []string{"a"}, CostLimitInUsd: 10}, false},
+		{"empty key id", &Budget{Name: "b", KeyIds: []string{""}, CostLimitInUsd: 10}, false},
+		{"no limit", &Budget{Name: "b"}, false},
+		{"negative limit", &Budget{Name: "b", CostLimitInUsd: -1, CostLimitInUsdOverTime: 10, CostLimitInUsdUnit: key.DayTimeUnit}, false},
+		{"missing unit", &Budget{Name: "b", CostLimitInUsdOverTime: 10}, false},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.valid, c.budget.Validate() == nil, c.name)
+	}
+}
+
+func TestBudget_Apply(t *testing.T) {
+	b := &Budget{Id: "b", Name: "team", Tag: "team-a", CostLimitInUsd: 10}
+
+	tag := ""
+	limit := 20.0
+	unit := key.DayTimeUnit
+	updated := b.Apply(&UpdateBudget{Tag: &tag, KeyIds: []string{"k"}, CostLimitInUsdOverTime: &limit, CostLimitInUsdUnit: &unit})
+
+	assert.Equal(t, &Budget{Id: "b", Name: "team", KeyIds: []string{"k"}, CostLimitInUsd: 10, CostLimitInUsdOverTime: 20, CostLimitInUsdUnit: key.DayTimeUnit}, updated)
+	assert.Equal(t, "team-a", b.Tag)
+}
+
+func TestBudget_AppliesTo(t *testing.T) {
+	assert.True(t, (&Budget{}).AppliesTo("k", nil))
+	assert.True(t, (&Budget{Tag: "team-a"}).AppliesTo("k", []string{"team-b", "team-a"}))
+	assert.False(t, (&Budget{Tag: "team-a"}).AppliesTo("k", []string{"team-b"}))
+	assert.True(t, (&Budget{KeyIds: []string{"j", "k"}}).AppliesTo("k", nil))
+	assert.False(t, (&Budget{KeyIds: []string{"j"}}).AppliesTo("k", []string{"team-a"}))
+}
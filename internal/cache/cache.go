@@ -8,6 +8,7 @@ import (
 
 type store interface {
 	Set(key string, value interface{}, ttl time.Duration) error
+	SetNX(key string, value interface{}, ttl time.Duration) (bool, error)
 	GetBytes(key string) ([]byte, error)
 }
 
@@ -29,6 +30,12 @@ func (c *Cache) StoreBytes(key string, value []byte, ttl time.Duration) error {
 	return c.store.Set(c.computeHashKey(key), value, ttl)
 }
 
+// StoreBytesIfNotExists stores the value only if the key is not cached yet. It reports
+// whether the value was stored.
+func (c *Cache) StoreBytesIfNotExists(key string, value []byte, ttl time.Duration) (bool, error) {
+	return c.store.SetNX(c.computeHashKey(key), value, ttl)
+}
+
 func (c *Cache) GetBytes(key string) ([]byte, error) {
 	return c.store.GetBytes(c.computeHashKey(key))
 }
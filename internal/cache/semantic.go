@@ -0,0 +1,183 @@
+package cache
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/util"
+)
+
+// each scope keeps at most this many embeddings so that a lookup reads and scans a
+// bounded amount of data
+const semanticCacheMaxEntries = 256
+
+type semanticStore interface {
+	AddEntry(scope string, entry []byte, max int64, ttl time.Duration) error
+	GetEntries(scope string, max int64) ([][]byte, error)
+}
+
+// SemanticEntry is stored in a compact binary form: the expiry in unix seconds, the
+// length of the cache key, the cache key and the normalized embedding as little endian
+// float32 values.
+type SemanticEntry struct {
+	CacheKey  string
+	Embedding []float32
+	ExpiresAt int64
+}
+
+func (e *SemanticEntry) MarshalBinary() ([]byte, error) {
+	if len(e.CacheKey) > math.MaxUint16 {
+		return nil, errors.New("semantic cache key is too long")
+	}
+
+	bs := make([]byte, 10+len(e.CacheKey)+4*len(e.Embedding))
+	binary.LittleEndian.PutUint64(bs[0:8], uint64(e.ExpiresAt))
+	binary.LittleEndian.PutUint16(bs[8:10], uint16(len(e.CacheKey)))
+	copy(bs[10:], e.CacheKey)
+
+	offset := 10 + len(e.CacheKey)
+	for i, v := range e.Embedding {
+		binary.LittleEndian.PutUint32(bs[offset+4*i:], math.Float32bits(v))
+	}
+
+	return bs, nil
+}
+
+func (e *SemanticEntry) UnmarshalBinary(bs []byte) error {
+	if len(bs) < 10 {
+		return errors.New("semantic cache entry is too short")
+	}
+
+	keyLen := int(binary.LittleEndian.Uint16(bs[8:10]))
+	if len(bs) < 10+keyLen || (len(bs)-10-keyLen)%4 != 0 {
+		return errors.New("semantic cache entry is malformed")
+	}
+
+	e.ExpiresAt = int64(binary.LittleEndian.Uint64(bs[0:8]))
+	e.CacheKey = string(bs[10 : 10+keyLen])
+
+	offset := 10 + keyLen
+	e.Embedding = make([]float32, (len(bs)-offset)/4)
+	for i := range e.Embedding {
+		e.Embedding[i] = math.Float32frombits(binary.LittleEndian.Uint32(bs[offset+4*i:]))
+	}
+
+	return nil
+}
+
+type SemanticCache struct {
+	store semanticStore
+	cache *Cache
+}
+
+func NewSemanticCache(s semanticStore, c *Cache) *SemanticCache {
+	return &SemanticCache{
+		store: s,
+		cache: c,
+	}
+}
+
+func normalize(v []float32) []float32 {
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+
+	if norm == 0 {
+		return nil
+	}
+
+	norm = math.Sqrt(norm)
+	normalized := make([]float32, len(v))
+	for i, x := range v {
+		normalized[i] = float32(float64(x) / norm)
+	}
+
+	return normalized
+}
+
+// dot is the cosine similarity of two normalized embeddings
+func dot(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+
+	return sum
+}
+
+func (c *SemanticCache) GetBytes(scope string, embedding []float32, threshold float64) ([]byte, float64, error) {
+	query := normalize(embedding)
+	if query == nil {
+		return nil, 0, nil
+	}
+
+	entries, err := c.store.GetEntries(scope, semanticCacheMaxEntries)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	now := time.Now().Unix()
+
+	selected := ""
+	var highest float64 = 0
+	entry := &SemanticEntry{}
+	for _, raw := range entries {
+		err := entry.UnmarshalBinary(raw)
+		if err != nil {
+			continue
+		}
+
+		if entry.ExpiresAt < now {
+			continue
+		}
+
+		similarity := dot(query, entry.Embedding)
+		if similarity >= threshold && similarity > highest {
+			highest = similarity
+			selected = entry.CacheKey
+		}
+	}
+
+	if len(selected) == 0 {
+		return nil, 0, nil
+	}
+
+	bs, err := c.cache.GetBytes(selected)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return bs, highest, nil
+}
+
+func (c *SemanticCache) StoreBytes(scope string, embedding []float32, value []byte, ttl time.Duration) error {
+	normalized := normalize(embedding)
+	if normalized == nil {
+		return errors.New("semantic cache embedding is empty")
+	}
+
+	entry := &SemanticEntry{
+		CacheKey:  util.NewUuid(),
+		Embedding: normalized,
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+	}
+
+	err := c.cache.StoreBytes(entry.CacheKey, value, ttl)
+	if err != nil {
+		return err
+	}
+
+	bs, err := entry.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	return c.store.AddEntry(scope, bs, semanticCacheMaxEntries, ttl)
+}
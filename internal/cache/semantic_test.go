@@ -0,0 +1,130 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStore struct {
+	values map[string][]byte
+}
+
+func (s *fakeStore) Set(key string, value interface{}, ttl time.Duration) error {
+	s.values[key] = value.([]byte)
+	return nil
+}
+
+func (s *fakeStore) SetNX(key string, value interface{}, ttl time.Duration) (bool, error) {
+	if _, ok := s.values[key]; ok {
+		return false, nil
+	}
+
+	s.values[key] = value.([]byte)
+	return true, nil
+}
+
+func (s *fakeStore) GetBytes(key string) ([]byte, error) {
+	bs, ok := s.values[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+
+	return bs, nil
+}
+
+type fakeSemanticStore struct {
+	entries map[string][][]byte
+	max     int64
+}
+
+func (s *fakeSemanticStore) AddEntry(scope string, entry []byte, max int64, ttl time.Duration) error {
+	entries := append([][]byte{entry}, s.entries[scope]...)
+	if int64(len(entries)) > max {
+		entries = entries[:max]
+	}
+
+	s.entries[scope] = entries
+	return nil
+}
+
+func (s *fakeSemanticStore) GetEntries(scope string, max int64) ([][]byte, error) {
+	s.max = max
+	return s.entries[scope], nil
+}
+
+func newTestSemanticCache() (*SemanticCache, *fakeSemanticStore) {
+	ss := &fakeSemanticStore{entries: map[string][][]byte{}}
+	return NewSemanticCache(ss, NewCache(&fakeStore{values: map[string][]byte{}})), ss
+}
+
+func TestSemanticEntry_Binary(t *testing.T) {
+	entry := &SemanticEntry{
+		CacheKey:  "key",
+		Embedding: []float32{0.6, -0.8, 0},
+		ExpiresAt: 1700000000,
+	}
+
+	bs, err := entry.MarshalBinary()
+	require.NoError(t, err)
+	assert.Len(t, bs, 10+3+4*3)
+
+	decoded := &SemanticEntry{}
+	require.NoError(t, decoded.UnmarshalBinary(bs))
+	assert.Equal(t, entry, decoded)
+
+	assert.Error(t, decoded.UnmarshalBinary(bs[:5]))
+	assert.Error(t, decoded.UnmarshalBinary(bs[:len(bs)-1]))
+}
+
+func TestSemanticCache_GetBytes(t *testing.T) {
+	sc, ss := newTestSemanticCache()
+
+	require.NoError(t, sc.StoreBytes("scope", []float32{1, 0}, []byte("east"), time.Minute))
+	require.NoError(t, sc.StoreBytes("scope", []float32{0, 2}, []byte("north"), time.Minute))
+
+	bs, similarity, err := sc.GetBytes("scope", []float32{0.1, 3}, 0.9)
+	require.NoError(t, err)
+	assert.Equal(t, "north", string(bs))
+	assert.InDelta(t, 0.9994, similarity, 0.001)
+	assert.Equal(t, int64(semanticCacheMaxEntries), ss.max)
+
+	bs, _, err = sc.GetBytes("scope", []float32{1, 1}, 0.9)
+	require.NoError(t, err)
+	assert.Nil(t, bs)
+
+	bs, _, err = sc.GetBytes("other", []float32{1, 0}, 0.9)
+	require.NoError(t, err)
+	assert.Nil(t, bs)
+
+	bs, _, err = sc.GetBytes("scope", []float32{0, 0}, 0.9)
+	require.NoError(t, err)
+	assert.Nil(t, bs)
+}
+
+func TestSemanticCache_GetBytes_SkipsExpired(t *testing.T) {
+	sc, ss := newTestSemanticCache()
+
+	expired := &SemanticEntry{CacheKey: "expired", Embedding: []float32{1, 0}, ExpiresAt: time.Now().Add(-time.Minute).Unix()}
+	bs, err := expired.MarshalBinary()
+	require.NoError(t, err)
+	ss.entries["scope"] = [][]byte{bs, []byte("malformed")}
+
+	got, _, err := sc.GetBytes("scope", []float32{1, 0}, 0.5)
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+func TestSemanticCache_StoreBytes_BoundsEntries(t *testing.T) {
+	sc, ss := newTestSemanticCache()
+
+	for i := 0; i < semanticCacheMaxEntries+10; i++ {
+		require.NoError(t, sc.StoreBytes("scope", []float32{1, float32(i)}, []byte("value"), time.Minute))
+	}
+
+	assert.Len(t, ss.entries["scope"], semanticCacheMaxEntries)
+	assert.Error(t, sc.StoreBytes("scope", []float32{0, 0}, []byte("value"), time.Minute))
+}
@@ -42,6 +42,7 @@ type Config struct {
 	NumberOfEventMessageConsumers int           `koanf:"number_of_event_message_consumers" env:"NUMBER_OF_EVENT_MESSAGE_CONSUMERS" envDefault:"3"`
 	OpenAiApiKey                  string        `koanf:"openai_api_key" env:"OPENAI_API_KEY"`
 	CustomPolicyDetectionTimeout  time.Duration `koanf:"custom_policy_detection_timeout" env:"CUSTOM_POLICY_DETECTION_TIMEOUT" envDefault:"10m"`
+	PiiDetector                   string        `koanf:"pii_detector" env:"PII_DETECTOR" envDefault:"amazon"`
 	AmazonRegion                  string        `koanf:"amazon_region" env:"AMAZON_REGION" envDefault:"us-west-2"`
 	AmazonRequestTimeout          time.Duration `koanf:"amazon_request_timeout" env:"AMAZON_REQUEST_TIMEOUT" envDefault:"5s"`
 	AmazonConnectionTimeout       time.Duration `koanf:"amazon_connection_timeout" env:"AMAZON_CONNECTION_TIMEOUT" envDefault:"10s"`
@@ -51,6 +52,16 @@ type Config struct {
 	DecryptionEndpoint            string        `koanf:"decryption_endpoint" env:"DECRYPTION_ENDPOINT"`
 	EncryptionTimeout             time.Duration `koanf:"encryption_timeout" env:"ENCRYPTION_TIMEOUT" envDefault:"5s"`
 	Audience                      string        `koanf:"audience" env:"AUDIENCE"`
+	WebhookDispatchInterval       time.Duration `koanf:"webhook_dispatch_interval" env:"WEBHOOK_DISPATCH_INTERVAL" envDefault:"5s"`
+	KeyRotationCheckInterval      time.Duration `koanf:"key_rotation_check_interval" env:"KEY_ROTATION_CHECK_INTERVAL" envDefault:"1m"`
+	TrustedProxies                string        `koanf:"trusted_proxies" env:"TRUSTED_PROXIES"`
+	JwtJwksFile                   string        `koanf:"jwt_jwks_file" env:"JWT_JWKS_FILE"`
+	JwtJwksUrl                    string        `koanf:"jwt_jwks_url" env:"JWT_JWKS_URL"`
+	JwtJwksRefreshInterval        time.Duration `koanf:"jwt_jwks_refresh_interval" env:"JWT_JWKS_REFRESH_INTERVAL" envDefault:"5m"`
+	JwtIssuer                     string        `koanf:"jwt_issuer" env:"JWT_ISSUER"`
+	JwtAudience                   string        `koanf:"jwt_audience" env:"JWT_AUDIENCE"`
+	JwtUserIdClaim                string        `koanf:"jwt_user_id_claim" env:"JWT_USER_ID_CLAIM" envDefault:"sub"`
+	JwtClaimMappings              string        `koanf:"jwt_claim_mappings" env:"JWT_CLAIM_MAPPINGS"`
 }
 
 func prepareDotEnv(envFilePath string) error {
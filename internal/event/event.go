@@ -26,10 +26,12 @@ type Event struct {
 	Response             []byte   `json:"response"`
 	UserId               string   `json:"userId"`
 	Action               string   `json:"action"`
+	ResponseAction       string   `json:"responseAction"`
 	PolicyId             string   `json:"policyId"`
 	RouteId              string   `json:"routeId"`
 	CorrelationId        string   `json:"correlationId"`
 	Metadata             []byte   `json:"metadata"`
+	RequestedModel       string   `json:"requestedModel"`
 }
 
 type EventResponse struct {
@@ -50,6 +52,7 @@ type EventRequest struct {
 	ResponseContent string   `json:"responseContent"`
 	PolicyIds       []string `json:"policyIds"`
 	Actions         []string `json:"actions"`
+	ResponseActions []string `json:"responseActions"`
 	CostOrder       string   `json:"costOrder"`
 	DateOrder       string   `json:"dateOrder"`
 	ReturnCount     bool     `json:"returnCount"`
@@ -122,10 +125,16 @@ func (r *EventRequest) Validate() error {
 	}
 
 	for _, a := range r.Actions {
-		if a != "warned" && a != "allowed" && a != "blocked" && a != "redacted" {
+		if a != "warned" && a != "allowed" && a != "blocked" && a != "redacted" && a != "ip_denied" {
 			return internal_errors.NewValidationError(fmt.Sprintf("action cannot be %s", a))
 		}
 	}
 
+	for _, a := range r.ResponseActions {
+		if a != "warned" && a != "allowed" && a != "blocked" && a != "redacted" {
+			return internal_errors.NewValidationError(fmt.Sprintf("response action cannot be %s", a))
+		}
+	}
+
 	return nil
 }
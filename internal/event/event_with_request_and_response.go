@@ -4,15 +4,29 @@ import (
 	"github.com/bricks-cloud/bricksllm/internal/key"
 	"github.com/bricks-cloud/bricksllm/internal/provider"
 	"github.com/bricks-cloud/bricksllm/internal/provider/custom"
+	"github.com/bricks-cloud/bricksllm/internal/user"
 )
 
 type EventWithRequestAndContent struct {
-	Event               *Event
-	IsEmbeddingsRequest bool
-	RouteConfig         *custom.RouteConfig
-	Request             interface{}
-	Content             string
-	Response            interface{}
-	Key                 *key.ResponseKey
-	CostMap             *provider.CostMap
+	Event                  *Event
+	IsEmbeddingsRequest    bool
+	RouteConfig            *custom.RouteConfig
+	Request                interface{}
+	Content                string
+	Response               interface{}
+	Key                    *key.ResponseKey
+	CostMap                *provider.CostMap
+	User                   *user.User
+	ReservedTokenCount     int
+	ReservedUserTokenCount int
+	// TokenWindow and UserTokenWindow identify the rate limit windows the tokens were
+	// reserved in.
+	TokenWindow      int64
+	UserTokenWindow  int64
+	SettingId        string
+	IsRetriedAttempt bool
+	// UpstreamCalled is set when the request reached the provider setting identified by
+	// SettingId, and UpstreamFailed when the provider failed to serve it.
+	UpstreamCalled bool
+	UpstreamFailed bool
 }
@@ -0,0 +1,423 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+	"go.uber.org/zap"
+)
+
+// leeway is the clock skew tolerated when checking exp and nbf.
+const leeway = 60 * time.Second
+
+// minRsaKeyBits is the smallest RSA modulus accepted for signing keys.
+const minRsaKeyBits = 2048
+
+// curves maps every supported ECDSA alg to the only curve it can be used with.
+var curves = map[string]elliptic.Curve{
+	"ES256": elliptic.P256(),
+	"ES384": elliptic.P384(),
+	"ES512": elliptic.P521(),
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jsonWebKeySet struct {
+	Keys []*jsonWebKey `json:"keys"`
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// signingKey is a public key along with the alg its JSON Web Key is restricted to.
+type signingKey struct {
+	pk  crypto.PublicKey
+	alg string
+}
+
+type Verifier struct {
+	file     string
+	url      string
+	issuer   string
+	audience string
+	client   http.Client
+	keys     map[string]*signingKey
+	lock     sync.RWMutex
+	done     chan bool
+	interval time.Duration
+	log      *zap.Logger
+}
+
+// NewVerifier loads a JSON Web Key Set from a file or a URL and verifies JWTs signed
+// by its keys. Issuer and audience are only checked when they are not empty.
+func NewVerifier(file, url, issuer, audience string, interval time.Duration, log *zap.Logger) (*Verifier, error) {
+	if len(file) == 0 && len(url) == 0 {
+		return nil, errors.New("either a jwks file or a jwks url is required")
+	}
+
+	v := &Verifier{
+		file:     file,
+		url:      url,
+		issuer:   issuer,
+		audience: audience,
+		client:   http.Client{Timeout: 10 * time.Second},
+		done:     make(chan bool),
+		interval: interval,
+		log:      log,
+	}
+
+	err := v.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+func (v *Verifier) readKeySet() ([]byte, error) {
+	if len(v.file) != 0 {
+		return os.ReadFile(v.file)
+	}
+
+	res, err := v.client.Get(v.url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks url responded with status code %d", res.StatusCode)
+	}
+
+	return io.ReadAll(res.Body)
+}
+
+func decodeInt(encoded string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(data), nil
+}
+
+func parseKey(jwk *jsonWebKey) (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+
+		if n.BitLen() < minRsaKeyBits {
+			return nil, fmt.Errorf("rsa key size %d is smaller than %d bits", n.BitLen(), minRsaKeyBits)
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("curve %s is not supported", jwk.Crv)
+		}
+
+		x, err := decodeInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point is not on curve %s", jwk.Crv)
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	return nil, fmt.Errorf("key type %s is not supported", jwk.Kty)
+}
+
+func (v *Verifier) load() error {
+	data, err := v.readKeySet()
+	if err != nil {
+		return err
+	}
+
+	set := &jsonWebKeySet{}
+	err = json.Unmarshal(data, set)
+	if err != nil {
+		return err
+	}
+
+	keys := map[string]*signingKey{}
+	for _, jwk := range set.Keys {
+		if len(jwk.Use) != 0 && jwk.Use != "sig" {
+			continue
+		}
+
+		parsed, err := parseKey(jwk)
+		if err != nil {
+			v.log.Sugar().Infof("skipping json web key %s: %v", jwk.Kid, err)
+			continue
+		}
+
+		keys[jwk.Kid] = &signingKey{pk: parsed, alg: jwk.Alg}
+	}
+
+	if len(keys) == 0 {
+		return errors.New("jwks does not contain any supported signing key")
+	}
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.keys = keys
+
+	return nil
+}
+
+func (v *Verifier) getKey(kid string) *signingKey {
+	v.lock.RLock()
+	defer v.lock.RUnlock()
+
+	if len(kid) == 0 && len(v.keys) == 1 {
+		for _, k := range v.keys {
+			return k
+		}
+	}
+
+	return v.keys[kid]
+}
+
+// verifySignature checks a signature with a key whose type, curve and size match alg.
+// A key is never used with an alg of a different family.
+func verifySignature(alg string, sk *signingKey, signed, signature []byte) error {
+	if len(sk.alg) != 0 && sk.alg != alg {
+		return fmt.Errorf("alg %s does not match the alg %s of the key", alg, sk.alg)
+	}
+
+	var hash crypto.Hash
+	switch alg[2:] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("alg %s is not supported", alg)
+	}
+
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch alg[:2] {
+	case "RS", "PS":
+		rpk, ok := sk.pk.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("alg %s does not match the key type", alg)
+		}
+
+		if rpk.N.BitLen() < minRsaKeyBits {
+			return fmt.Errorf("rsa key size %d is smaller than %d bits", rpk.N.BitLen(), minRsaKeyBits)
+		}
+
+		if alg[:2] == "PS" {
+			return rsa.VerifyPSS(rpk, hash, digest, signature, nil)
+		}
+
+		return rsa.VerifyPKCS1v15(rpk, hash, digest, signature)
+	case "ES":
+		epk, ok := sk.pk.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("alg %s does not match the key type", alg)
+		}
+
+		if epk.Curve != curves[alg] {
+			return fmt.Errorf("alg %s does not match the curve %s of the key", alg, epk.Curve.Params().Name)
+		}
+
+		size := (epk.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("signature length is not valid")
+		}
+
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(epk, digest, r, s) {
+			return errors.New("signature is not valid")
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("alg %s is not supported", alg)
+}
+
+func getNumericClaim(claims map[string]any, name string) (int64, bool) {
+	value, ok := claims[name].(float64)
+	if !ok {
+		return 0, false
+	}
+
+	return int64(value), true
+}
+
+func hasAudience(claims map[string]any, audience string) bool {
+	switch aud := claims["aud"].(type) {
+	case string:
+		return aud == audience
+	case []any:
+		for _, a := range aud {
+			if s, ok := a.(string); ok && s == audience {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// IsJwt reports whether a credential has the shape of a JWT rather than a static key.
+func IsJwt(token string) bool {
+	parts := strings.Split(token, ".")
+	return len(parts) == 3 && strings.HasPrefix(parts[0], "eyJ")
+}
+
+// Verify checks the signature, expiration, issuer and audience of a JWT and returns
+// its claims.
+func (v *Verifier) Verify(token string) (map[string]any, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, internal_errors.NewAuthError("jwt is malformed")
+	}
+
+	hb, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, internal_errors.NewAuthError("jwt header is malformed")
+	}
+
+	h := &header{}
+	if err := json.Unmarshal(hb, h); err != nil || len(h.Alg) != 5 {
+		return nil, internal_errors.NewAuthError("jwt header is malformed")
+	}
+
+	sk := v.getKey(h.Kid)
+	if sk == nil {
+		telemetry.Incr("bricksllm.jwt.verify.key_not_found", nil, 1)
+		return nil, internal_errors.NewAuthError(fmt.Sprintf("signing key %s is not found", h.Kid))
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, internal_errors.NewAuthError("jwt signature is malformed")
+	}
+
+	err = verifySignature(h.Alg, sk, []byte(parts[0]+"."+parts[1]), signature)
+	if err != nil {
+		return nil, internal_errors.NewAuthError("jwt signature is not valid: " + err.Error())
+	}
+
+	cb, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, internal_errors.NewAuthError("jwt claims are malformed")
+	}
+
+	claims := map[string]any{}
+	if err := json.Unmarshal(cb, &claims); err != nil {
+		return nil, internal_errors.NewAuthError("jwt claims are malformed")
+	}
+
+	now := time.Now()
+	exp, ok := getNumericClaim(claims, "exp")
+	if !ok {
+		return nil, internal_errors.NewAuthError("jwt does not have an expiration")
+	}
+
+	if now.Add(-leeway).Unix() >= exp {
+		return nil, internal_errors.NewAuthError("jwt is expired")
+	}
+
+	if nbf, ok := getNumericClaim(claims, "nbf"); ok && now.Add(leeway).Unix() < nbf {
+		return nil, internal_errors.NewAuthError("jwt is not valid yet")
+	}
+
+	if len(v.issuer) != 0 && claims["iss"] != v.issuer {
+		return nil, internal_errors.NewAuthError("jwt issuer is not valid")
+	}
+
+	if len(v.audience) != 0 && !hasAudience(claims, v.audience) {
+		return nil, internal_errors.NewAuthError("jwt audience is not valid")
+	}
+
+	return claims, nil
+}
+
+func (v *Verifier) Listen() {
+	ticker := time.NewTicker(v.interval)
+	v.log.Info("jwt verifier started refreshing json web keys")
+
+	go func() {
+		for {
+			select {
+			case <-v.done:
+				v.log.Info("jwt verifier stopped")
+				return
+			case <-ticker.C:
+				err := v.load()
+				if err != nil {
+					telemetry.Incr("bricksllm.jwt.listen.load_error", nil, 1)
+					v.log.Sugar().Infof("error refreshing json web keys: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+func (v *Verifier) Stop() {
+	v.log.Info("shutting down jwt verifier...")
+
+	v.done <- true
+}
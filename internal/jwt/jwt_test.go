@@ -0,0 +1,221 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func encodeJson(t *testing.T, v any) string {
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+
+	return encode(data)
+}
+
+func sign(t *testing.T, alg string, priv crypto.Signer, signed string) []byte {
+	var hash crypto.Hash
+	switch alg[2:] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	}
+
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	switch pk := priv.(type) {
+	case *rsa.PrivateKey:
+		if alg[:2] == "PS" {
+			signature, err := rsa.SignPSS(rand.Reader, pk, hash, digest, nil)
+			require.NoError(t, err)
+			return signature
+		}
+
+		signature, err := rsa.SignPKCS1v15(rand.Reader, pk, hash, digest)
+		require.NoError(t, err)
+		return signature
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, pk, digest)
+		require.NoError(t, err)
+
+		size := (pk.Curve.Params().BitSize + 7) / 8
+		signature := make([]byte, 2*size)
+		r.FillBytes(signature[:size])
+		s.FillBytes(signature[size:])
+		return signature
+	}
+
+	t.Fatalf("unsupported private key")
+	return nil
+}
+
+func newToken(t *testing.T, alg, kid string, priv crypto.Signer, claims map[string]any) string {
+	signed := encodeJson(t, map[string]string{"alg": alg, "kid": kid, "typ": "JWT"}) + "." + encodeJson(t, claims)
+	return signed + "." + encode(sign(t, alg, priv, signed))
+}
+
+func newVerifier(t *testing.T, keys []map[string]string) *Verifier {
+	file := filepath.Join(t.TempDir(), "jwks.json")
+
+	data, err := json.Marshal(map[string]any{"keys": keys})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(file, data, 0600))
+
+	v, err := NewVerifier(file, "", "https://issuer.example.com", "bricksllm", time.Minute, zap.NewNop())
+	require.NoError(t, err)
+
+	return v
+}
+
+func rsaJwk(kid, alg string, pk *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"alg": alg,
+		"n":   encode(pk.N.Bytes()),
+		"e":   encode(big.NewInt(int64(pk.E)).Bytes()),
+	}
+}
+
+func ecJwk(kid, crv string, pk *ecdsa.PublicKey) map[string]string {
+	size := (pk.Curve.Params().BitSize + 7) / 8
+	x := make([]byte, size)
+	y := make([]byte, size)
+	pk.X.FillBytes(x)
+	pk.Y.FillBytes(y)
+
+	return map[string]string{
+		"kty": "EC",
+		"kid": kid,
+		"crv": crv,
+		"x":   encode(x),
+		"y":   encode(y),
+	}
+}
+
+func TestVerify(t *testing.T) {
+	rk, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	ek, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	v := newVerifier(t, []map[string]string{
+		rsaJwk("rsa", "", &rk.PublicKey),
+		rsaJwk("rsa-rs256", "RS256", &rk.PublicKey),
+		ecJwk("ec", "P-256", &ek.PublicKey),
+	})
+
+	now := time.Now()
+	claims := func(overrides map[string]any) map[string]any {
+		c := map[string]any{
+			"sub": "user-1",
+			"iss": "https://issuer.example.com",
+			"aud": []string{"other", "bricksllm"},
+			"exp": now.Add(time.Hour).Unix(),
+			"nbf": now.Add(-time.Minute).Unix(),
+		}
+
+		for k, v := range overrides {
+			if v == nil {
+				delete(c, k)
+				continue
+			}
+
+			c[k] = v
+		}
+
+		return c
+	}
+
+	valid := newToken(t, "RS256", "rsa", rk, claims(nil))
+	parts := strings.Split(valid, ".")
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+	signature[0] ^= 1
+
+	cases := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"rs256", valid, true},
+		{"ps256", newToken(t, "PS256", "rsa", rk, claims(nil)), true},
+		{"es256", newToken(t, "ES256", "ec", ek, claims(nil)), true},
+		{"audience as string", newToken(t, "RS256", "rsa", rk, claims(map[string]any{"aud": "bricksllm"})), true},
+		{"expired", newToken(t, "RS256", "rsa", rk, claims(map[string]any{"exp": now.Add(-2 * leeway).Unix()})), false},
+		{"expired within leeway", newToken(t, "RS256", "rsa", rk, claims(map[string]any{"exp": now.Add(-leeway / 2).Unix()})), true},
+		{"missing expiration", newToken(t, "RS256", "rsa", rk, claims(map[string]any{"exp": nil})), false},
+		{"not yet valid", newToken(t, "RS256", "rsa", rk, claims(map[string]any{"nbf": now.Add(2 * leeway).Unix()})), false},
+		{"wrong issuer", newToken(t, "RS256", "rsa", rk, claims(map[string]any{"iss": "https://attacker.example.com"})), false},
+		{"wrong audience", newToken(t, "RS256", "rsa", rk, claims(map[string]any{"aud": "other"})), false},
+		{"tampered signature", parts[0] + "." + parts[1] + "." + encode(signature), false},
+		{"tampered claims", parts[0] + "." + encodeJson(t, claims(map[string]any{"sub": "admin"})) + "." + parts[2], false},
+		{"alg none", encodeJson(t, map[string]string{"alg": "none", "kid": "rsa"}) + "." + encodeJson(t, claims(nil)) + ".", false},
+		{"hmac alg", encodeJson(t, map[string]string{"alg": "HS256", "kid": "rsa"}) + "." + encodeJson(t, claims(nil)) + "." + encode([]byte("signature")), false},
+		{"ecdsa alg with rsa key", newToken(t, "ES256", "rsa", ek, claims(nil)), false},
+		{"rsa alg with ecdsa key", newToken(t, "RS256", "ec", rk, claims(nil)), false},
+		{"ecdsa alg with wrong curve", newToken(t, "ES384", "ec", ek, claims(nil)), false},
+		{"alg other than the alg of the key", newToken(t, "PS256", "rsa-rs256", rk, claims(nil)), false},
+		{"unknown key", newToken(t, "RS256", "unknown", rk, claims(nil)), false},
+	}
+
+	for _, tc := range cases {
+		parsed, err := v.Verify(tc.token)
+		if !tc.valid {
+			assert.Error(t, err, tc.name)
+			continue
+		}
+
+		if assert.NoError(t, err, tc.name) {
+			assert.Equal(t, "user-1", parsed["sub"], tc.name)
+		}
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	_, err = parseKey(&jsonWebKey{
+		Kty: "RSA",
+		N:   encode(small.N.Bytes()),
+		E:   encode(big.NewInt(int64(small.E)).Bytes()),
+	})
+	assert.Error(t, err, "rsa keys smaller than 2048 bits must be rejected")
+
+	ek, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	jwk := ecJwk("ec", "P-384", &ek.PublicKey)
+	_, err = parseKey(&jsonWebKey{Kty: "EC", Crv: jwk["crv"], X: jwk["x"], Y: jwk["y"]})
+	assert.Error(t, err, "points that are not on the curve of the key must be rejected")
+
+	jwk = ecJwk("ec", "P-256", &ek.PublicKey)
+	_, err = parseKey(&jsonWebKey{Kty: "EC", Crv: jwk["crv"], X: jwk["x"], Y: jwk["y"]})
+	assert.NoError(t, err)
+}
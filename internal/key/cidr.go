@@ -0,0 +1,54 @@
+package key
+
+import (
+	"fmt"
+	"net"
+	"sync"
+)
+
+// networks caches parsed allowed cidrs by their string form. Keys are decoded from the
+// keys cache on every request, so cidrs are parsed once per process rather than every
+// time a key is loaded. An invalid cidr is cached as nil.
+var networks sync.Map
+
+func validateCidrs(cidrs []string) []string {
+	invalid := []string{}
+	for index, cidr := range cidrs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			invalid = append(invalid, fmt.Sprintf("allowedCidrs.%d", index))
+		}
+	}
+
+	return invalid
+}
+
+func getNetwork(cidr string) *net.IPNet {
+	if cached, ok := networks.Load(cidr); ok {
+		return cached.(*net.IPNet)
+	}
+
+	_, network, _ := net.ParseCIDR(cidr)
+	networks.Store(cidr, network)
+	return network
+}
+
+// IsIpAllowed reports whether requests from an ip address can use the key. Every
+// address is allowed when the key does not have allowed cidrs.
+func (rk *ResponseKey) IsIpAllowed(ip string) bool {
+	if len(rk.AllowedCidrs) == 0 {
+		return true
+	}
+
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+
+	for _, cidr := range rk.AllowedCidrs {
+		if network := getNetwork(cidr); network != nil && network.Contains(parsed) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,33 @@
+package key
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsIpAllowed(t *testing.T) {
+	rk := &ResponseKey{AllowedCidrs: []string{"10.0.0.0/8", "192.168.1.10/32", "2001:db8::/32", "not-a-cidr"}}
+
+	cases := []struct {
+		ip      string
+		allowed bool
+	}{
+		{"10.1.2.3", true},
+		{"192.168.1.10", true},
+		{"192.168.1.11", false},
+		{"2001:db8::1", true},
+		{"2001:db9::1", false},
+		{"8.8.8.8", false},
+		{"", false},
+		{"not-an-ip", false},
+	}
+
+	for i := 0; i < 2; i++ {
+		for _, c := range cases {
+			assert.Equal(t, c.allowed, rk.IsIpAllowed(c.ip), c.ip)
+		}
+	}
+
+	assert.True(t, (&ResponseKey{}).IsIpAllowed("8.8.8.8"))
+}
@@ -12,25 +12,36 @@ import (
 const RevokedReasonExpired string = "expired"
 
 type UpdateKey struct {
-	Name                   string        `json:"name"`
-	UpdatedAt              int64         `json:"updatedAt"`
-	Tags                   []string      `json:"tags"`
-	Revoked                *bool         `json:"revoked"`
-	RevokedReason          string        `json:"revokedReason"`
-	Key                    string        `json:"key"`
-	SettingId              string        `json:"settingId"`
-	SettingIds             []string      `json:"settingIds"`
-	CostLimitInUsd         *float64      `json:"costLimitInUsd"`
-	CostLimitInUsdOverTime *float64      `json:"costLimitInUsdOverTime"`
-	CostLimitInUsdUnit     *TimeUnit     `json:"costLimitInUsdUnit"`
-	RateLimitOverTime      *int          `json:"rateLimitOverTime"`
-	RateLimitUnit          *TimeUnit     `json:"rateLimitUnit"`
-	AllowedPaths           *[]PathConfig `json:"allowedPaths,omitempty"`
-	ShouldLogRequest       *bool         `json:"shouldLogRequest"`
-	ShouldLogResponse      *bool         `json:"shouldLogResponse"`
-	RotationEnabled        *bool         `json:"rotationEnabled"`
-	PolicyId               *string       `json:"policyId"`
-	IsKeyNotHashed         *bool         `json:"isKeyNotHashed"`
+	Name                   string               `json:"name"`
+	UpdatedAt              int64                `json:"updatedAt"`
+	Tags                   []string             `json:"tags"`
+	Revoked                *bool                `json:"revoked"`
+	RevokedReason          string               `json:"revokedReason"`
+	Key                    string               `json:"key"`
+	SettingId              string               `json:"settingId"`
+	SettingIds             []string             `json:"settingIds"`
+	CostLimitInUsd         *float64             `json:"costLimitInUsd"`
+	CostLimitInUsdOverTime *float64             `json:"costLimitInUsdOverTime"`
+	CostLimitInUsdUnit     *TimeUnit            `json:"costLimitInUsdUnit"`
+	RateLimitOverTime      *int                 `json:"rateLimitOverTime"`
+	RateLimitUnit          *TimeUnit            `json:"rateLimitUnit"`
+	RateLimitAlgorithm     *RateLimitAlgorithm  `json:"rateLimitAlgorithm"`
+	RateLimitBurst         *int                 `json:"rateLimitBurst"`
+	TokenLimitOverTime     *int                 `json:"tokenLimitOverTime"`
+	TokenLimitUnit         *TimeUnit            `json:"tokenLimitUnit"`
+	AllowedPaths           *[]PathConfig        `json:"allowedPaths,omitempty"`
+	AllowedCidrs           *[]string            `json:"allowedCidrs,omitempty"`
+	ShouldLogRequest       *bool                `json:"shouldLogRequest"`
+	ShouldLogResponse      *bool                `json:"shouldLogResponse"`
+	RotationEnabled        *bool                `json:"rotationEnabled"`
+	PolicyId               *string              `json:"policyId"`
+	IsKeyNotHashed         *bool                `json:"isKeyNotHashed"`
+	CacheConfig            *CacheConfig         `json:"cacheConfig,omitempty"`
+	LoadBalancingConfig    *LoadBalancingConfig `json:"loadBalancingConfig,omitempty"`
+	RetryConfig            *RetryConfig         `json:"retryConfig,omitempty"`
+	RotationInterval       *string              `json:"rotationInterval"`
+	RotationGracePeriod    *string              `json:"rotationGracePeriod"`
+	NextRotationAt         *int64               `json:"-"`
 }
 
 func (uk *UpdateKey) Validate() error {
@@ -77,12 +88,36 @@ func (uk *UpdateKey) Validate() error {
 		}
 	}
 
+	if uk.AllowedCidrs != nil {
+		invalid = append(invalid, validateCidrs(*uk.AllowedCidrs)...)
+	}
+
 	if uk.PolicyId != nil {
 		if len(*uk.PolicyId) == 0 {
 			invalid = append(invalid, "policyId")
 		}
 	}
 
+	if uk.CacheConfig != nil && !uk.CacheConfig.isTtlValid() {
+		invalid = append(invalid, "cacheConfig.ttl")
+	}
+
+	if uk.LoadBalancingConfig != nil {
+		invalid = append(invalid, uk.LoadBalancingConfig.validate()...)
+	}
+
+	if uk.RetryConfig != nil {
+		invalid = append(invalid, uk.RetryConfig.validate()...)
+	}
+
+	if uk.RotationInterval != nil {
+		invalid = append(invalid, validateRotation(*uk.RotationInterval, "")...)
+	}
+
+	if uk.RotationGracePeriod != nil {
+		invalid = append(invalid, validateRotation("", *uk.RotationGracePeriod)...)
+	}
+
 	if len(invalid) > 0 {
 		return internal_errors.NewValidationError(fmt.Sprintf("fields [%s] are invalid", strings.Join(invalid, ", ")))
 	}
@@ -125,6 +160,28 @@ func (uk *UpdateKey) Validate() error {
 		}
 	}
 
+	if uk.RateLimitAlgorithm != nil && !isRateLimitAlgorithmValid(*uk.RateLimitAlgorithm) {
+		return internal_errors.NewValidationError("rate limit algorithm can not be identified")
+	}
+
+	if uk.RateLimitBurst != nil && *uk.RateLimitBurst < 0 {
+		return internal_errors.NewValidationError("rate limit burst can not be negative")
+	}
+
+	if uk.TokenLimitUnit != nil && uk.TokenLimitOverTime == nil {
+		return internal_errors.NewValidationError("token limit over time can not be empty if token limit unit is specified")
+	}
+
+	if uk.TokenLimitOverTime != nil {
+		if uk.TokenLimitUnit == nil {
+			return internal_errors.NewValidationError("token limit unit can not be empty if token limit over time is specified")
+		}
+
+		if err := ValidateTokenLimit(*uk.TokenLimitOverTime, *uk.TokenLimitUnit); err != nil {
+			return err
+		}
+	}
+
 	if uk.CostLimitInUsdOverTime != nil {
 		if uk.CostLimitInUsdUnit == nil {
 			return internal_errors.NewValidationError("cost limit unit can not be empty if cost limit over time is specified")
@@ -146,32 +203,232 @@ func (uk *UpdateKey) Validate() error {
 	return nil
 }
 
+const maxCacheTtl = time.Hour * 720
+
+type CacheConfig struct {
+	Enabled bool   `json:"enabled"`
+	Ttl     string `json:"ttl"`
+}
+
+func (cc *CacheConfig) isTtlValid() bool {
+	if len(cc.Ttl) == 0 {
+		return true
+	}
+
+	parsed, err := time.ParseDuration(cc.Ttl)
+	if err != nil {
+		return false
+	}
+
+	return parsed > 0 && parsed <= maxCacheTtl
+}
+
+type LoadBalancingStrategy string
+
+const (
+	RandomStrategy             LoadBalancingStrategy = "random"
+	WeightedRoundRobinStrategy LoadBalancingStrategy = "weighted_round_robin"
+	LeastLatencyStrategy       LoadBalancingStrategy = "least_latency"
+	LeastErrorsStrategy        LoadBalancingStrategy = "least_errors"
+)
+
+const (
+	defaultFailureThreshold = 5
+	defaultCooldown         = 30 * time.Second
+	maxCooldown             = time.Hour
+)
+
+type LoadBalancingConfig struct {
+	Strategy         LoadBalancingStrategy `json:"strategy"`
+	Weights          map[string]int        `json:"weights,omitempty"`
+	FailureThreshold int                   `json:"failureThreshold"`
+	Cooldown         string                `json:"cooldown"`
+}
+
+func (lbc *LoadBalancingConfig) validate() []string {
+	invalid := []string{}
+
+	if len(lbc.Strategy) != 0 && lbc.Strategy != RandomStrategy && lbc.Strategy != WeightedRoundRobinStrategy && lbc.Strategy != LeastLatencyStrategy && lbc.Strategy != LeastErrorsStrategy {
+		invalid = append(invalid, "loadBalancingConfig.strategy")
+	}
+
+	for _, weight := range lbc.Weights {
+		if weight < 0 {
+			invalid = append(invalid, "loadBalancingConfig.weights")
+			break
+		}
+	}
+
+	if lbc.FailureThreshold < 0 {
+		invalid = append(invalid, "loadBalancingConfig.failureThreshold")
+	}
+
+	if len(lbc.Cooldown) != 0 {
+		parsed, err := time.ParseDuration(lbc.Cooldown)
+		if err != nil || parsed <= 0 || parsed > maxCooldown {
+			invalid = append(invalid, "loadBalancingConfig.cooldown")
+		}
+	}
+
+	return invalid
+}
+
+// GetFailureThreshold returns the number of consecutive failures after which a
+// provider setting is ejected.
+func (lbc *LoadBalancingConfig) GetFailureThreshold() int {
+	if lbc == nil || lbc.FailureThreshold == 0 {
+		return defaultFailureThreshold
+	}
+
+	return lbc.FailureThreshold
+}
+
+func (lbc *LoadBalancingConfig) GetCooldown() time.Duration {
+	if lbc == nil || len(lbc.Cooldown) == 0 {
+		return defaultCooldown
+	}
+
+	parsed, err := time.ParseDuration(lbc.Cooldown)
+	if err != nil {
+		return defaultCooldown
+	}
+
+	return parsed
+}
+
+func (lbc *LoadBalancingConfig) GetWeight(settingId string) int {
+	if lbc == nil || lbc.Weights == nil {
+		return 1
+	}
+
+	weight, ok := lbc.Weights[settingId]
+	if !ok {
+		return 1
+	}
+
+	return weight
+}
+
+const (
+	maxRetryAttempts     = 10
+	defaultRetryInterval = time.Second
+	maxRetryInterval     = time.Minute
+)
+
+var defaultRetryStatusCodes = []int{429, 500, 502, 503, 504}
+
+type RetryConfig struct {
+	MaxAttempts int    `json:"maxAttempts"`
+	Strategy    string `json:"strategy"`
+	Interval    string `json:"interval"`
+	StatusCodes []int  `json:"statusCodes,omitempty"`
+	Failover    bool   `json:"failover"`
+}
+
+func (rc *RetryConfig) validate() []string {
+	invalid := []string{}
+
+	if rc.MaxAttempts < 0 || rc.MaxAttempts > maxRetryAttempts {
+		invalid = append(invalid, "retryConfig.maxAttempts")
+	}
+
+	if len(rc.Strategy) != 0 && rc.Strategy != "constant" && rc.Strategy != "exponential" {
+		invalid = append(invalid, "retryConfig.strategy")
+	}
+
+	if len(rc.Interval) != 0 {
+		parsed, err := time.ParseDuration(rc.Interval)
+		if err != nil || parsed <= 0 || parsed > maxRetryInterval {
+			invalid = append(invalid, "retryConfig.interval")
+		}
+	}
+
+	for _, code := range rc.StatusCodes {
+		if code < 400 || code > 599 {
+			invalid = append(invalid, "retryConfig.statusCodes")
+			break
+		}
+	}
+
+	return invalid
+}
+
+// GetMaxAttempts returns the total number of attempts including the first one.
+func (rc *RetryConfig) GetMaxAttempts() int {
+	if rc == nil || rc.MaxAttempts == 0 {
+		return 1
+	}
+
+	return rc.MaxAttempts
+}
+
+func (rc *RetryConfig) GetInterval() time.Duration {
+	if rc == nil || len(rc.Interval) == 0 {
+		return defaultRetryInterval
+	}
+
+	parsed, err := time.ParseDuration(rc.Interval)
+	if err != nil {
+		return defaultRetryInterval
+	}
+
+	return parsed
+}
+
+func (rc *RetryConfig) ShouldRetry(status int) bool {
+	codes := defaultRetryStatusCodes
+	if rc != nil && len(rc.StatusCodes) != 0 {
+		codes = rc.StatusCodes
+	}
+
+	for _, code := range codes {
+		if code == status {
+			return true
+		}
+	}
+
+	return false
+}
+
 type PathConfig struct {
 	Method string `json:"method"`
 	Path   string `json:"path"`
 }
 
 type RequestKey struct {
-	Name                   string       `json:"name"`
-	CreatedAt              int64        `json:"createdAt"`
-	UpdatedAt              int64        `json:"updatedAt"`
-	Tags                   []string     `json:"tags"`
-	KeyId                  string       `json:"keyId"`
-	Key                    string       `json:"key"`
-	CostLimitInUsd         float64      `json:"costLimitInUsd"`
-	CostLimitInUsdOverTime float64      `json:"costLimitInUsdOverTime"`
-	CostLimitInUsdUnit     TimeUnit     `json:"costLimitInUsdUnit"`
-	RateLimitOverTime      int          `json:"rateLimitOverTime"`
-	RateLimitUnit          TimeUnit     `json:"rateLimitUnit"`
-	Ttl                    string       `json:"ttl"`
-	SettingId              string       `json:"settingId"`
-	AllowedPaths           []PathConfig `json:"allowedPaths"`
-	SettingIds             []string     `json:"settingIds"`
-	ShouldLogRequest       bool         `json:"shouldLogRequest"`
-	ShouldLogResponse      bool         `json:"shouldLogResponse"`
-	RotationEnabled        bool         `json:"rotationEnabled"`
-	PolicyId               string       `json:"policyId"`
-	IsKeyNotHashed         bool         `json:"isKeyNotHashed"`
+	Name                   string               `json:"name"`
+	CreatedAt              int64                `json:"createdAt"`
+	UpdatedAt              int64                `json:"updatedAt"`
+	Tags                   []string             `json:"tags"`
+	KeyId                  string               `json:"keyId"`
+	Key                    string               `json:"key"`
+	CostLimitInUsd         float64              `json:"costLimitInUsd"`
+	CostLimitInUsdOverTime float64              `json:"costLimitInUsdOverTime"`
+	CostLimitInUsdUnit     TimeUnit             `json:"costLimitInUsdUnit"`
+	RateLimitOverTime      int                  `json:"rateLimitOverTime"`
+	RateLimitUnit          TimeUnit             `json:"rateLimitUnit"`
+	RateLimitAlgorithm     RateLimitAlgorithm   `json:"rateLimitAlgorithm"`
+	RateLimitBurst         int                  `json:"rateLimitBurst"`
+	TokenLimitOverTime     int                  `json:"tokenLimitOverTime"`
+	TokenLimitUnit         TimeUnit             `json:"tokenLimitUnit"`
+	Ttl                    string               `json:"ttl"`
+	SettingId              string               `json:"settingId"`
+	AllowedPaths           []PathConfig         `json:"allowedPaths"`
+	AllowedCidrs           []string             `json:"allowedCidrs"`
+	SettingIds             []string             `json:"settingIds"`
+	ShouldLogRequest       bool                 `json:"shouldLogRequest"`
+	ShouldLogResponse      bool                 `json:"shouldLogResponse"`
+	RotationEnabled        bool                 `json:"rotationEnabled"`
+	PolicyId               string               `json:"policyId"`
+	IsKeyNotHashed         bool                 `json:"isKeyNotHashed"`
+	CacheConfig            *CacheConfig         `json:"cacheConfig,omitempty"`
+	LoadBalancingConfig    *LoadBalancingConfig `json:"loadBalancingConfig,omitempty"`
+	RetryConfig            *RetryConfig         `json:"retryConfig,omitempty"`
+	// RotationInterval is how often the secret of the key is rotated automatically.
+	// The key is not rotated on a schedule when it is empty.
+	RotationInterval    string `json:"rotationInterval"`
+	RotationGracePeriod string `json:"rotationGracePeriod"`
+	NextRotationAt      int64  `json:"-"`
 }
 
 func (rk *RequestKey) Validate() error {
@@ -239,6 +496,21 @@ func (rk *RequestKey) Validate() error {
 		}
 	}
 
+	if rk.CacheConfig != nil && !rk.CacheConfig.isTtlValid() {
+		invalid = append(invalid, "cacheConfig.ttl")
+	}
+
+	if rk.LoadBalancingConfig != nil {
+		invalid = append(invalid, rk.LoadBalancingConfig.validate()...)
+	}
+
+	if rk.RetryConfig != nil {
+		invalid = append(invalid, rk.RetryConfig.validate()...)
+	}
+
+	invalid = append(invalid, validateRotation(rk.RotationInterval, rk.RotationGracePeriod)...)
+	invalid = append(invalid, validateCidrs(rk.AllowedCidrs)...)
+
 	if len(rk.AllowedPaths) != 0 {
 		for index, p := range rk.AllowedPaths {
 			if len(p.Path) == 0 {
@@ -275,6 +547,18 @@ func (rk *RequestKey) Validate() error {
 		}
 	}
 
+	if !isRateLimitAlgorithmValid(rk.RateLimitAlgorithm) {
+		return internal_errors.NewValidationError("rate limit algorithm can not be identified")
+	}
+
+	if rk.RateLimitBurst < 0 {
+		return internal_errors.NewValidationError("rate limit burst can not be negative")
+	}
+
+	if err := ValidateTokenLimit(rk.TokenLimitOverTime, rk.TokenLimitUnit); err != nil {
+		return err
+	}
+
 	if rk.CostLimitInUsdOverTime != 0 {
 		if len(rk.CostLimitInUsdUnit) == 0 {
 			return internal_errors.NewValidationError("cost limit unit can not be empty if cost limit over time is specified")
@@ -288,6 +572,34 @@ func (rk *RequestKey) Validate() error {
 	return nil
 }
 
+type RateLimitAlgorithm string
+
+const (
+	FixedWindowAlgorithm   RateLimitAlgorithm = "fixed"
+	SlidingWindowAlgorithm RateLimitAlgorithm = "sliding"
+	TokenBucketAlgorithm   RateLimitAlgorithm = "token_bucket"
+)
+
+func isRateLimitAlgorithmValid(algorithm RateLimitAlgorithm) bool {
+	return len(algorithm) == 0 || algorithm == FixedWindowAlgorithm || algorithm == SlidingWindowAlgorithm || algorithm == TokenBucketAlgorithm
+}
+
+func ValidateTokenLimit(tokenLimitOverTime int, tokenLimitUnit TimeUnit) error {
+	if tokenLimitOverTime < 0 {
+		return internal_errors.NewValidationError("token limit over time can not be negative")
+	}
+
+	if len(tokenLimitUnit) != 0 && tokenLimitOverTime == 0 {
+		return internal_errors.NewValidationError("token limit over time can not be empty if token limit unit is specified")
+	}
+
+	if tokenLimitOverTime != 0 && tokenLimitUnit != MinuteTimeUnit && tokenLimitUnit != HourTimeUnit && tokenLimitUnit != DayTimeUnit {
+		return internal_errors.NewValidationError("token limit unit can not be identified")
+	}
+
+	return nil
+}
+
 type TimeUnit string
 
 const (
@@ -299,28 +611,51 @@ const (
 )
 
 type ResponseKey struct {
-	Name                   string       `json:"name"`
-	CreatedAt              int64        `json:"createdAt"`
-	UpdatedAt              int64        `json:"updatedAt"`
-	Tags                   []string     `json:"tags"`
-	KeyId                  string       `json:"keyId"`
-	Revoked                bool         `json:"revoked"`
-	Key                    string       `json:"key"`
-	RevokedReason          string       `json:"revokedReason"`
-	CostLimitInUsd         float64      `json:"costLimitInUsd"`
-	CostLimitInUsdOverTime float64      `json:"costLimitInUsdOverTime"`
-	CostLimitInUsdUnit     TimeUnit     `json:"costLimitInUsdUnit"`
-	RateLimitOverTime      int          `json:"rateLimitOverTime"`
-	RateLimitUnit          TimeUnit     `json:"rateLimitUnit"`
-	Ttl                    string       `json:"ttl"`
-	SettingId              string       `json:"settingId"`
-	AllowedPaths           []PathConfig `json:"allowedPaths"`
-	SettingIds             []string     `json:"settingIds"`
-	ShouldLogRequest       bool         `json:"shouldLogRequest"`
-	ShouldLogResponse      bool         `json:"shouldLogResponse"`
-	RotationEnabled        bool         `json:"rotationEnabled"`
-	PolicyId               string       `json:"policyId"`
-	IsKeyNotHashed         bool         `json:"isKeyNotHashed"`
+	Name                   string               `json:"name"`
+	CreatedAt              int64                `json:"createdAt"`
+	UpdatedAt              int64                `json:"updatedAt"`
+	Tags                   []string             `json:"tags"`
+	KeyId                  string               `json:"keyId"`
+	Revoked                bool                 `json:"revoked"`
+	Key                    string               `json:"key"`
+	RevokedReason          string               `json:"revokedReason"`
+	CostLimitInUsd         float64              `json:"costLimitInUsd"`
+	CostLimitInUsdOverTime float64              `json:"costLimitInUsdOverTime"`
+	CostLimitInUsdUnit     TimeUnit             `json:"costLimitInUsdUnit"`
+	RateLimitOverTime      int                  `json:"rateLimitOverTime"`
+	RateLimitUnit          TimeUnit             `json:"rateLimitUnit"`
+	RateLimitAlgorithm     RateLimitAlgorithm   `json:"rateLimitAlgorithm"`
+	RateLimitBurst         int                  `json:"rateLimitBurst"`
+	TokenLimitOverTime     int                  `json:"tokenLimitOverTime"`
+	TokenLimitUnit         TimeUnit             `json:"tokenLimitUnit"`
+	Ttl                    string               `json:"ttl"`
+	SettingId              string               `json:"settingId"`
+	AllowedPaths           []PathConfig         `json:"allowedPaths"`
+	AllowedCidrs           []string             `json:"allowedCidrs"`
+	SettingIds             []string             `json:"settingIds"`
+	ShouldLogRequest       bool                 `json:"shouldLogRequest"`
+	ShouldLogResponse      bool                 `json:"shouldLogResponse"`
+	RotationEnabled        bool                 `json:"rotationEnabled"`
+	PolicyId               string               `json:"policyId"`
+	IsKeyNotHashed         bool                 `json:"isKeyNotHashed"`
+	CacheConfig            *CacheConfig         `json:"cacheConfig,omitempty"`
+	LoadBalancingConfig    *LoadBalancingConfig `json:"loadBalancingConfig,omitempty"`
+	RetryConfig            *RetryConfig         `json:"retryConfig,omitempty"`
+	RotationInterval       string               `json:"rotationInterval"`
+	RotationGracePeriod    string               `json:"rotationGracePeriod"`
+	NextRotationAt         int64                `json:"nextRotationAt"`
+	LastRotatedAt          int64                `json:"lastRotatedAt"`
+	// PreviousKey is the hash of the secret the key had before its last rotation. It
+	// authenticates until PreviousKeyExpiresAt.
+	PreviousKey          string `json:"previousKey,omitempty"`
+	PreviousKeyExpiresAt int64  `json:"previousKeyExpiresAt,omitempty"`
+}
+
+// IsRateLimitedInFixedWindow reports whether the key's request rate limit is
+// counted asynchronously in calendar aligned buckets rather than enforced
+// atomically per request.
+func (rk *ResponseKey) IsRateLimitedInFixedWindow() bool {
+	return len(rk.RateLimitAlgorithm) == 0 || rk.RateLimitAlgorithm == FixedWindowAlgorithm
 }
 
 func (rk *ResponseKey) GetSettingIds() []string {
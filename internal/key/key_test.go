@@ -0,0 +1,51 @@
+package key
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheConfig_IsTtlValid(t *testing.T) {
+	cases := []struct {
+		ttl   string
+		valid bool
+	}{
+		{"", true},
+		{"1h", true},
+		{"720h", true},
+		{"721h", false},
+		{"0s", false},
+		{"-1m", false},
+		{"tomorrow", false},
+	}
+
+	for _, c := range cases {
+		cc := &CacheConfig{Enabled: true, Ttl: c.ttl}
+		assert.Equal(t, c.valid, cc.isTtlValid(), c.ttl)
+	}
+}
+
+func TestUpdateKey_ValidateRateLimitAlgorithm(t *testing.T) {
+	sliding := SlidingWindowAlgorithm
+	unknown := RateLimitAlgorithm("leaky_bucket")
+	burst := 5
+	negative := -1
+
+	cases := []struct {
+		name      string
+		algorithm *RateLimitAlgorithm
+		burst     *int
+		valid     bool
+	}{
+		{"empty", nil, nil, true},
+		{"sliding window", &sliding, &burst, true},
+		{"unknown algorithm", &unknown, nil, false},
+		{"negative burst", &sliding, &negative, false},
+	}
+
+	for _, c := range cases {
+		uk := &UpdateKey{UpdatedAt: 1, RateLimitAlgorithm: c.algorithm, RateLimitBurst: c.burst}
+		assert.Equal(t, c.valid, uk.Validate() == nil, c.name)
+	}
+}
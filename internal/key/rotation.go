@@ -0,0 +1,103 @@
+package key
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+)
+
+// DefaultRotationGracePeriod is how long the previous secret of a rotated key keeps
+// authenticating when the key does not set a grace period.
+const DefaultRotationGracePeriod = 24 * time.Hour
+
+// Rotation is the new state of a key after its secret is rotated.
+type Rotation struct {
+	Key string
+	// PreviousKey is the hash of the current secret of a key stored in its raw form so
+	// that a raw secret is never kept as the previous one.
+	PreviousKey          string
+	RotatedAt            int64
+	PreviousKeyExpiresAt int64
+	NextRotationAt       int64
+}
+
+// RotatedKey is returned once when a key is rotated. Secret is the only copy of the new
+// secret since only its hash is stored.
+type RotatedKey struct {
+	ResponseKey
+	Secret string `json:"secret"`
+}
+
+func parseRotationDuration(raw string) (time.Duration, bool) {
+	if len(raw) == 0 {
+		return 0, true
+	}
+
+	parsed, err := time.ParseDuration(raw)
+	if err != nil || parsed <= 0 {
+		return 0, false
+	}
+
+	return parsed, true
+}
+
+func validateRotation(interval, gracePeriod string) []string {
+	invalid := []string{}
+
+	parsedInterval, ok := parseRotationDuration(interval)
+	if !ok {
+		invalid = append(invalid, "rotationInterval")
+	}
+
+	parsedGracePeriod, ok := parseRotationDuration(gracePeriod)
+	if !ok {
+		invalid = append(invalid, "rotationGracePeriod")
+	}
+
+	if len(invalid) == 0 && parsedInterval != 0 && parsedGracePeriod >= parsedInterval {
+		invalid = append(invalid, "rotationGracePeriod")
+	}
+
+	return invalid
+}
+
+// ValidateRotation checks the rotation interval and grace period that a key ends up
+// with after an update.
+func ValidateRotation(interval, gracePeriod string) error {
+	invalid := validateRotation(interval, gracePeriod)
+	if len(invalid) != 0 {
+		return internal_errors.NewValidationError(fmt.Sprintf("fields [%s] are invalid", strings.Join(invalid, ", ")))
+	}
+
+	return nil
+}
+
+// GetRotationGracePeriod returns the grace period of the key or the default one when
+// it is not set.
+func (rk *ResponseKey) GetRotationGracePeriod() time.Duration {
+	parsed, ok := parseRotationDuration(rk.RotationGracePeriod)
+	if !ok || parsed == 0 {
+		return DefaultRotationGracePeriod
+	}
+
+	return parsed
+}
+
+// GetNextRotationAt returns when a key rotated at the given time is due for rotation
+// again. It is 0 when the key is not rotated on a schedule.
+func GetNextRotationAt(interval string, from time.Time) int64 {
+	parsed, ok := parseRotationDuration(interval)
+	if !ok || parsed == 0 {
+		return 0
+	}
+
+	return from.Add(parsed).Unix()
+}
+
+// IsPreviousKeyValid reports whether a hash is the previous secret of the key and is
+// still within its grace period.
+func (rk *ResponseKey) IsPreviousKeyValid(hash string, now time.Time) bool {
+	return len(rk.PreviousKey) != 0 && rk.PreviousKey == hash && now.Unix() < rk.PreviousKeyExpiresAt
+}
@@ -0,0 +1,201 @@
+package manager
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/alert"
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+	"github.com/bricks-cloud/bricksllm/internal/util"
+)
+
+type WebhooksStorage interface {
+	CreateWebhook(w *alert.Webhook) (*alert.Webhook, error)
+	UpdateWebhook(id string, uw *alert.UpdateWebhook) (*alert.Webhook, error)
+	DeleteWebhook(id string, updatedAt int64) error
+	GetWebhook(id string) (*alert.Webhook, error)
+	GetWebhooks() ([]*alert.Webhook, error)
+	CreateDelivery(d *alert.Delivery) (bool, error)
+	GetDeliveries(filter *alert.DeliveryFilter) ([]*alert.Delivery, error)
+}
+
+type deliverer interface {
+	Deliver(w *alert.Webhook, d *alert.Delivery) *alert.Delivery
+}
+
+type WebhookManager struct {
+	Storage WebhooksStorage
+	d       deliverer
+}
+
+func NewWebhookManager(s WebhooksStorage, d deliverer) *WebhookManager {
+	return &WebhookManager{
+		Storage: s,
+		d:       d,
+	}
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 24)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+func redactWebhook(w *alert.Webhook) *alert.Webhook {
+	if w != nil {
+		w.Secret = ""
+	}
+
+	return w
+}
+
+// CreateWebhook generates a signing secret when none is given. The secret is only
+// returned in the response of this call.
+func (m *WebhookManager) CreateWebhook(w *alert.Webhook) (*alert.Webhook, error) {
+	if w != nil && len(w.Format) == 0 {
+		w.Format = alert.JsonFormat
+	}
+
+	if w != nil && len(w.Thresholds) == 0 {
+		w.Thresholds = alert.DefaultThresholds
+	}
+
+	if w != nil && w.Tags == nil {
+		w.Tags = []string{}
+	}
+
+	err := w.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(w.Secret) == 0 {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+
+		w.Secret = secret
+	}
+
+	w.CreatedAt = time.Now().Unix()
+	w.UpdatedAt = time.Now().Unix()
+	w.Id = util.NewUuid()
+
+	return m.Storage.CreateWebhook(w)
+}
+
+func (m *WebhookManager) UpdateWebhook(id string, uw *alert.UpdateWebhook) (*alert.Webhook, error) {
+	err := uw.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	if uw.Thresholds != nil && len(uw.Thresholds) == 0 {
+		return nil, internal_errors.NewValidationError("thresholds cannot be empty")
+	}
+
+	uw.UpdatedAt = time.Now().Unix()
+
+	updated, err := m.Storage.UpdateWebhook(id, uw)
+	if err != nil {
+		return nil, err
+	}
+
+	return redactWebhook(updated), nil
+}
+
+func (m *WebhookManager) DeleteWebhook(id string) error {
+	return m.Storage.DeleteWebhook(id, time.Now().Unix())
+}
+
+func (m *WebhookManager) GetWebhook(id string) (*alert.Webhook, error) {
+	w, err := m.Storage.GetWebhook(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return redactWebhook(w), nil
+}
+
+func (m *WebhookManager) GetWebhooks() ([]*alert.Webhook, error) {
+	webhooks, err := m.Storage.GetWebhooks()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, w := range webhooks {
+		redactWebhook(w)
+	}
+
+	return webhooks, nil
+}
+
+func (m *WebhookManager) GetDeliveries(filter *alert.DeliveryFilter) ([]*alert.Delivery, error) {
+	_, err := m.Storage.GetWebhook(filter.WebhookId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(filter.Status) != 0 && filter.Status != alert.DeliveryStatusPending && filter.Status != alert.DeliveryStatusSucceeded && filter.Status != alert.DeliveryStatusFailed {
+		return nil, internal_errors.NewValidationError("status " + string(filter.Status) + " is not supported")
+	}
+
+	deliveries, err := m.Storage.GetDeliveries(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range deliveries {
+		d.Payload = alert.RedactPayload(d.Payload)
+	}
+
+	return deliveries, nil
+}
+
+// TestWebhook sends a test event to a webhook right away and returns the delivery. A
+// failed test delivery is retried like any other delivery.
+func (m *WebhookManager) TestWebhook(id string) (*alert.Delivery, error) {
+	w, err := m.Storage.GetWebhook(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	e := &alert.Event{
+		Id:        util.NewUuid(),
+		Type:      alert.EventTypeTest,
+		CreatedAt: now.Unix(),
+	}
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &alert.Delivery{
+		Id:        e.Id,
+		CreatedAt: now.Unix(),
+		UpdatedAt: now.Unix(),
+		WebhookId: w.Id,
+		EventType: e.Type,
+		DedupeKey: e.Id,
+		Payload:   payload,
+		Status:    alert.DeliveryStatusPending,
+		// Keeps the dispatcher from picking up the delivery while it is sent here.
+		NextAttemptAt: now.Add(time.Minute).Unix(),
+	}
+
+	_, err = m.Storage.CreateDelivery(d)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.d.Deliver(w, d), nil
+}
@@ -0,0 +1,118 @@
+package manager
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bricks-cloud/bricksllm/internal/alert"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeWebhooksStorage struct {
+	webhooks   map[string]*alert.Webhook
+	deliveries []*alert.Delivery
+}
+
+func (s *fakeWebhooksStorage) CreateWebhook(w *alert.Webhook) (*alert.Webhook, error) {
+	stored := *w
+	s.webhooks[w.Id] = &stored
+	return w, nil
+}
+
+func (s *fakeWebhooksStorage) UpdateWebhook(id string, uw *alert.UpdateWebhook) (*alert.Webhook, error) {
+	stored := *s.webhooks[id]
+	return &stored, nil
+}
+
+func (s *fakeWebhooksStorage) DeleteWebhook(id string, updatedAt int64) error {
+	delete(s.webhooks, id)
+	return nil
+}
+
+func (s *fakeWebhooksStorage) GetWebhook(id string) (*alert.Webhook, error) {
+	stored := *s.webhooks[id]
+	return &stored, nil
+}
+
+func (s *fakeWebhooksStorage) GetWebhooks() ([]*alert.Webhook, error) {
+	webhooks := []*alert.Webhook{}
+	for _, w := range s.webhooks {
+		stored := *w
+		webhooks = append(webhooks, &stored)
+	}
+
+	return webhooks, nil
+}
+
+func (s *fakeWebhooksStorage) CreateDelivery(d *alert.Delivery) (bool, error) {
+	s.deliveries = append(s.deliveries, d)
+	return true, nil
+}
+
+func (s *fakeWebhooksStorage) GetDeliveries(filter *alert.DeliveryFilter) ([]*alert.Delivery, error) {
+	return s.deliveries, nil
+}
+
+type fakeDeliverer struct{}
+
+func (d *fakeDeliverer) Deliver(w *alert.Webhook, delivery *alert.Delivery) *alert.Delivery {
+	delivery.Attempts += 1
+	delivery.Status = alert.DeliveryStatusSucceeded
+	return delivery
+}
+
+func TestWebhookManager_SecretIsOnlyReturnedOnCreation(t *testing.T) {
+	s := &fakeWebhooksStorage{webhooks: map[string]*alert.Webhook{}}
+	m := NewWebhookManager(s, &fakeDeliverer{})
+
+	created, err := m.CreateWebhook(&alert.Webhook{Name: "ops", Url: "https://example.com/hook"})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(created.Secret, "whsec_"))
+	assert.Equal(t, alert.JsonFormat, created.Format)
+	assert.Equal(t, alert.DefaultThresholds, created.Thresholds)
+	assert.Equal(t, []string{}, created.Tags)
+
+	w, err := m.GetWebhook(created.Id)
+	require.NoError(t, err)
+	assert.Empty(t, w.Secret)
+
+	webhooks, err := m.GetWebhooks()
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+	assert.Empty(t, webhooks[0].Secret)
+
+	updated, err := m.UpdateWebhook(created.Id, &alert.UpdateWebhook{Name: "ops-2"})
+	require.NoError(t, err)
+	assert.Empty(t, updated.Secret)
+
+	_, err = m.UpdateWebhook(created.Id, &alert.UpdateWebhook{Thresholds: []float64{}})
+	assert.Error(t, err)
+
+	_, err = m.CreateWebhook(&alert.Webhook{Name: "ops"})
+	assert.Error(t, err)
+}
+
+func TestWebhookManager_GetDeliveries(t *testing.T) {
+	s := &fakeWebhooksStorage{
+		webhooks: map[string]*alert.Webhook{"w": {Id: "w"}},
+		deliveries: []*alert.Delivery{
+			{Id: "d", Payload: []byte(`{"type": "key.rotated", "key": {"keyId": "key-1", "secret": "new-secret"}}`)},
+		},
+	}
+	m := NewWebhookManager(s, &fakeDeliverer{})
+
+	deliveries, err := m.GetDeliveries(&alert.DeliveryFilter{WebhookId: "w"})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.NotContains(t, string(deliveries[0].Payload), "new-secret")
+
+	_, err = m.GetDeliveries(&alert.DeliveryFilter{WebhookId: "w", Status: "unknown"})
+	assert.Error(t, err)
+
+	d, err := m.TestWebhook("w")
+	require.NoError(t, err)
+	assert.Equal(t, alert.EventTypeTest, d.EventType)
+	assert.Equal(t, alert.DeliveryStatusSucceeded, d.Status)
+	assert.Len(t, s.deliveries, 2)
+}
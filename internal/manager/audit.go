@@ -0,0 +1,122 @@
+package manager
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/audit"
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+	"github.com/bricks-cloud/bricksllm/internal/util"
+)
+
+const (
+	defaultAuditLogsLimit = 100
+	maxAuditLogsLimit     = 1000
+	auditLogsVerifyBatch  = 500
+)
+
+type AuditLogsStorage interface {
+	AppendAuditLog(e *audit.Entry) (*audit.Entry, error)
+	GetAuditLogs(filter *audit.Filter) ([]*audit.Entry, error)
+	GetAuditLogsAfter(sequence int64, limit int) ([]*audit.Entry, error)
+}
+
+type AuditManager struct {
+	Storage AuditLogsStorage
+}
+
+func NewAuditManager(s AuditLogsStorage) *AuditManager {
+	return &AuditManager{
+		Storage: s,
+	}
+}
+
+func (m *AuditManager) RecordAuditLog(e *audit.Entry) (*audit.Entry, error) {
+	e.Id = util.NewUuid()
+	e.CreatedAt = time.Now().Unix()
+
+	return m.Storage.AppendAuditLog(e)
+}
+
+func (m *AuditManager) GetAuditLogs(filter *audit.Filter) ([]*audit.Entry, error) {
+	if len(filter.Action) != 0 && filter.Action != audit.ActionCreate && filter.Action != audit.ActionUpdate && filter.Action != audit.ActionDelete && filter.Action != audit.ActionRotate {
+		return nil, internal_errors.NewValidationError(fmt.Sprintf("action %s is not supported", filter.Action))
+	}
+
+	if filter.Limit < 0 || filter.Offset < 0 {
+		return nil, internal_errors.NewValidationError("limit and offset cannot be negative")
+	}
+
+	if filter.Limit == 0 {
+		filter.Limit = defaultAuditLogsLimit
+	}
+
+	if filter.Limit > maxAuditLogsLimit {
+		filter.Limit = maxAuditLogsLimit
+	}
+
+	return m.Storage.GetAuditLogs(filter)
+}
+
+func setUnresolvedSequences(v *audit.Verification, pending map[string]int64) *audit.Verification {
+	for _, sequence := range pending {
+		v.UnresolvedSequences = append(v.UnresolvedSequences, sequence)
+	}
+
+	sort.Slice(v.UnresolvedSequences, func(i, j int) bool {
+		return v.UnresolvedSequences[i] < v.UnresolvedSequences[j]
+	})
+
+	return v
+}
+
+// VerifyAuditLogs walks the audit log in chain order and recomputes the hash of every
+// entry. Verification stops at the first entry that does not match its predecessor.
+// Pending entries without an outcome are reported as unresolved.
+func (m *AuditManager) VerifyAuditLogs() (*audit.Verification, error) {
+	v := &audit.Verification{
+		Valid: true,
+	}
+
+	pending := map[string]int64{}
+
+	for {
+		entries, err := m.Storage.GetAuditLogsAfter(v.LastSequence, auditLogsVerifyBatch)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, e := range entries {
+			reason := ""
+			if e.Sequence != v.LastSequence+1 {
+				reason = fmt.Sprintf("expected sequence %d", v.LastSequence+1)
+			} else if e.PrevHash != v.LastHash {
+				reason = "previous hash does not match the hash of the previous entry"
+			} else if e.ComputeHash() != e.Hash {
+				reason = "hash does not match the content of the entry"
+			}
+
+			if len(reason) != 0 {
+				v.Valid = false
+				v.BrokenAtSequence = e.Sequence
+				v.Reason = reason
+				return setUnresolvedSequences(v, pending), nil
+			}
+
+			if e.Status == audit.StatusPending {
+				pending[e.Id] = e.Sequence
+			} else if len(e.IntentId) != 0 {
+				delete(pending, e.IntentId)
+			}
+
+			v.EntriesChecked += 1
+			v.LastSequence = e.Sequence
+			v.LastHash = e.Hash
+		}
+
+		if len(entries) < auditLogsVerifyBatch {
+			return setUnresolvedSequences(v, pending), nil
+		}
+	}
+}
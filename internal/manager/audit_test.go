@@ -0,0 +1,138 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/bricks-cloud/bricksllm/internal/audit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAuditLogsStorage struct {
+	entries []*audit.Entry
+}
+
+func (s *fakeAuditLogsStorage) AppendAuditLog(e *audit.Entry) (*audit.Entry, error) {
+	var prevSequence int64
+	prevHash := ""
+	if len(s.entries) != 0 {
+		prevSequence = s.entries[len(s.entries)-1].Sequence
+		prevHash = s.entries[len(s.entries)-1].Hash
+	}
+
+	e.Chain(prevSequence, prevHash)
+	s.entries = append(s.entries, e)
+	return e, nil
+}
+
+func (s *fakeAuditLogsStorage) GetAuditLogs(filter *audit.Filter) ([]*audit.Entry, error) {
+	return s.entries, nil
+}
+
+func (s *fakeAuditLogsStorage) GetAuditLogsAfter(sequence int64, limit int) ([]*audit.Entry, error) {
+	entries := []*audit.Entry{}
+	for _, e := range s.entries {
+		if e.Sequence > sequence && len(entries) < limit {
+			entries = append(entries, e)
+		}
+	}
+
+	return entries, nil
+}
+
+func newChainedAuditManager(t *testing.T, n int) (*AuditManager, *fakeAuditLogsStorage) {
+	s := &fakeAuditLogsStorage{}
+	m := NewAuditManager(s)
+
+	for i := 0; i < n; i++ {
+		_, err := m.RecordAuditLog(audit.NewEntry(audit.ActionUpdate, "key", "key-1", map[string]any{"name": i}, map[string]any{"name": i + 1}))
+		require.NoError(t, err)
+	}
+
+	return m, s
+}
+
+func TestVerifyAuditLogs(t *testing.T) {
+	m, _ := newChainedAuditManager(t, auditLogsVerifyBatch+5)
+
+	v, err := m.VerifyAuditLogs()
+	require.NoError(t, err)
+
+	assert.True(t, v.Valid)
+	assert.Equal(t, int64(auditLogsVerifyBatch+5), v.EntriesChecked)
+	assert.Equal(t, int64(auditLogsVerifyBatch+5), v.LastSequence)
+}
+
+func TestVerifyAuditLogs_Tampered(t *testing.T) {
+	cases := []struct {
+		name    string
+		tamper  func(s *fakeAuditLogsStorage)
+		broken  int64
+		checked int64
+	}{
+		{
+			name: "edited entry",
+			tamper: func(s *fakeAuditLogsStorage) {
+				s.entries[2].ActorName = "someone else"
+			},
+			broken:  3,
+			checked: 2,
+		},
+		{
+			name: "edited entry with recomputed hash",
+			tamper: func(s *fakeAuditLogsStorage) {
+				s.entries[2].ActorName = "someone else"
+				s.entries[2].Hash = s.entries[2].ComputeHash()
+			},
+			broken:  4,
+			checked: 3,
+		},
+		{
+			name: "removed entry",
+			tamper: func(s *fakeAuditLogsStorage) {
+				s.entries = append(s.entries[:2], s.entries[3:]...)
+			},
+			broken:  4,
+			checked: 2,
+		},
+	}
+
+	for _, tc := range cases {
+		m, s := newChainedAuditManager(t, 5)
+		tc.tamper(s)
+
+		v, err := m.VerifyAuditLogs()
+		require.NoError(t, err, tc.name)
+
+		assert.False(t, v.Valid, tc.name)
+		assert.Equal(t, tc.broken, v.BrokenAtSequence, tc.name)
+		assert.Equal(t, tc.checked, v.EntriesChecked, tc.name)
+		assert.NotEmpty(t, v.Reason, tc.name)
+	}
+}
+
+func TestVerifyAuditLogs_Unresolved(t *testing.T) {
+	m, _ := newChainedAuditManager(t, 2)
+
+	committed, err := m.RecordAuditLog(audit.NewIntent(audit.ActionUpdate, "key", "key-1", nil))
+	require.NoError(t, err)
+
+	unresolved, err := m.RecordAuditLog(audit.NewIntent(audit.ActionUpdate, "key", "key-2", nil))
+	require.NoError(t, err)
+
+	aborted, err := m.RecordAuditLog(audit.NewIntent(audit.ActionDelete, "key", "key-3", nil))
+	require.NoError(t, err)
+
+	_, err = m.RecordAuditLog(audit.NewEntry(audit.ActionUpdate, "key", "key-1", nil, map[string]any{"name": "updated"}).Resolve(committed, audit.StatusCommitted))
+	require.NoError(t, err)
+
+	_, err = m.RecordAuditLog(audit.NewIntent(audit.ActionDelete, "key", "key-3", nil).Resolve(aborted, audit.StatusAborted))
+	require.NoError(t, err)
+
+	v, err := m.VerifyAuditLogs()
+	require.NoError(t, err)
+
+	assert.True(t, v.Valid)
+	assert.Equal(t, int64(7), v.EntriesChecked)
+	assert.Equal(t, []int64{unresolved.Sequence}, v.UnresolvedSequences)
+}
@@ -0,0 +1,115 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/budget"
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/bricks-cloud/bricksllm/internal/util"
+)
+
+type BudgetsStorage interface {
+	CreateBudget(b *budget.Budget) (*budget.Budget, error)
+	UpdateBudget(id string, ub *budget.UpdateBudget) (*budget.Budget, error)
+	DeleteBudget(id string, updatedAt int64) error
+	GetBudget(id string) (*budget.Budget, error)
+	GetBudgets() ([]*budget.Budget, error)
+}
+
+type budgetCostLimitCache interface {
+	GetCounter(keyId string, rateLimitUnit key.TimeUnit) (int64, error)
+}
+
+type budgetCostStorage interface {
+	GetCounter(keyId string) (int64, error)
+}
+
+type BudgetManager struct {
+	Storage BudgetsStorage
+	clc     budgetCostLimitCache
+	cs      budgetCostStorage
+}
+
+func NewBudgetManager(s BudgetsStorage, clc budgetCostLimitCache, cs budgetCostStorage) *BudgetManager {
+	return &BudgetManager{
+		Storage: s,
+		clc:     clc,
+		cs:      cs,
+	}
+}
+
+func (m *BudgetManager) CreateBudget(b *budget.Budget) (*budget.Budget, error) {
+	err := b.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	b.CreatedAt = time.Now().Unix()
+	b.UpdatedAt = time.Now().Unix()
+	b.Id = util.NewUuid()
+
+	if b.KeyIds == nil {
+		b.KeyIds = []string{}
+	}
+
+	return m.Storage.CreateBudget(b)
+}
+
+func (m *BudgetManager) UpdateBudget(id string, ub *budget.UpdateBudget) (*budget.Budget, error) {
+	existing, err := m.Storage.GetBudget(id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = existing.Apply(ub).Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	ub.UpdatedAt = time.Now().Unix()
+
+	return m.Storage.UpdateBudget(id, ub)
+}
+
+func (m *BudgetManager) DeleteBudget(id string) error {
+	return m.Storage.DeleteBudget(id, time.Now().Unix())
+}
+
+func (m *BudgetManager) GetBudget(id string) (*budget.Budget, error) {
+	return m.Storage.GetBudget(id)
+}
+
+func (m *BudgetManager) GetBudgets() ([]*budget.Budget, error) {
+	return m.Storage.GetBudgets()
+}
+
+func (m *BudgetManager) GetBudgetSpend(id string) (*budget.Spend, error) {
+	b, err := m.Storage.GetBudget(id)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := m.cs.GetCounter(b.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &budget.Spend{
+		BudgetId:       b.Id,
+		CostInUsd:      float64(total) / 1000000,
+		CostLimitInUsd: b.CostLimitInUsd,
+	}
+
+	if b.CostLimitInUsdOverTime != 0 {
+		overTime, err := m.clc.GetCounter(b.Id, b.CostLimitInUsdUnit)
+		if err != nil {
+			return nil, err
+		}
+
+		s.CostInUsdOverTime = float64(overTime) / 1000000
+		s.CostLimitInUsdOverTime = b.CostLimitInUsdOverTime
+		s.CostLimitInUsdUnit = b.CostLimitInUsdUnit
+	}
+
+	return s, nil
+}
@@ -1,6 +1,8 @@
 package manager
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"strings"
@@ -26,6 +28,9 @@ type Storage interface {
 	GetProviderSettings(withSecret bool, ids []string) ([]*provider.Setting, error)
 	GetKey(keyId string) (*key.ResponseKey, error)
 	GetKeyByHash(hash string) (*key.ResponseKey, error)
+	RotateKey(id string, r *key.Rotation, dueBefore int64) (*key.ResponseKey, error)
+	RevertKeyRotation(id string, previous *key.ResponseKey, rotatedKey string) error
+	GetKeysDueForRotation(dueBefore int64, offset, limit int) ([]*key.ResponseKey, error)
 }
 
 type costLimitCache interface {
@@ -89,6 +94,8 @@ func (m *Manager) CreateKey(rk *key.RequestKey) (*key.ResponseKey, error) {
 		rk.Key = hasher.Hash(rk.Key)
 	}
 
+	rk.NextRotationAt = key.GetNextRotationAt(rk.RotationInterval, time.Now())
+
 	if len(rk.SettingId) != 0 {
 		if _, err := m.s.GetProviderSetting(rk.SettingId, false); err != nil {
 			return nil, err
@@ -130,6 +137,26 @@ func (m *Manager) UpdateKey(id string, uk *key.UpdateKey) (*key.ResponseKey, err
 
 	current := existing.Key
 
+	if uk.RotationInterval != nil || uk.RotationGracePeriod != nil {
+		interval, gracePeriod := existing.RotationInterval, existing.RotationGracePeriod
+		if uk.RotationInterval != nil {
+			interval = *uk.RotationInterval
+		}
+
+		if uk.RotationGracePeriod != nil {
+			gracePeriod = *uk.RotationGracePeriod
+		}
+
+		if err := key.ValidateRotation(interval, gracePeriod); err != nil {
+			return nil, err
+		}
+
+		if uk.RotationInterval != nil && interval != existing.RotationInterval {
+			next := key.GetNextRotationAt(interval, time.Now())
+			uk.NextRotationAt = &next
+		}
+	}
+
 	if uk.IsKeyNotHashed != nil && !*uk.IsKeyNotHashed {
 		uk.Key = hasher.Hash(existing.Key)
 	}
@@ -169,7 +196,7 @@ func (m *Manager) UpdateKey(id string, uk *key.UpdateKey) (*key.ResponseKey, err
 		}
 	}
 
-	if uk.CostLimitInUsdUnit != nil || uk.RateLimitUnit != nil {
+	if uk.CostLimitInUsdUnit != nil || uk.RateLimitUnit != nil || uk.RateLimitAlgorithm != nil {
 		err := m.ac.Delete(id)
 		if err != nil {
 			return nil, err
@@ -195,9 +222,121 @@ func (m *Manager) UpdateKey(id string, uk *key.UpdateKey) (*key.ResponseKey, err
 		telemetry.Incr("bricksllm.manager.update_key.delete_cache_error", nil, 1)
 	}
 
+	if len(existing.PreviousKey) != 0 {
+		err = m.kc.Delete(existing.PreviousKey)
+		if err != nil {
+			telemetry.Incr("bricksllm.manager.update_key.delete_cache_error", nil, 1)
+		}
+	}
+
 	return updated, nil
 }
 
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// RotateKey replaces the secret of a key. The previous secret keeps authenticating
+// until the grace period of the key ends. The new secret is only returned here.
+func (m *Manager) RotateKey(id string) (*key.RotatedKey, error) {
+	return m.rotateKey(id, 0, nil)
+}
+
+// RotateKeyIfDue rotates a key only if it is due for rotation by the given time and
+// hands the new secret to deliver. The rotation is reverted when deliver fails so that
+// a secret nobody received never replaces the current one. It returns nil when the key
+// is not rotated.
+func (m *Manager) RotateKeyIfDue(id string, dueBefore int64, deliver func(rk *key.RotatedKey) error) (*key.RotatedKey, error) {
+	return m.rotateKey(id, dueBefore, deliver)
+}
+
+func (m *Manager) rotateKey(id string, dueBefore int64, deliver func(rk *key.RotatedKey) error) (*key.RotatedKey, error) {
+	existing, err := m.s.GetKey(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing == nil {
+		return nil, internal_errors.NewNotFoundError("key is not found: " + id)
+	}
+
+	if existing.Revoked {
+		return nil, internal_errors.NewValidationError("revoked key cannot be rotated")
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	r := &key.Rotation{
+		Key:                  hasher.Hash(secret),
+		RotatedAt:            now.Unix(),
+		PreviousKeyExpiresAt: now.Add(existing.GetRotationGracePeriod()).Unix(),
+		NextRotationAt:       key.GetNextRotationAt(existing.RotationInterval, now),
+	}
+
+	if existing.IsKeyNotHashed {
+		r.PreviousKey = hasher.Hash(existing.Key)
+	}
+
+	rotated, err := m.s.RotateKey(id, r, dueBefore)
+	if err != nil {
+		return nil, err
+	}
+
+	if rotated == nil {
+		if dueBefore != 0 {
+			return nil, nil
+		}
+
+		return nil, internal_errors.NewNotFoundError("key is not found: " + id)
+	}
+
+	rk := &key.RotatedKey{
+		ResponseKey: *rotated,
+		Secret:      secret,
+	}
+
+	if deliver != nil {
+		if err := deliver(rk); err != nil {
+			telemetry.Incr("bricksllm.manager.rotate_key.deliver_error", nil, 1)
+
+			if rerr := m.s.RevertKeyRotation(id, existing, r.Key); rerr != nil {
+				telemetry.Incr("bricksllm.manager.rotate_key.revert_key_rotation_error", nil, 1)
+				return nil, errors.New("rotation of key " + id + " could not be delivered: " + err.Error() + " and could not be reverted: " + rerr.Error())
+			}
+
+			return nil, err
+		}
+	}
+
+	for _, hash := range []string{existing.Key, existing.PreviousKey} {
+		if len(hash) == 0 {
+			continue
+		}
+
+		err = m.kc.Delete(hash)
+		if err != nil {
+			telemetry.Incr("bricksllm.manager.rotate_key.delete_cache_error", nil, 1)
+		}
+	}
+
+	telemetry.Incr("bricksllm.manager.rotate_key.success", nil, 1)
+
+	return rk, nil
+}
+
+func (m *Manager) GetKeysDueForRotation(dueBefore int64, offset, limit int) ([]*key.ResponseKey, error) {
+	return m.s.GetKeysDueForRotation(dueBefore, offset, limit)
+}
+
 func (m *Manager) GetKeyViaCache(raw string) (*key.ResponseKey, error) {
 	k, _ := m.kc.Get(raw)
 
@@ -214,7 +353,14 @@ func (m *Manager) GetKeyViaCache(raw string) (*key.ResponseKey, error) {
 			return stored, nil
 		}
 
-		err = m.kc.Set(raw, bs, 24*time.Hour)
+		ttl := 24 * time.Hour
+		if stored.Key != raw {
+			ttl = min(ttl, time.Until(time.Unix(stored.PreviousKeyExpiresAt, 0)))
+		}
+
+		if ttl > 0 {
+			err = m.kc.Set(raw, bs, ttl)
+		}
 		if err != nil {
 			telemetry.Incr("bricksllm.manager.get_key_via_cache.set_error", nil, 1)
 		}
@@ -222,6 +368,17 @@ func (m *Manager) GetKeyViaCache(raw string) (*key.ResponseKey, error) {
 		k = stored
 	}
 
+	if k != nil && k.Key != raw && !k.IsPreviousKeyValid(raw, time.Now()) {
+		telemetry.Incr("bricksllm.manager.get_key_via_cache.previous_key_expired", nil, 1)
+
+		err := m.kc.Delete(raw)
+		if err != nil {
+			telemetry.Incr("bricksllm.manager.get_key_via_cache.delete_error", nil, 1)
+		}
+
+		return nil, internal_errors.NewNotFoundError("key is not found")
+	}
+
 	if k != nil {
 		telemetry.Incr("bricksllm.manager.get_key_via_cache.cache_hit", nil, 1)
 	}
@@ -0,0 +1,139 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/audit"
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+	"go.uber.org/zap"
+)
+
+const keyRotationBatchSize = 100
+
+type keyRotationManager interface {
+	GetKeysDueForRotation(dueBefore int64, offset, limit int) ([]*key.ResponseKey, error)
+	RotateKeyIfDue(id string, dueBefore int64, deliver func(rk *key.RotatedKey) error) (*key.RotatedKey, error)
+}
+
+type auditRecorder interface {
+	RecordAuditLog(e *audit.Entry) (*audit.Entry, error)
+}
+
+type rotationNotifier interface {
+	CanDeliverKeyRotation(tags []string) bool
+	NotifyKeyRotation(rk *key.RotatedKey) error
+}
+
+// KeyRotator rotates keys once their rotation interval elapses. New secrets are only
+// handed out through key.rotated webhook events, so a key is left as it is until a JSON
+// webhook matches its tags.
+type KeyRotator struct {
+	km       keyRotationManager
+	ar       auditRecorder
+	rn       rotationNotifier
+	log      *zap.Logger
+	interval time.Duration
+	done     chan bool
+}
+
+func NewKeyRotator(km keyRotationManager, ar auditRecorder, rn rotationNotifier, log *zap.Logger, interval time.Duration) *KeyRotator {
+	return &KeyRotator{
+		km:       km,
+		ar:       ar,
+		rn:       rn,
+		log:      log,
+		interval: interval,
+		done:     make(chan bool),
+	}
+}
+
+func (kr *KeyRotator) Listen() {
+	ticker := time.NewTicker(kr.interval)
+	kr.log.Info("key rotator started checking for keys due for rotation")
+
+	go func() {
+		for {
+			select {
+			case <-kr.done:
+				ticker.Stop()
+				kr.log.Info("key rotator stopped")
+				return
+			case <-ticker.C:
+				kr.rotate()
+			}
+		}
+	}()
+}
+
+func (kr *KeyRotator) Stop() {
+	kr.log.Info("shutting down key rotator...")
+
+	kr.done <- true
+}
+
+func (kr *KeyRotator) rotate() {
+	now := time.Now().Unix()
+
+	// Keys that are skipped or fail to rotate stay due, so they are paged over to reach
+	// the keys after them.
+	offset := 0
+	for {
+		keys, err := kr.km.GetKeysDueForRotation(now, offset, keyRotationBatchSize)
+		if err != nil {
+			telemetry.Incr("bricksllm.manager.key_rotator.rotate.get_keys_due_for_rotation_error", nil, 1)
+			kr.log.Debug("error when getting keys due for rotation", zap.Error(err))
+			return
+		}
+
+		for _, k := range keys {
+			if !kr.rn.CanDeliverKeyRotation(k.Tags) {
+				telemetry.Incr("bricksllm.manager.key_rotator.rotate.no_delivery_target", nil, 1)
+				kr.log.Debug("skipping rotation of key without a json webhook to deliver its secret to", zap.String("keyId", k.KeyId))
+				offset++
+				continue
+			}
+
+			rotated, err := kr.km.RotateKeyIfDue(k.KeyId, now, kr.rn.NotifyKeyRotation)
+			if err != nil {
+				telemetry.Incr("bricksllm.manager.key_rotator.rotate.rotate_key_error", nil, 1)
+				kr.log.Debug("error when rotating key", zap.String("keyId", k.KeyId), zap.Error(err))
+				offset++
+				continue
+			}
+
+			if rotated == nil {
+				continue
+			}
+
+			kr.record(k, rotated)
+		}
+
+		if len(keys) < keyRotationBatchSize {
+			return
+		}
+	}
+}
+
+func (kr *KeyRotator) record(before *key.ResponseKey, after *key.RotatedKey) {
+	bs, err := audit.Snapshot(before)
+	if err != nil {
+		kr.log.Debug("error when taking audit snapshot of key", zap.Error(err))
+		return
+	}
+
+	as, err := audit.Snapshot(after)
+	if err != nil {
+		kr.log.Debug("error when taking audit snapshot of key", zap.Error(err))
+		return
+	}
+
+	e := audit.NewEntry(audit.ActionRotate, "key", before.KeyId, bs, as)
+	e.ActorName = "key rotation scheduler"
+
+	_, err = kr.ar.RecordAuditLog(e)
+	if err != nil {
+		telemetry.Incr("bricksllm.manager.key_rotator.record.record_audit_log_error", nil, 1)
+		kr.log.Debug("error when recording key rotation audit log", zap.Error(err))
+	}
+}
@@ -0,0 +1,154 @@
+package manager
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/audit"
+	"github.com/bricks-cloud/bricksllm/internal/hasher"
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeRotationStorage keeps a single key and applies rotations the way the key storage
+// does.
+type fakeRotationStorage struct {
+	Storage
+	k       key.ResponseKey
+	rotated int
+}
+
+func (s *fakeRotationStorage) GetKey(keyId string) (*key.ResponseKey, error) {
+	copied := s.k
+	return &copied, nil
+}
+
+func (s *fakeRotationStorage) GetKeysDueForRotation(dueBefore int64, offset, limit int) ([]*key.ResponseKey, error) {
+	if s.k.NextRotationAt == 0 || s.k.NextRotationAt > dueBefore || offset != 0 {
+		return []*key.ResponseKey{}, nil
+	}
+
+	copied := s.k
+	return []*key.ResponseKey{&copied}, nil
+}
+
+func (s *fakeRotationStorage) RotateKey(id string, r *key.Rotation, dueBefore int64) (*key.ResponseKey, error) {
+	if dueBefore != 0 && (s.k.NextRotationAt == 0 || s.k.NextRotationAt > dueBefore) {
+		return nil, nil
+	}
+
+	s.rotated++
+	s.k.PreviousKey = s.k.Key
+	if s.k.IsKeyNotHashed {
+		s.k.PreviousKey = r.PreviousKey
+	}
+
+	s.k.Key = r.Key
+	s.k.IsKeyNotHashed = false
+	s.k.PreviousKeyExpiresAt = r.PreviousKeyExpiresAt
+	s.k.NextRotationAt = r.NextRotationAt
+	s.k.LastRotatedAt = r.RotatedAt
+
+	copied := s.k
+	return &copied, nil
+}
+
+func (s *fakeRotationStorage) RevertKeyRotation(id string, previous *key.ResponseKey, rotatedKey string) error {
+	if s.k.Key == rotatedKey {
+		s.k = *previous
+	}
+
+	return nil
+}
+
+type fakeKeyCache struct{}
+
+func (c *fakeKeyCache) Set(keyId string, value interface{}, ttl time.Duration) error { return nil }
+func (c *fakeKeyCache) Delete(keyId string) error                                    { return nil }
+func (c *fakeKeyCache) Get(keyId string) (*key.ResponseKey, error)                   { return nil, nil }
+
+type fakeRotationNotifier struct {
+	canDeliver bool
+	err        error
+	delivered  []*key.RotatedKey
+}
+
+func (n *fakeRotationNotifier) CanDeliverKeyRotation(tags []string) bool {
+	return n.canDeliver
+}
+
+func (n *fakeRotationNotifier) NotifyKeyRotation(rk *key.RotatedKey) error {
+	if n.err != nil {
+		return n.err
+	}
+
+	n.delivered = append(n.delivered, rk)
+	return nil
+}
+
+type fakeAuditRecorder struct {
+	entries []*audit.Entry
+}
+
+func (r *fakeAuditRecorder) RecordAuditLog(e *audit.Entry) (*audit.Entry, error) {
+	r.entries = append(r.entries, e)
+	return e, nil
+}
+
+func newRotatorFixture(rn *fakeRotationNotifier) (*KeyRotator, *fakeRotationStorage, *fakeAuditRecorder) {
+	s := &fakeRotationStorage{
+		k: key.ResponseKey{
+			KeyId:            "key-1",
+			Key:              "raw-secret",
+			IsKeyNotHashed:   true,
+			RotationInterval: "720h",
+			NextRotationAt:   time.Now().Add(-time.Minute).Unix(),
+			UpdatedAt:        1,
+		},
+	}
+
+	ar := &fakeAuditRecorder{}
+	m := NewManager(s, nil, nil, nil, &fakeKeyCache{})
+
+	return NewKeyRotator(m, ar, rn, zap.NewNop(), time.Minute), s, ar
+}
+
+func TestKeyRotator_Delivered(t *testing.T) {
+	rn := &fakeRotationNotifier{canDeliver: true}
+	kr, s, ar := newRotatorFixture(rn)
+
+	kr.rotate()
+
+	require.Len(t, rn.delivered, 1)
+	assert.Equal(t, hasher.Hash(rn.delivered[0].Secret), s.k.Key)
+	assert.Equal(t, hasher.Hash("raw-secret"), s.k.PreviousKey, "the raw secret must not be kept as the previous key")
+	assert.False(t, s.k.IsKeyNotHashed)
+	assert.Greater(t, s.k.NextRotationAt, time.Now().Unix())
+	assert.Len(t, ar.entries, 1)
+}
+
+func TestKeyRotator_NoDeliveryTarget(t *testing.T) {
+	rn := &fakeRotationNotifier{}
+	kr, s, ar := newRotatorFixture(rn)
+
+	kr.rotate()
+
+	assert.Zero(t, s.rotated)
+	assert.Equal(t, "raw-secret", s.k.Key)
+	assert.Empty(t, ar.entries)
+}
+
+func TestKeyRotator_DeliveryFailed(t *testing.T) {
+	rn := &fakeRotationNotifier{canDeliver: true, err: errors.New("storage is down")}
+	kr, s, ar := newRotatorFixture(rn)
+	before := s.k
+
+	kr.rotate()
+
+	assert.Equal(t, 1, s.rotated)
+	assert.Equal(t, before, s.k, "a rotation that is not delivered must be reverted")
+	assert.Empty(t, ar.entries)
+}
@@ -0,0 +1,76 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/alias"
+	"github.com/bricks-cloud/bricksllm/internal/util"
+)
+
+type ModelAliasesStorage interface {
+	CreateModelAlias(ma *alias.ModelAlias) (*alias.ModelAlias, error)
+	UpdateModelAlias(id string, uma *alias.UpdateModelAlias) (*alias.ModelAlias, error)
+	DeleteModelAlias(id string, updatedAt int64) error
+	GetModelAlias(id string) (*alias.ModelAlias, error)
+	GetModelAliases() ([]*alias.ModelAlias, error)
+}
+
+type ModelAliasesMemStorage interface {
+	GetModelAlias(name string) *alias.ModelAlias
+}
+
+type ModelAliasManager struct {
+	Storage ModelAliasesStorage
+	Memdb   ModelAliasesMemStorage
+}
+
+func NewModelAliasManager(s ModelAliasesStorage, memdb ModelAliasesMemStorage) *ModelAliasManager {
+	return &ModelAliasManager{
+		Storage: s,
+		Memdb:   memdb,
+	}
+}
+
+func (m *ModelAliasManager) CreateModelAlias(ma *alias.ModelAlias) (*alias.ModelAlias, error) {
+	err := ma.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	ma.CreatedAt = time.Now().Unix()
+	ma.UpdatedAt = time.Now().Unix()
+	ma.Id = util.NewUuid()
+
+	if ma.Overrides == nil {
+		ma.Overrides = []*alias.Override{}
+	}
+
+	return m.Storage.CreateModelAlias(ma)
+}
+
+func (m *ModelAliasManager) UpdateModelAlias(id string, uma *alias.UpdateModelAlias) (*alias.ModelAlias, error) {
+	err := uma.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	uma.UpdatedAt = time.Now().Unix()
+
+	return m.Storage.UpdateModelAlias(id, uma)
+}
+
+func (m *ModelAliasManager) DeleteModelAlias(id string) error {
+	return m.Storage.DeleteModelAlias(id, time.Now().Unix())
+}
+
+func (m *ModelAliasManager) GetModelAlias(id string) (*alias.ModelAlias, error) {
+	return m.Storage.GetModelAlias(id)
+}
+
+func (m *ModelAliasManager) GetModelAliases() ([]*alias.ModelAlias, error) {
+	return m.Storage.GetModelAliases()
+}
+
+func (m *ModelAliasManager) GetModelAliasFromMemdb(name string) *alias.ModelAlias {
+	return m.Memdb.GetModelAlias(name)
+}
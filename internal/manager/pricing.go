@@ -0,0 +1,98 @@
+package manager
+
+import (
+	"time"
+
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+	"github.com/bricks-cloud/bricksllm/internal/pricing"
+	"github.com/bricks-cloud/bricksllm/internal/util"
+)
+
+type PricesStorage interface {
+	SeedPrices(prices []*pricing.Price) error
+	CreatePrice(p *pricing.Price) (*pricing.Price, error)
+	UpdatePrice(id string, up *pricing.UpdatePrice) (*pricing.Price, error)
+	DeletePrice(id string, updatedAt int64) error
+	GetPrice(id string) (*pricing.Price, error)
+	GetPrices(filter *pricing.PriceFilter) ([]*pricing.Price, error)
+}
+
+type PriceManager struct {
+	Storage PricesStorage
+}
+
+func NewPriceManager(s PricesStorage) *PriceManager {
+	return &PriceManager{
+		Storage: s,
+	}
+}
+
+func (m *PriceManager) SeedDefaultPrices() error {
+	prices := pricing.GetDefaultPrices()
+
+	now := time.Now().Unix()
+	for _, p := range prices {
+		p.Id = util.NewUuid()
+		p.CreatedAt = now
+		p.UpdatedAt = now
+	}
+
+	return m.Storage.SeedPrices(prices)
+}
+
+func (m *PriceManager) CreatePrice(p *pricing.Price) (*pricing.Price, error) {
+	err := p.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	p.CreatedAt = time.Now().Unix()
+	p.UpdatedAt = time.Now().Unix()
+	p.Id = util.NewUuid()
+
+	if p.EffectiveFrom == 0 {
+		p.EffectiveFrom = p.CreatedAt
+	}
+
+	return m.Storage.CreatePrice(p)
+}
+
+// UpdatePrice only updates prices that are not in effect yet so that the cost of
+// requests recorded under a price is never rewritten. Price changes for the past
+// should be made by creating a new price.
+func (m *PriceManager) UpdatePrice(id string, up *pricing.UpdatePrice) (*pricing.Price, error) {
+	err := up.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := m.Storage.GetPrice(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	if existing.EffectiveFrom <= now {
+		return nil, internal_errors.NewValidationError("price is already in effect, create a new price with a later effectiveFrom instead")
+	}
+
+	if up.EffectiveFrom != 0 && up.EffectiveFrom <= now {
+		return nil, internal_errors.NewValidationError("effectiveFrom must be in the future")
+	}
+
+	up.UpdatedAt = now
+
+	return m.Storage.UpdatePrice(id, up)
+}
+
+func (m *PriceManager) DeletePrice(id string) error {
+	return m.Storage.DeletePrice(id, time.Now().Unix())
+}
+
+func (m *PriceManager) GetPrice(id string) (*pricing.Price, error) {
+	return m.Storage.GetPrice(id)
+}
+
+func (m *PriceManager) GetPrices(filter *pricing.PriceFilter) ([]*pricing.Price, error) {
+	return m.Storage.GetPrices(filter)
+}
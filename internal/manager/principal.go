@@ -0,0 +1,118 @@
+package manager
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+	"github.com/bricks-cloud/bricksllm/internal/principal"
+	"github.com/bricks-cloud/bricksllm/internal/util"
+)
+
+type PrincipalsStorage interface {
+	CreatePrincipal(p *principal.Principal) (*principal.Principal, error)
+	UpdatePrincipal(id string, up *principal.UpdatePrincipal) (*principal.Principal, error)
+	DeletePrincipal(id string, updatedAt int64) error
+	GetPrincipal(id string) (*principal.Principal, error)
+	GetPrincipalByTokenHash(hash string) (*principal.Principal, error)
+	GetPrincipals() ([]*principal.Principal, error)
+}
+
+type PrincipalManager struct {
+	Storage PrincipalsStorage
+}
+
+func NewPrincipalManager(s PrincipalsStorage) *PrincipalManager {
+	return &PrincipalManager{
+		Storage: s,
+	}
+}
+
+func newPrincipalToken() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return principal.TokenPrefix + hex.EncodeToString(b), nil
+}
+
+// CreatePrincipal generates the token of a principal. Only the hash of the token is
+// stored so the token is returned by this call only.
+func (m *PrincipalManager) CreatePrincipal(p *principal.Principal) (*principal.Principal, error) {
+	err := p.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := newPrincipalToken()
+	if err != nil {
+		return nil, err
+	}
+
+	p.CreatedAt = time.Now().Unix()
+	p.UpdatedAt = time.Now().Unix()
+	p.Id = util.NewUuid()
+	p.TokenHash = principal.HashToken(token)
+	p.TokenSuffix = token[len(token)-4:]
+
+	created, err := m.Storage.CreatePrincipal(p)
+	if err != nil {
+		return nil, err
+	}
+
+	created.Token = token
+
+	return created, nil
+}
+
+func (m *PrincipalManager) UpdatePrincipal(id string, up *principal.UpdatePrincipal) (*principal.Principal, error) {
+	err := up.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	up.UpdatedAt = time.Now().Unix()
+
+	return m.Storage.UpdatePrincipal(id, up)
+}
+
+func (m *PrincipalManager) DeletePrincipal(id string) error {
+	return m.Storage.DeletePrincipal(id, time.Now().Unix())
+}
+
+func (m *PrincipalManager) GetPrincipal(id string) (*principal.Principal, error) {
+	return m.Storage.GetPrincipal(id)
+}
+
+func (m *PrincipalManager) GetPrincipals() ([]*principal.Principal, error) {
+	return m.Storage.GetPrincipals()
+}
+
+func (m *PrincipalManager) HasPrincipals() (bool, error) {
+	principals, err := m.Storage.GetPrincipals()
+	if err != nil {
+		return false, err
+	}
+
+	return len(principals) != 0, nil
+}
+
+func (m *PrincipalManager) AuthenticatePrincipal(token string) (*principal.Principal, error) {
+	p, err := m.Storage.GetPrincipalByTokenHash(principal.HashToken(token))
+	if err != nil {
+		if _, ok := err.(notFoundError); ok {
+			return nil, internal_errors.NewAuthError("admin token is not valid")
+		}
+
+		return nil, err
+	}
+
+	if p.Revoked {
+		return nil, internal_errors.NewAuthError("admin token is revoked")
+	}
+
+	return p, nil
+}
@@ -48,7 +48,7 @@ func NewProviderSettingsManager(s ProviderSettingsStorage, cache ProviderSetting
 }
 
 func isProviderNativelySupported(provider string) bool {
-	return provider == "openai" || provider == "anthropic" || provider == "azure" || provider == "vllm" || provider == "deepinfra" || provider == "bedrock"
+	return provider == "openai" || provider == "anthropic" || provider == "azure" || provider == "vllm" || provider == "deepinfra" || provider == "bedrock" || provider == "gemini"
 }
 
 func findMissingAuthParams(providerName string, params map[string]string) string {
@@ -99,6 +99,24 @@ func findMissingAuthParams(providerName string, params map[string]string) string
 		}
 	}
 
+	if providerName == "gemini" {
+		// vertex ai settings authenticate with service account credentials
+		// instead of an api key.
+		if len(params["projectId"]) == 0 && len(params["location"]) == 0 && len(params["credentials"]) == 0 {
+			if len(params["apikey"]) == 0 {
+				missingFields = append(missingFields, "apikey")
+			}
+
+			return strings.Join(missingFields, ",")
+		}
+
+		for _, field := range []string{"projectId", "location", "credentials"} {
+			if len(params[field]) == 0 {
+				missingFields = append(missingFields, field)
+			}
+		}
+	}
+
 	return strings.Join(missingFields, ",")
 }
 
@@ -123,6 +141,27 @@ func (m *ProviderSettingsManager) validateSettings(providerName string, setting
 		return internal_errors.NewValidationError(fmt.Sprintf("provider %s is missing fields %s", providerName, missing))
 	}
 
+	if providerName == "gemini" && len(setting["credentials"]) != 0 && !json.Valid([]byte(setting["credentials"])) {
+		return internal_errors.NewValidationError("provider gemini credentials must be a service account json key")
+	}
+
+	return nil
+}
+
+func validateCacheConfig(cc *provider.CacheConfig) error {
+	if cc == nil || len(cc.Ttl) == 0 {
+		return nil
+	}
+
+	parsed, err := time.ParseDuration(cc.Ttl)
+	if err != nil {
+		return internal_errors.NewValidationError("cacheConfig.ttl is invalid")
+	}
+
+	if parsed <= 0 || parsed > time.Hour*720 {
+		return internal_errors.NewValidationError("cacheConfig.ttl must be between 0 and 30 days")
+	}
+
 	return nil
 }
 
@@ -142,6 +181,19 @@ func (m *ProviderSettingsManager) EncryptParams(updatedAt int64, provider string
 		}
 
 		params["apikey"] = encryted
+	} else if provider == "gemini" {
+		for _, field := range []string{"apikey", "credentials"} {
+			if len(params[field]) == 0 {
+				continue
+			}
+
+			encryted, err := m.Encryptor.Encrypt(params[field], map[string]string{"X-UPDATED-AT": strconv.FormatInt(updatedAt, 10)})
+			if err != nil {
+				return nil, err
+			}
+
+			params[field] = encryted
+		}
 	}
 
 	return params, nil
@@ -156,6 +208,10 @@ func (m *ProviderSettingsManager) CreateSetting(setting *provider.Setting) (*pro
 		return nil, err
 	}
 
+	if err := validateCacheConfig(setting.CacheConfig); err != nil {
+		return nil, err
+	}
+
 	setting.Id = util.NewUuid()
 	setting.CreatedAt = time.Now().Unix()
 	setting.UpdatedAt = time.Now().Unix()
@@ -182,6 +238,10 @@ func (m *ProviderSettingsManager) UpdateSetting(id string, setting *provider.Upd
 		return nil, internal_errors.NewNotFoundError("provider setting is not found")
 	}
 
+	if err := validateCacheConfig(setting.CacheConfig); err != nil {
+		return nil, err
+	}
+
 	if len(setting.Setting) != 0 {
 		if err := m.validateSettings(existing.Provider, setting.Setting); err != nil {
 			return nil, err
@@ -1,20 +1,42 @@
 package manager
 
-import "github.com/bricks-cloud/bricksllm/internal/key"
+import (
+	"fmt"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/key"
+)
 
 type Cache interface {
 	IncrementCounter(keyId string, rateLimitUnit key.TimeUnit, incr int64) error
 }
 
+type TokenCache interface {
+	IncrementCounterInWindow(keyId string, rateLimitUnit key.TimeUnit, window int64, incr int64) error
+	GetCounterWindow(rateLimitUnit key.TimeUnit) (int64, error)
+	ReserveCounter(keyId string, rateLimitUnit key.TimeUnit, limit int64, incr int64) (bool, int64, int64, error)
+}
+
+type RateLimiter interface {
+	AllowSlidingWindow(id string, limit int, window time.Duration) (bool, int64, error)
+	AllowTokenBucket(id string, limit int, window time.Duration, burst int) (bool, int64, error)
+}
+
 type RateLimitManager struct {
-	c  Cache
-	uc Cache
+	c   Cache
+	uc  Cache
+	tc  TokenCache
+	utc TokenCache
+	rl  RateLimiter
 }
 
-func NewRateLimitManager(c Cache, uc Cache) *RateLimitManager {
+func NewRateLimitManager(c Cache, uc Cache, tc TokenCache, utc TokenCache, rl RateLimiter) *RateLimitManager {
 	return &RateLimitManager{
-		c:  c,
-		uc: uc,
+		c:   c,
+		uc:  uc,
+		tc:  tc,
+		utc: utc,
+		rl:  rl,
 	}
 }
 
@@ -37,3 +59,78 @@ func (rlm *RateLimitManager) IncrementUser(id string, timeUnit key.TimeUnit) err
 
 	return nil
 }
+
+// ReserveTokens checks the tokens against the limit of the key and reserves them in one
+// atomic step. It returns whether the tokens were reserved, the token count of the
+// current window and the window so that the reservation can later be corrected in the
+// same window.
+func (rlm *RateLimitManager) ReserveTokens(keyId string, timeUnit key.TimeUnit, limit int, tks int64) (bool, int64, int64, error) {
+	return rlm.tc.ReserveCounter(keyId, timeUnit, int64(limit), tks)
+}
+
+func (rlm *RateLimitManager) ReserveUserTokens(id string, timeUnit key.TimeUnit, limit int, tks int64) (bool, int64, int64, error) {
+	return rlm.utc.ReserveCounter(id, timeUnit, int64(limit), tks)
+}
+
+// CorrectTokens adds the difference between the actual and the reserved tokens to the
+// window of the reservation. A window of 0 means that nothing was reserved and the
+// tokens are counted in the current window.
+func (rlm *RateLimitManager) CorrectTokens(keyId string, timeUnit key.TimeUnit, window int64, tks int64) error {
+	return correctTokens(rlm.tc, keyId, timeUnit, window, tks)
+}
+
+func (rlm *RateLimitManager) CorrectUserTokens(id string, timeUnit key.TimeUnit, window int64, tks int64) error {
+	return correctTokens(rlm.utc, id, timeUnit, window, tks)
+}
+
+func correctTokens(tc TokenCache, id string, timeUnit key.TimeUnit, window int64, tks int64) error {
+	if tks == 0 {
+		return nil
+	}
+
+	if window == 0 {
+		current, err := tc.GetCounterWindow(timeUnit)
+		if err != nil {
+			return err
+		}
+
+		window = current
+	}
+
+	return tc.IncrementCounterInWindow(id, timeUnit, window, tks)
+}
+
+func getRateLimitWindow(timeUnit key.TimeUnit) (time.Duration, error) {
+	switch timeUnit {
+	case key.SecondTimeUnit:
+		return time.Second, nil
+	case key.MinuteTimeUnit:
+		return time.Minute, nil
+	case key.HourTimeUnit:
+		return time.Hour, nil
+	case key.DayTimeUnit:
+		return 24 * time.Hour, nil
+	}
+
+	return 0, fmt.Errorf("cannot recognize rate limit time unit %v", timeUnit)
+}
+
+// Allow atomically checks and consumes one request from the key's rate limit
+// when it uses the sliding window or token bucket algorithm. It returns the
+// number of requests left in the current window.
+func (rlm *RateLimitManager) Allow(k *key.ResponseKey) (bool, int64, error) {
+	if k.RateLimitOverTime == 0 || k.IsRateLimitedInFixedWindow() {
+		return true, 0, nil
+	}
+
+	window, err := getRateLimitWindow(k.RateLimitUnit)
+	if err != nil {
+		return false, 0, err
+	}
+
+	if k.RateLimitAlgorithm == key.SlidingWindowAlgorithm {
+		return rlm.rl.AllowSlidingWindow(k.KeyId, k.RateLimitOverTime, window)
+	}
+
+	return rlm.rl.AllowTokenBucket(k.KeyId, k.RateLimitOverTime, window, k.RateLimitBurst)
+}
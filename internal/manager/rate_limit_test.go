@@ -0,0 +1,105 @@
+package manager
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTokenCache struct {
+	window int64
+	counts map[int64]int64
+	limits []int64
+}
+
+func (tc *fakeTokenCache) IncrementCounterInWindow(keyId string, rateLimitUnit key.TimeUnit, window int64, incr int64) error {
+	tc.counts[window] += incr
+	return nil
+}
+
+func (tc *fakeTokenCache) GetCounterWindow(rateLimitUnit key.TimeUnit) (int64, error) {
+	return tc.window, nil
+}
+
+func (tc *fakeTokenCache) ReserveCounter(keyId string, rateLimitUnit key.TimeUnit, limit int64, incr int64) (bool, int64, int64, error) {
+	tc.limits = append(tc.limits, limit)
+	if tc.counts[tc.window]+incr > limit {
+		return false, tc.counts[tc.window], tc.window, nil
+	}
+
+	tc.counts[tc.window] += incr
+	return true, tc.counts[tc.window], tc.window, nil
+}
+
+func TestRateLimitManager_ReserveAndCorrectTokens(t *testing.T) {
+	tc := &fakeTokenCache{window: 1, counts: map[int64]int64{}}
+	rlm := NewRateLimitManager(nil, nil, tc, tc, nil)
+
+	reserved, count, window, err := rlm.ReserveTokens("k", key.MinuteTimeUnit, 100, 80)
+	require.NoError(t, err)
+	assert.True(t, reserved)
+	assert.Equal(t, int64(80), count)
+	assert.Equal(t, int64(1), window)
+
+	reserved, count, _, err = rlm.ReserveUserTokens("u", key.MinuteTimeUnit, 100, 30)
+	require.NoError(t, err)
+	assert.False(t, reserved)
+	assert.Equal(t, int64(80), count)
+	assert.Equal(t, []int64{100, 100}, tc.limits)
+
+	require.NoError(t, rlm.CorrectTokens("k", key.MinuteTimeUnit, window, -50))
+	assert.Equal(t, int64(30), tc.counts[1])
+
+	tc.window = 2
+	require.NoError(t, rlm.CorrectTokens("k", key.MinuteTimeUnit, 0, 10))
+	assert.Equal(t, int64(10), tc.counts[2])
+
+	require.NoError(t, rlm.CorrectTokens("k", key.MinuteTimeUnit, 1, 0))
+	assert.Equal(t, int64(30), tc.counts[1])
+}
+
+type fakeRateLimiter struct {
+	calls []string
+}
+
+func (rl *fakeRateLimiter) AllowSlidingWindow(id string, limit int, window time.Duration) (bool, int64, error) {
+	rl.calls = append(rl.calls, fmt.Sprintf("sliding %s %d %s", id, limit, window))
+	return true, 1, nil
+}
+
+func (rl *fakeRateLimiter) AllowTokenBucket(id string, limit int, window time.Duration, burst int) (bool, int64, error) {
+	rl.calls = append(rl.calls, fmt.Sprintf("bucket %s %d %s %d", id, limit, window, burst))
+	return false, 0, nil
+}
+
+func TestRateLimitManager_Allow(t *testing.T) {
+	cases := []struct {
+		name    string
+		key     *key.ResponseKey
+		allowed bool
+		calls   []string
+		err     bool
+	}{
+		{"no limit", &key.ResponseKey{KeyId: "k", RateLimitAlgorithm: key.SlidingWindowAlgorithm}, true, nil, false},
+		{"fixed window is counted asynchronously", &key.ResponseKey{KeyId: "k", RateLimitOverTime: 10, RateLimitUnit: key.MinuteTimeUnit}, true, nil, false},
+		{"sliding window", &key.ResponseKey{KeyId: "k", RateLimitOverTime: 10, RateLimitUnit: key.MinuteTimeUnit, RateLimitAlgorithm: key.SlidingWindowAlgorithm}, true, []string{"sliding k 10 1m0s"}, false},
+		{"token bucket", &key.ResponseKey{KeyId: "k", RateLimitOverTime: 10, RateLimitUnit: key.HourTimeUnit, RateLimitAlgorithm: key.TokenBucketAlgorithm, RateLimitBurst: 20}, false, []string{"bucket k 10 1h0m0s 20"}, false},
+		{"unsupported unit", &key.ResponseKey{KeyId: "k", RateLimitOverTime: 10, RateLimitUnit: key.MonthTimeUnit, RateLimitAlgorithm: key.TokenBucketAlgorithm}, false, nil, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rl := &fakeRateLimiter{}
+			rlm := NewRateLimitManager(nil, nil, nil, nil, rl)
+
+			allowed, _, err := rlm.Allow(c.key)
+			assert.Equal(t, c.err, err != nil)
+			assert.Equal(t, c.allowed, allowed)
+			assert.Equal(t, c.calls, rl.calls)
+		})
+	}
+}
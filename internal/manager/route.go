@@ -79,6 +79,14 @@ func addDefaultValues(r *route.Route) {
 		r.CacheConfig.Ttl = "168h"
 	}
 
+	if r.CacheConfig.IsSemantic() && r.CacheConfig.SimilarityThreshold == 0 {
+		r.CacheConfig.SimilarityThreshold = 0.95
+	}
+
+	if r.CacheConfig.IsSemantic() && len(r.CacheConfig.EmbeddingModel) == 0 {
+		r.CacheConfig.EmbeddingModel = "text-embedding-3-small"
+	}
+
 	for _, step := range r.Steps {
 		if len(step.Timeout) == 0 {
 			step.Timeout = "5m"
@@ -96,6 +104,14 @@ func checkModelValidity(provider, model string) bool {
 		return contains(model, openaiSupportedModels)
 	}
 
+	if provider == "anthropic" {
+		return contains(model, anthropicSupportedModels)
+	}
+
+	if provider == "bedrock" {
+		return contains(model, bedrockSupportedModels)
+	}
+
 	return false
 }
 
@@ -159,6 +175,33 @@ var (
 		"text-embedding-ada-002",
 	}
 
+	anthropicSupportedModels = []string{
+		"claude-3-5-sonnet-latest",
+		"claude-3-5-sonnet-20241022",
+		"claude-3-5-sonnet-20240620",
+		"claude-3-5-haiku-latest",
+		"claude-3-5-haiku-20241022",
+		"claude-3-opus-latest",
+		"claude-3-opus-20240229",
+		"claude-3-sonnet-20240229",
+		"claude-3-haiku-20240307",
+	}
+
+	bedrockSupportedModels = []string{
+		"anthropic.claude-3-5-sonnet-20241022-v2:0",
+		"anthropic.claude-3-5-sonnet-20240620-v1:0",
+		"anthropic.claude-3-5-haiku-20241022-v1:0",
+		"anthropic.claude-3-opus-20240229-v1:0",
+		"anthropic.claude-3-sonnet-20240229-v1:0",
+		"anthropic.claude-3-haiku-20240307-v1:0",
+		"us.anthropic.claude-3-5-sonnet-20241022-v2:0",
+		"us.anthropic.claude-3-5-sonnet-20240620-v1:0",
+		"us.anthropic.claude-3-5-haiku-20241022-v1:0",
+		"us.anthropic.claude-3-opus-20240229-v1:0",
+		"us.anthropic.claude-3-sonnet-20240229-v1:0",
+		"us.anthropic.claude-3-haiku-20240307-v1:0",
+	}
+
 	supportedModels = []string{
 		"gpt-4o-2024-08-06",
 		"gpt-4o-2024-05-13",
@@ -197,6 +240,27 @@ var (
 		"gpt-3.5-turbo-16k-0613",
 		"ada",
 		"text-embedding-ada-002",
+		"claude-3-5-sonnet-latest",
+		"claude-3-5-sonnet-20241022",
+		"claude-3-5-sonnet-20240620",
+		"claude-3-5-haiku-latest",
+		"claude-3-5-haiku-20241022",
+		"claude-3-opus-latest",
+		"claude-3-opus-20240229",
+		"claude-3-sonnet-20240229",
+		"claude-3-haiku-20240307",
+		"anthropic.claude-3-5-sonnet-20241022-v2:0",
+		"anthropic.claude-3-5-sonnet-20240620-v1:0",
+		"anthropic.claude-3-5-haiku-20241022-v1:0",
+		"anthropic.claude-3-opus-20240229-v1:0",
+		"anthropic.claude-3-sonnet-20240229-v1:0",
+		"anthropic.claude-3-haiku-20240307-v1:0",
+		"us.anthropic.claude-3-5-sonnet-20241022-v2:0",
+		"us.anthropic.claude-3-5-sonnet-20240620-v1:0",
+		"us.anthropic.claude-3-5-haiku-20241022-v1:0",
+		"us.anthropic.claude-3-opus-20240229-v1:0",
+		"us.anthropic.claude-3-sonnet-20240229-v1:0",
+		"us.anthropic.claude-3-haiku-20240307-v1:0",
 	}
 
 	adaModels = []string{
@@ -242,11 +306,40 @@ var (
 		"gpt-3.5-turbo-0613",
 		"gpt-3.5-turbo-16k",
 		"gpt-3.5-turbo-16k-0613",
+		"claude-3-5-sonnet-latest",
+		"claude-3-5-sonnet-20241022",
+		"claude-3-5-sonnet-20240620",
+		"claude-3-5-haiku-latest",
+		"claude-3-5-haiku-20241022",
+		"claude-3-opus-latest",
+		"claude-3-opus-20240229",
+		"claude-3-sonnet-20240229",
+		"claude-3-haiku-20240307",
+		"anthropic.claude-3-5-sonnet-20241022-v2:0",
+		"anthropic.claude-3-5-sonnet-20240620-v1:0",
+		"anthropic.claude-3-5-haiku-20241022-v1:0",
+		"anthropic.claude-3-opus-20240229-v1:0",
+		"anthropic.claude-3-sonnet-20240229-v1:0",
+		"anthropic.claude-3-haiku-20240307-v1:0",
+		"us.anthropic.claude-3-5-sonnet-20241022-v2:0",
+		"us.anthropic.claude-3-5-sonnet-20240620-v1:0",
+		"us.anthropic.claude-3-5-haiku-20241022-v1:0",
+		"us.anthropic.claude-3-opus-20240229-v1:0",
+		"us.anthropic.claude-3-sonnet-20240229-v1:0",
+		"us.anthropic.claude-3-haiku-20240307-v1:0",
+	}
+
+	semanticCacheEmbeddingModels = []string{
+		"text-embedding-ada-002",
+		"text-embedding-3-small",
+		"text-embedding-3-large",
 	}
 
 	supportedProviders = []string{
 		"openai",
 		"azure",
+		"anthropic",
+		"bedrock",
 	}
 )
 
@@ -260,6 +353,16 @@ func contains(target string, source []string) bool {
 	return false
 }
 
+func containsOpenAiSetting(settings []*provider.Setting) bool {
+	for _, setting := range settings {
+		if setting.Provider == "openai" {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (m *RouteManager) validateRoute(r *route.Route) error {
 	fields := []string{}
 
@@ -302,7 +405,7 @@ func (m *RouteManager) validateRoute(r *route.Route) error {
 		}
 
 		if !contains(step.Provider, supportedProviders) {
-			return fmt.Errorf("steps.[%d].provider is not supported. Only azure and openai are supported", index)
+			return fmt.Errorf("steps.[%d].provider is not supported. Only azure, openai, anthropic and bedrock are supported", index)
 		}
 
 		if step.Provider == "azure" {
@@ -443,6 +546,24 @@ func (m *RouteManager) validateRoute(r *route.Route) error {
 		}
 	}
 
+	if r.CacheConfig != nil && len(r.CacheConfig.Mode) != 0 && r.CacheConfig.Mode != "exact" && r.CacheConfig.Mode != "semantic" {
+		fields = append(fields, "cacheConfig.mode")
+	}
+
+	if r.CacheConfig.IsSemantic() {
+		if containAda {
+			return internal_errors.NewValidationError("semantic cache is only supported for chat completion routes")
+		}
+
+		if r.CacheConfig.SimilarityThreshold < 0 || r.CacheConfig.SimilarityThreshold > 1 {
+			fields = append(fields, "cacheConfig.similarityThreshold")
+		}
+
+		if len(r.CacheConfig.EmbeddingModel) != 0 && !contains(r.CacheConfig.EmbeddingModel, semanticCacheEmbeddingModels) {
+			fields = append(fields, "cacheConfig.embeddingModel")
+		}
+	}
+
 	found, err := m.ks.GetKeys(nil, r.KeyIds, "")
 	if err != nil {
 		return err
@@ -458,6 +579,10 @@ func (m *RouteManager) validateRoute(r *route.Route) error {
 		if !r.ValidateSettings(settings) {
 			return errors.New("provider settings assosciated with the key cannot for accessing models specified in the route")
 		}
+
+		if r.CacheConfig.IsSemantic() && !containsOpenAiSetting(settings) {
+			return internal_errors.NewValidationError("semantic cache requires an openai provider setting associated with every key in the route")
+		}
 	}
 
 	_, err = m.s.GetRouteByPath(r.Path)
@@ -11,6 +11,7 @@ import (
 )
 
 type UserStorage interface {
+	GetUser(id string) (*user.User, error)
 	GetUsers(tags, keyIds, userIds []string, offset int, limit int) ([]*user.User, error)
 	CreateUser(u *user.User) (*user.User, error)
 	UpdateUser(id string, uu *user.UpdateUser) (*user.User, error)
@@ -33,6 +34,10 @@ func (m *UserManager) GetUsers(tags, keyIds, userIds []string, offset int, limit
 	return m.us.GetUsers(tags, keyIds, userIds, offset, limit)
 }
 
+func (m *UserManager) GetUser(id string) (*user.User, error) {
+	return m.us.GetUser(id)
+}
+
 func (m *UserManager) CreateUser(u *user.User) (*user.User, error) {
 	u.CreatedAt = time.Now().Unix()
 	u.UpdatedAt = time.Now().Unix()
@@ -17,6 +17,7 @@ type Consumer struct {
 type recorder interface {
 	RecordKeySpend(keyId string, micros int64, costLimitUnit key.TimeUnit) error
 	RecordUserSpend(userId string, micros int64, costLimitUnit key.TimeUnit) error
+	RecordBudgetSpend(k *key.ResponseKey, micros int64) error
 	RecordEvent(e *event.Event) error
 }
 
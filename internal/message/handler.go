@@ -38,6 +38,7 @@ type estimator interface {
 	EstimateCompletionCost(model string, tks int) (float64, error)
 	EstimateTotalCost(model string, promptTks, completionTks int) (float64, error)
 	EstimateEmbeddingsInputCost(model string, tks int) (float64, error)
+	EstimatePromptCost(model string, tks int) (float64, error)
 	EstimateChatCompletionPromptTokenCounts(model string, r *goopenai.ChatCompletionRequest) (int, error)
 }
 
@@ -77,6 +78,8 @@ type userManager interface {
 type rateLimitManager interface {
 	Increment(keyId string, timeUnit key.TimeUnit) error
 	IncrementUser(id string, timeUnit key.TimeUnit) error
+	CorrectTokens(keyId string, timeUnit key.TimeUnit, window int64, tks int64) error
+	CorrectUserTokens(id string, timeUnit key.TimeUnit, window int64, tks int64) error
 }
 
 type accessCache interface {
@@ -87,6 +90,15 @@ type userAccessCache interface {
 	Set(key string, timeUnit key.TimeUnit) error
 }
 
+type budgetNotifier interface {
+	NotifyKeySpend(k *key.ResponseKey)
+	NotifyUserSpend(u *user.User)
+}
+
+type healthRecorder interface {
+	RecordOutcome(settingId string, failed bool, latencyInMs int, threshold int, cooldown time.Duration) error
+}
+
 type Handler struct {
 	recorder recorder
 	log      *zap.Logger
@@ -101,9 +113,11 @@ type Handler struct {
 	rlm      rateLimitManager
 	ac       accessCache
 	uac      userAccessCache
+	hr       healthRecorder
+	bn       budgetNotifier
 }
 
-func NewHandler(r recorder, log *zap.Logger, ae anthropicEstimator, e estimator, vllme vllmEstimator, aze azureEstimator, v validator, uv userValidator, km keyManager, um userManager, rlm rateLimitManager, ac accessCache, uac accessCache) *Handler {
+func NewHandler(r recorder, log *zap.Logger, ae anthropicEstimator, e estimator, vllme vllmEstimator, aze azureEstimator, v validator, uv userValidator, km keyManager, um userManager, rlm rateLimitManager, ac accessCache, uac accessCache, hr healthRecorder, bn budgetNotifier) *Handler {
 	return &Handler{
 		recorder: r,
 		log:      log,
@@ -118,6 +132,8 @@ func NewHandler(r recorder, log *zap.Logger, ae anthropicEstimator, e estimator,
 		rlm:      rlm,
 		ac:       ac,
 		uac:      uac,
+		hr:       hr,
+		bn:       bn,
 	}
 }
 
@@ -313,6 +329,19 @@ func (h *Handler) handleUserValidationResult(u *user.User, cost float64) error {
 	return nil
 }
 
+func (h *Handler) recordProviderSettingOutcome(e *event.EventWithRequestAndContent) {
+	if h.hr == nil || len(e.SettingId) == 0 || e.Key.LoadBalancingConfig == nil || !e.UpstreamCalled {
+		return
+	}
+
+	lbc := e.Key.LoadBalancingConfig
+	err := h.hr.RecordOutcome(e.SettingId, e.UpstreamFailed, e.Event.LatencyInMs, lbc.GetFailureThreshold(), lbc.GetCooldown())
+	if err != nil {
+		telemetry.Incr("bricksllm.message.handler.record_provider_setting_outcome.record_outcome_error", nil, 1)
+		h.log.Debug("error when recording provider setting outcome", zap.Error(err))
+	}
+}
+
 func (h *Handler) HandleEventWithRequestAndResponse(m Message) error {
 	e, ok := m.Data.(*event.EventWithRequestAndContent)
 	if !ok {
@@ -321,7 +350,11 @@ func (h *Handler) HandleEventWithRequestAndResponse(m Message) error {
 		return errors.New("message data cannot be parsed as event with request and response")
 	}
 
-	if e.Key != nil && !e.Key.Revoked && e.Event != nil {
+	if e.Key != nil && e.Event != nil {
+		h.recordProviderSettingOutcome(e)
+	}
+
+	if e.Key != nil && !e.Key.Revoked && e.Event != nil && !e.IsRetriedAttempt {
 		err := h.decorateEvent(m)
 		if err != nil {
 			telemetry.Incr("bricksllm.message.handler.handle_event_with_request_and_response.decorate_event_error", nil, 1)
@@ -336,6 +369,14 @@ func (h *Handler) HandleEventWithRequestAndResponse(m Message) error {
 			if err != nil {
 				telemetry.Incr("bricksllm.message.handler.handle_event_with_request_and_response.record_key_spend_error", nil, 1)
 				h.log.Debug("error when recording key spend", zap.Error(err))
+			} else {
+				h.bn.NotifyKeySpend(e.Key)
+			}
+
+			err = h.recorder.RecordBudgetSpend(e.Key, micros)
+			if err != nil {
+				telemetry.Incr("bricksllm.message.handler.handle_event_with_request_and_response.record_budget_spend_error", nil, 1)
+				h.log.Debug("error when recording budget spend", zap.Error(err))
 			}
 
 			if len(e.Event.UserId) != 0 {
@@ -352,12 +393,14 @@ func (h *Handler) HandleEventWithRequestAndResponse(m Message) error {
 					if err != nil {
 						telemetry.Incr("bricksllm.message.handler.handle_event_with_request_and_response.record_user_spend_error", nil, 1)
 						h.log.Debug("error when recording user spend", zap.Error(err))
+					} else {
+						h.bn.NotifyUserSpend(u)
 					}
 				}
 			}
 		}
 
-		if len(e.Key.RateLimitUnit) != 0 {
+		if len(e.Key.RateLimitUnit) != 0 && e.Key.IsRateLimitedInFixedWindow() {
 			if err := h.rlm.Increment(e.Key.KeyId, e.Key.RateLimitUnit); err != nil {
 				telemetry.Incr("bricksllm.message.handler.handle_event_with_request_and_response.rate_limit_increment_error", nil, 1)
 
@@ -365,6 +408,24 @@ func (h *Handler) HandleEventWithRequestAndResponse(m Message) error {
 			}
 		}
 
+		tks := e.Event.PromptTokenCount + e.Event.CompletionTokenCount
+
+		if e.Key.TokenLimitOverTime != 0 {
+			if err := h.rlm.CorrectTokens(e.Key.KeyId, e.Key.TokenLimitUnit, e.TokenWindow, int64(tks-e.ReservedTokenCount)); err != nil {
+				telemetry.Incr("bricksllm.message.handler.handle_event_with_request_and_response.increment_tokens_error", nil, 1)
+
+				h.log.Debug("error when incrementing token count", zap.Error(err))
+			}
+		}
+
+		if e.User != nil && e.User.TokenLimitOverTime != 0 {
+			if err := h.rlm.CorrectUserTokens(e.User.Id, e.User.TokenLimitUnit, e.UserTokenWindow, int64(tks-e.ReservedUserTokenCount)); err != nil {
+				telemetry.Incr("bricksllm.message.handler.handle_event_with_request_and_response.increment_user_tokens_error", nil, 1)
+
+				h.log.Debug("error when incrementing user token count", zap.Error(err))
+			}
+		}
+
 		if u != nil {
 			if len(u.RateLimitUnit) != 0 {
 				if err := h.rlm.IncrementUser(u.Id, u.RateLimitUnit); err != nil {
@@ -386,7 +447,6 @@ func (h *Handler) HandleEventWithRequestAndResponse(m Message) error {
 			telemetry.Incr("bricksllm.message.handler.handle_event_with_request_and_response.handle_validation_result_error", nil, 1)
 			h.log.Debug("error when handling validation result", zap.Error(err))
 		}
-
 	}
 
 	start := time.Now()
@@ -649,6 +709,65 @@ func (h *Handler) decorateEvent(m Message) error {
 		}
 	}
 
+	if strings.HasPrefix(e.Event.Path, "/api/routes/") && e.Event.PromptTokenCount == 0 && (e.Event.Provider == "openai" || e.Event.Provider == "azure") {
+		ccr, ok := e.Request.(*goopenai.ChatCompletionRequest)
+		if ok && ccr.Stream {
+			model := e.Event.Model
+			if e.Event.Provider == "azure" {
+				model = "gpt-3.5-turbo"
+
+				if strings.HasPrefix(e.Event.Model, "gpt-4o") {
+					model = "gpt-4o"
+				}
+			}
+
+			tks, err := h.e.EstimateChatCompletionPromptTokenCounts(model, ccr)
+			if err != nil {
+				telemetry.Incr("bricksllm.message.handler.decorate_event.estimate_chat_completion_prompt_token_counts_error", nil, 1)
+				return err
+			}
+
+			completiontks := 0
+			var cost float64 = 0
+			var completionCost float64 = 0
+
+			if e.Event.Provider == "azure" {
+				cost, err = h.aze.EstimatePromptCost(e.Event.Model, tks)
+				if err != nil {
+					telemetry.Incr("bricksllm.message.handler.decorate_event.estimate_prompt_cost_error", nil, 1)
+					return err
+				}
+
+				completiontks, completionCost, err = h.aze.EstimateChatCompletionStreamCostWithTokenCounts(e.Event.Model, e.Content)
+				if err != nil {
+					telemetry.Incr("bricksllm.message.handler.decorate_event.estimate_chat_completion_stream_cost_with_token_counts_error", nil, 1)
+					return err
+				}
+			}
+
+			if e.Event.Provider == "openai" {
+				cost, err = h.e.EstimatePromptCost(e.Event.Model, tks)
+				if err != nil {
+					telemetry.Incr("bricksllm.message.handler.decorate_event.estimate_prompt_cost_error", nil, 1)
+					return err
+				}
+
+				completiontks, completionCost, err = h.e.EstimateChatCompletionStreamCostWithTokenCounts(e.Event.Model, e.Content)
+				if err != nil {
+					telemetry.Incr("bricksllm.message.handler.decorate_event.estimate_chat_completion_stream_cost_with_token_counts_error", nil, 1)
+					return err
+				}
+			}
+
+			e.Event.PromptTokenCount = tks
+			e.Event.CompletionTokenCount = completiontks
+
+			if e.Event.Status == http.StatusOK {
+				e.Event.CostInUsd = cost + completionCost
+			}
+		}
+	}
+
 	if e.Event.Path == "/api/providers/vllm/v1/chat/completions" {
 		ccr, ok := e.Request.(*vllm.ChatRequest)
 		if !ok {
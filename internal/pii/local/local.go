@@ -0,0 +1,249 @@
+package local
+
+import (
+	"math/big"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/pii"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+)
+
+type matcher struct {
+	entityType string
+	regex      *regexp.Regexp
+	group      int
+	validate   func(string) bool
+}
+
+// matchers are evaluated in order. A match that overlaps with a span
+// claimed by an earlier matcher is discarded.
+var matchers = []*matcher{
+	{
+		entityType: "AWS_ACCESS_KEY",
+		regex:      regexp.MustCompile(`\b(?:AKIA|ASIA|ABIA|ACCA|AGPA|AIDA|AIPA|ANPA|ANVA|AROA|APKA|ASCA)[A-Z0-9]{16}\b`),
+	},
+	{
+		entityType: "AWS_SECRET_KEY",
+		regex:      regexp.MustCompile(`(?i)aws.{0,20}?(?:secret|private)?.{0,20}?key.{0,5}?[\s:="']+([A-Za-z0-9/+]{40})(?:[^A-Za-z0-9/+]|$)`),
+		group:      1,
+	},
+	{
+		entityType: "URL",
+		regex:      regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"']+[^\s<>"'.,;:!?)\]]`),
+	},
+	{
+		entityType: "EMAIL",
+		regex:      regexp.MustCompile(`\b[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}\b`),
+	},
+	{
+		entityType: "INTERNATIONAL_BANK_ACCOUNT_NUMBER",
+		regex:      regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,3})?\b`),
+		validate:   isIbanValid,
+	},
+	{
+		entityType: "CREDIT_DEBIT_NUMBER",
+		regex:      regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		validate:   isCreditCardNumberValid,
+	},
+	{
+		entityType: "SSN",
+		regex:      regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+		validate:   isSsnValid,
+	},
+	{
+		entityType: "US_INDIVIDUAL_TAX_IDENTIFICATION_NUMBER",
+		regex:      regexp.MustCompile(`\b9\d{2}-(?:5[0-9]|6[0-5]|7\d|8[0-8]|9[0-2]|9[4-9])-\d{4}\b`),
+	},
+	{
+		entityType: "CA_SOCIAL_INSURANCE_NUMBER",
+		regex:      regexp.MustCompile(`\b\d{3}[ -]\d{3}[ -]\d{3}\b`),
+		validate:   isLuhnValid,
+	},
+	{
+		entityType: "UK_NATIONAL_INSURANCE_NUMBER",
+		regex:      regexp.MustCompile(`\b[A-CEGHJ-PR-TW-Z][A-CEGHJ-NPR-TW-Z] ?\d{2} ?\d{2} ?\d{2} ?[A-D]\b`),
+	},
+	{
+		entityType: "MAC_ADDRESS",
+		regex:      regexp.MustCompile(`\b[0-9A-Fa-f]{2}([:-])[0-9A-Fa-f]{2}(?:[:-][0-9A-Fa-f]{2}){4}\b`),
+		validate:   isMacAddressValid,
+	},
+	{
+		entityType: "IP_ADDRESS",
+		regex:      regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`),
+		validate:   isIpAddressValid,
+	},
+	{
+		entityType: "IP_ADDRESS",
+		regex:      regexp.MustCompile(`(?:[0-9A-Fa-f]{0,4}:){2,7}[0-9A-Fa-f]{0,4}`),
+		validate:   isIpv6AddressValid,
+	},
+	{
+		entityType: "PHONE",
+		regex:      regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{3}\) ?|\b\d{3}[ .-]?)\d{3}[ .-]?\d{4}\b`),
+	},
+}
+
+type Detector struct{}
+
+func NewDetector() *Detector {
+	return &Detector{}
+}
+
+func (d *Detector) Detect(input []string) (*pii.Result, error) {
+	start := time.Now()
+
+	result := &pii.Result{
+		Detections: make([]*pii.Detection, 0, len(input)),
+	}
+
+	for _, text := range input {
+		result.Detections = append(result.Detections, &pii.Detection{
+			Input:    text,
+			Entities: detect(text),
+		})
+	}
+
+	telemetry.Timing("bricksllm.local.detect.latency_in_ms", time.Since(start), nil, 1)
+
+	return result, nil
+}
+
+func detect(text string) []*pii.Entity {
+	entities := []*pii.Entity{}
+
+	for _, m := range matchers {
+		for _, loc := range m.regex.FindAllStringSubmatchIndex(text, -1) {
+			begin, end := loc[2*m.group], loc[2*m.group+1]
+			if begin < 0 || isOverlapping(entities, begin, end) {
+				continue
+			}
+
+			if m.validate != nil && !m.validate(text[begin:end]) {
+				continue
+			}
+
+			entities = append(entities, &pii.Entity{
+				BeginOffset: begin,
+				EndOffset:   end,
+				Type:        m.entityType,
+			})
+		}
+	}
+
+	sort.Slice(entities, func(i, j int) bool {
+		return entities[i].BeginOffset < entities[j].BeginOffset
+	})
+
+	return entities
+}
+
+func isOverlapping(entities []*pii.Entity, begin, end int) bool {
+	for _, e := range entities {
+		if begin < e.EndOffset && e.BeginOffset < end {
+			return true
+		}
+	}
+
+	return false
+}
+
+func stripSeparators(s string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(s)
+}
+
+func isLuhnValid(s string) bool {
+	digits := stripSeparators(s)
+
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		n := int(digits[i] - '0')
+		if n < 0 || n > 9 {
+			return false
+		}
+
+		if double {
+			n *= 2
+			if n > 9 {
+				n -= 9
+			}
+		}
+
+		sum += n
+		double = !double
+	}
+
+	return sum%10 == 0
+}
+
+func isCreditCardNumberValid(s string) bool {
+	digits := stripSeparators(s)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+
+	if strings.Count(digits, string(digits[0])) == len(digits) {
+		return false
+	}
+
+	return isLuhnValid(digits)
+}
+
+func isSsnValid(s string) bool {
+	parts := strings.Split(s, "-")
+	if len(parts) != 3 {
+		return false
+	}
+
+	area, group, serial := parts[0], parts[1], parts[2]
+	if area == "000" || area == "666" || area[0] == '9' {
+		return false
+	}
+
+	return group != "00" && serial != "0000"
+}
+
+func isIbanValid(s string) bool {
+	iban := strings.ReplaceAll(s, " ", "")
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+
+	rearranged := iban[4:] + iban[:4]
+
+	var sb strings.Builder
+	for _, r := range rearranged {
+		switch {
+		case r >= '0' && r <= '9':
+			sb.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			sb.WriteString(big.NewInt(int64(r-'A') + 10).String())
+		default:
+			return false
+		}
+	}
+
+	n, ok := new(big.Int).SetString(sb.String(), 10)
+	if !ok {
+		return false
+	}
+
+	return new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+func isMacAddressValid(s string) bool {
+	return !(strings.Contains(s, ":") && strings.Contains(s, "-"))
+}
+
+func isIpAddressValid(s string) bool {
+	return net.ParseIP(s) != nil
+}
+
+func isIpv6AddressValid(s string) bool {
+	return len(strings.Trim(s, ":")) != 0 && isIpAddressValid(s)
+}
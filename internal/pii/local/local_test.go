@@ -0,0 +1,95 @@
+package local
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsLuhnValid(t *testing.T) {
+	cases := []struct {
+		input string
+		valid bool
+	}{
+		{"79927398713", true},
+		{"79927398710", false},
+		{"046 454 286", true},
+		{"046 454 287", false},
+		{"4111-1111-1111-1111", true},
+		{"4111-1111-1111-1112", false},
+		{"12a4", false},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.valid, isLuhnValid(c.input), c.input)
+	}
+}
+
+func TestIsCreditCardNumberValid(t *testing.T) {
+	cases := []struct {
+		input string
+		valid bool
+	}{
+		{"4111 1111 1111 1111", true},
+		{"5500-0000-0000-0004", true},
+		{"378282246310005", true},
+		{"4111 1111 1111 1112", false},
+		{"0000 0000 0000 0000", false},
+		{"411111111111", false},
+		{"41111111111111111111", false},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.valid, isCreditCardNumberValid(c.input), c.input)
+	}
+}
+
+func TestIsSsnValid(t *testing.T) {
+	cases := []struct {
+		input string
+		valid bool
+	}{
+		{"123-45-6789", true},
+		{"078-05-1120", true},
+		{"000-45-6789", false},
+		{"666-45-6789", false},
+		{"912-45-6789", false},
+		{"123-00-6789", false},
+		{"123-45-0000", false},
+		{"123456789", false},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.valid, isSsnValid(c.input), c.input)
+	}
+}
+
+func TestIsIbanValid(t *testing.T) {
+	cases := []struct {
+		input string
+		valid bool
+	}{
+		{"GB82 WEST 1234 5698 7654 32", true},
+		{"DE89370400440532013000", true},
+		{"FR1420041010050500013M02606", true},
+		{"GB82 WEST 1234 5698 7654 33", false},
+		{"DE89370400440532013001", false},
+		{"GB82WEST12", false},
+		{"gb82west12345698765432", false},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.valid, isIbanValid(c.input), c.input)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	entities := detect("card 4111 1111 1111 1111, ssn 123-45-6789, iban GB82 WEST 1234 5698 7654 32, fake card 4111 1111 1111 1112")
+
+	types := []string{}
+	for _, e := range entities {
+		types = append(types, e.Type)
+	}
+
+	assert.Equal(t, []string{"CREDIT_DEBIT_NUMBER", "SSN", "INTERNATIONAL_BANK_ACCOUNT_NUMBER"}, types)
+}
@@ -5,22 +5,79 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/sashabaranov/go-openai"
+	"github.com/bricks-cloud/bricksllm/internal/event"
+	"github.com/bricks-cloud/bricksllm/internal/hasher"
+	"github.com/bricks-cloud/bricksllm/internal/message"
+	"github.com/bricks-cloud/bricksllm/internal/policy"
+	"github.com/bricks-cloud/bricksllm/internal/provider"
+	"github.com/bricks-cloud/bricksllm/internal/provider/anthropic"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+	"github.com/bricks-cloud/bricksllm/internal/util"
+	"go.uber.org/zap"
+
 	goopenai "github.com/sashabaranov/go-openai"
 )
 
-type OpenAiDetector struct {
-	client *goopenai.Client
+const (
+	defaultCacheTtl       = time.Hour
+	defaultAnthropicModel = "claude-3-haiku-20240307"
+	detectionPath         = "/api/policies/custom/detection"
+)
+
+type settingsManager interface {
+	GetSettingViaCache(id string) (*provider.Setting, error)
+}
+
+type decryptor interface {
+	Decrypt(input string, headers map[string]string) (string, error)
+	Enabled() bool
+}
+
+type cache interface {
+	StoreBytes(key string, value []byte, ttl time.Duration) error
+	GetBytes(key string) ([]byte, error)
+}
+
+type estimator interface {
+	EstimateTotalCost(model string, promptTks, completionTks int) (float64, error)
+}
+
+type publisher interface {
+	Publish(message.Message)
+}
+
+type Detector struct {
+	client http.Client
 	rt     time.Duration
+	key    string
+	psm    settingsManager
+	d      decryptor
+	ca     cache
+	pub    publisher
+	ce     estimator
+	ace    estimator
+	aoe    estimator
+	log    *zap.Logger
 }
 
-func NewOpenAiDetector(rt time.Duration, key string) *OpenAiDetector {
-	return &OpenAiDetector{
-		client: goopenai.NewClient(key),
+func NewDetector(rt time.Duration, key string, psm settingsManager, d decryptor, ca cache, pub publisher, ce, ace, aoe estimator, log *zap.Logger) *Detector {
+	return &Detector{
+		client: http.Client{},
 		rt:     rt,
+		key:    key,
+		psm:    psm,
+		d:      d,
+		ca:     ca,
+		pub:    pub,
+		ce:     ce,
+		ace:    ace,
+		aoe:    aoe,
+		log:    log,
 	}
 }
 
@@ -28,42 +85,395 @@ type result struct {
 	RelevantTextsFound bool `json:"relevant_texts_found"`
 }
 
-func (c *OpenAiDetector) Detect(input []string, requirements []string) (bool, error) {
-	requirement := strings.Join(requirements, ",")
-
-	resp, err := c.client.CreateChatCompletion(
-		context.Background(),
-		openai.ChatCompletionRequest{
-			Model: openai.GPT4Turbo0125,
-			Messages: []openai.ChatCompletionMessage{
-				{
-					Role:    openai.ChatMessageRoleSystem,
-					Content: "You are a text classifier. You take in an array of strings and ouput JSON with one field called relevant_texts_found. relevant_texts_found is a boolean field that indicates whether or not the given text contains: " + requirement,
-				},
-				{
-					Role:    openai.ChatMessageRoleUser,
-					Content: fmt.Sprintf("this is the input [%s]", strings.Join(input, " ,")),
-				},
+type usage struct {
+	model            string
+	promptTokens     int
+	completionTokens int
+}
+
+func getSystemPrompt(requirements []string) string {
+	return "You are a text classifier. You take in an array of strings and ouput JSON with one field called relevant_texts_found. relevant_texts_found is a boolean field that indicates whether or not the given text contains: " + strings.Join(requirements, ",")
+}
+
+func getUserPrompt(input []string) string {
+	return fmt.Sprintf("this is the input [%s]", strings.Join(input, " ,"))
+}
+
+func parseResult(content string) (bool, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return false, errors.New("classifier response does not contain json")
+	}
+
+	r := &result{}
+	err := json.Unmarshal([]byte(content[start:end+1]), r)
+	if err != nil {
+		return false, err
+	}
+
+	return r.RelevantTextsFound, nil
+}
+
+func getVerdictCacheKey(input []string, requirements []string, dc *policy.CustomDetectorConfig) string {
+	data, _ := json.Marshal(map[string]any{
+		"provider":     dc.Provider,
+		"settingId":    dc.SettingId,
+		"model":        dc.Model,
+		"url":          dc.Url,
+		"requirements": requirements,
+		"input":        input,
+	})
+
+	return "custom-policy-verdict-" + hasher.Hash(string(data))
+}
+
+func getDuration(val string, fallback time.Duration) time.Duration {
+	if len(val) == 0 {
+		return fallback
+	}
+
+	parsed, err := time.ParseDuration(val)
+	if err != nil {
+		return fallback
+	}
+
+	return parsed
+}
+
+func (d *Detector) Detect(input []string, requirements []string, policyId string, dc *policy.CustomDetectorConfig) (bool, error) {
+	if dc == nil {
+		dc = &policy.CustomDetectorConfig{}
+	}
+
+	if len(dc.Provider) == 0 {
+		dc = &policy.CustomDetectorConfig{
+			Provider:  policy.OpenAiDetectorProvider,
+			SettingId: dc.SettingId,
+			Model:     dc.Model,
+			Url:       dc.Url,
+			Timeout:   dc.Timeout,
+			CacheTtl:  dc.CacheTtl,
+		}
+	}
+
+	cacheKey := getVerdictCacheKey(input, requirements, dc)
+	if d.ca != nil {
+		cached, _ := d.ca.GetBytes(cacheKey)
+		if len(cached) != 0 {
+			telemetry.Incr("bricksllm.custom_policy.detector.detect.cache_hit", nil, 1)
+			return string(cached) == "true", nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), getDuration(dc.Timeout, d.rt))
+	defer cancel()
+
+	start := time.Now()
+
+	var (
+		found bool
+		u     *usage
+		err   error
+	)
+
+	switch dc.Provider {
+	case policy.OpenAiDetectorProvider, policy.AzureDetectorProvider, policy.VllmDetectorProvider:
+		found, u, err = d.detectWithChatCompletion(ctx, input, requirements, dc)
+	case policy.AnthropicDetectorProvider:
+		found, u, err = d.detectWithAnthropic(ctx, input, requirements, dc)
+	case policy.HttpDetectorProvider:
+		found, err = d.detectWithHttp(ctx, input, requirements, dc)
+	default:
+		err = fmt.Errorf("custom detector provider %s is not supported", dc.Provider)
+	}
+
+	telemetry.Timing("bricksllm.custom_policy.detector.detect.latency_in_ms", time.Since(start), []string{"provider:" + string(dc.Provider)}, 1)
+
+	if u != nil {
+		d.publishDetectionEvent(policyId, dc, u, time.Since(start))
+	}
+
+	if err != nil {
+		telemetry.Incr("bricksllm.custom_policy.detector.detect.error", []string{"provider:" + string(dc.Provider)}, 1)
+		return false, err
+	}
+
+	if d.ca != nil {
+		err = d.ca.StoreBytes(cacheKey, []byte(strconv.FormatBool(found)), getDuration(dc.CacheTtl, defaultCacheTtl))
+		if err != nil {
+			telemetry.Incr("bricksllm.custom_policy.detector.detect.store_verdict_error", nil, 1)
+			d.log.Debug("error when storing custom policy verdict", zap.Error(err))
+		}
+	}
+
+	return found, nil
+}
+
+func (d *Detector) getSetting(dc *policy.CustomDetectorConfig) (*provider.Setting, error) {
+	if len(dc.SettingId) == 0 {
+		return nil, nil
+	}
+
+	setting, err := d.psm.GetSettingViaCache(dc.SettingId)
+	if err != nil {
+		return nil, err
+	}
+
+	if setting == nil {
+		return nil, fmt.Errorf("provider setting %s is not found", dc.SettingId)
+	}
+
+	if setting.Provider != string(dc.Provider) {
+		return nil, fmt.Errorf("provider setting %s does not belong to %s", dc.SettingId, dc.Provider)
+	}
+
+	return setting, nil
+}
+
+func (d *Detector) getApiKey(setting *provider.Setting) string {
+	apiKey := setting.Setting["apikey"]
+	if len(apiKey) == 0 || d.d == nil || !d.d.Enabled() {
+		return apiKey
+	}
+
+	decrypted, err := d.d.Decrypt(apiKey, map[string]string{"X-UPDATED-AT": strconv.FormatInt(setting.UpdatedAt, 10)})
+	if err != nil {
+		telemetry.Incr("bricksllm.custom_policy.detector.get_api_key.decrypt_error", nil, 1)
+		return apiKey
+	}
+
+	return decrypted
+}
+
+func (d *Detector) detectWithChatCompletion(ctx context.Context, input []string, requirements []string, dc *policy.CustomDetectorConfig) (bool, *usage, error) {
+	setting, err := d.getSetting(dc)
+	if err != nil {
+		return false, nil, err
+	}
+
+	model := dc.Model
+	var cfg goopenai.ClientConfig
+
+	switch dc.Provider {
+	case policy.AzureDetectorProvider:
+		cfg = goopenai.DefaultAzureConfig(d.getApiKey(setting), fmt.Sprintf("https://%s.openai.azure.com", setting.Setting["resourceName"]))
+	case policy.VllmDetectorProvider:
+		cfg = goopenai.DefaultConfig(d.getApiKey(setting))
+		cfg.BaseURL = strings.TrimSuffix(setting.Setting["url"], "/") + "/v1"
+	default:
+		key := d.key
+		if setting != nil {
+			key = d.getApiKey(setting)
+		}
+
+		cfg = goopenai.DefaultConfig(key)
+		if len(model) == 0 {
+			model = goopenai.GPT4Turbo0125
+		}
+	}
+
+	cfg.HTTPClient = &d.client
+
+	req := goopenai.ChatCompletionRequest{
+		Model: model,
+		Messages: []goopenai.ChatCompletionMessage{
+			{
+				Role:    goopenai.ChatMessageRoleSystem,
+				Content: getSystemPrompt(requirements),
 			},
-			ResponseFormat: &goopenai.ChatCompletionResponseFormat{
-				Type: goopenai.ChatCompletionResponseFormatTypeJSONObject,
+			{
+				Role:    goopenai.ChatMessageRoleUser,
+				Content: getUserPrompt(input),
 			},
 		},
-	)
+	}
+
+	if dc.Provider != policy.VllmDetectorProvider {
+		req.ResponseFormat = &goopenai.ChatCompletionResponseFormat{
+			Type: goopenai.ChatCompletionResponseFormatTypeJSONObject,
+		}
+	}
+
+	resp, err := goopenai.NewClientWithConfig(cfg).CreateChatCompletion(ctx, req)
+	if err != nil {
+		return false, nil, err
+	}
+
+	u := &usage{
+		model:            model,
+		promptTokens:     resp.Usage.PromptTokens,
+		completionTokens: resp.Usage.CompletionTokens,
+	}
+
+	if len(resp.Choices) != 1 {
+		return false, u, fmt.Errorf("there are no choices from %s", dc.Provider)
+	}
+
+	found, err := parseResult(resp.Choices[0].Message.Content)
+	return found, u, err
+}
+
+func (d *Detector) detectWithAnthropic(ctx context.Context, input []string, requirements []string, dc *policy.CustomDetectorConfig) (bool, *usage, error) {
+	setting, err := d.getSetting(dc)
+	if err != nil {
+		return false, nil, err
+	}
+
+	model := dc.Model
+	if len(model) == 0 {
+		model = defaultAnthropicModel
+	}
+
+	data, err := json.Marshal(&anthropic.MessagesRequest{
+		Model:     model,
+		System:    getSystemPrompt(requirements),
+		MaxTokens: 100,
+		Messages: []anthropic.Message{
+			{
+				Role:    "user",
+				Content: getUserPrompt(input),
+			},
+		},
+	})
+	if err != nil {
+		return false, nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://api.anthropic.com/v1/messages", strings.NewReader(string(data)))
+	if err != nil {
+		return false, nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", d.getApiKey(setting))
+	req.Header.Set("anthropic-version", "2023-06-01")
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return false, nil, err
+	}
 
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return false, nil, fmt.Errorf("anthropic responded with status code %d", res.StatusCode)
+	}
+
+	mr := &anthropic.MessagesResponse{}
+	err = json.NewDecoder(res.Body).Decode(mr)
+	if err != nil {
+		return false, nil, err
+	}
+
+	u := &usage{
+		model:            model,
+		promptTokens:     mr.Usage.InputTokens,
+		completionTokens: mr.Usage.OutputTokens,
+	}
+
+	texts := []string{}
+	for _, c := range mr.Content {
+		if c.Type == "text" {
+			texts = append(texts, c.Text)
+		}
+	}
+
+	found, err := parseResult(strings.Join(texts, ""))
+	return found, u, err
+}
+
+type httpDetectionRequest struct {
+	Inputs       []string `json:"inputs"`
+	Requirements []string `json:"requirements"`
+}
+
+func (d *Detector) detectWithHttp(ctx context.Context, input []string, requirements []string, dc *policy.CustomDetectorConfig) (bool, error) {
+	data, err := json.Marshal(&httpDetectionRequest{
+		Inputs:       input,
+		Requirements: requirements,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dc.Url, strings.NewReader(string(data)))
 	if err != nil {
 		return false, err
 	}
 
-	if len(resp.Choices) == 1 {
-		r := &result{}
-		err = json.Unmarshal([]byte(resp.Choices[0].Message.Content), r)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("classifier responded with status code %d", res.StatusCode)
+	}
+
+	r := &result{}
+	err = json.NewDecoder(res.Body).Decode(r)
+	if err != nil {
+		return false, err
+	}
+
+	return r.RelevantTextsFound, nil
+}
+
+func (d *Detector) getEstimator(p policy.CustomDetectorProvider) estimator {
+	switch p {
+	case policy.OpenAiDetectorProvider:
+		return d.ce
+	case policy.AnthropicDetectorProvider:
+		return d.ace
+	case policy.AzureDetectorProvider:
+		return d.aoe
+	}
+
+	return nil
+}
+
+func (d *Detector) publishDetectionEvent(policyId string, dc *policy.CustomDetectorConfig, u *usage, dur time.Duration) {
+	if d.pub == nil {
+		return
+	}
+
+	var cost float64 = 0
+	if e := d.getEstimator(dc.Provider); e != nil {
+		estimated, err := e.EstimateTotalCost(u.model, u.promptTokens, u.completionTokens)
 		if err != nil {
-			return false, err
+			telemetry.Incr("bricksllm.custom_policy.detector.publish_detection_event.estimate_total_cost_error", nil, 1)
+			d.log.Debug("error when estimating custom policy detection cost", zap.Error(err))
 		}
 
-		return r.RelevantTextsFound, nil
+		cost = estimated
 	}
 
-	return false, errors.New("there are no choices from OpenAI")
+	d.pub.Publish(message.Message{
+		Type: "event",
+		Data: &event.EventWithRequestAndContent{
+			Event: &event.Event{
+				Id:                   util.NewUuid(),
+				CreatedAt:            time.Now().Unix(),
+				Tags:                 []string{},
+				CostInUsd:            cost,
+				Provider:             string(dc.Provider),
+				Model:                u.model,
+				Status:               http.StatusOK,
+				PromptTokenCount:     u.promptTokens,
+				CompletionTokenCount: u.completionTokens,
+				LatencyInMs:          int(dur.Milliseconds()),
+				Path:                 detectionPath,
+				Method:               http.MethodPost,
+				Request:              []byte(`{}`),
+				Response:             []byte(`{}`),
+				PolicyId:             policyId,
+				Metadata:             []byte(`{}`),
+			},
+		},
+	})
 }
@@ -0,0 +1,109 @@
+package custompolicy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/policy"
+	"github.com/bricks-cloud/bricksllm/internal/provider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeCache struct {
+	values map[string][]byte
+}
+
+func (c *fakeCache) StoreBytes(key string, value []byte, ttl time.Duration) error {
+	c.values[key] = value
+	return nil
+}
+
+func (c *fakeCache) GetBytes(key string) ([]byte, error) {
+	return c.values[key], nil
+}
+
+type fakeSettingsManager map[string]*provider.Setting
+
+func (sm fakeSettingsManager) GetSettingViaCache(id string) (*provider.Setting, error) {
+	return sm[id], nil
+}
+
+func TestParseResult(t *testing.T) {
+	cases := []struct {
+		content string
+		found   bool
+		err     bool
+	}{
+		{`{"relevant_texts_found": true}`, true, false},
+		{"```json\n{\"relevant_texts_found\": false}\n```", false, false},
+		{`the answer is {"relevant_texts_found": true}.`, true, false},
+		{`no json here`, false, true},
+		{`{"relevant_texts_found": "maybe"}`, false, true},
+	}
+
+	for _, c := range cases {
+		found, err := parseResult(c.content)
+		assert.Equal(t, c.err, err != nil, c.content)
+		assert.Equal(t, c.found, found, c.content)
+	}
+}
+
+func TestDetect_Http(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+
+		req := &httpDetectionRequest{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(req))
+		assert.Equal(t, []string{"hello"}, req.Inputs)
+		assert.Equal(t, []string{"greetings"}, req.Requirements)
+
+		w.Write([]byte(`{"relevant_texts_found": true}`))
+	}))
+	defer server.Close()
+
+	ca := &fakeCache{values: map[string][]byte{}}
+	d := NewDetector(time.Second, "", nil, nil, ca, nil, nil, nil, nil, zap.NewNop())
+	dc := &policy.CustomDetectorConfig{Provider: policy.HttpDetectorProvider, Url: server.URL}
+
+	for i := 0; i < 2; i++ {
+		found, err := d.Detect([]string{"hello"}, []string{"greetings"}, "policy", dc)
+		require.NoError(t, err)
+		assert.True(t, found)
+	}
+
+	assert.Equal(t, 1, calls)
+}
+
+func TestDetect_Vllm(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer vllm-key", r.Header.Get("Authorization"))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"{\"relevant_texts_found\": false}"}}],"usage":{"prompt_tokens":10,"completion_tokens":5}}`))
+	}))
+	defer server.Close()
+
+	sm := fakeSettingsManager{
+		"vllm-setting": {Id: "vllm-setting", Provider: "vllm", Setting: map[string]string{"url": server.URL + "/", "apikey": "vllm-key"}},
+		"openai":       {Id: "openai", Provider: "openai"},
+	}
+
+	d := NewDetector(time.Second, "", sm, nil, nil, nil, nil, nil, nil, zap.NewNop())
+
+	found, err := d.Detect([]string{"hello"}, []string{"greetings"}, "policy", &policy.CustomDetectorConfig{Provider: policy.VllmDetectorProvider, SettingId: "vllm-setting", Model: "llama"})
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	_, err = d.Detect([]string{"hello"}, []string{"greetings"}, "policy", &policy.CustomDetectorConfig{Provider: policy.VllmDetectorProvider, SettingId: "openai"})
+	assert.Error(t, err)
+
+	_, err = d.Detect([]string{"hello"}, []string{"greetings"}, "policy", &policy.CustomDetectorConfig{Provider: "unknown"})
+	assert.Error(t, err)
+}
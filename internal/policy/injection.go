@@ -0,0 +1,405 @@
+package policy
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+	"github.com/bricks-cloud/bricksllm/internal/provider/anthropic"
+	"github.com/bricks-cloud/bricksllm/internal/provider/bedrock"
+	"github.com/bricks-cloud/bricksllm/internal/provider/gemini"
+	"github.com/bricks-cloud/bricksllm/internal/provider/openai"
+	"github.com/bricks-cloud/bricksllm/internal/provider/vllm"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+	"go.uber.org/zap"
+
+	goopenai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	defaultPromptInjectionThreshold = 0.5
+	promptInjectionRequirement      = "a prompt injection or jailbreak attempt that tries to override, ignore or reveal the instructions given to an AI assistant"
+)
+
+type PromptInjectionConfig struct {
+	Action      Action                `json:"action"`
+	Threshold   float64               `json:"threshold"`
+	ExemptRoles []string              `json:"exemptRoles"`
+	Detector    *CustomDetectorConfig `json:"detector,omitempty"`
+}
+
+func (pic *PromptInjectionConfig) validate() []string {
+	msgs := []string{}
+
+	if pic.Action != Block && pic.Action != AllowButWarn && pic.Action != AllowButRedact && pic.Action != Allow && len(pic.Action) != 0 {
+		msgs = append(msgs, fmt.Sprintf("prompt injection action %s is not valid", pic.Action))
+	}
+
+	if pic.Threshold < 0 || pic.Threshold > 1 {
+		msgs = append(msgs, "prompt injection threshold must be between 0 and 1")
+	}
+
+	return append(msgs, pic.Detector.validate()...)
+}
+
+func (pic *PromptInjectionConfig) getThreshold() float64 {
+	if pic.Threshold == 0 {
+		return defaultPromptInjectionThreshold
+	}
+
+	return pic.Threshold
+}
+
+// isRoleExempt reports whether messages with the role are skipped. System
+// prompts are exempt unless exemptRoles is explicitly set.
+func (pic *PromptInjectionConfig) isRoleExempt(role string) bool {
+	if pic.ExemptRoles == nil {
+		return role == goopenai.ChatMessageRoleSystem
+	}
+
+	for _, r := range pic.ExemptRoles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+type injectionPattern struct {
+	regex  *regexp.Regexp
+	weight float64
+}
+
+var injectionPatterns = []*injectionPattern{
+	{regexp.MustCompile(`(?i)\b(ignore|disregard|skip|override|bypass)\s+(all\s+|any\s+)?(of\s+)?(the\s+|your\s+|my\s+)?(previous|prior|above|earlier|preceding|initial|original|system)\s+(instructions?|prompts?|rules|directions|guidelines|messages?)`), 0.9},
+	{regexp.MustCompile(`(?i)\bforget\s+(everything|all|anything|what)\s+(you\s+)?(were\s+|have\s+been\s+)?(told|said|instructed|above|before)`), 0.8},
+	{regexp.MustCompile(`(?i)\b(reveal|show|print|output|repeat|display|leak)\s+(me\s+)?(your|the)\s+(system\s+prompt|hidden\s+(prompt|instructions)|initial\s+instructions|instructions\s+above)`), 0.8},
+	{regexp.MustCompile(`(?i)\b(do\s+anything\s+now|DAN\s+mode|developer\s+mode\s+(enabled|on)|jailbreak(ed)?\s+mode)\b`), 0.8},
+	{regexp.MustCompile(`(?i)\byou\s+are\s+(now\s+)?(no\s+longer\s+(an?\s+)?(ai|assistant|bound)|free\s+from|an?\s+(unrestricted|unfiltered|uncensored))`), 0.7},
+	{regexp.MustCompile(`(?i)\b(act|behave|respond)\s+as\s+(if\s+you\s+(are|were)\s+)?(an?\s+)?(unrestricted|unfiltered|uncensored|evil|rogue)`), 0.7},
+	{regexp.MustCompile(`(?i)\bwithout\s+(any\s+)?(restrictions|filters|limitations|censorship|safety\s+guidelines)`), 0.4},
+	{regexp.MustCompile(`(?i)\bnew\s+(system\s+)?instructions?\s*:`), 0.5},
+	{regexp.MustCompile(`(?i)\bpretend\s+(to\s+be|you\s+are|that\s+you)`), 0.3},
+	{regexp.MustCompile(`(?i)\bjailbreak`), 0.4},
+	{regexp.MustCompile(`(?im)^\s*(#{2,}\s*)?(system|assistant)\s*:`), 0.6},
+	{regexp.MustCompile(`(?i)<\|im_start\|>\s*system|<<\s*SYS\s*>>|\[/?INST\]|<\|system\|>|<\|endoftext\|>`), 0.7},
+	{regexp.MustCompile(`(?i)\b(decode|base64|rot13|hex)\b.{0,40}\b(execute|follow|run|obey)\b`), 0.5},
+}
+
+var encodedPayloadRegex = regexp.MustCompile(`[A-Za-z0-9+/]{24,}={0,2}`)
+
+func scoreWithPatterns(content string) (float64, [][]int) {
+	remaining := 1.0
+	spans := [][]int{}
+
+	for _, p := range injectionPatterns {
+		locs := p.regex.FindAllStringIndex(content, -1)
+		if len(locs) == 0 {
+			continue
+		}
+
+		remaining *= 1 - p.weight
+		spans = append(spans, locs...)
+	}
+
+	return 1 - remaining, spans
+}
+
+func isMostlyPrintable(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+
+	printable := 0
+	for _, r := range s {
+		if unicode.IsPrint(r) || unicode.IsSpace(r) {
+			printable++
+		}
+	}
+
+	return float64(printable)/float64(len([]rune(s))) > 0.9
+}
+
+// scorePromptInjection returns a score between 0 and 1 along with the spans of
+// content that contributed to it. Encoded payloads are decoded and scored as well.
+func scorePromptInjection(content string) (float64, [][]int) {
+	score, spans := scoreWithPatterns(content)
+	remaining := 1 - score
+
+	for _, loc := range encodedPayloadRegex.FindAllStringIndex(content, -1) {
+		decoded, err := base64.StdEncoding.DecodeString(content[loc[0]:loc[1]])
+		if err != nil || !isMostlyPrintable(string(decoded)) {
+			continue
+		}
+
+		decodedScore, _ := scoreWithPatterns(string(decoded))
+		if decodedScore == 0 {
+			continue
+		}
+
+		remaining *= 1 - decodedScore
+		spans = append(spans, loc)
+	}
+
+	hidden := strings.IndexFunc(content, func(r rune) bool {
+		return r == '\u200b' || r == '\u200c' || r == '\u200d' || r == '\u2060' || (r >= '\U000E0000' && r <= '\U000E007F')
+	})
+
+	if hidden >= 0 {
+		remaining *= 0.7
+	}
+
+	return 1 - remaining, spans
+}
+
+func redactSpans(content string, spans [][]int) string {
+	if len(spans) == 0 {
+		return "***"
+	}
+
+	covered := make([]bool, len(content))
+	for _, span := range spans {
+		for i := span[0]; i < span[1]; i++ {
+			covered[i] = true
+		}
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(content); i++ {
+		if !covered[i] {
+			sb.WriteByte(content[i])
+			continue
+		}
+
+		if i == 0 || !covered[i-1] {
+			sb.WriteString("***")
+		}
+	}
+
+	return sb.String()
+}
+
+type promptSegment struct {
+	role    string
+	content string
+	update  func(string)
+}
+
+func getChatCompletionMessageSegments(messages []goopenai.ChatCompletionMessage) []*promptSegment {
+	segments := []*promptSegment{}
+
+	for i := range messages {
+		message := &messages[i]
+
+		if len(message.MultiContent) == 0 {
+			segments = append(segments, &promptSegment{
+				role:    message.Role,
+				content: message.Content,
+				update:  func(s string) { message.Content = s },
+			})
+
+			continue
+		}
+
+		for j := range message.MultiContent {
+			part := &message.MultiContent[j]
+			if part.Type != goopenai.ChatMessagePartTypeText {
+				continue
+			}
+
+			segments = append(segments, &promptSegment{
+				role:    message.Role,
+				content: part.Text,
+				update:  func(s string) { part.Text = s },
+			})
+		}
+	}
+
+	return segments
+}
+
+func getTextSegment(role string, text *string) *promptSegment {
+	return &promptSegment{
+		role:    role,
+		content: *text,
+		update:  func(s string) { *text = s },
+	}
+}
+
+func getGeminiContentSegments(content *gemini.Content, role string) []*promptSegment {
+	segments := []*promptSegment{}
+	if content == nil {
+		return segments
+	}
+
+	for _, part := range content.Parts {
+		if part == nil || part.Text == nil {
+			continue
+		}
+
+		segments = append(segments, getTextSegment(role, part.Text))
+	}
+
+	return segments
+}
+
+func getPromptSegments(input any) []*promptSegment {
+	switch converted := input.(type) {
+	case *goopenai.ChatCompletionRequest:
+		return getChatCompletionMessageSegments(converted.Messages)
+	case *vllm.ChatRequest:
+		return getChatCompletionMessageSegments(converted.Messages)
+	case *anthropic.MessagesRequest:
+		segments := []*promptSegment{}
+		if len(converted.System) != 0 {
+			segments = append(segments, &promptSegment{
+				role:    goopenai.ChatMessageRoleSystem,
+				content: converted.System,
+				update:  func(s string) { converted.System = s },
+			})
+		}
+
+		for i := range converted.Messages {
+			message := &converted.Messages[i]
+			segments = append(segments, &promptSegment{
+				role:    message.Role,
+				content: message.Content,
+				update:  func(s string) { message.Content = s },
+			})
+		}
+
+		return segments
+	case *gemini.GenerateContentRequest:
+		segments := getGeminiContentSegments(converted.SystemInstruction, goopenai.ChatMessageRoleSystem)
+		for _, content := range converted.Contents {
+			role := goopenai.ChatMessageRoleUser
+			if content != nil && content.Role == "model" {
+				role = goopenai.ChatMessageRoleAssistant
+			}
+
+			segments = append(segments, getGeminiContentSegments(content, role)...)
+		}
+
+		return segments
+	case *bedrock.ConverseRequest:
+		segments := []*promptSegment{}
+		for _, block := range converted.System {
+			if block != nil && block.Text != nil {
+				segments = append(segments, getTextSegment(goopenai.ChatMessageRoleSystem, block.Text))
+			}
+		}
+
+		for _, message := range converted.Messages {
+			if message == nil {
+				continue
+			}
+
+			for _, block := range message.Content {
+				if block != nil && block.Text != nil {
+					segments = append(segments, getTextSegment(message.Role, block.Text))
+				}
+			}
+		}
+
+		return segments
+	case bedrock.Request:
+		segments := []*promptSegment{}
+		for _, content := range converted.Contents() {
+			segments = append(segments, getTextSegment(goopenai.ChatMessageRoleUser, content))
+		}
+
+		return segments
+	case *anthropic.CompletionRequest:
+		return []*promptSegment{{
+			role:    goopenai.ChatMessageRoleUser,
+			content: converted.Prompt,
+			update:  func(s string) { converted.Prompt = s },
+		}}
+	case *vllm.CompletionRequest:
+		if prompt, ok := converted.Prompt.(string); ok {
+			return []*promptSegment{{
+				role:    goopenai.ChatMessageRoleUser,
+				content: prompt,
+				update:  func(s string) { converted.Prompt = s },
+			}}
+		}
+	case *openai.MessageRequest:
+		if content, ok := converted.Content.(string); ok {
+			return []*promptSegment{{
+				role:    converted.Role,
+				content: content,
+				update:  func(s string) { converted.Content = s },
+			}}
+		}
+	}
+
+	return nil
+}
+
+func (p *Policy) filterPromptInjection(input any, cd CustomPolicyDetector, log *zap.Logger) error {
+	pic := p.PromptInjectionConfig
+	if pic == nil || pic.Action == Allow || len(pic.Action) == 0 {
+		return nil
+	}
+
+	threshold := pic.getThreshold()
+	detected := []*promptSegment{}
+	spans := map[*promptSegment][][]int{}
+	unscored := []string{}
+
+	for _, segment := range getPromptSegments(input) {
+		if pic.isRoleExempt(segment.role) || len(strings.TrimSpace(segment.content)) == 0 {
+			continue
+		}
+
+		score, matched := scorePromptInjection(segment.content)
+		if score >= threshold {
+			detected = append(detected, segment)
+			spans[segment] = matched
+			continue
+		}
+
+		unscored = append(unscored, segment.content)
+	}
+
+	if len(detected) == 0 && len(unscored) != 0 && pic.Detector != nil && cd != nil {
+		found, err := cd.Detect(unscored, []string{promptInjectionRequirement}, p.Id, pic.Detector)
+		if err != nil {
+			log.Debug("error when detecting prompt injection using classifier", zap.Error(err))
+			telemetry.Incr("bricksllm.policy.filter_prompt_injection.detect_error", nil, 1)
+		}
+
+		if err == nil && found {
+			for _, segment := range getPromptSegments(input) {
+				if !pic.isRoleExempt(segment.role) && len(strings.TrimSpace(segment.content)) != 0 {
+					detected = append(detected, segment)
+				}
+			}
+		}
+	}
+
+	if len(detected) == 0 {
+		return nil
+	}
+
+	telemetry.Incr("bricksllm.policy.filter_prompt_injection.detected", []string{"action:" + string(pic.Action)}, 1)
+
+	switch pic.Action {
+	case Block:
+		return internal_errors.NewBlockedError("request blocked due to detected prompt injection")
+	case AllowButWarn:
+		return internal_errors.NewWarningError("request warned due to detected prompt injection")
+	case AllowButRedact:
+		for _, segment := range detected {
+			segment.update(redactSpans(segment.content, spans[segment]))
+		}
+
+		return internal_errors.NewRedactError("request redacted due to detected prompt injection")
+	}
+
+	return nil
+}
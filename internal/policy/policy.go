@@ -7,10 +7,13 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"time"
 
 	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
 	"github.com/bricks-cloud/bricksllm/internal/pii"
 	"github.com/bricks-cloud/bricksllm/internal/provider/anthropic"
+	"github.com/bricks-cloud/bricksllm/internal/provider/bedrock"
+	"github.com/bricks-cloud/bricksllm/internal/provider/gemini"
 	"github.com/bricks-cloud/bricksllm/internal/provider/openai"
 	"github.com/bricks-cloud/bricksllm/internal/provider/vllm"
 	"github.com/bricks-cloud/bricksllm/internal/telemetry"
@@ -88,28 +91,50 @@ type RegexConfig struct {
 	RegularExpressionRules []*RegularExpressionRule `json:"rules"`
 }
 
+type CustomDetectorProvider string
+
+const (
+	OpenAiDetectorProvider    CustomDetectorProvider = "openai"
+	AzureDetectorProvider     CustomDetectorProvider = "azure"
+	AnthropicDetectorProvider CustomDetectorProvider = "anthropic"
+	VllmDetectorProvider      CustomDetectorProvider = "vllm"
+	HttpDetectorProvider      CustomDetectorProvider = "http"
+)
+
+type CustomDetectorConfig struct {
+	Provider  CustomDetectorProvider `json:"provider"`
+	SettingId string                 `json:"settingId"`
+	Model     string                 `json:"model"`
+	Url       string                 `json:"url"`
+	Timeout   string                 `json:"timeout"`
+	CacheTtl  string                 `json:"cacheTtl"`
+}
+
 type CustomConfig struct {
-	CustomRules []*CustomRule `json:"rules"`
+	CustomRules []*CustomRule         `json:"rules"`
+	Detector    *CustomDetectorConfig `json:"detector,omitempty"`
 }
 
 type Policy struct {
-	Id           string        `json:"id"`
-	Name         string        `json:"name"`
-	CreatedAt    int64         `json:"createdAt"`
-	UpdatedAt    int64         `json:"updatedAt"`
-	Tags         []string      `json:"tags"`
-	Config       *Config       `json:"config"`
-	RegexConfig  *RegexConfig  `json:"regexConfig"`
-	CustomConfig *CustomConfig `json:"customConfig"`
+	Id                    string                 `json:"id"`
+	Name                  string                 `json:"name"`
+	CreatedAt             int64                  `json:"createdAt"`
+	UpdatedAt             int64                  `json:"updatedAt"`
+	Tags                  []string               `json:"tags"`
+	Config                *Config                `json:"config"`
+	RegexConfig           *RegexConfig           `json:"regexConfig"`
+	CustomConfig          *CustomConfig          `json:"customConfig"`
+	PromptInjectionConfig *PromptInjectionConfig `json:"promptInjectionConfig,omitempty"`
 }
 
 type UpdatePolicy struct {
-	Name         string        `json:"name"`
-	UpdatedAt    int64         `json:"updatedAt"`
-	Tags         []string      `json:"tags"`
-	Config       *Config       `json:"config"`
-	RegexConfig  *RegexConfig  `json:"regexConfig"`
-	CustomConfig *CustomConfig `json:"customConfig"`
+	Name                  string                 `json:"name"`
+	UpdatedAt             int64                  `json:"updatedAt"`
+	Tags                  []string               `json:"tags"`
+	Config                *Config                `json:"config"`
+	RegexConfig           *RegexConfig           `json:"regexConfig"`
+	CustomConfig          *CustomConfig          `json:"customConfig"`
+	PromptInjectionConfig *PromptInjectionConfig `json:"promptInjectionConfig,omitempty"`
 }
 
 func extractTextContents(input any) []string {
@@ -155,6 +180,14 @@ func (p *UpdatePolicy) Validate() error {
 		}
 	}
 
+	if p.CustomConfig != nil {
+		msgs = append(msgs, p.CustomConfig.Detector.validate()...)
+	}
+
+	if p.PromptInjectionConfig != nil {
+		msgs = append(msgs, p.PromptInjectionConfig.validate()...)
+	}
+
 	if len(msgs) != 0 {
 		return internal_errors.NewValidationError("policy is not valid: " + strings.Join(msgs, " ,"))
 	}
@@ -162,6 +195,52 @@ func (p *UpdatePolicy) Validate() error {
 	return nil
 }
 
+func (dc *CustomDetectorConfig) validate() []string {
+	if dc == nil {
+		return nil
+	}
+
+	msgs := []string{}
+
+	switch dc.Provider {
+	case OpenAiDetectorProvider, "":
+	case AzureDetectorProvider:
+		if len(dc.SettingId) == 0 {
+			msgs = append(msgs, "custom detector settingId is required for azure")
+		}
+
+		if len(dc.Model) == 0 {
+			msgs = append(msgs, "custom detector model is required for azure")
+		}
+	case AnthropicDetectorProvider, VllmDetectorProvider:
+		if len(dc.SettingId) == 0 {
+			msgs = append(msgs, fmt.Sprintf("custom detector settingId is required for %s", dc.Provider))
+		}
+	case HttpDetectorProvider:
+		if len(dc.Url) == 0 {
+			msgs = append(msgs, "custom detector url is required for http")
+		}
+	default:
+		msgs = append(msgs, fmt.Sprintf("custom detector provider %s is not supported", dc.Provider))
+	}
+
+	if len(dc.Timeout) != 0 {
+		parsed, err := time.ParseDuration(dc.Timeout)
+		if err != nil || parsed <= 0 {
+			msgs = append(msgs, "custom detector timeout is invalid")
+		}
+	}
+
+	if len(dc.CacheTtl) != 0 {
+		parsed, err := time.ParseDuration(dc.CacheTtl)
+		if err != nil || parsed < 0 {
+			msgs = append(msgs, "custom detector cacheTtl is invalid")
+		}
+	}
+
+	return msgs
+}
+
 type Request struct {
 	Contents []string `json:"contents"`
 	Policy   *Policy  `json:"policy"`
@@ -195,6 +274,14 @@ func (p *Policy) Validate() error {
 		}
 	}
 
+	if p.CustomConfig != nil {
+		msgs = append(msgs, p.CustomConfig.Detector.validate()...)
+	}
+
+	if p.PromptInjectionConfig != nil {
+		msgs = append(msgs, p.PromptInjectionConfig.validate()...)
+	}
+
 	if len(msgs) != 0 {
 		return internal_errors.NewValidationError("policy is not valid: " + strings.Join(msgs, " ,"))
 	}
@@ -202,16 +289,15 @@ func (p *Policy) Validate() error {
 	return nil
 }
 
-func (p *Policy) Filter(client http.Client, input any, scanner Scanner, cd CustomPolicyDetector, log *zap.Logger) error {
-	if p == nil || scanner == nil || input == nil {
-		return nil
+func (p *Policy) ShouldInspect() bool {
+	if p == nil {
+		return false
 	}
 
-	shouldInspect := false
 	if p.Config != nil {
 		for _, action := range p.Config.Rules {
 			if action != Allow {
-				shouldInspect = true
+				return true
 			}
 		}
 	}
@@ -219,7 +305,7 @@ func (p *Policy) Filter(client http.Client, input any, scanner Scanner, cd Custo
 	if p.RegexConfig != nil {
 		for _, regexr := range p.RegexConfig.RegularExpressionRules {
 			if regexr.Action != Allow {
-				shouldInspect = true
+				return true
 			}
 		}
 	}
@@ -227,12 +313,38 @@ func (p *Policy) Filter(client http.Client, input any, scanner Scanner, cd Custo
 	if p.CustomConfig != nil {
 		for _, cr := range p.CustomConfig.CustomRules {
 			if cr.Action != Allow {
-				shouldInspect = true
+				return true
 			}
 		}
 	}
 
-	if !shouldInspect {
+	return false
+}
+
+func (p *Policy) Filter(client http.Client, input any, scanner Scanner, cd CustomPolicyDetector, log *zap.Logger) error {
+	if p == nil || scanner == nil || input == nil {
+		return nil
+	}
+
+	injectionErr := p.filterPromptInjection(input, cd, log)
+	if _, ok := injectionErr.(*internal_errors.BlockedError); ok {
+		return injectionErr
+	}
+
+	err := p.filterInput(input, scanner, cd, log)
+	if err == nil {
+		return injectionErr
+	}
+
+	if _, ok := err.(*internal_errors.RedactError); ok && injectionErr != nil {
+		return injectionErr
+	}
+
+	return err
+}
+
+func (p *Policy) filterInput(input any, scanner Scanner, cd CustomPolicyDetector, log *zap.Logger) error {
+	if !p.ShouldInspect() {
 		return nil
 	}
 
@@ -473,6 +585,23 @@ func (p *Policy) Filter(client http.Client, input any, scanner Scanner, cd Custo
 
 		return nil
 
+	case *gemini.GenerateContentRequest:
+		return p.filterContents(getGeminiPartTexts(input.(*gemini.GenerateContentRequest).TextParts()), scanner, cd, log)
+
+	case *gemini.EmbedContentRequest:
+		converted := input.(*gemini.EmbedContentRequest)
+		if converted.Content == nil {
+			return nil
+		}
+
+		return p.filterContents(getGeminiPartTexts((&gemini.BatchEmbedContentsRequest{Requests: []*gemini.EmbedContentRequest{converted}}).TextParts()), scanner, cd, log)
+
+	case *gemini.BatchEmbedContentsRequest:
+		return p.filterContents(getGeminiPartTexts(input.(*gemini.BatchEmbedContentsRequest).TextParts()), scanner, cd, log)
+
+	case bedrock.Request:
+		return p.filterContents(input.(bedrock.Request).Contents(), scanner, cd, log)
+
 	case *anthropic.CompletionRequest:
 		converted := input.(*anthropic.CompletionRequest)
 		result, err := p.scan([]string{converted.Prompt}, scanner, cd, log)
@@ -818,6 +947,55 @@ func (p *Policy) Filter(client http.Client, input any, scanner Scanner, cd Custo
 	return nil
 }
 
+func getGeminiPartTexts(parts []*gemini.Part) []*string {
+	texts := []*string{}
+	for _, part := range parts {
+		texts = append(texts, part.Text)
+	}
+
+	return texts
+}
+
+// filterContents scans the contents of a request and replaces them with the
+// redacted contents.
+func (p *Policy) filterContents(texts []*string, scanner Scanner, cd CustomPolicyDetector, log *zap.Logger) error {
+	if len(texts) == 0 {
+		return nil
+	}
+
+	contents := []string{}
+	for _, text := range texts {
+		contents = append(contents, *text)
+	}
+
+	result, err := p.scan(contents, scanner, cd, log)
+	if err != nil {
+		return err
+	}
+
+	if result.Action == Block {
+		return internal_errors.NewBlockedError("request blocked due to detected entities: " + join(result.BlockedEntities, result.BlockedRegexDefinitions, result.BlockedCustomDefinitions))
+	}
+
+	if result.Action == AllowButWarn {
+		return internal_errors.NewWarningError("request warned due to detected entities: " + join(result.WarnedEntities, result.WarnedRegexDefinitions, []string{}))
+	}
+
+	if len(result.Updated) != len(texts) {
+		return errors.New("updated contents length not consistent with existing content length")
+	}
+
+	for index, updated := range result.Updated {
+		*texts[index] = updated
+	}
+
+	if result.Action == AllowButRedact {
+		return internal_errors.NewRedactError("request redacted due to detected entities")
+	}
+
+	return nil
+}
+
 func join(entities []Rule, regexDefitions []string, customDefinitions []string) string {
 	strs := []string{}
 	for _, entity := range entities {
@@ -840,7 +1018,7 @@ type Scanner interface {
 }
 
 type CustomPolicyDetector interface {
-	Detect(input []string, requirements []string) (bool, error)
+	Detect(input []string, requirements []string, policyId string, dc *CustomDetectorConfig) (bool, error)
 }
 
 var entityMap map[string]string = map[string]string{
@@ -1006,7 +1184,7 @@ func (p *Policy) scan(input []string, scanner Scanner, cd CustomPolicyDetector,
 			go func(action Action, reqs []string, result *ScanResult) {
 				defer wg.Done()
 
-				found, err := cd.Detect(input, reqs)
+				found, err := cd.Detect(input, reqs, p.Id, p.CustomConfig.Detector)
 				if err != nil {
 					log.Debug("error when detecting using custom policy", zap.Error(err))
 					telemetry.Incr("bricksllm.policy.scanner.scan.detect_error", nil, 1)
@@ -0,0 +1,30 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCustomDetectorConfig_Validate(t *testing.T) {
+	cases := []struct {
+		name  string
+		dc    *CustomDetectorConfig
+		valid bool
+	}{
+		{"nil", nil, true},
+		{"default openai", &CustomDetectorConfig{}, true},
+		{"azure", &CustomDetectorConfig{Provider: AzureDetectorProvider, SettingId: "s", Model: "gpt-4o"}, true},
+		{"azure without model", &CustomDetectorConfig{Provider: AzureDetectorProvider, SettingId: "s"}, false},
+		{"anthropic without setting", &CustomDetectorConfig{Provider: AnthropicDetectorProvider}, false},
+		{"vllm", &CustomDetectorConfig{Provider: VllmDetectorProvider, SettingId: "s"}, true},
+		{"http without url", &CustomDetectorConfig{Provider: HttpDetectorProvider}, false},
+		{"unknown provider", &CustomDetectorConfig{Provider: "cohere"}, false},
+		{"invalid timeout", &CustomDetectorConfig{Timeout: "-1s"}, false},
+		{"invalid cache ttl", &CustomDetectorConfig{CacheTtl: "soon"}, false},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.valid, len(c.dc.validate()) == 0, c.name)
+	}
+}
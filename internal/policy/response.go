@@ -0,0 +1,99 @@
+package policy
+
+import (
+	"errors"
+
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+	"github.com/bricks-cloud/bricksllm/internal/provider/anthropic"
+	"go.uber.org/zap"
+
+	goopenai "github.com/sashabaranov/go-openai"
+)
+
+// FilterOutput applies the policy to model outputs. It returns the contents with
+// redactions applied along with the same blocked, warning and redact errors as Filter.
+func (p *Policy) FilterOutput(contents []string, scanner Scanner, cd CustomPolicyDetector, log *zap.Logger) ([]string, error) {
+	if p == nil || scanner == nil || len(contents) == 0 || !p.ShouldInspect() {
+		return contents, nil
+	}
+
+	result, err := p.scan(contents, scanner, cd, log)
+	if err != nil {
+		return contents, err
+	}
+
+	if result.Action == Block {
+		return contents, internal_errors.NewBlockedError("response blocked due to detected entities: " + join(result.BlockedEntities, result.BlockedRegexDefinitions, result.BlockedCustomDefinitions))
+	}
+
+	updated := contents
+	if len(result.Updated) == len(contents) {
+		updated = result.Updated
+	}
+
+	if result.Action == AllowButWarn {
+		return updated, internal_errors.NewWarningError("response warned due to detected entities: " + join(result.WarnedEntities, result.WarnedRegexDefinitions, []string{}))
+	}
+
+	if result.Action == AllowButRedact {
+		return updated, internal_errors.NewRedactError("response redacted due to detected entities")
+	}
+
+	return updated, nil
+}
+
+func (p *Policy) FilterResponse(input any, scanner Scanner, cd CustomPolicyDetector, log *zap.Logger) error {
+	if p == nil || scanner == nil || input == nil {
+		return nil
+	}
+
+	switch input.(type) {
+	case *goopenai.ChatCompletionResponse:
+		converted := input.(*goopenai.ChatCompletionResponse)
+
+		contents := []string{}
+		for _, choice := range converted.Choices {
+			contents = append(contents, choice.Message.Content)
+		}
+
+		updated, err := p.FilterOutput(contents, scanner, cd, log)
+		if len(updated) != len(converted.Choices) {
+			return errors.New("updated contents length not consistent with existing content length")
+		}
+
+		for index, c := range updated {
+			converted.Choices[index].Message.Content = c
+		}
+
+		return err
+
+	case *anthropic.MessagesResponse:
+		converted := input.(*anthropic.MessagesResponse)
+
+		indexes := []int{}
+		contents := []string{}
+		for index, content := range converted.Content {
+			if content.Type == "text" {
+				indexes = append(indexes, index)
+				contents = append(contents, content.Text)
+			}
+		}
+
+		if len(contents) == 0 {
+			return nil
+		}
+
+		updated, err := p.FilterOutput(contents, scanner, cd, log)
+		if len(updated) != len(indexes) {
+			return errors.New("updated contents length not consistent with existing content length")
+		}
+
+		for i, c := range updated {
+			converted.Content[indexes[i]].Text = c
+		}
+
+		return err
+	}
+
+	return nil
+}
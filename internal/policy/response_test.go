@@ -0,0 +1,72 @@
+package policy
+
+import (
+	"testing"
+
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+	"github.com/bricks-cloud/bricksllm/internal/pii"
+	"github.com/bricks-cloud/bricksllm/internal/provider/anthropic"
+	goopenai "github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeScanner struct{}
+
+func (s *fakeScanner) Scan(input []string) (*pii.Result, error) {
+	return &pii.Result{}, nil
+}
+
+func newRegexPolicy(action Action) *Policy {
+	return &Policy{
+		RegexConfig: &RegexConfig{
+			RegularExpressionRules: []*RegularExpressionRule{{Definition: `secret-\d+`, Action: action}},
+		},
+	}
+}
+
+func TestFilterResponse_ChatCompletion(t *testing.T) {
+	res := &goopenai.ChatCompletionResponse{
+		Choices: []goopenai.ChatCompletionChoice{
+			{Message: goopenai.ChatCompletionMessage{Content: "the code is secret-123"}},
+			{Message: goopenai.ChatCompletionMessage{Content: "nothing to see"}},
+		},
+	}
+
+	err := newRegexPolicy(AllowButRedact).FilterResponse(res, &fakeScanner{}, nil, zap.NewNop())
+	require.Error(t, err)
+	assert.IsType(t, &internal_errors.RedactError{}, err)
+	assert.Equal(t, "the code is ***", res.Choices[0].Message.Content)
+	assert.Equal(t, "nothing to see", res.Choices[1].Message.Content)
+}
+
+func TestFilterResponse_Messages(t *testing.T) {
+	res := &anthropic.MessagesResponse{
+		Content: []anthropic.MessageResponseContent{
+			{Type: "tool_use", Name: "lookup"},
+			{Type: "text", Text: "the code is secret-123"},
+		},
+	}
+
+	err := newRegexPolicy(Block).FilterResponse(res, &fakeScanner{}, nil, zap.NewNop())
+	require.Error(t, err)
+	assert.IsType(t, &internal_errors.BlockedError{}, err)
+
+	err = newRegexPolicy(AllowButRedact).FilterResponse(res, &fakeScanner{}, nil, zap.NewNop())
+	require.Error(t, err)
+	assert.Equal(t, "lookup", res.Content[0].Name)
+	assert.Equal(t, "the code is ***", res.Content[1].Text)
+}
+
+func TestFilterOutput_Allowed(t *testing.T) {
+	contents := []string{"nothing to see"}
+
+	updated, err := newRegexPolicy(Block).FilterOutput(contents, &fakeScanner{}, nil, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, contents, updated)
+
+	updated, err = newRegexPolicy(Allow).FilterOutput([]string{"secret-1"}, &fakeScanner{}, nil, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"secret-1"}, updated)
+}
@@ -0,0 +1,184 @@
+package pricing
+
+import (
+	"fmt"
+	"strings"
+
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+	"github.com/bricks-cloud/bricksllm/internal/provider/anthropic"
+	"github.com/bricks-cloud/bricksllm/internal/provider/azure"
+	"github.com/bricks-cloud/bricksllm/internal/provider/bedrock"
+	"github.com/bricks-cloud/bricksllm/internal/provider/deepinfra"
+	"github.com/bricks-cloud/bricksllm/internal/provider/gemini"
+	"github.com/bricks-cloud/bricksllm/internal/provider/openai"
+)
+
+// supportedCostTypes lists the cost types each provider's cost estimator reads. Costs
+// use the unit of the provider's built in cost map: per thousand tokens for openai,
+// azure and bedrock and per million tokens for anthropic, deepinfra and gemini. Image
+// costs are per image and ratio cost types are fractions of the prompt price.
+var supportedCostTypes = map[string]map[string]bool{
+	"openai": {
+		"prompt":              true,
+		"completion":          true,
+		"embeddings":          true,
+		"audio":               true,
+		"finetune":            true,
+		"image":               true,
+		"cached_prompt_ratio": true,
+	},
+	"azure": {
+		"prompt":     true,
+		"completion": true,
+		"embeddings": true,
+	},
+	"anthropic": {
+		"prompt":               true,
+		"completion":           true,
+		"cache_creation_ratio": true,
+		"cache_read_ratio":     true,
+	},
+	"bedrock": {
+		"prompt":     true,
+		"completion": true,
+		"embeddings": true,
+	},
+	"deepinfra": {
+		"prompt": true,
+	},
+	"gemini": {
+		"prompt":                  true,
+		"completion":              true,
+		"prompt_long_context":     true,
+		"completion_long_context": true,
+		"embeddings":              true,
+	},
+}
+
+type Price struct {
+	Id        string  `json:"id"`
+	CreatedAt int64   `json:"createdAt"`
+	UpdatedAt int64   `json:"updatedAt"`
+	Provider  string  `json:"provider"`
+	Model     string  `json:"model"`
+	CostType  string  `json:"costType"`
+	Cost      float64 `json:"cost"`
+	// EffectiveFrom is the unix timestamp from which the price applies. A price stays
+	// in effect until a price for the same model with a later EffectiveFrom applies.
+	EffectiveFrom int64 `json:"effectiveFrom"`
+	Deleted       bool  `json:"-"`
+}
+
+type UpdatePrice struct {
+	UpdatedAt     int64    `json:"updatedAt"`
+	Cost          *float64 `json:"cost"`
+	EffectiveFrom int64    `json:"effectiveFrom"`
+}
+
+type PriceFilter struct {
+	Provider string
+	Model    string
+	CostType string
+}
+
+func (p *Price) Validate() error {
+	if p == nil {
+		return internal_errors.NewValidationError("price cannot be nil")
+	}
+
+	msgs := []string{}
+
+	costTypes, ok := supportedCostTypes[p.Provider]
+	if !ok {
+		msgs = append(msgs, fmt.Sprintf("provider %s is not supported", p.Provider))
+	}
+
+	if ok && !costTypes[p.CostType] {
+		msgs = append(msgs, fmt.Sprintf("cost type %s is not supported by %s", p.CostType, p.Provider))
+	}
+
+	if len(p.Model) == 0 {
+		msgs = append(msgs, "model cannot be empty")
+	}
+
+	if p.Cost < 0 {
+		msgs = append(msgs, "cost cannot be negative")
+	}
+
+	if p.EffectiveFrom < 0 {
+		msgs = append(msgs, "effectiveFrom cannot be negative")
+	}
+
+	if len(msgs) != 0 {
+		return internal_errors.NewValidationError("price is not valid: " + strings.Join(msgs, " ,"))
+	}
+
+	return nil
+}
+
+func (up *UpdatePrice) Validate() error {
+	if up == nil {
+		return internal_errors.NewValidationError("price update cannot be nil")
+	}
+
+	msgs := []string{}
+
+	if up.Cost != nil && *up.Cost < 0 {
+		msgs = append(msgs, "cost cannot be negative")
+	}
+
+	if up.EffectiveFrom < 0 {
+		msgs = append(msgs, "effectiveFrom cannot be negative")
+	}
+
+	if len(msgs) != 0 {
+		return internal_errors.NewValidationError("price is not valid: " + strings.Join(msgs, " ,"))
+	}
+
+	return nil
+}
+
+func addDefaultPrices(prices []*Price, seen map[string]bool, provider string, costMaps map[string]map[string]float64) []*Price {
+	for costType, costMap := range costMaps {
+		for model, cost := range costMap {
+			id := strings.Join([]string{provider, costType, model}, ":")
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			prices = append(prices, &Price{
+				Provider: provider,
+				Model:    model,
+				CostType: costType,
+				Cost:     cost,
+			})
+		}
+	}
+
+	return prices
+}
+
+// GetDefaultPrices returns the prices built into the provider cost maps. They are used
+// to seed the pricing catalog and are effective from the beginning of time.
+func GetDefaultPrices() []*Price {
+	prices := []*Price{}
+	seen := map[string]bool{}
+
+	prices = addDefaultPrices(prices, seen, "openai", openai.OpenAiPerThousandTokenCost)
+	prices = addDefaultPrices(prices, seen, "azure", azure.AzureOpenAiPerThousandTokenCost)
+	prices = addDefaultPrices(prices, seen, "anthropic", anthropic.AnthropicPerMillionTokenCost)
+	prices = addDefaultPrices(prices, seen, "deepinfra", deepinfra.DeepinfraPerMillionTokenCost)
+	prices = addDefaultPrices(prices, seen, "gemini", gemini.GeminiPerMillionTokenCost)
+
+	for _, costMaps := range []map[string]map[string]float64{
+		bedrock.LlamaPerThousandTokenCost,
+		bedrock.MistralPerThousandTokenCost,
+		bedrock.CoherePerThousandTokenCost,
+		bedrock.TitanPerThousandTokenCost,
+	} {
+		prices = addDefaultPrices(prices, seen, "bedrock", costMaps)
+	}
+
+	return prices
+}
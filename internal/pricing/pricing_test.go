@@ -0,0 +1,45 @@
+package pricing
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrice_Validate(t *testing.T) {
+	cases := []struct {
+		name  string
+		price *Price
+		valid bool
+	}{
+		{"nil", nil, false},
+		{"prompt", &Price{Provider: "openai", Model: "gpt-4o", CostType: "prompt", Cost: 0.0025}, true},
+		{"image", &Price{Provider: "openai", Model: "dall-e-3/hd/1024x1024", CostType: "image", Cost: 0.08}, true},
+		{"cached prompt ratio", &Price{Provider: "openai", Model: "default", CostType: "cached_prompt_ratio", Cost: 0.5}, true},
+		{"cache read ratio", &Price{Provider: "anthropic", Model: "claude-3-haiku", CostType: "cache_read_ratio", Cost: 0.1}, true},
+		{"unsupported cost type", &Price{Provider: "azure", Model: "gpt-4o", CostType: "image", Cost: 0.08}, false},
+		{"unsupported provider", &Price{Provider: "cohere", Model: "command", CostType: "prompt", Cost: 1}, false},
+		{"missing model", &Price{Provider: "openai", CostType: "prompt", Cost: 1}, false},
+		{"negative cost", &Price{Provider: "openai", Model: "gpt-4o", CostType: "prompt", Cost: -1}, false},
+		{"negative effective from", &Price{Provider: "openai", Model: "gpt-4o", CostType: "prompt", EffectiveFrom: -1}, false},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.valid, c.price.Validate() == nil, c.name)
+	}
+}
+
+func TestGetDefaultPrices(t *testing.T) {
+	prices := map[string]float64{}
+	for _, p := range GetDefaultPrices() {
+		assert.NoError(t, p.Validate(), p.Provider+":"+p.CostType+":"+p.Model)
+		prices[p.Provider+":"+p.CostType+":"+p.Model] = p.Cost
+	}
+
+	assert.Equal(t, 0.08, prices["openai:image:dall-e-3/hd/1024x1024"])
+	assert.Equal(t, 0.5, prices["openai:cached_prompt_ratio:default"])
+	assert.Equal(t, 1.25, prices["anthropic:cache_creation_ratio:default"])
+	assert.Equal(t, 0.1, prices["anthropic:cache_read_ratio:default"])
+	assert.Contains(t, prices, "bedrock:prompt:meta.llama3-8b-instruct-v1:0")
+	assert.Contains(t, prices, "gemini:prompt_long_context:gemini-1.5-pro")
+}
@@ -0,0 +1,119 @@
+package principal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+)
+
+type Role string
+
+const (
+	RoleViewer      Role = "viewer"
+	RoleKeyManager  Role = "key-manager"
+	RolePolicyAdmin Role = "policy-admin"
+	RoleSuperAdmin  Role = "super-admin"
+)
+
+type Permission string
+
+const (
+	// PermissionRead allows reading configurations and reporting.
+	PermissionRead Permission = "read"
+	// PermissionManageKeys allows managing keys, users and budgets.
+	PermissionManageKeys Permission = "keys:write"
+	// PermissionManagePolicies allows managing policies, routes, providers, model
+	// aliases and prices.
+	PermissionManagePolicies Permission = "policies:write"
+	// PermissionManageAdmin allows managing admin principals and webhooks.
+	PermissionManageAdmin Permission = "admin:write"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleViewer:      {PermissionRead},
+	RoleKeyManager:  {PermissionRead, PermissionManageKeys},
+	RolePolicyAdmin: {PermissionRead, PermissionManagePolicies},
+	RoleSuperAdmin:  {PermissionRead, PermissionManageKeys, PermissionManagePolicies, PermissionManageAdmin},
+}
+
+func (r Role) IsValid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+func (r Role) Can(p Permission) bool {
+	for _, rp := range rolePermissions[r] {
+		if rp == p {
+			return true
+		}
+	}
+
+	return false
+}
+
+const TokenPrefix = "bkadm_"
+
+type Principal struct {
+	Id        string `json:"id"`
+	CreatedAt int64  `json:"createdAt"`
+	UpdatedAt int64  `json:"updatedAt"`
+	Name      string `json:"name"`
+	Role      Role   `json:"role"`
+	// Token is only returned when the principal is created.
+	Token       string `json:"token,omitempty"`
+	TokenHash   string `json:"-"`
+	TokenSuffix string `json:"tokenSuffix"`
+	Revoked     bool   `json:"revoked"`
+	Deleted     bool   `json:"-"`
+}
+
+type UpdatePrincipal struct {
+	UpdatedAt int64  `json:"updatedAt"`
+	Name      string `json:"name"`
+	Role      Role   `json:"role"`
+	Revoked   *bool  `json:"revoked"`
+}
+
+func (p *Principal) Validate() error {
+	if p == nil {
+		return internal_errors.NewValidationError("principal cannot be nil")
+	}
+
+	msgs := []string{}
+
+	if len(p.Name) == 0 {
+		msgs = append(msgs, "name cannot be empty")
+	}
+
+	if !p.Role.IsValid() {
+		msgs = append(msgs, fmt.Sprintf("role %s is not supported", p.Role))
+	}
+
+	if len(msgs) != 0 {
+		return internal_errors.NewValidationError("principal is not valid: " + strings.Join(msgs, " ,"))
+	}
+
+	return nil
+}
+
+func (up *UpdatePrincipal) Validate() error {
+	if up == nil {
+		return internal_errors.NewValidationError("principal update cannot be nil")
+	}
+
+	if len(up.Role) != 0 && !up.Role.IsValid() {
+		return internal_errors.NewValidationError(fmt.Sprintf("principal is not valid: role %s is not supported", up.Role))
+	}
+
+	return nil
+}
+
+// HashToken returns the hex encoded SHA-256 of a token. Tokens are random and long
+// enough that a salt is not needed.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,5 +1,7 @@
 package anthropic
 
+import "encoding/json"
+
 type Metadata struct {
 	UserId string `json:"user_id"`
 }
@@ -16,21 +18,63 @@ type CompletionRequest struct {
 	Stream            bool      `json:"stream,omitempty"`
 }
 
+// ContentBlock is a text, tool_use or tool_result block of a message.
+type ContentBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	Id        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseId string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+}
+
 type Message struct {
 	Content string `json:"content"`
 	Role    string `json:"role"`
+	// Blocks are sent as the content of the message instead of Content when they are set.
+	Blocks []ContentBlock `json:"-"`
+}
+
+func (m Message) MarshalJSON() ([]byte, error) {
+	if len(m.Blocks) == 0 {
+		type message Message
+		return json.Marshal(message(m))
+	}
+
+	return json.Marshal(&struct {
+		Content []ContentBlock `json:"content"`
+		Role    string         `json:"role"`
+	}{
+		Content: m.Blocks,
+		Role:    m.Role,
+	})
+}
+
+type Tool struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	InputSchema any    `json:"input_schema"`
+}
+
+type ToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
 }
 
 type MessagesRequest struct {
-	Model         string    `json:"model"`
-	Messages      []Message `json:"messages"`
-	MaxTokens     int       `json:"max_tokens"`
-	StopSequences []string  `json:"stop_sequences,omitempty"`
-	Temperature   float32   `json:"temperature,omitempty"`
-	TopP          int       `json:"top_p,omitempty"`
-	TopK          int       `json:"top_k,omitempty"`
-	Metadata      *Metadata `json:"metadata,omitempty"`
-	Stream        bool      `json:"stream,omitempty"`
+	Model         string      `json:"model"`
+	System        string      `json:"system,omitempty"`
+	Messages      []Message   `json:"messages"`
+	MaxTokens     int         `json:"max_tokens"`
+	StopSequences []string    `json:"stop_sequences,omitempty"`
+	Temperature   float32     `json:"temperature,omitempty"`
+	TopP          float32     `json:"top_p,omitempty"`
+	TopK          int         `json:"top_k,omitempty"`
+	Metadata      *Metadata   `json:"metadata,omitempty"`
+	Stream        bool        `json:"stream,omitempty"`
+	Tools         []Tool      `json:"tools,omitempty"`
+	ToolChoice    *ToolChoice `json:"tool_choice,omitempty"`
 }
 
 type CompletionResponse struct {
@@ -40,8 +84,11 @@ type CompletionResponse struct {
 }
 
 type MessageResponseContent struct {
-	Type string `json:"type"`
-	Text string `json:"text,omitempty"`
+	Type  string          `json:"type"`
+	Text  string          `json:"text,omitempty"`
+	Id    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
 }
 
 type MessagesResponse struct {
@@ -52,10 +99,19 @@ type MessagesResponse struct {
 	Model        string                   `json:"model"`
 	StopReason   string                   `json:"stop_reason"`
 	StopSequence string                   `json:"stop_sequence,omitempty"`
-	Usage        struct {
-		InputTokens  int `json:"input_tokens"`
-		OutputTokens int `json:"output_tokens"`
-	}
+	Usage        Usage                    `json:"usage"`
+}
+
+type Usage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
+}
+
+// PromptTokens includes tokens written to and read from the prompt cache.
+func (u Usage) PromptTokens() int {
+	return u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
 }
 
 type MessagesStreamMessageStart struct {
@@ -83,6 +139,7 @@ type MessagesStreamBlockStart struct {
 }
 
 type MessagesStreamBlockDelta struct {
+	Type  string                 `json:"type,omitempty"`
 	Index int                    `json:"index"`
 	Delta MessageResponseContent `json:"delta"`
 }
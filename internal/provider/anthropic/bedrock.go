@@ -17,14 +17,17 @@ type BedrockCompletionResponse struct {
 }
 
 type BedrockMessageRequest struct {
-	AnthropicVersion string    `json:"anthropic_version"`
-	Messages         []Message `json:"messages"`
-	MaxTokens        int       `json:"max_tokens"`
-	StopSequences    []string  `json:"stop_sequences,omitempty"`
-	Temperature      float32   `json:"temperature,omitempty"`
-	TopP             int       `json:"top_p,omitempty"`
-	TopK             int       `json:"top_k,omitempty"`
-	Metadata         *Metadata `json:"metadata,omitempty"`
+	AnthropicVersion string      `json:"anthropic_version"`
+	System           string      `json:"system,omitempty"`
+	Messages         []Message   `json:"messages"`
+	MaxTokens        int         `json:"max_tokens"`
+	StopSequences    []string    `json:"stop_sequences,omitempty"`
+	Temperature      float32     `json:"temperature,omitempty"`
+	TopP             float32     `json:"top_p,omitempty"`
+	TopK             int         `json:"top_k,omitempty"`
+	Metadata         *Metadata   `json:"metadata,omitempty"`
+	Tools            []Tool      `json:"tools,omitempty"`
+	ToolChoice       *ToolChoice `json:"tool_choice,omitempty"`
 }
 
 type BedrockMessagesStopResponse struct {
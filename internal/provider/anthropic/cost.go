@@ -4,8 +4,13 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+
+	"github.com/bricks-cloud/bricksllm/internal/util"
 )
 
+// AnthropicPerMillionTokenCost also holds the ratios of the prompt price that prompt
+// cache writes and reads are billed at keyed by model, with the default ratios under
+// DefaultRatioModel.
 var AnthropicPerMillionTokenCost = map[string]map[string]float64{
 	"prompt": {
 		"claude-instant":    0.8,
@@ -25,24 +30,72 @@ var AnthropicPerMillionTokenCost = map[string]map[string]float64{
 		"claude-3.5-haiku":  5,
 		"claude-3-haiku":    1.25,
 	},
+	"cache_creation_ratio": {
+		DefaultRatioModel: 1.25,
+	},
+	"cache_read_ratio": {
+		DefaultRatioModel: 0.1,
+	},
 }
 
+// DefaultRatioModel is the model of the cost ratio that applies to models without a
+// ratio of their own.
+const DefaultRatioModel = "default"
+
 type tokenCounter interface {
 	Count(input string) int
 }
 
+type priceCatalog interface {
+	GetCostMap(provider, costType string) map[string]float64
+}
+
 type CostEstimator struct {
 	tokenCostMap map[string]map[string]float64
+	pc           priceCatalog
 	tc           tokenCounter
 }
 
-func NewCostEstimator(tc tokenCounter) *CostEstimator {
+func NewCostEstimator(tc tokenCounter, pc priceCatalog) *CostEstimator {
 	return &CostEstimator{
 		tokenCostMap: AnthropicPerMillionTokenCost,
 		tc:           tc,
+		pc:           pc,
 	}
 }
 
+func (ce *CostEstimator) getCostMap(costType string) (map[string]float64, bool) {
+	costMap, ok := ce.tokenCostMap[costType]
+	if ce.pc != nil {
+		if catalog := ce.pc.GetCostMap("anthropic", costType); len(catalog) != 0 {
+			return util.MergeCostMaps(costMap, catalog), true
+		}
+	}
+
+	return costMap, ok
+}
+
+func (ce *CostEstimator) getCostRatio(costType, model string) (float64, error) {
+	selected := ""
+	if strings.HasPrefix(model, "us") {
+		selected = convertAmazonModelToAnthropicModel(model)
+	} else {
+		selected = selectModel(model)
+	}
+
+	costMap, _ := ce.getCostMap(costType)
+	if ratio, ok := costMap[selected]; ok {
+		return ratio, nil
+	}
+
+	ratio, ok := costMap[DefaultRatioModel]
+	if !ok {
+		return 0, fmt.Errorf("%s is not provided for %s", costType, model)
+	}
+
+	return ratio, nil
+}
+
 func (ce *CostEstimator) EstimateTotalCost(model string, promptTks, completionTks int) (float64, error) {
 	promptCost, err := ce.EstimatePromptCost(model, promptTks)
 	if err != nil {
@@ -57,8 +110,51 @@ func (ce *CostEstimator) EstimateTotalCost(model string, promptTks, completionTk
 	return promptCost + completionCost, nil
 }
 
+func (ce *CostEstimator) EstimateTotalCostWithCache(model string, usage Usage) (float64, error) {
+	promptCost, err := ce.EstimatePromptCostWithCache(model, usage.InputTokens, usage.CacheCreationInputTokens, usage.CacheReadInputTokens)
+	if err != nil {
+		return 0, err
+	}
+
+	completionCost, err := ce.EstimateCompletionCost(model, usage.OutputTokens)
+	if err != nil {
+		return 0, err
+	}
+
+	return promptCost + completionCost, nil
+}
+
+func (ce *CostEstimator) EstimatePromptCostWithCache(model string, tks, cacheCreationTks, cacheReadTks int) (float64, error) {
+	cost, err := ce.EstimatePromptCost(model, tks)
+	if err != nil {
+		return 0, err
+	}
+
+	cacheCreationCost, err := ce.EstimatePromptCost(model, cacheCreationTks)
+	if err != nil {
+		return 0, err
+	}
+
+	cacheReadCost, err := ce.EstimatePromptCost(model, cacheReadTks)
+	if err != nil {
+		return 0, err
+	}
+
+	cacheCreationRatio, err := ce.getCostRatio("cache_creation_ratio", model)
+	if err != nil {
+		return 0, err
+	}
+
+	cacheReadRatio, err := ce.getCostRatio("cache_read_ratio", model)
+	if err != nil {
+		return 0, err
+	}
+
+	return cost + cacheCreationCost*cacheCreationRatio + cacheReadCost*cacheReadRatio, nil
+}
+
 func (ce *CostEstimator) EstimatePromptCost(model string, tks int) (float64, error) {
-	costMap, ok := ce.tokenCostMap["prompt"]
+	costMap, ok := ce.getCostMap("prompt")
 	if !ok {
 		return 0, errors.New("prompt token cost is not provided")
 
@@ -110,7 +206,7 @@ func convertAmazonModelToAnthropicModel(model string) string {
 }
 
 func (ce *CostEstimator) EstimateCompletionCost(model string, tks int) (float64, error) {
-	costMap, ok := ce.tokenCostMap["completion"]
+	costMap, ok := ce.getCostMap("completion")
 	if !ok {
 		return 0, errors.New("prompt token cost is not provided")
 	}
@@ -0,0 +1,66 @@
+package anthropic
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCostEstimator_EstimateTotalCostWithCache(t *testing.T) {
+	ce := NewCostEstimator(nil, nil)
+
+	usage := Usage{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"input_tokens": 1000000,
+		"output_tokens": 1000000,
+		"cache_creation_input_tokens": 1000000,
+		"cache_read_input_tokens": 1000000
+	}`), &usage))
+	assert.Equal(t, 3000000, usage.PromptTokens())
+
+	cost, err := ce.EstimateTotalCostWithCache("claude-3-5-sonnet-20241022", usage)
+	require.NoError(t, err)
+	assert.InDelta(t, 3+3*1.25+3*0.1+15, cost, 1e-9)
+
+	cost, err = ce.EstimateTotalCostWithCache("claude-3-haiku-20240307", Usage{InputTokens: 1000000})
+	require.NoError(t, err)
+	assert.InDelta(t, 0.25, cost, 1e-9)
+
+	_, err = ce.EstimateTotalCostWithCache("gpt-4o", usage)
+	assert.Error(t, err)
+}
+
+type fakePriceCatalog map[string]map[string]float64
+
+func (pc fakePriceCatalog) GetCostMap(provider, costType string) map[string]float64 {
+	if provider != "anthropic" {
+		return nil
+	}
+
+	return pc[costType]
+}
+
+func TestCostEstimator_CatalogOverridesBuiltInPrices(t *testing.T) {
+	ce := NewCostEstimator(nil, fakePriceCatalog{
+		"prompt":           {"claude-3-haiku": 0.5},
+		"cache_read_ratio": {"claude-3.5-sonnet": 0.2},
+	})
+
+	cost, err := ce.EstimatePromptCost("claude-3-haiku-20240307", 1000000)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.5, cost, 1e-9)
+
+	cost, err = ce.EstimatePromptCost("claude-3-opus-20240229", 1000000)
+	require.NoError(t, err)
+	assert.InDelta(t, 15, cost, 1e-9)
+
+	cost, err = ce.EstimatePromptCostWithCache("claude-3-5-sonnet-20241022", 0, 1000000, 1000000)
+	require.NoError(t, err)
+	assert.InDelta(t, 3*1.25+3*0.2, cost, 1e-9)
+
+	cost, err = ce.EstimatePromptCostWithCache("claude-3-opus-20240229", 0, 0, 1000000)
+	require.NoError(t, err)
+	assert.InDelta(t, 15*0.1, cost, 1e-9)
+}
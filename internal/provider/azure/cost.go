@@ -46,14 +46,31 @@ var AzureOpenAiPerThousandTokenCost = map[string]map[string]float64{
 	},
 }
 
+type priceCatalog interface {
+	GetCostMap(provider, costType string) map[string]float64
+}
+
 type CostEstimator struct {
 	tokenCostMap map[string]map[string]float64
+	pc           priceCatalog
 }
 
-func NewCostEstimator() *CostEstimator {
+func NewCostEstimator(pc priceCatalog) *CostEstimator {
 	return &CostEstimator{
 		tokenCostMap: AzureOpenAiPerThousandTokenCost,
+		pc:           pc,
+	}
+}
+
+func (ce *CostEstimator) getCostMap(costType string) (map[string]float64, bool) {
+	costMap, ok := ce.tokenCostMap[costType]
+	if ce.pc != nil {
+		if catalog := ce.pc.GetCostMap("azure", costType); len(catalog) != 0 {
+			return util.MergeCostMaps(costMap, catalog), true
+		}
 	}
+
+	return costMap, ok
 }
 
 func (ce *CostEstimator) EstimateTotalCost(model string, promptTks, completionTks int) (float64, error) {
@@ -71,7 +88,7 @@ func (ce *CostEstimator) EstimateTotalCost(model string, promptTks, completionTk
 }
 
 func (ce *CostEstimator) EstimatePromptCost(model string, tks int) (float64, error) {
-	costMap, ok := ce.tokenCostMap["prompt"]
+	costMap, ok := ce.getCostMap("prompt")
 	if !ok {
 		return 0, errors.New("prompt token cost is not provided")
 	}
@@ -86,7 +103,7 @@ func (ce *CostEstimator) EstimatePromptCost(model string, tks int) (float64, err
 }
 
 func (ce *CostEstimator) EstimateEmbeddingsInputCost(model string, tks int) (float64, error) {
-	costMap, ok := ce.tokenCostMap["embeddings"]
+	costMap, ok := ce.getCostMap("embeddings")
 	if !ok {
 		return 0, errors.New("embeddings token cost is not provided")
 
@@ -102,7 +119,7 @@ func (ce *CostEstimator) EstimateEmbeddingsInputCost(model string, tks int) (flo
 }
 
 func (ce *CostEstimator) EstimateCompletionCost(model string, tks int) (float64, error) {
-	costMap, ok := ce.tokenCostMap["completion"]
+	costMap, ok := ce.getCostMap("completion")
 	if !ok {
 		return 0, errors.New("prompt token cost is not provided")
 	}
@@ -0,0 +1,442 @@
+package bedrock
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+type Family string
+
+const (
+	FamilyAnthropic      Family = "anthropic"
+	FamilyLlama          Family = "llama"
+	FamilyMistral        Family = "mistral"
+	FamilyCohereCommand  Family = "cohere_command"
+	FamilyCohereCommandR Family = "cohere_command_r"
+	FamilyCohereEmbed    Family = "cohere_embed"
+	FamilyTitanEmbed     Family = "titan_embed"
+)
+
+// NormalizeModel removes the region prefix of cross region inference profiles such
+// as us.meta.llama3-2-11b-instruct-v1:0 so that they map to their base model.
+func NormalizeModel(model string) string {
+	parts := strings.SplitN(model, ".", 3)
+	if len(parts) == 3 && (parts[0] == "us" || parts[0] == "eu" || parts[0] == "apac") {
+		return parts[1] + "." + parts[2]
+	}
+
+	return model
+}
+
+// GetFamily returns the model family of a bedrock model id. It returns an empty
+// family for models that are not supported.
+func GetFamily(model string) Family {
+	normalized := NormalizeModel(model)
+
+	switch {
+	case strings.HasPrefix(normalized, "anthropic."):
+		return FamilyAnthropic
+	case strings.HasPrefix(normalized, "meta.llama"):
+		return FamilyLlama
+	case strings.HasPrefix(normalized, "mistral."):
+		return FamilyMistral
+	case strings.HasPrefix(normalized, "cohere.command-r"):
+		return FamilyCohereCommandR
+	case strings.HasPrefix(normalized, "cohere.command"):
+		return FamilyCohereCommand
+	case strings.HasPrefix(normalized, "cohere.embed"):
+		return FamilyCohereEmbed
+	case strings.HasPrefix(normalized, "amazon.titan-embed-text"):
+		return FamilyTitanEmbed
+	}
+
+	return ""
+}
+
+func (f Family) IsEmbedding() bool {
+	return f == FamilyCohereEmbed || f == FamilyTitanEmbed
+}
+
+// Request is implemented by the request types of the supported families. Contents
+// returns the text of the request so that it can be inspected and updated in place.
+type Request interface {
+	Contents() []*string
+}
+
+type Metrics struct {
+	InputTokenCount   int `json:"inputTokenCount"`
+	OutputTokenCount  int `json:"outputTokenCount"`
+	InvocationLatency int `json:"invocationLatency"`
+	FirstByteLatency  int `json:"firstByteLatency"`
+}
+
+type ErrorResponse struct {
+	Message string `json:"message"`
+}
+
+// Converse API
+
+type ContentBlock struct {
+	Text         *string         `json:"text,omitempty"`
+	Image        json.RawMessage `json:"image,omitempty"`
+	Document     json.RawMessage `json:"document,omitempty"`
+	ToolUse      json.RawMessage `json:"toolUse,omitempty"`
+	ToolResult   json.RawMessage `json:"toolResult,omitempty"`
+	GuardContent json.RawMessage `json:"guardContent,omitempty"`
+}
+
+type Message struct {
+	Role    string          `json:"role"`
+	Content []*ContentBlock `json:"content"`
+}
+
+type SystemContentBlock struct {
+	Text         *string         `json:"text,omitempty"`
+	GuardContent json.RawMessage `json:"guardContent,omitempty"`
+}
+
+type InferenceConfig struct {
+	MaxTokens     *int     `json:"maxTokens,omitempty"`
+	Temperature   *float32 `json:"temperature,omitempty"`
+	TopP          *float32 `json:"topP,omitempty"`
+	StopSequences []string `json:"stopSequences,omitempty"`
+}
+
+type ConverseRequest struct {
+	Messages                          []*Message            `json:"messages"`
+	System                            []*SystemContentBlock `json:"system,omitempty"`
+	InferenceConfig                   *InferenceConfig      `json:"inferenceConfig,omitempty"`
+	ToolConfig                        json.RawMessage       `json:"toolConfig,omitempty"`
+	GuardrailConfig                   json.RawMessage       `json:"guardrailConfig,omitempty"`
+	AdditionalModelRequestFields      json.RawMessage       `json:"additionalModelRequestFields,omitempty"`
+	AdditionalModelResponseFieldPaths []string              `json:"additionalModelResponseFieldPaths,omitempty"`
+}
+
+// Contents returns the text of the system prompts and message content blocks of the
+// request in order.
+func (r *ConverseRequest) Contents() []*string {
+	texts := []*string{}
+	for _, block := range r.System {
+		if block != nil && block.Text != nil {
+			texts = append(texts, block.Text)
+		}
+	}
+
+	for _, message := range r.Messages {
+		if message == nil {
+			continue
+		}
+
+		for _, block := range message.Content {
+			if block != nil && block.Text != nil {
+				texts = append(texts, block.Text)
+			}
+		}
+	}
+
+	return texts
+}
+
+type TokenUsage struct {
+	InputTokens  int `json:"inputTokens"`
+	OutputTokens int `json:"outputTokens"`
+	TotalTokens  int `json:"totalTokens"`
+}
+
+type ConverseOutput struct {
+	Message *Message `json:"message"`
+}
+
+type ConverseResponse struct {
+	Output     *ConverseOutput `json:"output"`
+	StopReason string          `json:"stopReason"`
+	Usage      *TokenUsage     `json:"usage"`
+}
+
+// Text returns the text content generated by the model.
+func (r *ConverseResponse) Text() string {
+	if r.Output == nil || r.Output.Message == nil {
+		return ""
+	}
+
+	sb := strings.Builder{}
+	for _, block := range r.Output.Message.Content {
+		if block != nil && block.Text != nil {
+			sb.WriteString(*block.Text)
+		}
+	}
+
+	return sb.String()
+}
+
+type ConverseStreamContentBlockDelta struct {
+	Delta *struct {
+		Text string `json:"text"`
+	} `json:"delta"`
+	ContentBlockIndex int `json:"contentBlockIndex"`
+}
+
+type ConverseStreamMetadata struct {
+	Usage *TokenUsage `json:"usage"`
+}
+
+// InvokeModel API
+
+type LlamaRequest struct {
+	Prompt      string   `json:"prompt"`
+	MaxGenLen   *int     `json:"max_gen_len,omitempty"`
+	Temperature *float32 `json:"temperature,omitempty"`
+	TopP        *float32 `json:"top_p,omitempty"`
+	Images      []string `json:"images,omitempty"`
+}
+
+func (r *LlamaRequest) Contents() []*string {
+	return []*string{&r.Prompt}
+}
+
+type LlamaResponse struct {
+	Generation           string   `json:"generation"`
+	PromptTokenCount     int      `json:"prompt_token_count"`
+	GenerationTokenCount int      `json:"generation_token_count"`
+	StopReason           string   `json:"stop_reason"`
+	Metrics              *Metrics `json:"amazon-bedrock-invocationMetrics,omitempty"`
+}
+
+type MistralRequest struct {
+	Prompt      string   `json:"prompt"`
+	MaxTokens   *int     `json:"max_tokens,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	Temperature *float32 `json:"temperature,omitempty"`
+	TopP        *float32 `json:"top_p,omitempty"`
+	TopK        *int     `json:"top_k,omitempty"`
+}
+
+func (r *MistralRequest) Contents() []*string {
+	return []*string{&r.Prompt}
+}
+
+type MistralOutput struct {
+	Text       string `json:"text"`
+	StopReason string `json:"stop_reason"`
+}
+
+type MistralResponse struct {
+	Outputs []*MistralOutput `json:"outputs"`
+	Metrics *Metrics         `json:"amazon-bedrock-invocationMetrics,omitempty"`
+}
+
+type CohereCommandRequest struct {
+	Prompt            string          `json:"prompt"`
+	Temperature       *float32        `json:"temperature,omitempty"`
+	P                 *float32        `json:"p,omitempty"`
+	K                 *int            `json:"k,omitempty"`
+	MaxTokens         *int            `json:"max_tokens,omitempty"`
+	StopSequences     []string        `json:"stop_sequences,omitempty"`
+	ReturnLikelihoods string          `json:"return_likelihoods,omitempty"`
+	Stream            bool            `json:"stream,omitempty"`
+	NumGenerations    *int            `json:"num_generations,omitempty"`
+	LogitBias         json.RawMessage `json:"logit_bias,omitempty"`
+	Truncate          string          `json:"truncate,omitempty"`
+}
+
+func (r *CohereCommandRequest) Contents() []*string {
+	return []*string{&r.Prompt}
+}
+
+type CohereGeneration struct {
+	Text         string `json:"text"`
+	FinishReason string `json:"finish_reason"`
+}
+
+// CohereCommandResponse is both the response and the stream chunk shape of cohere
+// command models. Stream chunks carry the generated text in the text field.
+type CohereCommandResponse struct {
+	Generations []*CohereGeneration `json:"generations"`
+	Text        string              `json:"text"`
+	IsFinished  bool                `json:"is_finished"`
+	Metrics     *Metrics            `json:"amazon-bedrock-invocationMetrics,omitempty"`
+}
+
+func (r *CohereCommandResponse) GeneratedText() string {
+	if len(r.Generations) != 0 && r.Generations[0] != nil {
+		return r.Generations[0].Text
+	}
+
+	return r.Text
+}
+
+type CohereChatMessage struct {
+	Role    string `json:"role"`
+	Message string `json:"message"`
+}
+
+type CohereCommandRRequest struct {
+	Message           string               `json:"message"`
+	ChatHistory       []*CohereChatMessage `json:"chat_history,omitempty"`
+	Preamble          *string              `json:"preamble,omitempty"`
+	Documents         json.RawMessage      `json:"documents,omitempty"`
+	SearchQueriesOnly *bool                `json:"search_queries_only,omitempty"`
+	MaxTokens         *int                 `json:"max_tokens,omitempty"`
+	Temperature       *float32             `json:"temperature,omitempty"`
+	P                 *float32             `json:"p,omitempty"`
+	K                 *int                 `json:"k,omitempty"`
+	PromptTruncation  string               `json:"prompt_truncation,omitempty"`
+	FrequencyPenalty  *float32             `json:"frequency_penalty,omitempty"`
+	PresencePenalty   *float32             `json:"presence_penalty,omitempty"`
+	Seed              *int                 `json:"seed,omitempty"`
+	ReturnPrompt      *bool                `json:"return_prompt,omitempty"`
+	Tools             json.RawMessage      `json:"tools,omitempty"`
+	ToolResults       json.RawMessage      `json:"tool_results,omitempty"`
+	StopSequences     []string             `json:"stop_sequences,omitempty"`
+	RawPrompting      *bool                `json:"raw_prompting,omitempty"`
+}
+
+func (r *CohereCommandRRequest) Contents() []*string {
+	texts := []*string{}
+	if r.Preamble != nil {
+		texts = append(texts, r.Preamble)
+	}
+
+	for _, message := range r.ChatHistory {
+		if message != nil {
+			texts = append(texts, &message.Message)
+		}
+	}
+
+	return append(texts, &r.Message)
+}
+
+type CohereCommandRResponse struct {
+	Text         string   `json:"text"`
+	FinishReason string   `json:"finish_reason"`
+	IsFinished   bool     `json:"is_finished"`
+	Metrics      *Metrics `json:"amazon-bedrock-invocationMetrics,omitempty"`
+}
+
+type CohereEmbedRequest struct {
+	Texts          []string `json:"texts"`
+	InputType      string   `json:"input_type,omitempty"`
+	Truncate       string   `json:"truncate,omitempty"`
+	EmbeddingTypes []string `json:"embedding_types,omitempty"`
+}
+
+func (r *CohereEmbedRequest) Contents() []*string {
+	texts := []*string{}
+	for i := range r.Texts {
+		texts = append(texts, &r.Texts[i])
+	}
+
+	return texts
+}
+
+type CohereEmbedResponse struct {
+	Id           string          `json:"id"`
+	Embeddings   json.RawMessage `json:"embeddings"`
+	ResponseType string          `json:"response_type"`
+	Texts        []string        `json:"texts"`
+}
+
+type TitanEmbedRequest struct {
+	InputText  string `json:"inputText"`
+	Dimensions *int   `json:"dimensions,omitempty"`
+	Normalize  *bool  `json:"normalize,omitempty"`
+}
+
+func (r *TitanEmbedRequest) Contents() []*string {
+	return []*string{&r.InputText}
+}
+
+type TitanEmbedResponse struct {
+	Embedding           []float64 `json:"embedding"`
+	InputTextTokenCount int       `json:"inputTextTokenCount"`
+}
+
+// StreamChunk is the union of the stream chunk shapes of the supported families.
+// Every family reports the token usage of the invocation in the metrics of the
+// last chunk.
+type StreamChunk struct {
+	Generation  string              `json:"generation"`
+	Outputs     []*MistralOutput    `json:"outputs"`
+	Generations []*CohereGeneration `json:"generations"`
+	Text        string              `json:"text"`
+	Metrics     *Metrics            `json:"amazon-bedrock-invocationMetrics,omitempty"`
+}
+
+// GeneratedText returns the text generated in the chunk.
+func (c *StreamChunk) GeneratedText() string {
+	if len(c.Generation) != 0 {
+		return c.Generation
+	}
+
+	if len(c.Outputs) != 0 && c.Outputs[0] != nil {
+		return c.Outputs[0].Text
+	}
+
+	if len(c.Generations) != 0 && c.Generations[0] != nil {
+		return c.Generations[0].Text
+	}
+
+	return c.Text
+}
+
+// ParseInvokeRequest unmarshals an invoke model request body into the request type
+// of the family of the model.
+func ParseInvokeRequest(model string, data []byte) (Request, error) {
+	var req Request
+	switch GetFamily(model) {
+	case FamilyLlama:
+		req = &LlamaRequest{}
+	case FamilyMistral:
+		req = &MistralRequest{}
+	case FamilyCohereCommand:
+		req = &CohereCommandRequest{}
+	case FamilyCohereCommandR:
+		req = &CohereCommandRRequest{}
+	case FamilyCohereEmbed:
+		req = &CohereEmbedRequest{}
+	case FamilyTitanEmbed:
+		req = &TitanEmbedRequest{}
+	default:
+		return nil, nil
+	}
+
+	err := json.Unmarshal(data, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// ParseInvokeResponse returns the generated text and the token usage reported in
+// the body of an invoke model response.
+func ParseInvokeResponse(model string, data []byte) (string, int, int, error) {
+	switch GetFamily(model) {
+	case FamilyLlama:
+		res := &LlamaResponse{}
+		err := json.Unmarshal(data, res)
+		return res.Generation, res.PromptTokenCount, res.GenerationTokenCount, err
+	case FamilyMistral:
+		res := &MistralResponse{}
+		err := json.Unmarshal(data, res)
+		if len(res.Outputs) != 0 && res.Outputs[0] != nil {
+			return res.Outputs[0].Text, 0, 0, err
+		}
+
+		return "", 0, 0, err
+	case FamilyCohereCommand:
+		res := &CohereCommandResponse{}
+		err := json.Unmarshal(data, res)
+		return res.GeneratedText(), 0, 0, err
+	case FamilyCohereCommandR:
+		res := &CohereCommandRResponse{}
+		err := json.Unmarshal(data, res)
+		return res.Text, 0, 0, err
+	case FamilyTitanEmbed:
+		res := &TitanEmbedResponse{}
+		err := json.Unmarshal(data, res)
+		return "", res.InputTextTokenCount, 0, err
+	}
+
+	return "", 0, 0, nil
+}
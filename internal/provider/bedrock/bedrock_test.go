@@ -0,0 +1,140 @@
+package bedrock
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetFamily(t *testing.T) {
+	cases := map[string]Family{
+		"anthropic.claude-3-haiku-20240307-v1:0":   FamilyAnthropic,
+		"us.meta.llama3-2-11b-instruct-v1:0":       FamilyLlama,
+		"meta.llama3-8b-instruct-v1:0":             FamilyLlama,
+		"mistral.mistral-large-2407-v1:0":          FamilyMistral,
+		"cohere.command-text-v14":                  FamilyCohereCommand,
+		"cohere.command-r-plus-v1:0":               FamilyCohereCommandR,
+		"cohere.embed-english-v3":                  FamilyCohereEmbed,
+		"amazon.titan-embed-text-v2:0":             FamilyTitanEmbed,
+		"amazon.titan-text-express-v1":             "",
+		"ai21.j2-ultra-v1":                         "",
+		"eu.anthropic.claude-3-sonnet-20240229-v1": FamilyAnthropic,
+	}
+
+	for model, family := range cases {
+		assert.Equal(t, family, GetFamily(model), model)
+	}
+
+	assert.Equal(t, "meta.llama3-2-11b-instruct-v1:0", NormalizeModel("us.meta.llama3-2-11b-instruct-v1:0"))
+	assert.Equal(t, "meta.llama3-8b-instruct-v1:0", NormalizeModel("meta.llama3-8b-instruct-v1:0"))
+	assert.True(t, FamilyTitanEmbed.IsEmbedding())
+	assert.False(t, FamilyLlama.IsEmbedding())
+}
+
+func TestParseInvokeRequest(t *testing.T) {
+	cases := []struct {
+		model    string
+		body     string
+		contents []string
+	}{
+		{"meta.llama3-8b-instruct-v1:0", `{"prompt": "hi", "max_gen_len": 10}`, []string{"hi"}},
+		{"mistral.mistral-7b-instruct-v0:2", `{"prompt": "<s>[INST] hi [/INST]"}`, []string{"<s>[INST] hi [/INST]"}},
+		{"cohere.command-text-v14", `{"prompt": "hi"}`, []string{"hi"}},
+		{"cohere.command-r-v1:0", `{"message": "now", "preamble": "sys", "chat_history": [{"role": "USER", "message": "before"}]}`, []string{"sys", "before", "now"}},
+		{"cohere.embed-english-v3", `{"texts": ["a", "b"], "input_type": "search_query"}`, []string{"a", "b"}},
+		{"amazon.titan-embed-text-v1", `{"inputText": "a"}`, []string{"a"}},
+	}
+
+	for _, c := range cases {
+		req, err := ParseInvokeRequest(c.model, []byte(c.body))
+		require.NoError(t, err, c.model)
+
+		contents := []string{}
+		for _, content := range req.Contents() {
+			contents = append(contents, *content)
+		}
+
+		assert.Equal(t, c.contents, contents, c.model)
+	}
+
+	req, err := ParseInvokeRequest("ai21.j2-ultra-v1", []byte(`{}`))
+	assert.NoError(t, err)
+	assert.Nil(t, req)
+
+	_, err = ParseInvokeRequest("meta.llama3-8b-instruct-v1:0", []byte(`{`))
+	assert.Error(t, err)
+}
+
+func TestParseInvokeRequest_UpdatesContentsInPlace(t *testing.T) {
+	req, err := ParseInvokeRequest("cohere.embed-english-v3", []byte(`{"texts": ["secret"]}`))
+	require.NoError(t, err)
+
+	*req.Contents()[0] = "***"
+
+	data, err := json.Marshal(req)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"texts": ["***"]}`, string(data))
+}
+
+func TestParseInvokeResponse(t *testing.T) {
+	cases := []struct {
+		model      string
+		body       string
+		text       string
+		prompt     int
+		completion int
+	}{
+		{"meta.llama3-8b-instruct-v1:0", `{"generation": "hello", "prompt_token_count": 3, "generation_token_count": 2}`, "hello", 3, 2},
+		{"mistral.mistral-7b-instruct-v0:2", `{"outputs": [{"text": "hello"}]}`, "hello", 0, 0},
+		{"cohere.command-text-v14", `{"generations": [{"text": "hello"}]}`, "hello", 0, 0},
+		{"cohere.command-r-v1:0", `{"text": "hello"}`, "hello", 0, 0},
+		{"amazon.titan-embed-text-v1", `{"embedding": [0.1], "inputTextTokenCount": 4}`, "", 4, 0},
+	}
+
+	for _, c := range cases {
+		text, prompt, completion, err := ParseInvokeResponse(c.model, []byte(c.body))
+		require.NoError(t, err, c.model)
+		assert.Equal(t, c.text, text, c.model)
+		assert.Equal(t, c.prompt, prompt, c.model)
+		assert.Equal(t, c.completion, completion, c.model)
+	}
+}
+
+func TestStreamChunk_GeneratedText(t *testing.T) {
+	cases := map[string]string{
+		`{"generation": "a"}`:                      "a",
+		`{"outputs": [{"text": "b"}]}`:             "b",
+		`{"generations": [{"text": "c"}]}`:         "c",
+		`{"text": "d", "is_finished": false}`:      "d",
+		`{"amazon-bedrock-invocationMetrics": {}}`: "",
+	}
+
+	for body, text := range cases {
+		chunk := &StreamChunk{}
+		require.NoError(t, json.Unmarshal([]byte(body), chunk))
+		assert.Equal(t, text, chunk.GeneratedText(), body)
+	}
+}
+
+func TestConverseRequest_Contents(t *testing.T) {
+	req := &ConverseRequest{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"system": [{"text": "sys"}],
+		"messages": [{"role": "user", "content": [{"text": "hi"}, {"image": {"format": "png"}}]}]
+	}`), req))
+
+	contents := req.Contents()
+	require.Len(t, contents, 2)
+	assert.Equal(t, "sys", *contents[0])
+	assert.Equal(t, "hi", *contents[1])
+
+	res := &ConverseResponse{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"output": {"message": {"role": "assistant", "content": [{"text": "hel"}, {"text": "lo"}]}},
+		"usage": {"inputTokens": 3, "outputTokens": 2}
+	}`), res))
+	assert.Equal(t, "hello", res.Text())
+	assert.Equal(t, "", (&ConverseResponse{}).Text())
+}
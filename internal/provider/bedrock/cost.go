@@ -0,0 +1,184 @@
+package bedrock
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/bricks-cloud/bricksllm/internal/util"
+)
+
+var LlamaPerThousandTokenCost = map[string]map[string]float64{
+	"prompt": {
+		"meta.llama2-13b-chat-v1":          0.00075,
+		"meta.llama2-70b-chat-v1":          0.00195,
+		"meta.llama3-8b-instruct-v1:0":     0.0003,
+		"meta.llama3-70b-instruct-v1:0":    0.00265,
+		"meta.llama3-1-8b-instruct-v1:0":   0.00022,
+		"meta.llama3-1-70b-instruct-v1:0":  0.00072,
+		"meta.llama3-1-405b-instruct-v1:0": 0.0024,
+		"meta.llama3-2-1b-instruct-v1:0":   0.0001,
+		"meta.llama3-2-3b-instruct-v1:0":   0.00015,
+		"meta.llama3-2-11b-instruct-v1:0":  0.00016,
+		"meta.llama3-2-90b-instruct-v1:0":  0.00072,
+	},
+	"completion": {
+		"meta.llama2-13b-chat-v1":          0.001,
+		"meta.llama2-70b-chat-v1":          0.00256,
+		"meta.llama3-8b-instruct-v1:0":     0.0006,
+		"meta.llama3-70b-instruct-v1:0":    0.0035,
+		"meta.llama3-1-8b-instruct-v1:0":   0.00022,
+		"meta.llama3-1-70b-instruct-v1:0":  0.00072,
+		"meta.llama3-1-405b-instruct-v1:0": 0.0024,
+		"meta.llama3-2-1b-instruct-v1:0":   0.0001,
+		"meta.llama3-2-3b-instruct-v1:0":   0.00015,
+		"meta.llama3-2-11b-instruct-v1:0":  0.00016,
+		"meta.llama3-2-90b-instruct-v1:0":  0.00072,
+	},
+}
+
+var MistralPerThousandTokenCost = map[string]map[string]float64{
+	"prompt": {
+		"mistral.mistral-7b-instruct-v0:2":   0.00015,
+		"mistral.mixtral-8x7b-instruct-v0:1": 0.00045,
+		"mistral.mistral-small-2402-v1:0":    0.001,
+		"mistral.mistral-large-2402-v1:0":    0.004,
+		"mistral.mistral-large-2407-v1:0":    0.002,
+	},
+	"completion": {
+		"mistral.mistral-7b-instruct-v0:2":   0.0002,
+		"mistral.mixtral-8x7b-instruct-v0:1": 0.0007,
+		"mistral.mistral-small-2402-v1:0":    0.003,
+		"mistral.mistral-large-2402-v1:0":    0.012,
+		"mistral.mistral-large-2407-v1:0":    0.006,
+	},
+}
+
+var CoherePerThousandTokenCost = map[string]map[string]float64{
+	"prompt": {
+		"cohere.command-text-v14":       0.0015,
+		"cohere.command-light-text-v14": 0.0003,
+		"cohere.command-r-v1:0":         0.0005,
+		"cohere.command-r-plus-v1:0":    0.003,
+	},
+	"completion": {
+		"cohere.command-text-v14":       0.002,
+		"cohere.command-light-text-v14": 0.0006,
+		"cohere.command-r-v1:0":         0.0015,
+		"cohere.command-r-plus-v1:0":    0.015,
+	},
+	"embeddings": {
+		"cohere.embed-english-v3":      0.0001,
+		"cohere.embed-multilingual-v3": 0.0001,
+	},
+}
+
+var TitanPerThousandTokenCost = map[string]map[string]float64{
+	"embeddings": {
+		"amazon.titan-embed-text-v1":   0.0001,
+		"amazon.titan-embed-text-v2:0": 0.00002,
+	},
+}
+
+type priceCatalog interface {
+	GetCostMap(provider, costType string) map[string]float64
+}
+
+type CostEstimator struct {
+	familyCostMaps map[Family]map[string]map[string]float64
+	pc             priceCatalog
+}
+
+func NewCostEstimator(pc priceCatalog) *CostEstimator {
+	return &CostEstimator{
+		pc: pc,
+		familyCostMaps: map[Family]map[string]map[string]float64{
+			FamilyLlama:          LlamaPerThousandTokenCost,
+			FamilyMistral:        MistralPerThousandTokenCost,
+			FamilyCohereCommand:  CoherePerThousandTokenCost,
+			FamilyCohereCommandR: CoherePerThousandTokenCost,
+			FamilyCohereEmbed:    CoherePerThousandTokenCost,
+			FamilyTitanEmbed:     TitanPerThousandTokenCost,
+		},
+	}
+}
+
+func (ce *CostEstimator) getCostMap(costType string, model string) (map[string]float64, error) {
+	var catalog map[string]float64
+	if ce.pc != nil {
+		catalog = ce.pc.GetCostMap("bedrock", costType)
+	}
+
+	tokenCostMap, ok := ce.familyCostMaps[GetFamily(model)]
+	if !ok && len(catalog) == 0 {
+		return nil, fmt.Errorf("%s does not belong to a supported model family", model)
+	}
+
+	costMap, ok := tokenCostMap[costType]
+	if !ok && len(catalog) == 0 {
+		return nil, fmt.Errorf("%s token cost is not provided", costType)
+	}
+
+	return util.MergeCostMaps(costMap, catalog), nil
+}
+
+func (ce *CostEstimator) estimateCost(costType string, model string, tks int) (float64, error) {
+	costMap, err := ce.getCostMap(costType, model)
+	if err != nil {
+		return 0, err
+	}
+
+	cost, ok := costMap[NormalizeModel(model)]
+	if !ok {
+		return 0, fmt.Errorf("%s is not present in the cost map provided", model)
+	}
+
+	return float64(tks) / 1000 * cost, nil
+}
+
+func (ce *CostEstimator) EstimatePromptCost(model string, tks int) (float64, error) {
+	return ce.estimateCost("prompt", model, tks)
+}
+
+func (ce *CostEstimator) EstimateCompletionCost(model string, tks int) (float64, error) {
+	return ce.estimateCost("completion", model, tks)
+}
+
+func (ce *CostEstimator) EstimateTotalCost(model string, promptTks, completionTks int) (float64, error) {
+	promptCost, err := ce.EstimatePromptCost(model, promptTks)
+	if err != nil {
+		return 0, err
+	}
+
+	completionCost, err := ce.EstimateCompletionCost(model, completionTks)
+	if err != nil {
+		return 0, err
+	}
+
+	return promptCost + completionCost, nil
+}
+
+func (ce *CostEstimator) EstimateEmbeddingsCost(model string, tks int) (float64, error) {
+	if len(model) == 0 {
+		return 0, errors.New("model is empty")
+	}
+
+	return ce.estimateCost("embeddings", model, tks)
+}
+
+// Count approximates the number of tokens in the input. The families served by
+// bedrock use different tokenizers that average about four characters per token.
+func (ce *CostEstimator) Count(input string) int {
+	return (utf8.RuneCountInString(input) + 3) / 4
+}
+
+func (ce *CostEstimator) CountContents(contents []*string) int {
+	count := 0
+	for _, content := range contents {
+		if content != nil {
+			count += ce.Count(*content)
+		}
+	}
+
+	return count
+}
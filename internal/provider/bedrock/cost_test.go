@@ -0,0 +1,51 @@
+package bedrock
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCostEstimator_EstimateTotalCost(t *testing.T) {
+	ce := NewCostEstimator(nil)
+
+	cost, err := ce.EstimateTotalCost("us.meta.llama3-2-11b-instruct-v1:0", 1000, 2000)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.00048, cost, 1e-12)
+
+	cost, err = ce.EstimateTotalCost("cohere.command-r-plus-v1:0", 1000, 1000)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.018, cost, 1e-12)
+
+	_, err = ce.EstimateTotalCost("meta.llama9-unknown", 10, 10)
+	assert.Error(t, err)
+
+	_, err = ce.EstimateTotalCost("ai21.j2-ultra-v1", 10, 10)
+	assert.Error(t, err)
+
+	_, err = ce.EstimateTotalCost("amazon.titan-embed-text-v1", 10, 10)
+	assert.Error(t, err)
+}
+
+func TestCostEstimator_EstimateEmbeddingsCost(t *testing.T) {
+	ce := NewCostEstimator(nil)
+
+	cost, err := ce.EstimateEmbeddingsCost("amazon.titan-embed-text-v2:0", 1000)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.00002, cost, 1e-12)
+
+	cost, err = ce.EstimateEmbeddingsCost("cohere.embed-english-v3", 2000)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.0002, cost, 1e-12)
+
+	_, err = ce.EstimateEmbeddingsCost("", 10)
+	assert.Error(t, err)
+}
+
+func TestCostEstimator_CountContents(t *testing.T) {
+	ce := NewCostEstimator(nil)
+	a, b := "abcd", "abcde"
+
+	assert.Equal(t, 3, ce.CountContents([]*string{&a, nil, &b}))
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+
+	"github.com/bricks-cloud/bricksllm/internal/util"
 )
 
 var DeepinfraPerMillionTokenCost = map[string]map[string]float64{
@@ -25,18 +27,35 @@ var DeepinfraPerMillionTokenCost = map[string]map[string]float64{
 	},
 }
 
+type priceCatalog interface {
+	GetCostMap(provider, costType string) map[string]float64
+}
+
 type CostEstimator struct {
 	tokenCostMap map[string]map[string]float64
+	pc           priceCatalog
 }
 
-func NewCostEstimator() *CostEstimator {
+func NewCostEstimator(pc priceCatalog) *CostEstimator {
 	return &CostEstimator{
 		tokenCostMap: DeepinfraPerMillionTokenCost,
+		pc:           pc,
 	}
 }
 
+func (ce *CostEstimator) getCostMap(costType string) (map[string]float64, bool) {
+	costMap, ok := ce.tokenCostMap[costType]
+	if ce.pc != nil {
+		if catalog := ce.pc.GetCostMap("deepinfra", costType); len(catalog) != 0 {
+			return util.MergeCostMaps(costMap, catalog), true
+		}
+	}
+
+	return costMap, ok
+}
+
 func (ce *CostEstimator) EstimateEmbeddingsInputCost(model string, tks int) (float64, error) {
-	costMap, ok := ce.tokenCostMap["prompt"]
+	costMap, ok := ce.getCostMap("prompt")
 	if !ok {
 		return 0, errors.New("prompt token cost is not provided")
 
@@ -51,3 +70,33 @@ func (ce *CostEstimator) EstimateEmbeddingsInputCost(model string, tks int) (flo
 	tksInFloat := float64(tks)
 	return tksInFloat / 1000000 * cost, nil
 }
+
+func (ce *CostEstimator) estimateCost(costType, model string, tks int) (float64, error) {
+	costMap, ok := ce.getCostMap(costType)
+	if !ok {
+		return 0, fmt.Errorf("%s token cost is not provided", costType)
+	}
+
+	cost, ok := costMap[strings.ToLower(model)]
+	if !ok {
+		return 0, fmt.Errorf("%s is not present in the cost map provided", model)
+	}
+
+	return float64(tks) / 1000000 * cost, nil
+}
+
+// EstimateTotalCost returns the cost of a chat completion priced with the prompt and
+// completion costs of the pricing catalog.
+func (ce *CostEstimator) EstimateTotalCost(model string, promptTks, completionTks int) (float64, error) {
+	promptCost, err := ce.estimateCost("prompt", model, promptTks)
+	if err != nil {
+		return 0, err
+	}
+
+	completionCost, err := ce.estimateCost("completion", model, completionTks)
+	if err != nil {
+		return 0, err
+	}
+
+	return promptCost + completionCost, nil
+}
@@ -0,0 +1,158 @@
+package gemini
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bricks-cloud/bricksllm/internal/util"
+)
+
+// longContextThreshold is the number of prompt tokens above which models with long
+// context pricing are billed at the long context rates.
+const longContextThreshold = 128000
+
+var GeminiPerMillionTokenCost = map[string]map[string]float64{
+	"prompt": {
+		"gemini-2.0-flash-lite": 0.075,
+		"gemini-2.0-flash":      0.1,
+		"gemini-1.5-pro":        1.25,
+		"gemini-1.5-flash":      0.075,
+		"gemini-1.5-flash-8b":   0.0375,
+		"gemini-1.0-pro":        0.5,
+		"gemini-pro":            0.5,
+	},
+	"completion": {
+		"gemini-2.0-flash-lite": 0.3,
+		"gemini-2.0-flash":      0.4,
+		"gemini-1.5-pro":        5,
+		"gemini-1.5-flash":      0.3,
+		"gemini-1.5-flash-8b":   0.15,
+		"gemini-1.0-pro":        1.5,
+		"gemini-pro":            1.5,
+	},
+	"prompt_long_context": {
+		"gemini-1.5-pro":      2.5,
+		"gemini-1.5-flash":    0.15,
+		"gemini-1.5-flash-8b": 0.075,
+	},
+	"completion_long_context": {
+		"gemini-1.5-pro":      10,
+		"gemini-1.5-flash":    0.6,
+		"gemini-1.5-flash-8b": 0.3,
+	},
+	"embeddings": {
+		"text-embedding-004": 0,
+		"embedding-001":      0,
+	},
+}
+
+type priceCatalog interface {
+	GetCostMap(provider, costType string) map[string]float64
+}
+
+type CostEstimator struct {
+	tokenCostMap map[string]map[string]float64
+	pc           priceCatalog
+}
+
+func NewCostEstimator(pc priceCatalog) *CostEstimator {
+	return &CostEstimator{
+		tokenCostMap: GeminiPerMillionTokenCost,
+		pc:           pc,
+	}
+}
+
+func (ce *CostEstimator) getCostMap(costType string) (map[string]float64, bool) {
+	costMap, ok := ce.tokenCostMap[costType]
+	if ce.pc != nil {
+		if catalog := ce.pc.GetCostMap("gemini", costType); len(catalog) != 0 {
+			return util.MergeCostMaps(costMap, catalog), true
+		}
+	}
+
+	return costMap, ok
+}
+
+// selectModel returns the longest model in the cost map that prefixes the model so
+// that versioned models such as gemini-1.5-flash-002 use the price of their family.
+func selectModel(model string, costMap map[string]float64) string {
+	model = strings.TrimPrefix(model, "models/")
+
+	selected := ""
+	for name := range costMap {
+		if strings.HasPrefix(model, name) && len(name) > len(selected) {
+			selected = name
+		}
+	}
+
+	return selected
+}
+
+func (ce *CostEstimator) estimateCost(costType string, model string, tks int) (float64, error) {
+	costMap, ok := ce.getCostMap(costType)
+	if !ok {
+		return 0, fmt.Errorf("%s token cost is not provided", costType)
+	}
+
+	selected := selectModel(model, costMap)
+	if len(selected) == 0 {
+		return 0, fmt.Errorf("%s is not present in the cost map provided", model)
+	}
+
+	return float64(tks) / 1000000 * costMap[selected], nil
+}
+
+func (ce *CostEstimator) EstimatePromptCost(model string, tks int) (float64, error) {
+	return ce.estimateCost("prompt", model, tks)
+}
+
+func (ce *CostEstimator) EstimateCompletionCost(model string, tks int) (float64, error) {
+	return ce.estimateCost("completion", model, tks)
+}
+
+func (ce *CostEstimator) EstimateTotalCost(model string, promptTks, completionTks int) (float64, error) {
+	promptType, completionType := "prompt", "completion"
+	longContextCostMap, _ := ce.getCostMap("prompt_long_context")
+	if promptTks > longContextThreshold && len(selectModel(model, longContextCostMap)) != 0 {
+		promptType, completionType = "prompt_long_context", "completion_long_context"
+	}
+
+	promptCost, err := ce.estimateCost(promptType, model, promptTks)
+	if err != nil {
+		return 0, err
+	}
+
+	completionCost, err := ce.estimateCost(completionType, model, completionTks)
+	if err != nil {
+		return 0, err
+	}
+
+	return promptCost + completionCost, nil
+}
+
+func (ce *CostEstimator) EstimateEmbeddingsCost(model string, tks int) (float64, error) {
+	if len(model) == 0 {
+		return 0, errors.New("model is empty")
+	}
+
+	return ce.estimateCost("embeddings", model, tks)
+}
+
+// Count approximates the number of gemini tokens in the input. Gemini tokens are
+// about four characters long.
+func (ce *CostEstimator) Count(input string) int {
+	return (utf8.RuneCountInString(input) + 3) / 4
+}
+
+func (ce *CostEstimator) CountPartsTokens(parts []*Part) int {
+	count := 0
+	for _, part := range parts {
+		if part != nil && part.Text != nil {
+			count += ce.Count(*part.Text)
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,51 @@
+package gemini
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCostEstimator_EstimateTotalCost(t *testing.T) {
+	ce := NewCostEstimator(nil)
+
+	cost, err := ce.EstimateTotalCost("gemini-1.5-flash-002", 100000, 100000)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.0375, cost, 1e-9)
+
+	cost, err = ce.EstimateTotalCost("models/gemini-1.5-flash-8b", 100000, 0)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.00375, cost, 1e-9)
+
+	cost, err = ce.EstimateTotalCost("gemini-1.5-pro", 200000, 100000)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.5+1, cost, 1e-9)
+
+	cost, err = ce.EstimateTotalCost("gemini-2.0-flash", 200000, 0)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.02, cost, 1e-9)
+
+	_, err = ce.EstimateTotalCost("palm-2", 10, 10)
+	assert.Error(t, err)
+}
+
+func TestCostEstimator_EstimateEmbeddingsCost(t *testing.T) {
+	ce := NewCostEstimator(nil)
+
+	cost, err := ce.EstimateEmbeddingsCost("text-embedding-004", 1000)
+	require.NoError(t, err)
+	assert.Equal(t, float64(0), cost)
+
+	_, err = ce.EstimateEmbeddingsCost("", 1000)
+	assert.Error(t, err)
+}
+
+func TestCostEstimator_Count(t *testing.T) {
+	ce := NewCostEstimator(nil)
+	text := "héllo world"
+
+	assert.Equal(t, 0, ce.Count(""))
+	assert.Equal(t, 3, ce.Count(text))
+	assert.Equal(t, 3, ce.CountPartsTokens([]*Part{nil, {}, {Text: &text}}))
+}
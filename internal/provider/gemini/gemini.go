@@ -0,0 +1,294 @@
+package gemini
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// fields holds the json fields of a gemini object that are not modeled so that
+// requests can be modified and forwarded without dropping them. Gemini accepts
+// both camel case and snake case field names.
+type fields map[string]json.RawMessage
+
+func (f fields) pop(names ...string) json.RawMessage {
+	for _, name := range names {
+		if val, ok := f[name]; ok {
+			delete(f, name)
+			return val
+		}
+	}
+
+	return nil
+}
+
+type Part struct {
+	Text   *string `json:"text,omitempty"`
+	others fields
+}
+
+func (p *Part) UnmarshalJSON(data []byte) error {
+	f := fields{}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return err
+	}
+
+	if raw := f.pop("text"); raw != nil {
+		text := ""
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return err
+		}
+
+		p.Text = &text
+	}
+
+	p.others = f
+	return nil
+}
+
+func (p Part) MarshalJSON() ([]byte, error) {
+	f := fields{}
+	for k, v := range p.others {
+		f[k] = v
+	}
+
+	if p.Text != nil {
+		data, err := json.Marshal(*p.Text)
+		if err != nil {
+			return nil, err
+		}
+
+		f["text"] = data
+	}
+
+	return json.Marshal(f)
+}
+
+type Content struct {
+	Role  string  `json:"role,omitempty"`
+	Parts []*Part `json:"parts"`
+}
+
+// Texts returns the text of every text part of the content.
+func (c *Content) Texts() []string {
+	texts := []string{}
+	if c == nil {
+		return texts
+	}
+
+	for _, part := range c.Parts {
+		if part != nil && part.Text != nil {
+			texts = append(texts, *part.Text)
+		}
+	}
+
+	return texts
+}
+
+type GenerateContentRequest struct {
+	Contents          []*Content `json:"contents"`
+	SystemInstruction *Content   `json:"systemInstruction,omitempty"`
+	others            fields
+}
+
+func (r *GenerateContentRequest) UnmarshalJSON(data []byte) error {
+	f := fields{}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return err
+	}
+
+	if raw := f.pop("contents"); raw != nil {
+		if err := json.Unmarshal(raw, &r.Contents); err != nil {
+			return err
+		}
+	}
+
+	if raw := f.pop("systemInstruction", "system_instruction"); raw != nil {
+		if err := json.Unmarshal(raw, &r.SystemInstruction); err != nil {
+			return err
+		}
+	}
+
+	r.others = f
+	return nil
+}
+
+func (r GenerateContentRequest) MarshalJSON() ([]byte, error) {
+	f := fields{}
+	for k, v := range r.others {
+		f[k] = v
+	}
+
+	data, err := json.Marshal(r.Contents)
+	if err != nil {
+		return nil, err
+	}
+
+	f["contents"] = data
+
+	if r.SystemInstruction != nil {
+		data, err := json.Marshal(r.SystemInstruction)
+		if err != nil {
+			return nil, err
+		}
+
+		f["systemInstruction"] = data
+	}
+
+	return json.Marshal(f)
+}
+
+// TextParts returns the text parts of the system instruction and contents of the
+// request in order.
+func (r *GenerateContentRequest) TextParts() []*Part {
+	parts := []*Part{}
+
+	contents := append([]*Content{r.SystemInstruction}, r.Contents...)
+	for _, content := range contents {
+		if content == nil {
+			continue
+		}
+
+		for _, part := range content.Parts {
+			if part != nil && part.Text != nil {
+				parts = append(parts, part)
+			}
+		}
+	}
+
+	return parts
+}
+
+type EmbedContentRequest struct {
+	Model   string   `json:"model,omitempty"`
+	Content *Content `json:"content"`
+	others  fields
+}
+
+func (r *EmbedContentRequest) UnmarshalJSON(data []byte) error {
+	f := fields{}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return err
+	}
+
+	if raw := f.pop("model"); raw != nil {
+		if err := json.Unmarshal(raw, &r.Model); err != nil {
+			return err
+		}
+	}
+
+	if raw := f.pop("content"); raw != nil {
+		if err := json.Unmarshal(raw, &r.Content); err != nil {
+			return err
+		}
+	}
+
+	r.others = f
+	return nil
+}
+
+func (r EmbedContentRequest) MarshalJSON() ([]byte, error) {
+	f := fields{}
+	for k, v := range r.others {
+		f[k] = v
+	}
+
+	if len(r.Model) != 0 {
+		data, err := json.Marshal(r.Model)
+		if err != nil {
+			return nil, err
+		}
+
+		f["model"] = data
+	}
+
+	data, err := json.Marshal(r.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	f["content"] = data
+
+	return json.Marshal(f)
+}
+
+type BatchEmbedContentsRequest struct {
+	Requests []*EmbedContentRequest `json:"requests"`
+}
+
+// TextParts returns the text parts of every embedding request in order.
+func (r *BatchEmbedContentsRequest) TextParts() []*Part {
+	parts := []*Part{}
+	for _, req := range r.Requests {
+		if req == nil || req.Content == nil {
+			continue
+		}
+
+		for _, part := range req.Content.Parts {
+			if part != nil && part.Text != nil {
+				parts = append(parts, part)
+			}
+		}
+	}
+
+	return parts
+}
+
+type UsageMetadata struct {
+	PromptTokenCount        int `json:"promptTokenCount"`
+	CandidatesTokenCount    int `json:"candidatesTokenCount"`
+	ThoughtsTokenCount      int `json:"thoughtsTokenCount"`
+	CachedContentTokenCount int `json:"cachedContentTokenCount"`
+	TotalTokenCount         int `json:"totalTokenCount"`
+}
+
+// CompletionTokenCount returns the number of tokens billed as output.
+func (um *UsageMetadata) CompletionTokenCount() int {
+	if um == nil {
+		return 0
+	}
+
+	return um.CandidatesTokenCount + um.ThoughtsTokenCount
+}
+
+type Candidate struct {
+	Content      *Content `json:"content"`
+	FinishReason string   `json:"finishReason"`
+	Index        int      `json:"index"`
+}
+
+type GenerateContentResponse struct {
+	Candidates    []*Candidate   `json:"candidates"`
+	UsageMetadata *UsageMetadata `json:"usageMetadata"`
+	ModelVersion  string         `json:"modelVersion"`
+}
+
+// Text returns the text generated in the first candidate of the response.
+func (r *GenerateContentResponse) Text() string {
+	if len(r.Candidates) == 0 || r.Candidates[0] == nil {
+		return ""
+	}
+
+	return strings.Join(r.Candidates[0].Content.Texts(), "")
+}
+
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+type ErrorResponse struct {
+	Error *Error `json:"error"`
+}
+
+// ParseModelAction splits the last path segment of a gemini model endpoint such as
+// gemini-1.5-flash:generateContent into the model and the action.
+func ParseModelAction(segment string) (string, string) {
+	segment = strings.TrimPrefix(segment, "/")
+	idx := strings.LastIndex(segment, ":")
+	if idx < 0 {
+		return segment, ""
+	}
+
+	return segment[:idx], segment[idx+1:]
+}
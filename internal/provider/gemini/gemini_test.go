@@ -0,0 +1,92 @@
+package gemini
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateContentRequest_RoundTrip(t *testing.T) {
+	body := `{
+		"contents": [{"role": "user", "parts": [{"text": "hi"}, {"inline_data": {"mime_type": "image/png", "data": "AA=="}}]}],
+		"system_instruction": {"parts": [{"text": "be brief"}]},
+		"generationConfig": {"temperature": 0.2}
+	}`
+
+	req := &GenerateContentRequest{}
+	require.NoError(t, json.Unmarshal([]byte(body), req))
+	require.NotNil(t, req.SystemInstruction)
+
+	parts := req.TextParts()
+	require.Len(t, parts, 2)
+	assert.Equal(t, "be brief", *parts[0].Text)
+	assert.Equal(t, "hi", *parts[1].Text)
+
+	redacted := "***"
+	parts[1].Text = &redacted
+
+	data, err := json.Marshal(req)
+	require.NoError(t, err)
+
+	out := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(data, &out))
+	assert.Contains(t, out, "systemInstruction")
+	assert.NotContains(t, out, "system_instruction")
+	assert.Equal(t, map[string]interface{}{"temperature": 0.2}, out["generationConfig"])
+
+	contents := out["contents"].([]interface{})
+	outParts := contents[0].(map[string]interface{})["parts"].([]interface{})
+	assert.Equal(t, "***", outParts[0].(map[string]interface{})["text"])
+	assert.Contains(t, outParts[1], "inline_data")
+}
+
+func TestEmbedContentRequest_RoundTrip(t *testing.T) {
+	body := `{"model": "models/text-embedding-004", "content": {"parts": [{"text": "a"}]}, "taskType": "RETRIEVAL_QUERY"}`
+
+	req := &EmbedContentRequest{}
+	require.NoError(t, json.Unmarshal([]byte(body), req))
+	assert.Equal(t, "models/text-embedding-004", req.Model)
+	assert.Equal(t, []string{"a"}, req.Content.Texts())
+
+	data, err := json.Marshal(req)
+	require.NoError(t, err)
+	assert.JSONEq(t, body, string(data))
+
+	batch := &BatchEmbedContentsRequest{Requests: []*EmbedContentRequest{req, nil, {}}}
+	assert.Len(t, batch.TextParts(), 1)
+}
+
+func TestGenerateContentResponse_Text(t *testing.T) {
+	resp := &GenerateContentResponse{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"candidates": [{"content": {"role": "model", "parts": [{"text": "hel"}, {"text": "lo"}]}}],
+		"usageMetadata": {"promptTokenCount": 3, "candidatesTokenCount": 2, "thoughtsTokenCount": 5}
+	}`), resp))
+
+	assert.Equal(t, "hello", resp.Text())
+	assert.Equal(t, 7, resp.UsageMetadata.CompletionTokenCount())
+	assert.Equal(t, "", (&GenerateContentResponse{}).Text())
+
+	var um *UsageMetadata
+	assert.Equal(t, 0, um.CompletionTokenCount())
+}
+
+func TestParseModelAction(t *testing.T) {
+	cases := []struct {
+		segment string
+		model   string
+		action  string
+	}{
+		{"/gemini-1.5-flash:generateContent", "gemini-1.5-flash", "generateContent"},
+		{"gemini-1.5-pro:streamGenerateContent", "gemini-1.5-pro", "streamGenerateContent"},
+		{"/gemini-1.5-pro", "gemini-1.5-pro", ""},
+	}
+
+	for _, c := range cases {
+		model, action := ParseModelAction(c.segment)
+		assert.Equal(t, c.model, model, c.segment)
+		assert.Equal(t, c.action, action, c.segment)
+	}
+}
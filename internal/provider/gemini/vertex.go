@@ -0,0 +1,61 @@
+package gemini
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const vertexScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// VertexTokenSource issues access tokens for Vertex AI from service account
+// credentials. Token sources are cached per credentials so that tokens are reused
+// until they expire.
+type VertexTokenSource struct {
+	lock    sync.Mutex
+	sources map[string]oauth2.TokenSource
+}
+
+func NewVertexTokenSource() *VertexTokenSource {
+	return &VertexTokenSource{
+		sources: map[string]oauth2.TokenSource{},
+	}
+}
+
+func (vts *VertexTokenSource) getTokenSource(credentials string) (oauth2.TokenSource, error) {
+	sum := sha256.Sum256([]byte(credentials))
+	id := hex.EncodeToString(sum[:])
+
+	vts.lock.Lock()
+	defer vts.lock.Unlock()
+
+	if ts, ok := vts.sources[id]; ok {
+		return ts, nil
+	}
+
+	creds, err := google.CredentialsFromJSON(context.Background(), []byte(credentials), vertexScope)
+	if err != nil {
+		return nil, err
+	}
+
+	vts.sources[id] = creds.TokenSource
+	return creds.TokenSource, nil
+}
+
+func (vts *VertexTokenSource) Token(credentials string) (string, error) {
+	ts, err := vts.getTokenSource(credentials)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := ts.Token()
+	if err != nil {
+		return "", err
+	}
+
+	return token.AccessToken, nil
+}
@@ -32,6 +32,9 @@ func parseFinetuneModel(model string) string {
 	return model
 }
 
+// OpenAiPerThousandTokenCost also holds the cost of an image keyed by ImageModel and
+// the ratio of the prompt price that cached prompt tokens are billed at keyed by
+// model, with the default ratio under DefaultRatioModel.
 var OpenAiPerThousandTokenCost = map[string]map[string]float64{
 	"prompt": {
 		"o1":                          0.015,
@@ -99,6 +102,20 @@ var OpenAiPerThousandTokenCost = map[string]map[string]float64{
 		"tts-1":     0.015,
 		"tts-1-hd":  0.03,
 	},
+	"image": {
+		"dall-e-3/standard/1024x1024": 0.04,
+		"dall-e-3/standard/1024x1792": 0.08,
+		"dall-e-3/standard/1792x1024": 0.08,
+		"dall-e-3/hd/1024x1024":       0.08,
+		"dall-e-3/hd/1024x1792":       0.12,
+		"dall-e-3/hd/1792x1024":       0.12,
+		"dall-e-2/standard/256x256":   0.016,
+		"dall-e-2/standard/512x512":   0.018,
+		"dall-e-2/standard/1024x1024": 0.02,
+	},
+	"cached_prompt_ratio": {
+		DefaultRatioModel: 0.5,
+	},
 	"completion": {
 		"o1":                          0.06,
 		"o1-2024-12-17":               0.06,
@@ -139,20 +156,65 @@ var OpenAiPerThousandTokenCost = map[string]map[string]float64{
 	},
 }
 
+// DefaultRatioModel is the model of the cost ratio that applies to models without a
+// ratio of their own.
+const DefaultRatioModel = "default"
+
+// requests made through the batch api are billed at half of the regular price
+var BatchCostRatio = 0.5
+
+// ImageModel returns the model an image of the quality and size is priced under.
+func ImageModel(model, quality, size string) string {
+	return strings.Join([]string{model, quality, size}, "/")
+}
+
 type tokenCounter interface {
 	Count(model string, input string) (int, error)
 }
 
+type priceCatalog interface {
+	GetCostMap(provider, costType string) map[string]float64
+}
+
 type CostEstimator struct {
 	tokenCostMap map[string]map[string]float64
+	pc           priceCatalog
 	tc           tokenCounter
 }
 
-func NewCostEstimator(m map[string]map[string]float64, tc tokenCounter) *CostEstimator {
+func NewCostEstimator(m map[string]map[string]float64, tc tokenCounter, pc priceCatalog) *CostEstimator {
 	return &CostEstimator{
 		tokenCostMap: m,
 		tc:           tc,
+		pc:           pc,
+	}
+}
+
+// getCostMap applies prices from the pricing catalog on top of the built in cost map
+// so that models missing from the catalog keep their built in prices.
+func (ce *CostEstimator) getCostMap(costType string) (map[string]float64, bool) {
+	costMap, ok := ce.tokenCostMap[costType]
+	if ce.pc != nil {
+		if catalog := ce.pc.GetCostMap("openai", costType); len(catalog) != 0 {
+			return util.MergeCostMaps(costMap, catalog), true
+		}
+	}
+
+	return costMap, ok
+}
+
+func (ce *CostEstimator) getCostRatio(costType, model string) (float64, error) {
+	costMap, _ := ce.getCostMap(costType)
+	if ratio, ok := costMap[useFinetuneModel(model)]; ok {
+		return ratio, nil
 	}
+
+	ratio, ok := costMap[DefaultRatioModel]
+	if !ok {
+		return 0, fmt.Errorf("%s is not provided for %s", costType, model)
+	}
+
+	return ratio, nil
 }
 
 func (ce *CostEstimator) EstimateTotalCost(model string, promptTks, completionTks int) (float64, error) {
@@ -169,8 +231,64 @@ func (ce *CostEstimator) EstimateTotalCost(model string, promptTks, completionTk
 	return promptCost + completionCost, nil
 }
 
+func (ce *CostEstimator) EstimateTotalCostWithCachedTokens(model string, promptTks, cachedTks, completionTks int) (float64, error) {
+	promptCost, err := ce.EstimatePromptCostWithCachedTokens(model, promptTks, cachedTks)
+	if err != nil {
+		return 0, err
+	}
+
+	completionCost, err := ce.EstimateCompletionCost(model, completionTks)
+	if err != nil {
+		return 0, err
+	}
+
+	return promptCost + completionCost, nil
+}
+
+// EstimatePromptCostWithCachedTokens expects cachedTks to be a subset of tks as reported in prompt_tokens_details.
+func (ce *CostEstimator) EstimatePromptCostWithCachedTokens(model string, tks, cachedTks int) (float64, error) {
+	if cachedTks > tks {
+		cachedTks = tks
+	}
+
+	uncachedCost, err := ce.EstimatePromptCost(model, tks-cachedTks)
+	if err != nil {
+		return 0, err
+	}
+
+	cachedCost, err := ce.EstimatePromptCost(model, cachedTks)
+	if err != nil {
+		return 0, err
+	}
+
+	ratio, err := ce.getCostRatio("cached_prompt_ratio", model)
+	if err != nil {
+		return 0, err
+	}
+
+	return uncachedCost + cachedCost*ratio, nil
+}
+
+func (ce *CostEstimator) EstimateBatchCost(model string, promptTks, cachedTks, completionTks int) (float64, error) {
+	cost, err := ce.EstimateTotalCostWithCachedTokens(model, promptTks, cachedTks, completionTks)
+	if err != nil {
+		return 0, err
+	}
+
+	return cost * BatchCostRatio, nil
+}
+
+func (ce *CostEstimator) EstimateBatchEmbeddingsCost(model string, tks int) (float64, error) {
+	cost, err := ce.EstimateEmbeddingsInputCost(model, tks)
+	if err != nil {
+		return 0, err
+	}
+
+	return cost * BatchCostRatio, nil
+}
+
 func (ce *CostEstimator) EstimatePromptCost(model string, tks int) (float64, error) {
-	costMap, ok := ce.tokenCostMap["prompt"]
+	costMap, ok := ce.getCostMap("prompt")
 	if !ok {
 		return 0, errors.New("prompt token cost is not provided")
 
@@ -186,7 +304,7 @@ func (ce *CostEstimator) EstimatePromptCost(model string, tks int) (float64, err
 }
 
 func (ce *CostEstimator) EstimateEmbeddingsInputCost(model string, tks int) (float64, error) {
-	costMap, ok := ce.tokenCostMap["embeddings"]
+	costMap, ok := ce.getCostMap("embeddings")
 	if !ok {
 		return 0, errors.New("embeddings token cost is not provided")
 
@@ -202,7 +320,7 @@ func (ce *CostEstimator) EstimateEmbeddingsInputCost(model string, tks int) (flo
 }
 
 func (ce *CostEstimator) EstimateCompletionCost(model string, tks int) (float64, error) {
-	costMap, ok := ce.tokenCostMap["completion"]
+	costMap, ok := ce.getCostMap("completion")
 	if !ok {
 		return 0, errors.New("prompt token cost is not provided")
 	}
@@ -303,7 +421,7 @@ func (ce *CostEstimator) EstimateCompletionsStreamCostWithTokenCounts(model stri
 }
 
 func (ce *CostEstimator) EstimateTranscriptionCost(secs float64, model string) (float64, error) {
-	costMap, ok := ce.tokenCostMap["audio"]
+	costMap, ok := ce.getCostMap("audio")
 	if !ok {
 		return 0, errors.New("audio cost map is not provided")
 	}
@@ -317,7 +435,7 @@ func (ce *CostEstimator) EstimateTranscriptionCost(secs float64, model string) (
 }
 
 func (ce *CostEstimator) EstimateSpeechCost(input string, model string) (float64, error) {
-	costMap, ok := ce.tokenCostMap["audio"]
+	costMap, ok := ce.getCostMap("audio")
 	if !ok {
 		return 0, errors.New("audio cost map is not provided")
 	}
@@ -331,7 +449,7 @@ func (ce *CostEstimator) EstimateSpeechCost(input string, model string) (float64
 }
 
 func (ce *CostEstimator) EstimateFinetuningCost(num int, model string) (float64, error) {
-	costMap, ok := ce.tokenCostMap["finetune"]
+	costMap, ok := ce.getCostMap("finetune")
 	if !ok {
 		return 0, errors.New("audio cost map is not provided")
 	}
@@ -344,6 +462,36 @@ func (ce *CostEstimator) EstimateFinetuningCost(num int, model string) (float64,
 	return cost * float64(num), nil
 }
 
+func (ce *CostEstimator) EstimateImageCost(model, quality, size string, n int) (float64, error) {
+	if len(model) == 0 {
+		model = "dall-e-2"
+	}
+
+	if len(quality) == 0 {
+		quality = "standard"
+	}
+
+	if len(size) == 0 {
+		size = "1024x1024"
+	}
+
+	if n == 0 {
+		n = 1
+	}
+
+	costMap, ok := ce.getCostMap("image")
+	if !ok {
+		return 0, errors.New("image cost is not provided")
+	}
+
+	cost, ok := costMap[ImageModel(model, quality, size)]
+	if !ok {
+		return 0, fmt.Errorf("%s images of quality %s and size %s are not present in the image cost map", model, quality, size)
+	}
+
+	return cost * float64(n), nil
+}
+
 func (ce *CostEstimator) EstimateEmbeddingsCost(r *goopenai.EmbeddingRequest) (float64, error) {
 	if len(string(r.Model)) == 0 {
 		return 0, errors.New("model is not provided")
@@ -0,0 +1,116 @@
+package openai
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCostEstimator_EstimateImageCost(t *testing.T) {
+	ce := NewCostEstimator(OpenAiPerThousandTokenCost, nil, nil)
+
+	cases := []struct {
+		model   string
+		quality string
+		size    string
+		n       int
+		cost    float64
+		valid   bool
+	}{
+		{"", "", "", 0, 0.02, true},
+		{"dall-e-2", "", "256x256", 3, 0.048, true},
+		{"dall-e-3", "hd", "1792x1024", 2, 0.24, true},
+		{"dall-e-3", "standard", "1024x1024", 1, 0.04, true},
+		{"dall-e-2", "hd", "1024x1024", 1, 0, false},
+		{"dall-e-3", "standard", "256x256", 1, 0, false},
+		{"gpt-image-0", "", "", 1, 0, false},
+	}
+
+	for _, c := range cases {
+		cost, err := ce.EstimateImageCost(c.model, c.quality, c.size, c.n)
+		assert.Equal(t, c.valid, err == nil, c.model)
+		assert.InDelta(t, c.cost, cost, 1e-12, c.model)
+	}
+}
+
+func TestCostEstimator_EstimatePromptCostWithCachedTokens(t *testing.T) {
+	ce := NewCostEstimator(OpenAiPerThousandTokenCost, nil, nil)
+
+	cost, err := ce.EstimatePromptCostWithCachedTokens("gpt-4o", 2000, 1000)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.0025+0.00125, cost, 1e-12)
+
+	cost, err = ce.EstimatePromptCostWithCachedTokens("gpt-4o", 1000, 5000)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.00125, cost, 1e-12)
+
+	cost, err = ce.EstimateTotalCostWithCachedTokens("gpt-4o", 2000, 1000, 1000)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.00375+0.01, cost, 1e-12)
+
+	_, err = ce.EstimatePromptCostWithCachedTokens("unknown", 10, 1)
+	assert.Error(t, err)
+}
+
+func TestCostEstimator_EstimateBatchCost(t *testing.T) {
+	ce := NewCostEstimator(OpenAiPerThousandTokenCost, nil, nil)
+
+	cost, err := ce.EstimateBatchCost("gpt-4o", 2000, 0, 1000)
+	require.NoError(t, err)
+	assert.InDelta(t, (0.005+0.01)/2, cost, 1e-12)
+
+	cost, err = ce.EstimateBatchEmbeddingsCost("text-embedding-3-small", 1000)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.00001, cost, 1e-12)
+}
+
+type fakePriceCatalog map[string]map[string]float64
+
+func (pc fakePriceCatalog) GetCostMap(provider, costType string) map[string]float64 {
+	if provider != "openai" {
+		return nil
+	}
+
+	return pc[costType]
+}
+
+func TestCostEstimator_CatalogOverridesBuiltInPrices(t *testing.T) {
+	ce := NewCostEstimator(OpenAiPerThousandTokenCost, nil, fakePriceCatalog{
+		"prompt":              {"gpt-4o": 0.001, "gpt-5-preview": 0.002},
+		"image":               {ImageModel("dall-e-3", "hd", "1024x1024"): 0.1, ImageModel("gpt-image-1", "high", "1024x1024"): 0.2},
+		"cached_prompt_ratio": {"gpt-4o-mini": 0.25},
+	})
+
+	cost, err := ce.EstimatePromptCost("gpt-4o", 1000)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.001, cost, 1e-12)
+
+	cost, err = ce.EstimatePromptCost("gpt-5-preview", 1000)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.002, cost, 1e-12)
+
+	cost, err = ce.EstimatePromptCost("gpt-4-turbo", 1000)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.01, cost, 1e-12)
+
+	cost, err = ce.EstimateImageCost("dall-e-3", "hd", "1024x1024", 1)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.1, cost, 1e-12)
+
+	cost, err = ce.EstimateImageCost("gpt-image-1", "high", "1024x1024", 2)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.4, cost, 1e-12)
+
+	cost, err = ce.EstimateImageCost("dall-e-2", "", "512x512", 1)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.018, cost, 1e-12)
+
+	cost, err = ce.EstimatePromptCostWithCachedTokens("gpt-4o-mini", 2000, 2000)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.0003*0.25, cost, 1e-12)
+
+	cost, err = ce.EstimatePromptCostWithCachedTokens("gpt-4o", 2000, 2000)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.002*0.5, cost, 1e-12)
+}
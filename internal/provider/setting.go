@@ -11,6 +11,7 @@ type Setting struct {
 	Name          string            `json:"name"`
 	AllowedModels []string          `json:"allowedModels"`
 	CostMap       *CostMap          `json:"costMap"`
+	CacheConfig   *CacheConfig      `json:"cacheConfig,omitempty"`
 }
 
 type CostMap struct {
@@ -19,6 +20,11 @@ type CostMap struct {
 	EmbeddingsCostPerModel map[string]float64 `json:"embeddingsCostPerModel"`
 }
 
+type CacheConfig struct {
+	Enabled bool   `json:"enabled"`
+	Ttl     string `json:"ttl"`
+}
+
 func (s *Setting) GetParam(key string) string {
 	return s.Setting[key]
 }
@@ -29,6 +35,7 @@ type UpdateSetting struct {
 	Name          *string           `json:"name"`
 	AllowedModels *[]string         `json:"allowedModels,omitempty"`
 	CostMap       *CostMap          `json:"costMap,omitempty"`
+	CacheConfig   *CacheConfig      `json:"cacheConfig,omitempty"`
 }
 
 func EstimateCostWithCostMap(model string, tks int, div float64, costMap map[string]float64) (float64, error) {
@@ -57,3 +64,15 @@ func EstimateTotalCostWithCostMaps(model string, ptks, ctks int, div float64, pr
 
 	return ptksInFloat/div*pcost + ctksInFloat/div*ccost, nil
 }
+
+type SettingHealth struct {
+	ErrorRate   float64
+	LatencyInMs float64
+	// HasLatency is false until the setting has served a request successfully.
+	HasLatency   bool
+	EjectedUntil int64
+}
+
+func (sh *SettingHealth) IsEjected(now int64) bool {
+	return sh != nil && sh.EjectedUntil > now
+}
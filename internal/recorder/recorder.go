@@ -1,6 +1,7 @@
 package recorder
 
 import (
+	"github.com/bricks-cloud/bricksllm/internal/budget"
 	"github.com/bricks-cloud/bricksllm/internal/event"
 	"github.com/bricks-cloud/bricksllm/internal/key"
 )
@@ -10,10 +11,17 @@ type Recorder struct {
 	c  Cache
 	us Store
 	uc Cache
+	bs Store
+	bc Cache
+	b  BudgetsStorage
 	ce CostEstimator
 	es EventsStore
 }
 
+type BudgetsStorage interface {
+	GetBudgetsForKey(keyId string, tags []string) []*budget.Budget
+}
+
 type EventsStore interface {
 	InsertEvent(e *event.Event) error
 }
@@ -31,12 +39,15 @@ type CostEstimator interface {
 	EstimateCompletionCost(model string, tks int) (float64, error)
 }
 
-func NewRecorder(s, us Store, c, uc Cache, ce CostEstimator, es EventsStore) *Recorder {
+func NewRecorder(s, us, bs Store, c, uc, bc Cache, b BudgetsStorage, ce CostEstimator, es EventsStore) *Recorder {
 	return &Recorder{
 		s:  s,
 		c:  c,
 		us: us,
 		uc: uc,
+		bs: bs,
+		bc: bc,
+		b:  b,
 		ce: ce,
 		es: es,
 	}
@@ -74,6 +85,25 @@ func (r *Recorder) RecordKeySpend(keyId string, micros int64, costLimitUnit key.
 	return nil
 }
 
+// RecordBudgetSpend attributes spend of a key to every budget the key belongs to.
+func (r *Recorder) RecordBudgetSpend(k *key.ResponseKey, micros int64) error {
+	for _, b := range r.b.GetBudgetsForKey(k.KeyId, k.Tags) {
+		err := r.bs.IncrementCounter(b.Id, micros)
+		if err != nil {
+			return err
+		}
+
+		if len(b.CostLimitInUsdUnit) != 0 {
+			err = r.bc.IncrementCounter(b.Id, b.CostLimitInUsdUnit, micros)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 func (r *Recorder) RecordEvent(e *event.Event) error {
 	return r.es.InsertEvent(e)
 }
@@ -0,0 +1,65 @@
+package recorder
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bricks-cloud/bricksllm/internal/budget"
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeStore struct {
+	counters map[string]int64
+	err      error
+}
+
+func (s *fakeStore) IncrementCounter(keyId string, incr int64) error {
+	if s.err != nil {
+		return s.err
+	}
+
+	s.counters[keyId] += incr
+	return nil
+}
+
+type fakeCache struct {
+	counters map[string]int64
+}
+
+func (c *fakeCache) IncrementCounter(keyId string, rateLimitUnit key.TimeUnit, incr int64) error {
+	c.counters[keyId+":"+string(rateLimitUnit)] += incr
+	return nil
+}
+
+type fakeBudgetsStorage []*budget.Budget
+
+func (s fakeBudgetsStorage) GetBudgetsForKey(keyId string, tags []string) []*budget.Budget {
+	budgets := []*budget.Budget{}
+	for _, b := range s {
+		if b.AppliesTo(keyId, tags) {
+			budgets = append(budgets, b)
+		}
+	}
+
+	return budgets
+}
+
+func TestRecorder_RecordBudgetSpend(t *testing.T) {
+	bs := &fakeStore{counters: map[string]int64{}}
+	bc := &fakeCache{counters: map[string]int64{}}
+	budgets := fakeBudgetsStorage{
+		{Id: "org", CostLimitInUsd: 100},
+		{Id: "team", Tag: "team-a", CostLimitInUsdOverTime: 10, CostLimitInUsdUnit: key.DayTimeUnit},
+		{Id: "other", KeyIds: []string{"j"}, CostLimitInUsd: 1},
+	}
+
+	r := NewRecorder(nil, nil, bs, nil, nil, bc, budgets, nil, nil)
+
+	assert.NoError(t, r.RecordBudgetSpend(&key.ResponseKey{KeyId: "k", Tags: []string{"team-a"}}, 500))
+	assert.Equal(t, map[string]int64{"org": 500, "team": 500}, bs.counters)
+	assert.Equal(t, map[string]int64{"team:d": 500}, bc.counters)
+
+	bs.err = errors.New("storage is down")
+	assert.Error(t, r.RecordBudgetSpend(&key.ResponseKey{KeyId: "k"}, 500))
+}
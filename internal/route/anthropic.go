@@ -0,0 +1,347 @@
+package route
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/provider/anthropic"
+	goopenai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	anthropicApiVersion        = "2023-06-01"
+	bedrockAnthropicApiVersion = "bedrock-2023-05-31"
+	anthropicDefaultMaxTokens  = 4096
+)
+
+func getChatCompletionMessageContent(message goopenai.ChatCompletionMessage) string {
+	if len(message.MultiContent) == 0 {
+		return message.Content
+	}
+
+	texts := []string{}
+	for _, part := range message.MultiContent {
+		if part.Type == goopenai.ChatMessagePartTypeText {
+			texts = append(texts, part.Text)
+		}
+	}
+
+	return strings.Join(texts, "\n")
+}
+
+// getToolUseInput returns the arguments of a tool call as the input of a tool_use block.
+// Anthropic requires an object, so arguments that are not a JSON object become empty.
+func getToolUseInput(arguments string) json.RawMessage {
+	parsed := map[string]any{}
+	if err := json.Unmarshal([]byte(arguments), &parsed); err != nil {
+		return json.RawMessage("{}")
+	}
+
+	return json.RawMessage(arguments)
+}
+
+func convertChatCompletionMessageToContentBlocks(message goopenai.ChatCompletionMessage) []anthropic.ContentBlock {
+	content := getChatCompletionMessageContent(message)
+
+	if message.Role == goopenai.ChatMessageRoleTool {
+		return []anthropic.ContentBlock{{
+			Type:      "tool_result",
+			ToolUseId: message.ToolCallID,
+			Content:   content,
+		}}
+	}
+
+	blocks := []anthropic.ContentBlock{}
+	if len(strings.TrimSpace(content)) != 0 {
+		blocks = append(blocks, anthropic.ContentBlock{
+			Type: "text",
+			Text: content,
+		})
+	}
+
+	if message.Role != goopenai.ChatMessageRoleAssistant {
+		return blocks
+	}
+
+	for _, tc := range message.ToolCalls {
+		blocks = append(blocks, anthropic.ContentBlock{
+			Type:  "tool_use",
+			Id:    tc.ID,
+			Name:  tc.Function.Name,
+			Input: getToolUseInput(tc.Function.Arguments),
+		})
+	}
+
+	return blocks
+}
+
+func isTextOnly(blocks []anthropic.ContentBlock) bool {
+	for _, b := range blocks {
+		if b.Type != "text" {
+			return false
+		}
+	}
+
+	return true
+}
+
+func convertTools(tools []goopenai.Tool) []anthropic.Tool {
+	converted := []anthropic.Tool{}
+	for _, t := range tools {
+		if t.Type != goopenai.ToolTypeFunction || t.Function == nil {
+			continue
+		}
+
+		var schema any = map[string]any{"type": "object", "properties": map[string]any{}}
+		if t.Function.Parameters != nil {
+			schema = t.Function.Parameters
+		}
+
+		converted = append(converted, anthropic.Tool{
+			Name:        t.Function.Name,
+			Description: t.Function.Description,
+			InputSchema: schema,
+		})
+	}
+
+	return converted
+}
+
+// convertToolChoice converts none, auto, required and a named function. Any other
+// choice is left to the default of Anthropic.
+func convertToolChoice(choice any) *anthropic.ToolChoice {
+	switch value := choice.(type) {
+	case string:
+		switch value {
+		case "none", "auto":
+			return &anthropic.ToolChoice{Type: value}
+		case "required":
+			return &anthropic.ToolChoice{Type: "any"}
+		}
+	case goopenai.ToolChoice:
+		return &anthropic.ToolChoice{Type: "tool", Name: value.Function.Name}
+	case *goopenai.ToolChoice:
+		if value != nil {
+			return &anthropic.ToolChoice{Type: "tool", Name: value.Function.Name}
+		}
+	case map[string]any:
+		function, _ := value["function"].(map[string]any)
+		if name, _ := function["name"].(string); len(name) != 0 {
+			return &anthropic.ToolChoice{Type: "tool", Name: name}
+		}
+	}
+
+	return nil
+}
+
+// convertChatCompletionRequestToMessagesRequest converts a chat completion request into
+// a messages request. Tool calls of assistant messages become tool_use blocks and tool
+// messages become tool_result blocks of a user turn. Consecutive messages of the same
+// role are merged and messages without content are skipped since Anthropic rejects them.
+func convertChatCompletionRequestToMessagesRequest(req *goopenai.ChatCompletionRequest) *anthropic.MessagesRequest {
+	systems := []string{}
+	messages := []anthropic.Message{}
+
+	for _, message := range req.Messages {
+		if message.Role == goopenai.ChatMessageRoleSystem || message.Role == "developer" {
+			systems = append(systems, getChatCompletionMessageContent(message))
+			continue
+		}
+
+		role := goopenai.ChatMessageRoleUser
+		if message.Role == goopenai.ChatMessageRoleAssistant {
+			role = goopenai.ChatMessageRoleAssistant
+		}
+
+		blocks := convertChatCompletionMessageToContentBlocks(message)
+		if len(blocks) == 0 {
+			continue
+		}
+
+		if len(messages) != 0 && messages[len(messages)-1].Role == role {
+			messages[len(messages)-1].Blocks = append(messages[len(messages)-1].Blocks, blocks...)
+			continue
+		}
+
+		messages = append(messages, anthropic.Message{
+			Role:   role,
+			Blocks: blocks,
+		})
+	}
+
+	for index, message := range messages {
+		if !isTextOnly(message.Blocks) {
+			continue
+		}
+
+		texts := []string{}
+		for _, b := range message.Blocks {
+			texts = append(texts, b.Text)
+		}
+
+		messages[index].Content = strings.Join(texts, "\n\n")
+		messages[index].Blocks = nil
+	}
+
+	maxTokens := req.MaxTokens
+	if req.MaxCompletionTokens != 0 {
+		maxTokens = req.MaxCompletionTokens
+	}
+
+	if maxTokens == 0 {
+		maxTokens = anthropicDefaultMaxTokens
+	}
+
+	mr := &anthropic.MessagesRequest{
+		Model:         req.Model,
+		System:        strings.Join(systems, "\n"),
+		Messages:      messages,
+		MaxTokens:     maxTokens,
+		StopSequences: req.Stop,
+		Temperature:   req.Temperature,
+		TopP:          req.TopP,
+		Stream:        req.Stream,
+	}
+
+	if len(req.Tools) != 0 {
+		mr.Tools = convertTools(req.Tools)
+		mr.ToolChoice = convertToolChoice(req.ToolChoice)
+	}
+
+	if len(req.User) != 0 {
+		mr.Metadata = &anthropic.Metadata{
+			UserId: req.User,
+		}
+	}
+
+	return mr
+}
+
+func convertMessagesRequestToBedrockMessageRequest(mr *anthropic.MessagesRequest) *anthropic.BedrockMessageRequest {
+	return &anthropic.BedrockMessageRequest{
+		AnthropicVersion: bedrockAnthropicApiVersion,
+		System:           mr.System,
+		Messages:         mr.Messages,
+		MaxTokens:        mr.MaxTokens,
+		StopSequences:    mr.StopSequences,
+		Temperature:      mr.Temperature,
+		TopP:             mr.TopP,
+		Tools:            mr.Tools,
+		ToolChoice:       mr.ToolChoice,
+	}
+}
+
+func convertStopReasonToFinishReason(reason string) goopenai.FinishReason {
+	if reason == "max_tokens" {
+		return goopenai.FinishReasonLength
+	}
+
+	if reason == "tool_use" {
+		return goopenai.FinishReasonToolCalls
+	}
+
+	if len(reason) == 0 {
+		return goopenai.FinishReasonNull
+	}
+
+	return goopenai.FinishReasonStop
+}
+
+func convertMessagesResponseToChatCompletionResponse(res *anthropic.MessagesResponse, model string) *goopenai.ChatCompletionResponse {
+	texts := []string{}
+	toolCalls := []goopenai.ToolCall{}
+	for _, content := range res.Content {
+		if content.Type == "text" {
+			texts = append(texts, content.Text)
+		}
+
+		if content.Type == "tool_use" {
+			toolCalls = append(toolCalls, goopenai.ToolCall{
+				ID:   content.Id,
+				Type: goopenai.ToolTypeFunction,
+				Function: goopenai.FunctionCall{
+					Name:      content.Name,
+					Arguments: string(content.Input),
+				},
+			})
+		}
+	}
+
+	message := goopenai.ChatCompletionMessage{
+		Role:    goopenai.ChatMessageRoleAssistant,
+		Content: strings.Join(texts, ""),
+	}
+
+	if len(toolCalls) != 0 {
+		message.ToolCalls = toolCalls
+	}
+
+	if len(res.Model) != 0 {
+		model = res.Model
+	}
+
+	return &goopenai.ChatCompletionResponse{
+		ID:      res.Id,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   model,
+		Choices: []goopenai.ChatCompletionChoice{
+			{
+				Index:        0,
+				Message:      message,
+				FinishReason: convertStopReasonToFinishReason(res.StopReason),
+			},
+		},
+		Usage: goopenai.Usage{
+			PromptTokens:     res.Usage.InputTokens,
+			CompletionTokens: res.Usage.OutputTokens,
+			TotalTokens:      res.Usage.InputTokens + res.Usage.OutputTokens,
+		},
+	}
+}
+
+func translateMessagesResponse(data []byte, model string) ([]byte, error) {
+	mr := &anthropic.MessagesResponse{}
+	err := json.Unmarshal(data, mr)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(convertMessagesResponseToChatCompletionResponse(mr, model))
+}
+
+type bedrockErrorResponse struct {
+	Message string `json:"message"`
+}
+
+func translateMessagesErrorResponse(data []byte) []byte {
+	apiErr := &goopenai.APIError{}
+
+	er := &anthropic.ErrorResponse{}
+	err := json.Unmarshal(data, er)
+	if err == nil && er.Error != nil {
+		apiErr.Type = er.Error.Type
+		apiErr.Message = er.Error.Message
+	}
+
+	if len(apiErr.Message) == 0 {
+		ber := &bedrockErrorResponse{}
+		err := json.Unmarshal(data, ber)
+		if err != nil || len(ber.Message) == 0 {
+			return data
+		}
+
+		apiErr.Type = "bedrock_error"
+		apiErr.Message = ber.Message
+	}
+
+	bs, err := json.Marshal(&goopenai.ErrorResponse{
+		Error: apiErr,
+	})
+	if err != nil {
+		return data
+	}
+
+	return bs
+}
@@ -0,0 +1,200 @@
+package route
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/bricks-cloud/bricksllm/internal/provider/anthropic"
+	goopenai "github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertChatCompletionRequestToMessagesRequest(t *testing.T) {
+	toolCall := goopenai.ToolCall{
+		ID:   "call_1",
+		Type: goopenai.ToolTypeFunction,
+		Function: goopenai.FunctionCall{
+			Name:      "get_weather",
+			Arguments: `{"city":"Paris"}`,
+		},
+	}
+
+	cases := []struct {
+		name     string
+		messages []goopenai.ChatCompletionMessage
+		system   string
+		expected string
+	}{
+		{
+			name: "text messages",
+			messages: []goopenai.ChatCompletionMessage{
+				{Role: "system", Content: "be brief"},
+				{Role: "user", Content: "hi"},
+				{Role: "user", Content: "there"},
+				{Role: "assistant", Content: "hello"},
+			},
+			system:   "be brief",
+			expected: `[{"role":"user","content":"hi\n\nthere"},{"role":"assistant","content":"hello"}]`,
+		},
+		{
+			name: "tool call and tool results",
+			messages: []goopenai.ChatCompletionMessage{
+				{Role: "user", Content: "weather in Paris and Rome?"},
+				{Role: "assistant", ToolCalls: []goopenai.ToolCall{toolCall, {ID: "call_2", Type: goopenai.ToolTypeFunction, Function: goopenai.FunctionCall{Name: "get_weather", Arguments: `{"city":"Rome"}`}}}},
+				{Role: "tool", ToolCallID: "call_1", Content: "sunny"},
+				{Role: "tool", ToolCallID: "call_2", Content: "rainy"},
+				{Role: "assistant", Content: "Paris is sunny and Rome is rainy."},
+			},
+			expected: `[
+				{"role":"user","content":"weather in Paris and Rome?"},
+				{"role":"assistant","content":[
+					{"type":"tool_use","id":"call_1","name":"get_weather","input":{"city":"Paris"}},
+					{"type":"tool_use","id":"call_2","name":"get_weather","input":{"city":"Rome"}}
+				]},
+				{"role":"user","content":[
+					{"type":"tool_result","tool_use_id":"call_1","content":"sunny"},
+					{"type":"tool_result","tool_use_id":"call_2","content":"rainy"}
+				]},
+				{"role":"assistant","content":"Paris is sunny and Rome is rainy."}
+			]`,
+		},
+		{
+			name: "assistant text with a tool call and a following user message",
+			messages: []goopenai.ChatCompletionMessage{
+				{Role: "user", Content: "weather?"},
+				{Role: "assistant", Content: "let me check", ToolCalls: []goopenai.ToolCall{toolCall}},
+				{Role: "tool", ToolCallID: "call_1", Content: "sunny"},
+				{Role: "user", Content: "thanks"},
+			},
+			expected: `[
+				{"role":"user","content":"weather?"},
+				{"role":"assistant","content":[
+					{"type":"text","text":"let me check"},
+					{"type":"tool_use","id":"call_1","name":"get_weather","input":{"city":"Paris"}}
+				]},
+				{"role":"user","content":[
+					{"type":"tool_result","tool_use_id":"call_1","content":"sunny"},
+					{"type":"text","text":"thanks"}
+				]}
+			]`,
+		},
+		{
+			name: "empty assistant turns",
+			messages: []goopenai.ChatCompletionMessage{
+				{Role: "user", Content: "hi"},
+				{Role: "assistant", Content: ""},
+				{Role: "user", Content: "anyone?"},
+				{Role: "assistant", Content: "  "},
+			},
+			expected: `[{"role":"user","content":"hi\n\nanyone?"}]`,
+		},
+		{
+			name: "tool call with arguments that are not an object",
+			messages: []goopenai.ChatCompletionMessage{
+				{Role: "user", Content: "now"},
+				{Role: "assistant", ToolCalls: []goopenai.ToolCall{{ID: "call_1", Type: goopenai.ToolTypeFunction, Function: goopenai.FunctionCall{Name: "get_time"}}}},
+				{Role: "tool", ToolCallID: "call_1", Content: "noon"},
+			},
+			expected: `[
+				{"role":"user","content":"now"},
+				{"role":"assistant","content":[{"type":"tool_use","id":"call_1","name":"get_time","input":{}}]},
+				{"role":"user","content":[{"type":"tool_result","tool_use_id":"call_1","content":"noon"}]}
+			]`,
+		},
+	}
+
+	for _, tc := range cases {
+		mr := convertChatCompletionRequestToMessagesRequest(&goopenai.ChatCompletionRequest{
+			Model:    "claude-3-haiku-20240307",
+			Messages: tc.messages,
+		})
+
+		assert.Equal(t, tc.system, mr.System, tc.name)
+
+		data, err := json.Marshal(mr.Messages)
+		require.NoError(t, err, tc.name)
+		assert.JSONEq(t, tc.expected, string(data), tc.name)
+	}
+}
+
+func TestConvertChatCompletionRequestToMessagesRequest_Tools(t *testing.T) {
+	tools := []goopenai.Tool{{
+		Type: goopenai.ToolTypeFunction,
+		Function: &goopenai.FunctionDefinition{
+			Name:        "get_weather",
+			Description: "weather of a city",
+			Parameters:  map[string]any{"type": "object", "properties": map[string]any{"city": map[string]any{"type": "string"}}},
+		},
+	}}
+
+	cases := []struct {
+		choice   any
+		expected *anthropic.ToolChoice
+	}{
+		{nil, nil},
+		{"auto", &anthropic.ToolChoice{Type: "auto"}},
+		{"none", &anthropic.ToolChoice{Type: "none"}},
+		{"required", &anthropic.ToolChoice{Type: "any"}},
+		{map[string]any{"type": "function", "function": map[string]any{"name": "get_weather"}}, &anthropic.ToolChoice{Type: "tool", Name: "get_weather"}},
+	}
+
+	for _, tc := range cases {
+		mr := convertChatCompletionRequestToMessagesRequest(&goopenai.ChatCompletionRequest{
+			Messages:   []goopenai.ChatCompletionMessage{{Role: "user", Content: "weather?"}},
+			Tools:      tools,
+			ToolChoice: tc.choice,
+		})
+
+		require.Len(t, mr.Tools, 1)
+		assert.Equal(t, "get_weather", mr.Tools[0].Name)
+		assert.Equal(t, tools[0].Function.Parameters, mr.Tools[0].InputSchema)
+		assert.Equal(t, tc.expected, mr.ToolChoice, tc.choice)
+	}
+
+	br := convertMessagesRequestToBedrockMessageRequest(convertChatCompletionRequestToMessagesRequest(&goopenai.ChatCompletionRequest{
+		Messages: []goopenai.ChatCompletionMessage{{Role: "user", Content: "weather?"}},
+		Tools:    tools,
+	}))
+	assert.Len(t, br.Tools, 1)
+}
+
+func TestConvertMessagesResponseToChatCompletionResponse_ToolUse(t *testing.T) {
+	res := convertMessagesResponseToChatCompletionResponse(&anthropic.MessagesResponse{
+		Id: "msg_1",
+		Content: []anthropic.MessageResponseContent{
+			{Type: "text", Text: "checking"},
+			{Type: "tool_use", Id: "toolu_1", Name: "get_weather", Input: json.RawMessage(`{"city":"Paris"}`)},
+		},
+		StopReason: "tool_use",
+	}, "claude-3-haiku-20240307")
+
+	require.Len(t, res.Choices, 1)
+	assert.Equal(t, "checking", res.Choices[0].Message.Content)
+	assert.Equal(t, goopenai.FinishReasonToolCalls, res.Choices[0].FinishReason)
+	require.Len(t, res.Choices[0].Message.ToolCalls, 1)
+	assert.Equal(t, "toolu_1", res.Choices[0].Message.ToolCalls[0].ID)
+	assert.Equal(t, "get_weather", res.Choices[0].Message.ToolCalls[0].Function.Name)
+	assert.JSONEq(t, `{"city":"Paris"}`, res.Choices[0].Message.ToolCalls[0].Function.Arguments)
+}
+
+func TestTranslateMessagesErrorResponse(t *testing.T) {
+	cases := []struct {
+		data     string
+		expected string
+	}{
+		{`{"type":"error","error":{"type":"invalid_request_error","message":"bad"}}`, `{"error":{"type":"invalid_request_error","message":"bad"}}`},
+		{`{"message":"throttled"}`, `{"error":{"type":"bedrock_error","message":"throttled"}}`},
+		{`not json`, `not json`},
+	}
+
+	for _, tc := range cases {
+		translated := translateMessagesErrorResponse([]byte(tc.data))
+		if !json.Valid([]byte(tc.expected)) {
+			assert.Equal(t, tc.expected, string(translated))
+			continue
+		}
+
+		assert.JSONEq(t, tc.expected, string(translated), tc.data)
+	}
+}
@@ -1,16 +1,22 @@
 package route
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	"github.com/cenkalti/backoff/v4"
 	goopenai "github.com/sashabaranov/go-openai"
 	"go.uber.org/zap"
@@ -26,8 +32,15 @@ type recorder interface {
 }
 
 type CacheConfig struct {
-	Enabled bool   `json:"enabled"`
-	Ttl     string `json:"ttl"`
+	Enabled             bool    `json:"enabled"`
+	Ttl                 string  `json:"ttl"`
+	Mode                string  `json:"mode"`
+	SimilarityThreshold float64 `json:"similarityThreshold"`
+	EmbeddingModel      string  `json:"embeddingModel"`
+}
+
+func (cc *CacheConfig) IsSemantic() bool {
+	return cc != nil && cc.Enabled && cc.Mode == "semantic"
 }
 
 type Step struct {
@@ -100,6 +113,14 @@ func (s *Step) DecorateRequest(provider string, body []byte, isEmbedding bool) (
 
 		s.DecorateChatCompletionRequest(completionReq)
 
+		if provider == "anthropic" {
+			return json.Marshal(convertChatCompletionRequestToMessagesRequest(completionReq))
+		}
+
+		if provider == "bedrock" {
+			return json.Marshal(convertMessagesRequestToBedrockMessageRequest(convertChatCompletionRequestToMessagesRequest(completionReq)))
+		}
+
 		return json.Marshal(completionReq)
 	}
 
@@ -178,6 +199,15 @@ func (s *Step) DecorateChatCompletionRequest(req *goopenai.ChatCompletionRequest
 			req.TopLogProbs = int(parsed)
 		}
 	}
+	if val, ok := s.RequestParams["stream_options"]; ok && req.Stream {
+		if parsed, ok := val.(map[string]any); ok {
+			if includeUsage, ok := parsed["include_usage"].(bool); ok {
+				req.StreamOptions = &goopenai.StreamOptions{
+					IncludeUsage: includeUsage,
+				}
+			}
+		}
+	}
 }
 
 type Route struct {
@@ -320,7 +350,7 @@ func (r *Route) RunStepsV2(req *Request, rec recorder, log *zap.Logger, kc *key.
 				}
 			}()
 
-			hreq, err := req.createHttpRequest(ctx, step.Provider, r.ShouldRunEmbeddings(), step.Params, bs)
+			hreq, err := req.createHttpRequest(ctx, step.Provider, step.Model, r.ShouldRunEmbeddings(), step.Params, bs)
 			if err != nil {
 				return err
 			}
@@ -332,8 +362,14 @@ func (r *Route) RunStepsV2(req *Request, rec recorder, log *zap.Logger, kc *key.
 
 			response.Provider = step.Provider
 			response.Model = step.Model
+			response.SettingId = ""
+			if setting := req.GetSetting(step.Provider); setting != nil {
+				response.SettingId = setting.Id
+			}
+
 			response.Response = res
 			response.Cancel = cancel
+			response.Usage = nil
 			evt.Status = res.StatusCode
 
 			if res.StatusCode != http.StatusOK {
@@ -344,10 +380,65 @@ func (r *Route) RunStepsV2(req *Request, rec recorder, log *zap.Logger, kc *key.
 					return err
 				}
 
+				if step.Provider == "anthropic" || step.Provider == "bedrock" {
+					bytes = translateMessagesErrorResponse(bytes)
+				}
+
 				response.Data = bytes
 				return errors.New("response is not okay")
 			}
 
+			if req.Stream {
+				usage := &goopenai.Usage{}
+
+				if step.Provider == "anthropic" {
+					res.Body = newAnthropicStreamReader(res.Body, step.Model, usage)
+					response.Usage = usage
+				}
+
+				if step.Provider == "bedrock" {
+					res.Body = newBedrockStreamReader(res.Body, step.Model, usage)
+					response.Usage = usage
+				}
+
+				if step.Provider == "anthropic" || step.Provider == "bedrock" {
+					res.ContentLength = -1
+					res.Header.Del("Content-Length")
+					res.Header.Set("Content-Type", "text/event-stream")
+				}
+
+				buffer := bufio.NewReader(res.Body)
+				if _, err := buffer.Peek(1); err != nil {
+					res.Body.Close()
+					response.Response = nil
+					response.Usage = nil
+					return err
+				}
+
+				res.Body = &streamBody{
+					Reader: buffer,
+					Closer: res.Body,
+				}
+			}
+
+			if !req.Stream && (step.Provider == "anthropic" || step.Provider == "bedrock") {
+				defer res.Body.Close()
+
+				data, err := io.ReadAll(res.Body)
+				if err != nil {
+					return err
+				}
+
+				translated, err := translateMessagesResponse(data, step.Model)
+				if err != nil {
+					return err
+				}
+
+				res.Body = io.NopCloser(bytes.NewReader(translated))
+				res.ContentLength = int64(len(translated))
+				res.Header.Del("Content-Length")
+			}
+
 			if kc.ShouldLogResponse {
 				evt.Response = body
 			}
@@ -623,32 +714,72 @@ type Request struct {
 	PolicyId      string
 	Action        string
 	CorrelationId string
+	Stream        bool
 }
 
-func (r *Request) GetSettingValue(provider string, param string) (string, error) {
+// GetSetting returns the setting used to send requests to a provider. The setting with
+// the smallest id is picked so that every lookup for a provider resolves to the same setting.
+func (r *Request) GetSetting(p string) *provider.Setting {
+	var selected *provider.Setting
 	for _, setting := range r.Settings {
-		if setting.Provider == provider {
-			val, ok := setting.Setting[param]
-			if ok {
-				return val, nil
-			}
-
-			return "", errors.New(fmt.Sprintf("%s setting param: %s not found", provider, param))
+		if setting.Provider == p && (selected == nil || setting.Id < selected.Id) {
+			selected = setting
 		}
 	}
 
-	return "", errors.New(fmt.Sprintf("%s setting is not found", provider))
+	return selected
+}
+
+func (r *Request) GetSettingValue(provider string, param string) (string, error) {
+	setting := r.GetSetting(provider)
+	if setting == nil {
+		return "", errors.New(fmt.Sprintf("%s setting is not found", provider))
+	}
+
+	val, ok := setting.Setting[param]
+	if !ok {
+		return "", errors.New(fmt.Sprintf("%s setting param: %s not found", provider, param))
+	}
+
+	return val, nil
 }
 
 type Response struct {
 	Provider string
 	Model    string
-	Data     []byte
-	Cancel   context.CancelFunc
-	Response *http.Response
+	// SettingId is the id of the provider setting used by the step that produced the response.
+	SettingId string
+	Data      []byte
+	Cancel    context.CancelFunc
+	Response  *http.Response
+	Usage     *goopenai.Usage
 }
 
 func buildRequestUrl(provider string, runEmbeddings bool, resourceName string, params map[string]string) string {
+	if provider == "deepinfra" && runEmbeddings {
+		return "https://api.deepinfra.com/v1/openai/embeddings"
+	}
+
+	if provider == "deepinfra" && !runEmbeddings {
+		return "https://api.deepinfra.com/v1/openai/chat/completions"
+	}
+
+	if provider == "vllm" && runEmbeddings {
+		return strings.TrimSuffix(resourceName, "/") + "/v1/embeddings"
+	}
+
+	if provider == "vllm" && !runEmbeddings {
+		return strings.TrimSuffix(resourceName, "/") + "/v1/chat/completions"
+	}
+
+	if provider == "gemini" && runEmbeddings {
+		return "https://generativelanguage.googleapis.com/v1beta/openai/embeddings"
+	}
+
+	if provider == "gemini" && !runEmbeddings {
+		return "https://generativelanguage.googleapis.com/v1beta/openai/chat/completions"
+	}
+
 	if provider == "openai" && runEmbeddings {
 		return "https://api.openai.com/v1/embeddings"
 	}
@@ -668,19 +799,84 @@ func buildRequestUrl(provider string, runEmbeddings bool, resourceName string, p
 		return fmt.Sprintf("https://%s.openai.azure.com/openai/deployments/%s/chat/completions?api-version=%s", resourceName, deploymentId, apiVersion)
 	}
 
+	if provider == "anthropic" && !runEmbeddings {
+		return "https://api.anthropic.com/v1/messages"
+	}
+
 	return ""
 }
 
+func buildBedrockRequestUrl(region, model string, stream bool) string {
+	action := "invoke"
+	if stream {
+		action = "invoke-with-response-stream"
+	}
+
+	return fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com/model/%s/%s", region, strings.ReplaceAll(url.PathEscape(model), ":", "%3A"), action)
+}
+
 func setHttpRequestAuthHeader(provider string, req *http.Request, key string) {
 	if provider == "azure" {
 		req.Header.Set("api-key", key)
 		return
 	}
 
+	if provider == "anthropic" {
+		req.Header.Set("x-api-key", key)
+		return
+	}
+
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", key))
 }
 
-func (r *Request) createHttpRequest(ctx context.Context, provider string, runEmbeddings bool, params map[string]string, data []byte) (*http.Request, error) {
+func (r *Request) createBedrockHttpRequest(ctx context.Context, model string, data []byte) (*http.Request, error) {
+	keyId, err := r.GetSettingValue("bedrock", "awsAccessKeyId")
+	if err != nil {
+		return nil, err
+	}
+
+	secretKey, err := r.GetSettingValue("bedrock", "awsSecretAccessKey")
+	if err != nil {
+		return nil, err
+	}
+
+	region, err := r.GetSettingValue("bedrock", "awsRegion")
+	if err != nil {
+		return nil, err
+	}
+
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, buildBedrockRequestUrl(region, model, r.Stream), io.NopCloser(bytes.NewReader(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	hreq.ContentLength = int64(len(data))
+	hreq.Header.Set("Content-Type", "application/json")
+	hreq.Header.Set("Accept", "application/json")
+	if r.Stream {
+		hreq.Header.Set("Accept", "application/vnd.amazon.eventstream")
+	}
+
+	hash := sha256.Sum256(data)
+	creds := aws.Credentials{
+		AccessKeyID:     keyId,
+		SecretAccessKey: secretKey,
+		Source:          "BricksLLM Credentials",
+	}
+
+	err = v4.NewSigner().SignHTTP(ctx, creds, hreq, hex.EncodeToString(hash[:]), "bedrock", region, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return hreq, nil
+}
+
+func (r *Request) createHttpRequest(ctx context.Context, provider, model string, runEmbeddings bool, params map[string]string, data []byte) (*http.Request, error) {
+	if provider == "bedrock" {
+		return r.createBedrockHttpRequest(ctx, model, data)
+	}
+
 	resourceName := ""
 	if provider == "azure" {
 		val, err := r.GetSettingValue("azure", "resourceName")
@@ -691,8 +887,17 @@ func (r *Request) createHttpRequest(ctx context.Context, provider string, runEmb
 		resourceName = val
 	}
 
+	if provider == "vllm" {
+		val, err := r.GetSettingValue("vllm", "url")
+		if err != nil {
+			return nil, err
+		}
+
+		resourceName = val
+	}
+
 	key, err := r.GetSettingValue(provider, "apikey")
-	if err != nil {
+	if err != nil && provider != "vllm" {
 		return nil, err
 	}
 
@@ -702,11 +907,21 @@ func (r *Request) createHttpRequest(ctx context.Context, provider string, runEmb
 	}
 
 	hreq, err := http.NewRequestWithContext(ctx, r.Forwarded.Method, url, io.NopCloser(bytes.NewReader(data)))
+	if err != nil {
+		return nil, err
+	}
 
-	if provider == "azure" {
-		hreq.Header.Set("api-key", key)
-	} else {
-		hreq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", key))
+	if len(key) != 0 {
+		setHttpRequestAuthHeader(provider, hreq, key)
+	}
+
+	if provider == "anthropic" {
+		version := params["anthropicVersion"]
+		if len(version) == 0 {
+			version = anthropicApiVersion
+		}
+
+		hreq.Header.Set("anthropic-version", version)
 	}
 
 	for k := range r.Forwarded.Header {
@@ -718,6 +933,10 @@ func (r *Request) createHttpRequest(ctx context.Context, provider string, runEmb
 			continue
 		}
 
+		if strings.ToLower(k) == "x-api-key" {
+			continue
+		}
+
 		if strings.ToLower(k) == "accept-encoding" {
 			continue
 		}
@@ -725,5 +944,57 @@ func (r *Request) createHttpRequest(ctx context.Context, provider string, runEmb
 		hreq.Header.Set(k, r.Forwarded.Header.Get(k))
 	}
 
-	return hreq, err
+	return hreq, nil
+}
+
+// CreateEmbedding returns the embedding of the input along with the number of tokens
+// billed for it.
+func (r *Request) CreateEmbedding(ctx context.Context, model, input string) ([]float32, int, error) {
+	key, err := r.GetSettingValue("openai", "apikey")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	data, err := json.Marshal(&goopenai.EmbeddingRequest{
+		Input: input,
+		Model: goopenai.EmbeddingModel(model),
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, buildRequestUrl("openai", true, "", nil), io.NopCloser(bytes.NewReader(data)))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	hreq.Header.Set("Content-Type", "application/json")
+	setHttpRequestAuthHeader("openai", hreq, key)
+
+	res, err := r.Client.Do(hreq)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer res.Body.Close()
+
+	bs, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("embedding request failed with status code: %d", res.StatusCode)
+	}
+
+	er := &goopenai.EmbeddingResponse{}
+	err = json.Unmarshal(bs, er)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(er.Data) == 0 {
+		return nil, 0, errors.New("embedding response is empty")
+	}
+
+	return er.Data[0].Embedding, er.Usage.PromptTokens, nil
 }
@@ -0,0 +1,261 @@
+package route
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream"
+	"github.com/bricks-cloud/bricksllm/internal/provider/anthropic"
+	goopenai "github.com/sashabaranov/go-openai"
+)
+
+var (
+	headerData = []byte("data:")
+	streamDone = []byte("data: [DONE]\n\n")
+)
+
+type messagesStreamEvent struct {
+	Type    string                      `json:"type"`
+	Message *anthropic.MessagesResponse `json:"message,omitempty"`
+	Delta   *struct {
+		Type       string `json:"type"`
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta,omitempty"`
+	Usage *struct {
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage,omitempty"`
+	Error   *anthropic.Error          `json:"error,omitempty"`
+	Metrics *anthropic.BedrockMetrics `json:"amazon-bedrock-invocationMetrics,omitempty"`
+}
+
+type bedrockStreamChunk struct {
+	Bytes string `json:"bytes"`
+}
+
+type streamBody struct {
+	io.Reader
+	io.Closer
+}
+
+// messagesStreamReader translates Anthropic messages stream events into
+// OpenAI chat completion chunks so that route clients receive one stream shape.
+type messagesStreamReader struct {
+	next    func() ([]byte, error)
+	closer  io.Closer
+	buf     bytes.Buffer
+	done    bool
+	id      string
+	model   string
+	created int64
+	usage   *goopenai.Usage
+}
+
+func newAnthropicStreamReader(body io.ReadCloser, model string, usage *goopenai.Usage) io.ReadCloser {
+	reader := bufio.NewReader(body)
+
+	return &messagesStreamReader{
+		next: func() ([]byte, error) {
+			for {
+				raw, err := reader.ReadBytes('\n')
+				if err != nil && len(bytes.TrimSpace(raw)) == 0 {
+					return nil, err
+				}
+
+				line := bytes.TrimSpace(raw)
+				if bytes.HasPrefix(line, headerData) {
+					return bytes.TrimSpace(bytes.TrimPrefix(line, headerData)), nil
+				}
+
+				if err != nil {
+					return nil, err
+				}
+			}
+		},
+		closer:  body,
+		model:   model,
+		created: time.Now().Unix(),
+		usage:   usage,
+	}
+}
+
+func newBedrockStreamReader(body io.ReadCloser, model string, usage *goopenai.Usage) io.ReadCloser {
+	decoder := eventstream.NewDecoder()
+	payloadBuf := make([]byte, 10*1024)
+
+	return &messagesStreamReader{
+		next: func() ([]byte, error) {
+			msg, err := decoder.Decode(body, payloadBuf)
+			if err != nil {
+				return nil, err
+			}
+
+			if msg.Headers.Get(":message-type").String() == "exception" {
+				ber := &bedrockErrorResponse{}
+				if err := json.Unmarshal(msg.Payload, ber); err != nil {
+					ber.Message = string(msg.Payload)
+				}
+
+				return json.Marshal(&messagesStreamEvent{
+					Type: "error",
+					Error: &anthropic.Error{
+						Type:    msg.Headers.Get(":exception-type").String(),
+						Message: ber.Message,
+					},
+				})
+			}
+
+			chunk := &bedrockStreamChunk{}
+			err = json.Unmarshal(msg.Payload, chunk)
+			if err != nil {
+				return nil, err
+			}
+
+			return base64.StdEncoding.DecodeString(chunk.Bytes)
+		},
+		closer:  body,
+		model:   model,
+		created: time.Now().Unix(),
+		usage:   usage,
+	}
+}
+
+func (r *messagesStreamReader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+
+		data, err := r.next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				r.done = true
+				continue
+			}
+
+			return 0, err
+		}
+
+		err = r.translate(data)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return r.buf.Read(p)
+}
+
+func (r *messagesStreamReader) Close() error {
+	return r.closer.Close()
+}
+
+func (r *messagesStreamReader) writeChunk(delta goopenai.ChatCompletionStreamChoiceDelta, reason goopenai.FinishReason) error {
+	bs, err := json.Marshal(&goopenai.ChatCompletionStreamResponse{
+		ID:      r.id,
+		Object:  "chat.completion.chunk",
+		Created: r.created,
+		Model:   r.model,
+		Choices: []goopenai.ChatCompletionStreamChoice{
+			{
+				Index:        0,
+				Delta:        delta,
+				FinishReason: reason,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	r.buf.WriteString(fmt.Sprintf("data: %s\n\n", bs))
+	return nil
+}
+
+func (r *messagesStreamReader) translate(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	evt := &messagesStreamEvent{}
+	err := json.Unmarshal(data, evt)
+	if err != nil {
+		return err
+	}
+
+	if evt.Metrics != nil && r.usage != nil {
+		r.usage.PromptTokens = evt.Metrics.InputTokenCount
+		r.usage.CompletionTokens = evt.Metrics.OutputTokenCount
+		r.usage.TotalTokens = evt.Metrics.InputTokenCount + evt.Metrics.OutputTokenCount
+	}
+
+	switch evt.Type {
+	case "message_start":
+		if evt.Message != nil {
+			r.id = evt.Message.Id
+			if len(evt.Message.Model) != 0 {
+				r.model = evt.Message.Model
+			}
+
+			if r.usage != nil {
+				r.usage.PromptTokens = evt.Message.Usage.InputTokens
+				r.usage.TotalTokens = r.usage.PromptTokens + r.usage.CompletionTokens
+			}
+		}
+
+		return r.writeChunk(goopenai.ChatCompletionStreamChoiceDelta{
+			Role: goopenai.ChatMessageRoleAssistant,
+		}, "")
+
+	case "content_block_delta":
+		if evt.Delta == nil || len(evt.Delta.Text) == 0 {
+			return nil
+		}
+
+		return r.writeChunk(goopenai.ChatCompletionStreamChoiceDelta{
+			Content: evt.Delta.Text,
+		}, "")
+
+	case "message_delta":
+		if evt.Usage != nil && r.usage != nil {
+			r.usage.CompletionTokens = evt.Usage.OutputTokens
+			r.usage.TotalTokens = r.usage.PromptTokens + r.usage.CompletionTokens
+		}
+
+		reason := goopenai.FinishReasonStop
+		if evt.Delta != nil {
+			reason = convertStopReasonToFinishReason(evt.Delta.StopReason)
+		}
+
+		return r.writeChunk(goopenai.ChatCompletionStreamChoiceDelta{}, reason)
+
+	case "message_stop":
+		r.buf.Write(streamDone)
+		r.done = true
+
+	case "error":
+		apiErr := &goopenai.APIError{}
+		if evt.Error != nil {
+			apiErr.Type = evt.Error.Type
+			apiErr.Message = evt.Error.Message
+		}
+
+		bs, err := json.Marshal(&goopenai.ErrorResponse{
+			Error: apiErr,
+		})
+		if err != nil {
+			return err
+		}
+
+		r.buf.WriteString(fmt.Sprintf("data: %s\n\n", bs))
+		r.buf.Write(streamDone)
+		r.done = true
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package route
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream"
+	goopenai "github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const anthropicStream = `event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"model":"claude-3-haiku-20240307","usage":{"input_tokens":12,"output_tokens":1}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: ping
+data: {"type": "ping"}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" world"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"max_tokens","stop_sequence":null},"usage":{"output_tokens":7}}
+
+event: message_stop
+data: {"type":"message_stop"}
+`
+
+func readChunks(t *testing.T, r io.Reader) ([]*goopenai.ChatCompletionStreamResponse, bool) {
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+
+	chunks := []*goopenai.ChatCompletionStreamResponse{}
+	done := false
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+
+		payload := strings.TrimPrefix(line, "data: ")
+		if payload == "[DONE]" {
+			done = true
+			continue
+		}
+
+		chunk := &goopenai.ChatCompletionStreamResponse{}
+		require.NoError(t, json.Unmarshal([]byte(payload), chunk), payload)
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks, done
+}
+
+func TestAnthropicStreamReader(t *testing.T) {
+	usage := &goopenai.Usage{}
+	r := newAnthropicStreamReader(io.NopCloser(strings.NewReader(anthropicStream)), "claude-3-haiku", usage)
+
+	chunks, done := readChunks(t, r)
+	assert.True(t, done)
+	require.Len(t, chunks, 4)
+
+	assert.Equal(t, goopenai.ChatMessageRoleAssistant, chunks[0].Choices[0].Delta.Role)
+	assert.Equal(t, "Hello", chunks[1].Choices[0].Delta.Content)
+	assert.Equal(t, " world", chunks[2].Choices[0].Delta.Content)
+	assert.Equal(t, goopenai.FinishReasonLength, chunks[3].Choices[0].FinishReason)
+
+	for _, c := range chunks {
+		assert.Equal(t, "msg_1", c.ID)
+		assert.Equal(t, "claude-3-haiku-20240307", c.Model)
+		assert.Equal(t, "chat.completion.chunk", c.Object)
+	}
+
+	assert.Equal(t, goopenai.Usage{PromptTokens: 12, CompletionTokens: 7, TotalTokens: 19}, *usage)
+}
+
+func TestAnthropicStreamReader_Error(t *testing.T) {
+	stream := "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n"
+	r := newAnthropicStreamReader(io.NopCloser(strings.NewReader(stream)), "claude-3-haiku", nil)
+
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"type":"overloaded_error"`)
+	assert.Contains(t, string(data), `"message":"Overloaded"`)
+	assert.True(t, strings.HasSuffix(string(data), "data: [DONE]\n\n"))
+}
+
+func encodeBedrockChunk(t *testing.T, buf *bytes.Buffer, event string) {
+	payload, err := json.Marshal(&bedrockStreamChunk{Bytes: base64.StdEncoding.EncodeToString([]byte(event))})
+	require.NoError(t, err)
+
+	msg := eventstream.Message{Payload: payload}
+	msg.Headers.Set(":message-type", eventstream.StringValue("event"))
+	require.NoError(t, eventstream.NewEncoder().Encode(buf, msg))
+}
+
+func TestBedrockStreamReader(t *testing.T) {
+	buf := &bytes.Buffer{}
+	encodeBedrockChunk(t, buf, `{"type":"message_start","message":{"id":"msg_1","model":"claude-3-haiku","usage":{"input_tokens":3}}}`)
+	encodeBedrockChunk(t, buf, `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi"}}`)
+	encodeBedrockChunk(t, buf, `{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":2}}`)
+	encodeBedrockChunk(t, buf, `{"type":"message_stop","amazon-bedrock-invocationMetrics":{"inputTokenCount":3,"outputTokenCount":2}}`)
+
+	usage := &goopenai.Usage{}
+	r := newBedrockStreamReader(io.NopCloser(buf), "anthropic.claude-3-haiku", usage)
+
+	chunks, done := readChunks(t, r)
+	assert.True(t, done)
+	require.Len(t, chunks, 3)
+	assert.Equal(t, "Hi", chunks[1].Choices[0].Delta.Content)
+	assert.Equal(t, goopenai.FinishReasonStop, chunks[2].Choices[0].FinishReason)
+	assert.Equal(t, goopenai.Usage{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5}, *usage)
+}
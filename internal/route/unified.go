@@ -0,0 +1,112 @@
+package route
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bricks-cloud/bricksllm/internal/provider"
+)
+
+const defaultAzureApiVersion = "2024-02-01"
+
+// ModelAlias maps a model name used with the unified endpoints to a provider and
+// the model name understood by that provider.
+type ModelAlias struct {
+	Provider string
+	Model    string
+}
+
+var unifiedProviders = map[string]bool{
+	"openai":    true,
+	"azure":     true,
+	"anthropic": true,
+	"bedrock":   true,
+	"vllm":      true,
+	"deepinfra": true,
+	"gemini":    true,
+}
+
+// IsUnifiedProvider reports whether requests to the unified endpoints can be served
+// by the provider.
+func IsUnifiedProvider(provider string) bool {
+	return unifiedProviders[provider]
+}
+
+// ResolveModelAlias returns the provider and model that a model name used with the
+// unified endpoints resolves to. The model has to be prefixed with its provider such as
+// vllm/llama-3-8b. Model aliases managed with the admin api are rewritten to this form
+// before they are resolved.
+func ResolveModelAlias(model string) (*ModelAlias, error) {
+	parts := strings.SplitN(model, "/", 2)
+	if len(parts) != 2 || !unifiedProviders[parts[0]] || len(parts[1]) == 0 {
+		return nil, fmt.Errorf("model %s is not a model alias and is not prefixed with a supported provider", model)
+	}
+
+	return &ModelAlias{Provider: parts[0], Model: parts[1]}, nil
+}
+
+// SelectSetting returns the first provider setting of the provider, keeping the order
+// of settings chosen by the authenticator.
+func SelectSetting(providerName string, settings []*provider.Setting) *provider.Setting {
+	for _, setting := range settings {
+		if setting.Provider == providerName {
+			return setting
+		}
+	}
+
+	return nil
+}
+
+// NewUnifiedRoute builds a single step route that forwards an openai formatted request
+// to the provider a model resolves to.
+func NewUnifiedRoute(path string, alias *ModelAlias, setting *provider.Setting, runEmbeddings bool, stream bool, timeout string) (*Route, error) {
+	if setting == nil {
+		return nil, errors.New("provider setting is not found")
+	}
+
+	if runEmbeddings && (alias.Provider == "anthropic" || alias.Provider == "bedrock") {
+		return nil, fmt.Errorf("embeddings are not supported for provider %s", alias.Provider)
+	}
+
+	// gemini models are served through the openai compatible api of gemini, which
+	// only accepts api keys.
+	if alias.Provider == "gemini" && len(setting.GetParam("apikey")) == 0 {
+		return nil, errors.New("gemini provider setting without an api key is not supported")
+	}
+
+	format := "openai_chat_completion"
+	if runEmbeddings {
+		format = "openai_embeddings"
+	}
+
+	step := &Step{
+		Provider:      alias.Provider,
+		Model:         alias.Model,
+		Timeout:       timeout,
+		Params:        map[string]string{},
+		RequestParams: map[string]any{},
+	}
+
+	if alias.Provider == "azure" {
+		apiVersion := setting.GetParam("apiVersion")
+		if len(apiVersion) == 0 {
+			apiVersion = defaultAzureApiVersion
+		}
+
+		step.Params["deploymentId"] = alias.Model
+		step.Params["apiVersion"] = apiVersion
+	}
+
+	if stream && alias.Provider != "anthropic" && alias.Provider != "bedrock" {
+		step.RequestParams["stream_options"] = map[string]any{
+			"include_usage": true,
+		}
+	}
+
+	return &Route{
+		Path:          path,
+		RequestFormat: format,
+		Steps:         []*Step{step},
+	}, nil
+}
@@ -0,0 +1,49 @@
+package route
+
+import (
+	"testing"
+
+	"github.com/bricks-cloud/bricksllm/internal/provider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveModelAlias(t *testing.T) {
+	cases := []struct {
+		model    string
+		provider string
+		resolved string
+	}{
+		{"openai/gpt-4o", "openai", "gpt-4o"},
+		{"anthropic/claude-3-haiku-20240307", "anthropic", "claude-3-haiku-20240307"},
+		{"gemini/text-embedding-004", "gemini", "text-embedding-004"},
+		{"vllm/llama-3-8b", "vllm", "llama-3-8b"},
+		{"deepinfra/meta-llama/Meta-Llama-3-8B-Instruct", "deepinfra", "meta-llama/Meta-Llama-3-8B-Instruct"},
+	}
+
+	for _, c := range cases {
+		alias, err := ResolveModelAlias(c.model)
+		require.NoError(t, err, c.model)
+		assert.Equal(t, c.provider, alias.Provider, c.model)
+		assert.Equal(t, c.resolved, alias.Model, c.model)
+	}
+
+	for _, model := range []string{"unknown", "gpt-4o", "claude-3-haiku", "meta-llama/Meta-Llama-3-8B-Instruct", "custom/model", "openai/", ""} {
+		_, err := ResolveModelAlias(model)
+		assert.Error(t, err, model)
+	}
+}
+
+func TestNewUnifiedRoute_Gemini(t *testing.T) {
+	alias := &ModelAlias{Provider: "gemini", Model: "gemini-1.5-flash"}
+
+	r, err := NewUnifiedRoute("/v1/chat/completions", alias, &provider.Setting{Provider: "gemini", Setting: map[string]string{"apikey": "key"}}, false, false, "10s")
+	require.NoError(t, err)
+	require.Len(t, r.Steps, 1)
+	assert.Equal(t, "gemini", r.Steps[0].Provider)
+	assert.Equal(t, "https://generativelanguage.googleapis.com/v1beta/openai/chat/completions", buildRequestUrl("gemini", false, "", r.Steps[0].Params))
+	assert.Equal(t, "https://generativelanguage.googleapis.com/v1beta/openai/embeddings", buildRequestUrl("gemini", true, "", r.Steps[0].Params))
+
+	_, err = NewUnifiedRoute("/v1/chat/completions", alias, &provider.Setting{Provider: "gemini", Setting: map[string]string{"projectId": "p", "location": "us-central1", "credentials": "{}"}}, false, false, "10s")
+	assert.Error(t, err)
+}
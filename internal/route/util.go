@@ -1,7 +1,9 @@
 package route
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/bricks-cloud/bricksllm/internal/hasher"
 	"github.com/bricks-cloud/bricksllm/internal/util"
@@ -36,3 +38,43 @@ func ComputeCacheKeyForChatCompletionRequest(path string, req *goopenai.ChatComp
 
 	return hasher.Hash(fmt.Sprintf("%s-%s-%s", path, input, req.User))
 }
+
+// ComputeSemanticCacheScope scopes semantically cached responses to the route, the key, the
+// models of the route steps and every request parameter that changes the completion so
+// that only the messages are compared by similarity.
+func ComputeSemanticCacheScope(r *Route, keyId string, req *goopenai.ChatCompletionRequest) string {
+	if r == nil || req == nil {
+		return ""
+	}
+
+	models := []string{}
+	for _, s := range r.Steps {
+		if s != nil {
+			models = append(models, s.Provider+"/"+s.Model)
+		}
+	}
+
+	params := *req
+	params.Messages = nil
+	params.Stream = false
+	params.StreamOptions = nil
+	params.Store = false
+	params.Metadata = nil
+
+	bs, _ := json.Marshal(params)
+
+	return hasher.Hash(fmt.Sprintf("%s-%s-%s-%s", r.Path, keyId, strings.Join(models, ","), string(bs)))
+}
+
+func ComputeSemanticCacheInputForChatCompletionRequest(req *goopenai.ChatCompletionRequest) string {
+	if req == nil {
+		return ""
+	}
+
+	input := ""
+	for _, m := range req.Messages {
+		input += fmt.Sprintf("%s: %s\n", m.Role, getChatCompletionMessageContent(m))
+	}
+
+	return input
+}
@@ -0,0 +1,50 @@
+package route
+
+import (
+	"testing"
+
+	goopenai "github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeSemanticCacheScope(t *testing.T) {
+	r := &Route{
+		Path:  "/chat",
+		Steps: []*Step{{Provider: "openai", Model: "gpt-4o"}},
+	}
+
+	base := &goopenai.ChatCompletionRequest{
+		Model:       "gpt-4o",
+		Temperature: 0.2,
+		Messages:    []goopenai.ChatCompletionMessage{{Role: "user", Content: "hi"}},
+	}
+
+	scope := ComputeSemanticCacheScope(r, "key", base)
+	assert.NotEmpty(t, scope)
+
+	sameParams := *base
+	sameParams.Messages = []goopenai.ChatCompletionMessage{{Role: "user", Content: "hello"}}
+	assert.Equal(t, scope, ComputeSemanticCacheScope(r, "key", &sameParams))
+
+	temperature := *base
+	temperature.Temperature = 0.9
+	assert.NotEqual(t, scope, ComputeSemanticCacheScope(r, "key", &temperature))
+
+	maxTokens := *base
+	maxTokens.MaxTokens = 10
+	assert.NotEqual(t, scope, ComputeSemanticCacheScope(r, "key", &maxTokens))
+
+	model := *base
+	model.Model = "gpt-4o-mini"
+	assert.NotEqual(t, scope, ComputeSemanticCacheScope(r, "key", &model))
+
+	assert.NotEqual(t, scope, ComputeSemanticCacheScope(r, "other", base))
+
+	other := &Route{
+		Path:  "/chat",
+		Steps: []*Step{{Provider: "openai", Model: "gpt-4o-mini"}},
+	}
+	assert.NotEqual(t, scope, ComputeSemanticCacheScope(other, "key", base))
+
+	assert.Empty(t, ComputeSemanticCacheScope(nil, "key", base))
+}
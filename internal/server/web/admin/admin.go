@@ -11,6 +11,7 @@ import (
 	"github.com/bricks-cloud/bricksllm/internal/event"
 	"github.com/bricks-cloud/bricksllm/internal/key"
 	"github.com/bricks-cloud/bricksllm/internal/policy"
+	"github.com/bricks-cloud/bricksllm/internal/principal"
 	"github.com/bricks-cloud/bricksllm/internal/provider"
 	"github.com/bricks-cloud/bricksllm/internal/provider/custom"
 	"github.com/bricks-cloud/bricksllm/internal/telemetry"
@@ -32,6 +33,7 @@ type KeyManager interface {
 	UpdateKey(id string, key *key.UpdateKey) (*key.ResponseKey, error)
 	CreateKey(key *key.RequestKey) (*key.ResponseKey, error)
 	DeleteKey(id string) error
+	RotateKey(id string) (*key.RotatedKey, error)
 }
 
 type KeyReportingManager interface {
@@ -49,6 +51,7 @@ type PoliciesManager interface {
 	CreatePolicy(p *policy.Policy) (*policy.Policy, error)
 	UpdatePolicy(id string, p *policy.UpdatePolicy) (*policy.Policy, error)
 	GetPoliciesByTags(tags []string) ([]*policy.Policy, error)
+	GetPolicyByIdFromMemdb(id string) *policy.Policy
 }
 
 type ErrorResponse struct {
@@ -65,51 +68,94 @@ type AdminServer struct {
 	m      KeyManager
 }
 
-func NewAdminServer(log *zap.Logger, mode string, m KeyManager, krm KeyReportingManager, psm ProviderSettingsManager, cpm CustomProvidersManager, rm RouteManager, pm PoliciesManager, um UserManager, adminPass string) (*AdminServer, error) {
+func NewAdminServer(log *zap.Logger, mode string, m KeyManager, krm KeyReportingManager, psm ProviderSettingsManager, cpm CustomProvidersManager, rm RouteManager, pm PoliciesManager, um UserManager, mam ModelAliasManager, prm PriceManager, wm WebhookManager, bm BudgetManager, pam PrincipalManager, alm AuditManager, adminPass string) (*AdminServer, error) {
 	router := gin.New()
 
 	prod := mode == "production"
-	router.Use(getAdminLoggerMiddleware(log, "admin", prod, adminPass))
+	router.Use(getAdminLoggerMiddleware(log, "admin", prod))
+	router.Use(getAuthenticationMiddleware(pam, adminPass, prod))
 
-	router.GET("/api/health", getGetHealthCheckHandler())
-
-	router.POST("/api/v2/key-management/keys", getGetKeysV2Handler(m, prod))
-	router.GET("/api/key-management/keys", getGetKeysHandler(m, prod))
-	router.PUT("/api/key-management/keys", getCreateKeyHandler(m, prod))
-	router.PATCH("/api/key-management/keys/:id", getUpdateKeyHandler(m, prod))
-	router.DELETE("/api/key-management/keys/:id", getDeleteKeyHandler(m, prod))
-
-	router.GET("/api/reporting/keys/:id", getGetKeyReportingHandler(krm, prod))
-	router.POST("/api/reporting/events", getGetEventMetricsHandler(krm, prod))
-	router.POST("/api/reporting/events-by-day", getGetEventMetricsByDayHandler(krm, prod))
-	router.GET("/api/events", getGetEventsHandler(krm, prod))
-	router.POST("/api/v2/events", getGetEventsV2Handler(krm, prod))
-	router.GET("/api/reporting/user-ids", getGetUserIdsHandler(krm, prod))
-	router.POST("/api/reporting/top-keys", getGetTopKeysMetricsHandler(krm, prod))
-
-	router.GET("/api/reporting/custom-ids", getGetCustomIdsHandler(krm, prod))
-
-	router.PUT("/api/provider-settings", getCreateProviderSettingHandler(psm, prod))
-	router.GET("/api/provider-settings", getGetProviderSettingsHandler(psm, prod))
-	router.PATCH("/api/provider-settings/:id", getUpdateProviderSettingHandler(psm, prod))
-
-	router.POST("/api/custom/providers", getCreateCustomProviderHandler(cpm, prod))
-	router.GET("/api/custom/providers", getGetCustomProvidersHandler(cpm, prod))
-	router.PATCH("/api/custom/providers/:id", getUpdateCustomProvidersHandler(cpm, prod))
-
-	router.POST("/api/routes", getCreateRouteHandler(rm, prod))
-	router.GET("/api/routes/:id", getGetRouteHandler(rm, prod))
-	router.GET("/api/routes", getGetRoutesHandler(rm, prod))
-	router.DELETE("/api/routes/:id", getDeleteRouteHandler(rm, prod))
+	lookups := getEntityLookups(m, psm, cpm, rm, pm, um, mam, prm, wm, bm, pam)
+	audited := func(entityType string) gin.HandlerFunc {
+		return getAuditMiddleware(alm, entityType, getEntityIdFromParam, lookups[entityType], prod)
+	}
 
-	router.POST("/api/policies", getCreatePolicyHandler(pm, prod))
-	router.PATCH("/api/policies/:id", getUpdatePolicyHandler(pm, prod))
-	router.GET("/api/policies", getGetPoliciesByTagsHandler(pm, prod))
+	router.GET("/api/health", getGetHealthCheckHandler())
 
-	router.POST("/api/users", getCreateUserHandler(um, prod))
-	router.PATCH("/api/users/:id", getUpdateUserHandler(um, prod))
-	router.PATCH("/api/users", getUpdateUserViaTagsAndUserIdHandler(um, prod))
-	router.GET("/api/users", getGetUsersHandler(um, prod))
+	router.POST("/api/v2/key-management/keys", requirePermission(principal.PermissionRead), getGetKeysV2Handler(m, prod))
+	router.GET("/api/key-management/keys", requirePermission(principal.PermissionRead), getGetKeysHandler(m, prod))
+	router.PUT("/api/key-management/keys", requirePermission(principal.PermissionManageKeys), audited("key"), getCreateKeyHandler(m, prod))
+	router.PATCH("/api/key-management/keys/:id", requirePermission(principal.PermissionManageKeys), audited("key"), getUpdateKeyHandler(m, prod))
+	router.DELETE("/api/key-management/keys/:id", requirePermission(principal.PermissionManageKeys), audited("key"), getDeleteKeyHandler(m, prod))
+	router.POST("/api/key-management/keys/:id/rotate", requirePermission(principal.PermissionManageKeys), audited("key"), getRotateKeyHandler(m, prod))
+
+	router.GET("/api/reporting/keys/:id", requirePermission(principal.PermissionRead), getGetKeyReportingHandler(krm, prod))
+	router.POST("/api/reporting/events", requirePermission(principal.PermissionRead), getGetEventMetricsHandler(krm, prod))
+	router.POST("/api/reporting/events-by-day", requirePermission(principal.PermissionRead), getGetEventMetricsByDayHandler(krm, prod))
+	router.GET("/api/events", requirePermission(principal.PermissionRead), getGetEventsHandler(krm, prod))
+	router.POST("/api/v2/events", requirePermission(principal.PermissionRead), getGetEventsV2Handler(krm, prod))
+	router.GET("/api/reporting/user-ids", requirePermission(principal.PermissionRead), getGetUserIdsHandler(krm, prod))
+	router.POST("/api/reporting/top-keys", requirePermission(principal.PermissionRead), getGetTopKeysMetricsHandler(krm, prod))
+
+	router.GET("/api/reporting/custom-ids", requirePermission(principal.PermissionRead), getGetCustomIdsHandler(krm, prod))
+
+	router.PUT("/api/provider-settings", requirePermission(principal.PermissionManagePolicies), audited("provider-setting"), getCreateProviderSettingHandler(psm, prod))
+	router.GET("/api/provider-settings", requirePermission(principal.PermissionRead), getGetProviderSettingsHandler(psm, prod))
+	router.PATCH("/api/provider-settings/:id", requirePermission(principal.PermissionManagePolicies), audited("provider-setting"), getUpdateProviderSettingHandler(psm, prod))
+
+	router.POST("/api/custom/providers", requirePermission(principal.PermissionManagePolicies), audited("custom-provider"), getCreateCustomProviderHandler(cpm, prod))
+	router.GET("/api/custom/providers", requirePermission(principal.PermissionRead), getGetCustomProvidersHandler(cpm, prod))
+	router.PATCH("/api/custom/providers/:id", requirePermission(principal.PermissionManagePolicies), audited("custom-provider"), getUpdateCustomProvidersHandler(cpm, prod))
+
+	router.POST("/api/routes", requirePermission(principal.PermissionManagePolicies), audited("route"), getCreateRouteHandler(rm, prod))
+	router.GET("/api/routes/:id", requirePermission(principal.PermissionRead), getGetRouteHandler(rm, prod))
+	router.GET("/api/routes", requirePermission(principal.PermissionRead), getGetRoutesHandler(rm, prod))
+	router.DELETE("/api/routes/:id", requirePermission(principal.PermissionManagePolicies), audited("route"), getDeleteRouteHandler(rm, prod))
+
+	router.POST("/api/policies", requirePermission(principal.PermissionManagePolicies), audited("policy"), getCreatePolicyHandler(pm, prod))
+	router.PATCH("/api/policies/:id", requirePermission(principal.PermissionManagePolicies), audited("policy"), getUpdatePolicyHandler(pm, prod))
+	router.GET("/api/policies", requirePermission(principal.PermissionRead), getGetPoliciesByTagsHandler(pm, prod))
+
+	router.POST("/api/model-aliases", requirePermission(principal.PermissionManagePolicies), audited("model-alias"), getCreateModelAliasHandler(mam, prod))
+	router.GET("/api/model-aliases", requirePermission(principal.PermissionRead), getGetModelAliasesHandler(mam, prod))
+	router.GET("/api/model-aliases/:id", requirePermission(principal.PermissionRead), getGetModelAliasHandler(mam, prod))
+	router.PATCH("/api/model-aliases/:id", requirePermission(principal.PermissionManagePolicies), audited("model-alias"), getUpdateModelAliasHandler(mam, prod))
+	router.DELETE("/api/model-aliases/:id", requirePermission(principal.PermissionManagePolicies), audited("model-alias"), getDeleteModelAliasHandler(mam, prod))
+
+	router.POST("/api/prices", requirePermission(principal.PermissionManagePolicies), audited("price"), getCreatePriceHandler(prm, prod))
+	router.GET("/api/prices", requirePermission(principal.PermissionRead), getGetPricesHandler(prm, prod))
+	router.GET("/api/prices/:id", requirePermission(principal.PermissionRead), getGetPriceHandler(prm, prod))
+	router.PATCH("/api/prices/:id", requirePermission(principal.PermissionManagePolicies), audited("price"), getUpdatePriceHandler(prm, prod))
+	router.DELETE("/api/prices/:id", requirePermission(principal.PermissionManagePolicies), audited("price"), getDeletePriceHandler(prm, prod))
+
+	router.POST("/api/webhooks", requirePermission(principal.PermissionManageAdmin), audited("webhook"), getCreateWebhookHandler(wm, prod))
+	router.GET("/api/webhooks", requirePermission(principal.PermissionRead), getGetWebhooksHandler(wm, prod))
+	router.GET("/api/webhooks/:id", requirePermission(principal.PermissionRead), getGetWebhookHandler(wm, prod))
+	router.PATCH("/api/webhooks/:id", requirePermission(principal.PermissionManageAdmin), audited("webhook"), getUpdateWebhookHandler(wm, prod))
+	router.DELETE("/api/webhooks/:id", requirePermission(principal.PermissionManageAdmin), audited("webhook"), getDeleteWebhookHandler(wm, prod))
+	router.GET("/api/webhooks/:id/deliveries", requirePermission(principal.PermissionRead), getGetWebhookDeliveriesHandler(wm, prod))
+	router.POST("/api/webhooks/:id/test", requirePermission(principal.PermissionManageAdmin), getTestWebhookHandler(wm, prod))
+
+	router.POST("/api/budgets", requirePermission(principal.PermissionManageKeys), audited("budget"), getCreateBudgetHandler(bm, prod))
+	router.GET("/api/budgets", requirePermission(principal.PermissionRead), getGetBudgetsHandler(bm, prod))
+	router.GET("/api/budgets/:id", requirePermission(principal.PermissionRead), getGetBudgetHandler(bm, prod))
+	router.PATCH("/api/budgets/:id", requirePermission(principal.PermissionManageKeys), audited("budget"), getUpdateBudgetHandler(bm, prod))
+	router.DELETE("/api/budgets/:id", requirePermission(principal.PermissionManageKeys), audited("budget"), getDeleteBudgetHandler(bm, prod))
+	router.GET("/api/budgets/:id/spend", requirePermission(principal.PermissionRead), getGetBudgetSpendHandler(bm, prod))
+
+	router.POST("/api/admin-principals", requirePermission(principal.PermissionManageAdmin), audited("admin-principal"), getCreatePrincipalHandler(pam, prod))
+	router.GET("/api/admin-principals", requirePermission(principal.PermissionManageAdmin), getGetPrincipalsHandler(pam, prod))
+	router.GET("/api/admin-principals/:id", requirePermission(principal.PermissionManageAdmin), getGetPrincipalHandler(pam, prod))
+	router.PATCH("/api/admin-principals/:id", requirePermission(principal.PermissionManageAdmin), audited("admin-principal"), getUpdatePrincipalHandler(pam, prod))
+	router.DELETE("/api/admin-principals/:id", requirePermission(principal.PermissionManageAdmin), audited("admin-principal"), getDeletePrincipalHandler(pam, prod))
+
+	router.POST("/api/users", requirePermission(principal.PermissionManageKeys), audited("user"), getCreateUserHandler(um, prod))
+	router.PATCH("/api/users/:id", requirePermission(principal.PermissionManageKeys), audited("user"), getUpdateUserHandler(um, prod))
+	router.PATCH("/api/users", requirePermission(principal.PermissionManageKeys), getAuditMiddleware(alm, "user", getUserIdResolver(um), lookups["user"], prod), getUpdateUserViaTagsAndUserIdHandler(um, prod))
+	router.GET("/api/users", requirePermission(principal.PermissionRead), getGetUsersHandler(um, prod))
+
+	router.GET("/api/audit-logs", requirePermission(principal.PermissionRead), getGetAuditLogsHandler(alm, prod))
+	router.GET("/api/audit-logs/verify", requirePermission(principal.PermissionRead), getVerifyAuditLogsHandler(alm, prod))
 
 	srv := &http.Server{
 		Addr:    ":8001",
@@ -131,6 +177,7 @@ func (as *AdminServer) Run() {
 		as.log.Info("PORT 8001 | POST   | /api/v2/key-management/keys is set up for retrieving keys")
 		as.log.Info("PORT 8001 | PUT    | /api/key-management/keys is set up for creating a key")
 		as.log.Info("PORT 8001 | PATCH  | /api/key-management/keys/:id is set up for updating a key using an id")
+		as.log.Info("PORT 8001 | POST   | /api/key-management/keys/:id/rotate is set up for rotating the secret of a key using an id")
 		as.log.Info("PORT 8001 | GET    | /api/provider-settings is set up for getting provider settings")
 		as.log.Info("PORT 8001 | PUT    | /api/provider-settings is set up for creating a provider setting")
 		as.log.Info("PORT 8001 | PATCH  | /api/provider-settings:id is set up for updating provider setting")
@@ -147,9 +194,39 @@ func (as *AdminServer) Run() {
 		as.log.Info("PORT 8001 | POST   | /api/policies is set up for creating a policy")
 		as.log.Info("PORT 8001 | PATCH  | /api/policies/:id is set up for retrieving a policy")
 		as.log.Info("PORT 8001 | GET    | /api/policies is set up for retrieving policies")
+		as.log.Info("PORT 8001 | POST   | /api/model-aliases is set up for creating a model alias")
+		as.log.Info("PORT 8001 | GET    | /api/model-aliases is set up for retrieving model aliases")
+		as.log.Info("PORT 8001 | GET    | /api/model-aliases/:id is set up for retrieving a model alias")
+		as.log.Info("PORT 8001 | PATCH  | /api/model-aliases/:id is set up for updating a model alias")
+		as.log.Info("PORT 8001 | DELETE | /api/model-aliases/:id is set up for deleting a model alias")
+		as.log.Info("PORT 8001 | POST   | /api/prices is set up for creating a price")
+		as.log.Info("PORT 8001 | GET    | /api/prices is set up for retrieving prices")
+		as.log.Info("PORT 8001 | GET    | /api/prices/:id is set up for retrieving a price")
+		as.log.Info("PORT 8001 | PATCH  | /api/prices/:id is set up for updating a price")
+		as.log.Info("PORT 8001 | DELETE | /api/prices/:id is set up for deleting a price")
+		as.log.Info("PORT 8001 | POST   | /api/webhooks is set up for creating a webhook")
+		as.log.Info("PORT 8001 | GET    | /api/webhooks is set up for retrieving webhooks")
+		as.log.Info("PORT 8001 | GET    | /api/webhooks/:id is set up for retrieving a webhook")
+		as.log.Info("PORT 8001 | PATCH  | /api/webhooks/:id is set up for updating a webhook")
+		as.log.Info("PORT 8001 | DELETE | /api/webhooks/:id is set up for deleting a webhook")
+		as.log.Info("PORT 8001 | GET    | /api/webhooks/:id/deliveries is set up for retrieving webhook deliveries")
+		as.log.Info("PORT 8001 | POST   | /api/webhooks/:id/test is set up for sending a test delivery to a webhook")
+		as.log.Info("PORT 8001 | POST   | /api/admin-principals is set up for creating an admin principal")
+		as.log.Info("PORT 8001 | GET    | /api/admin-principals is set up for retrieving admin principals")
+		as.log.Info("PORT 8001 | GET    | /api/admin-principals/:id is set up for retrieving an admin principal")
+		as.log.Info("PORT 8001 | PATCH  | /api/admin-principals/:id is set up for updating an admin principal")
+		as.log.Info("PORT 8001 | DELETE | /api/admin-principals/:id is set up for deleting an admin principal")
+		as.log.Info("PORT 8001 | POST   | /api/budgets is set up for creating a budget")
+		as.log.Info("PORT 8001 | GET    | /api/budgets is set up for retrieving budgets")
+		as.log.Info("PORT 8001 | GET    | /api/budgets/:id is set up for retrieving a budget")
+		as.log.Info("PORT 8001 | PATCH  | /api/budgets/:id is set up for updating a budget")
+		as.log.Info("PORT 8001 | DELETE | /api/budgets/:id is set up for deleting a budget")
+		as.log.Info("PORT 8001 | GET    | /api/budgets/:id/spend is set up for retrieving the spend of a budget")
 		as.log.Info("PORT 8001 | POST   | /api/users is set up for creating a user")
 		as.log.Info("PORT 8001 | GET    | /api/users is set up for retrieving users")
 		as.log.Info("PORT 8001 | PATCH  | /api/users is set up for updating a user")
+		as.log.Info("PORT 8001 | GET    | /api/audit-logs is set up for retrieving audit logs")
+		as.log.Info("PORT 8001 | GET    | /api/audit-logs/verify is set up for verifying the hash chain of audit logs")
 
 		if err := as.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			as.log.Sugar().Fatalf("error admin server listening: %v", err)
@@ -229,7 +306,7 @@ func getGetKeysHandler(m KeyManager, prod bool) gin.HandlerFunc {
 		}
 
 		telemetry.Incr("bricksllm.admin.get_get_keys_handler.success", nil, 1)
-		c.JSON(http.StatusOK, keys)
+		c.JSON(http.StatusOK, maskKeys(c, keys))
 	}
 }
 
@@ -317,10 +394,68 @@ func getGetKeysV2Handler(m KeyManager, prod bool) gin.HandlerFunc {
 		}
 
 		telemetry.Incr("bricksllm.admin.get_get_keys_v2_handler.success", nil, 1)
-		c.JSON(http.StatusOK, keys)
+		c.JSON(http.StatusOK, &key.GetKeysResponse{
+			Keys:  maskKeys(c, keys.Keys),
+			Count: keys.Count,
+		})
 	}
 }
 
+// maskKeys removes the secrets of keys, which are stored in plain text for keys that are
+// not hashed, unless the principal of the request can manage keys.
+func maskKeys(c *gin.Context, keys []*key.ResponseKey) []*key.ResponseKey {
+	if p := getPrincipalFromCtx(c); p != nil && p.Role.Can(principal.PermissionManageKeys) {
+		return keys
+	}
+
+	masked := make([]*key.ResponseKey, 0, len(keys))
+	for _, k := range keys {
+		copied := *k
+		copied.Key = ""
+		copied.PreviousKey = ""
+		masked = append(masked, &copied)
+	}
+
+	return masked
+}
+
+// publicSettingParams are the provider setting params that do not hold credentials.
+var publicSettingParams = map[string]bool{
+	"url":          true,
+	"resourceName": true,
+	"awsRegion":    true,
+	"projectId":    true,
+	"location":     true,
+}
+
+// maskProviderSettings removes the credentials of provider settings unless the principal
+// of the request can manage providers. Params of custom providers are treated as
+// credentials since any of them can be the authentication param.
+func maskProviderSettings(c *gin.Context, settings []*provider.Setting) []*provider.Setting {
+	if p := getPrincipalFromCtx(c); p != nil && p.Role.Can(principal.PermissionManagePolicies) {
+		return settings
+	}
+
+	masked := make([]*provider.Setting, 0, len(settings))
+	for _, s := range settings {
+		if s == nil {
+			continue
+		}
+
+		copied := *s
+		copied.Setting = map[string]string{}
+		for param, val := range s.Setting {
+			if publicSettingParams[param] {
+				copied.Setting[param] = val
+			}
+		}
+
+		masked = append(masked, &copied)
+	}
+
+	return masked
+}
+
 type validationError interface {
 	Error() string
 	Validation()
@@ -372,7 +507,7 @@ func getGetProviderSettingsHandler(m ProviderSettingsManager, prod bool) gin.Han
 
 		telemetry.Incr("bricksllm.admin.get_get_provider_settings.success", nil, 1)
 
-		c.JSON(http.StatusOK, created)
+		c.JSON(http.StatusOK, maskProviderSettings(c, created))
 	}
 }
 
@@ -816,6 +951,78 @@ func getDeleteKeyHandler(m KeyManager, prod bool) gin.HandlerFunc {
 	}
 }
 
+func getRotateKeyHandler(m KeyManager, prod bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := util.GetLogFromCtx(c)
+		telemetry.Incr("bricksllm.admin.get_rotate_key_handler.requests", nil, 1)
+
+		start := time.Now()
+		defer func() {
+			dur := time.Since(start)
+			telemetry.Timing("bricksllm.admin.get_rotate_key_handler.latency", dur, nil, 1)
+		}()
+
+		path := "/api/key-management/keys/:id/rotate"
+		if c == nil || c.Request == nil {
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/empty-context",
+				Title:    "context is empty error",
+				Status:   http.StatusInternalServerError,
+				Detail:   "gin context is empty",
+				Instance: path,
+			})
+			return
+		}
+
+		rotated, err := m.RotateKey(c.Param("id"))
+		if err != nil {
+			errType := "internal"
+			defer func() {
+				telemetry.Incr("bricksllm.admin.get_rotate_key_handler.rotate_key_error", []string{
+					"error_type:" + errType,
+				}, 1)
+			}()
+
+			if _, ok := err.(notFoundError); ok {
+				errType = "not_found"
+				c.JSON(http.StatusNotFound, &ErrorResponse{
+					Type:     "/errors/not-found",
+					Title:    "key not found",
+					Status:   http.StatusNotFound,
+					Detail:   err.Error(),
+					Instance: path,
+				})
+				return
+			}
+
+			if _, ok := err.(validationError); ok {
+				errType = "validation"
+				c.JSON(http.StatusBadRequest, &ErrorResponse{
+					Type:     "/errors/validation",
+					Title:    "key rotation failed",
+					Status:   http.StatusBadRequest,
+					Detail:   err.Error(),
+					Instance: path,
+				})
+				return
+			}
+
+			logError(log, "error when rotating api key", prod, err)
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/key-manager",
+				Title:    "key rotation error",
+				Status:   http.StatusInternalServerError,
+				Detail:   err.Error(),
+				Instance: path,
+			})
+			return
+		}
+
+		telemetry.Incr("bricksllm.admin.get_rotate_key_handler.success", nil, 1)
+		c.JSON(http.StatusOK, rotated)
+	}
+}
+
 type notFoundError interface {
 	Error() string
 	NotFound()
@@ -0,0 +1,116 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/bricks-cloud/bricksllm/internal/principal"
+	"github.com/bricks-cloud/bricksllm/internal/provider"
+	"github.com/bricks-cloud/bricksllm/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestMaskKeys(t *testing.T) {
+	cases := []struct {
+		role   principal.Role
+		masked bool
+	}{
+		{principal.RoleViewer, true},
+		{principal.RolePolicyAdmin, true},
+		{principal.RoleKeyManager, false},
+		{principal.RoleSuperAdmin, false},
+	}
+
+	for _, tc := range cases {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Set(principalContextKey, &principal.Principal{Name: "test", Role: tc.role})
+
+		keys := []*key.ResponseKey{{KeyId: "key-1", Key: "secret", PreviousKey: "previous"}}
+		result := maskKeys(c, keys)
+
+		assert.Len(t, result, 1)
+		assert.Equal(t, "key-1", result[0].KeyId)
+		assert.Equal(t, "secret", keys[0].Key, "keys passed in must not be modified")
+
+		if tc.masked {
+			assert.Empty(t, result[0].Key, tc.role)
+			assert.Empty(t, result[0].PreviousKey, tc.role)
+			continue
+		}
+
+		assert.Equal(t, "secret", result[0].Key, tc.role)
+	}
+}
+
+type fakeProviderSettingsManager struct {
+	settings []*provider.Setting
+}
+
+func (m *fakeProviderSettingsManager) CreateSetting(setting *provider.Setting) (*provider.Setting, error) {
+	return setting, nil
+}
+
+func (m *fakeProviderSettingsManager) UpdateSetting(id string, setting *provider.UpdateSetting) (*provider.Setting, error) {
+	return nil, nil
+}
+
+func (m *fakeProviderSettingsManager) GetSettingViaCache(id string) (*provider.Setting, error) {
+	return m.settings[0], nil
+}
+
+func (m *fakeProviderSettingsManager) GetSettingsViaCache(ids []string) ([]*provider.Setting, error) {
+	return m.settings, nil
+}
+
+func TestGetProviderSettingsHandler_MasksCredentials(t *testing.T) {
+	cases := []struct {
+		role   principal.Role
+		masked bool
+	}{
+		{principal.RoleViewer, true},
+		{principal.RoleKeyManager, true},
+		{principal.RolePolicyAdmin, false},
+		{principal.RoleSuperAdmin, false},
+	}
+
+	for _, tc := range cases {
+		m := &fakeProviderSettingsManager{settings: []*provider.Setting{
+			{Id: "azure", Provider: "azure", Setting: map[string]string{"apikey": "azure-key", "resourceName": "resource"}},
+			{Id: "bedrock", Provider: "bedrock", Setting: map[string]string{"awsAccessKeyId": "id", "awsSecretAccessKey": "secret", "awsRegion": "us-east-1"}},
+			{Id: "custom", Provider: "custom", Setting: map[string]string{"token": "custom-token"}},
+		}}
+
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			util.SetLogToCtx(c, zap.NewNop())
+			c.Set(principalContextKey, &principal.Principal{Name: "test", Role: tc.role})
+		})
+		router.GET("/api/provider-settings", getGetProviderSettingsHandler(m, true))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/provider-settings?ids=azure&ids=bedrock&ids=custom", nil))
+		require.Equal(t, http.StatusOK, w.Code, tc.role)
+
+		settings := []*provider.Setting{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &settings))
+		require.Len(t, settings, 3)
+
+		assert.Equal(t, "azure-key", m.settings[0].Setting["apikey"], "settings of the manager must not be modified")
+
+		if tc.masked {
+			assert.Equal(t, map[string]string{"resourceName": "resource"}, settings[0].Setting, tc.role)
+			assert.Equal(t, map[string]string{"awsRegion": "us-east-1"}, settings[1].Setting, tc.role)
+			assert.Empty(t, settings[2].Setting, tc.role)
+			continue
+		}
+
+		assert.Equal(t, m.settings[0].Setting, settings[0].Setting, tc.role)
+		assert.Equal(t, m.settings[2].Setting, settings[2].Setting, tc.role)
+	}
+}
@@ -0,0 +1,400 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/audit"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+	"github.com/bricks-cloud/bricksllm/internal/util"
+	"github.com/gin-gonic/gin"
+)
+
+type AuditManager interface {
+	RecordAuditLog(e *audit.Entry) (*audit.Entry, error)
+	GetAuditLogs(filter *audit.Filter) ([]*audit.Entry, error)
+	VerifyAuditLogs() (*audit.Verification, error)
+}
+
+// entityLookup returns the current state of an entity so that it can be compared
+// with the state after a change.
+type entityLookup func(id string) (any, error)
+
+// entityIdResolver returns the id of the entity that a request changes.
+type entityIdResolver func(c *gin.Context) (string, error)
+
+func getEntityIdFromParam(c *gin.Context) (string, error) {
+	return c.Param("id"), nil
+}
+
+// getUserIdResolver resolves the internal id of the user that is updated via tags and
+// user id so that updates made through either user endpoint share the same entity id.
+func getUserIdResolver(um UserManager) entityIdResolver {
+	return func(c *gin.Context) (string, error) {
+		uid := c.Query("userId")
+		if len(uid) == 0 {
+			return "", nil
+		}
+
+		users, err := um.GetUsers(c.QueryArray("tags"), nil, []string{uid}, 0, 0)
+		if err != nil || len(users) != 1 {
+			return "", err
+		}
+
+		return users[0].Id, nil
+	}
+}
+
+// responseRecorder holds the response of a handler until the change has been recorded
+// in the audit log.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	return r.body.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	return r.body.WriteString(s)
+}
+
+func (r *responseRecorder) WriteHeaderNow() {}
+
+func (r *responseRecorder) flush() error {
+	r.ResponseWriter.WriteHeaderNow()
+	if r.body.Len() == 0 {
+		return nil
+	}
+
+	_, err := r.ResponseWriter.Write(r.body.Bytes())
+	return err
+}
+
+func getAuditAction(method, path string) audit.Action {
+	if strings.HasSuffix(path, "/rotate") {
+		return audit.ActionRotate
+	}
+
+	switch method {
+	case http.MethodPatch:
+		return audit.ActionUpdate
+	case http.MethodDelete:
+		return audit.ActionDelete
+	}
+
+	return audit.ActionCreate
+}
+
+// getAuditMiddleware records a change to an entity in the audit log. A pending entry
+// with the state before the change, which comes from the lookup, is recorded before the
+// handler runs and the request fails without running the handler if it cannot be
+// recorded. Once the handler has run, an entry with the outcome of the change and the
+// state after the change, which comes from the response of the handler, is recorded.
+// The response is only sent once the change has been committed in the audit log.
+func getAuditMiddleware(am AuditManager, entityType string, resolve entityIdResolver, lookup entityLookup, prod bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := util.GetLogFromCtx(c)
+
+		id, err := resolve(c)
+		if err != nil {
+			telemetry.Incr("bricksllm.admin.get_audit_middleware.resolve_entity_id_error", nil, 1)
+			logError(log, "error when resolving an entity id for the audit log", prod, err)
+		}
+
+		var before any
+		if len(id) != 0 && lookup != nil {
+			found, err := lookup(id)
+			if err == nil {
+				before, err = audit.Snapshot(found)
+			}
+
+			if err != nil {
+				if _, ok := err.(notFoundError); !ok {
+					telemetry.Incr("bricksllm.admin.get_audit_middleware.lookup_error", nil, 1)
+					logError(log, "error when looking up an entity for the audit log", prod, err)
+				}
+			}
+		}
+
+		action := getAuditAction(c.Request.Method, c.FullPath())
+
+		intent := audit.NewIntent(action, entityType, id, before)
+		if p := getPrincipalFromCtx(c); p != nil {
+			intent.ActorId = p.Id
+			intent.ActorName = p.Name
+			intent.ActorRole = string(p.Role)
+		}
+
+		intent, err = am.RecordAuditLog(intent)
+		if err != nil {
+			telemetry.Incr("bricksllm.admin.get_audit_middleware.record_intent_error", nil, 1)
+			logError(log, "error when recording a pending audit log", prod, err)
+
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/audit-manager",
+				Title:    "recording audit log error",
+				Status:   http.StatusInternalServerError,
+				Detail:   "the change was not applied because it could not be recorded in the audit log: " + err.Error(),
+				Instance: c.Request.URL.Path,
+			})
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		c.Next()
+
+		c.Writer = recorder.ResponseWriter
+
+		if recorder.Status() < http.StatusOK || recorder.Status() >= http.StatusMultipleChoices {
+			if err := recorder.flush(); err != nil {
+				logError(log, "error when writing a response", prod, err)
+			}
+
+			_, err := am.RecordAuditLog(audit.NewIntent(action, entityType, id, before).Resolve(intent, audit.StatusAborted))
+			if err != nil {
+				telemetry.Incr("bricksllm.admin.get_audit_middleware.record_abort_error", nil, 1)
+				logError(log, "error when recording an aborted audit log", prod, err)
+			}
+
+			return
+		}
+
+		var after any
+		if action != audit.ActionDelete && recorder.body.Len() != 0 {
+			err := json.Unmarshal(recorder.body.Bytes(), &after)
+			if err != nil {
+				telemetry.Incr("bricksllm.admin.get_audit_middleware.unmarshal_error", nil, 1)
+				logError(log, "error when unmarshalling a response for the audit log", prod, err)
+			}
+		}
+
+		if len(id) == 0 {
+			if parsed, ok := after.(map[string]any); ok {
+				id, _ = parsed["id"].(string)
+			}
+		}
+
+		_, err = am.RecordAuditLog(audit.NewEntry(action, entityType, id, before, after).Resolve(intent, audit.StatusCommitted))
+		if err != nil {
+			telemetry.Incr("bricksllm.admin.get_audit_middleware.record_audit_log_error", nil, 1)
+			logError(log, "error when recording an audit log", prod, err)
+
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/audit-manager",
+				Title:    "recording audit log error",
+				Status:   http.StatusInternalServerError,
+				Detail:   "the change was applied but could not be committed in the audit log, where it stays pending: " + err.Error(),
+				Instance: c.Request.URL.Path,
+			})
+			return
+		}
+
+		if err := recorder.flush(); err != nil {
+			logError(log, "error when writing a response", prod, err)
+		}
+
+		telemetry.Incr("bricksllm.admin.get_audit_middleware.success", []string{
+			"entity_type:" + entityType,
+		}, 1)
+	}
+}
+
+func getEntityLookups(m KeyManager, psm ProviderSettingsManager, cpm CustomProvidersManager, rm RouteManager, pm PoliciesManager, um UserManager, mam ModelAliasManager, prm PriceManager, wm WebhookManager, bm BudgetManager, pam PrincipalManager) map[string]entityLookup {
+	return map[string]entityLookup{
+		"key": func(id string) (any, error) {
+			keys, err := m.GetKeys(nil, []string{id}, "")
+			if err != nil || len(keys) == 0 {
+				return nil, err
+			}
+
+			return keys[0], nil
+		},
+		"provider-setting": func(id string) (any, error) {
+			return psm.GetSettingViaCache(id)
+		},
+		"custom-provider": func(id string) (any, error) {
+			providers, err := cpm.GetCustomProviders()
+			if err != nil {
+				return nil, err
+			}
+
+			for _, p := range providers {
+				if p.Id == id {
+					return p, nil
+				}
+			}
+
+			return nil, nil
+		},
+		"route": func(id string) (any, error) {
+			return rm.GetRoute(id)
+		},
+		"policy": func(id string) (any, error) {
+			return pm.GetPolicyByIdFromMemdb(id), nil
+		},
+		"user": func(id string) (any, error) {
+			return um.GetUser(id)
+		},
+		"model-alias": func(id string) (any, error) {
+			return mam.GetModelAlias(id)
+		},
+		"price": func(id string) (any, error) {
+			return prm.GetPrice(id)
+		},
+		"webhook": func(id string) (any, error) {
+			return wm.GetWebhook(id)
+		},
+		"budget": func(id string) (any, error) {
+			return bm.GetBudget(id)
+		},
+		"admin-principal": func(id string) (any, error) {
+			return pam.GetPrincipal(id)
+		},
+	}
+}
+
+func getGetAuditLogsHandler(m AuditManager, prod bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := util.GetLogFromCtx(c)
+		telemetry.Incr("bricksllm.admin.get_get_audit_logs_handler.requests", nil, 1)
+
+		start := time.Now()
+		defer func() {
+			dur := time.Since(start)
+			telemetry.Timing("bricksllm.admin.get_get_audit_logs_handler.latency", dur, nil, 1)
+		}()
+
+		path := "/api/audit-logs"
+		if c == nil || c.Request == nil {
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/empty-context",
+				Title:    "context is empty error",
+				Status:   http.StatusInternalServerError,
+				Detail:   "gin context is empty",
+				Instance: path,
+			})
+			return
+		}
+
+		filter := &audit.Filter{
+			ActorId:    c.Query("actorId"),
+			EntityType: c.Query("entityType"),
+			EntityId:   c.Query("entityId"),
+			Action:     audit.Action(c.Query("action")),
+		}
+
+		var offset, limit int64
+		for _, qp := range []struct {
+			name  string
+			value *int64
+		}{
+			{"start", &filter.Start},
+			{"end", &filter.End},
+			{"offset", &offset},
+			{"limit", &limit},
+		} {
+			str, ok := c.GetQuery(qp.name)
+			if !ok {
+				continue
+			}
+
+			parsed, err := strconv.ParseInt(str, 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, &ErrorResponse{
+					Type:     "/errors/bad-" + qp.name,
+					Title:    "bad " + qp.name + " query param",
+					Status:   http.StatusBadRequest,
+					Detail:   qp.name + " query param cannot be converted to integer",
+					Instance: path,
+				})
+				return
+			}
+
+			*qp.value = parsed
+		}
+
+		filter.Offset = int(offset)
+		filter.Limit = int(limit)
+
+		entries, err := m.GetAuditLogs(filter)
+		if err != nil {
+			errType := "internal"
+			defer func() {
+				telemetry.Incr("bricksllm.admin.get_get_audit_logs_handler.get_audit_logs_error", []string{
+					"error_type:" + errType,
+				}, 1)
+			}()
+
+			if _, ok := err.(validationError); ok {
+				errType = "validation"
+				c.JSON(http.StatusBadRequest, &ErrorResponse{
+					Type:     "/errors/validation",
+					Title:    "audit log filter validation failed",
+					Status:   http.StatusBadRequest,
+					Detail:   err.Error(),
+					Instance: path,
+				})
+				return
+			}
+
+			logError(log, "error when getting audit logs", prod, err)
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/audit-manager",
+				Title:    "getting audit logs error",
+				Status:   http.StatusInternalServerError,
+				Detail:   err.Error(),
+				Instance: path,
+			})
+			return
+		}
+
+		telemetry.Incr("bricksllm.admin.get_get_audit_logs_handler.success", nil, 1)
+		c.JSON(http.StatusOK, entries)
+	}
+}
+
+func getVerifyAuditLogsHandler(m AuditManager, prod bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := util.GetLogFromCtx(c)
+		telemetry.Incr("bricksllm.admin.get_verify_audit_logs_handler.requests", nil, 1)
+
+		start := time.Now()
+		defer func() {
+			dur := time.Since(start)
+			telemetry.Timing("bricksllm.admin.get_verify_audit_logs_handler.latency", dur, nil, 1)
+		}()
+
+		path := "/api/audit-logs/verify"
+
+		v, err := m.VerifyAuditLogs()
+		if err != nil {
+			telemetry.Incr("bricksllm.admin.get_verify_audit_logs_handler.verify_audit_logs_error", nil, 1)
+
+			logError(log, "error when verifying audit logs", prod, err)
+			c.JSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/audit-manager",
+				Title:    "verifying audit logs error",
+				Status:   http.StatusInternalServerError,
+				Detail:   err.Error(),
+				Instance: path,
+			})
+			return
+		}
+
+		if !v.Valid {
+			telemetry.Incr("bricksllm.admin.get_verify_audit_logs_handler.invalid_chain", nil, 1)
+		}
+
+		telemetry.Incr("bricksllm.admin.get_verify_audit_logs_handler.success", nil, 1)
+		c.JSON(http.StatusOK, v)
+	}
+}
@@ -0,0 +1,146 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bricks-cloud/bricksllm/internal/audit"
+	"github.com/bricks-cloud/bricksllm/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeAuditManager struct {
+	entries []*audit.Entry
+	err     error
+	// failAfter makes recording fail once this many entries have been recorded.
+	failAfter int
+}
+
+func (m *fakeAuditManager) RecordAuditLog(e *audit.Entry) (*audit.Entry, error) {
+	if m.err != nil && len(m.entries) >= m.failAfter {
+		return nil, m.err
+	}
+
+	e.Id = fmt.Sprintf("entry-%d", len(m.entries)+1)
+	m.entries = append(m.entries, e)
+	return e, nil
+}
+
+func (m *fakeAuditManager) GetAuditLogs(filter *audit.Filter) ([]*audit.Entry, error) {
+	return m.entries, nil
+}
+
+func (m *fakeAuditManager) VerifyAuditLogs() (*audit.Verification, error) {
+	return &audit.Verification{Valid: true}, nil
+}
+
+func newAuditedRouter(am AuditManager, status int, ran *bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		util.SetLogToCtx(c, zap.NewNop())
+	})
+
+	lookup := func(id string) (any, error) {
+		return map[string]any{"id": id, "name": "before"}, nil
+	}
+
+	router.PATCH("/api/things/:id", getAuditMiddleware(am, "thing", getEntityIdFromParam, lookup, false), func(c *gin.Context) {
+		*ran = true
+		c.JSON(status, map[string]any{"id": c.Param("id"), "name": "after"})
+	})
+
+	return router
+}
+
+func TestGetAuditMiddleware(t *testing.T) {
+	am := &fakeAuditManager{}
+	ran := false
+	router := newAuditedRouter(am, http.StatusOK, &ran)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/api/things/thing-1", nil))
+
+	assert.True(t, ran)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"id":"thing-1","name":"after"}`, w.Body.String())
+
+	require.Len(t, am.entries, 2)
+
+	intent := am.entries[0]
+	assert.Equal(t, audit.StatusPending, intent.Status)
+	assert.Equal(t, audit.ActionUpdate, intent.Action)
+	assert.Equal(t, "thing-1", intent.EntityId)
+	assert.Empty(t, intent.Changes)
+
+	outcome := am.entries[1]
+	assert.Equal(t, audit.StatusCommitted, outcome.Status)
+	assert.Equal(t, intent.Id, outcome.IntentId)
+	assert.Equal(t, audit.ActionUpdate, outcome.Action)
+	assert.Equal(t, "thing-1", outcome.EntityId)
+	require.Len(t, outcome.Changes, 1)
+	assert.Equal(t, "name", outcome.Changes[0].Field)
+}
+
+func TestGetAuditMiddleware_RecordIntentError(t *testing.T) {
+	am := &fakeAuditManager{err: errors.New("storage is down")}
+	ran := false
+	router := newAuditedRouter(am, http.StatusOK, &ran)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/api/things/thing-1", nil))
+
+	assert.False(t, ran)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	res := &ErrorResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), res))
+	assert.Equal(t, "/errors/audit-manager", res.Type)
+	assert.Contains(t, res.Detail, "not applied")
+	assert.Empty(t, am.entries)
+}
+
+func TestGetAuditMiddleware_RecordCommitError(t *testing.T) {
+	am := &fakeAuditManager{err: errors.New("storage is down"), failAfter: 1}
+	ran := false
+	router := newAuditedRouter(am, http.StatusOK, &ran)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/api/things/thing-1", nil))
+
+	assert.True(t, ran)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	res := &ErrorResponse{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), res))
+	assert.Equal(t, "/errors/audit-manager", res.Type)
+	assert.NotContains(t, w.Body.String(), "after")
+
+	require.Len(t, am.entries, 1)
+	assert.Equal(t, audit.StatusPending, am.entries[0].Status)
+}
+
+func TestGetAuditMiddleware_FailedRequest(t *testing.T) {
+	am := &fakeAuditManager{}
+	ran := false
+	router := newAuditedRouter(am, http.StatusBadRequest, &ran)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/api/things/thing-1", nil))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.JSONEq(t, `{"id":"thing-1","name":"after"}`, w.Body.String())
+
+	require.Len(t, am.entries, 2)
+	assert.Equal(t, audit.StatusPending, am.entries[0].Status)
+	assert.Equal(t, audit.StatusAborted, am.entries[1].Status)
+	assert.Equal(t, am.entries[0].Id, am.entries[1].IntentId)
+	assert.Empty(t, am.entries[1].Changes)
+}
@@ -1,21 +1,31 @@
 package admin
 
 import (
+	"crypto/subtle"
+	"net/http"
 	"time"
 
+	"github.com/bricks-cloud/bricksllm/internal/principal"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
 	"github.com/bricks-cloud/bricksllm/internal/util"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
-func getAdminLoggerMiddleware(log *zap.Logger, prefix string, prod bool, adminPass string) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		if len(adminPass) != 0 && c.Request.Header.Get("X-API-KEY") != adminPass {
-			c.Status(200)
-			c.Abort()
-			return
-		}
+const principalContextKey = "adminPrincipal"
+
+type PrincipalAuthenticator interface {
+	AuthenticatePrincipal(token string) (*principal.Principal, error)
+	HasPrincipals() (bool, error)
+}
 
+type authError interface {
+	Error() string
+	Authenticated()
+}
+
+func getAdminLoggerMiddleware(log *zap.Logger, prefix string, prod bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
 		cid := util.NewUuid()
 		c.Set(util.STRING_CORRELATION_ID, cid)
 		logWithCid := log.With(zap.String(util.STRING_CORRELATION_ID, cid))
@@ -38,3 +48,114 @@ func getAdminLoggerMiddleware(log *zap.Logger, prefix string, prod bool, adminPa
 		}
 	}
 }
+
+// getAuthenticationMiddleware resolves the principal of a request from the X-API-KEY
+// header. The admin pass authenticates as a super admin. Requests without a credential
+// are only let through as a super admin when neither the admin pass nor any principal
+// is configured.
+func getAuthenticationMiddleware(pa PrincipalAuthenticator, adminPass string, prod bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.FullPath() == "/api/health" {
+			return
+		}
+
+		log := util.GetLogFromCtx(c)
+		token := c.Request.Header.Get("X-API-KEY")
+
+		if len(token) == 0 {
+			if len(adminPass) == 0 {
+				has, err := pa.HasPrincipals()
+				if err != nil {
+					telemetry.Incr("bricksllm.admin.get_authentication_middleware.has_principals_error", nil, 1)
+					logError(log, "error when checking admin principals", prod, err)
+					c.AbortWithStatusJSON(http.StatusInternalServerError, &ErrorResponse{
+						Type:     "/errors/principal-manager",
+						Title:    "checking admin principals error",
+						Status:   http.StatusInternalServerError,
+						Detail:   err.Error(),
+						Instance: c.FullPath(),
+					})
+					return
+				}
+
+				if !has {
+					c.Set(principalContextKey, &principal.Principal{Name: "anonymous", Role: principal.RoleSuperAdmin})
+					return
+				}
+			}
+
+			telemetry.Incr("bricksllm.admin.get_authentication_middleware.missing_credential", nil, 1)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, &ErrorResponse{
+				Type:     "/errors/unauthorized",
+				Title:    "unauthorized error",
+				Status:   http.StatusUnauthorized,
+				Detail:   "X-API-KEY header is missing",
+				Instance: c.FullPath(),
+			})
+			return
+		}
+
+		if len(adminPass) != 0 && subtle.ConstantTimeCompare([]byte(token), []byte(adminPass)) == 1 {
+			c.Set(principalContextKey, &principal.Principal{Name: "admin pass", Role: principal.RoleSuperAdmin})
+			return
+		}
+
+		p, err := pa.AuthenticatePrincipal(token)
+		if err != nil {
+			if _, ok := err.(authError); ok {
+				telemetry.Incr("bricksllm.admin.get_authentication_middleware.invalid_credential", nil, 1)
+				c.AbortWithStatusJSON(http.StatusUnauthorized, &ErrorResponse{
+					Type:     "/errors/unauthorized",
+					Title:    "unauthorized error",
+					Status:   http.StatusUnauthorized,
+					Detail:   err.Error(),
+					Instance: c.FullPath(),
+				})
+				return
+			}
+
+			telemetry.Incr("bricksllm.admin.get_authentication_middleware.authenticate_principal_error", nil, 1)
+			logError(log, "error when authenticating an admin principal", prod, err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, &ErrorResponse{
+				Type:     "/errors/principal-manager",
+				Title:    "authenticating admin principal error",
+				Status:   http.StatusInternalServerError,
+				Detail:   err.Error(),
+				Instance: c.FullPath(),
+			})
+			return
+		}
+
+		c.Set(principalContextKey, p)
+	}
+}
+
+func getPrincipalFromCtx(c *gin.Context) *principal.Principal {
+	p, ok := c.Get(principalContextKey)
+	if !ok {
+		return nil
+	}
+
+	parsed, _ := p.(*principal.Principal)
+	return parsed
+}
+
+func requirePermission(perm principal.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p := getPrincipalFromCtx(c)
+		if p == nil || !p.Role.Can(perm) {
+			telemetry.Incr("bricksllm.admin.require_permission.forbidden", []string{
+				"permission:" + string(perm),
+			}, 1)
+
+			c.AbortWithStatusJSON(http.StatusForbidden, &ErrorResponse{
+				Type:     "/errors/forbidden",
+				Title:    "forbidden error",
+				Status:   http.StatusForbidden,
+				Detail:   "permission " + string(perm) + " is required",
+				Instance: c.FullPath(),
+			})
+			return
+		}
+	}
+}
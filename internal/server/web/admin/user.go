@@ -14,6 +14,7 @@ import (
 )
 
 type UserManager interface {
+	GetUser(id string) (*user.User, error)
 	GetUsers(tags, keyIds, userIds []string, offset int, limit int) ([]*user.User, error)
 	CreateUser(u *user.User) (*user.User, error)
 	UpdateUser(id string, uu *user.UpdateUser) (*user.User, error)
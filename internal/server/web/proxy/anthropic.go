@@ -24,6 +24,8 @@ type anthropicEstimator interface {
 	EstimateTotalCost(model string, promptTks, completionTks int) (float64, error)
 	EstimateCompletionCost(model string, tks int) (float64, error)
 	EstimatePromptCost(model string, tks int) (float64, error)
+	EstimateTotalCostWithCache(model string, usage anthropic.Usage) (float64, error)
+	EstimatePromptCostWithCache(model string, tks, cacheCreationTks, cacheReadTks int) (float64, error)
 	Count(input string) int
 	CountMessagesTokens(messages []anthropic.Message) int
 }
@@ -80,7 +82,7 @@ func getCompletionHandler(prod, private bool, client http.Client) gin.HandlerFun
 		}
 
 		start := time.Now()
-		res, err := client.Do(req)
+		res, err := doRequest(c, client, req)
 		if err != nil {
 			telemetry.Incr("bricksllm.proxy.get_completion_handler.http_client_error", nil, 1)
 
@@ -297,7 +299,7 @@ var (
 	eventContentBlockStop  = []byte("event: content_block_stop")
 )
 
-func getMessagesHandler(prod, private bool, client http.Client, e anthropicEstimator) gin.HandlerFunc {
+func getMessagesHandler(prod, private bool, client http.Client, e anthropicEstimator, ca cache, scanner Scanner, cd CustomPolicyDetector) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		log := util.GetLogFromCtx(c)
 		telemetry.Incr("bricksllm.proxy.get_messages_handler.requests", nil, 1)
@@ -307,6 +309,14 @@ func getMessagesHandler(prod, private bool, client http.Client, e anthropicEstim
 			return
 		}
 
+		cached := getCachedResponse(c, ca)
+		if len(cached) != 0 {
+			telemetry.Incr("bricksllm.proxy.get_messages_handler.cache_hit", nil, 1)
+			c.Set("provider", "cached")
+			c.Data(http.StatusOK, "application/json", cached)
+			return
+		}
+
 		ctx, cancel := context.WithTimeout(context.Background(), c.GetDuration("requestTimeout"))
 		defer cancel()
 
@@ -335,7 +345,7 @@ func getMessagesHandler(prod, private bool, client http.Client, e anthropicEstim
 		}
 
 		start := time.Now()
-		res, err := client.Do(req)
+		res, err := doRequest(c, client, req)
 		if err != nil {
 			telemetry.Incr("bricksllm.proxy.get_messages_handler.http_client_error", nil, 1)
 
@@ -381,8 +391,8 @@ func getMessagesHandler(prod, private bool, client http.Client, e anthropicEstim
 			if err == nil {
 				logCompletionResponse(log, bytes, prod, private)
 				completionTokens = completionRes.Usage.OutputTokens
-				promptTokens = completionRes.Usage.InputTokens
-				cost, err = e.EstimateTotalCost(model, promptTokens, completionTokens)
+				promptTokens = completionRes.Usage.PromptTokens()
+				cost, err = e.EstimateTotalCostWithCache(model, completionRes.Usage)
 				if err != nil {
 					telemetry.Incr("bricksllm.proxy.get_messages_handler.estimate_total_cost_error", nil, 1)
 					logError(log, "error when estimating anthropic cost", prod, err)
@@ -393,6 +403,21 @@ func getMessagesHandler(prod, private bool, client http.Client, e anthropicEstim
 			c.Set("promptTokenCount", promptTokens)
 			c.Set("completionTokenCount", completionTokens)
 
+			if p := getResponsePolicy(c); p != nil && len(completionRes.Content) != 0 {
+				filtered, ok := filterResponseWithPolicy(c, prod, log, "get_messages_handler", p, completionRes, bytes, scanner, cd)
+				if !ok {
+					return
+				}
+
+				bytes = filtered
+			}
+
+			err = storeCachedResponse(c, ca, bytes)
+			if err != nil {
+				telemetry.Incr("bricksllm.proxy.get_messages_handler.store_cached_response_error", nil, 1)
+				logError(log, "error when storing cached anthropic response", prod, err)
+			}
+
 			c.Data(res.StatusCode, "application/json", bytes)
 			return
 		}
@@ -431,7 +456,7 @@ func getMessagesHandler(prod, private bool, client http.Client, e anthropicEstim
 				logError(log, "error when estimating anthropic messages stream cost", prod, err)
 			}
 
-			estimatedPromptCost, err := e.EstimatePromptCost(model, response.Usage.InputTokens)
+			estimatedPromptCost, err := e.EstimatePromptCostWithCache(model, response.Usage.InputTokens, response.Usage.CacheCreationInputTokens, response.Usage.CacheReadInputTokens)
 			if err != nil {
 				telemetry.Incr("bricksllm.proxy.get_messages_handler.estimate_prompt_cost_error", nil, 1)
 				logError(log, "error when estimating anthropic prompt cost", prod, err)
@@ -440,12 +465,17 @@ func getMessagesHandler(prod, private bool, client http.Client, e anthropicEstim
 			totalCost = cost + estimatedPromptCost
 
 			c.Set("costInUsd", totalCost)
-			c.Set("promptTokenCount", response.Usage.InputTokens)
+			c.Set("promptTokenCount", response.Usage.PromptTokens())
 			c.Set("completionTokenCount", tks)
 		}()
 
 		telemetry.Incr("bricksllm.proxy.get_messages_handler.streaming_requests", nil, 1)
 
+		var sf *streamPolicyFilter
+		if p := getResponsePolicy(c); p != nil {
+			sf = newStreamPolicyFilter(c, p, scanner, cd, log)
+		}
+
 		eventName := ""
 		c.Stream(func(w io.Writer) bool {
 			raw, err := buffer.ReadBytes('\n')
@@ -536,7 +566,31 @@ func getMessagesHandler(prod, private bool, client http.Client, e anthropicEstim
 			}
 
 			noPrefixLine := bytes.TrimPrefix(noSpaceLine, headerData)
-			c.SSEvent(eventName, " "+string(noPrefixLine))
+
+			if sf != nil && eventName == " content_block_stop" {
+				flushed, err := sf.flushBlock(noPrefixLine)
+				if err != nil {
+					writeMessagesStreamResponseBlocked(c, "get_messages_handler")
+					return false
+				}
+
+				if len(flushed) != 0 {
+					c.SSEvent(" content_block_delta", " "+string(flushed))
+				}
+			}
+
+			forwarded := noPrefixLine
+			if sf != nil && eventName == " content_block_delta" {
+				filtered, err := sf.filterBlockDelta(noPrefixLine)
+				if err != nil {
+					writeMessagesStreamResponseBlocked(c, "get_messages_handler")
+					return false
+				}
+
+				forwarded = filtered
+			}
+
+			c.SSEvent(eventName, " "+string(forwarded))
 
 			if eventName == " message_start" {
 				messageStart := &anthropic.MessagesStreamMessageStart{}
@@ -548,6 +602,8 @@ func getMessagesHandler(prod, private bool, client http.Client, e anthropicEstim
 				}
 
 				response.Usage.InputTokens = messageStart.Message.Usage.InputTokens
+				response.Usage.CacheCreationInputTokens = messageStart.Message.Usage.CacheCreationInputTokens
+				response.Usage.CacheReadInputTokens = messageStart.Message.Usage.CacheReadInputTokens
 			}
 
 			if eventName == " message_delta" {
@@ -30,7 +30,7 @@ func buildAzureUrl(path, deploymentId, apiVersion, resourceName string) string {
 	return fmt.Sprintf("https://%s.openai.azure.com/openai/deployments/%s/embeddings?api-version=%s", resourceName, deploymentId, apiVersion)
 }
 
-func getAzureChatCompletionHandler(prod, private bool, client http.Client, aoe azureEstimator) gin.HandlerFunc {
+func getAzureChatCompletionHandler(prod, private bool, client http.Client, aoe azureEstimator, scanner Scanner, cd CustomPolicyDetector) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		log := util.GetLogFromCtx(c)
 		telemetry.Incr("bricksllm.proxy.get_azure_chat_completion_handler.requests", nil, 1)
@@ -60,7 +60,7 @@ func getAzureChatCompletionHandler(prod, private bool, client http.Client, aoe a
 		}
 
 		start := time.Now()
-		res, err := client.Do(req)
+		res, err := doRequest(c, client, req)
 		if err != nil {
 			telemetry.Incr("bricksllm.proxy.get_azure_chat_completion_handler.http_client_error", nil, 1)
 			logError(log, "error when sending chat completion http request to azure openai", prod, err)
@@ -128,6 +128,15 @@ func getAzureChatCompletionHandler(prod, private bool, client http.Client, aoe a
 			c.Set("promptTokenCount", chatRes.Usage.PromptTokens)
 			c.Set("completionTokenCount", chatRes.Usage.CompletionTokens)
 
+			if p := getResponsePolicy(c); p != nil && len(chatRes.Choices) != 0 {
+				filtered, ok := filterResponseWithPolicy(c, prod, log, "get_azure_chat_completion_handler", p, chatRes, bytes, scanner, cd)
+				if !ok {
+					return
+				}
+
+				bytes = filtered
+			}
+
 			c.Data(res.StatusCode, "application/json", bytes)
 			return
 		}
@@ -184,6 +193,11 @@ func getAzureChatCompletionHandler(prod, private bool, client http.Client, aoe a
 
 		telemetry.Incr("bricksllm.proxy.get_azure_chat_completion_handler.streaming_requests", nil, 1)
 
+		var sf *streamPolicyFilter
+		if p := getResponsePolicy(c); p != nil {
+			sf = newStreamPolicyFilter(c, p, scanner, cd, log)
+		}
+
 		c.Stream(func(w io.Writer) bool {
 			raw, err := buffer.ReadBytes('\n')
 			if err != nil {
@@ -225,7 +239,16 @@ func getAzureChatCompletionHandler(prod, private bool, client http.Client, aoe a
 			}
 
 			noPrefixLine := bytes.TrimPrefix(noSpaceLine, headerData)
-			c.SSEvent("", " "+string(noPrefixLine))
+
+			forwarded, err := sf.filterStreamLine(noPrefixLine)
+			if err != nil {
+				writeStreamResponseBlocked(c, "get_azure_chat_completion_handler")
+				return false
+			}
+
+			for _, line := range forwarded {
+				c.SSEvent("", " "+string(line))
+			}
 
 			if string(noPrefixLine) == "[DONE]" {
 				return false
@@ -96,7 +96,7 @@ func getAzureCompletionsHandler(prod, private bool, client http.Client, aoe azur
 		}
 
 		start := time.Now()
-		res, err := client.Do(req)
+		res, err := doRequest(c, client, req)
 		if err != nil {
 			telemetry.Incr("bricksllm.proxy.get_azure_completions_handler.http_client_error", nil, 1)
 			logError(log, "error when sending completions http request to azure openai", prod, err)
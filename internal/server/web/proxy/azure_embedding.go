@@ -45,7 +45,7 @@ func getAzureEmbeddingsHandler(prod, private bool, client http.Client, aoe azure
 
 		start := time.Now()
 
-		res, err := client.Do(req)
+		res, err := doRequest(c, client, req)
 		if err != nil {
 			telemetry.Incr("bricksllm.proxy.get_azure_embeddings_handler.http_client_error", nil, 1)
 
@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tidwall/gjson"
+)
+
+// batch output files are kept by openai for 30 days
+const batchOutputCostRecordTtl = 30 * 24 * time.Hour
+
+// setBatchOutputCost prices the requests in a batch output file with the batch discount. Output files
+// are only priced by the request that claims the record of the file so that downloading a file again,
+// even concurrently, is not billed twice.
+func setBatchOutputCost(c *gin.Context, ca cache, e estimator, data []byte) error {
+	var cost float64 = 0
+	promptTks := 0
+	completionTks := 0
+	model := ""
+
+	var estimationErr error
+	for _, line := range bytes.Split(data, []byte{'\n'}) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		if !gjson.ValidBytes(line) || !gjson.GetBytes(line, "custom_id").Exists() || !gjson.GetBytes(line, "response").Exists() {
+			return nil
+		}
+
+		if gjson.GetBytes(line, "response.status_code").Int() != http.StatusOK {
+			continue
+		}
+
+		body := gjson.GetBytes(line, "response.body")
+		usage := body.Get("usage")
+		lineModel := body.Get("model").String()
+		if len(model) == 0 {
+			model = lineModel
+		}
+
+		var lineCost float64
+		var err error
+		if body.Get("object").String() == "list" {
+			lineCost, err = e.EstimateBatchEmbeddingsCost(lineModel, int(usage.Get("prompt_tokens").Int()))
+		} else {
+			lineCost, err = e.EstimateBatchCost(lineModel, int(usage.Get("prompt_tokens").Int()), int(usage.Get("prompt_tokens_details.cached_tokens").Int()), int(usage.Get("completion_tokens").Int()))
+		}
+
+		if err != nil {
+			estimationErr = err
+		}
+
+		cost += lineCost
+		promptTks += int(usage.Get("prompt_tokens").Int())
+		completionTks += int(usage.Get("completion_tokens").Int())
+	}
+
+	claimed, err := ca.StoreBytesIfNotExists("batch_output_cost:"+c.Param("file_id"), []byte(time.Now().UTC().Format(time.RFC3339)), batchOutputCostRecordTtl)
+	if err != nil {
+		return err
+	}
+
+	if !claimed {
+		return nil
+	}
+
+	if len(model) != 0 {
+		c.Set("model", model)
+	}
+
+	c.Set("costInUsd", cost)
+	c.Set("promptTokenCount", promptTks)
+	c.Set("completionTokenCount", completionTks)
+
+	return estimationErr
+}
@@ -0,0 +1,108 @@
+package proxy
+
+import (
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/provider/openai"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCache struct {
+	lock   sync.Mutex
+	values map[string][]byte
+	err    error
+}
+
+func newFakeCache() *fakeCache {
+	return &fakeCache{values: map[string][]byte{}}
+}
+
+func (fc *fakeCache) StoreBytes(key string, value []byte, ttl time.Duration) error {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+
+	fc.values[key] = value
+	return fc.err
+}
+
+func (fc *fakeCache) StoreBytesIfNotExists(key string, value []byte, ttl time.Duration) (bool, error) {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+
+	if fc.err != nil {
+		return false, fc.err
+	}
+
+	if _, ok := fc.values[key]; ok {
+		return false, nil
+	}
+
+	fc.values[key] = value
+	return true, nil
+}
+
+func (fc *fakeCache) GetBytes(key string) ([]byte, error) {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+
+	return fc.values[key], fc.err
+}
+
+const testBatchOutput = `{"id":"batch_req_1","custom_id":"request-1","response":{"status_code":200,"body":{"object":"chat.completion","model":"gpt-4o-mini","usage":{"prompt_tokens":1000,"completion_tokens":1000}}}}
+{"id":"batch_req_2","custom_id":"request-2","response":{"status_code":200,"body":{"object":"chat.completion","model":"gpt-4o-mini","usage":{"prompt_tokens":1000,"completion_tokens":1000}}}}`
+
+func newTestBatchContext() *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Params = gin.Params{{Key: "file_id", Value: "file-abc"}}
+
+	return c
+}
+
+func TestSetBatchOutputCost_PricesFileOnce(t *testing.T) {
+	e := openai.NewCostEstimator(openai.OpenAiPerThousandTokenCost, nil, nil)
+	ca := newFakeCache()
+
+	contexts := []*gin.Context{}
+	for i := 0; i < 10; i++ {
+		contexts = append(contexts, newTestBatchContext())
+	}
+
+	var wg sync.WaitGroup
+	for _, c := range contexts {
+		wg.Add(1)
+		go func(c *gin.Context) {
+			defer wg.Done()
+			assert.NoError(t, setBatchOutputCost(c, ca, e, []byte(testBatchOutput)))
+		}(c)
+	}
+
+	wg.Wait()
+
+	billed := 0
+	for _, c := range contexts {
+		if c.GetFloat64("costInUsd") != 0 {
+			billed++
+			assert.Equal(t, 2000, c.GetInt("promptTokenCount"))
+			assert.Equal(t, 2000, c.GetInt("completionTokenCount"))
+		}
+	}
+
+	assert.Equal(t, 1, billed)
+}
+
+func TestSetBatchOutputCost_ReturnsCacheError(t *testing.T) {
+	e := openai.NewCostEstimator(openai.OpenAiPerThousandTokenCost, nil, nil)
+	ca := newFakeCache()
+	ca.err = errors.New("redis is unavailable")
+
+	c := newTestBatchContext()
+	err := setBatchOutputCost(c, ca, e, []byte(testBatchOutput))
+	require.Error(t, err)
+	assert.Zero(t, c.GetFloat64("costInUsd"))
+}
@@ -393,12 +393,12 @@ func getBedrockMessagesHandler(prod bool, e anthropicEstimator) gin.HandlerFunc
 
 			if err == nil {
 				completionTokens = messagesRes.Usage.OutputTokens
-				promptTokens = messagesRes.Usage.InputTokens
+				promptTokens = messagesRes.Usage.PromptTokens()
 
 				model := c.GetString("model")
 				translated := util.TranslateBedrockModelToAnthropicModel(model)
 
-				cost, err = e.EstimateTotalCost(translated, promptTokens, completionTokens)
+				cost, err = e.EstimateTotalCostWithCache(translated, messagesRes.Usage)
 				if err != nil {
 					telemetry.Incr("bricksllm.proxy.get_bedrock_messages_handler.estimate_total_cost_error", nil, 1)
 					logError(log, "error when estimating anthropic cost", prod, err)
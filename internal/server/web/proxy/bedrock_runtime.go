@@ -0,0 +1,361 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/bricks-cloud/bricksllm/internal/provider"
+	"github.com/bricks-cloud/bricksllm/internal/provider/bedrock"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+	"github.com/bricks-cloud/bricksllm/internal/util"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type bedrockEstimator interface {
+	EstimateTotalCost(model string, promptTks, completionTks int) (float64, error)
+	EstimateEmbeddingsCost(model string, tks int) (float64, error)
+	CountContents(contents []*string) int
+}
+
+func createBedrockRuntimeHttpRequest(ctx context.Context, c *gin.Context, model, action string, data []byte) (*http.Request, error) {
+	keyId := c.GetString("awsAccessKeyId")
+	secretKey := c.GetString("awsSecretAccessKey")
+	region := c.GetString("awsRegion")
+
+	if len(keyId) == 0 || len(secretKey) == 0 || len(region) == 0 {
+		return nil, errors.New("key id, secret key or region is missing")
+	}
+
+	u := fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com/model/%s/%s", region, strings.ReplaceAll(url.PathEscape(model), ":", "%3A"), action)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	req.ContentLength = int64(len(data))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if strings.HasSuffix(action, "stream") {
+		req.Header.Set("Accept", "application/vnd.amazon.eventstream")
+	}
+
+	if !c.GetBool("removeUserAgent") && len(c.Request.UserAgent()) != 0 {
+		req.Header.Set("User-Agent", c.Request.UserAgent())
+	}
+
+	hash := sha256.Sum256(data)
+	creds := aws.Credentials{
+		AccessKeyID:     keyId,
+		SecretAccessKey: secretKey,
+		Source:          "BricksLLM Credentials",
+	}
+
+	err = v4.NewSigner().SignHTTP(ctx, creds, req, hex.EncodeToString(hash[:]), "bedrock", region, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+func getEventStreamHeader(msg eventstream.Message, name string) string {
+	val := msg.Headers.Get(name)
+	if val == nil {
+		return ""
+	}
+
+	return val.String()
+}
+
+func estimateBedrockRuntimeCost(c *gin.Context, log *zap.Logger, prod bool, e bedrockEstimator, ae anthropicEstimator, model string, promptTks, completionTks int) float64 {
+	family := bedrock.GetFamily(model)
+
+	var cost float64 = 0
+	var err error
+	if family == bedrock.FamilyAnthropic {
+		cost, err = ae.EstimateTotalCost(util.TranslateBedrockModelToAnthropicModel(bedrock.NormalizeModel(model)), promptTks, completionTks)
+	} else if family.IsEmbedding() {
+		cost, err = e.EstimateEmbeddingsCost(model, promptTks)
+	} else {
+		cost, err = e.EstimateTotalCost(model, promptTks, completionTks)
+	}
+
+	if err != nil {
+		telemetry.Incr("bricksllm.proxy.estimate_bedrock_runtime_cost.estimate_cost_error", nil, 1)
+		logError(log, "error when estimating bedrock cost", prod, err)
+	}
+
+	m, exists := c.Get("cost_map")
+	if exists {
+		converted, ok := m.(*provider.CostMap)
+		if ok {
+			var newCost float64 = 0
+			if family.IsEmbedding() {
+				newCost, err = provider.EstimateCostWithCostMap(model, promptTks, 1000, converted.EmbeddingsCostPerModel)
+			} else {
+				newCost, err = provider.EstimateTotalCostWithCostMaps(model, promptTks, completionTks, 1000, converted.PromptCostPerModel, converted.CompletionCostPerModel)
+			}
+
+			if err != nil {
+				telemetry.Incr("bricksllm.proxy.estimate_bedrock_runtime_cost.estimate_cost_with_cost_maps_error", nil, 1)
+				logError(log, "error when estimating bedrock cost with cost maps", prod, err)
+			}
+
+			if newCost != 0 {
+				cost = newCost
+			}
+		}
+	}
+
+	return cost
+}
+
+// getBedrockRuntimeHandler forwards requests to the converse and invoke model apis of
+// bedrock runtime. Usage is read from the converse response or from the token
+// count headers and invocation metrics of invoke model responses.
+func getBedrockRuntimeHandler(prod bool, client http.Client, action string, e bedrockEstimator, ae anthropicEstimator) gin.HandlerFunc {
+	isConverse := strings.HasPrefix(action, "converse")
+	isStreaming := strings.HasSuffix(action, "stream")
+
+	return func(c *gin.Context) {
+		log := util.GetLogFromCtx(c)
+		telemetry.Incr("bricksllm.proxy.get_bedrock_runtime_handler.requests", []string{"action:" + action}, 1)
+
+		if c == nil || c.Request == nil {
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] context is empty")
+			return
+		}
+
+		model := c.Param("model_id")
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			telemetry.Incr("bricksllm.proxy.get_bedrock_runtime_handler.read_all_error", nil, 1)
+			logError(log, "error when reading bedrock request body", prod, err)
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to read bedrock request body")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), c.GetDuration("requestTimeout"))
+		defer cancel()
+
+		req, err := createBedrockRuntimeHttpRequest(ctx, c, model, action, body)
+		if err != nil {
+			telemetry.Incr("bricksllm.proxy.get_bedrock_runtime_handler.create_http_request_error", nil, 1)
+			logError(log, "error when creating bedrock http request", prod, err)
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to create bedrock http request")
+			return
+		}
+
+		start := time.Now()
+		res, err := doRequest(c, client, req)
+		if err != nil {
+			telemetry.Incr("bricksllm.proxy.get_bedrock_runtime_handler.http_client_error", nil, 1)
+
+			logError(log, "error when sending http request to bedrock", prod, err)
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to send http request to bedrock")
+			return
+		}
+
+		defer res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			telemetry.Timing("bricksllm.proxy.get_bedrock_runtime_handler.error_latency", time.Since(start), nil, 1)
+			telemetry.Incr("bricksllm.proxy.get_bedrock_runtime_handler.error_response", nil, 1)
+
+			bytes, err := io.ReadAll(res.Body)
+			if err != nil {
+				logError(log, "error when reading bedrock http response body", prod, err)
+				JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to read bedrock response body")
+				return
+			}
+
+			if prod {
+				log.Info("bedrock error response", zap.Int("status", res.StatusCode), zap.String("type", res.Header.Get("X-Amzn-ErrorType")), zap.String("message", string(bytes)))
+			}
+
+			c.Data(res.StatusCode, "application/json", bytes)
+			return
+		}
+
+		promptTokenCount := 0
+		completionTokenCount := 0
+		content := ""
+
+		if !isStreaming {
+			telemetry.Timing("bricksllm.proxy.get_bedrock_runtime_handler.latency", time.Since(start), nil, 1)
+
+			bytes, err := io.ReadAll(res.Body)
+			if err != nil {
+				logError(log, "error when reading bedrock http response body", prod, err)
+				JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to read bedrock response body")
+				return
+			}
+
+			telemetry.Incr("bricksllm.proxy.get_bedrock_runtime_handler.success", nil, 1)
+			telemetry.Timing("bricksllm.proxy.get_bedrock_runtime_handler.success_latency", time.Since(start), nil, 1)
+
+			if isConverse {
+				cr := &bedrock.ConverseResponse{}
+				err = json.Unmarshal(bytes, cr)
+				if err != nil {
+					logError(log, "error when unmarshalling bedrock converse response body", prod, err)
+				}
+
+				content = cr.Text()
+				if cr.Usage != nil {
+					promptTokenCount = cr.Usage.InputTokens
+					completionTokenCount = cr.Usage.OutputTokens
+				}
+			}
+
+			if !isConverse {
+				content, promptTokenCount, completionTokenCount, err = bedrock.ParseInvokeResponse(model, bytes)
+				if err != nil {
+					logError(log, "error when unmarshalling bedrock invoke model response body", prod, err)
+				}
+
+				if count, err := strconv.Atoi(res.Header.Get("X-Amzn-Bedrock-Input-Token-Count")); err == nil {
+					promptTokenCount = count
+				}
+
+				if count, err := strconv.Atoi(res.Header.Get("X-Amzn-Bedrock-Output-Token-Count")); err == nil {
+					completionTokenCount = count
+				}
+			}
+
+			c.Set("content", content)
+			c.Set("promptTokenCount", promptTokenCount)
+			c.Set("completionTokenCount", completionTokenCount)
+			c.Set("costInUsd", estimateBedrockRuntimeCost(c, log, prod, e, ae, model, promptTokenCount, completionTokenCount))
+
+			c.Data(res.StatusCode, "application/json", bytes)
+			return
+		}
+
+		streamingResponse := [][]byte{}
+		defer func() {
+			c.Set("content", content)
+			c.Set("promptTokenCount", promptTokenCount)
+			c.Set("completionTokenCount", completionTokenCount)
+			c.Set("costInUsd", estimateBedrockRuntimeCost(c, log, prod, e, ae, model, promptTokenCount, completionTokenCount))
+			c.Set("streaming_response", bytes.Join(streamingResponse, []byte{'\n'}))
+		}()
+
+		telemetry.Incr("bricksllm.proxy.get_bedrock_runtime_handler.streaming_requests", nil, 1)
+
+		decoder := eventstream.NewDecoder()
+		payloadBuf := make([]byte, 10*1024)
+
+		c.Stream(func(w io.Writer) bool {
+			msg, err := decoder.Decode(res.Body, payloadBuf)
+			if err != nil {
+				if err == io.EOF {
+					return false
+				}
+
+				if errors.Is(err, context.DeadlineExceeded) {
+					telemetry.Incr("bricksllm.proxy.get_bedrock_runtime_handler.context_deadline_exceeded_error", nil, 1)
+					logError(log, "context deadline exceeded when reading bedrock streaming response", prod, err)
+
+					return false
+				}
+
+				telemetry.Incr("bricksllm.proxy.get_bedrock_runtime_handler.decode_error", nil, 1)
+				logError(log, "error when decoding bedrock streaming response", prod, err)
+				return false
+			}
+
+			if getEventStreamHeader(msg, ":message-type") == "exception" {
+				telemetry.Incr("bricksllm.proxy.get_bedrock_runtime_handler.stream_exception", []string{"type:" + getEventStreamHeader(msg, ":exception-type")}, 1)
+				c.SSEvent(" error", " "+string(msg.Payload))
+				return false
+			}
+
+			eventType := getEventStreamHeader(msg, ":event-type")
+
+			if isConverse {
+				data := string(msg.Payload)
+				streamingResponse = append(streamingResponse, []byte(data))
+
+				if eventType == "contentBlockDelta" {
+					delta := &bedrock.ConverseStreamContentBlockDelta{}
+					if err := json.Unmarshal(msg.Payload, delta); err == nil && delta.Delta != nil {
+						content += delta.Delta.Text
+					}
+				}
+
+				if eventType == "metadata" {
+					metadata := &bedrock.ConverseStreamMetadata{}
+					if err := json.Unmarshal(msg.Payload, metadata); err == nil && metadata.Usage != nil {
+						promptTokenCount = metadata.Usage.InputTokens
+						completionTokenCount = metadata.Usage.OutputTokens
+					}
+				}
+
+				c.SSEvent(" "+eventType, " "+data)
+				return true
+			}
+
+			if eventType != "chunk" {
+				return true
+			}
+
+			encoded := &struct {
+				Bytes string `json:"bytes"`
+			}{}
+
+			err = json.Unmarshal(msg.Payload, encoded)
+			if err != nil {
+				telemetry.Incr("bricksllm.proxy.get_bedrock_runtime_handler.unmarshal_chunk_error", nil, 1)
+				logError(log, "error when unmarshalling bedrock streaming response chunk", prod, err)
+				return false
+			}
+
+			decoded, err := base64.StdEncoding.DecodeString(encoded.Bytes)
+			if err != nil {
+				telemetry.Incr("bricksllm.proxy.get_bedrock_runtime_handler.decode_chunk_error", nil, 1)
+				logError(log, "error when decoding bedrock streaming response chunk", prod, err)
+				return false
+			}
+
+			streamingResponse = append(streamingResponse, decoded)
+
+			chunk := &bedrock.StreamChunk{}
+			err = json.Unmarshal(decoded, chunk)
+			if err != nil {
+				telemetry.Incr("bricksllm.proxy.get_bedrock_runtime_handler.unmarshal_stream_chunk_error", nil, 1)
+				logError(log, "error when unmarshalling bedrock streaming response chunk", prod, err)
+			}
+
+			if err == nil {
+				content += chunk.GeneratedText()
+				if chunk.Metrics != nil {
+					promptTokenCount = chunk.Metrics.InputTokenCount
+					completionTokenCount = chunk.Metrics.OutputTokenCount
+				}
+			}
+
+			c.SSEvent("", " "+string(decoded))
+			return true
+		})
+
+		telemetry.Timing("bricksllm.proxy.get_bedrock_runtime_handler.streaming_latency", time.Since(start), nil, 1)
+	}
+}
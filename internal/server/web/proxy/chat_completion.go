@@ -17,7 +17,7 @@ import (
 	goopenai "github.com/sashabaranov/go-openai"
 )
 
-func getChatCompletionHandler(prod, private bool, client http.Client, e estimator) gin.HandlerFunc {
+func getChatCompletionHandler(prod, private bool, client http.Client, e estimator, ca cache, scanner Scanner, cd CustomPolicyDetector) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		log := util.GetLogFromCtx(c)
 		telemetry.Incr("bricksllm.proxy.get_chat_completion_handler.requests", nil, 1)
@@ -27,6 +27,14 @@ func getChatCompletionHandler(prod, private bool, client http.Client, e estimato
 			return
 		}
 
+		cached := getCachedResponse(c, ca)
+		if len(cached) != 0 {
+			telemetry.Incr("bricksllm.proxy.get_chat_completion_handler.cache_hit", nil, 1)
+			c.Set("provider", "cached")
+			c.Data(http.StatusOK, "application/json", cached)
+			return
+		}
+
 		ctx, cancel := context.WithTimeout(context.Background(), c.GetDuration("requestTimeout"))
 		defer cancel()
 
@@ -47,7 +55,7 @@ func getChatCompletionHandler(prod, private bool, client http.Client, e estimato
 		}
 
 		start := time.Now()
-		res, err := client.Do(req)
+		res, err := doRequest(c, client, req)
 		if err != nil {
 			telemetry.Incr("bricksllm.proxy.get_chat_completion_handler.http_client_error", nil, 1)
 
@@ -89,7 +97,7 @@ func getChatCompletionHandler(prod, private bool, client http.Client, e estimato
 
 			if err == nil {
 				logChatCompletionResponse(log, prod, private, chatRes)
-				cost, err = e.EstimateTotalCost(model, chatRes.Usage.PromptTokens, chatRes.Usage.CompletionTokens)
+				cost, err = e.EstimateTotalCostWithCachedTokens(model, chatRes.Usage.PromptTokens, getCachedTokens(&chatRes.Usage), chatRes.Usage.CompletionTokens)
 				if err != nil {
 					telemetry.Incr("bricksllm.proxy.get_chat_completion_handler.estimate_total_cost_error", nil, 1)
 					logError(log, "error when estimating openai cost", prod, err)
@@ -116,6 +124,21 @@ func getChatCompletionHandler(prod, private bool, client http.Client, e estimato
 			c.Set("promptTokenCount", chatRes.Usage.PromptTokens)
 			c.Set("completionTokenCount", chatRes.Usage.CompletionTokens)
 
+			if p := getResponsePolicy(c); p != nil && err == nil {
+				filtered, ok := filterResponseWithPolicy(c, prod, log, "get_chat_completion_handler", p, chatRes, bytes, scanner, cd)
+				if !ok {
+					return
+				}
+
+				bytes = filtered
+			}
+
+			err = storeCachedResponse(c, ca, bytes)
+			if err != nil {
+				telemetry.Incr("bricksllm.proxy.get_chat_completion_handler.store_cached_response_error", nil, 1)
+				logError(log, "error when storing cached openai response", prod, err)
+			}
+
 			c.Data(res.StatusCode, "application/json", bytes)
 			return
 		}
@@ -155,6 +178,11 @@ func getChatCompletionHandler(prod, private bool, client http.Client, e estimato
 
 		telemetry.Incr("bricksllm.proxy.get_chat_completion_handler.streaming_requests", nil, 1)
 
+		var sf *streamPolicyFilter
+		if p := getResponsePolicy(c); p != nil {
+			sf = newStreamPolicyFilter(c, p, scanner, cd, log)
+		}
+
 		c.Stream(func(w io.Writer) bool {
 			raw, err := buffer.ReadBytes('\n')
 			if err != nil {
@@ -199,7 +227,16 @@ func getChatCompletionHandler(prod, private bool, client http.Client, e estimato
 			}
 
 			noPrefixLine := bytes.TrimPrefix(noSpaceLine, headerData)
-			c.SSEvent("", " "+string(noPrefixLine))
+
+			forwarded, err := sf.filterStreamLine(noPrefixLine)
+			if err != nil {
+				writeStreamResponseBlocked(c, "get_chat_completion_handler")
+				return false
+			}
+
+			for _, line := range forwarded {
+				c.SSEvent("", " "+string(line))
+			}
 
 			if string(noPrefixLine) == "[DONE]" {
 				return false
@@ -224,3 +261,11 @@ func getChatCompletionHandler(prod, private bool, client http.Client, e estimato
 		telemetry.Timing("bricksllm.proxy.get_chat_completion_handler.streaming_latency", time.Since(start), nil, 1)
 	}
 }
+
+func getCachedTokens(usage *goopenai.Usage) int {
+	if usage == nil || usage.PromptTokensDetails == nil {
+		return 0
+	}
+
+	return usage.PromptTokensDetails.CachedTokens
+}
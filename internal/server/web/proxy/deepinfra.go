@@ -46,7 +46,7 @@ func getDeepinfraCompletionsHandler(prod, private bool, client http.Client) gin.
 		}
 
 		start := time.Now()
-		res, err := client.Do(req)
+		res, err := doRequest(c, client, req)
 		if err != nil {
 			telemetry.Incr("bricksllm.proxy.get_deepinfra_completions_handler.http_client_error", nil, 1)
 
@@ -250,7 +250,7 @@ func getDeepinfraChatCompletionsHandler(prod, private bool, client http.Client)
 		}
 
 		start := time.Now()
-		res, err := client.Do(req)
+		res, err := doRequest(c, client, req)
 		if err != nil {
 			telemetry.Incr("bricksllm.proxy.get_deepinfra_chat_completions_handler.http_client_error", nil, 1)
 
@@ -437,7 +437,7 @@ func getDeepinfraEmbeddingsHandler(prod, private bool, client http.Client, e dee
 
 		start := time.Now()
 
-		res, err := client.Do(req)
+		res, err := doRequest(c, client, req)
 		if err != nil {
 			telemetry.Incr("bricksllm.proxy.get_deepinfra_embeddings_handler.http_client_error", nil, 1)
 
@@ -61,7 +61,7 @@ func getEmbeddingHandler(prod, private bool, client http.Client, e estimator) gi
 
 		start := time.Now()
 
-		res, err := client.Do(req)
+		res, err := doRequest(c, client, req)
 		if err != nil {
 			telemetry.Incr("bricksllm.proxy.get_embedding_handler.http_client_error", nil, 1)
 
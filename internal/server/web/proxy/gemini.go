@@ -0,0 +1,478 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/provider"
+	"github.com/bricks-cloud/bricksllm/internal/provider/gemini"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+	"github.com/bricks-cloud/bricksllm/internal/util"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type geminiEstimator interface {
+	EstimateTotalCost(model string, promptTks, completionTks int) (float64, error)
+	EstimateEmbeddingsCost(model string, tks int) (float64, error)
+	Count(input string) int
+	CountPartsTokens(parts []*gemini.Part) int
+}
+
+type vertexTokenSource interface {
+	Token(credentials string) (string, error)
+}
+
+func isVertex(c *gin.Context) bool {
+	return len(c.GetString("vertexProjectId")) != 0
+}
+
+// getGeminiUrl returns the upstream url of a gemini resource such as
+// models/gemini-1.5-flash:generateContent. Requests made with a vertex setting are
+// sent to the vertex ai endpoint of the project.
+func getGeminiUrl(c *gin.Context, version, resource string) string {
+	u := fmt.Sprintf("https://generativelanguage.googleapis.com/%s/%s", version, resource)
+
+	if isVertex(c) {
+		if version == "v1beta" {
+			version = "v1beta1"
+		}
+
+		location := c.GetString("vertexLocation")
+		u = fmt.Sprintf("https://%s-aiplatform.googleapis.com/%s/projects/%s/locations/%s/publishers/google/%s", location, version, c.GetString("vertexProjectId"), location, resource)
+	}
+
+	// the key query param carries the bricksllm key and must not be forwarded.
+	if alt := c.Query("alt"); len(alt) != 0 {
+		u += "?" + url.Values{"alt": []string{alt}}.Encode()
+	}
+
+	return u
+}
+
+// setGeminiModelParam replaces the model of the path of a gemini request so that
+// handlers forward the request to the model an alias resolves to.
+func setGeminiModelParam(c *gin.Context, model, action string) {
+	value := model
+	if len(action) != 0 {
+		value += ":" + action
+	}
+
+	for i, p := range c.Params {
+		if p.Key == "model" {
+			c.Params[i].Value = value
+		}
+	}
+}
+
+func setGeminiAuthHeaders(c *gin.Context, req *http.Request, vts vertexTokenSource) error {
+	// the response body is parsed, so let the http client handle its encoding.
+	req.Header.Del("Accept-Encoding")
+	req.Header.Del("x-api-key")
+	req.Header.Del("api-key")
+
+	if !isVertex(c) {
+		req.Header.Del("Authorization")
+		return nil
+	}
+
+	req.Header.Del("x-goog-api-key")
+
+	token, err := vts.Token(c.GetString("vertexCredentials"))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	return nil
+}
+
+func logGeminiErrorResponse(log *zap.Logger, data []byte, prod bool) {
+	errorRes := &gemini.ErrorResponse{}
+	err := json.Unmarshal(data, errorRes)
+	if err != nil {
+		logError(log, "error when unmarshalling gemini error response body", prod, err)
+		return
+	}
+
+	if prod && errorRes.Error != nil {
+		log.Info("gemini error response", zap.Int("code", errorRes.Error.Code), zap.String("status", errorRes.Error.Status), zap.String("message", errorRes.Error.Message))
+	}
+}
+
+func getGeminiModelsHandler(prod bool, client http.Client, version string, vts vertexTokenSource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := util.GetLogFromCtx(c)
+		telemetry.Incr("bricksllm.proxy.get_gemini_models_handler.requests", nil, 1)
+
+		if c == nil || c.Request == nil {
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] context is empty")
+			return
+		}
+
+		if isVertex(c) {
+			JSON(c, http.StatusBadRequest, "[BricksLLM] listing models is not supported for vertex ai settings")
+			return
+		}
+
+		resource := "models"
+		if model := strings.TrimPrefix(c.Param("model"), "/"); len(model) != 0 {
+			resource += "/" + model
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), c.GetDuration("requestTimeout"))
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, getGeminiUrl(c, version, resource), nil)
+		if err != nil {
+			logError(log, "error when creating gemini http request", prod, err)
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to create gemini http request")
+			return
+		}
+
+		copyHttpHeaders(c.Request, req, c.GetBool("removeUserAgent"))
+
+		err = setGeminiAuthHeaders(c, req, vts)
+		if err != nil {
+			logError(log, "error when setting gemini auth headers", prod, err)
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to authenticate with gemini")
+			return
+		}
+
+		res, err := doRequest(c, client, req)
+		if err != nil {
+			telemetry.Incr("bricksllm.proxy.get_gemini_models_handler.http_client_error", nil, 1)
+
+			logError(log, "error when sending http request to gemini", prod, err)
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to send http request to gemini")
+			return
+		}
+
+		defer res.Body.Close()
+
+		bytes, err := io.ReadAll(res.Body)
+		if err != nil {
+			logError(log, "error when reading gemini models response body", prod, err)
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to read gemini response body")
+			return
+		}
+
+		if res.StatusCode != http.StatusOK {
+			telemetry.Incr("bricksllm.proxy.get_gemini_models_handler.error_response", nil, 1)
+			logGeminiErrorResponse(log, bytes, prod)
+		}
+
+		for name, values := range res.Header {
+			for _, value := range values {
+				c.Header(name, value)
+			}
+		}
+
+		c.Data(res.StatusCode, "application/json", bytes)
+	}
+}
+
+func getGeminiHandler(prod, private bool, client http.Client, version string, e geminiEstimator, vts vertexTokenSource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		log := util.GetLogFromCtx(c)
+		telemetry.Incr("bricksllm.proxy.get_gemini_handler.requests", nil, 1)
+
+		if c == nil || c.Request == nil {
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] context is empty")
+			return
+		}
+
+		model, action := gemini.ParseModelAction(c.Param("model"))
+		if action != "generateContent" && action != "streamGenerateContent" && action != "countTokens" && action != "embedContent" && action != "batchEmbedContents" {
+			JSON(c, http.StatusNotFound, fmt.Sprintf("[BricksLLM] gemini action %s is not supported", action))
+			return
+		}
+
+		isEmbedding := action == "embedContent" || action == "batchEmbedContents"
+		if isEmbedding && isVertex(c) {
+			JSON(c, http.StatusBadRequest, fmt.Sprintf("[BricksLLM] gemini action %s is not supported for vertex ai settings", action))
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			logError(log, "error when reading gemini request body", prod, err)
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to read gemini request body")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), c.GetDuration("requestTimeout"))
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, getGeminiUrl(c, version, "models/"+model+":"+action), bytes.NewReader(body))
+		if err != nil {
+			logError(log, "error when creating gemini http request", prod, err)
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to create gemini http request")
+			return
+		}
+
+		copyHttpHeaders(c.Request, req, c.GetBool("removeUserAgent"))
+
+		err = setGeminiAuthHeaders(c, req, vts)
+		if err != nil {
+			logError(log, "error when setting gemini auth headers", prod, err)
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to authenticate with gemini")
+			return
+		}
+
+		isStreaming := action == "streamGenerateContent"
+		isSse := c.Query("alt") == "sse"
+		if isStreaming && isSse {
+			req.Header.Set("Accept", "text/event-stream")
+			req.Header.Set("Cache-Control", "no-cache")
+			req.Header.Set("Connection", "keep-alive")
+		}
+
+		start := time.Now()
+		res, err := doRequest(c, client, req)
+		if err != nil {
+			telemetry.Incr("bricksllm.proxy.get_gemini_handler.http_client_error", nil, 1)
+
+			logError(log, "error when sending http request to gemini", prod, err)
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to send http request to gemini")
+			return
+		}
+
+		defer res.Body.Close()
+
+		for name, values := range res.Header {
+			for _, value := range values {
+				c.Header(name, value)
+			}
+		}
+
+		if res.StatusCode != http.StatusOK {
+			dur := time.Since(start)
+			telemetry.Timing("bricksllm.proxy.get_gemini_handler.error_latency", dur, nil, 1)
+			telemetry.Incr("bricksllm.proxy.get_gemini_handler.error_response", nil, 1)
+
+			bytes, err := io.ReadAll(res.Body)
+			if err != nil {
+				logError(log, "error when reading gemini http response body", prod, err)
+				JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to read gemini response body")
+				return
+			}
+
+			logGeminiErrorResponse(log, bytes, prod)
+			c.Data(res.StatusCode, "application/json", bytes)
+			return
+		}
+
+		if !isStreaming {
+			dur := time.Since(start)
+			telemetry.Timing("bricksllm.proxy.get_gemini_handler.latency", dur, nil, 1)
+
+			bytes, err := io.ReadAll(res.Body)
+			if err != nil {
+				logError(log, "error when reading gemini http response body", prod, err)
+				JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to read gemini response body")
+				return
+			}
+
+			telemetry.Incr("bricksllm.proxy.get_gemini_handler.success", nil, 1)
+			telemetry.Timing("bricksllm.proxy.get_gemini_handler.success_latency", dur, nil, 1)
+
+			if action == "generateContent" {
+				gr := &gemini.GenerateContentResponse{}
+				err = json.Unmarshal(bytes, gr)
+				if err != nil {
+					logError(log, "error when unmarshalling gemini generate content response body", prod, err)
+				}
+
+				if err == nil {
+					c.Set("content", gr.Text())
+					setGeminiUsage(c, log, prod, e, model, gr.UsageMetadata)
+				}
+			}
+
+			if isEmbedding {
+				setGeminiEmbeddingsUsage(c, log, prod, e, model, action, body)
+			}
+
+			c.Data(res.StatusCode, "application/json", bytes)
+			return
+		}
+
+		buffer := bufio.NewReader(res.Body)
+		content := ""
+		streamingResponse := [][]byte{}
+		var usage *gemini.UsageMetadata
+		defer func() {
+			// streams without the sse alt are a json array that is sent in chunks.
+			if !isSse {
+				responses := []*gemini.GenerateContentResponse{}
+				err := json.Unmarshal(bytes.Join(streamingResponse, []byte{}), &responses)
+				if err != nil {
+					telemetry.Incr("bricksllm.proxy.get_gemini_handler.stream_response_unmarshal_error", nil, 1)
+					logError(log, "error when unmarshalling gemini stream response", prod, err)
+				}
+
+				for _, gr := range responses {
+					content += gr.Text()
+					if gr.UsageMetadata != nil {
+						usage = gr.UsageMetadata
+					}
+				}
+			}
+
+			c.Set("content", content)
+			c.Set("streaming_response", bytes.Join(streamingResponse, []byte{'\n'}))
+			setGeminiUsage(c, log, prod, e, model, usage)
+		}()
+
+		telemetry.Incr("bricksllm.proxy.get_gemini_handler.streaming_requests", nil, 1)
+
+		c.Stream(func(w io.Writer) bool {
+			raw, err := buffer.ReadBytes('\n')
+			if len(raw) != 0 {
+				streamingResponse = append(streamingResponse, raw)
+
+				if _, err := w.Write(raw); err != nil {
+					telemetry.Incr("bricksllm.proxy.get_gemini_handler.write_error", nil, 1)
+					logError(log, "error when writing gemini streaming response", prod, err)
+					return false
+				}
+
+				noSpaceLine := bytes.TrimSpace(raw)
+				if isSse && bytes.HasPrefix(noSpaceLine, headerData) {
+					gr := &gemini.GenerateContentResponse{}
+					err := json.Unmarshal(bytes.TrimPrefix(noSpaceLine, headerData), gr)
+					if err != nil {
+						telemetry.Incr("bricksllm.proxy.get_gemini_handler.stream_response_unmarshal_error", nil, 1)
+						logError(log, "error when unmarshalling gemini stream response", prod, err)
+					}
+
+					if err == nil {
+						content += gr.Text()
+						if gr.UsageMetadata != nil {
+							usage = gr.UsageMetadata
+						}
+					}
+				}
+			}
+
+			if err != nil {
+				if err == io.EOF {
+					return false
+				}
+
+				if errors.Is(err, context.DeadlineExceeded) {
+					telemetry.Incr("bricksllm.proxy.get_gemini_handler.context_deadline_exceeded_error", nil, 1)
+					logError(log, "context deadline exceeded when reading bytes from gemini streaming response", prod, err)
+
+					return false
+				}
+
+				telemetry.Incr("bricksllm.proxy.get_gemini_handler.read_bytes_error", nil, 1)
+				logError(log, "error when reading bytes from gemini streaming response", prod, err)
+				return false
+			}
+
+			return true
+		})
+
+		telemetry.Timing("bricksllm.proxy.get_gemini_handler.streaming_latency", time.Since(start), nil, 1)
+	}
+}
+
+func setGeminiUsage(c *gin.Context, log *zap.Logger, prod bool, e geminiEstimator, model string, usage *gemini.UsageMetadata) {
+	if usage == nil {
+		return
+	}
+
+	promptTks, completionTks := usage.PromptTokenCount, usage.CompletionTokenCount()
+
+	cost, err := e.EstimateTotalCost(model, promptTks, completionTks)
+	if err != nil {
+		telemetry.Incr("bricksllm.proxy.set_gemini_usage.estimate_total_cost_error", nil, 1)
+		logError(log, "error when estimating gemini cost", prod, err)
+	}
+
+	m, exists := c.Get("cost_map")
+	if exists {
+		converted, ok := m.(*provider.CostMap)
+		if ok {
+			newCost, err := provider.EstimateTotalCostWithCostMaps(model, promptTks, completionTks, 1000, converted.PromptCostPerModel, converted.CompletionCostPerModel)
+			if err != nil {
+				telemetry.Incr("bricksllm.proxy.set_gemini_usage.estimate_total_cost_with_cost_maps_error", nil, 1)
+				logError(log, "error when estimating gemini total cost with cost maps", prod, err)
+			}
+
+			if newCost != 0 {
+				cost = newCost
+			}
+		}
+	}
+
+	c.Set("costInUsd", cost)
+	c.Set("promptTokenCount", promptTks)
+	c.Set("completionTokenCount", completionTks)
+}
+
+// setGeminiEmbeddingsUsage estimates the cost of embedding requests from the
+// request since gemini embedding responses do not include token usage.
+func setGeminiEmbeddingsUsage(c *gin.Context, log *zap.Logger, prod bool, e geminiEstimator, model, action string, body []byte) {
+	var parts []*gemini.Part
+	if action == "embedContent" {
+		er := &gemini.EmbedContentRequest{}
+		err := json.Unmarshal(body, er)
+		if err != nil {
+			logError(log, "error when unmarshalling gemini embed content request", prod, err)
+			return
+		}
+
+		parts = (&gemini.BatchEmbedContentsRequest{Requests: []*gemini.EmbedContentRequest{er}}).TextParts()
+	}
+
+	if action == "batchEmbedContents" {
+		br := &gemini.BatchEmbedContentsRequest{}
+		err := json.Unmarshal(body, br)
+		if err != nil {
+			logError(log, "error when unmarshalling gemini batch embed contents request", prod, err)
+			return
+		}
+
+		parts = br.TextParts()
+	}
+
+	tks := e.CountPartsTokens(parts)
+
+	cost, err := e.EstimateEmbeddingsCost(model, tks)
+	if err != nil {
+		telemetry.Incr("bricksllm.proxy.set_gemini_embeddings_usage.estimate_embeddings_cost_error", nil, 1)
+		logError(log, "error when estimating gemini embeddings cost", prod, err)
+	}
+
+	m, exists := c.Get("cost_map")
+	if exists {
+		converted, ok := m.(*provider.CostMap)
+		if ok {
+			newCost, err := provider.EstimateCostWithCostMap(model, tks, 1000, converted.EmbeddingsCostPerModel)
+			if err != nil {
+				telemetry.Incr("bricksllm.proxy.set_gemini_embeddings_usage.estimate_cost_with_cost_map_error", nil, 1)
+				logError(log, "error when estimating gemini embeddings cost with cost map", prod, err)
+			}
+
+			if newCost != 0 {
+				cost = newCost
+			}
+		}
+	}
+
+	c.Set("costInUsd", cost)
+	c.Set("promptTokenCount", tks)
+}
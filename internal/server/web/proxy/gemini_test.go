@@ -0,0 +1,25 @@
+package proxy
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bricks-cloud/bricksllm/internal/provider/gemini"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetGeminiModelParam(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Params = gin.Params{{Key: "model", Value: "/flash:generateContent"}}
+
+	model, action := gemini.ParseModelAction(c.Param("model"))
+	assert.Equal(t, "flash", model)
+	assert.Equal(t, "generateContent", action)
+
+	setGeminiModelParam(c, "gemini-1.5-flash", action)
+	assert.Equal(t, "gemini-1.5-flash:generateContent", c.Param("model"))
+
+	setGeminiModelParam(c, "gemini-1.5-pro", "")
+	assert.Equal(t, "gemini-1.5-pro", c.Param("model"))
+}
@@ -3,6 +3,7 @@ package proxy
 import (
 	"encoding/json"
 
+	"github.com/gin-gonic/gin"
 	goopenai "github.com/sashabaranov/go-openai"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -108,3 +109,19 @@ func logImageResponse(log *zap.Logger, data []byte, prod, private bool) {
 		log.Info("openai image response", fields...)
 	}
 }
+
+func setImageCost(c *gin.Context, e estimator, data []byte) error {
+	ir := &goopenai.ImageResponse{}
+	err := json.Unmarshal(data, ir)
+	if err != nil {
+		return err
+	}
+
+	cost, err := e.EstimateImageCost(c.GetString("model"), c.GetString("imageQuality"), c.GetString("imageSize"), len(ir.Data))
+	if err != nil {
+		return err
+	}
+
+	c.Set("costInUsd", cost)
+	return nil
+}
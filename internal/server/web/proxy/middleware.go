@@ -10,11 +10,15 @@ import (
 	"strings"
 	"time"
 
+	"github.com/bricks-cloud/bricksllm/internal/alias"
 	"github.com/bricks-cloud/bricksllm/internal/event"
 	"github.com/bricks-cloud/bricksllm/internal/key"
 	"github.com/bricks-cloud/bricksllm/internal/message"
+	"github.com/bricks-cloud/bricksllm/internal/policy"
 	"github.com/bricks-cloud/bricksllm/internal/provider"
 	"github.com/bricks-cloud/bricksllm/internal/provider/anthropic"
+	"github.com/bricks-cloud/bricksllm/internal/provider/bedrock"
+	"github.com/bricks-cloud/bricksllm/internal/provider/gemini"
 	"github.com/bricks-cloud/bricksllm/internal/provider/openai"
 	"github.com/bricks-cloud/bricksllm/internal/provider/vllm"
 	"github.com/bricks-cloud/bricksllm/internal/route"
@@ -49,7 +53,11 @@ type estimator interface {
 	EstimateChatCompletionStreamCostWithTokenCounts(model, content string) (int, float64, error)
 	EstimateCompletionCost(model string, tks int) (float64, error)
 	EstimateTotalCost(model string, promptTks, completionTks int) (float64, error)
+	EstimateTotalCostWithCachedTokens(model string, promptTks, cachedTks, completionTks int) (float64, error)
 	EstimateEmbeddingsInputCost(model string, tks int) (float64, error)
+	EstimateImageCost(model, quality, size string, n int) (float64, error)
+	EstimateBatchCost(model string, promptTks, cachedTks, completionTks int) (float64, error)
+	EstimateBatchEmbeddingsCost(model string, tks int) (float64, error)
 	EstimateChatCompletionPromptTokenCounts(model string, r *goopenai.ChatCompletionRequest) (int, error)
 }
 
@@ -64,18 +72,25 @@ type azureEstimator interface {
 
 type deepinfraEstimator interface {
 	EstimateEmbeddingsInputCost(model string, tks int) (float64, error)
+	EstimateTotalCost(model string, promptTks, completionTks int) (float64, error)
 }
 
 type authenticator interface {
-	AuthenticateHttpRequest(req *http.Request) (*key.ResponseKey, []*provider.Setting, error)
+	AuthenticateHttpRequest(req *http.Request) (*key.ResponseKey, []*provider.Setting, string, error)
+	RewriteHttpAuthHeaderWithSetting(uri string, req *http.Request, setting *provider.Setting) error
 }
 
 type validator interface {
 	Validate(k *key.ResponseKey, promptCost float64) error
+	ValidateBudgets(k *key.ResponseKey) error
 }
 
 type rateLimitManager interface {
 	Increment(keyId string, timeUnit key.TimeUnit) error
+	ReserveTokens(keyId string, timeUnit key.TimeUnit, limit int, tks int64) (bool, int64, int64, error)
+	ReserveUserTokens(id string, timeUnit key.TimeUnit, limit int, tks int64) (bool, int64, int64, error)
+	CorrectTokens(keyId string, timeUnit key.TimeUnit, window int64, tks int64) error
+	Allow(k *key.ResponseKey) (bool, int64, error)
 }
 
 type accessCache interface {
@@ -118,6 +133,11 @@ type redactedError interface {
 	Redacted()
 }
 
+type costLimitError interface {
+	Error() string
+	CostLimit()
+}
+
 type publisher interface {
 	Publish(message.Message)
 }
@@ -165,11 +185,15 @@ func (w responseWriter) Write(b []byte) (int, error) {
 	return w.ResponseWriter.Write(b)
 }
 
+type modelAliasManager interface {
+	GetModelAliasFromMemdb(name string) *alias.ModelAlias
+}
+
 type CustomPolicyDetector interface {
-	Detect(input []string, requirements []string) (bool, error)
+	Detect(input []string, requirements []string, policyId string, dc *policy.CustomDetectorConfig) (bool, error)
 }
 
-func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManager, a authenticator, prod, private bool, log *zap.Logger, pub publisher, prefix string, ac accessCache, uac userAccessCache, client http.Client, scanner Scanner, cd CustomPolicyDetector, um userManager, removeUserAgent bool) gin.HandlerFunc {
+func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManager, a authenticator, prod, private bool, log *zap.Logger, pub publisher, prefix string, ac accessCache, uac userAccessCache, client http.Client, scanner Scanner, cd CustomPolicyDetector, um userManager, removeUserAgent bool, rlm rateLimitManager, e estimator, ae anthropicEstimator, ge geminiEstimator, be bedrockEstimator, mam modelAliasManager, v validator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if c == nil || c.Request == nil {
 			JSON(c, http.StatusInternalServerError, "[BricksLLM] request is empty")
@@ -275,12 +299,19 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 				UserId:               userId,
 				PolicyId:             c.GetString("policyId"),
 				Action:               c.GetString("action"),
+				ResponseAction:       c.GetString("responseAction"),
 				RouteId:              c.GetString("routeId"),
+				RequestedModel:       c.GetString("requestedModel"),
 				CorrelationId:        cid,
 				Metadata:             metadataBytes,
 			}
 
 			enrichedEvent.Event = evt
+			enrichedEvent.SettingId = c.GetString("settingId")
+			if _, ok := c.Get("upstreamFailed"); ok {
+				enrichedEvent.UpstreamCalled = true
+				enrichedEvent.UpstreamFailed = c.GetBool("upstreamFailed")
+			}
 			content := c.GetString("content")
 			if len(content) != 0 {
 				enrichedEvent.Content = content
@@ -304,7 +335,7 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 			return
 		}
 
-		kc, settings, err := a.AuthenticateHttpRequest(c.Request)
+		kc, settings, jwtUserId, err := a.AuthenticateHttpRequest(c.Request)
 		enrichedEvent.Key = kc
 		_, ok := err.(notAuthorizedError)
 		if ok {
@@ -332,6 +363,15 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 			return
 		}
 
+		if ip := c.ClientIP(); !kc.IsIpAllowed(ip) {
+			telemetry.Incr("bricksllm.proxy.get_middleware.ip_not_allowed", nil, 1)
+			logWithCid.Info("request from an ip address that is not allowed by the key", zap.String("keyId", kc.KeyId), zap.String("ip", ip))
+			c.Set("action", "ip_denied")
+			JSON(c, http.StatusForbidden, "[BricksLLM] ip address is not allowed")
+			c.Abort()
+			return
+		}
+
 		c.Set("key", kc)
 		c.Set("settings", settings)
 
@@ -349,7 +389,7 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 				}
 			}
 
-			if strings.HasPrefix(c.FullPath(), "/api/providers/bedrock/anthropic") {
+			if strings.HasPrefix(c.FullPath(), "/api/providers/bedrock") {
 				if selected != nil && len(selected.Setting["awsAccessKeyId"]) != 0 {
 					c.Set("awsAccessKeyId", selected.Setting["awsAccessKeyId"])
 				}
@@ -368,6 +408,14 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 					c.Set("vllmUrl", selected.Setting["url"])
 				}
 			}
+
+			if strings.HasPrefix(c.FullPath(), "/api/providers/gemini") {
+				if selected != nil && len(selected.Setting["projectId"]) != 0 {
+					c.Set("vertexProjectId", selected.Setting["projectId"])
+					c.Set("vertexLocation", selected.Setting["location"])
+					c.Set("vertexCredentials", selected.Setting["credentials"])
+				}
+			}
 		}
 
 		p := pm.GetPolicyByIdFromMemdb(kc.PolicyId)
@@ -392,6 +440,52 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 			c.Request.Body = io.NopCloser(bytes.NewReader(body))
 		}
 
+		isUnified := c.FullPath() == "/v1/chat/completions" || c.FullPath() == "/v1/embeddings"
+		if isUnified || strings.HasPrefix(c.FullPath(), "/api/providers/") {
+			requested := gjson.GetBytes(body, "model").String()
+
+			// gemini requests carry the model in the path along with the action.
+			isGemini := strings.HasPrefix(c.FullPath(), "/api/providers/gemini") && c.Request.Method == http.MethodPost
+			geminiAction := ""
+			if isGemini {
+				requested, geminiAction = gemini.ParseModelAction(c.Param("model"))
+			}
+
+			if ma := mam.GetModelAliasFromMemdb(requested); len(requested) != 0 && ma != nil {
+				aliasProvider, model := ma.Resolve(kc.KeyId)
+				if isUnified {
+					model = aliasProvider + "/" + model
+				} else if aliasProvider != getProvider(c) {
+					telemetry.Incr("bricksllm.proxy.get_middleware.model_alias_provider_mismatch", nil, 1)
+					JSON(c, http.StatusBadRequest, fmt.Sprintf("[BricksLLM] model alias %s resolves to provider %s", requested, aliasProvider))
+					c.Abort()
+					return
+				}
+
+				if isGemini {
+					setGeminiModelParam(c, model, geminiAction)
+				}
+
+				if !isGemini {
+					rewritten, err := sjson.SetBytes(body, "model", model)
+					if err != nil {
+						telemetry.Incr("bricksllm.proxy.get_middleware.rewrite_model_error", nil, 1)
+						logError(logWithCid, "error when rewriting model of a request", prod, err)
+						JSON(c, http.StatusInternalServerError, "[BricksLLM] error when rewriting model")
+						c.Abort()
+						return
+					}
+
+					body = rewritten
+					c.Request.Body = io.NopCloser(bytes.NewReader(body))
+				}
+
+				c.Set("requestedModel", requested)
+			}
+		}
+
+		forwarded := body
+
 		if c.FullPath() == "/api/providers/anthropic/v1/complete" {
 			logCompletionRequest(logWithCid, body, prod, private)
 
@@ -488,6 +582,85 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 			policyInput = mr
 		}
 
+		if strings.HasPrefix(c.FullPath(), "/api/providers/bedrock/model/:model_id/") {
+			model := c.Param("model_id")
+			c.Set("model", model)
+
+			if strings.HasSuffix(c.FullPath(), "stream") {
+				c.Set("stream", true)
+			}
+
+			if strings.Contains(c.FullPath(), "/converse") {
+				cr := &bedrock.ConverseRequest{}
+				err = json.Unmarshal(body, cr)
+				if err != nil {
+					logError(logWithCid, "error when unmarshalling bedrock converse request", prod, err)
+					return
+				}
+
+				enrichedEvent.Request = cr
+				policyInput = cr
+			}
+
+			if strings.Contains(c.FullPath(), "/invoke") {
+				ir, err := bedrock.ParseInvokeRequest(model, body)
+				if err != nil {
+					logError(logWithCid, "error when unmarshalling bedrock invoke model request", prod, err)
+					return
+				}
+
+				if ir != nil {
+					enrichedEvent.Request = ir
+					policyInput = ir
+				}
+			}
+		}
+
+		if strings.HasPrefix(c.FullPath(), "/api/providers/gemini") && c.Request.Method == http.MethodPost {
+			model, action := gemini.ParseModelAction(c.Param("model"))
+			c.Set("model", model)
+
+			if action == "streamGenerateContent" {
+				c.Set("stream", true)
+			}
+
+			if action == "generateContent" || action == "streamGenerateContent" || action == "countTokens" {
+				gr := &gemini.GenerateContentRequest{}
+				err = json.Unmarshal(body, gr)
+				if err != nil {
+					logError(logWithCid, "error when unmarshalling gemini generate content request", prod, err)
+					return
+				}
+
+				enrichedEvent.Request = gr
+				policyInput = gr
+			}
+
+			if action == "embedContent" {
+				er := &gemini.EmbedContentRequest{}
+				err = json.Unmarshal(body, er)
+				if err != nil {
+					logError(logWithCid, "error when unmarshalling gemini embed content request", prod, err)
+					return
+				}
+
+				enrichedEvent.Request = er
+				policyInput = er
+			}
+
+			if action == "batchEmbedContents" {
+				br := &gemini.BatchEmbedContentsRequest{}
+				err = json.Unmarshal(body, br)
+				if err != nil {
+					logError(logWithCid, "error when unmarshalling gemini batch embed contents request", prod, err)
+					return
+				}
+
+				enrichedEvent.Request = br
+				policyInput = br
+			}
+		}
+
 		if strings.HasPrefix(c.FullPath(), "/api/custom/providers/:provider") {
 			providerName := c.Param("provider")
 
@@ -538,9 +711,59 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 			}
 		}
 
+		var rc *route.Route
+
+		if c.FullPath() == "/v1/chat/completions" || c.FullPath() == "/v1/embeddings" {
+			requested := gjson.GetBytes(body, "model").String()
+			alias, err := route.ResolveModelAlias(requested)
+			if err != nil {
+				telemetry.Incr("bricksllm.proxy.get_middleware.resolve_model_alias_error", nil, 1)
+				JSON(c, http.StatusBadRequest, fmt.Sprintf("[BricksLLM] %v", err))
+				c.Abort()
+				return
+			}
+
+			setting := route.SelectSetting(alias.Provider, settings)
+			if setting == nil {
+				telemetry.Incr("bricksllm.proxy.get_middleware.unified_provider_setting_not_found", nil, 1)
+				JSON(c, http.StatusForbidden, fmt.Sprintf("[BricksLLM] key is not associated with a %s provider setting", alias.Provider))
+				c.Abort()
+				return
+			}
+
+			// the request carries the resolved model from here on so that it is checked
+			// against the models allowed by the setting and recorded on the event.
+			rewritten, err := sjson.SetBytes(body, "model", alias.Model)
+			if err != nil {
+				telemetry.Incr("bricksllm.proxy.get_middleware.rewrite_unified_model_error", nil, 1)
+				logError(logWithCid, "error when rewriting model of a unified request", prod, err)
+				JSON(c, http.StatusInternalServerError, "[BricksLLM] error when rewriting model")
+				c.Abort()
+				return
+			}
+
+			body = rewritten
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+			if len(c.GetString("requestedModel")) == 0 {
+				c.Set("requestedModel", requested)
+			}
+
+			rc, err = route.NewUnifiedRoute(c.FullPath(), alias, setting, c.FullPath() == "/v1/embeddings", gjson.GetBytes(body, "stream").Bool(), c.GetDuration("requestTimeout").String())
+			if err != nil {
+				telemetry.Incr("bricksllm.proxy.get_middleware.new_unified_route_error", nil, 1)
+				JSON(c, http.StatusBadRequest, fmt.Sprintf("[BricksLLM] %v", err))
+				c.Abort()
+				return
+			}
+
+			settings = []*provider.Setting{setting}
+			c.Set("settings", settings)
+			enrichedEvent.CostMap = setting.CostMap
+			c.Set("route_cost_map", setting.CostMap)
+		}
+
 		if strings.HasPrefix(c.FullPath(), "/api/routes") {
-			r := c.Param("route")
-			rc := rm.GetRouteFromMemDb(r)
+			rc = rm.GetRouteFromMemDb(c.Param("route"))
 
 			if rc == nil {
 				telemetry.Incr("bricksllm.proxy.get_middleware.route_config_not_found", nil, 1)
@@ -549,8 +772,12 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 				return
 			}
 
-			c.Set("route_config", rc)
 			c.Set("routeId", rc.Id)
+		}
+
+		if rc != nil {
+			r := c.Param("route")
+			c.Set("route_config", rc)
 
 			if rc.ShouldRunEmbeddings() {
 				er := &goopenai.EmbeddingRequest{}
@@ -589,13 +816,10 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 				logRequest(logWithCid, prod, private, ccr)
 
 				if ccr.Stream {
-					telemetry.Incr("bricksllm.proxy.get_middleware.streaming_not_allowed", nil, 1)
-					JSON(c, http.StatusForbidden, "[BricksLLM] streaming is not allowed")
-					c.Abort()
-					return
+					c.Set("stream", true)
 				}
 
-				if rc.CacheConfig != nil && rc.CacheConfig.Enabled {
+				if rc.CacheConfig != nil && rc.CacheConfig.Enabled && !rc.CacheConfig.IsSemantic() && !ccr.Stream {
 					c.Set("cache_key", route.ComputeCacheKeyForChatCompletionRequest(r, ccr))
 				}
 
@@ -823,7 +1047,8 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 				c.Set("model", "dall-e-2")
 			}
 
-			c.Set("model", ir.Model)
+			c.Set("imageQuality", ir.Quality)
+			c.Set("imageSize", ir.Size)
 			logCreateImageRequest(logWithCid, ir, prod, private)
 		}
 
@@ -844,6 +1069,7 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 				c.Set("model", "dall-e-2")
 			}
 
+			c.Set("imageSize", size)
 			logEditImageRequest(logWithCid, prompt, model, n, size, responseFormat, user, prod, private)
 		}
 
@@ -863,6 +1089,7 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 				c.Set("model", "dall-e-2")
 			}
 
+			c.Set("imageSize", size)
 			logImageVariationsRequest(logWithCid, model, n, size, responseFormat, user, prod)
 		}
 
@@ -1154,6 +1381,24 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 			return
 		}
 
+		if err := v.ValidateBudgets(kc); err != nil {
+			if _, ok := err.(costLimitError); ok {
+				telemetry.Incr("bricksllm.proxy.get_middleware.budget_exceeded", nil, 1)
+				JSON(c, http.StatusTooManyRequests, "[BricksLLM] "+err.Error())
+				c.Abort()
+				return
+			}
+
+			telemetry.Incr("bricksllm.proxy.get_middleware.validate_budgets_error", nil, 1)
+			logError(logWithCid, "error when validating budgets", prod, err)
+		}
+
+		if len(jwtUserId) != 0 {
+			userId = jwtUserId
+		}
+
+		var u *user.User
+
 		if len(userId) != 0 {
 			c.Set("userId", userId)
 			us, err := um.GetUsers(kc.Tags, nil, []string{userId}, 0, 0)
@@ -1163,6 +1408,8 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 			}
 
 			if len(us) == 1 {
+				u = us[0]
+
 				if us[0].Revoked {
 					telemetry.Incr("bricksllm.proxy.get_middleware.user_revoked", nil, 1)
 					JSON(c, http.StatusUnauthorized, fmt.Sprintf("[BricksLLM] user is revoked: %s", userId))
@@ -1200,6 +1447,7 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 
 		if p != nil {
 			c.Set("policyId", p.Id)
+			c.Set("policy", p)
 		}
 
 		if p != nil && policyInput != nil {
@@ -1234,6 +1482,7 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 			data, err := json.Marshal(policyInput)
 			if err == nil {
 				c.Request.Body = io.NopCloser(bytes.NewReader(data))
+				forwarded = data
 
 				if kc.ShouldLogRequest {
 					requestBytes = data
@@ -1241,6 +1490,61 @@ func getMiddleware(cpm CustomProvidersManager, rm routeManager, pm PoliciesManag
 			}
 		}
 
+		// the semantic cache input is sent to the embeddings api so it is computed from the
+		// request after the policy redacts it
+		if ccr, ok := policyInput.(*goopenai.ChatCompletionRequest); ok && rc != nil && rc.CacheConfig.IsSemantic() && !ccr.Stream {
+			c.Set("semantic_cache_input", route.ComputeSemanticCacheInputForChatCompletionRequest(ccr))
+			c.Set("semantic_cache_scope", route.ComputeSemanticCacheScope(rc, kc.KeyId, ccr))
+		}
+
+		if kc.RateLimitOverTime != 0 && !kc.IsRateLimitedInFixedWindow() {
+			allowed, remaining, err := rlm.Allow(kc)
+			if err != nil {
+				telemetry.Incr("bricksllm.proxy.get_middleware.rate_limit_allow_error", nil, 1)
+				logError(logWithCid, "error when checking rate limit", prod, err)
+			}
+
+			if err == nil {
+				c.Header(requestLimitHeader, strconv.Itoa(kc.RateLimitOverTime))
+				c.Header(requestLimitRemainingHeader, strconv.FormatInt(remaining, 10))
+			}
+
+			if err == nil && !allowed {
+				telemetry.Incr("bricksllm.proxy.get_middleware.rate_limited", nil, 1)
+				JSON(c, http.StatusTooManyRequests, "[BricksLLM] too many requests")
+				c.Abort()
+				return
+			}
+		}
+
+		if kc.TokenLimitOverTime != 0 || (u != nil && u.TokenLimitOverTime != 0) {
+			estimated := estimatePromptTokens(e, ae, ge, be, policyInput)
+
+			if msg := reserveTokenLimits(c, rlm, logWithCid, prod, kc, u, estimated, enrichedEvent); len(msg) != 0 {
+				JSON(c, http.StatusTooManyRequests, msg)
+				c.Abort()
+				return
+			}
+		}
+
+		enrichedEvent.User = u
+
+		if c.FullPath() == "/api/providers/openai/v1/chat/completions" {
+			setResponseCacheKey(c, kc, settings, "openai", forwarded)
+		}
+
+		if c.FullPath() == "/api/providers/anthropic/v1/messages" {
+			setResponseCacheKey(c, kc, settings, "anthropic", forwarded)
+		}
+
+		if len(settings) >= 1 {
+			c.Set("settingId", settings[0].Id)
+		}
+
+		if kc.RetryConfig != nil && kc.RetryConfig.GetMaxAttempts() > 1 {
+			c.Set("retrier", newRetrier(c, a, pub, kc, settings, userId, logWithCid))
+		}
+
 		c.Next()
 
 		if kc.ShouldLogResponse {
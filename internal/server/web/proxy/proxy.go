@@ -79,14 +79,27 @@ func CorsMiddleware() gin.HandlerFunc {
 	}
 }
 
-func NewProxyServer(log *zap.Logger, mode, privacyMode string, c cache, m KeyManager, rm routeManager, a authenticator, psm ProviderSettingsManager, cpm CustomProvidersManager, ks keyStorage, e estimator, ae anthropicEstimator, aoe azureEstimator, v validator, r recorder, pub publisher, rlm rateLimitManager, timeout time.Duration, ac accessCache, uac userAccessCache, pm PoliciesManager, scanner Scanner, cd CustomPolicyDetector, die deepinfraEstimator, um userManager, removeAgentHeaders bool) (*ProxyServer, error) {
+// setTrustedProxies parses the trusted proxies once when the server is created. X-Forwarded-For
+// is only used for the client ip when the request comes from a trusted proxy.
+func setTrustedProxies(router *gin.Engine, trustedProxies []string) error {
+	router.RemoteIPHeaders = []string{"X-Forwarded-For"}
+	return router.SetTrustedProxies(trustedProxies)
+}
+
+func NewProxyServer(log *zap.Logger, mode, privacyMode string, c cache, sc semanticCache, m KeyManager, rm routeManager, a authenticator, psm ProviderSettingsManager, cpm CustomProvidersManager, ks keyStorage, e estimator, ae anthropicEstimator, aoe azureEstimator, v validator, r recorder, pub publisher, rlm rateLimitManager, timeout time.Duration, ac accessCache, uac userAccessCache, pm PoliciesManager, scanner Scanner, cd CustomPolicyDetector, die deepinfraEstimator, ge geminiEstimator, vts vertexTokenSource, be bedrockEstimator, um userManager, removeAgentHeaders bool, mam modelAliasManager, trustedProxies []string) (*ProxyServer, error) {
 	router := gin.New()
+
+	err := setTrustedProxies(router, trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
 	prod := mode == "production"
 	private := privacyMode == "strict"
 
 	router.Use(CorsMiddleware())
 	router.Use(getTimeoutMiddleware(timeout))
-	router.Use(getMiddleware(cpm, rm, pm, a, prod, private, log, pub, "proxy", ac, uac, http.Client{}, scanner, cd, um, removeAgentHeaders))
+	router.Use(getMiddleware(cpm, rm, pm, a, prod, private, log, pub, "proxy", ac, uac, http.Client{}, scanner, cd, um, removeAgentHeaders, rlm, e, ae, ge, be, mam, v))
 
 	client := http.Client{}
 
@@ -102,90 +115,95 @@ func NewProxyServer(log *zap.Logger, mode, privacyMode string, c cache, m KeyMan
 	router.POST("/api/providers/openai/v1/audio/translations", getTranslationsHandler(prod, client, e))
 
 	// completions
-	router.POST("/api/providers/openai/v1/chat/completions", getChatCompletionHandler(prod, private, client, e))
+	router.POST("/api/providers/openai/v1/chat/completions", getChatCompletionHandler(prod, private, client, e, c, scanner, cd))
 
 	// embeddings
 	router.POST("/api/providers/openai/v1/embeddings", getEmbeddingHandler(prod, private, client, e))
 
 	// moderations
-	router.POST("/api/providers/openai/v1/moderations", getPassThroughHandler(prod, private, client))
+	router.POST("/api/providers/openai/v1/moderations", getPassThroughHandler(prod, private, client, e, c))
 
 	// models
-	router.GET("/api/providers/openai/v1/models", getPassThroughHandler(prod, private, client))
-	router.GET("/api/providers/openai/v1/models/:model", getPassThroughHandler(prod, private, client))
-	router.DELETE("/api/providers/openai/v1/models/:model", getPassThroughHandler(prod, private, client))
+	router.GET("/api/providers/openai/v1/models", getPassThroughHandler(prod, private, client, e, c))
+	router.GET("/api/providers/openai/v1/models/:model", getPassThroughHandler(prod, private, client, e, c))
+	router.DELETE("/api/providers/openai/v1/models/:model", getPassThroughHandler(prod, private, client, e, c))
 
 	// assistants
-	router.POST("/api/providers/openai/v1/assistants", getPassThroughHandler(prod, private, client))
-	router.GET("/api/providers/openai/v1/assistants/:assistant_id", getPassThroughHandler(prod, private, client))
-	router.POST("/api/providers/openai/v1/assistants/:assistant_id", getPassThroughHandler(prod, private, client))
-	router.DELETE("/api/providers/openai/v1/assistants/:assistant_id", getPassThroughHandler(prod, private, client))
-	router.GET("/api/providers/openai/v1/assistants", getPassThroughHandler(prod, private, client))
+	router.POST("/api/providers/openai/v1/assistants", getPassThroughHandler(prod, private, client, e, c))
+	router.GET("/api/providers/openai/v1/assistants/:assistant_id", getPassThroughHandler(prod, private, client, e, c))
+	router.POST("/api/providers/openai/v1/assistants/:assistant_id", getPassThroughHandler(prod, private, client, e, c))
+	router.DELETE("/api/providers/openai/v1/assistants/:assistant_id", getPassThroughHandler(prod, private, client, e, c))
+	router.GET("/api/providers/openai/v1/assistants", getPassThroughHandler(prod, private, client, e, c))
 
 	// assistant files
-	router.POST("/api/providers/openai/v1/assistants/:assistant_id/files", getPassThroughHandler(prod, private, client))
-	router.GET("/api/providers/openai/v1/assistants/:assistant_id/files/:file_id", getPassThroughHandler(prod, private, client))
-	router.DELETE("/api/providers/openai/v1/assistants/:assistant_id/files/:file_id", getPassThroughHandler(prod, private, client))
-	router.GET("/api/providers/openai/v1/assistants/:assistant_id/files", getPassThroughHandler(prod, private, client))
+	router.POST("/api/providers/openai/v1/assistants/:assistant_id/files", getPassThroughHandler(prod, private, client, e, c))
+	router.GET("/api/providers/openai/v1/assistants/:assistant_id/files/:file_id", getPassThroughHandler(prod, private, client, e, c))
+	router.DELETE("/api/providers/openai/v1/assistants/:assistant_id/files/:file_id", getPassThroughHandler(prod, private, client, e, c))
+	router.GET("/api/providers/openai/v1/assistants/:assistant_id/files", getPassThroughHandler(prod, private, client, e, c))
 
 	// threads
-	router.POST("/api/providers/openai/v1/threads", getPassThroughHandler(prod, private, client))
-	router.GET("/api/providers/openai/v1/threads/:thread_id", getPassThroughHandler(prod, private, client))
-	router.POST("/api/providers/openai/v1/threads/:thread_id", getPassThroughHandler(prod, private, client))
-	router.DELETE("/api/providers/openai/v1/threads/:thread_id", getPassThroughHandler(prod, private, client))
+	router.POST("/api/providers/openai/v1/threads", getPassThroughHandler(prod, private, client, e, c))
+	router.GET("/api/providers/openai/v1/threads/:thread_id", getPassThroughHandler(prod, private, client, e, c))
+	router.POST("/api/providers/openai/v1/threads/:thread_id", getPassThroughHandler(prod, private, client, e, c))
+	router.DELETE("/api/providers/openai/v1/threads/:thread_id", getPassThroughHandler(prod, private, client, e, c))
 
 	// messages
-	router.POST("/api/providers/openai/v1/threads/:thread_id/messages", getPassThroughHandler(prod, private, client))
-	router.GET("/api/providers/openai/v1/threads/:thread_id/messages/:message_id", getPassThroughHandler(prod, private, client))
-	router.POST("/api/providers/openai/v1/threads/:thread_id/messages/:message_id", getPassThroughHandler(prod, private, client))
-	router.GET("/api/providers/openai/v1/threads/:thread_id/messages", getPassThroughHandler(prod, private, client))
+	router.POST("/api/providers/openai/v1/threads/:thread_id/messages", getPassThroughHandler(prod, private, client, e, c))
+	router.GET("/api/providers/openai/v1/threads/:thread_id/messages/:message_id", getPassThroughHandler(prod, private, client, e, c))
+	router.POST("/api/providers/openai/v1/threads/:thread_id/messages/:message_id", getPassThroughHandler(prod, private, client, e, c))
+	router.GET("/api/providers/openai/v1/threads/:thread_id/messages", getPassThroughHandler(prod, private, client, e, c))
 
 	// message files
-	router.GET("/api/providers/openai/v1/threads/:thread_id/messages/:message_id/files/:file_id", getPassThroughHandler(prod, private, client))
-	router.GET("/api/providers/openai/v1/threads/:thread_id/messages/:message_id/files", getPassThroughHandler(prod, private, client))
+	router.GET("/api/providers/openai/v1/threads/:thread_id/messages/:message_id/files/:file_id", getPassThroughHandler(prod, private, client, e, c))
+	router.GET("/api/providers/openai/v1/threads/:thread_id/messages/:message_id/files", getPassThroughHandler(prod, private, client, e, c))
 
 	// runs
-	router.POST("/api/providers/openai/v1/threads/:thread_id/runs", getPassThroughHandler(prod, private, client))
-	router.GET("/api/providers/openai/v1/threads/:thread_id/runs/:run_id", getPassThroughHandler(prod, private, client))
-	router.POST("/api/providers/openai/v1/threads/:thread_id/runs/:run_id", getPassThroughHandler(prod, private, client))
-	router.GET("/api/providers/openai/v1/threads/:thread_id/runs", getPassThroughHandler(prod, private, client))
-	router.POST("/api/providers/openai/v1/threads/:thread_id/runs/:run_id/submit_tool_outputs", getPassThroughHandler(prod, private, client))
-	router.POST("/api/providers/openai/v1/threads/:thread_id/runs/:run_id/cancel", getPassThroughHandler(prod, private, client))
-	router.POST("/api/providers/openai/v1/threads/runs", getPassThroughHandler(prod, private, client))
-	router.GET("/api/providers/openai/v1/threads/:thread_id/runs/:run_id/steps/:step_id", getPassThroughHandler(prod, private, client))
-	router.GET("/api/providers/openai/v1/threads/:thread_id/runs/:run_id/steps", getPassThroughHandler(prod, private, client))
+	router.POST("/api/providers/openai/v1/threads/:thread_id/runs", getPassThroughHandler(prod, private, client, e, c))
+	router.GET("/api/providers/openai/v1/threads/:thread_id/runs/:run_id", getPassThroughHandler(prod, private, client, e, c))
+	router.POST("/api/providers/openai/v1/threads/:thread_id/runs/:run_id", getPassThroughHandler(prod, private, client, e, c))
+	router.GET("/api/providers/openai/v1/threads/:thread_id/runs", getPassThroughHandler(prod, private, client, e, c))
+	router.POST("/api/providers/openai/v1/threads/:thread_id/runs/:run_id/submit_tool_outputs", getPassThroughHandler(prod, private, client, e, c))
+	router.POST("/api/providers/openai/v1/threads/:thread_id/runs/:run_id/cancel", getPassThroughHandler(prod, private, client, e, c))
+	router.POST("/api/providers/openai/v1/threads/runs", getPassThroughHandler(prod, private, client, e, c))
+	router.GET("/api/providers/openai/v1/threads/:thread_id/runs/:run_id/steps/:step_id", getPassThroughHandler(prod, private, client, e, c))
+	router.GET("/api/providers/openai/v1/threads/:thread_id/runs/:run_id/steps", getPassThroughHandler(prod, private, client, e, c))
 
 	// files
-	router.GET("/api/providers/openai/v1/files", getPassThroughHandler(prod, private, client))
-	router.POST("/api/providers/openai/v1/files", getPassThroughHandler(prod, private, client))
-	router.DELETE("/api/providers/openai/v1/files/:file_id", getPassThroughHandler(prod, private, client))
-	router.GET("/api/providers/openai/v1/files/:file_id", getPassThroughHandler(prod, private, client))
-	router.GET("/api/providers/openai/v1/files/:file_id/content", getPassThroughHandler(prod, private, client))
+	router.GET("/api/providers/openai/v1/files", getPassThroughHandler(prod, private, client, e, c))
+	router.POST("/api/providers/openai/v1/files", getPassThroughHandler(prod, private, client, e, c))
+	router.DELETE("/api/providers/openai/v1/files/:file_id", getPassThroughHandler(prod, private, client, e, c))
+	router.GET("/api/providers/openai/v1/files/:file_id", getPassThroughHandler(prod, private, client, e, c))
+	router.GET("/api/providers/openai/v1/files/:file_id/content", getPassThroughHandler(prod, private, client, e, c))
 
 	// batch
-	router.POST("/api/providers/openai/v1/batches", getPassThroughHandler(prod, private, client))
-	router.GET("/api/providers/openai/v1/batches/:batch_id", getPassThroughHandler(prod, private, client))
-	router.POST("/api/providers/openai/v1/batches/:batch_id/cancel", getPassThroughHandler(prod, private, client))
-	router.GET("/api/providers/openai/v1/batches", getPassThroughHandler(prod, private, client))
+	router.POST("/api/providers/openai/v1/batches", getPassThroughHandler(prod, private, client, e, c))
+	router.GET("/api/providers/openai/v1/batches/:batch_id", getPassThroughHandler(prod, private, client, e, c))
+	router.POST("/api/providers/openai/v1/batches/:batch_id/cancel", getPassThroughHandler(prod, private, client, e, c))
+	router.GET("/api/providers/openai/v1/batches", getPassThroughHandler(prod, private, client, e, c))
 
 	// images
-	router.POST("/api/providers/openai/v1/images/generations", getPassThroughHandler(prod, private, client))
-	router.POST("/api/providers/openai/v1/images/edits", getPassThroughHandler(prod, private, client))
-	router.POST("/api/providers/openai/v1/images/variations", getPassThroughHandler(prod, private, client))
+	router.POST("/api/providers/openai/v1/images/generations", getPassThroughHandler(prod, private, client, e, c))
+	router.POST("/api/providers/openai/v1/images/edits", getPassThroughHandler(prod, private, client, e, c))
+	router.POST("/api/providers/openai/v1/images/variations", getPassThroughHandler(prod, private, client, e, c))
 
 	// azure
-	router.POST("/api/providers/azure/openai/deployments/:deployment_id/chat/completions", getAzureChatCompletionHandler(prod, private, client, aoe))
+	router.POST("/api/providers/azure/openai/deployments/:deployment_id/chat/completions", getAzureChatCompletionHandler(prod, private, client, aoe, scanner, cd))
 	router.POST("/api/providers/azure/openai/deployments/:deployment_id/embeddings", getAzureEmbeddingsHandler(prod, private, client, aoe))
 	router.POST("/api/providers/azure/openai/deployments/:deployment_id/completions", getAzureCompletionsHandler(prod, private, client, aoe))
 
 	// anthropic
 	router.POST("/api/providers/anthropic/v1/complete", getCompletionHandler(prod, private, client))
-	router.POST("/api/providers/anthropic/v1/messages", getMessagesHandler(prod, private, client, ae))
+	router.POST("/api/providers/anthropic/v1/messages", getMessagesHandler(prod, private, client, ae, c, scanner, cd))
 
 	// bedrock anthropic
 	router.POST("/api/providers/bedrock/anthropic/v1/complete", getBedrockCompletionHandler(prod, ae))
 	router.POST("/api/providers/bedrock/anthropic/v1/messages", getBedrockMessagesHandler(prod, ae))
 
+	// bedrock runtime
+	for _, action := range []string{"converse", "converse-stream", "invoke", "invoke-with-response-stream"} {
+		router.POST("/api/providers/bedrock/model/:model_id/"+action, getBedrockRuntimeHandler(prod, client, action, be, ae))
+	}
+
 	// vllm
 	router.POST("/api/providers/vllm/v1/chat/completions", getVllmChatCompletionsHandler(prod, private, client))
 	router.POST("/api/providers/vllm/v1/completions", getVllmCompletionsHandler(prod, private, client))
@@ -195,11 +213,22 @@ func NewProxyServer(log *zap.Logger, mode, privacyMode string, c cache, m KeyMan
 	router.POST("/api/providers/deepinfra/v1/completions", getDeepinfraCompletionsHandler(prod, private, client))
 	router.POST("/api/providers/deepinfra/v1/embeddings", getDeepinfraEmbeddingsHandler(prod, private, client, die))
 
+	// gemini
+	for _, version := range []string{"v1", "v1beta"} {
+		router.GET("/api/providers/gemini/"+version+"/models", getGeminiModelsHandler(prod, client, version, vts))
+		router.GET("/api/providers/gemini/"+version+"/models/:model", getGeminiModelsHandler(prod, client, version, vts))
+		router.POST("/api/providers/gemini/"+version+"/models/:model", getGeminiHandler(prod, private, client, version, ge, vts))
+	}
+
 	// custom provider
 	router.POST("/api/custom/providers/:provider/*wildcard", getCustomProviderHandler(prod, client))
 
 	// custom route
-	router.POST("/api/routes/*route", getRouteHandler(prod, c, aoe, e, client, r))
+	router.POST("/api/routes/*route", getRouteHandler(prod, c, sc, aoe, e, ae, die, ge, client, r, scanner, cd))
+
+	// unified openai compatible endpoints
+	router.POST("/v1/chat/completions", getRouteHandler(prod, c, sc, aoe, e, ae, die, ge, client, r, scanner, cd))
+	router.POST("/v1/embeddings", getRouteHandler(prod, c, sc, aoe, e, ae, die, ge, client, r, scanner, cd))
 
 	// vector store
 	router.POST("/api/providers/openai/v1/vector_stores", getCreateVectorStoreHandler(prod, client))
@@ -279,7 +308,7 @@ func writeFieldToBuffer(fields []string, c *gin.Context, writer *multipart.Write
 	return nil
 }
 
-func getPassThroughHandler(prod, private bool, client http.Client) gin.HandlerFunc {
+func getPassThroughHandler(prod, private bool, client http.Client, e estimator, ca cache) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		log := util.GetLogFromCtx(c)
 
@@ -671,18 +700,42 @@ func getPassThroughHandler(prod, private bool, client http.Client) gin.HandlerFu
 
 			if c.FullPath() == "/api/providers/openai/v1/files/:file_id/content" && c.Request.Method == http.MethodGet {
 				logRetrieveFileContentResponse(log, prod)
+
+				err := setBatchOutputCost(c, ca, e, bytes)
+				if err != nil {
+					telemetry.Incr("bricksllm.proxy.get_pass_through_handler.set_batch_output_cost_error", tags, 1)
+					logError(log, "error when setting batch output cost", prod, err)
+				}
 			}
 
 			if c.FullPath() == "/api/providers/openai/v1/images/generations" && c.Request.Method == http.MethodPost {
 				logImageResponse(log, bytes, prod, private)
+
+				err := setImageCost(c, e, bytes)
+				if err != nil {
+					telemetry.Incr("bricksllm.proxy.get_pass_through_handler.set_image_cost_error", tags, 1)
+					logError(log, "error when setting image cost", prod, err)
+				}
 			}
 
 			if c.FullPath() == "/api/providers/openai/v1/images/edits" && c.Request.Method == http.MethodPost {
 				logImageResponse(log, bytes, prod, private)
+
+				err := setImageCost(c, e, bytes)
+				if err != nil {
+					telemetry.Incr("bricksllm.proxy.get_pass_through_handler.set_image_cost_error", tags, 1)
+					logError(log, "error when setting image cost", prod, err)
+				}
 			}
 
 			if c.FullPath() == "/api/providers/openai/v1/images/variations" && c.Request.Method == http.MethodPost {
 				logImageResponse(log, bytes, prod, private)
+
+				err := setImageCost(c, e, bytes)
+				if err != nil {
+					telemetry.Incr("bricksllm.proxy.get_pass_through_handler.set_image_cost_error", tags, 1)
+					logError(log, "error when setting image cost", prod, err)
+				}
 			}
 		}
 
@@ -1001,6 +1054,12 @@ func (ps *ProxyServer) Run() {
 		ps.log.Info("PORT 8002 | POST   | /api/providers/bedrock/anthropic/v1/complete is ready for forwarding completion requests to bedrock anthropic")
 		ps.log.Info("PORT 8002 | POST   | /api/providers/bedrock/anthropic/v1/messages is ready for forwarding message requests to bedrock anthropic")
 
+		// bedrock runtime
+		ps.log.Info("PORT 8002 | POST   | /api/providers/bedrock/model/:model_id/converse is ready for forwarding converse requests to bedrock")
+		ps.log.Info("PORT 8002 | POST   | /api/providers/bedrock/model/:model_id/converse-stream is ready for forwarding streaming converse requests to bedrock")
+		ps.log.Info("PORT 8002 | POST   | /api/providers/bedrock/model/:model_id/invoke is ready for forwarding invoke model requests to bedrock")
+		ps.log.Info("PORT 8002 | POST   | /api/providers/bedrock/model/:model_id/invoke-with-response-stream is ready for forwarding streaming invoke model requests to bedrock")
+
 		// vllm
 		ps.log.Info("PORT 8002 | POST   | /api/providers/vllm/v1/chat/completions is ready for forwarding vllm chat completions requests")
 		ps.log.Info("PORT 8002 | POST   | /api/providers/vllm/v1/completions is ready for forwarding vllm completions requests")
@@ -1010,11 +1069,20 @@ func (ps *ProxyServer) Run() {
 		ps.log.Info("PORT 8002 | POST   | /api/providers/deepinfra/v1/completions is ready for forwarding deepinfra completions requests")
 		ps.log.Info("PORT 8002 | POST   | /api/providers/deepinfra/v1/embeddings is ready for forwarding deepinfra embeddings requests")
 
+		// gemini
+		for _, version := range []string{"v1", "v1beta"} {
+			ps.log.Info(fmt.Sprintf("PORT 8002 | GET    | /api/providers/gemini/%s/models is ready for forwarding gemini list models requests", version))
+			ps.log.Info(fmt.Sprintf("PORT 8002 | GET    | /api/providers/gemini/%s/models/:model is ready for forwarding gemini get model requests", version))
+			ps.log.Info(fmt.Sprintf("PORT 8002 | POST   | /api/providers/gemini/%s/models/:model is ready for forwarding gemini generate content, count tokens and embed content requests", version))
+		}
+
 		// custom provider
 		ps.log.Info("PORT 8002 | POST   | /api/custom/providers/:provider/*wildcard is ready for forwarding requests to custom providers")
 
 		// custom route
 		ps.log.Info("PORT 8002 | POST   | /api/routes/*route is ready for forwarding requests to a custom route")
+		ps.log.Info("PORT 8002 | POST   | /v1/chat/completions is ready for forwarding openai formatted chat completions requests to the provider of the model")
+		ps.log.Info("PORT 8002 | POST   | /v1/embeddings is ready for forwarding openai formatted embeddings requests to the provider of the model")
 
 		// vector store
 		ps.log.Info("PORT 8002 | POST   | /api/providers/openai/v1/vector_stores is ready for creating an openai vector store")
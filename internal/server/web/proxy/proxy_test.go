@@ -0,0 +1,63 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetTrustedProxies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	kc := &key.ResponseKey{AllowedCidrs: []string{"203.0.113.0/24"}}
+
+	cases := []struct {
+		name           string
+		trustedProxies []string
+		remoteAddr     string
+		forwardedFor   string
+		clientIp       string
+		allowed        bool
+	}{
+		{"trusted proxy forwards an allowed ip", []string{"10.0.0.0/8"}, "10.0.0.2:1234", "203.0.113.5", "203.0.113.5", true},
+		{"trusted proxy forwards a denied ip", []string{"10.0.0.0/8"}, "10.0.0.2:1234", "198.51.100.7", "198.51.100.7", false},
+		{"chain of trusted proxies", []string{"10.0.0.0/8"}, "10.0.0.2:1234", "203.0.113.5, 10.0.0.3", "203.0.113.5", true},
+		{"spoofed header before a trusted proxy", []string{"10.0.0.0/8"}, "10.0.0.2:1234", "203.0.113.5, 198.51.100.7", "198.51.100.7", false},
+		{"untrusted proxy", []string{"10.0.0.0/8"}, "198.51.100.7:1234", "203.0.113.5", "198.51.100.7", false},
+		{"no trusted proxies", nil, "10.0.0.2:1234", "203.0.113.5", "10.0.0.2", false},
+		{"direct request from an allowed ip", nil, "203.0.113.9:1234", "", "203.0.113.9", true},
+	}
+
+	for _, tc := range cases {
+		router := gin.New()
+		require.NoError(t, setTrustedProxies(router, tc.trustedProxies), tc.name)
+
+		var clientIp string
+		var allowed bool
+		router.GET("/", func(c *gin.Context) {
+			clientIp = c.ClientIP()
+			allowed = kc.IsIpAllowed(clientIp)
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = tc.remoteAddr
+		if len(tc.forwardedFor) != 0 {
+			req.Header.Set("X-Forwarded-For", tc.forwardedFor)
+		}
+
+		// X-Real-Ip is not a remote ip header and must never be used for the client ip.
+		req.Header.Set("X-Real-Ip", "203.0.113.1")
+
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Equal(t, tc.clientIp, clientIp, tc.name)
+		assert.Equal(t, tc.allowed, allowed, tc.name)
+	}
+
+	assert.Error(t, setTrustedProxies(gin.New(), []string{"not-a-proxy"}))
+}
@@ -0,0 +1,148 @@
+package proxy
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/event"
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/bricks-cloud/bricksllm/internal/provider/anthropic"
+	"github.com/bricks-cloud/bricksllm/internal/provider/bedrock"
+	"github.com/bricks-cloud/bricksllm/internal/provider/gemini"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+	"github.com/bricks-cloud/bricksllm/internal/user"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	goopenai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	requestLimitHeader          = "X-BRICKS-RATELIMIT-LIMIT-REQUESTS"
+	requestLimitRemainingHeader = "X-BRICKS-RATELIMIT-REMAINING-REQUESTS"
+	tokenLimitHeader            = "X-BRICKS-RATELIMIT-LIMIT-TOKENS"
+	tokenLimitRemainingHeader   = "X-BRICKS-RATELIMIT-REMAINING-TOKENS"
+	tokenLimitResetHeader       = "X-BRICKS-RATELIMIT-RESET-TOKENS"
+)
+
+func estimatePromptTokens(e estimator, ae anthropicEstimator, ge geminiEstimator, be bedrockEstimator, input any) int {
+	switch r := input.(type) {
+	case *goopenai.ChatCompletionRequest:
+		tks, err := e.EstimateChatCompletionPromptTokenCounts(r.Model, r)
+		if err != nil {
+			return 0
+		}
+
+		return tks
+	case *anthropic.MessagesRequest:
+		return ae.CountMessagesTokens(r.Messages) + ae.Count(r.System)
+	case *anthropic.CompletionRequest:
+		return ae.Count(r.Prompt)
+	case *gemini.GenerateContentRequest:
+		return ge.CountPartsTokens(r.TextParts())
+	case *gemini.EmbedContentRequest:
+		if r.Content == nil {
+			return 0
+		}
+
+		return ge.CountPartsTokens(r.Content.Parts)
+	case *gemini.BatchEmbedContentsRequest:
+		return ge.CountPartsTokens(r.TextParts())
+	case bedrock.Request:
+		return be.CountContents(r.Contents())
+	}
+
+	return 0
+}
+
+func getTokenLimitReset(unit key.TimeUnit) time.Duration {
+	now := time.Now().UTC()
+
+	switch unit {
+	case key.MinuteTimeUnit:
+		return now.Truncate(time.Minute).Add(time.Minute).Sub(now)
+	case key.HourTimeUnit:
+		return now.Truncate(time.Hour).Add(time.Hour).Sub(now)
+	case key.DayTimeUnit:
+		return now.Truncate(24 * time.Hour).Add(24 * time.Hour).Sub(now)
+	}
+
+	return 0
+}
+
+// setTokenLimitHeaders sets the token rate limit headers from the token count of the
+// current window, which includes the estimated prompt tokens once they are reserved.
+func setTokenLimitHeaders(c *gin.Context, count int64, limit int, unit key.TimeUnit, estimated int, reserved bool) {
+	remaining := int64(limit) - count
+	if !reserved {
+		remaining -= int64(estimated)
+	}
+
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	c.Header(tokenLimitHeader, strconv.Itoa(limit))
+	c.Header(tokenLimitRemainingHeader, strconv.FormatInt(remaining, 10))
+	c.Header(tokenLimitResetHeader, getTokenLimitReset(unit).Round(time.Second).String())
+}
+
+// reserveTokenLimits atomically checks and reserves the estimated prompt tokens against
+// the token limits of the key and the user. It returns a message when a limit would be
+// exceeded, in which case a reservation already made for the key is released.
+func reserveTokenLimits(c *gin.Context, rlm rateLimitManager, log *zap.Logger, prod bool, kc *key.ResponseKey, u *user.User, estimated int, evt *event.EventWithRequestAndContent) string {
+	if kc.TokenLimitOverTime != 0 {
+		reserved, count, window, err := rlm.ReserveTokens(kc.KeyId, kc.TokenLimitUnit, kc.TokenLimitOverTime, int64(estimated))
+		if err != nil {
+			telemetry.Incr("bricksllm.proxy.reserve_token_limits.reserve_tokens_error", nil, 1)
+			logError(log, "error when reserving tokens", prod, err)
+		}
+
+		if err == nil {
+			setTokenLimitHeaders(c, count, kc.TokenLimitOverTime, kc.TokenLimitUnit, estimated, reserved)
+
+			if !reserved {
+				telemetry.Incr("bricksllm.proxy.reserve_token_limits.token_rate_limited", nil, 1)
+				return "[BricksLLM] too many tokens"
+			}
+
+			evt.ReservedTokenCount = estimated
+			evt.TokenWindow = window
+		}
+	}
+
+	if u != nil && u.TokenLimitOverTime != 0 {
+		reserved, count, window, err := rlm.ReserveUserTokens(u.Id, u.TokenLimitUnit, u.TokenLimitOverTime, int64(estimated))
+		if err != nil {
+			telemetry.Incr("bricksllm.proxy.reserve_token_limits.reserve_user_tokens_error", nil, 1)
+			logError(log, "error when reserving user tokens", prod, err)
+		}
+
+		if err == nil {
+			setTokenLimitHeaders(c, count, u.TokenLimitOverTime, u.TokenLimitUnit, estimated, reserved)
+
+			if !reserved {
+				telemetry.Incr("bricksllm.proxy.reserve_token_limits.user_token_rate_limited", nil, 1)
+
+				if evt.ReservedTokenCount != 0 {
+					err := rlm.CorrectTokens(kc.KeyId, kc.TokenLimitUnit, evt.TokenWindow, -int64(evt.ReservedTokenCount))
+					if err != nil {
+						telemetry.Incr("bricksllm.proxy.reserve_token_limits.release_tokens_error", nil, 1)
+						logError(log, "error when releasing reserved tokens", prod, err)
+					}
+
+					evt.ReservedTokenCount = 0
+					evt.TokenWindow = 0
+				}
+
+				return fmt.Sprintf("[BricksLLM] too many tokens for user: %s", u.Id)
+			}
+
+			evt.ReservedUserTokenCount = estimated
+			evt.UserTokenWindow = window
+		}
+	}
+
+	return ""
+}
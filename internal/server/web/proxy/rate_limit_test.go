@@ -0,0 +1,83 @@
+package proxy
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bricks-cloud/bricksllm/internal/event"
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/bricks-cloud/bricksllm/internal/user"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type fakeTokenLimiter struct {
+	counts map[string]int64
+}
+
+func (rl *fakeTokenLimiter) reserve(id string, limit int, tks int64) (bool, int64, int64, error) {
+	if rl.counts[id]+tks > int64(limit) {
+		return false, rl.counts[id], 1000, nil
+	}
+
+	rl.counts[id] += tks
+	return true, rl.counts[id], 1000, nil
+}
+
+func (rl *fakeTokenLimiter) Increment(keyId string, timeUnit key.TimeUnit) error {
+	return nil
+}
+
+func (rl *fakeTokenLimiter) ReserveTokens(keyId string, timeUnit key.TimeUnit, limit int, tks int64) (bool, int64, int64, error) {
+	return rl.reserve("key-"+keyId, limit, tks)
+}
+
+func (rl *fakeTokenLimiter) ReserveUserTokens(id string, timeUnit key.TimeUnit, limit int, tks int64) (bool, int64, int64, error) {
+	return rl.reserve("user-"+id, limit, tks)
+}
+
+func (rl *fakeTokenLimiter) CorrectTokens(keyId string, timeUnit key.TimeUnit, window int64, tks int64) error {
+	rl.counts["key-"+keyId] += tks
+	return nil
+}
+
+func (rl *fakeTokenLimiter) Allow(k *key.ResponseKey) (bool, int64, error) {
+	return true, 0, nil
+}
+
+func TestReserveTokenLimits(t *testing.T) {
+	rl := &fakeTokenLimiter{counts: map[string]int64{}}
+	kc := &key.ResponseKey{KeyId: "k", TokenLimitOverTime: 100, TokenLimitUnit: key.MinuteTimeUnit}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	evt := &event.EventWithRequestAndContent{}
+	assert.Empty(t, reserveTokenLimits(c, rl, zap.NewNop(), true, kc, nil, 60, evt))
+	assert.Equal(t, 60, evt.ReservedTokenCount)
+	assert.Equal(t, int64(1000), evt.TokenWindow)
+	assert.Equal(t, "100", w.Header().Get(tokenLimitHeader))
+	assert.Equal(t, "40", w.Header().Get(tokenLimitRemainingHeader))
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	evt = &event.EventWithRequestAndContent{}
+	assert.NotEmpty(t, reserveTokenLimits(c, rl, zap.NewNop(), true, kc, nil, 60, evt))
+	assert.Zero(t, evt.ReservedTokenCount)
+	assert.Equal(t, "0", w.Header().Get(tokenLimitRemainingHeader))
+	assert.Equal(t, int64(60), rl.counts["key-k"])
+}
+
+func TestReserveTokenLimits_ReleasesKeyTokensWhenUserIsLimited(t *testing.T) {
+	rl := &fakeTokenLimiter{counts: map[string]int64{"user-u": 90}}
+	kc := &key.ResponseKey{KeyId: "k", TokenLimitOverTime: 100, TokenLimitUnit: key.MinuteTimeUnit}
+	u := &user.User{Id: "u", TokenLimitOverTime: 100, TokenLimitUnit: key.MinuteTimeUnit}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	evt := &event.EventWithRequestAndContent{}
+	assert.Equal(t, "[BricksLLM] too many tokens for user: u", reserveTokenLimits(c, rl, zap.NewNop(), true, kc, u, 20, evt))
+	assert.Zero(t, evt.ReservedTokenCount)
+	assert.Zero(t, evt.ReservedUserTokenCount)
+	assert.Zero(t, rl.counts["key-k"])
+	assert.Equal(t, int64(90), rl.counts["user-u"])
+}
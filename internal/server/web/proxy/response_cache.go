@@ -0,0 +1,93 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/bricks-cloud/bricksllm/internal/provider"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	responseCacheHeader       = "X-BRICKS-CACHE"
+	responseCacheStatusHeader = "X-BRICKS-CACHE-STATUS"
+	defaultResponseCacheTtl   = 168 * time.Hour
+)
+
+func getResponseCacheTtl(kc *key.ResponseKey, settings []*provider.Setting, providerName string) (time.Duration, bool) {
+	enabled, ttl := false, ""
+
+	if kc != nil && kc.CacheConfig != nil {
+		enabled, ttl = kc.CacheConfig.Enabled, kc.CacheConfig.Ttl
+	}
+
+	if kc == nil || kc.CacheConfig == nil {
+		for _, setting := range settings {
+			if setting.Provider == providerName && setting.CacheConfig != nil {
+				enabled, ttl = setting.CacheConfig.Enabled, setting.CacheConfig.Ttl
+				break
+			}
+		}
+	}
+
+	if !enabled {
+		return 0, false
+	}
+
+	if len(ttl) == 0 {
+		return defaultResponseCacheTtl, true
+	}
+
+	parsed, err := time.ParseDuration(ttl)
+	if err != nil || parsed <= 0 {
+		return 0, false
+	}
+
+	return parsed, true
+}
+
+func setResponseCacheKey(c *gin.Context, kc *key.ResponseKey, settings []*provider.Setting, providerName string, body []byte) {
+	if c.GetBool("stream") || c.Request.Method != http.MethodPost {
+		return
+	}
+
+	ttl, enabled := getResponseCacheTtl(kc, settings, providerName)
+	if !enabled {
+		return
+	}
+
+	if c.Request.Header.Get(responseCacheHeader) == "no-store" {
+		c.Header(responseCacheStatusHeader, "BYPASS")
+		return
+	}
+
+	c.Set("response_cache_key", fmt.Sprintf("%s-%s-%s", c.FullPath(), kc.KeyId, body))
+	c.Set("response_cache_ttl", ttl)
+}
+
+func getCachedResponse(c *gin.Context, ca cache) []byte {
+	cacheKey := c.GetString("response_cache_key")
+	if len(cacheKey) == 0 {
+		return nil
+	}
+
+	bytes, err := ca.GetBytes(cacheKey)
+	if err != nil || len(bytes) == 0 {
+		c.Header(responseCacheStatusHeader, "MISS")
+		return nil
+	}
+
+	c.Header(responseCacheStatusHeader, "HIT")
+	return bytes
+}
+
+func storeCachedResponse(c *gin.Context, ca cache, data []byte) error {
+	cacheKey := c.GetString("response_cache_key")
+	if len(cacheKey) == 0 {
+		return nil
+	}
+
+	return ca.StoreBytes(cacheKey, data, c.GetDuration("response_cache_ttl"))
+}
@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/bricks-cloud/bricksllm/internal/provider"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetResponseCacheTtl(t *testing.T) {
+	settings := []*provider.Setting{
+		{Provider: "anthropic", CacheConfig: &provider.CacheConfig{Enabled: true, Ttl: "1h"}},
+		{Provider: "openai", CacheConfig: &provider.CacheConfig{Enabled: true}},
+	}
+
+	cases := []struct {
+		name     string
+		kc       *key.ResponseKey
+		provider string
+		ttl      time.Duration
+		enabled  bool
+	}{
+		{"key config wins over setting", &key.ResponseKey{CacheConfig: &key.CacheConfig{Enabled: true, Ttl: "5m"}}, "anthropic", 5 * time.Minute, true},
+		{"disabled key config wins over setting", &key.ResponseKey{CacheConfig: &key.CacheConfig{Enabled: false}}, "anthropic", 0, false},
+		{"setting config", &key.ResponseKey{}, "anthropic", time.Hour, true},
+		{"default ttl", &key.ResponseKey{}, "openai", defaultResponseCacheTtl, true},
+		{"no config", &key.ResponseKey{}, "azure", 0, false},
+		{"invalid ttl", &key.ResponseKey{CacheConfig: &key.CacheConfig{Enabled: true, Ttl: "-1h"}}, "openai", 0, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ttl, enabled := getResponseCacheTtl(c.kc, settings, c.provider)
+			assert.Equal(t, c.enabled, enabled)
+			assert.Equal(t, c.ttl, ttl)
+		})
+	}
+}
+
+func newTestResponseCacheContext(header string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/providers/openai/v1/chat/completions", nil)
+	if len(header) != 0 {
+		c.Request.Header.Set(responseCacheHeader, header)
+	}
+
+	return c, w
+}
+
+func TestResponseCache_MissThenHit(t *testing.T) {
+	kc := &key.ResponseKey{KeyId: "key", CacheConfig: &key.CacheConfig{Enabled: true, Ttl: "1m"}}
+	ca := newFakeCache()
+
+	c, w := newTestResponseCacheContext("")
+	setResponseCacheKey(c, kc, nil, "openai", []byte(`{"model":"gpt-4o"}`))
+	assert.Nil(t, getCachedResponse(c, ca))
+	assert.Equal(t, "MISS", w.Header().Get(responseCacheStatusHeader))
+	require.NoError(t, storeCachedResponse(c, ca, []byte("response")))
+
+	c, w = newTestResponseCacheContext("")
+	setResponseCacheKey(c, kc, nil, "openai", []byte(`{"model":"gpt-4o"}`))
+	assert.Equal(t, "response", string(getCachedResponse(c, ca)))
+	assert.Equal(t, "HIT", w.Header().Get(responseCacheStatusHeader))
+
+	c, _ = newTestResponseCacheContext("")
+	setResponseCacheKey(c, kc, nil, "openai", []byte(`{"model":"gpt-4o-mini"}`))
+	assert.Nil(t, getCachedResponse(c, ca))
+}
+
+func TestResponseCache_Skipped(t *testing.T) {
+	kc := &key.ResponseKey{KeyId: "key", CacheConfig: &key.CacheConfig{Enabled: true}}
+
+	c, w := newTestResponseCacheContext("no-store")
+	setResponseCacheKey(c, kc, nil, "openai", []byte("{}"))
+	assert.Empty(t, c.GetString("response_cache_key"))
+	assert.Equal(t, "BYPASS", w.Header().Get(responseCacheStatusHeader))
+
+	c, w = newTestResponseCacheContext("")
+	c.Set("stream", true)
+	setResponseCacheKey(c, kc, nil, "openai", []byte("{}"))
+	assert.Empty(t, c.GetString("response_cache_key"))
+	assert.Empty(t, w.Header().Get(responseCacheStatusHeader))
+
+	c, _ = newTestResponseCacheContext("")
+	setResponseCacheKey(c, &key.ResponseKey{KeyId: "key"}, nil, "openai", []byte("{}"))
+	assert.Empty(t, c.GetString("response_cache_key"))
+}
@@ -0,0 +1,341 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bricks-cloud/bricksllm/internal/policy"
+	"github.com/bricks-cloud/bricksllm/internal/provider/anthropic"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+	"github.com/gin-gonic/gin"
+	goopenai "github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+)
+
+// responsePolicyLookBehind is the number of bytes of streamed content held back
+// so that entities split across chunks are scanned as a whole.
+const responsePolicyLookBehind = 256
+
+// responsePolicyScanWindow is the minimum number of bytes of streamed content
+// released at once so that detectors run once per window rather than per chunk.
+const responsePolicyScanWindow = 2048
+
+func getResponsePolicy(c *gin.Context) *policy.Policy {
+	val, ok := c.Get("policy")
+	if !ok {
+		return nil
+	}
+
+	p, ok := val.(*policy.Policy)
+	if !ok || !p.ShouldInspect() {
+		return nil
+	}
+
+	return p
+}
+
+func getResponseAction(err error) string {
+	if err == nil {
+		return "allowed"
+	}
+
+	if _, ok := err.(blockedError); ok {
+		return "blocked"
+	}
+
+	if _, ok := err.(warnedError); ok {
+		return "warned"
+	}
+
+	if _, ok := err.(redactedError); ok {
+		return "redacted"
+	}
+
+	return ""
+}
+
+// filterResponseWithPolicy applies the response policy to a parsed provider response
+// and returns the bytes to forward. It returns false if an error response has been
+// written instead.
+func filterResponseWithPolicy(c *gin.Context, prod bool, log *zap.Logger, handler string, p *policy.Policy, res any, data []byte, scanner Scanner, cd CustomPolicyDetector) ([]byte, bool) {
+	err := p.FilterResponse(res, scanner, cd, log)
+	setResponseAction(c, getResponseAction(err))
+
+	if _, ok := err.(blockedError); ok {
+		telemetry.Incr("bricksllm.proxy."+handler+".response_blocked", nil, 1)
+		JSON(c, http.StatusForbidden, "[BricksLLM] response blocked")
+		return nil, false
+	}
+
+	if err != nil {
+		logError(log, "error when filtering response with policy", prod, err)
+	}
+
+	if _, ok := err.(redactedError); ok {
+		redacted, err := json.Marshal(res)
+		if err != nil {
+			telemetry.Incr("bricksllm.proxy."+handler+".json_marshal_error", nil, 1)
+			logError(log, "error when marshalling redacted response", prod, err)
+			JSON(c, http.StatusInternalServerError, "[BricksLLM] failed to redact response")
+			return nil, false
+		}
+
+		return redacted, true
+	}
+
+	return data, true
+}
+
+func writeStreamResponseBlocked(c *gin.Context, handler string) {
+	telemetry.Incr("bricksllm.proxy."+handler+".streaming_response_blocked", nil, 1)
+
+	bytes, err := json.Marshal(&goopenai.ErrorResponse{
+		Error: &goopenai.APIError{
+			Type:    "bricksllm_error",
+			Message: "[BricksLLM] response blocked",
+		},
+	})
+	if err == nil {
+		c.SSEvent("", string(bytes))
+	}
+
+	c.SSEvent("", " [DONE]")
+}
+
+func writeMessagesStreamResponseBlocked(c *gin.Context, handler string) {
+	telemetry.Incr("bricksllm.proxy."+handler+".streaming_response_blocked", nil, 1)
+
+	bytes, err := json.Marshal(&anthropic.ErrorResponse{
+		Error: &anthropic.Error{
+			Type:    "bricksllm_error",
+			Message: "[BricksLLM] response blocked",
+		},
+	})
+	if err == nil {
+		c.SSEvent(" error", string(bytes))
+	}
+
+	bytes, err = json.Marshal(&anthropic.MessagesStreamMessageStop{
+		Type: "message_stop",
+	})
+	if err == nil {
+		c.SSEvent(" message_stop", string(bytes))
+	}
+}
+
+var responseActionPriorities = map[string]int{
+	"allowed":  1,
+	"redacted": 2,
+	"warned":   3,
+	"blocked":  4,
+}
+
+func setResponseAction(c *gin.Context, action string) {
+	if responseActionPriorities[action] > responseActionPriorities[c.GetString("responseAction")] {
+		c.Set("responseAction", action)
+	}
+}
+
+type streamPolicyFilter struct {
+	c        *gin.Context
+	p        *policy.Policy
+	scanner  Scanner
+	cd       CustomPolicyDetector
+	log      *zap.Logger
+	pending  map[int]string
+	template *goopenai.ChatCompletionStreamResponse
+}
+
+func newStreamPolicyFilter(c *gin.Context, p *policy.Policy, scanner Scanner, cd CustomPolicyDetector, log *zap.Logger) *streamPolicyFilter {
+	return &streamPolicyFilter{
+		c:       c,
+		p:       p,
+		scanner: scanner,
+		cd:      cd,
+		log:     log,
+		pending: map[int]string{},
+	}
+}
+
+func getReleaseCut(pending string) int {
+	limit := len(pending) - responsePolicyLookBehind
+	if limit <= 0 {
+		return 0
+	}
+
+	cut := strings.LastIndexFunc(pending[:limit], unicode.IsSpace)
+	if cut > 0 {
+		return cut + 1
+	}
+
+	for limit > 0 && !utf8.RuneStart(pending[limit]) {
+		limit--
+	}
+
+	return limit
+}
+
+func (f *streamPolicyFilter) release(index int, content string, final bool) (string, error) {
+	pending := f.pending[index] + content
+
+	cut := len(pending)
+	if !final {
+		if len(pending) < responsePolicyScanWindow+responsePolicyLookBehind {
+			f.pending[index] = pending
+			return "", nil
+		}
+
+		cut = getReleaseCut(pending)
+	}
+
+	f.pending[index] = pending[cut:]
+	if cut == 0 {
+		return "", nil
+	}
+
+	updated, err := f.p.FilterOutput([]string{pending[:cut]}, f.scanner, f.cd, f.log)
+	setResponseAction(f.c, getResponseAction(err))
+	if _, ok := err.(blockedError); ok {
+		return "", err
+	}
+
+	if len(updated) != 1 {
+		return pending[:cut], nil
+	}
+
+	return updated[0], nil
+}
+
+// filterChunk withholds the trailing content of a chat completion chunk and
+// returns the chunk carrying the content released after applying the policy.
+func (f *streamPolicyFilter) filterChunk(data []byte) ([]byte, error) {
+	chunk := &goopenai.ChatCompletionStreamResponse{}
+	err := json.Unmarshal(data, chunk)
+	if err != nil {
+		return data, nil
+	}
+
+	f.template = chunk
+
+	for i, choice := range chunk.Choices {
+		released, err := f.release(choice.Index, choice.Delta.Content, len(choice.FinishReason) != 0)
+		if err != nil {
+			return nil, err
+		}
+
+		chunk.Choices[i].Delta.Content = released
+	}
+
+	return json.Marshal(chunk)
+}
+
+// flush releases the content still held back when the stream ends without finish reasons.
+func (f *streamPolicyFilter) flush() ([]byte, error) {
+	if f.template == nil {
+		return nil, nil
+	}
+
+	choices := []goopenai.ChatCompletionStreamChoice{}
+	for index, pending := range f.pending {
+		if len(pending) == 0 {
+			continue
+		}
+
+		released, err := f.release(index, "", true)
+		if err != nil {
+			return nil, err
+		}
+
+		choices = append(choices, goopenai.ChatCompletionStreamChoice{
+			Index: index,
+			Delta: goopenai.ChatCompletionStreamChoiceDelta{
+				Content: released,
+			},
+		})
+	}
+
+	if len(choices) == 0 {
+		return nil, nil
+	}
+
+	return json.Marshal(&goopenai.ChatCompletionStreamResponse{
+		ID:      f.template.ID,
+		Object:  f.template.Object,
+		Created: f.template.Created,
+		Model:   f.template.Model,
+		Choices: choices,
+	})
+}
+
+// filterStreamLine returns the data lines to forward for a line of an openai compatible
+// stream. Content still held back is released ahead of [DONE].
+func (f *streamPolicyFilter) filterStreamLine(line []byte) ([][]byte, error) {
+	if f == nil {
+		return [][]byte{line}, nil
+	}
+
+	if string(line) != "[DONE]" {
+		filtered, err := f.filterChunk(line)
+		if err != nil {
+			return nil, err
+		}
+
+		return [][]byte{filtered}, nil
+	}
+
+	flushed, err := f.flush()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(flushed) == 0 {
+		return [][]byte{line}, nil
+	}
+
+	return [][]byte{flushed, line}, nil
+}
+
+// filterBlockDelta withholds the trailing text of an anthropic content_block_delta event.
+func (f *streamPolicyFilter) filterBlockDelta(data []byte) ([]byte, error) {
+	delta := &anthropic.MessagesStreamBlockDelta{}
+	err := json.Unmarshal(data, delta)
+	if err != nil || delta.Delta.Type != "text_delta" {
+		return data, nil
+	}
+
+	released, err := f.release(delta.Index, delta.Delta.Text, false)
+	if err != nil {
+		return nil, err
+	}
+
+	delta.Delta.Text = released
+
+	return json.Marshal(delta)
+}
+
+// flushBlock releases the text held back for the anthropic content block being stopped
+// as a content_block_delta event to be sent ahead of the content_block_stop event.
+func (f *streamPolicyFilter) flushBlock(data []byte) ([]byte, error) {
+	stop := &anthropic.MessagesStreamBlockStop{}
+	err := json.Unmarshal(data, stop)
+	if err != nil || len(f.pending[stop.Index]) == 0 {
+		return nil, nil
+	}
+
+	released, err := f.release(stop.Index, "", true)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(&anthropic.MessagesStreamBlockDelta{
+		Type:  "content_block_delta",
+		Index: stop.Index,
+		Delta: anthropic.MessageResponseContent{
+			Type: "text_delta",
+			Text: released,
+		},
+	})
+}
@@ -0,0 +1,144 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bricks-cloud/bricksllm/internal/pii"
+	"github.com/bricks-cloud/bricksllm/internal/policy"
+	"github.com/bricks-cloud/bricksllm/internal/provider/anthropic"
+	"github.com/gin-gonic/gin"
+	goopenai "github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeScanner struct{}
+
+func (s *fakeScanner) Scan(input []string) (*pii.Result, error) {
+	return &pii.Result{}, nil
+}
+
+func newTestStreamPolicyFilter(action policy.Action) (*streamPolicyFilter, *gin.Context) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	p := &policy.Policy{
+		RegexConfig: &policy.RegexConfig{
+			RegularExpressionRules: []*policy.RegularExpressionRule{{Definition: `secret-\d+`, Action: action}},
+		},
+	}
+
+	return newStreamPolicyFilter(c, p, &fakeScanner{}, nil, zap.NewNop()), c
+}
+
+func newTestChunk(t *testing.T, content string, finishReason goopenai.FinishReason) []byte {
+	bs, err := json.Marshal(&goopenai.ChatCompletionStreamResponse{
+		ID: "chatcmpl-1",
+		Choices: []goopenai.ChatCompletionStreamChoice{
+			{Delta: goopenai.ChatCompletionStreamChoiceDelta{Content: content}, FinishReason: finishReason},
+		},
+	})
+	require.NoError(t, err)
+
+	return bs
+}
+
+func getChunkContent(t *testing.T, data []byte) string {
+	chunk := &goopenai.ChatCompletionStreamResponse{}
+	require.NoError(t, json.Unmarshal(data, chunk))
+
+	content := ""
+	for _, choice := range chunk.Choices {
+		content += choice.Delta.Content
+	}
+
+	return content
+}
+
+func TestGetReleaseCut(t *testing.T) {
+	assert.Equal(t, 0, getReleaseCut(strings.Repeat("a", responsePolicyLookBehind)))
+
+	pending := "hello " + strings.Repeat("a", responsePolicyLookBehind)
+	assert.Equal(t, len("hello "), getReleaseCut(pending))
+
+	pending = strings.Repeat("é", responsePolicyLookBehind)
+	cut := getReleaseCut(pending)
+	assert.True(t, cut > 0 && cut%2 == 0)
+}
+
+func TestStreamPolicyFilter_RedactsEntitiesSplitAcrossChunks(t *testing.T) {
+	f, c := newTestStreamPolicyFilter(policy.AllowButRedact)
+
+	released := ""
+	for _, content := range []string{"the code is sec", "ret-", "123 ok"} {
+		lines, err := f.filterStreamLine(newTestChunk(t, content, ""))
+		require.NoError(t, err)
+		require.Len(t, lines, 1)
+		released += getChunkContent(t, lines[0])
+	}
+
+	assert.Empty(t, released)
+
+	lines, err := f.filterStreamLine([]byte("[DONE]"))
+	require.NoError(t, err)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "the code is *** ok", getChunkContent(t, lines[0]))
+	assert.Equal(t, "[DONE]", string(lines[1]))
+	assert.Equal(t, "redacted", c.GetString("responseAction"))
+}
+
+func TestStreamPolicyFilter_ReleasesPerWindow(t *testing.T) {
+	f, _ := newTestStreamPolicyFilter(policy.AllowButRedact)
+
+	content := strings.Repeat("word ", (responsePolicyScanWindow+responsePolicyLookBehind)/5+1)
+	lines, err := f.filterStreamLine(newTestChunk(t, content, ""))
+	require.NoError(t, err)
+
+	released := getChunkContent(t, lines[0])
+	assert.NotEmpty(t, released)
+	assert.Equal(t, content, released+f.pending[0])
+
+	lines, err = f.filterStreamLine(newTestChunk(t, "", goopenai.FinishReasonStop))
+	require.NoError(t, err)
+	assert.Empty(t, f.pending[0])
+	assert.Equal(t, content, released+getChunkContent(t, lines[0]))
+}
+
+func TestStreamPolicyFilter_Blocks(t *testing.T) {
+	f, c := newTestStreamPolicyFilter(policy.Block)
+
+	_, err := f.filterStreamLine(newTestChunk(t, "secret-1", goopenai.FinishReasonStop))
+	require.Error(t, err)
+	assert.Equal(t, "blocked", c.GetString("responseAction"))
+}
+
+func TestStreamPolicyFilter_AnthropicBlocks(t *testing.T) {
+	f, _ := newTestStreamPolicyFilter(policy.AllowButRedact)
+
+	delta, err := json.Marshal(&anthropic.MessagesStreamBlockDelta{
+		Type:  "content_block_delta",
+		Index: 0,
+		Delta: anthropic.MessageResponseContent{Type: "text_delta", Text: "key secret-42"},
+	})
+	require.NoError(t, err)
+
+	filtered, err := f.filterBlockDelta(delta)
+	require.NoError(t, err)
+
+	held := &anthropic.MessagesStreamBlockDelta{}
+	require.NoError(t, json.Unmarshal(filtered, held))
+	assert.Empty(t, held.Delta.Text)
+
+	flushed, err := f.flushBlock([]byte(`{"type":"content_block_stop","index":0}`))
+	require.NoError(t, err)
+
+	released := &anthropic.MessagesStreamBlockDelta{}
+	require.NoError(t, json.Unmarshal(flushed, released))
+	assert.Equal(t, "key ***", released.Delta.Text)
+
+	flushed, err = f.flushBlock([]byte(`{"type":"content_block_stop","index":0}`))
+	require.NoError(t, err)
+	assert.Nil(t, flushed)
+}
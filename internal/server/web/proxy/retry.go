@@ -0,0 +1,241 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/event"
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/bricks-cloud/bricksllm/internal/message"
+	"github.com/bricks-cloud/bricksllm/internal/provider"
+	"github.com/bricks-cloud/bricksllm/internal/route"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+	"github.com/bricks-cloud/bricksllm/internal/util"
+	"github.com/cenkalti/backoff/v4"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// retrier retries requests sent to providers according to the retry config of a key.
+// Every attempt except the last one is published as its own event sharing the
+// correlation id of the request.
+type retrier struct {
+	c        *gin.Context
+	a        authenticator
+	pub      publisher
+	kc       *key.ResponseKey
+	settings []*provider.Setting
+	userId   string
+	log      *zap.Logger
+}
+
+func newRetrier(c *gin.Context, a authenticator, pub publisher, kc *key.ResponseKey, settings []*provider.Setting, userId string, log *zap.Logger) *retrier {
+	return &retrier{
+		c:        c,
+		a:        a,
+		pub:      pub,
+		kc:       kc,
+		settings: settings,
+		userId:   userId,
+		log:      log,
+	}
+}
+
+// canFailover reports whether requests of a provider can be sent with the credentials
+// of another setting without changing the url.
+func canFailover(provider string) bool {
+	return provider == "openai" || provider == "anthropic" || provider == "deepinfra"
+}
+
+func (r *retrier) getFailoverSettings() []*provider.Setting {
+	if len(r.settings) == 0 {
+		return nil
+	}
+
+	used := r.settings[0]
+	if !r.kc.RetryConfig.Failover || !canFailover(used.Provider) {
+		return []*provider.Setting{used}
+	}
+
+	selected := []*provider.Setting{}
+	for _, setting := range r.settings {
+		if setting.Provider == used.Provider {
+			selected = append(selected, setting)
+		}
+	}
+
+	return selected
+}
+
+func (r *retrier) publishAttempt(setting *provider.Setting, status int, latency time.Duration, responseBytes []byte) {
+	requestBytes := []byte(`{}`)
+	if r.kc.ShouldLogRequest {
+		if data, ok := r.c.Get("requestBytes"); ok {
+			if converted, ok := data.([]byte); ok {
+				requestBytes = converted
+			}
+		}
+	}
+
+	if !r.kc.ShouldLogResponse || len(responseBytes) == 0 {
+		responseBytes = []byte(`{}`)
+	}
+
+	settingId := ""
+	if setting != nil {
+		settingId = setting.Id
+	}
+
+	evt := &event.Event{
+		Id:             util.NewUuid(),
+		CreatedAt:      time.Now().Unix(),
+		Tags:           r.kc.Tags,
+		KeyId:          r.kc.KeyId,
+		Provider:       getProvider(r.c),
+		Model:          r.c.GetString("model"),
+		RequestedModel: r.c.GetString("requestedModel"),
+		Status:         status,
+		LatencyInMs:    int(latency.Milliseconds()),
+		Path:           r.c.Request.URL.Path,
+		Method:         r.c.Request.Method,
+		CustomId:       r.c.Request.Header.Get("X-CUSTOM-EVENT-ID"),
+		Request:        requestBytes,
+		Response:       responseBytes,
+		UserId:         r.userId,
+		PolicyId:       r.c.GetString("policyId"),
+		Action:         r.c.GetString("action"),
+		CorrelationId:  r.c.GetString(util.STRING_CORRELATION_ID),
+		Metadata:       []byte(`{}`),
+	}
+
+	r.pub.Publish(message.Message{
+		Type: "event",
+		Data: &event.EventWithRequestAndContent{
+			Event:            evt,
+			Key:              r.kc,
+			SettingId:        settingId,
+			IsRetriedAttempt: true,
+			UpstreamCalled:   true,
+			UpstreamFailed:   isUpstreamFailure(status),
+		},
+	})
+}
+
+func (r *retrier) do(client http.Client, req *http.Request) (*http.Response, error) {
+	rc := r.kc.RetryConfig
+
+	var body []byte
+	if req.Body != nil {
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Body.Close()
+		body = data
+	}
+
+	settings := r.getFailoverSettings()
+	b := route.InitializeBackoff(rc.Strategy, rc.GetInterval())
+	b.Reset()
+
+	maxAttempts := rc.GetMaxAttempts()
+	for attempt := 1; ; attempt++ {
+		var setting *provider.Setting
+		if len(settings) != 0 {
+			setting = settings[(attempt-1)%len(settings)]
+		}
+
+		areq := req.Clone(req.Context())
+		areq.Body = io.NopCloser(bytes.NewReader(body))
+		areq.ContentLength = int64(len(body))
+
+		if attempt > 1 && setting != nil && setting != r.settings[0] {
+			err := r.a.RewriteHttpAuthHeaderWithSetting(r.c.Request.URL.RequestURI(), areq, setting)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		start := time.Now()
+		res, err := client.Do(areq)
+
+		retryable := err != nil || rc.ShouldRetry(res.StatusCode)
+		if !retryable || attempt >= maxAttempts {
+			if setting != nil && attempt > 1 {
+				r.c.Set("settingId", setting.Id)
+				if setting.CostMap != nil {
+					r.c.Set("cost_map", setting.CostMap)
+				}
+			}
+
+			return res, err
+		}
+
+		dur := time.Since(start)
+		status := 0
+		var responseBytes []byte
+		if err == nil {
+			status = res.StatusCode
+			responseBytes, _ = io.ReadAll(res.Body)
+			res.Body.Close()
+		}
+
+		telemetry.Incr("bricksllm.proxy.retrier.do.retry", []string{"status:" + strconv.Itoa(status)}, 1)
+		r.log.Debug("retrying request sent to provider", zap.Int("attempt", attempt), zap.Int("status", status), zap.Error(err))
+		r.publishAttempt(setting, status, dur, responseBytes)
+
+		wait := b.NextBackOff()
+		if wait == backoff.Stop {
+			wait = rc.GetInterval()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// isUpstreamFailure reports whether a provider failed to serve a request. A status of
+// 0 means that no response was received.
+func isUpstreamFailure(status int) bool {
+	return status == 0 || status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// setUpstreamOutcome records whether the provider setting a request was sent to
+// served it so that gateway errors are not attributed to the provider.
+func setUpstreamOutcome(c *gin.Context, res *http.Response, err error) {
+	status := 0
+	if err == nil && res != nil {
+		status = res.StatusCode
+	}
+
+	c.Set("upstreamFailed", isUpstreamFailure(status))
+}
+
+// doRequest sends a request to a provider, retrying it when the key has a retry config.
+func doRequest(c *gin.Context, client http.Client, req *http.Request) (*http.Response, error) {
+	res, err := sendRequest(c, client, req)
+	setUpstreamOutcome(c, res, err)
+
+	return res, err
+}
+
+func sendRequest(c *gin.Context, client http.Client, req *http.Request) (*http.Response, error) {
+	val, ok := c.Get("retrier")
+	if !ok {
+		return client.Do(req)
+	}
+
+	r, ok := val.(*retrier)
+	if !ok {
+		return client.Do(req)
+	}
+
+	return r.do(client, req)
+}
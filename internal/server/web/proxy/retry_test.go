@@ -0,0 +1,143 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bricks-cloud/bricksllm/internal/event"
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/bricks-cloud/bricksllm/internal/message"
+	"github.com/bricks-cloud/bricksllm/internal/provider"
+	"github.com/bricks-cloud/bricksllm/internal/util"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeAuthenticator struct{}
+
+func (a *fakeAuthenticator) AuthenticateHttpRequest(req *http.Request) (*key.ResponseKey, []*provider.Setting, string, error) {
+	return nil, nil, "", nil
+}
+
+func (a *fakeAuthenticator) RewriteHttpAuthHeaderWithSetting(uri string, req *http.Request, setting *provider.Setting) error {
+	req.Header.Set("Authorization", "Bearer "+setting.GetParam("apikey"))
+	return nil
+}
+
+type fakePublisher struct {
+	lock     sync.Mutex
+	messages []message.Message
+}
+
+func (p *fakePublisher) Publish(m message.Message) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.messages = append(p.messages, m)
+}
+
+func newTestRetrier(rc *key.RetryConfig, settings []*provider.Setting) (*retrier, *fakePublisher, *gin.Context) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/providers/openai/v1/chat/completions", nil)
+	c.Set(util.STRING_CORRELATION_ID, "cid")
+
+	pub := &fakePublisher{}
+	kc := &key.ResponseKey{KeyId: "k", RetryConfig: rc}
+
+	return newRetrier(c, &fakeAuthenticator{}, pub, kc, settings, "", zap.NewNop()), pub, c
+}
+
+func TestRetrier_RetriesWithFailover(t *testing.T) {
+	auths := []string{}
+	bodies := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auths = append(auths, r.Header.Get("Authorization"))
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+
+		if len(auths) < 3 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	settings := []*provider.Setting{
+		{Id: "a", Provider: "openai", Setting: map[string]string{"apikey": "key-a"}},
+		{Id: "b", Provider: "openai", Setting: map[string]string{"apikey": "key-b"}, CostMap: &provider.CostMap{}},
+		{Id: "c", Provider: "azure", Setting: map[string]string{"apikey": "key-c"}},
+	}
+
+	r, pub, c := newTestRetrier(&key.RetryConfig{MaxAttempts: 3, Interval: "1ms", Failover: true}, settings)
+
+	req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(`{"model":"gpt-4o"}`))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer key-a")
+
+	res, err := r.do(http.Client{}, req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, []string{"Bearer key-a", "Bearer key-b", "Bearer key-a"}, auths)
+	assert.Equal(t, []string{`{"model":"gpt-4o"}`, `{"model":"gpt-4o"}`, `{"model":"gpt-4o"}`}, bodies)
+	assert.Equal(t, "a", c.GetString("settingId"))
+
+	require.Len(t, pub.messages, 2)
+	for _, m := range pub.messages {
+		evt := m.Data.(*event.EventWithRequestAndContent)
+		assert.True(t, evt.IsRetriedAttempt)
+		assert.True(t, evt.UpstreamFailed)
+		assert.Equal(t, "cid", evt.Event.CorrelationId)
+		assert.Equal(t, http.StatusTooManyRequests, evt.Event.Status)
+	}
+}
+
+func TestRetrier_StopsOnNonRetryableStatus(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	r, pub, _ := newTestRetrier(&key.RetryConfig{MaxAttempts: 3, Interval: "1ms"}, []*provider.Setting{{Id: "a", Provider: "openai"}})
+
+	req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("{}"))
+	require.NoError(t, err)
+
+	res, err := r.do(http.Client{}, req)
+	require.NoError(t, err)
+	res.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	assert.Equal(t, 1, calls)
+	assert.Empty(t, pub.messages)
+}
+
+func TestRetryConfig(t *testing.T) {
+	var rc *key.RetryConfig
+	assert.Equal(t, 1, rc.GetMaxAttempts())
+	assert.True(t, rc.ShouldRetry(http.StatusServiceUnavailable))
+	assert.False(t, rc.ShouldRetry(http.StatusBadRequest))
+
+	rc = &key.RetryConfig{StatusCodes: []int{http.StatusBadRequest}}
+	assert.True(t, rc.ShouldRetry(http.StatusBadRequest))
+	assert.False(t, rc.ShouldRetry(http.StatusTooManyRequests))
+}
+
+func TestIsUpstreamFailure(t *testing.T) {
+	assert.True(t, isUpstreamFailure(0))
+	assert.True(t, isUpstreamFailure(http.StatusTooManyRequests))
+	assert.True(t, isUpstreamFailure(http.StatusBadGateway))
+	assert.False(t, isUpstreamFailure(http.StatusBadRequest))
+	assert.False(t, isUpstreamFailure(http.StatusOK))
+}
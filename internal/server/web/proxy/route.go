@@ -1,7 +1,11 @@
 package proxy
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -14,6 +18,7 @@ import (
 	"github.com/bricks-cloud/bricksllm/internal/util"
 	"github.com/gin-gonic/gin"
 	goopenai "github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
 )
 
 type routeManager interface {
@@ -22,10 +27,18 @@ type routeManager interface {
 
 type cache interface {
 	StoreBytes(key string, value []byte, ttl time.Duration) error
+	StoreBytesIfNotExists(key string, value []byte, ttl time.Duration) (bool, error)
 	GetBytes(key string) ([]byte, error)
 }
 
-func getRouteHandler(prod bool, ca cache, aoe azureEstimator, e estimator, client http.Client, rec recorder) gin.HandlerFunc {
+type semanticCache interface {
+	StoreBytes(scope string, embedding []float32, value []byte, ttl time.Duration) error
+	GetBytes(scope string, embedding []float32, threshold float64) ([]byte, float64, error)
+}
+
+const semanticCacheEmbeddingTimeout = 10 * time.Second
+
+func getRouteHandler(prod bool, ca cache, sc semanticCache, aoe azureEstimator, e estimator, ae anthropicEstimator, die deepinfraEstimator, ge geminiEstimator, client http.Client, rec recorder, scanner Scanner, cd CustomPolicyDetector) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		log := util.GetLogFromCtx(c)
 		trueStart := time.Now()
@@ -99,6 +112,7 @@ func getRouteHandler(prod bool, ca cache, aoe azureEstimator, e estimator, clien
 			PolicyId:      c.GetString("policyId"),
 			Action:        c.GetString("action"),
 			CorrelationId: cid,
+			Stream:        c.GetBool("stream"),
 		}
 
 		val, exists := c.Get("requestBytes")
@@ -107,6 +121,52 @@ func getRouteHandler(prod bool, ca cache, aoe azureEstimator, e estimator, clien
 			rreq.Request = bs
 		}
 
+		semanticScope := c.GetString("semantic_cache_scope")
+		semanticInput := c.GetString("semantic_cache_input")
+		var embedding []float32
+
+		if rc.CacheConfig.IsSemantic() && len(semanticInput) != 0 && len(semanticScope) != 0 {
+			ctx, cancel := context.WithTimeout(context.Background(), semanticCacheEmbeddingTimeout)
+			created, tokens, err := rreq.CreateEmbedding(ctx, rc.CacheConfig.EmbeddingModel, semanticInput)
+			cancel()
+
+			if err != nil {
+				telemetry.Incr("bricksllm.proxy.get_route_handeler.create_embedding_error", tags, 1)
+				logError(log, "error when creating embedding for semantic cache", prod, err)
+			}
+
+			if err == nil {
+				embeddingCost, err := e.EstimateEmbeddingsInputCost(rc.CacheConfig.EmbeddingModel, tokens)
+				if err != nil {
+					telemetry.Incr("bricksllm.proxy.get_route_handeler.estimate_embedding_cost_error", tags, 1)
+					logError(log, "error when estimating semantic cache embedding cost", prod, err)
+				}
+
+				embedding = created
+				bytes, _, err := sc.GetBytes(semanticScope, embedding, rc.CacheConfig.SimilarityThreshold)
+				if err != nil {
+					telemetry.Incr("bricksllm.proxy.get_route_handeler.semantic_cache_get_bytes_error", tags, 1)
+					logError(log, "error when getting semantic cached response", prod, err)
+				}
+
+				if err == nil && len(bytes) != 0 {
+					telemetry.Incr("bricksllm.proxy.get_route_handeler.success", nil, 1)
+					telemetry.Incr("bricksllm.proxy.get_route_handeler.semantic_cache_hit", nil, 1)
+					telemetry.Timing("bricksllm.proxy.get_route_handeler.success_latency", time.Since(trueStart), nil, 1)
+
+					c.Set("provider", "cached")
+					c.Data(http.StatusOK, "application/json", bytes)
+					return
+				}
+
+				// a cache hit is served without cost so the embedding is only billed when the
+				// request is sent to the provider
+				defer func() {
+					c.Set("costInUsd", c.GetFloat64("costInUsd")+embeddingCost)
+				}()
+			}
+		}
+
 		runRes, err := rc.RunStepsV2(rreq, rec, log, kc)
 		if err != nil {
 			telemetry.Incr("bricksllm.proxy.get_route_handeler.run_steps_error", tags, 1)
@@ -119,6 +179,14 @@ func getRouteHandler(prod bool, ca cache, aoe azureEstimator, e estimator, clien
 
 		c.Set("model", runRes.Model)
 		c.Set("provider", runRes.Provider)
+		c.Set("settingId", runRes.SettingId)
+		setUpstreamOutcome(c, runRes.Response, nil)
+
+		if _, ok := c.Get("route_cost_map"); !ok {
+			if setting, ok := settingsMap[runRes.SettingId]; ok && setting.CostMap != nil {
+				c.Set("route_cost_map", setting.CostMap)
+			}
+		}
 
 		res := runRes.Response
 
@@ -127,6 +195,23 @@ func getRouteHandler(prod bool, ca cache, aoe azureEstimator, e estimator, clien
 		dur := time.Since(start)
 		telemetry.Timing("bricksllm.proxy.get_route_handeler.latency", dur, nil, 1)
 
+		if res.StatusCode == http.StatusOK && rreq.Stream {
+			for name, values := range res.Header {
+				for _, value := range values {
+					c.Header(name, value)
+				}
+			}
+
+			var sf *streamPolicyFilter
+			if p := getResponsePolicy(c); p != nil && !rc.ShouldRunEmbeddings() {
+				sf = newStreamPolicyFilter(c, p, scanner, cd, log)
+			}
+
+			streamRouteResponse(c, prod, log, res.Body, runRes, e, aoe, ae, die, ge, sf)
+			telemetry.Timing("bricksllm.proxy.get_route_handeler.streaming_latency", time.Since(start), nil, 1)
+			return
+		}
+
 		bytes := runRes.Data
 
 		if res.StatusCode == http.StatusOK {
@@ -140,22 +225,46 @@ func getRouteHandler(prod bool, ca cache, aoe azureEstimator, e estimator, clien
 			telemetry.Incr("bricksllm.proxy.get_route_handeler.success", nil, 1)
 			telemetry.Timing("bricksllm.proxy.get_route_handeler.success_latency", dur, nil, 1)
 
-			if shouldCache && rc.CacheConfig != nil {
+			if p := getResponsePolicy(c); p != nil && !rc.ShouldRunEmbeddings() {
+				chatRes := &goopenai.ChatCompletionResponse{}
+				err := json.Unmarshal(bytes, chatRes)
+				if err != nil {
+					logError(log, "error when unmarshalling route chat completion response", prod, err)
+				}
+
+				if err == nil {
+					filtered, ok := filterResponseWithPolicy(c, prod, log, "get_route_handeler", p, chatRes, bytes, scanner, cd)
+					if !ok {
+						return
+					}
+
+					bytes = filtered
+				}
+			}
+
+			if (shouldCache || len(embedding) != 0) && rc.CacheConfig != nil {
 				parsed, err := time.ParseDuration(rc.CacheConfig.Ttl)
 				if err != nil {
 					logError(log, "error when parsing cache config ttl", prod, err)
 				}
 
-				if err == nil {
+				if err == nil && shouldCache {
 					err := ca.StoreBytes(cacheKey, bytes, parsed)
 					if err != nil {
 						logError(log, "error when storing cached response", prod, err)
 					}
 				}
 
+				if err == nil && len(embedding) != 0 {
+					err := sc.StoreBytes(semanticScope, embedding, bytes, parsed)
+					if err != nil {
+						telemetry.Incr("bricksllm.proxy.get_route_handeler.semantic_cache_store_bytes_error", tags, 1)
+						logError(log, "error when storing semantic cached response", prod, err)
+					}
+				}
 			}
 
-			err = parseResult(c, rc.ShouldRunEmbeddings(), bytes, e, aoe, runRes.Model, runRes.Provider)
+			err = parseResult(c, rc.ShouldRunEmbeddings(), bytes, e, aoe, ae, die, ge, runRes.Model, runRes.Provider)
 			if err != nil {
 				logError(log, "error when parsing run steps result", prod, err)
 			}
@@ -184,7 +293,7 @@ func getRouteHandler(prod bool, ca cache, aoe azureEstimator, e estimator, clien
 	}
 }
 
-func parseResult(c *gin.Context, runEmbeddings bool, bytes []byte, e estimator, aoe azureEstimator, model, provider string) error {
+func parseResult(c *gin.Context, runEmbeddings bool, bytes []byte, e estimator, aoe azureEstimator, ae anthropicEstimator, die deepinfraEstimator, ge geminiEstimator, model, provider string) error {
 	base64ChatRes := &EmbeddingResponseBase64{}
 	chatRes := &EmbeddingResponse{}
 
@@ -226,20 +335,24 @@ func parseResult(c *gin.Context, runEmbeddings bool, bytes []byte, e estimator,
 			promptTokenCounts = chatRes.Usage.PromptTokens
 		}
 
+		var err error
 		if provider == "azure" {
-			ecost, err := aoe.EstimateEmbeddingsInputCost(model, totalTokens)
-			if err != nil {
-				return err
-			}
-
-			cost = ecost
+			cost, err = aoe.EstimateEmbeddingsInputCost(model, totalTokens)
 		} else if provider == "openai" {
-			ecost, err := e.EstimateEmbeddingsInputCost(model, totalTokens)
-			if err != nil {
-				return err
-			}
+			cost, err = e.EstimateEmbeddingsInputCost(model, totalTokens)
+		} else if provider == "deepinfra" {
+			cost, err = die.EstimateEmbeddingsInputCost(model, totalTokens)
+		} else if provider == "gemini" {
+			cost, err = ge.EstimateEmbeddingsCost(model, totalTokens)
+		}
 
-			cost = ecost
+		if newCost := estimateRouteCostWithCostMap(c, true, model, totalTokens, 0); newCost != 0 {
+			cost = newCost
+			err = nil
+		}
+
+		if err != nil {
+			return err
 		}
 
 		// micros := int64(cost * 1000000)
@@ -260,17 +373,19 @@ func parseResult(c *gin.Context, runEmbeddings bool, bytes []byte, e estimator,
 		promptTokenCounts = chatRes.Usage.PromptTokens
 		completionTokenCounts = chatRes.Usage.CompletionTokens
 
-		if provider == "azure" {
-			cost, err = aoe.EstimateTotalCost(chatRes.Model, chatRes.Usage.PromptTokens, chatRes.Usage.CompletionTokens)
-			if err != nil {
-				return err
-			}
+		estimatedModel := model
+		if provider == "azure" || provider == "openai" {
+			estimatedModel = chatRes.Model
+		}
 
-		} else if provider == "openai" {
-			cost, err = e.EstimateTotalCost(chatRes.Model, chatRes.Usage.PromptTokens, chatRes.Usage.CompletionTokens)
-			if err != nil {
-				return err
-			}
+		cost, err = estimateRouteChatCost(&chatRes.Usage, e, aoe, ae, die, ge, estimatedModel, provider)
+		if newCost := estimateRouteCostWithCostMap(c, false, model, promptTokenCounts, completionTokenCounts); newCost != 0 {
+			cost = newCost
+			err = nil
+		}
+
+		if err != nil {
+			return err
 		}
 
 		// micros := int64(cost * 1000000)
@@ -282,3 +397,171 @@ func parseResult(c *gin.Context, runEmbeddings bool, bytes []byte, e estimator,
 
 	return nil
 }
+
+func streamRouteResponse(c *gin.Context, prod bool, log *zap.Logger, body io.Reader, runRes *route.Response, e estimator, aoe azureEstimator, ae anthropicEstimator, die deepinfraEstimator, ge geminiEstimator, sf *streamPolicyFilter) {
+	telemetry.Incr("bricksllm.proxy.get_route_handeler.streaming_requests", nil, 1)
+
+	buffer := bufio.NewReader(body)
+	content := ""
+	streamingResponse := [][]byte{}
+	var usage *goopenai.Usage
+
+	defer func() {
+		c.Set("content", content)
+		c.Set("streaming_response", bytes.Join(streamingResponse, []byte{'\n'}))
+
+		if runRes.Usage != nil && runRes.Usage.PromptTokens != 0 {
+			usage = runRes.Usage
+		}
+
+		if usage == nil {
+			return
+		}
+
+		err := setRouteStreamingCost(c, usage, e, aoe, ae, die, ge, runRes.Model, runRes.Provider)
+		if err != nil {
+			telemetry.Incr("bricksllm.proxy.get_route_handeler.set_route_streaming_cost_error", nil, 1)
+			logError(log, "error when estimating route streaming cost", prod, err)
+		}
+	}()
+
+	c.Stream(func(w io.Writer) bool {
+		raw, err := buffer.ReadBytes('\n')
+		if err != nil {
+			if err == io.EOF {
+				return false
+			}
+
+			if errors.Is(err, context.DeadlineExceeded) {
+				telemetry.Incr("bricksllm.proxy.get_route_handeler.context_deadline_exceeded_error", nil, 1)
+				logError(log, "context deadline exceeded when reading bytes from route streaming response", prod, err)
+
+				return false
+			}
+
+			telemetry.Incr("bricksllm.proxy.get_route_handeler.read_bytes_error", nil, 1)
+			logError(log, "error when reading bytes from route streaming response", prod, err)
+
+			apiErr := &goopenai.ErrorResponse{
+				Error: &goopenai.APIError{
+					Type:    "bricksllm_error",
+					Message: err.Error(),
+				},
+			}
+
+			bytes, err := json.Marshal(apiErr)
+			if err != nil {
+				telemetry.Incr("bricksllm.proxy.get_route_handeler.json_marshal_error", nil, 1)
+				logError(log, "error when marshalling bytes for route streaming error response", prod, err)
+				return false
+			}
+
+			c.SSEvent("", string(bytes))
+			c.SSEvent("", " [DONE]")
+			return false
+		}
+
+		streamingResponse = append(streamingResponse, raw)
+
+		noSpaceLine := bytes.TrimSpace(raw)
+		if !bytes.HasPrefix(noSpaceLine, headerData) {
+			return true
+		}
+
+		noPrefixLine := bytes.TrimSpace(bytes.TrimPrefix(noSpaceLine, headerData))
+
+		forwarded, err := sf.filterStreamLine(noPrefixLine)
+		if err != nil {
+			writeStreamResponseBlocked(c, "get_route_handeler")
+			return false
+		}
+
+		for _, line := range forwarded {
+			c.SSEvent("", " "+string(line))
+		}
+
+		if string(noPrefixLine) == "[DONE]" {
+			return false
+		}
+
+		chatCompletionStreamResp := &goopenai.ChatCompletionStreamResponse{}
+		err = json.Unmarshal(noPrefixLine, chatCompletionStreamResp)
+		if err != nil {
+			telemetry.Incr("bricksllm.proxy.get_route_handeler.completion_response_unmarshall_error", nil, 1)
+			logError(log, "error when unmarshalling route chat completion stream response", prod, err)
+		}
+
+		if err == nil {
+			if len(chatCompletionStreamResp.Choices) > 0 && len(chatCompletionStreamResp.Choices[0].Delta.Content) != 0 {
+				content += chatCompletionStreamResp.Choices[0].Delta.Content
+			}
+
+			if chatCompletionStreamResp.Usage != nil {
+				usage = chatCompletionStreamResp.Usage
+			}
+		}
+
+		return true
+	})
+}
+
+// estimateRouteCostWithCostMap returns the cost based on the cost map of the provider
+// setting a unified route resolved to, which takes precedence over the built in cost tables.
+func estimateRouteCostWithCostMap(c *gin.Context, runEmbeddings bool, model string, promptTks, completionTks int) float64 {
+	m, exists := c.Get("route_cost_map")
+	if !exists {
+		return 0
+	}
+
+	converted, ok := m.(*provider.CostMap)
+	if !ok || converted == nil {
+		return 0
+	}
+
+	if runEmbeddings {
+		cost, _ := provider.EstimateCostWithCostMap(model, promptTks, 1000, converted.EmbeddingsCostPerModel)
+		return cost
+	}
+
+	cost, _ := provider.EstimateTotalCostWithCostMaps(model, promptTks, completionTks, 1000, converted.PromptCostPerModel, converted.CompletionCostPerModel)
+	return cost
+}
+
+// estimateRouteChatCost returns the cost of a chat completion served by a route step
+// according to the built in cost tables. vllm models are only priced with cost maps.
+func estimateRouteChatCost(usage *goopenai.Usage, e estimator, aoe azureEstimator, ae anthropicEstimator, die deepinfraEstimator, ge geminiEstimator, model, provider string) (float64, error) {
+	if provider == "azure" {
+		return aoe.EstimateTotalCost(model, usage.PromptTokens, usage.CompletionTokens)
+	} else if provider == "openai" {
+		return e.EstimateTotalCostWithCachedTokens(model, usage.PromptTokens, getCachedTokens(usage), usage.CompletionTokens)
+	} else if provider == "anthropic" {
+		return ae.EstimateTotalCost(model, usage.PromptTokens, usage.CompletionTokens)
+	} else if provider == "bedrock" {
+		return ae.EstimateTotalCost(util.TranslateBedrockModelToAnthropicModel(model), usage.PromptTokens, usage.CompletionTokens)
+	} else if provider == "deepinfra" {
+		return die.EstimateTotalCost(model, usage.PromptTokens, usage.CompletionTokens)
+	} else if provider == "gemini" {
+		return ge.EstimateTotalCost(model, usage.PromptTokens, usage.CompletionTokens)
+	}
+
+	return 0, nil
+}
+
+func setRouteStreamingCost(c *gin.Context, usage *goopenai.Usage, e estimator, aoe azureEstimator, ae anthropicEstimator, die deepinfraEstimator, ge geminiEstimator, model, provider string) error {
+	c.Set("promptTokenCount", usage.PromptTokens)
+	c.Set("completionTokenCount", usage.CompletionTokens)
+
+	cost, err := estimateRouteChatCost(usage, e, aoe, ae, die, ge, model, provider)
+	if newCost := estimateRouteCostWithCostMap(c, false, model, usage.PromptTokens, usage.CompletionTokens); newCost != 0 {
+		cost = newCost
+		err = nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	c.Set("costInUsd", cost)
+
+	return nil
+}
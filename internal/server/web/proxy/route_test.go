@@ -0,0 +1,97 @@
+package proxy
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bricks-cloud/bricksllm/internal/provider"
+	"github.com/bricks-cloud/bricksllm/internal/provider/deepinfra"
+	"github.com/bricks-cloud/bricksllm/internal/provider/gemini"
+	"github.com/gin-gonic/gin"
+	goopenai "github.com/sashabaranov/go-openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePriceCatalog map[string]map[string]map[string]float64
+
+func (pc fakePriceCatalog) GetCostMap(provider, costType string) map[string]float64 {
+	return pc[provider][costType]
+}
+
+var testPriceCatalog = fakePriceCatalog{
+	"deepinfra": {
+		"prompt":     {"meta-llama/meta-llama-3-8b-instruct": 0.05},
+		"completion": {"meta-llama/meta-llama-3-8b-instruct": 0.08},
+	},
+	"gemini": {
+		"prompt":     {"gemini-1.5-flash": 0.075},
+		"completion": {"gemini-1.5-flash": 0.3},
+	},
+}
+
+const testChatCompletionResponse = `{"id":"chatcmpl-1","object":"chat.completion","model":"unused","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}],"usage":{"prompt_tokens":1000,"completion_tokens":500,"total_tokens":1500}}`
+
+func newTestRouteContext(cm *provider.CostMap) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	if cm != nil {
+		c.Set("route_cost_map", cm)
+	}
+
+	return c
+}
+
+func TestParseResult_ChatCompletionCost(t *testing.T) {
+	die := deepinfra.NewCostEstimator(testPriceCatalog)
+	ge := gemini.NewCostEstimator(testPriceCatalog)
+
+	vllmCostMap := &provider.CostMap{
+		PromptCostPerModel:     map[string]float64{"llama-3-8b": 0.01},
+		CompletionCostPerModel: map[string]float64{"llama-3-8b": 0.02},
+	}
+
+	cases := []struct {
+		name     string
+		provider string
+		model    string
+		costMap  *provider.CostMap
+		expected float64
+	}{
+		{"deepinfra", "deepinfra", "meta-llama/Meta-Llama-3-8B-Instruct", nil, 1000.0/1000000*0.05 + 500.0/1000000*0.08},
+		{"vllm", "vllm", "llama-3-8b", vllmCostMap, 1000.0/1000*0.01 + 500.0/1000*0.02},
+		{"gemini", "gemini", "gemini-1.5-flash", nil, 1000.0/1000000*0.075 + 500.0/1000000*0.3},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestRouteContext(tc.costMap)
+
+			err := parseResult(c, false, []byte(testChatCompletionResponse), nil, nil, nil, die, ge, tc.model, tc.provider)
+			require.NoError(t, err)
+
+			assert.NotZero(t, c.GetFloat64("costInUsd"))
+			assert.InDelta(t, tc.expected, c.GetFloat64("costInUsd"), 1e-12)
+			assert.Equal(t, 1000, c.GetInt("promptTokenCount"))
+			assert.Equal(t, 500, c.GetInt("completionTokenCount"))
+		})
+	}
+}
+
+func TestSetRouteStreamingCost(t *testing.T) {
+	die := deepinfra.NewCostEstimator(testPriceCatalog)
+	ge := gemini.NewCostEstimator(testPriceCatalog)
+	usage := &goopenai.Usage{PromptTokens: 1000, CompletionTokens: 500}
+
+	c := newTestRouteContext(nil)
+	err := setRouteStreamingCost(c, usage, nil, nil, nil, die, ge, "meta-llama/Meta-Llama-3-8B-Instruct", "deepinfra")
+	require.NoError(t, err)
+	assert.InDelta(t, 1000.0/1000000*0.05+500.0/1000000*0.08, c.GetFloat64("costInUsd"), 1e-12)
+
+	c = newTestRouteContext(&provider.CostMap{
+		PromptCostPerModel:     map[string]float64{"llama-3-8b": 0.01},
+		CompletionCostPerModel: map[string]float64{"llama-3-8b": 0.02},
+	})
+	err = setRouteStreamingCost(c, usage, nil, nil, nil, die, ge, "llama-3-8b", "vllm")
+	require.NoError(t, err)
+	assert.InDelta(t, 1000.0/1000*0.01+500.0/1000*0.02, c.GetFloat64("costInUsd"), 1e-12)
+}
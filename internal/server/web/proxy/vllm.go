@@ -56,7 +56,7 @@ func getVllmCompletionsHandler(prod, private bool, client http.Client) gin.Handl
 		}
 
 		start := time.Now()
-		res, err := client.Do(req)
+		res, err := doRequest(c, client, req)
 		if err != nil {
 			telemetry.Incr("bricksllm.proxy.get_vllm_completions_handler.http_client_error", nil, 1)
 
@@ -404,7 +404,7 @@ func getVllmChatCompletionsHandler(prod, private bool, client http.Client) gin.H
 		}
 
 		start := time.Now()
-		res, err := client.Do(req)
+		res, err := doRequest(c, client, req)
 		if err != nil {
 			telemetry.Incr("bricksllm.proxy.get_vllm_chat_completions_handler.http_client_error", nil, 1)
 
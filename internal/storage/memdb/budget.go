@@ -0,0 +1,136 @@
+package memdb
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/budget"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+	"go.uber.org/zap"
+)
+
+type BudgetsStorage interface {
+	GetBudgets() ([]*budget.Budget, error)
+	GetUpdatedBudgets(updatedAt int64) ([]*budget.Budget, error)
+}
+
+type BudgetsMemDb struct {
+	external    BudgetsStorage
+	lastUpdated int64
+	idToBudget  map[string]*budget.Budget
+	lock        sync.RWMutex
+	done        chan bool
+	interval    time.Duration
+	log         *zap.Logger
+}
+
+func NewBudgetsMemDb(ex BudgetsStorage, log *zap.Logger, interval time.Duration) (*BudgetsMemDb, error) {
+	idToBudget := map[string]*budget.Budget{}
+
+	budgets, err := ex.GetBudgets()
+	if err != nil {
+		return nil, err
+	}
+
+	var latetest int64 = -1
+	for _, b := range budgets {
+		idToBudget[b.Id] = b
+		if b.UpdatedAt > latetest {
+			latetest = b.UpdatedAt
+		}
+	}
+
+	if len(budgets) != 0 {
+		log.Sugar().Infof("budgets memdb updated at %d with %d budgets", latetest, len(budgets))
+	}
+
+	return &BudgetsMemDb{
+		external:    ex,
+		idToBudget:  idToBudget,
+		log:         log,
+		lastUpdated: latetest,
+		interval:    interval,
+		done:        make(chan bool),
+	}, nil
+}
+
+// GetBudgetsForKey returns the budgets that spend of a key counts towards.
+func (mdb *BudgetsMemDb) GetBudgetsForKey(keyId string, tags []string) []*budget.Budget {
+	mdb.lock.RLock()
+	defer mdb.lock.RUnlock()
+
+	budgets := []*budget.Budget{}
+	for _, b := range mdb.idToBudget {
+		if b.AppliesTo(keyId, tags) {
+			budgets = append(budgets, b)
+		}
+	}
+
+	return budgets
+}
+
+func (mdb *BudgetsMemDb) getBudget(id string) *budget.Budget {
+	mdb.lock.RLock()
+	defer mdb.lock.RUnlock()
+
+	return mdb.idToBudget[id]
+}
+
+func (mdb *BudgetsMemDb) setBudget(b *budget.Budget) {
+	mdb.lock.Lock()
+	defer mdb.lock.Unlock()
+
+	if b.Deleted {
+		delete(mdb.idToBudget, b.Id)
+		return
+	}
+
+	mdb.idToBudget[b.Id] = b
+}
+
+func (mdb *BudgetsMemDb) Listen() {
+	ticker := time.NewTicker(mdb.interval)
+	mdb.log.Info("budgets memdb started listening for budget updates")
+
+	go func() {
+		lastUpdated := mdb.lastUpdated
+		for {
+			select {
+			case <-mdb.done:
+				mdb.log.Info("budgets memdb stopped")
+				return
+			case <-ticker.C:
+				budgets, err := mdb.external.GetUpdatedBudgets(lastUpdated)
+				if err != nil {
+					telemetry.Incr("bricksllm.memdb.budgets_memdb.listen.get_updated_budgets_error", nil, 1)
+
+					mdb.log.Sugar().Debugf("memdb failed to update budgets: %v", err)
+					continue
+				}
+
+				numberOfUpdated := 0
+				for _, b := range budgets {
+					if b.UpdatedAt > lastUpdated {
+						lastUpdated = b.UpdatedAt
+					}
+
+					existing := mdb.getBudget(b.Id)
+					if (existing == nil && !b.Deleted) || (existing != nil && b.UpdatedAt > existing.UpdatedAt) {
+						numberOfUpdated += 1
+						mdb.setBudget(b)
+					}
+				}
+
+				if numberOfUpdated != 0 {
+					mdb.log.Sugar().Infof("budgets memdb updated at %d with %d budgets", lastUpdated, numberOfUpdated)
+				}
+			}
+		}
+	}()
+}
+
+func (mdb *BudgetsMemDb) Stop() {
+	mdb.log.Info("shutting down budgets memdb...")
+
+	mdb.done <- true
+}
@@ -0,0 +1,46 @@
+package memdb
+
+import (
+	"testing"
+
+	"github.com/bricks-cloud/bricksllm/internal/budget"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeBudgetsStorage struct {
+	budgets []*budget.Budget
+}
+
+func (s *fakeBudgetsStorage) GetBudgets() ([]*budget.Budget, error) {
+	return s.budgets, nil
+}
+
+func (s *fakeBudgetsStorage) GetUpdatedBudgets(updatedAt int64) ([]*budget.Budget, error) {
+	return nil, nil
+}
+
+func TestBudgetsMemDb_GetBudgetsForKey(t *testing.T) {
+	mdb, err := NewBudgetsMemDb(&fakeBudgetsStorage{budgets: []*budget.Budget{
+		{Id: "org"},
+		{Id: "team", Tag: "team-a"},
+		{Id: "keys", KeyIds: []string{"k"}},
+	}}, zap.NewNop(), 0)
+	require.NoError(t, err)
+
+	ids := func(budgets []*budget.Budget) []string {
+		result := []string{}
+		for _, b := range budgets {
+			result = append(result, b.Id)
+		}
+
+		return result
+	}
+
+	assert.ElementsMatch(t, []string{"org", "team", "keys"}, ids(mdb.GetBudgetsForKey("k", []string{"team-a"})))
+	assert.ElementsMatch(t, []string{"org"}, ids(mdb.GetBudgetsForKey("j", []string{"team-b"})))
+
+	mdb.setBudget(&budget.Budget{Id: "org", Deleted: true})
+	assert.ElementsMatch(t, []string{"team"}, ids(mdb.GetBudgetsForKey("j", []string{"team-a"})))
+}
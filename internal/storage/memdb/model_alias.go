@@ -0,0 +1,147 @@
+package memdb
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/alias"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+	"go.uber.org/zap"
+)
+
+type ModelAliasesStorage interface {
+	GetModelAliases() ([]*alias.ModelAlias, error)
+	GetUpdatedModelAliases(updatedAt int64) ([]*alias.ModelAlias, error)
+}
+
+type ModelAliasesMemDb struct {
+	external    ModelAliasesStorage
+	lastUpdated int64
+	nameToAlias map[string]*alias.ModelAlias
+	lock        sync.RWMutex
+	done        chan bool
+	interval    time.Duration
+	log         *zap.Logger
+}
+
+func NewModelAliasesMemDb(ex ModelAliasesStorage, log *zap.Logger, interval time.Duration) (*ModelAliasesMemDb, error) {
+	nameToAlias := map[string]*alias.ModelAlias{}
+
+	aliases, err := ex.GetModelAliases()
+	if err != nil {
+		return nil, err
+	}
+
+	numberOfAliases := 0
+	var latetest int64 = -1
+	for _, ma := range aliases {
+		nameToAlias[ma.Name] = ma
+		numberOfAliases++
+		if ma.UpdatedAt > latetest {
+			latetest = ma.UpdatedAt
+		}
+	}
+
+	if numberOfAliases != 0 {
+		log.Sugar().Infof("model aliases memdb updated at %d with %d aliases", latetest, numberOfAliases)
+	}
+
+	return &ModelAliasesMemDb{
+		external:    ex,
+		nameToAlias: nameToAlias,
+		log:         log,
+		lastUpdated: latetest,
+		interval:    interval,
+		done:        make(chan bool),
+	}, nil
+}
+
+func (mdb *ModelAliasesMemDb) GetModelAlias(name string) *alias.ModelAlias {
+	mdb.lock.RLock()
+	defer mdb.lock.RUnlock()
+
+	ma, ok := mdb.nameToAlias[name]
+	if ok {
+		return ma
+	}
+
+	return nil
+}
+
+func (mdb *ModelAliasesMemDb) getModelAliasById(id string) *alias.ModelAlias {
+	mdb.lock.RLock()
+	defer mdb.lock.RUnlock()
+
+	for _, ma := range mdb.nameToAlias {
+		if ma.Id == id {
+			return ma
+		}
+	}
+
+	return nil
+}
+
+func (mdb *ModelAliasesMemDb) SetModelAlias(ma *alias.ModelAlias) {
+	mdb.lock.Lock()
+	defer mdb.lock.Unlock()
+
+	for name, existing := range mdb.nameToAlias {
+		if existing.Id == ma.Id {
+			delete(mdb.nameToAlias, name)
+		}
+	}
+
+	if !ma.Deleted {
+		mdb.nameToAlias[ma.Name] = ma
+	}
+}
+
+func (mdb *ModelAliasesMemDb) Listen() {
+	ticker := time.NewTicker(mdb.interval)
+	mdb.log.Info("model aliases memdb started listening for model alias updates")
+
+	go func() {
+		lastUpdated := mdb.lastUpdated
+		for {
+			select {
+			case <-mdb.done:
+				mdb.log.Info("model aliases memdb stopped")
+				return
+			case <-ticker.C:
+				aliases, err := mdb.external.GetUpdatedModelAliases(lastUpdated)
+				if err != nil {
+					telemetry.Incr("bricksllm.memdb.model_aliases_memdb.listen.get_updated_model_aliases_error", nil, 1)
+
+					mdb.log.Sugar().Debugf("memdb failed to update model aliases: %v", err)
+					continue
+				}
+
+				any := false
+				numberOfUpdated := 0
+				for _, ma := range aliases {
+					if ma.UpdatedAt > lastUpdated {
+						lastUpdated = ma.UpdatedAt
+					}
+
+					existing := mdb.getModelAliasById(ma.Id)
+					if (existing == nil && !ma.Deleted) || (existing != nil && ma.UpdatedAt > existing.UpdatedAt) {
+						mdb.log.Sugar().Infof("model aliases memdb updated a model alias: %s", ma.Id)
+						numberOfUpdated += 1
+						any = true
+						mdb.SetModelAlias(ma)
+					}
+				}
+
+				if any {
+					mdb.log.Sugar().Infof("model aliases memdb updated at %d with %d aliases", lastUpdated, numberOfUpdated)
+				}
+			}
+		}
+	}()
+}
+
+func (mdb *ModelAliasesMemDb) Stop() {
+	mdb.log.Info("shutting down model aliases memdb...")
+
+	mdb.done <- true
+}
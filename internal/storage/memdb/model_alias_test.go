@@ -0,0 +1,40 @@
+package memdb
+
+import (
+	"testing"
+
+	"github.com/bricks-cloud/bricksllm/internal/alias"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakeModelAliasesStorage struct {
+	aliases []*alias.ModelAlias
+}
+
+func (s *fakeModelAliasesStorage) GetModelAliases() ([]*alias.ModelAlias, error) {
+	return s.aliases, nil
+}
+
+func (s *fakeModelAliasesStorage) GetUpdatedModelAliases(updatedAt int64) ([]*alias.ModelAlias, error) {
+	return s.aliases, nil
+}
+
+func TestModelAliasesMemDb(t *testing.T) {
+	s := &fakeModelAliasesStorage{aliases: []*alias.ModelAlias{
+		{Id: "1", Name: "fast", Provider: "openai", Model: "gpt-4o-mini", UpdatedAt: 1},
+	}}
+
+	mdb, err := NewModelAliasesMemDb(s, zap.NewNop(), 0)
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-4o-mini", mdb.GetModelAlias("fast").Model)
+	assert.Nil(t, mdb.GetModelAlias("slow"))
+
+	mdb.SetModelAlias(&alias.ModelAlias{Id: "1", Name: "quick", Provider: "openai", Model: "gpt-4o-mini", UpdatedAt: 2})
+	assert.Nil(t, mdb.GetModelAlias("fast"))
+	assert.NotNil(t, mdb.GetModelAlias("quick"))
+
+	mdb.SetModelAlias(&alias.ModelAlias{Id: "1", Name: "quick", Deleted: true, UpdatedAt: 3})
+	assert.Nil(t, mdb.GetModelAlias("quick"))
+}
@@ -0,0 +1,171 @@
+package memdb
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/pricing"
+	"github.com/bricks-cloud/bricksllm/internal/telemetry"
+	"go.uber.org/zap"
+)
+
+type PricesStorage interface {
+	GetPrices(filter *pricing.PriceFilter) ([]*pricing.Price, error)
+	GetUpdatedPrices(updatedAt int64) ([]*pricing.Price, error)
+}
+
+// PricesMemDb keeps the prices that are in effect keyed by provider, cost type and
+// model. Effective prices are recomputed on every tick so that prices scheduled for
+// the future apply without another update.
+type PricesMemDb struct {
+	external    PricesStorage
+	lastUpdated int64
+	idToPrice   map[string]*pricing.Price
+	effective   map[string]map[string]map[string]float64
+	lock        sync.RWMutex
+	done        chan bool
+	interval    time.Duration
+	log         *zap.Logger
+}
+
+func NewPricesMemDb(ex PricesStorage, log *zap.Logger, interval time.Duration) (*PricesMemDb, error) {
+	idToPrice := map[string]*pricing.Price{}
+
+	prices, err := ex.GetPrices(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var latetest int64 = -1
+	for _, p := range prices {
+		idToPrice[p.Id] = p
+		if p.UpdatedAt > latetest {
+			latetest = p.UpdatedAt
+		}
+	}
+
+	if len(prices) != 0 {
+		log.Sugar().Infof("prices memdb updated at %d with %d prices", latetest, len(prices))
+	}
+
+	mdb := &PricesMemDb{
+		external:    ex,
+		idToPrice:   idToPrice,
+		log:         log,
+		lastUpdated: latetest,
+		interval:    interval,
+		done:        make(chan bool),
+	}
+
+	mdb.computeEffectivePrices(time.Now().Unix())
+
+	return mdb, nil
+}
+
+func (mdb *PricesMemDb) computeEffectivePrices(now int64) {
+	mdb.lock.Lock()
+	defer mdb.lock.Unlock()
+
+	effective := map[string]map[string]map[string]float64{}
+	effectiveFrom := map[string]int64{}
+
+	for _, p := range mdb.idToPrice {
+		if p.EffectiveFrom > now {
+			continue
+		}
+
+		id := p.Provider + ":" + p.CostType + ":" + p.Model
+		if from, ok := effectiveFrom[id]; ok && from > p.EffectiveFrom {
+			continue
+		}
+		effectiveFrom[id] = p.EffectiveFrom
+
+		if _, ok := effective[p.Provider]; !ok {
+			effective[p.Provider] = map[string]map[string]float64{}
+		}
+
+		if _, ok := effective[p.Provider][p.CostType]; !ok {
+			effective[p.Provider][p.CostType] = map[string]float64{}
+		}
+
+		effective[p.Provider][p.CostType][p.Model] = p.Cost
+	}
+
+	mdb.effective = effective
+}
+
+// GetCostMap returns the cost of every model for a provider and cost type. The
+// returned map must not be modified.
+func (mdb *PricesMemDb) GetCostMap(provider, costType string) map[string]float64 {
+	mdb.lock.RLock()
+	defer mdb.lock.RUnlock()
+
+	return mdb.effective[provider][costType]
+}
+
+func (mdb *PricesMemDb) setPrice(p *pricing.Price) {
+	mdb.lock.Lock()
+	defer mdb.lock.Unlock()
+
+	if p.Deleted {
+		delete(mdb.idToPrice, p.Id)
+		return
+	}
+
+	mdb.idToPrice[p.Id] = p
+}
+
+func (mdb *PricesMemDb) getPrice(id string) *pricing.Price {
+	mdb.lock.RLock()
+	defer mdb.lock.RUnlock()
+
+	return mdb.idToPrice[id]
+}
+
+func (mdb *PricesMemDb) Listen() {
+	ticker := time.NewTicker(mdb.interval)
+	mdb.log.Info("prices memdb started listening for price updates")
+
+	go func() {
+		lastUpdated := mdb.lastUpdated
+		for {
+			select {
+			case <-mdb.done:
+				mdb.log.Info("prices memdb stopped")
+				return
+			case <-ticker.C:
+				prices, err := mdb.external.GetUpdatedPrices(lastUpdated)
+				if err != nil {
+					telemetry.Incr("bricksllm.memdb.prices_memdb.listen.get_updated_prices_error", nil, 1)
+
+					mdb.log.Sugar().Debugf("memdb failed to update prices: %v", err)
+				}
+
+				numberOfUpdated := 0
+				for _, p := range prices {
+					if p.UpdatedAt > lastUpdated {
+						lastUpdated = p.UpdatedAt
+					}
+
+					existing := mdb.getPrice(p.Id)
+					if (existing == nil && !p.Deleted) || (existing != nil && *existing != *p) {
+						numberOfUpdated += 1
+						mdb.setPrice(p)
+					}
+				}
+
+				if numberOfUpdated != 0 {
+					mdb.log.Sugar().Infof("prices memdb updated at %d with %d prices", lastUpdated, numberOfUpdated)
+				}
+
+				mdb.computeEffectivePrices(time.Now().Unix())
+			}
+		}
+	}()
+}
+
+func (mdb *PricesMemDb) Stop() {
+	mdb.log.Info("shutting down prices memdb...")
+
+	mdb.done <- true
+}
@@ -0,0 +1,45 @@
+package memdb
+
+import (
+	"testing"
+
+	"github.com/bricks-cloud/bricksllm/internal/pricing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type fakePricesStorage struct {
+	prices []*pricing.Price
+}
+
+func (s *fakePricesStorage) GetPrices(filter *pricing.PriceFilter) ([]*pricing.Price, error) {
+	return s.prices, nil
+}
+
+func (s *fakePricesStorage) GetUpdatedPrices(updatedAt int64) ([]*pricing.Price, error) {
+	return nil, nil
+}
+
+func TestPricesMemDb_EffectivePrices(t *testing.T) {
+	s := &fakePricesStorage{prices: []*pricing.Price{
+		{Id: "1", Provider: "openai", CostType: "prompt", Model: "gpt-4o", Cost: 0.005, EffectiveFrom: 0},
+		{Id: "2", Provider: "openai", CostType: "prompt", Model: "gpt-4o", Cost: 0.0025, EffectiveFrom: 100},
+		{Id: "3", Provider: "openai", CostType: "prompt", Model: "gpt-4o", Cost: 0.001, EffectiveFrom: 200},
+		{Id: "4", Provider: "openai", CostType: "prompt", Model: "gpt-4o-mini", Cost: 0.00015, EffectiveFrom: 300},
+	}}
+
+	mdb, err := NewPricesMemDb(s, zap.NewNop(), 0)
+	require.NoError(t, err)
+
+	mdb.computeEffectivePrices(150)
+	assert.Equal(t, map[string]float64{"gpt-4o": 0.0025}, mdb.GetCostMap("openai", "prompt"))
+
+	mdb.computeEffectivePrices(300)
+	assert.Equal(t, map[string]float64{"gpt-4o": 0.001, "gpt-4o-mini": 0.00015}, mdb.GetCostMap("openai", "prompt"))
+
+	mdb.setPrice(&pricing.Price{Id: "3", Deleted: true})
+	mdb.computeEffectivePrices(300)
+	assert.Equal(t, 0.0025, mdb.GetCostMap("openai", "prompt")["gpt-4o"])
+	assert.Nil(t, mdb.GetCostMap("anthropic", "prompt"))
+}
@@ -0,0 +1,338 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/bricks-cloud/bricksllm/internal/alert"
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+	"github.com/lib/pq"
+)
+
+func (s *Store) CreateWebhooksTable() error {
+	createTableQuery := `
+	CREATE TABLE IF NOT EXISTS webhooks (
+		id VARCHAR(255) PRIMARY KEY,
+		created_at BIGINT NOT NULL,
+		updated_at BIGINT NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		url TEXT NOT NULL,
+		secret VARCHAR(255) NOT NULL,
+		format VARCHAR(255) NOT NULL,
+		thresholds FLOAT8[] NOT NULL,
+		tags VARCHAR(255)[] NOT NULL,
+		disabled BOOLEAN NOT NULL DEFAULT FALSE,
+		deleted BOOLEAN NOT NULL DEFAULT FALSE
+	);
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id VARCHAR(255) PRIMARY KEY,
+		created_at BIGINT NOT NULL,
+		updated_at BIGINT NOT NULL,
+		webhook_id VARCHAR(255) NOT NULL,
+		event_type VARCHAR(255) NOT NULL,
+		dedupe_key VARCHAR(1024) NOT NULL,
+		payload JSONB NOT NULL,
+		status VARCHAR(255) NOT NULL,
+		attempts INT NOT NULL DEFAULT 0,
+		next_attempt_at BIGINT NOT NULL,
+		last_status_code INT NOT NULL DEFAULT 0,
+		last_error TEXT NOT NULL DEFAULT ''
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS webhook_deliveries_dedupe_idx ON webhook_deliveries (webhook_id, dedupe_key);
+	CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (status, next_attempt_at);
+	CREATE INDEX IF NOT EXISTS webhook_deliveries_created_at_idx ON webhook_deliveries (webhook_id, created_at);
+	`
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+	_, err := s.db.ExecContext(ctxTimeout, createTableQuery)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func scanWebhook(row rowScanner) (*alert.Webhook, error) {
+	w := &alert.Webhook{}
+
+	if err := row.Scan(
+		&w.Id,
+		&w.CreatedAt,
+		&w.UpdatedAt,
+		&w.Name,
+		&w.Url,
+		&w.Secret,
+		&w.Format,
+		pq.Array(&w.Thresholds),
+		pq.Array(&w.Tags),
+		&w.Disabled,
+		&w.Deleted,
+	); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func scanDelivery(row rowScanner) (*alert.Delivery, error) {
+	d := &alert.Delivery{}
+
+	var payload []byte
+	if err := row.Scan(
+		&d.Id,
+		&d.CreatedAt,
+		&d.UpdatedAt,
+		&d.WebhookId,
+		&d.EventType,
+		&d.DedupeKey,
+		&payload,
+		&d.Status,
+		&d.Attempts,
+		&d.NextAttemptAt,
+		&d.LastStatusCode,
+		&d.LastError,
+	); err != nil {
+		return nil, err
+	}
+
+	d.Payload = payload
+
+	return d, nil
+}
+
+func (s *Store) CreateWebhook(w *alert.Webhook) (*alert.Webhook, error) {
+	query := `
+		INSERT INTO webhooks (id, created_at, updated_at, name, url, secret, format, thresholds, tags, disabled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING *
+	`
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	return scanWebhook(s.db.QueryRowContext(ctxTimeout, query, w.Id, w.CreatedAt, w.UpdatedAt, w.Name, w.Url, w.Secret, w.Format, pq.Array(w.Thresholds), pq.Array(w.Tags), w.Disabled))
+}
+
+func (s *Store) UpdateWebhook(id string, uw *alert.UpdateWebhook) (*alert.Webhook, error) {
+	values := []any{
+		id,
+		uw.UpdatedAt,
+	}
+
+	fields := []string{"updated_at = $2"}
+
+	for _, c := range []struct {
+		column string
+		value  string
+	}{
+		{"name", uw.Name},
+		{"url", uw.Url},
+		{"secret", uw.Secret},
+		{"format", string(uw.Format)},
+	} {
+		if len(c.value) != 0 {
+			values = append(values, c.value)
+			fields = append(fields, fmt.Sprintf("%s = $%d", c.column, len(values)))
+		}
+	}
+
+	if uw.Thresholds != nil {
+		values = append(values, pq.Array(uw.Thresholds))
+		fields = append(fields, fmt.Sprintf("thresholds = $%d", len(values)))
+	}
+
+	if uw.Tags != nil {
+		values = append(values, pq.Array(uw.Tags))
+		fields = append(fields, fmt.Sprintf("tags = $%d", len(values)))
+	}
+
+	if uw.Disabled != nil {
+		values = append(values, *uw.Disabled)
+		fields = append(fields, fmt.Sprintf("disabled = $%d", len(values)))
+	}
+
+	query := fmt.Sprintf("UPDATE webhooks SET %s WHERE id = $1 AND deleted = FALSE RETURNING *", strings.Join(fields, ","))
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	updated, err := scanWebhook(s.db.QueryRowContext(ctxTimeout, query, values...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, internal_errors.NewNotFoundError("webhook is not found for id: " + id)
+		}
+
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// DeleteWebhook soft deletes a webhook so that its delivery history stays available.
+func (s *Store) DeleteWebhook(id string, updatedAt int64) error {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctxTimeout, "UPDATE webhooks SET deleted = TRUE, updated_at = $2 WHERE id = $1 AND deleted = FALSE", id, updatedAt)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return internal_errors.NewNotFoundError("webhook is not found for id: " + id)
+	}
+
+	return nil
+}
+
+func (s *Store) GetWebhook(id string) (*alert.Webhook, error) {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.rt)
+	defer cancel()
+
+	w, err := scanWebhook(s.db.QueryRowContext(ctxTimeout, "SELECT * FROM webhooks WHERE id = $1 AND deleted = FALSE", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, internal_errors.NewNotFoundError("webhook is not found for id: " + id)
+		}
+
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (s *Store) GetWebhooks() ([]*alert.Webhook, error) {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.rt)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctxTimeout, "SELECT * FROM webhooks WHERE deleted = FALSE ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []*alert.Webhook{}
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		webhooks = append(webhooks, w)
+	}
+
+	return webhooks, nil
+}
+
+// CreateDelivery inserts a delivery unless the webhook already has a delivery with the
+// same dedupe key. It reports whether the delivery was inserted.
+func (s *Store) CreateDelivery(d *alert.Delivery) (bool, error) {
+	query := `
+		INSERT INTO webhook_deliveries (id, created_at, updated_at, webhook_id, event_type, dedupe_key, payload, status, attempts, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (webhook_id, dedupe_key) DO NOTHING
+	`
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctxTimeout, query, d.Id, d.CreatedAt, d.UpdatedAt, d.WebhookId, d.EventType, d.DedupeKey, []byte(d.Payload), d.Status, d.Attempts, d.NextAttemptAt)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected != 0, nil
+}
+
+func (s *Store) queryDeliveries(query string, args ...any) ([]*alert.Delivery, error) {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.rt)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctxTimeout, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*alert.Delivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, nil
+}
+
+// ClaimDueDeliveries returns pending deliveries that are due and pushes their next
+// attempt to leaseUntil so that other instances do not send them at the same time.
+func (s *Store) ClaimDueDeliveries(now, leaseUntil int64, limit int) ([]*alert.Delivery, error) {
+	query := `
+		UPDATE webhook_deliveries SET next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = $3 AND next_attempt_at <= $1
+			ORDER BY next_attempt_at
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *
+	`
+
+	return s.queryDeliveries(query, now, leaseUntil, alert.DeliveryStatusPending, limit)
+}
+
+func (s *Store) UpdateDelivery(d *alert.Delivery) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET updated_at = $2, status = $3, attempts = $4, next_attempt_at = $5, last_status_code = $6, last_error = $7, payload = $8
+		WHERE id = $1
+	`
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctxTimeout, query, d.Id, d.UpdatedAt, d.Status, d.Attempts, d.NextAttemptAt, d.LastStatusCode, d.LastError, []byte(d.Payload))
+
+	return err
+}
+
+func (s *Store) GetDeliveries(filter *alert.DeliveryFilter) ([]*alert.Delivery, error) {
+	args := []any{filter.WebhookId}
+	conditions := []string{"webhook_id = $1"}
+
+	if len(filter.Status) != 0 {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+
+	query := fmt.Sprintf("SELECT * FROM webhook_deliveries WHERE %s ORDER BY created_at DESC", strings.Join(conditions, " AND "))
+
+	if filter.Limit != 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	if filter.Offset != 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	return s.queryDeliveries(query, args...)
+}
@@ -0,0 +1,227 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bricks-cloud/bricksllm/internal/audit"
+)
+
+// auditLogLockId serializes appends to the audit log across instances.
+const auditLogLockId = 7265434
+
+func (s *Store) CreateAuditLogsTable() error {
+	createTableQuery := `
+	CREATE TABLE IF NOT EXISTS audit_logs (
+		sequence BIGINT PRIMARY KEY,
+		id VARCHAR(255) NOT NULL,
+		created_at BIGINT NOT NULL,
+		actor_id VARCHAR(255) NOT NULL,
+		actor_name VARCHAR(255) NOT NULL,
+		actor_role VARCHAR(255) NOT NULL,
+		action VARCHAR(255) NOT NULL,
+		entity_type VARCHAR(255) NOT NULL,
+		entity_id VARCHAR(255) NOT NULL,
+		before TEXT NOT NULL,
+		after TEXT NOT NULL,
+		changes TEXT NOT NULL,
+		prev_hash VARCHAR(255) NOT NULL,
+		hash VARCHAR(255) NOT NULL,
+		status VARCHAR(255) NOT NULL DEFAULT '',
+		intent_id VARCHAR(255) NOT NULL DEFAULT ''
+	);
+	ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS status VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN IF NOT EXISTS intent_id VARCHAR(255) NOT NULL DEFAULT '';
+	CREATE INDEX IF NOT EXISTS audit_logs_entity_idx ON audit_logs (entity_type, entity_id);
+	CREATE INDEX IF NOT EXISTS audit_logs_actor_id_idx ON audit_logs (actor_id);
+	CREATE INDEX IF NOT EXISTS audit_logs_created_at_idx ON audit_logs (created_at);
+	CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS TRIGGER AS $$
+	BEGIN
+		RAISE EXCEPTION 'audit_logs is append only';
+	END;
+	$$ LANGUAGE plpgsql;
+	DROP TRIGGER IF EXISTS audit_logs_append_only_trigger ON audit_logs;
+	CREATE TRIGGER audit_logs_append_only_trigger BEFORE UPDATE OR DELETE ON audit_logs
+	FOR EACH ROW EXECUTE PROCEDURE audit_logs_append_only();
+	DROP TRIGGER IF EXISTS audit_logs_no_truncate_trigger ON audit_logs;
+	CREATE TRIGGER audit_logs_no_truncate_trigger BEFORE TRUNCATE ON audit_logs
+	FOR EACH STATEMENT EXECUTE PROCEDURE audit_logs_append_only();
+	`
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+	_, err := s.db.ExecContext(ctxTimeout, createTableQuery)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func scanAuditLog(row rowScanner) (*audit.Entry, error) {
+	e := &audit.Entry{}
+
+	var before, after, changes string
+	if err := row.Scan(
+		&e.Sequence,
+		&e.Id,
+		&e.CreatedAt,
+		&e.ActorId,
+		&e.ActorName,
+		&e.ActorRole,
+		&e.Action,
+		&e.EntityType,
+		&e.EntityId,
+		&before,
+		&after,
+		&changes,
+		&e.PrevHash,
+		&e.Hash,
+		&e.Status,
+		&e.IntentId,
+	); err != nil {
+		return nil, err
+	}
+
+	e.Before = json.RawMessage(before)
+	e.After = json.RawMessage(after)
+
+	if err := json.Unmarshal([]byte(changes), &e.Changes); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// AppendAuditLog chains an entry to the last entry of the audit log and stores it.
+func (s *Store) AppendAuditLog(e *audit.Entry) (*audit.Entry, error) {
+	changes, err := json.Marshal(e.Changes)
+	if err != nil {
+		return nil, err
+	}
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctxTimeout, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctxTimeout, "SELECT pg_advisory_xact_lock($1)", auditLogLockId)
+	if err != nil {
+		return nil, err
+	}
+
+	var prevSequence int64
+	var prevHash string
+	err = tx.QueryRowContext(ctxTimeout, "SELECT sequence, hash FROM audit_logs ORDER BY sequence DESC LIMIT 1").Scan(&prevSequence, &prevHash)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	e.Chain(prevSequence, prevHash)
+
+	query := `
+		INSERT INTO audit_logs (sequence, id, created_at, actor_id, actor_name, actor_role, action, entity_type, entity_id, before, after, changes, prev_hash, hash, status, intent_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+	`
+
+	_, err = tx.ExecContext(ctxTimeout, query, e.Sequence, e.Id, e.CreatedAt, e.ActorId, e.ActorName, e.ActorRole, e.Action, e.EntityType, e.EntityId, string(e.Before), string(e.After), string(changes), e.PrevHash, e.Hash, e.Status, e.IntentId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (s *Store) queryAuditLogs(query string, args ...any) ([]*audit.Entry, error) {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.rt)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctxTimeout, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*audit.Entry{}
+	for rows.Next() {
+		e, err := scanAuditLog(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+func (s *Store) GetAuditLogs(filter *audit.Filter) ([]*audit.Entry, error) {
+	args := []any{}
+	conditions := []string{}
+
+	if len(filter.ActorId) != 0 {
+		args = append(args, filter.ActorId)
+		conditions = append(conditions, fmt.Sprintf("actor_id = $%d", len(args)))
+	}
+
+	if len(filter.EntityType) != 0 {
+		args = append(args, filter.EntityType)
+		conditions = append(conditions, fmt.Sprintf("entity_type = $%d", len(args)))
+	}
+
+	if len(filter.EntityId) != 0 {
+		args = append(args, filter.EntityId)
+		conditions = append(conditions, fmt.Sprintf("entity_id = $%d", len(args)))
+	}
+
+	if len(filter.Action) != 0 {
+		args = append(args, filter.Action)
+		conditions = append(conditions, fmt.Sprintf("action = $%d", len(args)))
+	}
+
+	if filter.Start != 0 {
+		args = append(args, filter.Start)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+
+	if filter.End != 0 {
+		args = append(args, filter.End)
+		conditions = append(conditions, fmt.Sprintf("created_at <= $%d", len(args)))
+	}
+
+	query := "SELECT * FROM audit_logs"
+	if len(conditions) != 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query += " ORDER BY sequence DESC"
+
+	if filter.Limit != 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	if filter.Offset != 0 {
+		args = append(args, filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+
+	return s.queryAuditLogs(query, args...)
+}
+
+// GetAuditLogsAfter returns entries with a sequence greater than the given one in
+// chain order.
+func (s *Store) GetAuditLogsAfter(sequence int64, limit int) ([]*audit.Entry, error) {
+	return s.queryAuditLogs("SELECT * FROM audit_logs WHERE sequence > $1 ORDER BY sequence LIMIT $2", sequence, limit)
+}
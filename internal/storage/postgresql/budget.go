@@ -0,0 +1,199 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/bricks-cloud/bricksllm/internal/budget"
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+	"github.com/lib/pq"
+)
+
+func (s *Store) CreateBudgetsTable() error {
+	createTableQuery := `
+	CREATE TABLE IF NOT EXISTS budgets (
+		id VARCHAR(255) PRIMARY KEY,
+		created_at BIGINT NOT NULL,
+		updated_at BIGINT NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		tag VARCHAR(255) NOT NULL DEFAULT '',
+		key_ids VARCHAR(255)[] NOT NULL,
+		cost_limit_in_usd FLOAT8 NOT NULL DEFAULT 0,
+		cost_limit_in_usd_over_time FLOAT8 NOT NULL DEFAULT 0,
+		cost_limit_in_usd_unit VARCHAR(255) NOT NULL DEFAULT '',
+		deleted BOOLEAN NOT NULL DEFAULT FALSE
+	);
+	CREATE INDEX IF NOT EXISTS budgets_updated_at_idx ON budgets (updated_at);
+	`
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+	_, err := s.db.ExecContext(ctxTimeout, createTableQuery)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func scanBudget(row rowScanner) (*budget.Budget, error) {
+	b := &budget.Budget{}
+
+	if err := row.Scan(
+		&b.Id,
+		&b.CreatedAt,
+		&b.UpdatedAt,
+		&b.Name,
+		&b.Tag,
+		pq.Array(&b.KeyIds),
+		&b.CostLimitInUsd,
+		&b.CostLimitInUsdOverTime,
+		&b.CostLimitInUsdUnit,
+		&b.Deleted,
+	); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func (s *Store) CreateBudget(b *budget.Budget) (*budget.Budget, error) {
+	query := `
+		INSERT INTO budgets (id, created_at, updated_at, name, tag, key_ids, cost_limit_in_usd, cost_limit_in_usd_over_time, cost_limit_in_usd_unit)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING *
+	`
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	return scanBudget(s.db.QueryRowContext(ctxTimeout, query, b.Id, b.CreatedAt, b.UpdatedAt, b.Name, b.Tag, pq.Array(b.KeyIds), b.CostLimitInUsd, b.CostLimitInUsdOverTime, b.CostLimitInUsdUnit))
+}
+
+func (s *Store) UpdateBudget(id string, ub *budget.UpdateBudget) (*budget.Budget, error) {
+	values := []any{
+		id,
+		ub.UpdatedAt,
+	}
+
+	fields := []string{"updated_at = $2"}
+
+	if len(ub.Name) != 0 {
+		values = append(values, ub.Name)
+		fields = append(fields, fmt.Sprintf("name = $%d", len(values)))
+	}
+
+	if ub.Tag != nil {
+		values = append(values, *ub.Tag)
+		fields = append(fields, fmt.Sprintf("tag = $%d", len(values)))
+	}
+
+	if ub.KeyIds != nil {
+		values = append(values, pq.Array(ub.KeyIds))
+		fields = append(fields, fmt.Sprintf("key_ids = $%d", len(values)))
+	}
+
+	if ub.CostLimitInUsd != nil {
+		values = append(values, *ub.CostLimitInUsd)
+		fields = append(fields, fmt.Sprintf("cost_limit_in_usd = $%d", len(values)))
+	}
+
+	if ub.CostLimitInUsdOverTime != nil {
+		values = append(values, *ub.CostLimitInUsdOverTime)
+		fields = append(fields, fmt.Sprintf("cost_limit_in_usd_over_time = $%d", len(values)))
+	}
+
+	if ub.CostLimitInUsdUnit != nil {
+		values = append(values, *ub.CostLimitInUsdUnit)
+		fields = append(fields, fmt.Sprintf("cost_limit_in_usd_unit = $%d", len(values)))
+	}
+
+	query := fmt.Sprintf("UPDATE budgets SET %s WHERE id = $1 AND deleted = FALSE RETURNING *", strings.Join(fields, ","))
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	updated, err := scanBudget(s.db.QueryRowContext(ctxTimeout, query, values...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, internal_errors.NewNotFoundError("budget is not found for id: " + id)
+		}
+
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// DeleteBudget soft deletes a budget so that in memory stores polling for updates can
+// remove it.
+func (s *Store) DeleteBudget(id string, updatedAt int64) error {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctxTimeout, "UPDATE budgets SET deleted = TRUE, updated_at = $2 WHERE id = $1 AND deleted = FALSE", id, updatedAt)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return internal_errors.NewNotFoundError("budget is not found for id: " + id)
+	}
+
+	return nil
+}
+
+func (s *Store) GetBudget(id string) (*budget.Budget, error) {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.rt)
+	defer cancel()
+
+	b, err := scanBudget(s.db.QueryRowContext(ctxTimeout, "SELECT * FROM budgets WHERE id = $1 AND deleted = FALSE", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, internal_errors.NewNotFoundError("budget is not found for id: " + id)
+		}
+
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func (s *Store) queryBudgets(query string, args ...any) ([]*budget.Budget, error) {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.rt)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctxTimeout, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	budgets := []*budget.Budget{}
+	for rows.Next() {
+		b, err := scanBudget(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		budgets = append(budgets, b)
+	}
+
+	return budgets, nil
+}
+
+func (s *Store) GetBudgets() ([]*budget.Budget, error) {
+	return s.queryBudgets("SELECT * FROM budgets WHERE deleted = FALSE ORDER BY created_at")
+}
+
+// GetUpdatedBudgets returns budgets updated since updatedAt including deleted ones.
+func (s *Store) GetUpdatedBudgets(updatedAt int64) ([]*budget.Budget, error) {
+	return s.queryBudgets("SELECT * FROM budgets WHERE updated_at >= $1", updatedAt)
+}
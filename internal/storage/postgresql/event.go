@@ -37,7 +37,7 @@ func (s *Store) CreateEventsByDayTable() error {
 
 func (s *Store) AlterEventsTable() error {
 	alterTableQuery := `
-		ALTER TABLE events ADD COLUMN IF NOT EXISTS path VARCHAR(255), ADD COLUMN IF NOT EXISTS method VARCHAR(255), ADD COLUMN IF NOT EXISTS custom_id VARCHAR(255), ADD COLUMN IF NOT EXISTS request JSONB, ADD COLUMN IF NOT EXISTS response JSONB, ADD COLUMN IF NOT EXISTS user_id VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN IF NOT EXISTS action VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN IF NOT EXISTS policy_id VARCHAR(255) NOT NULL DEFAULT '',  ADD COLUMN IF NOT EXISTS route_id VARCHAR(255) NOT NULL DEFAULT '',  ADD COLUMN IF NOT EXISTS correlation_id VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN IF NOT EXISTS metadata JSONB;
+		ALTER TABLE events ADD COLUMN IF NOT EXISTS path VARCHAR(255), ADD COLUMN IF NOT EXISTS method VARCHAR(255), ADD COLUMN IF NOT EXISTS custom_id VARCHAR(255), ADD COLUMN IF NOT EXISTS request JSONB, ADD COLUMN IF NOT EXISTS response JSONB, ADD COLUMN IF NOT EXISTS user_id VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN IF NOT EXISTS action VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN IF NOT EXISTS policy_id VARCHAR(255) NOT NULL DEFAULT '',  ADD COLUMN IF NOT EXISTS route_id VARCHAR(255) NOT NULL DEFAULT '',  ADD COLUMN IF NOT EXISTS correlation_id VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN IF NOT EXISTS metadata JSONB, ADD COLUMN IF NOT EXISTS response_action VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN IF NOT EXISTS requested_model VARCHAR(255) NOT NULL DEFAULT '';
 	`
 
 	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
@@ -193,6 +193,8 @@ func (s *Store) GetEvents(userId string, customId string, keyIds []string, start
 			&e.RouteId,
 			&e.CorrelationId,
 			&e.Metadata,
+			&e.ResponseAction,
+			&e.RequestedModel,
 		); err != nil {
 			return nil, err
 		}
@@ -708,6 +710,11 @@ func (s *Store) GetEventsV2(req *event.EventRequest) (*event.EventResponse, erro
 		cquery += fmt.Sprintf(" AND action = ANY('%s')", sliceToSqlStringArray(req.Actions))
 	}
 
+	if len(req.ResponseActions) != 0 {
+		query += fmt.Sprintf(" AND response_action = ANY('%s')", sliceToSqlStringArray(req.ResponseActions))
+		cquery += fmt.Sprintf(" AND response_action = ANY('%s')", sliceToSqlStringArray(req.ResponseActions))
+	}
+
 	if len(req.CostOrder) != 0 {
 		query += fmt.Sprintf(" ORDER BY cost_in_usd %s", strings.ToUpper(req.CostOrder))
 	}
@@ -777,6 +784,8 @@ func (s *Store) GetEventsV2(req *event.EventRequest) (*event.EventResponse, erro
 			&e.RouteId,
 			&e.CorrelationId,
 			&e.Metadata,
+			&e.ResponseAction,
+			&e.RequestedModel,
 		); err != nil {
 			return nil, err
 		}
@@ -796,8 +805,8 @@ func (s *Store) GetEventsV2(req *event.EventRequest) (*event.EventResponse, erro
 
 func (s *Store) InsertEvent(e *event.Event) error {
 	query := `
-		INSERT INTO events (event_id, created_at, tags, key_id, cost_in_usd, provider, model, status_code, prompt_token_count, completion_token_count, latency_in_ms, path, method, custom_id, request, response, user_id, action, policy_id, route_id, correlation_id, metadata)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22)
+		INSERT INTO events (event_id, created_at, tags, key_id, cost_in_usd, provider, model, status_code, prompt_token_count, completion_token_count, latency_in_ms, path, method, custom_id, request, response, user_id, action, policy_id, route_id, correlation_id, metadata, response_action, requested_model)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24)
 	`
 
 	values := []any{
@@ -823,6 +832,8 @@ func (s *Store) InsertEvent(e *event.Event) error {
 		e.RouteId,
 		e.CorrelationId,
 		e.Metadata,
+		e.ResponseAction,
+		e.RequestedModel,
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), s.wt)
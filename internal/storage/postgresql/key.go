@@ -55,7 +55,9 @@ func (s *Store) AlterKeysTable() error {
 			END IF;
 		END
 		$$;
-		ALTER TABLE keys ADD COLUMN IF NOT EXISTS setting_id VARCHAR(255), ADD COLUMN IF NOT EXISTS allowed_paths JSONB, ADD COLUMN IF NOT EXISTS setting_ids VARCHAR(255)[] NOT NULL DEFAULT ARRAY[]::VARCHAR(255)[], ADD COLUMN IF NOT EXISTS should_log_request BOOLEAN NOT NULL DEFAULT FALSE, ADD COLUMN IF NOT EXISTS should_log_response BOOLEAN NOT NULL DEFAULT FALSE, ADD COLUMN IF NOT EXISTS rotation_enabled BOOLEAN NOT NULL DEFAULT FALSE, ADD COLUMN IF NOT EXISTS policy_id VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN IF NOT EXISTS is_key_not_hashed BOOLEAN NOT NULL DEFAULT FALSE;
+		ALTER TABLE keys ADD COLUMN IF NOT EXISTS setting_id VARCHAR(255), ADD COLUMN IF NOT EXISTS allowed_paths JSONB, ADD COLUMN IF NOT EXISTS setting_ids VARCHAR(255)[] NOT NULL DEFAULT ARRAY[]::VARCHAR(255)[], ADD COLUMN IF NOT EXISTS should_log_request BOOLEAN NOT NULL DEFAULT FALSE, ADD COLUMN IF NOT EXISTS should_log_response BOOLEAN NOT NULL DEFAULT FALSE, ADD COLUMN IF NOT EXISTS rotation_enabled BOOLEAN NOT NULL DEFAULT FALSE, ADD COLUMN IF NOT EXISTS policy_id VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN IF NOT EXISTS is_key_not_hashed BOOLEAN NOT NULL DEFAULT FALSE, ADD COLUMN IF NOT EXISTS cache_config JSONB, ADD COLUMN IF NOT EXISTS token_limit_over_time INT NOT NULL DEFAULT 0, ADD COLUMN IF NOT EXISTS token_limit_unit VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN IF NOT EXISTS rate_limit_algorithm VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN IF NOT EXISTS rate_limit_burst INT NOT NULL DEFAULT 0, ADD COLUMN IF NOT EXISTS load_balancing_config JSONB, ADD COLUMN IF NOT EXISTS retry_config JSONB, ADD COLUMN IF NOT EXISTS rotation_interval VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN IF NOT EXISTS rotation_grace_period VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN IF NOT EXISTS next_rotation_at BIGINT NOT NULL DEFAULT 0, ADD COLUMN IF NOT EXISTS last_rotated_at BIGINT NOT NULL DEFAULT 0, ADD COLUMN IF NOT EXISTS previous_key VARCHAR(255) NOT NULL DEFAULT '', ADD COLUMN IF NOT EXISTS previous_key_expires_at BIGINT NOT NULL DEFAULT 0, ADD COLUMN IF NOT EXISTS allowed_cidrs VARCHAR(255)[] NOT NULL DEFAULT ARRAY[]::VARCHAR(255)[];
+		CREATE INDEX IF NOT EXISTS keys_previous_key_idx ON keys(previous_key) WHERE previous_key <> '';
+		CREATE INDEX IF NOT EXISTS keys_next_rotation_at_idx ON keys(next_rotation_at) WHERE next_rotation_at <> 0;
 	`
 
 	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
@@ -164,6 +166,9 @@ func (s *Store) GetKeys(tags, keyIds []string, provider string) ([]*key.Response
 		var k key.ResponseKey
 		var settingId sql.NullString
 		var data []byte
+		var ccdata []byte
+		var lbdata []byte
+		var rcdata []byte
 		if err := rows.Scan(
 			&k.Name,
 			&k.CreatedAt,
@@ -187,6 +192,20 @@ func (s *Store) GetKeys(tags, keyIds []string, provider string) ([]*key.Response
 			&k.RotationEnabled,
 			&k.PolicyId,
 			&k.IsKeyNotHashed,
+			&ccdata,
+			&k.TokenLimitOverTime,
+			&k.TokenLimitUnit,
+			&k.RateLimitAlgorithm,
+			&k.RateLimitBurst,
+			&lbdata,
+			&rcdata,
+			&k.RotationInterval,
+			&k.RotationGracePeriod,
+			&k.NextRotationAt,
+			&k.LastRotatedAt,
+			&k.PreviousKey,
+			&k.PreviousKeyExpiresAt,
+			pq.Array(&k.AllowedCidrs),
 		); err != nil {
 			return nil, err
 		}
@@ -203,6 +222,33 @@ func (s *Store) GetKeys(tags, keyIds []string, provider string) ([]*key.Response
 			pk.AllowedPaths = pathConfigs
 		}
 
+		if len(ccdata) != 0 {
+			var cc *key.CacheConfig
+			if err := json.Unmarshal(ccdata, &cc); err != nil {
+				return nil, err
+			}
+
+			pk.CacheConfig = cc
+		}
+
+		if len(lbdata) != 0 {
+			var lbc *key.LoadBalancingConfig
+			if err := json.Unmarshal(lbdata, &lbc); err != nil {
+				return nil, err
+			}
+
+			pk.LoadBalancingConfig = lbc
+		}
+
+		if len(rcdata) != 0 {
+			var rc *key.RetryConfig
+			if err := json.Unmarshal(rcdata, &rc); err != nil {
+				return nil, err
+			}
+
+			pk.RetryConfig = rc
+		}
+
 		keys = append(keys, pk)
 	}
 
@@ -288,6 +334,9 @@ func (s *Store) GetKeysV2(tags, keyIds []string, revoked *bool, limit, offset in
 		var k key.ResponseKey
 		var settingId sql.NullString
 		var data []byte
+		var ccdata []byte
+		var lbdata []byte
+		var rcdata []byte
 		if err := rows.Scan(
 			&k.Name,
 			&k.CreatedAt,
@@ -311,6 +360,20 @@ func (s *Store) GetKeysV2(tags, keyIds []string, revoked *bool, limit, offset in
 			&k.RotationEnabled,
 			&k.PolicyId,
 			&k.IsKeyNotHashed,
+			&ccdata,
+			&k.TokenLimitOverTime,
+			&k.TokenLimitUnit,
+			&k.RateLimitAlgorithm,
+			&k.RateLimitBurst,
+			&lbdata,
+			&rcdata,
+			&k.RotationInterval,
+			&k.RotationGracePeriod,
+			&k.NextRotationAt,
+			&k.LastRotatedAt,
+			&k.PreviousKey,
+			&k.PreviousKeyExpiresAt,
+			pq.Array(&k.AllowedCidrs),
 		); err != nil {
 			return nil, err
 		}
@@ -327,6 +390,33 @@ func (s *Store) GetKeysV2(tags, keyIds []string, revoked *bool, limit, offset in
 			pk.AllowedPaths = pathConfigs
 		}
 
+		if len(ccdata) != 0 {
+			var cc *key.CacheConfig
+			if err := json.Unmarshal(ccdata, &cc); err != nil {
+				return nil, err
+			}
+
+			pk.CacheConfig = cc
+		}
+
+		if len(lbdata) != 0 {
+			var lbc *key.LoadBalancingConfig
+			if err := json.Unmarshal(lbdata, &lbc); err != nil {
+				return nil, err
+			}
+
+			pk.LoadBalancingConfig = lbc
+		}
+
+		if len(rcdata) != 0 {
+			var rc *key.RetryConfig
+			if err := json.Unmarshal(rcdata, &rc); err != nil {
+				return nil, err
+			}
+
+			pk.RetryConfig = rc
+		}
+
 		keys = append(keys, pk)
 	}
 
@@ -365,8 +455,11 @@ func (s *Store) GetKeyByHash(hash string) (*key.ResponseKey, error) {
 	var k key.ResponseKey
 	var settingId sql.NullString
 	var data []byte
+	var ccdata []byte
+	var lbdata []byte
+	var rcdata []byte
 
-	err := s.db.QueryRowContext(ctxTimeout, "SELECT * FROM keys WHERE key = $1", hash).Scan(
+	err := s.db.QueryRowContext(ctxTimeout, "SELECT * FROM keys WHERE key = $1 OR (previous_key = $1 AND previous_key <> '') ORDER BY key = $1 DESC LIMIT 1", hash).Scan(
 		&k.Name,
 		&k.CreatedAt,
 		&k.UpdatedAt,
@@ -389,6 +482,20 @@ func (s *Store) GetKeyByHash(hash string) (*key.ResponseKey, error) {
 		&k.RotationEnabled,
 		&k.PolicyId,
 		&k.IsKeyNotHashed,
+		&ccdata,
+		&k.TokenLimitOverTime,
+		&k.TokenLimitUnit,
+		&k.RateLimitAlgorithm,
+		&k.RateLimitBurst,
+		&lbdata,
+		&rcdata,
+		&k.RotationInterval,
+		&k.RotationGracePeriod,
+		&k.NextRotationAt,
+		&k.LastRotatedAt,
+		&k.PreviousKey,
+		&k.PreviousKeyExpiresAt,
+		pq.Array(&k.AllowedCidrs),
 	)
 
 	if err != nil {
@@ -410,6 +517,33 @@ func (s *Store) GetKeyByHash(hash string) (*key.ResponseKey, error) {
 		k.AllowedPaths = pathConfigs
 	}
 
+	if len(ccdata) != 0 {
+		var cc *key.CacheConfig
+		if err := json.Unmarshal(ccdata, &cc); err != nil {
+			return nil, err
+		}
+
+		k.CacheConfig = cc
+	}
+
+	if len(lbdata) != 0 {
+		var lbc *key.LoadBalancingConfig
+		if err := json.Unmarshal(lbdata, &lbc); err != nil {
+			return nil, err
+		}
+
+		k.LoadBalancingConfig = lbc
+	}
+
+	if len(rcdata) != 0 {
+		var rc *key.RetryConfig
+		if err := json.Unmarshal(rcdata, &rc); err != nil {
+			return nil, err
+		}
+
+		k.RetryConfig = rc
+	}
+
 	return &k, nil
 }
 
@@ -428,6 +562,9 @@ func (s *Store) GetKey(keyId string) (*key.ResponseKey, error) {
 		var k key.ResponseKey
 		var settingId sql.NullString
 		var data []byte
+		var ccdata []byte
+		var lbdata []byte
+		var rcdata []byte
 
 		if err := rows.Scan(
 			&k.Name,
@@ -452,6 +589,20 @@ func (s *Store) GetKey(keyId string) (*key.ResponseKey, error) {
 			&k.RotationEnabled,
 			&k.PolicyId,
 			&k.IsKeyNotHashed,
+			&ccdata,
+			&k.TokenLimitOverTime,
+			&k.TokenLimitUnit,
+			&k.RateLimitAlgorithm,
+			&k.RateLimitBurst,
+			&lbdata,
+			&rcdata,
+			&k.RotationInterval,
+			&k.RotationGracePeriod,
+			&k.NextRotationAt,
+			&k.LastRotatedAt,
+			&k.PreviousKey,
+			&k.PreviousKeyExpiresAt,
+			pq.Array(&k.AllowedCidrs),
 		); err != nil {
 			return nil, err
 		}
@@ -468,6 +619,33 @@ func (s *Store) GetKey(keyId string) (*key.ResponseKey, error) {
 			pk.AllowedPaths = pathConfigs
 		}
 
+		if len(ccdata) != 0 {
+			var cc *key.CacheConfig
+			if err := json.Unmarshal(ccdata, &cc); err != nil {
+				return nil, err
+			}
+
+			pk.CacheConfig = cc
+		}
+
+		if len(lbdata) != 0 {
+			var lbc *key.LoadBalancingConfig
+			if err := json.Unmarshal(lbdata, &lbc); err != nil {
+				return nil, err
+			}
+
+			pk.LoadBalancingConfig = lbc
+		}
+
+		if len(rcdata) != 0 {
+			var rc *key.RetryConfig
+			if err := json.Unmarshal(rcdata, &rc); err != nil {
+				return nil, err
+			}
+
+			pk.RetryConfig = rc
+		}
+
 		keys = append(keys, pk)
 	}
 
@@ -493,6 +671,9 @@ func (s *Store) GetAllKeys() ([]*key.ResponseKey, error) {
 		var k key.ResponseKey
 		var settingId sql.NullString
 		var data []byte
+		var ccdata []byte
+		var lbdata []byte
+		var rcdata []byte
 		if err := rows.Scan(
 			&k.Name,
 			&k.CreatedAt,
@@ -516,6 +697,20 @@ func (s *Store) GetAllKeys() ([]*key.ResponseKey, error) {
 			&k.RotationEnabled,
 			&k.PolicyId,
 			&k.IsKeyNotHashed,
+			&ccdata,
+			&k.TokenLimitOverTime,
+			&k.TokenLimitUnit,
+			&k.RateLimitAlgorithm,
+			&k.RateLimitBurst,
+			&lbdata,
+			&rcdata,
+			&k.RotationInterval,
+			&k.RotationGracePeriod,
+			&k.NextRotationAt,
+			&k.LastRotatedAt,
+			&k.PreviousKey,
+			&k.PreviousKeyExpiresAt,
+			pq.Array(&k.AllowedCidrs),
 		); err != nil {
 			return nil, err
 		}
@@ -531,6 +726,33 @@ func (s *Store) GetAllKeys() ([]*key.ResponseKey, error) {
 			pk.AllowedPaths = pathConfigs
 		}
 
+		if len(ccdata) != 0 {
+			var cc *key.CacheConfig
+			if err := json.Unmarshal(ccdata, &cc); err != nil {
+				return nil, err
+			}
+
+			pk.CacheConfig = cc
+		}
+
+		if len(lbdata) != 0 {
+			var lbc *key.LoadBalancingConfig
+			if err := json.Unmarshal(lbdata, &lbc); err != nil {
+				return nil, err
+			}
+
+			pk.LoadBalancingConfig = lbc
+		}
+
+		if len(rcdata) != 0 {
+			var rc *key.RetryConfig
+			if err := json.Unmarshal(rcdata, &rc); err != nil {
+				return nil, err
+			}
+
+			pk.RetryConfig = rc
+		}
+
 		keys = append(keys, pk)
 	}
 
@@ -552,6 +774,9 @@ func (s *Store) GetUpdatedKeys(updatedAt int64) ([]*key.ResponseKey, error) {
 		var k key.ResponseKey
 		var settingId sql.NullString
 		var data []byte
+		var ccdata []byte
+		var lbdata []byte
+		var rcdata []byte
 		if err := rows.Scan(
 			&k.Name,
 			&k.CreatedAt,
@@ -575,6 +800,20 @@ func (s *Store) GetUpdatedKeys(updatedAt int64) ([]*key.ResponseKey, error) {
 			&k.RotationEnabled,
 			&k.PolicyId,
 			&k.IsKeyNotHashed,
+			&ccdata,
+			&k.TokenLimitOverTime,
+			&k.TokenLimitUnit,
+			&k.RateLimitAlgorithm,
+			&k.RateLimitBurst,
+			&lbdata,
+			&rcdata,
+			&k.RotationInterval,
+			&k.RotationGracePeriod,
+			&k.NextRotationAt,
+			&k.LastRotatedAt,
+			&k.PreviousKey,
+			&k.PreviousKeyExpiresAt,
+			pq.Array(&k.AllowedCidrs),
 		); err != nil {
 			return nil, err
 		}
@@ -590,6 +829,33 @@ func (s *Store) GetUpdatedKeys(updatedAt int64) ([]*key.ResponseKey, error) {
 			pk.AllowedPaths = pathConfigs
 		}
 
+		if len(ccdata) != 0 {
+			var cc *key.CacheConfig
+			if err := json.Unmarshal(ccdata, &cc); err != nil {
+				return nil, err
+			}
+
+			pk.CacheConfig = cc
+		}
+
+		if len(lbdata) != 0 {
+			var lbc *key.LoadBalancingConfig
+			if err := json.Unmarshal(lbdata, &lbc); err != nil {
+				return nil, err
+			}
+
+			pk.LoadBalancingConfig = lbc
+		}
+
+		if len(rcdata) != 0 {
+			var rc *key.RetryConfig
+			if err := json.Unmarshal(rcdata, &rc); err != nil {
+				return nil, err
+			}
+
+			pk.RetryConfig = rc
+		}
+
 		keys = append(keys, pk)
 	}
 
@@ -669,6 +935,30 @@ func (s *Store) UpdateKey(id string, uk *key.UpdateKey) (*key.ResponseKey, error
 		counter++
 	}
 
+	if uk.RateLimitAlgorithm != nil {
+		values = append(values, *uk.RateLimitAlgorithm)
+		fields = append(fields, fmt.Sprintf("rate_limit_algorithm = $%d", counter))
+		counter++
+	}
+
+	if uk.RateLimitBurst != nil {
+		values = append(values, *uk.RateLimitBurst)
+		fields = append(fields, fmt.Sprintf("rate_limit_burst = $%d", counter))
+		counter++
+	}
+
+	if uk.TokenLimitOverTime != nil {
+		values = append(values, *uk.TokenLimitOverTime)
+		fields = append(fields, fmt.Sprintf("token_limit_over_time = $%d", counter))
+		counter++
+	}
+
+	if uk.TokenLimitUnit != nil {
+		values = append(values, *uk.TokenLimitUnit)
+		fields = append(fields, fmt.Sprintf("token_limit_unit = $%d", counter))
+		counter++
+	}
+
 	if len(uk.SettingId) != 0 {
 		values = append(values, uk.SettingId)
 		fields = append(fields, fmt.Sprintf("setting_id = $%d", counter))
@@ -681,6 +971,12 @@ func (s *Store) UpdateKey(id string, uk *key.UpdateKey) (*key.ResponseKey, error
 		counter++
 	}
 
+	if uk.AllowedCidrs != nil {
+		values = append(values, sliceToSqlStringArray(*uk.AllowedCidrs))
+		fields = append(fields, fmt.Sprintf("allowed_cidrs = $%d", counter))
+		counter++
+	}
+
 	if uk.ShouldLogRequest != nil {
 		values = append(values, *uk.ShouldLogRequest)
 		fields = append(fields, fmt.Sprintf("should_log_request = $%d", counter))
@@ -707,6 +1003,40 @@ func (s *Store) UpdateKey(id string, uk *key.UpdateKey) (*key.ResponseKey, error
 
 		values = append(values, data)
 		fields = append(fields, fmt.Sprintf("allowed_paths = $%d", counter))
+		counter++
+	}
+
+	if uk.CacheConfig != nil {
+		data, err := json.Marshal(uk.CacheConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, data)
+		fields = append(fields, fmt.Sprintf("cache_config = $%d", counter))
+		counter++
+	}
+
+	if uk.LoadBalancingConfig != nil {
+		data, err := json.Marshal(uk.LoadBalancingConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, data)
+		fields = append(fields, fmt.Sprintf("load_balancing_config = $%d", counter))
+		counter++
+	}
+
+	if uk.RetryConfig != nil {
+		data, err := json.Marshal(uk.RetryConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, data)
+		fields = append(fields, fmt.Sprintf("retry_config = $%d", counter))
+		counter++
 	}
 
 	if uk.PolicyId != nil {
@@ -721,6 +1051,24 @@ func (s *Store) UpdateKey(id string, uk *key.UpdateKey) (*key.ResponseKey, error
 		counter++
 	}
 
+	if uk.RotationInterval != nil {
+		values = append(values, *uk.RotationInterval)
+		fields = append(fields, fmt.Sprintf("rotation_interval = $%d", counter))
+		counter++
+	}
+
+	if uk.RotationGracePeriod != nil {
+		values = append(values, *uk.RotationGracePeriod)
+		fields = append(fields, fmt.Sprintf("rotation_grace_period = $%d", counter))
+		counter++
+	}
+
+	if uk.NextRotationAt != nil {
+		values = append(values, *uk.NextRotationAt)
+		fields = append(fields, fmt.Sprintf("next_rotation_at = $%d", counter))
+		counter++
+	}
+
 	query := fmt.Sprintf("UPDATE keys SET %s WHERE key_id = $1 RETURNING *;", strings.Join(fields, ","))
 
 	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
@@ -729,6 +1077,9 @@ func (s *Store) UpdateKey(id string, uk *key.UpdateKey) (*key.ResponseKey, error
 	var k key.ResponseKey
 	var settingId sql.NullString
 	var data []byte
+	var ccdata []byte
+	var lbdata []byte
+	var rcdata []byte
 	if err := s.db.QueryRowContext(ctxTimeout, query, values...).Scan(
 		&k.Name,
 		&k.CreatedAt,
@@ -752,6 +1103,20 @@ func (s *Store) UpdateKey(id string, uk *key.UpdateKey) (*key.ResponseKey, error
 		&k.RotationEnabled,
 		&k.PolicyId,
 		&k.IsKeyNotHashed,
+		&ccdata,
+		&k.TokenLimitOverTime,
+		&k.TokenLimitUnit,
+		&k.RateLimitAlgorithm,
+		&k.RateLimitBurst,
+		&lbdata,
+		&rcdata,
+		&k.RotationInterval,
+		&k.RotationGracePeriod,
+		&k.NextRotationAt,
+		&k.LastRotatedAt,
+		&k.PreviousKey,
+		&k.PreviousKeyExpiresAt,
+		pq.Array(&k.AllowedCidrs),
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, internal_errors.NewNotFoundError(fmt.Sprintf("key not found for id: %s", id))
@@ -771,13 +1136,40 @@ func (s *Store) UpdateKey(id string, uk *key.UpdateKey) (*key.ResponseKey, error
 		pk.AllowedPaths = pathConfigs
 	}
 
+	if len(ccdata) != 0 {
+		var cc *key.CacheConfig
+		if err := json.Unmarshal(ccdata, &cc); err != nil {
+			return nil, err
+		}
+
+		pk.CacheConfig = cc
+	}
+
+	if len(lbdata) != 0 {
+		var lbc *key.LoadBalancingConfig
+		if err := json.Unmarshal(lbdata, &lbc); err != nil {
+			return nil, err
+		}
+
+		pk.LoadBalancingConfig = lbc
+	}
+
+	if len(rcdata) != 0 {
+		var rc *key.RetryConfig
+		if err := json.Unmarshal(rcdata, &rc); err != nil {
+			return nil, err
+		}
+
+		pk.RetryConfig = rc
+	}
+
 	return pk, nil
 }
 
 func (s *Store) CreateKey(rk *key.RequestKey) (*key.ResponseKey, error) {
 	query := `
-		INSERT INTO keys (name, created_at, updated_at, tags, revoked, key_id, key, revoked_reason, cost_limit_in_usd, cost_limit_in_usd_over_time, cost_limit_in_usd_unit, rate_limit_over_time, rate_limit_unit, ttl, setting_id, allowed_paths, setting_ids, should_log_request, should_log_response, rotation_enabled, policy_id, is_key_not_hashed)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22)
+		INSERT INTO keys (name, created_at, updated_at, tags, revoked, key_id, key, revoked_reason, cost_limit_in_usd, cost_limit_in_usd_over_time, cost_limit_in_usd_unit, rate_limit_over_time, rate_limit_unit, ttl, setting_id, allowed_paths, setting_ids, should_log_request, should_log_response, rotation_enabled, policy_id, is_key_not_hashed, cache_config, token_limit_over_time, token_limit_unit, rate_limit_algorithm, rate_limit_burst, load_balancing_config, retry_config, rotation_interval, rotation_grace_period, next_rotation_at, allowed_cidrs)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31, $32, $33)
 		RETURNING *;
 	`
 
@@ -786,6 +1178,21 @@ func (s *Store) CreateKey(rk *key.RequestKey) (*key.ResponseKey, error) {
 		return nil, err
 	}
 
+	ccd, err := json.Marshal(rk.CacheConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	lbd, err := json.Marshal(rk.LoadBalancingConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	rcd, err := json.Marshal(rk.RetryConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	values := []any{
 		rk.Name,
 		rk.CreatedAt,
@@ -809,6 +1216,17 @@ func (s *Store) CreateKey(rk *key.RequestKey) (*key.ResponseKey, error) {
 		rk.RotationEnabled,
 		rk.PolicyId,
 		rk.IsKeyNotHashed,
+		ccd,
+		rk.TokenLimitOverTime,
+		rk.TokenLimitUnit,
+		rk.RateLimitAlgorithm,
+		rk.RateLimitBurst,
+		lbd,
+		rcd,
+		rk.RotationInterval,
+		rk.RotationGracePeriod,
+		rk.NextRotationAt,
+		sliceToSqlStringArray(rk.AllowedCidrs),
 	}
 
 	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
@@ -818,6 +1236,9 @@ func (s *Store) CreateKey(rk *key.RequestKey) (*key.ResponseKey, error) {
 
 	var settingId sql.NullString
 	var data []byte
+	var ccdata []byte
+	var lbdata []byte
+	var rcdata []byte
 	if err := s.db.QueryRowContext(ctxTimeout, query, values...).Scan(
 		&k.Name,
 		&k.CreatedAt,
@@ -841,6 +1262,20 @@ func (s *Store) CreateKey(rk *key.RequestKey) (*key.ResponseKey, error) {
 		&k.RotationEnabled,
 		&k.PolicyId,
 		&k.IsKeyNotHashed,
+		&ccdata,
+		&k.TokenLimitOverTime,
+		&k.TokenLimitUnit,
+		&k.RateLimitAlgorithm,
+		&k.RateLimitBurst,
+		&lbdata,
+		&rcdata,
+		&k.RotationInterval,
+		&k.RotationGracePeriod,
+		&k.NextRotationAt,
+		&k.LastRotatedAt,
+		&k.PreviousKey,
+		&k.PreviousKeyExpiresAt,
+		pq.Array(&k.AllowedCidrs),
 	); err != nil {
 		return nil, err
 	}
@@ -857,6 +1292,33 @@ func (s *Store) CreateKey(rk *key.RequestKey) (*key.ResponseKey, error) {
 		pk.AllowedPaths = pathConfigs
 	}
 
+	if len(ccdata) != 0 {
+		var cc *key.CacheConfig
+		if err := json.Unmarshal(ccdata, &cc); err != nil {
+			return nil, err
+		}
+
+		pk.CacheConfig = cc
+	}
+
+	if len(lbdata) != 0 {
+		var lbc *key.LoadBalancingConfig
+		if err := json.Unmarshal(lbdata, &lbc); err != nil {
+			return nil, err
+		}
+
+		pk.LoadBalancingConfig = lbc
+	}
+
+	if len(rcdata) != 0 {
+		var rc *key.RetryConfig
+		if err := json.Unmarshal(rcdata, &rc); err != nil {
+			return nil, err
+		}
+
+		pk.RetryConfig = rc
+	}
+
 	return pk, nil
 }
 
@@ -868,6 +1330,86 @@ func (s *Store) DeleteKey(id string) error {
 	return err
 }
 
+// RotateKey replaces the hash of a key and keeps the current hash as the previous one.
+// A key stored in its raw form keeps the hash of its raw secret instead. When dueBefore
+// is not 0, the key is only rotated if it is due for rotation by then so that a key is
+// not rotated twice by concurrent schedulers. It returns nil when no key is rotated.
+func (s *Store) RotateKey(id string, r *key.Rotation, dueBefore int64) (*key.ResponseKey, error) {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	query := `
+		UPDATE keys SET previous_key = CASE WHEN is_key_not_hashed THEN $6 ELSE key END, key = $2, is_key_not_hashed = FALSE, previous_key_expires_at = $3, next_rotation_at = $4, last_rotated_at = $5, updated_at = $5
+		WHERE key_id = $1`
+
+	args := []any{id, r.Key, r.PreviousKeyExpiresAt, r.NextRotationAt, r.RotatedAt, r.PreviousKey}
+	if dueBefore != 0 {
+		query += " AND revoked = FALSE AND next_rotation_at <> 0 AND next_rotation_at <= $7"
+		args = append(args, dueBefore)
+	}
+
+	res, err := s.db.ExecContext(ctxTimeout, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if affected == 0 {
+		return nil, nil
+	}
+
+	return s.GetKey(id)
+}
+
+// RevertKeyRotation restores the state of a key before a rotation as long as the key
+// still has the secret it was rotated to.
+func (s *Store) RevertKeyRotation(id string, previous *key.ResponseKey, rotatedKey string) error {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	query := `
+		UPDATE keys SET key = $2, previous_key = $3, is_key_not_hashed = $4, previous_key_expires_at = $5, next_rotation_at = $6, last_rotated_at = $7, updated_at = $8
+		WHERE key_id = $1 AND key = $9`
+
+	_, err := s.db.ExecContext(ctxTimeout, query, id, previous.Key, previous.PreviousKey, previous.IsKeyNotHashed, previous.PreviousKeyExpiresAt, previous.NextRotationAt, previous.LastRotatedAt, previous.UpdatedAt, rotatedKey)
+	return err
+}
+
+func (s *Store) GetKeysDueForRotation(dueBefore int64, offset, limit int) ([]*key.ResponseKey, error) {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.rt)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctxTimeout, "SELECT key_id FROM keys WHERE revoked = FALSE AND next_rotation_at <> 0 AND next_rotation_at <= $1 ORDER BY next_rotation_at, key_id OFFSET $2 LIMIT $3", dueBefore, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(ids) == 0 {
+		return []*key.ResponseKey{}, nil
+	}
+
+	return s.GetKeys(nil, ids, "")
+}
+
 func sliceToSqlStringArray(slice []string) string {
 	return "{" + strings.Join(slice, ",") + "}"
 }
@@ -0,0 +1,215 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/bricks-cloud/bricksllm/internal/alias"
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+)
+
+func (s *Store) CreateModelAliasesTable() error {
+	createTableQuery := `
+	CREATE TABLE IF NOT EXISTS model_aliases (
+		id VARCHAR(255) PRIMARY KEY,
+		created_at BIGINT NOT NULL,
+		updated_at BIGINT NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		provider VARCHAR(255) NOT NULL,
+		model VARCHAR(255) NOT NULL,
+		overrides JSONB NOT NULL,
+		deleted BOOLEAN NOT NULL DEFAULT FALSE
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS model_aliases_name_idx ON model_aliases (name) WHERE deleted = FALSE;
+	`
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+	_, err := s.db.ExecContext(ctxTimeout, createTableQuery)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanModelAlias(row rowScanner) (*alias.ModelAlias, error) {
+	ma := &alias.ModelAlias{}
+
+	var od []byte
+	if err := row.Scan(
+		&ma.Id,
+		&ma.CreatedAt,
+		&ma.UpdatedAt,
+		&ma.Name,
+		&ma.Provider,
+		&ma.Model,
+		&od,
+		&ma.Deleted,
+	); err != nil {
+		return nil, err
+	}
+
+	if len(od) != 0 {
+		if err := json.Unmarshal(od, &ma.Overrides); err != nil {
+			return nil, err
+		}
+	}
+
+	return ma, nil
+}
+
+func (s *Store) CreateModelAlias(ma *alias.ModelAlias) (*alias.ModelAlias, error) {
+	od, err := json.Marshal(ma.Overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO model_aliases (id, created_at, updated_at, name, provider, model, overrides)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING *
+	`
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	created, err := scanModelAlias(s.db.QueryRowContext(ctxTimeout, query, ma.Id, ma.CreatedAt, ma.UpdatedAt, ma.Name, ma.Provider, ma.Model, od))
+	if err != nil {
+		if strings.Contains(err.Error(), "model_aliases_name_idx") {
+			return nil, internal_errors.NewValidationError("model alias name already exists: " + ma.Name)
+		}
+
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (s *Store) UpdateModelAlias(id string, uma *alias.UpdateModelAlias) (*alias.ModelAlias, error) {
+	values := []any{
+		id,
+		uma.UpdatedAt,
+	}
+
+	fields := []string{"updated_at = $2"}
+
+	d := 3
+
+	if len(uma.Provider) != 0 {
+		values = append(values, uma.Provider)
+		fields = append(fields, fmt.Sprintf("provider = $%d", d))
+		d++
+	}
+
+	if len(uma.Model) != 0 {
+		values = append(values, uma.Model)
+		fields = append(fields, fmt.Sprintf("model = $%d", d))
+		d++
+	}
+
+	if uma.Overrides != nil {
+		data, err := json.Marshal(uma.Overrides)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, data)
+		fields = append(fields, fmt.Sprintf("overrides = $%d", d))
+	}
+
+	query := fmt.Sprintf("UPDATE model_aliases SET %s WHERE id = $1 AND deleted = FALSE RETURNING *", strings.Join(fields, ","))
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	updated, err := scanModelAlias(s.db.QueryRowContext(ctxTimeout, query, values...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, internal_errors.NewNotFoundError("model alias is not found for id: " + id)
+		}
+
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// DeleteModelAlias soft deletes a model alias so that in memory stores polling
+// for updates can remove it.
+func (s *Store) DeleteModelAlias(id string, updatedAt int64) error {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctxTimeout, "UPDATE model_aliases SET deleted = TRUE, updated_at = $2 WHERE id = $1 AND deleted = FALSE", id, updatedAt)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return internal_errors.NewNotFoundError("model alias is not found for id: " + id)
+	}
+
+	return nil
+}
+
+func (s *Store) GetModelAlias(id string) (*alias.ModelAlias, error) {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.rt)
+	defer cancel()
+
+	ma, err := scanModelAlias(s.db.QueryRowContext(ctxTimeout, "SELECT * FROM model_aliases WHERE id = $1 AND deleted = FALSE", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, internal_errors.NewNotFoundError("model alias is not found for id: " + id)
+		}
+
+		return nil, err
+	}
+
+	return ma, nil
+}
+
+func (s *Store) queryModelAliases(query string, args ...any) ([]*alias.ModelAlias, error) {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.rt)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctxTimeout, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mas := []*alias.ModelAlias{}
+	for rows.Next() {
+		ma, err := scanModelAlias(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		mas = append(mas, ma)
+	}
+
+	return mas, nil
+}
+
+func (s *Store) GetModelAliases() ([]*alias.ModelAlias, error) {
+	return s.queryModelAliases("SELECT * FROM model_aliases WHERE deleted = FALSE")
+}
+
+// GetUpdatedModelAliases returns model aliases updated since updatedAt including
+// deleted ones.
+func (s *Store) GetUpdatedModelAliases(updatedAt int64) ([]*alias.ModelAlias, error) {
+	return s.queryModelAliases("SELECT * FROM model_aliases WHERE updated_at >= $1", updatedAt)
+}
@@ -35,6 +35,21 @@ func (s *Store) CreatePolicyTable() error {
 	return nil
 }
 
+func (s *Store) AlterPolicyTable() error {
+	alterTableQuery := `
+		ALTER TABLE policies ADD COLUMN IF NOT EXISTS prompt_injection_config JSONB
+	`
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+	_, err := s.db.ExecContext(ctxTimeout, alterTableQuery)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (s *Store) CreatePolicy(p *policy.Policy) (*policy.Policy, error) {
 	fields := []string{
 		"id",
@@ -90,6 +105,18 @@ func (s *Store) CreatePolicy(p *policy.Policy) (*policy.Policy, error) {
 		fields = append(fields, "custom_config")
 		values = append(values, cd)
 		vidxs = append(vidxs, fmt.Sprintf("$%d", idx))
+		idx++
+	}
+
+	if p.PromptInjectionConfig != nil {
+		cd, err := json.Marshal(p.PromptInjectionConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		fields = append(fields, "prompt_injection_config")
+		values = append(values, cd)
+		vidxs = append(vidxs, fmt.Sprintf("$%d", idx))
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), s.wt)
@@ -105,6 +132,7 @@ func (s *Store) CreatePolicy(p *policy.Policy) (*policy.Policy, error) {
 
 	var createdcd []byte
 	var createdcusd []byte
+	var createdpid []byte
 	var createdregexd []byte
 	row := s.db.QueryRowContext(ctx, query, values...)
 	if err := row.Scan(
@@ -116,6 +144,7 @@ func (s *Store) CreatePolicy(p *policy.Policy) (*policy.Policy, error) {
 		&createdcd,
 		&createdregexd,
 		&createdcusd,
+		&createdpid,
 	); err != nil {
 
 		return nil, err
@@ -139,6 +168,12 @@ func (s *Store) CreatePolicy(p *policy.Policy) (*policy.Policy, error) {
 		}
 	}
 
+	if len(createdpid) != 0 {
+		if err := json.Unmarshal(createdpid, &created.PromptInjectionConfig); err != nil {
+			return nil, err
+		}
+	}
+
 	return created, nil
 }
 
@@ -194,6 +229,17 @@ func (s *Store) UpdatePolicy(id string, p *policy.UpdatePolicy) (*policy.Policy,
 
 		values = append(values, data)
 		fields = append(fields, fmt.Sprintf("custom_config = $%d", d))
+		d++
+	}
+
+	if p.PromptInjectionConfig != nil {
+		data, err := json.Marshal(p.PromptInjectionConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, data)
+		fields = append(fields, fmt.Sprintf("prompt_injection_config = $%d", d))
 	}
 
 	query := fmt.Sprintf("UPDATE policies SET %s WHERE id = $1 RETURNING *", strings.Join(fields, ","))
@@ -203,6 +249,7 @@ func (s *Store) UpdatePolicy(id string, p *policy.UpdatePolicy) (*policy.Policy,
 
 	var cd []byte
 	var cusd []byte
+	var pid []byte
 	var regexd []byte
 	row := s.db.QueryRowContext(ctxTimeout, query, values...)
 	if err := row.Scan(
@@ -214,6 +261,7 @@ func (s *Store) UpdatePolicy(id string, p *policy.UpdatePolicy) (*policy.Policy,
 		&cd,
 		&regexd,
 		&cusd,
+		&pid,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, internal_errors.NewNotFoundError("policy is not found for id: " + id)
@@ -240,6 +288,12 @@ func (s *Store) UpdatePolicy(id string, p *policy.UpdatePolicy) (*policy.Policy,
 		}
 	}
 
+	if len(pid) != 0 {
+		if err := json.Unmarshal(pid, &updated.PromptInjectionConfig); err != nil {
+			return nil, err
+		}
+	}
+
 	return updated, nil
 }
 
@@ -257,6 +311,7 @@ func (s *Store) GetAllPolicies() ([]*policy.Policy, error) {
 	for rows.Next() {
 		var cd []byte
 		var cusd []byte
+		var pid []byte
 		var regexd []byte
 
 		p := &policy.Policy{}
@@ -269,6 +324,7 @@ func (s *Store) GetAllPolicies() ([]*policy.Policy, error) {
 			&cd,
 			&regexd,
 			&cusd,
+			&pid,
 		); err != nil {
 			return nil, err
 		}
@@ -291,6 +347,12 @@ func (s *Store) GetAllPolicies() ([]*policy.Policy, error) {
 			}
 		}
 
+		if len(pid) != 0 {
+			if err := json.Unmarshal(pid, &p.PromptInjectionConfig); err != nil {
+				return nil, err
+			}
+		}
+
 		ps = append(ps, p)
 	}
 
@@ -306,6 +368,7 @@ func (s *Store) GetPolicyById(id string) (*policy.Policy, error) {
 
 	var cd []byte
 	var cusd []byte
+	var pid []byte
 	var regexd []byte
 
 	if err := row.Scan(
@@ -317,6 +380,7 @@ func (s *Store) GetPolicyById(id string) (*policy.Policy, error) {
 		&cd,
 		&regexd,
 		&cusd,
+		&pid,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, internal_errors.NewNotFoundError("policy is not found for id: " + id)
@@ -343,6 +407,12 @@ func (s *Store) GetPolicyById(id string) (*policy.Policy, error) {
 		}
 	}
 
+	if len(pid) != 0 {
+		if err := json.Unmarshal(pid, &p.PromptInjectionConfig); err != nil {
+			return nil, err
+		}
+	}
+
 	return p, nil
 }
 
@@ -361,6 +431,7 @@ func (s *Store) GetPoliciesByTags(tags []string) ([]*policy.Policy, error) {
 	for rows.Next() {
 		var cd []byte
 		var cusd []byte
+		var pid []byte
 		var regexd []byte
 
 		p := &policy.Policy{}
@@ -374,6 +445,7 @@ func (s *Store) GetPoliciesByTags(tags []string) ([]*policy.Policy, error) {
 			&cd,
 			&regexd,
 			&cusd,
+			&pid,
 		); err != nil {
 			return nil, err
 		}
@@ -396,6 +468,12 @@ func (s *Store) GetPoliciesByTags(tags []string) ([]*policy.Policy, error) {
 			}
 		}
 
+		if len(pid) != 0 {
+			if err := json.Unmarshal(pid, &p.PromptInjectionConfig); err != nil {
+				return nil, err
+			}
+		}
+
 		ps = append(ps, p)
 
 	}
@@ -417,6 +495,7 @@ func (s *Store) GetUpdatedPolicies(updatedAt int64) ([]*policy.Policy, error) {
 	for rows.Next() {
 		var cd []byte
 		var cusd []byte
+		var pid []byte
 		var regexd []byte
 
 		p := &policy.Policy{}
@@ -429,6 +508,7 @@ func (s *Store) GetUpdatedPolicies(updatedAt int64) ([]*policy.Policy, error) {
 			&cd,
 			&regexd,
 			&cusd,
+			&pid,
 		); err != nil {
 			return nil, err
 		}
@@ -451,6 +531,12 @@ func (s *Store) GetUpdatedPolicies(updatedAt int64) ([]*policy.Policy, error) {
 			}
 		}
 
+		if len(pid) != 0 {
+			if err := json.Unmarshal(pid, &p.PromptInjectionConfig); err != nil {
+				return nil, err
+			}
+		}
+
 		ps = append(ps, p)
 	}
 
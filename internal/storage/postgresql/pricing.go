@@ -0,0 +1,246 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+	"github.com/bricks-cloud/bricksllm/internal/pricing"
+)
+
+func (s *Store) CreatePricesTable() error {
+	createTableQuery := `
+	CREATE TABLE IF NOT EXISTS prices (
+		id VARCHAR(255) PRIMARY KEY,
+		created_at BIGINT NOT NULL,
+		updated_at BIGINT NOT NULL,
+		provider VARCHAR(255) NOT NULL,
+		model VARCHAR(255) NOT NULL,
+		cost_type VARCHAR(255) NOT NULL,
+		cost FLOAT8 NOT NULL,
+		effective_from BIGINT NOT NULL,
+		deleted BOOLEAN NOT NULL DEFAULT FALSE
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS prices_effective_idx ON prices (provider, cost_type, model, effective_from);
+	CREATE INDEX IF NOT EXISTS prices_updated_at_idx ON prices (updated_at);
+	`
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+	_, err := s.db.ExecContext(ctxTimeout, createTableQuery)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func scanPrice(row rowScanner) (*pricing.Price, error) {
+	p := &pricing.Price{}
+
+	if err := row.Scan(
+		&p.Id,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+		&p.Provider,
+		&p.Model,
+		&p.CostType,
+		&p.Cost,
+		&p.EffectiveFrom,
+		&p.Deleted,
+	); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// SeedPrices inserts prices that do not exist yet. Seeded prices that were updated or
+// deleted through the admin api are left untouched.
+func (s *Store) SeedPrices(prices []*pricing.Price) error {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctxTimeout, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctxTimeout, `
+		INSERT INTO prices (id, created_at, updated_at, provider, model, cost_type, cost, effective_from)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT DO NOTHING
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, p := range prices {
+		_, err := stmt.ExecContext(ctxTimeout, p.Id, p.CreatedAt, p.UpdatedAt, p.Provider, p.Model, p.CostType, p.Cost, p.EffectiveFrom)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *Store) CreatePrice(p *pricing.Price) (*pricing.Price, error) {
+	query := `
+		INSERT INTO prices (id, created_at, updated_at, provider, model, cost_type, cost, effective_from)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING *
+	`
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	created, err := scanPrice(s.db.QueryRowContext(ctxTimeout, query, p.Id, p.CreatedAt, p.UpdatedAt, p.Provider, p.Model, p.CostType, p.Cost, p.EffectiveFrom))
+	if err != nil {
+		if strings.Contains(err.Error(), "prices_effective_idx") {
+			return nil, internal_errors.NewValidationError(fmt.Sprintf("a %s price for %s is already effective from %d", p.CostType, p.Model, p.EffectiveFrom))
+		}
+
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (s *Store) UpdatePrice(id string, up *pricing.UpdatePrice) (*pricing.Price, error) {
+	values := []any{
+		id,
+		up.UpdatedAt,
+	}
+
+	fields := []string{"updated_at = $2"}
+
+	d := 3
+
+	if up.Cost != nil {
+		values = append(values, *up.Cost)
+		fields = append(fields, fmt.Sprintf("cost = $%d", d))
+		d++
+	}
+
+	if up.EffectiveFrom != 0 {
+		values = append(values, up.EffectiveFrom)
+		fields = append(fields, fmt.Sprintf("effective_from = $%d", d))
+	}
+
+	query := fmt.Sprintf("UPDATE prices SET %s WHERE id = $1 AND deleted = FALSE RETURNING *", strings.Join(fields, ","))
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	updated, err := scanPrice(s.db.QueryRowContext(ctxTimeout, query, values...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, internal_errors.NewNotFoundError("price is not found for id: " + id)
+		}
+
+		if strings.Contains(err.Error(), "prices_effective_idx") {
+			return nil, internal_errors.NewValidationError(fmt.Sprintf("another price is already effective from %d", up.EffectiveFrom))
+		}
+
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// DeletePrice soft deletes a price so that in memory stores polling for updates can
+// remove it.
+func (s *Store) DeletePrice(id string, updatedAt int64) error {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctxTimeout, "UPDATE prices SET deleted = TRUE, updated_at = $2 WHERE id = $1 AND deleted = FALSE", id, updatedAt)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return internal_errors.NewNotFoundError("price is not found for id: " + id)
+	}
+
+	return nil
+}
+
+func (s *Store) GetPrice(id string) (*pricing.Price, error) {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.rt)
+	defer cancel()
+
+	p, err := scanPrice(s.db.QueryRowContext(ctxTimeout, "SELECT * FROM prices WHERE id = $1 AND deleted = FALSE", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, internal_errors.NewNotFoundError("price is not found for id: " + id)
+		}
+
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (s *Store) queryPrices(query string, args ...any) ([]*pricing.Price, error) {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.rt)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctxTimeout, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := []*pricing.Price{}
+	for rows.Next() {
+		p, err := scanPrice(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		prices = append(prices, p)
+	}
+
+	return prices, nil
+}
+
+func (s *Store) GetPrices(filter *pricing.PriceFilter) ([]*pricing.Price, error) {
+	conditions := []string{"deleted = FALSE"}
+	args := []any{}
+
+	if filter != nil {
+		for _, c := range []struct {
+			column string
+			value  string
+		}{
+			{"provider", filter.Provider},
+			{"model", filter.Model},
+			{"cost_type", filter.CostType},
+		} {
+			if len(c.value) != 0 {
+				args = append(args, c.value)
+				conditions = append(conditions, fmt.Sprintf("%s = $%d", c.column, len(args)))
+			}
+		}
+	}
+
+	query := fmt.Sprintf("SELECT * FROM prices WHERE %s ORDER BY provider, cost_type, model, effective_from", strings.Join(conditions, " AND "))
+
+	return s.queryPrices(query, args...)
+}
+
+// GetUpdatedPrices returns prices updated since updatedAt including deleted ones.
+func (s *Store) GetUpdatedPrices(updatedAt int64) ([]*pricing.Price, error) {
+	return s.queryPrices("SELECT * FROM prices WHERE updated_at >= $1", updatedAt)
+}
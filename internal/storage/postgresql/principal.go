@@ -0,0 +1,183 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+	"github.com/bricks-cloud/bricksllm/internal/principal"
+)
+
+func (s *Store) CreatePrincipalsTable() error {
+	createTableQuery := `
+	CREATE TABLE IF NOT EXISTS admin_principals (
+		id VARCHAR(255) PRIMARY KEY,
+		created_at BIGINT NOT NULL,
+		updated_at BIGINT NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		role VARCHAR(255) NOT NULL,
+		token_hash VARCHAR(255) NOT NULL,
+		token_suffix VARCHAR(255) NOT NULL,
+		revoked BOOLEAN NOT NULL DEFAULT FALSE,
+		deleted BOOLEAN NOT NULL DEFAULT FALSE
+	);
+	CREATE UNIQUE INDEX IF NOT EXISTS admin_principals_token_hash_idx ON admin_principals (token_hash);
+	`
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+	_, err := s.db.ExecContext(ctxTimeout, createTableQuery)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func scanPrincipal(row rowScanner) (*principal.Principal, error) {
+	p := &principal.Principal{}
+
+	if err := row.Scan(
+		&p.Id,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+		&p.Name,
+		&p.Role,
+		&p.TokenHash,
+		&p.TokenSuffix,
+		&p.Revoked,
+		&p.Deleted,
+	); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (s *Store) CreatePrincipal(p *principal.Principal) (*principal.Principal, error) {
+	query := `
+		INSERT INTO admin_principals (id, created_at, updated_at, name, role, token_hash, token_suffix)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING *
+	`
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	return scanPrincipal(s.db.QueryRowContext(ctxTimeout, query, p.Id, p.CreatedAt, p.UpdatedAt, p.Name, p.Role, p.TokenHash, p.TokenSuffix))
+}
+
+func (s *Store) UpdatePrincipal(id string, up *principal.UpdatePrincipal) (*principal.Principal, error) {
+	values := []any{
+		id,
+		up.UpdatedAt,
+	}
+
+	fields := []string{"updated_at = $2"}
+
+	if len(up.Name) != 0 {
+		values = append(values, up.Name)
+		fields = append(fields, fmt.Sprintf("name = $%d", len(values)))
+	}
+
+	if len(up.Role) != 0 {
+		values = append(values, up.Role)
+		fields = append(fields, fmt.Sprintf("role = $%d", len(values)))
+	}
+
+	if up.Revoked != nil {
+		values = append(values, *up.Revoked)
+		fields = append(fields, fmt.Sprintf("revoked = $%d", len(values)))
+	}
+
+	query := fmt.Sprintf("UPDATE admin_principals SET %s WHERE id = $1 AND deleted = FALSE RETURNING *", strings.Join(fields, ","))
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	updated, err := scanPrincipal(s.db.QueryRowContext(ctxTimeout, query, values...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, internal_errors.NewNotFoundError("principal is not found for id: " + id)
+		}
+
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func (s *Store) DeletePrincipal(id string, updatedAt int64) error {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctxTimeout, "UPDATE admin_principals SET deleted = TRUE, updated_at = $2 WHERE id = $1 AND deleted = FALSE", id, updatedAt)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return internal_errors.NewNotFoundError("principal is not found for id: " + id)
+	}
+
+	return nil
+}
+
+func (s *Store) getPrincipal(query string, arg string) (*principal.Principal, error) {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.rt)
+	defer cancel()
+
+	p, err := scanPrincipal(s.db.QueryRowContext(ctxTimeout, query, arg))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, internal_errors.NewNotFoundError("principal is not found")
+		}
+
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (s *Store) GetPrincipal(id string) (*principal.Principal, error) {
+	p, err := s.getPrincipal("SELECT * FROM admin_principals WHERE id = $1 AND deleted = FALSE", id)
+	if _, ok := err.(*internal_errors.NotFoundError); ok {
+		return nil, internal_errors.NewNotFoundError("principal is not found for id: " + id)
+	}
+
+	return p, err
+}
+
+func (s *Store) GetPrincipalByTokenHash(hash string) (*principal.Principal, error) {
+	return s.getPrincipal("SELECT * FROM admin_principals WHERE token_hash = $1 AND deleted = FALSE", hash)
+}
+
+func (s *Store) GetPrincipals() ([]*principal.Principal, error) {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.rt)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctxTimeout, "SELECT * FROM admin_principals WHERE deleted = FALSE ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	principals := []*principal.Principal{}
+	for rows.Next() {
+		p, err := scanPrincipal(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		principals = append(principals, p)
+	}
+
+	return principals, nil
+}
@@ -36,7 +36,7 @@ func (s *Store) CreateProviderSettingsTable() error {
 
 func (s *Store) AlterProviderSettingsTable() error {
 	alterTableQuery := `
-		ALTER TABLE provider_settings ADD COLUMN IF NOT EXISTS name VARCHAR(255), ADD COLUMN IF NOT EXISTS allowed_models VARCHAR(255)[], ADD COLUMN IF NOT EXISTS cost_map JSONB NOT NULL DEFAULT '{}'::JSONB
+		ALTER TABLE provider_settings ADD COLUMN IF NOT EXISTS name VARCHAR(255), ADD COLUMN IF NOT EXISTS allowed_models VARCHAR(255)[], ADD COLUMN IF NOT EXISTS cost_map JSONB NOT NULL DEFAULT '{}'::JSONB, ADD COLUMN IF NOT EXISTS cache_config JSONB
 	`
 
 	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
@@ -56,6 +56,7 @@ func (s *Store) GetProviderSetting(id string, withSecret bool) (*provider.Settin
 	setting := &provider.Setting{}
 	var data []byte
 	var cmdata []byte
+	var ccdata []byte
 	var name sql.NullString
 	err := s.db.QueryRowContext(ctxTimeout, "SELECT * FROM provider_settings WHERE $1 = id", id).Scan(
 		&setting.Id,
@@ -66,6 +67,7 @@ func (s *Store) GetProviderSetting(id string, withSecret bool) (*provider.Settin
 		&name,
 		pq.Array(&setting.AllowedModels),
 		&cmdata,
+		&ccdata,
 	)
 
 	if err != nil {
@@ -93,6 +95,15 @@ func (s *Store) GetProviderSetting(id string, withSecret bool) (*provider.Settin
 	setting.Setting = m
 	setting.CostMap = cm
 
+	if len(ccdata) != 0 {
+		var cc *provider.CacheConfig
+		if err := json.Unmarshal(ccdata, &cc); err != nil {
+			return nil, err
+		}
+
+		setting.CacheConfig = cc
+	}
+
 	setting.Name = name.String
 
 	return setting, nil
@@ -113,6 +124,7 @@ func (s *Store) GetUpdatedProviderSettings(updatedAt int64) ([]*provider.Setting
 		setting := &provider.Setting{}
 		var data []byte
 		var cmdata []byte
+		var ccdata []byte
 		var name sql.NullString
 		if err := rows.Scan(
 			&setting.Id,
@@ -123,6 +135,7 @@ func (s *Store) GetUpdatedProviderSettings(updatedAt int64) ([]*provider.Setting
 			&name,
 			pq.Array(&setting.AllowedModels),
 			&cmdata,
+			&ccdata,
 		); err != nil {
 			return nil, err
 		}
@@ -139,6 +152,15 @@ func (s *Store) GetUpdatedProviderSettings(updatedAt int64) ([]*provider.Setting
 
 		setting.Setting = m
 		setting.CostMap = cm
+
+		if len(ccdata) != 0 {
+			var cc *provider.CacheConfig
+			if err := json.Unmarshal(ccdata, &cc); err != nil {
+				return nil, err
+			}
+
+			setting.CacheConfig = cc
+		}
 		setting.Name = name.String
 		settings = append(settings, setting)
 	}
@@ -186,15 +208,27 @@ func (s *Store) UpdateProviderSetting(id string, setting *provider.UpdateSetting
 
 		values = append(values, data)
 		fields = append(fields, fmt.Sprintf("cost_map = $%d", d))
+		d++
 	}
 
-	query := fmt.Sprintf("UPDATE provider_settings SET %s WHERE id = $1 RETURNING id, created_at, updated_at, provider, name, allowed_models, setting, cost_map;", strings.Join(fields, ","))
+	if setting.CacheConfig != nil {
+		data, err := json.Marshal(setting.CacheConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, data)
+		fields = append(fields, fmt.Sprintf("cache_config = $%d", d))
+	}
+
+	query := fmt.Sprintf("UPDATE provider_settings SET %s WHERE id = $1 RETURNING id, created_at, updated_at, provider, name, allowed_models, setting, cost_map, cache_config;", strings.Join(fields, ","))
 	updated := &provider.Setting{}
 	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
 	defer cancel()
 
 	var rawd []byte
 	var cmdata []byte
+	var ccdata []byte
 
 	row := s.db.QueryRowContext(ctxTimeout, query, values...)
 	if err := row.Scan(
@@ -206,6 +240,7 @@ func (s *Store) UpdateProviderSetting(id string, setting *provider.UpdateSetting
 		pq.Array(&updated.AllowedModels),
 		&rawd,
 		&cmdata,
+		&ccdata,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, internal_errors.NewNotFoundError("provider setting is not found for: " + id)
@@ -229,6 +264,15 @@ func (s *Store) UpdateProviderSetting(id string, setting *provider.UpdateSetting
 	updated.Setting = m
 	updated.CostMap = cm
 
+	if len(ccdata) != 0 {
+		var cc *provider.CacheConfig
+		if err := json.Unmarshal(ccdata, &cc); err != nil {
+			return nil, err
+		}
+
+		updated.CacheConfig = cc
+	}
+
 	return updated, nil
 }
 
@@ -255,9 +299,9 @@ func (s *Store) CreateProviderSetting(setting *provider.Setting) (*provider.Sett
 	}
 
 	query := `
-		INSERT INTO provider_settings (id, created_at, updated_at, provider, setting, name, allowed_models, cost_map)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		RETURNING id, created_at, updated_at, provider, name, allowed_models, setting, cost_map
+		INSERT INTO provider_settings (id, created_at, updated_at, provider, setting, name, allowed_models, cost_map, cache_config)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, updated_at, provider, name, allowed_models, setting, cost_map, cache_config
 	`
 
 	data, err := json.Marshal(setting.Setting)
@@ -270,6 +314,11 @@ func (s *Store) CreateProviderSetting(setting *provider.Setting) (*provider.Sett
 		return nil, err
 	}
 
+	ccd, err := json.Marshal(setting.CacheConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	values := []any{
 		setting.Id,
 		setting.CreatedAt,
@@ -279,10 +328,12 @@ func (s *Store) CreateProviderSetting(setting *provider.Setting) (*provider.Sett
 		setting.Name,
 		sliceToSqlStringArray(setting.AllowedModels),
 		cmd,
+		ccd,
 	}
 
 	var rawd []byte
 	var rawcmd []byte
+	var ccdata []byte
 
 	created := &provider.Setting{}
 	var name sql.NullString
@@ -295,6 +346,7 @@ func (s *Store) CreateProviderSetting(setting *provider.Setting) (*provider.Sett
 		pq.Array(&created.AllowedModels),
 		&rawd,
 		&rawcmd,
+		&ccdata,
 	); err != nil {
 		return nil, err
 	}
@@ -314,6 +366,15 @@ func (s *Store) CreateProviderSetting(setting *provider.Setting) (*provider.Sett
 	created.Setting = m
 	created.CostMap = cm
 
+	if len(ccdata) != 0 {
+		var cc *provider.CacheConfig
+		if err := json.Unmarshal(ccdata, &cc); err != nil {
+			return nil, err
+		}
+
+		created.CacheConfig = cc
+	}
+
 	created.Name = name.String
 	return created, nil
 }
@@ -342,6 +403,7 @@ func (s *Store) GetProviderSettings(withSecret bool, ids []string) ([]*provider.
 		setting := &provider.Setting{}
 		var data []byte
 		var cmdata []byte
+		var ccdata []byte
 
 		var name sql.NullString
 		if err := rows.Scan(
@@ -353,6 +415,7 @@ func (s *Store) GetProviderSettings(withSecret bool, ids []string) ([]*provider.
 			&name,
 			pq.Array(&setting.AllowedModels),
 			&cmdata,
+			&ccdata,
 		); err != nil {
 			return nil, err
 		}
@@ -374,6 +437,15 @@ func (s *Store) GetProviderSettings(withSecret bool, ids []string) ([]*provider.
 		setting.Setting = m
 		setting.CostMap = cm
 
+		if len(ccdata) != 0 {
+			var cc *provider.CacheConfig
+			if err := json.Unmarshal(ccdata, &cc); err != nil {
+				return nil, err
+			}
+
+			setting.CacheConfig = cc
+		}
+
 		setting.Name = name.String
 		settings = append(settings, setting)
 	}
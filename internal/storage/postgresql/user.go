@@ -46,6 +46,21 @@ func (s *Store) CreateUsersTable() error {
 	return nil
 }
 
+func (s *Store) AlterUsersTable() error {
+	alterTableQuery := `
+		ALTER TABLE users ADD COLUMN IF NOT EXISTS token_limit_over_time INT NOT NULL DEFAULT 0, ADD COLUMN IF NOT EXISTS token_limit_unit VARCHAR(255) NOT NULL DEFAULT '';
+	`
+
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
+	defer cancel()
+	_, err := s.db.ExecContext(ctxTimeout, alterTableQuery)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (s *Store) CreateCreatedAtIndexForUsers() error {
 	createIndexQuery := `
 	CREATE INDEX IF NOT EXISTS created_at_idx ON users(created_at);
@@ -151,6 +166,8 @@ func (s *Store) GetUsers(tags, keyIds, userIds []string, offset, limit int) ([]*
 			&data,
 			pq.Array(&u.AllowedModels),
 			&u.UserId,
+			&u.TokenLimitOverTime,
+			&u.TokenLimitUnit,
 		); err != nil {
 			return nil, err
 		}
@@ -172,10 +189,58 @@ func (s *Store) GetUsers(tags, keyIds, userIds []string, offset, limit int) ([]*
 	return users, nil
 }
 
+func (s *Store) GetUser(id string) (*user.User, error) {
+	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.rt)
+	defer cancel()
+
+	var u user.User
+	var data []byte
+	if err := s.db.QueryRowContext(ctxTimeout, "SELECT * FROM users WHERE id = $1", id).Scan(
+		&u.Id,
+		&u.Name,
+		&u.CreatedAt,
+		&u.UpdatedAt,
+		pq.Array(&u.Tags),
+		&u.Revoked,
+		&u.RevokedReason,
+		&u.CostLimitInUsd,
+		&u.CostLimitInUsdOverTime,
+		&u.CostLimitInUsdUnit,
+		&u.RateLimitOverTime,
+		&u.RateLimitUnit,
+		&u.Ttl,
+		pq.Array(&u.KeyIds),
+		&data,
+		pq.Array(&u.AllowedModels),
+		&u.UserId,
+		&u.TokenLimitOverTime,
+		&u.TokenLimitUnit,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, internal_errors.NewNotFoundError("user is not found for id: " + id)
+		}
+
+		return nil, err
+	}
+
+	pu := &u
+
+	if len(data) != 0 {
+		pathConfigs := []key.PathConfig{}
+		if err := json.Unmarshal(data, &pathConfigs); err != nil {
+			return nil, err
+		}
+
+		pu.AllowedPaths = pathConfigs
+	}
+
+	return pu, nil
+}
+
 func (s *Store) CreateUser(u *user.User) (*user.User, error) {
 	query := `
-		INSERT INTO users (id, name, created_at, updated_at, tags, revoked, revoked_reason, cost_limit_in_usd, cost_limit_in_usd_over_time, cost_limit_in_usd_unit, rate_limit_over_time, rate_limit_unit, ttl, key_ids, allowed_paths, allowed_models, user_id)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+		INSERT INTO users (id, name, created_at, updated_at, tags, revoked, revoked_reason, cost_limit_in_usd, cost_limit_in_usd_over_time, cost_limit_in_usd_unit, rate_limit_over_time, rate_limit_unit, ttl, key_ids, allowed_paths, allowed_models, user_id, token_limit_over_time, token_limit_unit)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
 		RETURNING *;
 	`
 
@@ -202,6 +267,8 @@ func (s *Store) CreateUser(u *user.User) (*user.User, error) {
 		rdata,
 		pq.Array(u.AllowedModels),
 		u.UserId,
+		u.TokenLimitOverTime,
+		u.TokenLimitUnit,
 	}
 
 	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
@@ -228,6 +295,8 @@ func (s *Store) CreateUser(u *user.User) (*user.User, error) {
 		&data,
 		pq.Array(&created.AllowedModels),
 		&created.UserId,
+		&created.TokenLimitOverTime,
+		&created.TokenLimitUnit,
 	); err != nil {
 		return nil, err
 	}
@@ -319,6 +388,18 @@ func (s *Store) UpdateUser(id string, uu *user.UpdateUser) (*user.User, error) {
 		counter++
 	}
 
+	if uu.TokenLimitOverTime != nil {
+		values = append(values, *uu.TokenLimitOverTime)
+		fields = append(fields, fmt.Sprintf("token_limit_over_time = $%d", counter))
+		counter++
+	}
+
+	if uu.TokenLimitUnit != nil {
+		values = append(values, *uu.TokenLimitUnit)
+		fields = append(fields, fmt.Sprintf("token_limit_unit = $%d", counter))
+		counter++
+	}
+
 	if uu.AllowedPaths != nil {
 		data, err := json.Marshal(uu.AllowedPaths)
 		if err != nil {
@@ -368,6 +449,8 @@ func (s *Store) UpdateUser(id string, uu *user.UpdateUser) (*user.User, error) {
 		&data,
 		pq.Array(&updated.AllowedModels),
 		&updated.UserId,
+		&updated.TokenLimitOverTime,
+		&updated.TokenLimitUnit,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, internal_errors.NewNotFoundError(fmt.Sprintf("key not found for id: %s", id))
@@ -464,6 +547,18 @@ func (s *Store) UpdateUserViaTagsAndUserId(tags []string, uid string, uu *user.U
 		counter++
 	}
 
+	if uu.TokenLimitOverTime != nil {
+		values = append(values, *uu.TokenLimitOverTime)
+		fields = append(fields, fmt.Sprintf("token_limit_over_time = $%d", counter))
+		counter++
+	}
+
+	if uu.TokenLimitUnit != nil {
+		values = append(values, *uu.TokenLimitUnit)
+		fields = append(fields, fmt.Sprintf("token_limit_unit = $%d", counter))
+		counter++
+	}
+
 	if uu.AllowedPaths != nil {
 		data, err := json.Marshal(uu.AllowedPaths)
 		if err != nil {
@@ -513,6 +608,8 @@ func (s *Store) UpdateUserViaTagsAndUserId(tags []string, uid string, uu *user.U
 		&data,
 		pq.Array(&updated.AllowedModels),
 		&updated.UserId,
+		&updated.TokenLimitOverTime,
+		&updated.TokenLimitUnit,
 	); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, internal_errors.NewNotFoundError(fmt.Sprintf("key not found for user id: %s tags: [%s]", uid, strings.Join(tags, ",")))
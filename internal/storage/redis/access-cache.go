@@ -34,7 +34,7 @@ func (ac *AccessCache) Delete(key string) error {
 }
 
 func (ac *AccessCache) Set(key string, timeUnit key.TimeUnit) error {
-	ttl, err := getCounterTtl(timeUnit)
+	ttl, err := getCounterTtlAt(timeUnit, time.Now())
 	if err != nil {
 		return err
 	}
@@ -10,15 +10,52 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// reserveCounterScript sums the counter of the current window and only adds the
+// increment when the sum stays within the limit so that concurrent requests cannot
+// reserve past it.
+var reserveCounterScript = redis.NewScript(`
+local key = KEYS[1]
+local field = ARGV[1]
+local expireAt = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+local incr = tonumber(ARGV[4])
+
+local count = 0
+for _, v in ipairs(redis.call('HVALS', key)) do
+	count = count + (tonumber(v) or 0)
+end
+
+if count + incr > limit then
+	return {0, count}
+end
+
+if incr ~= 0 then
+	redis.call('HINCRBY', key, field, incr)
+	if redis.call('PTTL', key) < 0 then
+		redis.call('PEXPIREAT', key, expireAt)
+	end
+end
+
+return {1, count + incr}
+`)
+
 type Cache struct {
 	client *redis.Client
+	prefix string
 	wt     time.Duration
 	rt     time.Duration
 }
 
 func NewCache(c *redis.Client, wt time.Duration, rt time.Duration) *Cache {
+	return NewCacheWithPrefix(c, "", wt, rt)
+}
+
+// NewCacheWithPrefix returns a cache whose keys are namespaced by the prefix so that it
+// can share a redis db with another cache.
+func NewCacheWithPrefix(c *redis.Client, prefix string, wt time.Duration, rt time.Duration) *Cache {
 	return &Cache{
 		client: c,
+		prefix: prefix,
 		wt:     wt,
 		rt:     rt,
 	}
@@ -27,7 +64,7 @@ func NewCache(c *redis.Client, wt time.Duration, rt time.Duration) *Cache {
 func (c *Cache) Set(key string, value interface{}, ttl time.Duration) error {
 	ctx, cancel := context.WithTimeout(context.Background(), c.wt)
 	defer cancel()
-	err := c.client.Set(ctx, key, value, ttl).Err()
+	err := c.client.Set(ctx, c.prefix+key, value, ttl).Err()
 	if err != nil {
 		return err
 	}
@@ -35,10 +72,19 @@ func (c *Cache) Set(key string, value interface{}, ttl time.Duration) error {
 	return nil
 }
 
+// SetNX sets the value of a key only if the key does not exist. It reports whether
+// the value was set.
+func (c *Cache) SetNX(key string, value interface{}, ttl time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.wt)
+	defer cancel()
+
+	return c.client.SetNX(ctx, c.prefix+key, value, ttl).Result()
+}
+
 func (c *Cache) Delete(key string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), c.wt)
 	defer cancel()
-	err := c.client.Del(ctx, key).Err()
+	err := c.client.Del(ctx, c.prefix+key).Err()
 	if err != nil {
 		return err
 	}
@@ -50,7 +96,7 @@ func (c *Cache) GetBytes(key string) ([]byte, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), c.rt)
 	defer cancel()
 
-	result := c.client.Get(ctx, key)
+	result := c.client.Get(ctx, c.prefix+key)
 	err := result.Err()
 	if err != nil {
 		return nil, err
@@ -60,10 +106,70 @@ func (c *Cache) GetBytes(key string) ([]byte, error) {
 }
 
 func (c *Cache) IncrementCounter(keyId string, timeUnit key.TimeUnit, incr int64) error {
+	return c.incrementCounterAt(keyId, timeUnit, time.Now(), incr)
+}
+
+// GetCounterWindow returns the end of the current counter window in unix milliseconds.
+func (c *Cache) GetCounterWindow(timeUnit key.TimeUnit) (int64, error) {
+	end, err := getCounterTtlAt(timeUnit, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	return end.UnixMilli(), nil
+}
+
+// IncrementCounterInWindow increments the counter only while the window that ends at
+// window is the current one. Once that window is over its counter has expired, so the
+// increment would otherwise land in a window it does not belong to.
+func (c *Cache) IncrementCounterInWindow(keyId string, timeUnit key.TimeUnit, window int64, incr int64) error {
+	now := time.Now()
+	end, err := getCounterTtlAt(timeUnit, now)
+	if err != nil {
+		return err
+	}
+
+	if end.UnixMilli() != window {
+		return nil
+	}
+
+	return c.incrementCounterAt(keyId, timeUnit, now, incr)
+}
+
+// ReserveCounter atomically increments the counter of the current window when the
+// increment fits in the limit. It reports whether the increment was reserved, the
+// counter after the reservation or the current counter if it was not reserved and the
+// end of the window in unix milliseconds.
+func (c *Cache) ReserveCounter(keyId string, timeUnit key.TimeUnit, limit int64, incr int64) (bool, int64, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.wt)
+	defer cancel()
+
+	now := time.Now()
+	end, err := getCounterTtlAt(timeUnit, now)
+	if err != nil {
+		return false, 0, 0, err
+	}
+
+	ts, err := getCounterTimeStampAt(timeUnit, now)
+	if err != nil {
+		return false, 0, 0, err
+	}
+
+	res, err := reserveCounterScript.Run(ctx, c.client, []string{c.prefix + keyId}, strconv.FormatInt(ts, 10), end.UnixMilli(), limit, incr).Int64Slice()
+	if err != nil {
+		return false, 0, 0, err
+	}
+
+	return res[0] == 1, res[1], end.UnixMilli(), nil
+}
+
+func (c *Cache) incrementCounterAt(keyId string, timeUnit key.TimeUnit, now time.Time, incr int64) error {
 	ctxTimeout, cancel := context.WithTimeout(context.Background(), c.wt)
 	defer cancel()
 
-	ts, err := getCounterTimeStamp(timeUnit)
+	keyId = c.prefix + keyId
+
+	ts, err := getCounterTimeStampAt(timeUnit, now)
 	if err != nil {
 		return err
 	}
@@ -83,7 +189,7 @@ func (c *Cache) IncrementCounter(keyId string, timeUnit key.TimeUnit, incr int64
 
 	val := dur.Val()
 	if val < 0 {
-		ttl, err := getCounterTtl(timeUnit)
+		ttl, err := getCounterTtlAt(timeUnit, now)
 		if err != nil {
 			return err
 		}
@@ -100,8 +206,8 @@ func (c *Cache) IncrementCounter(keyId string, timeUnit key.TimeUnit, incr int64
 	return nil
 }
 
-func getCounterTtl(rateLimitUnit key.TimeUnit) (time.Time, error) {
-	now := time.Now().UTC()
+func getCounterTtlAt(rateLimitUnit key.TimeUnit, now time.Time) (time.Time, error) {
+	now = now.UTC()
 	switch rateLimitUnit {
 	case key.SecondTimeUnit:
 		return now.Truncate(time.Second).Add(time.Second).Add(-time.Millisecond), nil
@@ -119,8 +225,8 @@ func getCounterTtl(rateLimitUnit key.TimeUnit) (time.Time, error) {
 	return time.Time{}, fmt.Errorf("cannot recognize rate limit time unit %v", rateLimitUnit)
 }
 
-func getCounterTimeStamp(rateLimitUnit key.TimeUnit) (int64, error) {
-	now := time.Now().UTC()
+func getCounterTimeStampAt(rateLimitUnit key.TimeUnit, now time.Time) (int64, error) {
+	now = now.UTC()
 	switch rateLimitUnit {
 	case key.SecondTimeUnit:
 		return now.UnixMilli() * 10, nil
@@ -141,7 +247,7 @@ func (c *Cache) GetCounter(keyId string, rateLimitUnit key.TimeUnit) (int64, err
 	ctxTimeout, cancel := context.WithTimeout(context.Background(), c.rt)
 	defer cancel()
 
-	strSlices := c.client.HVals(ctxTimeout, keyId)
+	strSlices := c.client.HVals(ctxTimeout, c.prefix+keyId)
 	err := strSlices.Err()
 
 	if err != nil && err != redis.Nil {
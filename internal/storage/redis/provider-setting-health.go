@@ -0,0 +1,151 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/provider"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	healthKeyPrefix      = "provider-setting-health-"
+	roundRobinKeyPrefix  = "provider-setting-round-robin-"
+	healthStateRetention = 24 * time.Hour
+)
+
+// recordOutcomeScript keeps exponentially weighted averages of the error rate
+// and latency of a provider setting, and ejects the setting for a cooldown once
+// the number of consecutive failures reaches the threshold.
+var recordOutcomeScript = redis.NewScript(`
+local key = KEYS[1]
+local failed = tonumber(ARGV[1])
+local latency = tonumber(ARGV[2])
+local threshold = tonumber(ARGV[3])
+local cooldown = tonumber(ARGV[4])
+local retention = tonumber(ARGV[5])
+local alpha = 0.2
+
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call('HMGET', key, 'error_rate', 'latency', 'failures')
+local errorRate = tonumber(state[1]) or 0
+local avgLatency = tonumber(state[2])
+local failures = tonumber(state[3]) or 0
+
+errorRate = errorRate * (1 - alpha) + failed * alpha
+
+if failed == 1 then
+	failures = failures + 1
+else
+	failures = 0
+	if avgLatency == nil then
+		avgLatency = latency
+	else
+		avgLatency = avgLatency * (1 - alpha) + latency * alpha
+	end
+end
+
+redis.call('HSET', key, 'error_rate', tostring(errorRate), 'failures', failures)
+
+if avgLatency ~= nil then
+	redis.call('HSET', key, 'latency', tostring(avgLatency))
+end
+
+if failures >= threshold then
+	redis.call('HSET', key, 'ejected_until', now + cooldown, 'failures', 0)
+end
+
+redis.call('PEXPIRE', key, retention)
+
+return failures
+`)
+
+type ProviderSettingHealthCache struct {
+	client *redis.Client
+	wt     time.Duration
+	rt     time.Duration
+}
+
+func NewProviderSettingHealthCache(c *redis.Client, wt time.Duration, rt time.Duration) *ProviderSettingHealthCache {
+	return &ProviderSettingHealthCache{
+		client: c,
+		wt:     wt,
+		rt:     rt,
+	}
+}
+
+func (c *ProviderSettingHealthCache) RecordOutcome(settingId string, failed bool, latencyInMs int, threshold int, cooldown time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.wt)
+	defer cancel()
+
+	f := 0
+	if failed {
+		f = 1
+	}
+
+	return recordOutcomeScript.Run(ctx, c.client, []string{healthKeyPrefix + settingId}, f, latencyInMs, threshold, cooldown.Milliseconds(), healthStateRetention.Milliseconds()).Err()
+}
+
+func parseFloat(val interface{}) float64 {
+	str, ok := val.(string)
+	if !ok {
+		return 0
+	}
+
+	parsed, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return 0
+	}
+
+	return parsed
+}
+
+// parseHealth returns nil when the setting has no health state, in which case HMGET
+// returns a nil for every field.
+func parseHealth(vals []interface{}) *provider.SettingHealth {
+	if len(vals) != 3 || (vals[0] == nil && vals[1] == nil && vals[2] == nil) {
+		return nil
+	}
+
+	return &provider.SettingHealth{
+		ErrorRate:    parseFloat(vals[0]),
+		LatencyInMs:  parseFloat(vals[1]),
+		HasLatency:   vals[1] != nil,
+		EjectedUntil: int64(parseFloat(vals[2])),
+	}
+}
+
+func (c *ProviderSettingHealthCache) GetHealth(settingIds []string) (map[string]*provider.SettingHealth, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.rt)
+	defer cancel()
+
+	pipe := c.client.Pipeline()
+	cmds := make([]*redis.SliceCmd, 0, len(settingIds))
+	for _, id := range settingIds {
+		cmds = append(cmds, pipe.HMGet(ctx, healthKeyPrefix+id, "error_rate", "latency", "ejected_until"))
+	}
+
+	_, err := pipe.Exec(ctx)
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	health := map[string]*provider.SettingHealth{}
+	for i, cmd := range cmds {
+		if h := parseHealth(cmd.Val()); h != nil {
+			health[settingIds[i]] = h
+		}
+	}
+
+	return health, nil
+}
+
+func (c *ProviderSettingHealthCache) NextRoundRobinCounter(keyId string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.wt)
+	defer cancel()
+
+	return c.client.Incr(ctx, roundRobinKeyPrefix+keyId).Result()
+}
@@ -0,0 +1,25 @@
+package redis
+
+import (
+	"testing"
+
+	"github.com/bricks-cloud/bricksllm/internal/provider"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHealth(t *testing.T) {
+	cases := []struct {
+		name     string
+		vals     []interface{}
+		expected *provider.SettingHealth
+	}{
+		{"missing hash", []interface{}{nil, nil, nil}, nil},
+		{"unexpected length", []interface{}{"0.1"}, nil},
+		{"failures only", []interface{}{"0.2", nil, nil}, &provider.SettingHealth{ErrorRate: 0.2}},
+		{"measured", []interface{}{"0", "120.5", "1700000000000"}, &provider.SettingHealth{LatencyInMs: 120.5, HasLatency: true, EjectedUntil: 1700000000000}},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.expected, parseHealth(c.vals), c.name)
+	}
+}
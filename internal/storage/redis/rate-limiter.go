@@ -0,0 +1,107 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/bricks-cloud/bricksllm/internal/util"
+	"github.com/redis/go-redis/v9"
+)
+
+// slidingWindowScript keeps a log of request timestamps in a sorted set and
+// only admits a request when fewer than the limit fall inside the window.
+var slidingWindowScript = redis.NewScript(`
+local key = KEYS[1]
+local window = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+local member = ARGV[3]
+
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+redis.call('ZREMRANGEBYSCORE', key, 0, now - window)
+
+local count = redis.call('ZCARD', key)
+if count >= limit then
+	return {0, 0}
+end
+
+redis.call('ZADD', key, now, member)
+redis.call('PEXPIRE', key, window)
+
+return {1, limit - count - 1}
+`)
+
+// tokenBucketScript refills the bucket continuously at limit per window and
+// admits a request when at least one token is left, allowing bursts up to
+// the bucket capacity.
+var tokenBucketScript = redis.NewScript(`
+local key = KEYS[1]
+local window = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+local capacity = tonumber(ARGV[3])
+
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call('HMGET', key, 'tokens', 'ts')
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+
+local rate = limit / window
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', key, 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', key, math.ceil(capacity / rate))
+
+return {allowed, math.floor(tokens)}
+`)
+
+type RateLimiter struct {
+	client *redis.Client
+	wt     time.Duration
+	rt     time.Duration
+}
+
+func NewRateLimiter(c *redis.Client, wt time.Duration, rt time.Duration) *RateLimiter {
+	return &RateLimiter{
+		client: c,
+		wt:     wt,
+		rt:     rt,
+	}
+}
+
+func (rl *RateLimiter) AllowSlidingWindow(id string, limit int, window time.Duration) (bool, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), rl.wt)
+	defer cancel()
+
+	res, err := slidingWindowScript.Run(ctx, rl.client, []string{"sliding-window-" + id}, window.Milliseconds(), limit, util.NewUuid()).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+
+	return res[0] == 1, res[1], nil
+}
+
+func (rl *RateLimiter) AllowTokenBucket(id string, limit int, window time.Duration, burst int) (bool, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), rl.wt)
+	defer cancel()
+
+	capacity := burst
+	if capacity == 0 {
+		capacity = limit
+	}
+
+	res, err := tokenBucketScript.Run(ctx, rl.client, []string{"token-bucket-" + id}, window.Milliseconds(), limit, capacity).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+
+	return res[0] == 1, res[1], nil
+}
@@ -0,0 +1,62 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type SemanticCache struct {
+	client *redis.Client
+	wt     time.Duration
+	rt     time.Duration
+}
+
+func NewSemanticCache(c *redis.Client, wt time.Duration, rt time.Duration) *SemanticCache {
+	return &SemanticCache{
+		client: c,
+		wt:     wt,
+		rt:     rt,
+	}
+}
+
+func getSemanticCacheKey(scope string) string {
+	return "semantic-cache-v2-" + scope
+}
+
+func (c *SemanticCache) AddEntry(scope string, entry []byte, max int64, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.wt)
+	defer cancel()
+
+	key := getSemanticCacheKey(scope)
+
+	pipe := c.client.TxPipeline()
+	pipe.LPush(ctx, key, entry)
+	pipe.LTrim(ctx, key, 0, max-1)
+	pipe.Expire(ctx, key, ttl)
+
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *SemanticCache) GetEntries(scope string, max int64) ([][]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.rt)
+	defer cancel()
+
+	result, err := c.client.LRange(ctx, getSemanticCacheKey(scope), 0, max-1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := [][]byte{}
+	for _, r := range result {
+		entries = append(entries, []byte(r))
+	}
+
+	return entries, nil
+}
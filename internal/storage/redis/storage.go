@@ -9,13 +9,21 @@ import (
 
 type Store struct {
 	client *redis.Client
+	prefix string
 	wt     time.Duration
 	rt     time.Duration
 }
 
 func NewStore(c *redis.Client, wt time.Duration, rt time.Duration) *Store {
+	return NewStoreWithPrefix(c, "", wt, rt)
+}
+
+// NewStoreWithPrefix returns a store whose counters are namespaced by the prefix so that
+// it can share a redis db with another store.
+func NewStoreWithPrefix(c *redis.Client, prefix string, wt time.Duration, rt time.Duration) *Store {
 	return &Store{
 		client: c,
+		prefix: prefix,
 		wt:     wt,
 		rt:     rt,
 	}
@@ -25,21 +33,21 @@ func (s *Store) IncrementCounter(keyId string, incr int64) error {
 	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
 	defer cancel()
 
-	return s.client.IncrBy(ctxTimeout, keyId, incr).Err()
+	return s.client.IncrBy(ctxTimeout, s.prefix+keyId, incr).Err()
 }
 
 func (s *Store) DeleteCounter(keyId string) error {
 	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.wt)
 	defer cancel()
 
-	return s.client.Del(ctxTimeout, keyId).Err()
+	return s.client.Del(ctxTimeout, s.prefix+keyId).Err()
 }
 
 func (s *Store) GetCounter(keyId string) (int64, error) {
 	ctxTimeout, cancel := context.WithTimeout(context.Background(), s.rt)
 	defer cancel()
 
-	val := s.client.Get(ctxTimeout, keyId)
+	val := s.client.Get(ctxTimeout, s.prefix+keyId)
 	result, err := val.Int64()
 	if err == nil {
 		return result, nil
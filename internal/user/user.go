@@ -23,6 +23,8 @@ type User struct {
 	CostLimitInUsdUnit     key.TimeUnit     `json:"costLimitInUsdUnit"`
 	RateLimitOverTime      int              `json:"rateLimitOverTime"`
 	RateLimitUnit          key.TimeUnit     `json:"rateLimitUnit"`
+	TokenLimitOverTime     int              `json:"tokenLimitOverTime"`
+	TokenLimitUnit         key.TimeUnit     `json:"tokenLimitUnit"`
 	Ttl                    string           `json:"ttl"`
 	AllowedPaths           []key.PathConfig `json:"allowedPaths"`
 	AllowedModels          []string         `json:"allowedModels"`
@@ -118,6 +120,10 @@ func (u *User) Validate() error {
 		}
 	}
 
+	if err := key.ValidateTokenLimit(u.TokenLimitOverTime, u.TokenLimitUnit); err != nil {
+		return err
+	}
+
 	if u.CostLimitInUsdOverTime != 0 {
 		if len(u.CostLimitInUsdUnit) == 0 {
 			return internal_errors.NewValidationError("cost limit unit can not be empty if cost limit over time is specified")
@@ -142,6 +148,8 @@ type UpdateUser struct {
 	CostLimitInUsdUnit     *key.TimeUnit    `json:"costLimitInUsdUnit"`
 	RateLimitOverTime      *int             `json:"rateLimitOverTime"`
 	RateLimitUnit          *key.TimeUnit    `json:"rateLimitUnit"`
+	TokenLimitOverTime     *int             `json:"tokenLimitOverTime"`
+	TokenLimitUnit         *key.TimeUnit    `json:"tokenLimitUnit"`
 	AllowedPaths           []key.PathConfig `json:"allowedPaths"`
 	AllowedModels          []string         `json:"allowedModels"`
 	Ttl                    *string          `json:"ttl"`
@@ -233,6 +241,20 @@ func (uu *UpdateUser) Validate() error {
 		}
 	}
 
+	if uu.TokenLimitUnit != nil && uu.TokenLimitOverTime == nil {
+		return internal_errors.NewValidationError("token limit over time can not be empty if token limit unit is specified")
+	}
+
+	if uu.TokenLimitOverTime != nil {
+		if uu.TokenLimitUnit == nil {
+			return internal_errors.NewValidationError("token limit unit can not be empty if token limit over time is specified")
+		}
+
+		if err := key.ValidateTokenLimit(*uu.TokenLimitOverTime, *uu.TokenLimitUnit); err != nil {
+			return err
+		}
+	}
+
 	if uu.CostLimitInUsdOverTime != nil {
 		if uu.CostLimitInUsdUnit == nil {
 			return internal_errors.NewValidationError("cost limit unit can not be empty if cost limit over time is specified")
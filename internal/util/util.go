@@ -73,9 +73,32 @@ func TranslateBedrockModelToAnthropicModel(model string) string {
 		return "claude-3.5-sonnet"
 	}
 
+	if strings.HasPrefix(model, "anthropic.claude-3-5-haiku") {
+		return "claude-3.5-haiku"
+	}
+
 	if strings.HasPrefix(model, "anthropic.claude-instant") {
 		return "claude-instant"
 	}
 
 	return model
 }
+
+// MergeCostMaps returns the costs of base with the costs of overrides applied on top
+// so that a catalog listing a few models does not hide the costs of the others.
+func MergeCostMaps(base, overrides map[string]float64) map[string]float64 {
+	if len(overrides) == 0 {
+		return base
+	}
+
+	merged := make(map[string]float64, len(base)+len(overrides))
+	for model, cost := range base {
+		merged[model] = cost
+	}
+
+	for model, cost := range overrides {
+		merged[model] = cost
+	}
+
+	return merged
+}
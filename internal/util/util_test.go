@@ -0,0 +1,18 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeCostMaps(t *testing.T) {
+	base := map[string]float64{"a": 1, "b": 2}
+
+	assert.Equal(t, base, MergeCostMaps(base, nil))
+	assert.Equal(t, map[string]float64{"c": 3}, MergeCostMaps(nil, map[string]float64{"c": 3}))
+
+	merged := MergeCostMaps(base, map[string]float64{"b": 4, "c": 3})
+	assert.Equal(t, map[string]float64{"a": 1, "b": 4, "c": 3}, merged)
+	assert.Equal(t, map[string]float64{"a": 1, "b": 2}, base)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/bricks-cloud/bricksllm/internal/budget"
 	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
 	"github.com/bricks-cloud/bricksllm/internal/key"
 )
@@ -21,21 +22,34 @@ type costLimitStorage interface {
 	GetCounter(keyId string) (int64, error)
 }
 
+type budgetsStorage interface {
+	GetBudgetsForKey(keyId string, tags []string) []*budget.Budget
+}
+
 type Validator struct {
-	clc costLimitCache
-	rlc rateLimitCache
-	cls costLimitStorage
+	clc  costLimitCache
+	rlc  rateLimitCache
+	cls  costLimitStorage
+	bs   budgetsStorage
+	bclc costLimitCache
+	bcls costLimitStorage
 }
 
 func NewValidator(
 	clc costLimitCache,
 	rlc rateLimitCache,
 	cls costLimitStorage,
+	bs budgetsStorage,
+	bclc costLimitCache,
+	bcls costLimitStorage,
 ) *Validator {
 	return &Validator{
-		clc: clc,
-		rlc: rlc,
-		cls: cls,
+		clc:  clc,
+		rlc:  rlc,
+		cls:  cls,
+		bs:   bs,
+		bclc: bclc,
+		bcls: bcls,
 	}
 }
 
@@ -53,12 +67,14 @@ func (v *Validator) Validate(k *key.ResponseKey, promptCost float64) error {
 		return internal_errors.NewExpirationError("api key expired", internal_errors.TtlExpiration)
 	}
 
-	err := v.validateRateLimitOverTime(k.KeyId, k.RateLimitOverTime, k.RateLimitUnit)
-	if err != nil {
-		return err
+	if k.IsRateLimitedInFixedWindow() {
+		err := v.validateRateLimitOverTime(k.KeyId, k.RateLimitOverTime, k.RateLimitUnit)
+		if err != nil {
+			return err
+		}
 	}
 
-	err = v.validateCostLimitOverTime(k.KeyId, k.CostLimitInUsdOverTime, k.CostLimitInUsdUnit)
+	err := v.validateCostLimitOverTime(k.KeyId, k.CostLimitInUsdOverTime, k.CostLimitInUsdUnit)
 	if err != nil {
 		return err
 	}
@@ -71,6 +87,40 @@ func (v *Validator) Validate(k *key.ResponseKey, promptCost float64) error {
 	return nil
 }
 
+// ValidateBudgets checks the budgets that a key shares with other keys. Unlike the
+// limits of the key itself, reaching a budget never revokes the key.
+func (v *Validator) ValidateBudgets(k *key.ResponseKey) error {
+	if k == nil {
+		return internal_errors.NewValidationError("empty api key")
+	}
+
+	for _, b := range v.bs.GetBudgetsForKey(k.KeyId, k.Tags) {
+		if b.CostLimitInUsdOverTime != 0 {
+			cachedCost, err := v.bclc.GetCounter(b.Id, b.CostLimitInUsdUnit)
+			if err != nil {
+				return errors.New("failed to get cached budget cost")
+			}
+
+			if cachedCost >= convertDollarToMicroDollars(b.CostLimitInUsdOverTime) {
+				return internal_errors.NewCostLimitError(fmt.Sprintf("budget %s cost limit: %f has been reached for the current time period: %s", b.Name, b.CostLimitInUsdOverTime, b.CostLimitInUsdUnit))
+			}
+		}
+
+		if b.CostLimitInUsd != 0 {
+			existingTotalCost, err := v.bcls.GetCounter(b.Id)
+			if err != nil {
+				return errors.New("failed to get total budget cost")
+			}
+
+			if existingTotalCost >= convertDollarToMicroDollars(b.CostLimitInUsd) {
+				return internal_errors.NewCostLimitError(fmt.Sprintf("budget %s total cost limit: %f has been reached", b.Name, b.CostLimitInUsd))
+			}
+		}
+	}
+
+	return nil
+}
+
 func (v *Validator) validateTtl(createdAt int64, ttl time.Duration) bool {
 	ttlInSecs := int64(ttl.Seconds())
 
@@ -0,0 +1,78 @@
+package validator
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bricks-cloud/bricksllm/internal/budget"
+	internal_errors "github.com/bricks-cloud/bricksllm/internal/errors"
+	"github.com/bricks-cloud/bricksllm/internal/key"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeCostLimitCache map[string]int64
+
+func (c fakeCostLimitCache) GetCounter(keyId string, rateLimitUnit key.TimeUnit) (int64, error) {
+	count, ok := c[keyId]
+	if !ok {
+		return 0, errors.New("cache is down")
+	}
+
+	return count, nil
+}
+
+type fakeCostLimitStorage map[string]int64
+
+func (s fakeCostLimitStorage) GetCounter(keyId string) (int64, error) {
+	count, ok := s[keyId]
+	if !ok {
+		return 0, errors.New("storage is down")
+	}
+
+	return count, nil
+}
+
+type fakeBudgetsStorage []*budget.Budget
+
+func (s fakeBudgetsStorage) GetBudgetsForKey(keyId string, tags []string) []*budget.Budget {
+	budgets := []*budget.Budget{}
+	for _, b := range s {
+		if b.AppliesTo(keyId, tags) {
+			budgets = append(budgets, b)
+		}
+	}
+
+	return budgets
+}
+
+func TestValidator_ValidateBudgets(t *testing.T) {
+	budgets := fakeBudgetsStorage{
+		{Id: "org", Name: "org", CostLimitInUsd: 100},
+		{Id: "team", Name: "team", Tag: "team-a", CostLimitInUsdOverTime: 10, CostLimitInUsdUnit: key.DayTimeUnit},
+	}
+
+	cases := []struct {
+		name     string
+		k        *key.ResponseKey
+		periodic fakeCostLimitCache
+		total    fakeCostLimitStorage
+		limited  bool
+		err      bool
+	}{
+		{"nil key", nil, nil, nil, false, true},
+		{"under budgets", &key.ResponseKey{KeyId: "k", Tags: []string{"team-a"}}, fakeCostLimitCache{"team": 9999999}, fakeCostLimitStorage{"org": 99999999}, false, false},
+		{"team budget reached", &key.ResponseKey{KeyId: "k", Tags: []string{"team-a"}}, fakeCostLimitCache{"team": 10000000}, fakeCostLimitStorage{"org": 0}, true, false},
+		{"org budget reached", &key.ResponseKey{KeyId: "k"}, fakeCostLimitCache{}, fakeCostLimitStorage{"org": 100000000}, true, false},
+		{"team budget of another team", &key.ResponseKey{KeyId: "k", Tags: []string{"team-b"}}, fakeCostLimitCache{"team": 10000000}, fakeCostLimitStorage{"org": 0}, false, false},
+		{"counter error", &key.ResponseKey{KeyId: "k", Tags: []string{"team-a"}}, fakeCostLimitCache{}, fakeCostLimitStorage{"org": 0}, false, true},
+	}
+
+	for _, c := range cases {
+		v := NewValidator(nil, nil, nil, budgets, c.periodic, c.total)
+		err := v.ValidateBudgets(c.k)
+
+		_, limited := err.(*internal_errors.CostLimitError)
+		assert.Equal(t, c.limited, limited, c.name)
+		assert.Equal(t, c.err, err != nil && !limited, c.name)
+	}
+}